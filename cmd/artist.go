@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/broar/chipmusic-cli/pkg/chipmusic"
+	"github.com/spf13/cobra"
+	"strings"
+)
+
+var artistCmd = &cobra.Command{
+	Use:   "artist url",
+	Short: "Print the profile and tracks of an artist with an exact URL from chipmusic.org",
+	Run: func(cmd *cobra.Command, args []string) {
+		page, _ := cmd.Flags().GetInt("page")
+		if err := printArtist(args[0], page); err != nil {
+			panic(err)
+		}
+	},
+	Args: cobra.ExactArgs(1),
+}
+
+func init() {
+	rootCmd.AddCommand(artistCmd)
+	artistCmd.Flags().Int("page", 1, "Page of tracks to print")
+}
+
+func printArtist(artistURL string, page int) error {
+	client, err := newClient()
+	if err != nil {
+		return fmt.Errorf("failed to create chipmusic client: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	artist, err := client.GetArtist(ctx, artistURL)
+	if err != nil {
+		return fmt.Errorf("failed to get artist: %w", err)
+	}
+
+	fmt.Println(artist.Name)
+	if artist.Location != "" {
+		fmt.Printf("Location: %s\n", artist.Location)
+	}
+
+	if artist.Bio != "" {
+		fmt.Printf("\n%s\n", artist.Bio)
+	}
+
+	tracks := artist.Tracks
+	if page > 1 {
+		tracks, err = client.GetArtistTracks(ctx, artist.Name, page)
+		if err != nil {
+			return fmt.Errorf("failed to get tracks: %w", err)
+		}
+	}
+
+	fmt.Printf("\nTracks (page %d):\n", page)
+	for _, track := range tracks {
+		fmt.Printf("  %s\n    %s\n", track.Title, track.URL)
+	}
+
+	if len(tracks) == 0 {
+		fmt.Println("  No tracks")
+	}
+
+	return nil
+}
+
+// artistSearch returns the search text for shuffling only the tracks of artist, which is either the name of an artist
+// or the URL of their profile page
+func artistSearch(artist string) string {
+	return chipmusic.ArtistQuery(artistName(artist))
+}
+
+// artistName returns the name of artist, which is either the name of an artist or the URL of their profile page
+func artistName(artist string) string {
+	if strings.HasPrefix(artist, "http://") || strings.HasPrefix(artist, "https://") {
+		return chipmusic.ArtistName(artist)
+	}
+
+	return artist
+}
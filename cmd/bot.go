@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/broar/chipmusic-cli/pkg/dashboard"
+	"github.com/broar/chipmusic-cli/pkg/events"
+	"github.com/broar/chipmusic-cli/pkg/integrations/bot"
+	"github.com/spf13/viper"
+)
+
+// newBot creates a chat bot configured from flags and the config file. If no chat is configured, nil is returned
+func newBot() (*bot.Bot, error) {
+	var options []bot.Option
+	if webhook := viper.GetString("discord-webhook"); webhook != "" {
+		options = append(options, bot.WithDiscordWebhook(webhook))
+	}
+
+	if server := viper.GetString("irc-server"); server != "" {
+		options = append(options, bot.WithIRC(server, viper.GetString("irc-channel"), viper.GetString("irc-nick"), viper.GetBool("irc-tls")))
+	}
+
+	if len(options) == 0 {
+		return nil, nil
+	}
+
+	options = append(options, bot.WithOperators(viper.GetStringSlice("bot-operators")...))
+	return bot.NewBot(options...)
+}
+
+// runBot connects the bot to chat, announces every track played, and applies the commands sent by operators until ctx
+// is done
+func (s *session) runBot(ctx context.Context) {
+	// Subscribing first keeps tracks which start while connecting from going unannounced
+	ch, unsubscribe := s.bus.Subscribe(0, events.NamePlaybackStarted)
+	defer unsubscribe()
+
+	if err := s.bot.Start(ctx); err != nil {
+		s.bus.Publish(events.Error{Err: fmt.Errorf("failed to start chat bot: %w", err)})
+	}
+
+	actions := make(chan dashboard.Action)
+	go func() {
+		defer close(actions)
+		for command := range s.bot.Commands() {
+			if command.Name == bot.CommandSkip {
+				actions <- dashboard.SkipAction{}
+			}
+		}
+	}()
+
+	go handleTrackControlActions(actions, s.player, s.bus)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-ch:
+			if !ok {
+				return
+			}
+
+			if event, ok := event.(events.PlaybackStarted); ok {
+				if err := s.bot.Announce(ctx, event.Track); err != nil {
+					s.bus.Publish(events.Error{Err: err, Track: event.Track})
+				}
+			}
+		}
+	}
+}
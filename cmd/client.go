@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"crypto/tls"
+	"fmt"
+	"github.com/broar/chipmusic-cli/pkg/chipmusic"
+	"github.com/spf13/viper"
+	"os"
+	"path/filepath"
+)
+
+const (
+	// dataSaverWorkers is the number of concurrent requests used to download a track in data saver mode. Fewer workers
+	// keep a metered or tethered connection from being saturated
+	dataSaverWorkers = 2
+
+	// dataSaverPrefetch is the most tracks downloaded ahead in data saver mode, so little is wasted on tracks skipped
+	// before they play
+	dataSaverPrefetch = 1
+
+	// defaultCacheSizeMB is the default maximum size of the track cache in megabytes
+	defaultCacheSizeMB = 512
+
+	defaultCacheDirName = "cache"
+)
+
+// newClient creates a chipmusic client configured from flags and the config file. Any options are applied after the
+// configured ones
+func newClient(extra ...chipmusic.Option) (*chipmusic.Client, error) {
+	spoolDir := viper.GetString("spool-dir")
+	if spoolDir == "" {
+		spoolDir = os.TempDir()
+	}
+
+	options := []chipmusic.Option{
+		chipmusic.WithSpoolDir(spoolDir),
+		chipmusic.WithDialTimeout(viper.GetDuration("dial-timeout")),
+		chipmusic.WithTLSHandshakeTimeout(viper.GetDuration("tls-handshake-timeout")),
+		chipmusic.WithResponseHeaderTimeout(viper.GetDuration("response-header-timeout")),
+		chipmusic.WithChunkRetries(viper.GetInt("chunk-retries")),
+		chipmusic.WithDownloadDeadline(viper.GetDuration("download-deadline")),
+	}
+
+	// Artwork is never downloaded, so data saver mode has nothing to turn off for it
+	if viper.GetBool("data-saver") {
+		options = append(options, chipmusic.WithWorkers(dataSaverWorkers), chipmusic.WithPreferCache())
+	}
+
+	if viper.GetBool("stream") {
+		options = append(options, chipmusic.WithStreaming(chipmusic.DefaultStreamWindow))
+	}
+
+	if servers := viper.GetStringSlice("dns-servers"); len(servers) > 0 {
+		options = append(options, chipmusic.WithDNSServers(servers...))
+	}
+
+	if version := viper.GetString("ip-version"); version != "" {
+		options = append(options, chipmusic.WithIPVersion(chipmusic.IPVersion(version)))
+	}
+
+	tlsConfig, err := newTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	if tlsConfig != nil {
+		options = append(options, chipmusic.WithTLSConfig(tlsConfig))
+	}
+
+	if path := viper.GetString("debug-http"); path != "" {
+		// The log stays open for as long as the process since the client has no lifetime of its own
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open HTTP debug log %s: %w", path, err)
+		}
+
+		options = append(options, chipmusic.WithHTTPDebugLog(file, viper.GetBool("debug-http-bodies")))
+	}
+
+	if conns := viper.GetInt("max-conns-per-host"); conns > 0 {
+		options = append(options, chipmusic.WithMaxConnsPerHost(conns))
+	}
+
+	if rps := viper.GetFloat64("rate-limit"); rps > 0 {
+		options = append(options, chipmusic.WithRateLimit(rps), chipmusic.WithRateBurst(viper.GetInt("rate-burst")))
+	}
+
+	if size := viper.GetInt64("cache-size"); size > 0 {
+		dir, err := cacheDir()
+		if err != nil {
+			return nil, err
+		}
+
+		options = append(options, chipmusic.WithCache(dir, size*1024*1024))
+	}
+
+	terms := viper.GetStringSlice("blocklist")
+	if viper.GetBool("sfw") {
+		terms = append(terms, chipmusic.DefaultBlocklistTerms...)
+	}
+
+	if len(terms) > 0 {
+		options = append(options, chipmusic.WithBlocklist(chipmusic.NewBlocklist(terms)))
+	}
+
+	return chipmusic.NewClient(append(options, extra...)...)
+}
+
+// prefetchAhead returns how many tracks to download ahead of the next track, which data saver mode limits
+func prefetchAhead() int {
+	ahead := viper.GetInt("prefetch")
+	if viper.GetBool("data-saver") && ahead > dataSaverPrefetch {
+		return dataSaverPrefetch
+	}
+
+	return ahead
+}
+
+// newTLSConfig creates a TLS configuration from flags and the config file. If no TLS options are set, it returns nil so
+// the default configuration is used
+func newTLSConfig() (*tls.Config, error) {
+	caFile := viper.GetString("tls-ca-file")
+	minVersion := viper.GetString("tls-min-version")
+	insecure := viper.GetBool("tls-insecure-skip-verify")
+	if caFile == "" && minVersion == "" && !insecure {
+		return nil, nil
+	}
+
+	config := &tls.Config{InsecureSkipVerify: insecure}
+	if caFile != "" {
+		pool, err := chipmusic.LoadCABundle(caFile)
+		if err != nil {
+			return nil, err
+		}
+
+		config.RootCAs = pool
+	}
+
+	if minVersion != "" {
+		version, err := chipmusic.ParseTLSVersion(minVersion)
+		if err != nil {
+			return nil, err
+		}
+
+		config.MinVersion = version
+	}
+
+	return config, nil
+}
+
+// cacheDir returns the directory where tracks are cached. It defaults to a directory within the data directory
+func cacheDir() (string, error) {
+	if dir := viper.GetString("cache-dir"); dir != "" {
+		return dir, nil
+	}
+
+	data, err := dataDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve data directory: %w", err)
+	}
+
+	return filepath.Join(data, defaultCacheDirName), nil
+}
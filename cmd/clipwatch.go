@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/broar/chipmusic-cli/pkg/chipmusic"
+	"github.com/broar/chipmusic-cli/pkg/clipboard"
+	"github.com/broar/chipmusic-cli/pkg/player"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var clipWatchCmd = &cobra.Command{
+	Use:   "clip-watch",
+	Short: "Play tracks from chipmusic.org as their links are copied to the clipboard",
+	Long: `Play tracks from chipmusic.org as their links are copied to the clipboard.
+
+The clipboard is checked every --clip-interval. Each time it changes, the links to track pages in the copied text are
+queued in the order they appear. Links copied before watching starts are ignored. On Linux, the clipboard is read with
+wl-paste, xclip, or xsel, so one of them must be installed. Watching continues until the command is interrupted.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := watchClipboard(); err != nil {
+			panic(err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(clipWatchCmd)
+	clipWatchCmd.Flags().Duration("clip-interval", clipboard.DefaultInterval, "how often the clipboard is checked for new links")
+
+	if err := viper.BindPFlags(clipWatchCmd.Flags()); err != nil {
+		panic(fmt.Errorf("failed to bind flags: %w", err))
+	}
+}
+
+func watchClipboard() error {
+	// Checking the clipboard first reports a missing clipboard tool before the dashboard takes over the terminal
+	if _, err := clipboard.Read(); err != nil {
+		return fmt.Errorf("failed to read the clipboard: %w", err)
+	}
+
+	s, err := newSession()
+	if err != nil {
+		return err
+	}
+
+	defer s.close()
+
+	s.start()
+	s.dashboard.UpdateNotice("Copy the link of a track on chipmusic.org to play it")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for text := range clipboard.NewWatcher(viper.GetDuration("clip-interval")).Watch(ctx) {
+		for _, trackPageURL := range chipmusic.FindTrackPageURLs(text) {
+			s.queueTrackPage(trackPageURL, player.PriorityEnd, false)
+		}
+	}
+
+	return nil
+}
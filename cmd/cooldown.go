@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/broar/chipmusic-cli/pkg/chipmusic"
+	"github.com/broar/chipmusic-cli/pkg/shuffle"
+	"time"
+)
+
+// artistCooldown keeps an artist from playing again within the next tracks tracks or within window after it last
+// played during the session. The artists in exempt may play as often as they come up. If both tracks and window are 0,
+// artists are not cooled down
+func (s *session) artistCooldown(tracks int, window time.Duration, exempt ...string) error {
+	if tracks == 0 && window == 0 {
+		s.cooldown = nil
+		return nil
+	}
+
+	cooldown, err := shuffle.NewCooldown(tracks, window, exempt...)
+	if err != nil {
+		return fmt.Errorf("failed to create artist cooldown: %w", err)
+	}
+
+	s.cooldown = cooldown
+	return nil
+}
+
+// coolDown returns tracks in the order they should play so no artist plays again before it cooled down. Tracks whose
+// artist doesn't cool down among tracks are reported as skipped and left out
+func (s *session) coolDown(tracks []string) []string {
+	if s.cooldown == nil {
+		return tracks
+	}
+
+	allowed, held := s.cooldown.Filter(tracks, time.Now())
+	for _, trackURL := range held {
+		s.skip(nil, fmt.Errorf("skipped %s because its artist played too recently", trackURL))
+	}
+
+	return allowed
+}
+
+// recordCooldown records that track started playing at playedAt, so its artist starts cooling down
+func (s *session) recordCooldown(track *chipmusic.Track, playedAt time.Time) {
+	if s.cooldown != nil {
+		s.cooldown.Record(track.PageURL, playedAt)
+	}
+}
@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/broar/chipmusic-cli/pkg/integrations/control"
+	"github.com/broar/chipmusic-cli/pkg/output"
+	"github.com/spf13/cobra"
+	"os"
+	"strings"
+)
+
+var ctlCmd = &cobra.Command{
+	Use:   "ctl command...",
+	Short: "Send a command to a running daemon",
+	Long: `Send a command to a running daemon, e.g. from a script or a key binding of the window manager.
+
+Commands:
+
+	status          print the track playing
+	add URL         play a track after the queue
+	next URL        play a track right after the current track
+	play-now URL    play a track right away
+	pause           pause or resume playback
+	play            resume playback
+	skip            skip the current track
+	stop            stop playback and clear the queue
+	seek OFFSET     move the current track by an offset, e.g. 30s or -10s
+	jump N          play the Nth upcoming track
+	volume-up       raise the volume
+	volume-down     lower the volume
+	queue export    print the queue to paste into chat, see ctl queue export --help
+	quit            stop the daemon
+
+The other track controls of the dashboard, e.g. loop and crossfeed, are accepted as well. With --output=json, the reply
+of the daemon is printed as JSON, including the status for status.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		format, err := outputFormat(cmd)
+		if err != nil {
+			panic(err)
+		}
+
+		if err := sendCommand(strings.Join(args, " "), format); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	},
+	Args: cobra.MinimumNArgs(1),
+}
+
+var ctlQueueCmd = &cobra.Command{
+	Use:   "queue",
+	Short: "Inspect the queue of a running daemon",
+	Args:  cobra.NoArgs,
+}
+
+var ctlQueueExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Print the queue of a running daemon to paste into chat",
+	Long: `Print the queue of a running daemon as a numbered list of the titles, artists, and links of the tracks waiting to
+play, e.g. to paste into IRC or Discord. The list is plain text unless --format=markdown is given.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		exportFormat, _ := cmd.Flags().GetString("format")
+		format, err := outputFormat(cmd)
+		if err != nil {
+			panic(err)
+		}
+
+		if _, err := control.ParseExportFormat(exportFormat); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		if err := sendCommand(strings.TrimSpace(commandExport+" "+exportFormat), format); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	},
+	Args: cobra.NoArgs,
+}
+
+var queueCmd = &cobra.Command{
+	Use:   "queue track...",
+	Short: "Queue tracks with exact URLs from chipmusic.org in a running daemon",
+	Long: `Queue tracks with exact URLs from chipmusic.org in a running daemon.
+
+The tracks play after the tracks already queued, in the order they are given. With --next, they play right after the
+current track instead.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		next, _ := cmd.Flags().GetBool("next")
+		format, err := outputFormat(cmd)
+		if err != nil {
+			panic(err)
+		}
+
+		command := commandAdd
+		if next {
+			command = commandNext
+
+			// Queueing each track right after the current one would reverse their order
+			for i, j := 0, len(args)-1; i < j; i, j = i+1, j-1 {
+				args[i], args[j] = args[j], args[i]
+			}
+		}
+
+		failed := false
+		for _, trackPageURL := range args {
+			if err := sendCommand(command+" "+trackPageURL, format); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				failed = true
+			}
+		}
+
+		if failed {
+			os.Exit(1)
+		}
+	},
+	Args: cobra.MinimumNArgs(1),
+}
+
+func init() {
+	rootCmd.AddCommand(ctlCmd)
+	ctlCmd.AddCommand(ctlQueueCmd)
+	ctlQueueCmd.AddCommand(ctlQueueExportCmd)
+	ctlQueueExportCmd.Flags().String("format", string(control.ExportText), "format of the list. Allowed formats: [text, markdown]")
+	rootCmd.AddCommand(queueCmd)
+	queueCmd.Flags().Bool("next", false, "play the tracks right after the current track instead of after the queue")
+}
+
+// sendCommand sends command to the running daemon and prints its reply in format
+func sendCommand(command string, format output.Format) error {
+	path, err := controlSocketPath()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), control.DefaultTimeout)
+	defer cancel()
+
+	response, err := control.Send(ctx, path, command)
+	if format == output.FormatJSON && (err == nil || response.Error != "") {
+		if err := output.WriteJSON(os.Stdout, response); err != nil {
+			return err
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if format != output.FormatJSON {
+		fmt.Println(response.Message)
+	}
+
+	return nil
+}
@@ -0,0 +1,254 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/broar/chipmusic-cli/pkg/chipmusic"
+	"github.com/broar/chipmusic-cli/pkg/dashboard"
+	"github.com/broar/chipmusic-cli/pkg/events"
+	"github.com/broar/chipmusic-cli/pkg/integrations/control"
+	"github.com/broar/chipmusic-cli/pkg/integrations/status"
+	"github.com/broar/chipmusic-cli/pkg/player"
+	"github.com/gdamore/tcell/v2"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	// commandAdd, commandNext, commandStatus, commandExport, and commandQuit are the commands of the daemon besides the
+	// track controls, e.g. "add https://chipmusic.org/..." or "export markdown"
+	commandAdd    = "add"
+	commandNext   = "next"
+	commandStatus = "status"
+	commandExport = "export"
+	commandQuit   = "quit"
+)
+
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Keep a player running in the background which is controlled with ctl and queue",
+	Long: `Keep a player running in the background which is controlled with ctl and queue.
+
+The daemon starts with an empty queue and plays the tracks sent to it with queue, e.g.
+
+	chipmusic daemon &
+	chipmusic queue https://chipmusic.org/artist/music/track
+	chipmusic ctl pause
+
+Commands are sent over a unix socket in the data directory which only the user can connect to. The daemon has no
+dashboard, so it can run without a terminal, e.g. as a service. It runs until it is interrupted or sent ctl quit.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runDaemon(); err != nil {
+			panic(err)
+		}
+	},
+	Args: cobra.NoArgs,
+}
+
+func init() {
+	rootCmd.AddCommand(daemonCmd)
+}
+
+// controlSocketPath returns the path of the control socket, which is in the data directory unless configured
+func controlSocketPath() (string, error) {
+	if path := viper.GetString("control-socket"); path != "" {
+		return path, nil
+	}
+
+	dir, err := dataDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve data directory: %w", err)
+	}
+
+	return filepath.Join(dir, control.SocketName), nil
+}
+
+func runDaemon() error {
+	path, err := controlSocketPath()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create directory of control socket: %w", err)
+	}
+
+	// The dashboard is drawn to a screen nobody sees, so the session runs the same as with a terminal
+	s, err := newSession(dashboard.WithScreen(tcell.NewSimulationScreen("")))
+	if err != nil {
+		return err
+	}
+
+	defer s.close()
+
+	s.start()
+
+	d := newDaemon(s)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go d.watchCurrentTrack(ctx)
+
+	server, err := control.Listen(path, d.handle)
+	if err != nil {
+		return err
+	}
+
+	defer server.Close()
+
+	served := make(chan error, 1)
+	go func() { served <- server.Serve() }()
+
+	fmt.Printf("Listening for commands on %s\n", path)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	select {
+	case <-signals:
+	case <-d.quit:
+	case err := <-served:
+		return err
+	}
+
+	return nil
+}
+
+// daemon performs the commands sent to the control socket on a session
+type daemon struct {
+	session *session
+	actions chan dashboard.Action
+
+	// quit is closed once the daemon is asked to quit
+	quit     chan struct{}
+	quitOnce sync.Once
+
+	// current is the track playing, which the status command reports
+	mux     sync.Mutex
+	current *chipmusic.Track
+}
+
+func newDaemon(s *session) *daemon {
+	d := &daemon{session: s, actions: make(chan dashboard.Action), quit: make(chan struct{})}
+	s.closers = append(s.closers, func() { close(d.actions) })
+
+	go handleTrackControlActions(d.actions, s.player, s.bus)
+	return d
+}
+
+// watchCurrentTrack keeps track of the track playing until ctx is done
+func (d *daemon) watchCurrentTrack(ctx context.Context) {
+	ch, unsubscribe := d.session.bus.Subscribe(0, events.NamePlaybackStarted, events.NamePlaybackFinished)
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-ch:
+			if !ok {
+				return
+			}
+
+			switch event := event.(type) {
+			case events.PlaybackStarted:
+				d.setCurrent(event.Track)
+			case events.PlaybackFinished:
+				d.setCurrent(nil)
+			}
+		}
+	}
+}
+
+func (d *daemon) setCurrent(track *chipmusic.Track) {
+	d.mux.Lock()
+	d.current = track
+	d.mux.Unlock()
+}
+
+// handle performs a command sent to the control socket. Besides add, next, status, export, and quit, every action of the
+// dashboard is accepted as it is written in recorded sessions, e.g. "skip" or "seek 30s"
+func (d *daemon) handle(ctx context.Context, command string) control.Response {
+	name, argument := command, ""
+	if i := strings.Index(command, " "); i >= 0 {
+		name, argument = command[:i], strings.TrimSpace(command[i+1:])
+	}
+
+	switch name {
+	case commandAdd:
+		return d.queue(argument, player.PriorityEnd, false)
+	case commandNext:
+		return d.queue(argument, player.PriorityNext, false)
+	case commandStatus:
+		d.mux.Lock()
+		current := status.NewStatus(d.current, d.session.player.Paused(), time.Now())
+		d.mux.Unlock()
+
+		return control.Response{OK: true, Message: formatStatus(current), Status: &current}
+	case commandExport:
+		format, err := control.ParseExportFormat(argument)
+		if err != nil {
+			return control.Response{Error: err.Error()}
+		}
+
+		queue, _ := d.session.sessionTracks(d.session.player.Queue(), nil)
+		return control.Response{OK: true, Message: control.ExportQueue(queue, format)}
+	case commandQuit:
+		d.quitOnce.Do(func() { close(d.quit) })
+		return control.Response{OK: true, Message: "Quitting"}
+	}
+
+	action, err := dashboard.ParseAction(command)
+	if err != nil {
+		return control.Response{Error: err.Error()}
+	}
+
+	switch action := action.(type) {
+	case dashboard.EnqueueAction:
+		return d.queue(action.URL, player.PriorityEnd, false)
+	case dashboard.PlayNextAction:
+		return d.queue(action.URL, player.PriorityNext, false)
+	case dashboard.PlayNowAction:
+		return d.queue(action.URL, player.PriorityNext, true)
+	case dashboard.SearchAction, dashboard.SearchMoreAction:
+		return control.Response{Error: fmt.Sprintf("%s is only supported by the dashboard", name)}
+	}
+
+	select {
+	case d.actions <- action:
+	case <-ctx.Done():
+		return control.Response{Error: "timed out waiting for the player"}
+	}
+
+	return control.Response{OK: true, Message: fmt.Sprintf("Performed %s", action)}
+}
+
+// queue downloads the track at trackPageURL and queues it with priority, or plays it right away if now is true
+func (d *daemon) queue(trackPageURL string, priority player.Priority, now bool) control.Response {
+	if trackPageURL == "" {
+		return control.Response{Error: "missing URL of the track page"}
+	}
+
+	track, err := d.session.queueTrackPage(trackPageURL, priority, now)
+	if err != nil {
+		return control.Response{Error: err.Error()}
+	}
+
+	message := fmt.Sprintf("Queued %s by %s", track.Title, track.Artist)
+	switch {
+	case now:
+		message = fmt.Sprintf("Playing %s by %s", track.Title, track.Artist)
+	case priority == player.PriorityNext:
+		message = fmt.Sprintf("Playing %s by %s next", track.Title, track.Artist)
+	}
+
+	return control.Response{OK: true, Message: message}
+}
@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/broar/chipmusic-cli/pkg/chipmusic"
+	"github.com/broar/chipmusic-cli/pkg/fingerprint"
+	"github.com/broar/chipmusic-cli/pkg/player"
+	"io"
+)
+
+// fingerprintTrack decodes the whole audio of a track to compute its fingerprint and then rewinds the Reader of the
+// track so it can be played from the start
+func fingerprintTrack(track *chipmusic.Track) (fingerprint.Fingerprint, error) {
+	// Decoding closes the reader along with the stream, which must not happen before the track is played
+	decoded := *track
+	decoded.Reader = &chipmusic.ReadSeekNopCloser{Reader: track.Reader}
+
+	stream, format, err := player.Decode(&decoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode track audio: %w", err)
+	}
+
+	defer stream.Close()
+
+	fp, err := fingerprint.Compute(stream, format)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute fingerprint: %w", err)
+	}
+
+	if _, err := track.Reader.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to rewind track: %w", err)
+	}
+
+	return fp, nil
+}
+
+// duplicateOf returns the URL of a track played earlier in the session with the same audio as track. If duplicate
+// detection is disabled, the track cannot be fingerprinted, or there is no such track, an empty string is returned
+func (s *session) duplicateOf(track *chipmusic.Track) string {
+	if s.fingerprints == nil {
+		return ""
+	}
+
+	fp, err := fingerprintTrack(track)
+	if err != nil {
+		return ""
+	}
+
+	duplicate, _ := s.fingerprints.Add(track.PageURL, fp)
+	return duplicate
+}
@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/broar/chipmusic-cli/pkg/events"
+	"github.com/broar/chipmusic-cli/pkg/player"
+)
+
+// followDevice moves playback to the default audio device each time it changes without restarting the current track.
+// It returns once ctx is done
+func (s *session) followDevice(ctx context.Context) {
+	if _, err := player.DefaultDevice(); err != nil {
+		s.bus.Publish(events.Error{Err: fmt.Errorf("failed to follow the default audio device: %w", err)})
+		return
+	}
+
+	for device := range player.NewDeviceDetector(player.DefaultDeviceInterval).Watch(ctx) {
+		if err := s.player.Reinit(); err != nil {
+			s.bus.Publish(events.Error{Err: fmt.Errorf("failed to move playback to %s: %w", device, err)})
+			continue
+		}
+
+		s.bus.Publish(events.AudioDeviceChanged{Device: device})
+	}
+}
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"github.com/broar/chipmusic-cli/pkg/chipmusic"
+	"github.com/broar/chipmusic-cli/pkg/library"
+	"github.com/spf13/viper"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// diskSpaceReserve is how many bytes are left free on top of the tracks downloaded, so the disk isn't filled to the
+// brim and the estimated sizes have some slack
+const diskSpaceReserve = 64 * 1024 * 1024
+
+// diskSpaceError is returned when there isn't enough free space on a disk for the tracks about to be downloaded
+type diskSpaceError struct {
+	dir    string
+	needed int64
+	free   int64
+}
+
+func (e *diskSpaceError) Error() string {
+	return fmt.Sprintf("not enough free disk space in %s: ~%s needed, %s free", e.dir, formatSize(e.needed), formatSize(e.free))
+}
+
+// checkDiskSpace returns a *diskSpaceError if the disk holding dir or the track cache doesn't have room for tracks.
+// Downloaded tracks are cached too until the cache is full, and the cache is usually on the same disk, so its share is
+// counted against both disks. Tracks of unknown size count as 0 bytes. If the free space can't be found on this
+// system, nothing is checked
+func checkDiskSpace(tracks []*chipmusic.Track, dir string) error {
+	var total int64
+	for _, track := range tracks {
+		total += track.Size
+	}
+
+	cached := int64(0)
+	cache := ""
+	if quota := viper.GetInt64("cache-size") * 1024 * 1024; quota > 0 {
+		var err error
+		cache, err = cacheDir()
+		if err != nil {
+			return err
+		}
+
+		cached = total
+		if cached > quota {
+			cached = quota
+		}
+	}
+
+	if err := checkFreeSpace(dir, total+cached); err != nil || cache == "" {
+		return err
+	}
+
+	return checkFreeSpace(cache, cached)
+}
+
+// checkFreeSpace returns a *diskSpaceError if the disk holding dir has less than needed bytes free, along with the
+// reserve. dir doesn't have to exist yet
+func checkFreeSpace(dir string, needed int64) error {
+	// The directory is only created once the first track is saved, so the closest directory that exists is checked
+	for {
+		if _, err := os.Stat(dir); err == nil || filepath.Dir(dir) == dir {
+			break
+		}
+
+		dir = filepath.Dir(dir)
+	}
+
+	free, err := library.FreeSpace(dir)
+	if errors.Is(err, library.ErrFreeSpaceUnsupported) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if free < needed+diskSpaceReserve {
+		return &diskSpaceError{dir: dir, needed: needed, free: free}
+	}
+
+	return nil
+}
+
+// confirm asks the user question on the terminal and returns true if they answer yes. The question is printed to
+// standard error, which keeps standard output clean for scripts. If standard input isn't a terminal, nobody can answer,
+// so false is returned
+func confirm(question string) bool {
+	info, err := os.Stdin.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+
+	fmt.Fprintf(os.Stderr, "%s [y/N] ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/broar/chipmusic-cli/pkg/chipmusic"
+	"github.com/broar/chipmusic-cli/pkg/player"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// doctorLookupTimeout is how long each host may take to resolve before its check fails
+	doctorLookupTimeout = 10 * time.Second
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Print capabilities and check the local environment for problems",
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := doctor(); err != nil {
+			panic(err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+	doctorCmd.Flags().Bool("skip-audio", false, "Don't play the startup jingle to check audio output")
+
+	if err := viper.BindPFlags(doctorCmd.Flags()); err != nil {
+		panic(fmt.Errorf("failed to bind flags: %w", err))
+	}
+}
+
+func doctor() error {
+	fmt.Printf("Recognized file types: %s\n", joinFileTypes(chipmusic.SupportedFileTypes()))
+	fmt.Printf("Playable formats: %s\n", joinFileTypes(player.SupportedFormats()))
+
+	dir, err := dataDir()
+	if err != nil {
+		return fmt.Errorf("failed to resolve data directory: %w", err)
+	}
+
+	fmt.Printf("Data directory: %s\n", dir)
+
+	if err := checkHosts(); err != nil {
+		return err
+	}
+
+	if !viper.GetBool("skip-audio") {
+		checkAudio()
+	}
+
+	checkPriority()
+
+	s, err := openStore()
+	if err != nil {
+		fmt.Printf("Store: FAIL (%v)\n", err)
+		return nil
+	}
+
+	defer s.Close()
+	fmt.Println("Store: OK")
+	return nil
+}
+
+// checkHosts resolves the hosts tracks are played from the same way the client does, so problems with the configured
+// DNS servers or IP version show up before playback fails
+func checkHosts() error {
+	client, err := newClient()
+	if err != nil {
+		fmt.Printf("Client: FAIL (%v)\n", err)
+		return nil
+	}
+
+	base, err := url.Parse(chipmusic.DefaultBaseURL)
+	if err != nil {
+		return fmt.Errorf("failed to parse base URL: %w", err)
+	}
+
+	for _, host := range []string{base.Hostname(), chipmusic.DefaultAudioHost} {
+		ctx, cancel := context.WithTimeout(context.Background(), doctorLookupTimeout)
+		addresses, err := client.LookupHost(ctx, host)
+		cancel()
+		if err != nil {
+			fmt.Printf("DNS %s: FAIL (%v)\n", host, err)
+			continue
+		}
+
+		fmt.Printf("DNS %s: OK (%s)\n", host, strings.Join(addresses, ", "))
+	}
+
+	return nil
+}
+
+// checkAudio plays the startup jingle through the same output pipeline as tracks, so a missing or stuck audio device
+// shows up before playback fails
+func checkAudio() {
+	tp, err := player.NewTrackPlayer()
+	if err != nil {
+		fmt.Printf("Audio output: FAIL (%v)\n", err)
+		return
+	}
+
+	defer tp.Close()
+
+	if err := playJingle(tp, player.StartupJingle); err != nil {
+		fmt.Printf("Audio output: FAIL (%v)\n", err)
+		return
+	}
+
+	fmt.Println("Audio output: OK (played the startup jingle)")
+}
+
+// checkPriority raises the priority of a throwaway thread the same way --realtime-audio raises the priority of the
+// audio thread, so missing privileges show up before they cause underruns
+func checkPriority() {
+	type result struct {
+		priority player.ThreadPriority
+		err      error
+	}
+
+	// The goroutine exits while locked to its thread, so the thread is thrown away along with its raised priority
+	results := make(chan result)
+	go func() {
+		priority, err := player.RaisePriority()
+		results <- result{priority: priority, err: err}
+	}()
+
+	res := <-results
+	switch {
+	case errors.Is(res.err, player.ErrPriorityUnsupported):
+		fmt.Println("Audio priority: UNSUPPORTED (--realtime-audio has no effect on this system)")
+	case res.err != nil:
+		fmt.Printf("Audio priority: FAIL (%v. On Linux, allow it with CAP_SYS_NICE or by raising rtprio or nice in /etc/security/limits.conf)\n", res.err)
+	case res.priority == player.ThreadPriorityHigh:
+		fmt.Println("Audio priority: OK (high, raise rtprio in /etc/security/limits.conf for real-time scheduling)")
+	default:
+		fmt.Printf("Audio priority: OK (%s)\n", res.priority)
+	}
+}
+
+func joinFileTypes(fileTypes []chipmusic.AudioFileType) string {
+	names := make([]string, 0, len(fileTypes))
+	for _, fileType := range fileTypes {
+		names = append(names, string(fileType))
+	}
+
+	return strings.Join(names, ", ")
+}
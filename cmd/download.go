@@ -0,0 +1,309 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/broar/chipmusic-cli/pkg/chipmusic"
+	"github.com/broar/chipmusic-cli/pkg/chipmusic/tags"
+	"github.com/broar/chipmusic-cli/pkg/output"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	defaultFilenameTemplate = "{artist} - {title}.{ext}"
+	defaultDownloadLimit    = 10
+)
+
+var downloadCmd = &cobra.Command{
+	Use:   "download track-url|search",
+	Short: "Save a track or the tracks found by a search to disk",
+	Long: `Save a track or the tracks found by a search to disk.
+
+If the argument is the URL of a track page on chipmusic.org, that track is saved. Otherwise the argument is searched
+for and up to --limit tracks are saved. Files are named with --template, where {artist}, {title}, and {ext} are
+replaced by the artist, title, and file type of the track. Slashes in the template create subdirectories. Files which
+already exist are not downloaded again. Saved MP3s are tagged with the title, artist, tags, and URL of the track
+unless --no-tags is given.
+
+Before downloading, the estimated size of the tracks is checked against the free space of the disk they are saved to
+and of the disk of the track cache. If there isn't enough room, you are asked whether to download them anyway, or the
+download is aborted if nobody can answer. --skip-space-check turns the check off.
+
+With --output=json, the tracks saved are printed as a JSON array once every track is saved, and the progress is
+printed to standard error instead.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		dir, _ := cmd.Flags().GetString("output-dir")
+		template, _ := cmd.Flags().GetString("template")
+		limit, _ := cmd.Flags().GetInt("limit")
+		noTags, _ := cmd.Flags().GetBool("no-tags")
+		skipSpaceCheck, _ := cmd.Flags().GetBool("skip-space-check")
+		format, err := outputFormat(cmd)
+		if err != nil {
+			panic(err)
+		}
+
+		if err := downloadTracks(args[0], dir, template, limit, !noTags, !skipSpaceCheck, format); err != nil {
+			panic(err)
+		}
+	},
+	Args: cobra.ExactArgs(1),
+}
+
+func init() {
+	rootCmd.AddCommand(downloadCmd)
+	downloadCmd.Flags().String("output-dir", "", "directory where tracks are saved (default is the library directory in the data directory)")
+	downloadCmd.Flags().String("template", defaultFilenameTemplate, "template for the names of saved files. Placeholders: [{artist}, {title}, {ext}]")
+	downloadCmd.Flags().Int("limit", defaultDownloadLimit, "maximum number of tracks to save from a search")
+	downloadCmd.Flags().Bool("no-tags", false, "save MP3s without writing ID3 tags")
+	downloadCmd.Flags().Bool("skip-space-check", false, "download without checking that the disk has room for the tracks")
+}
+
+func downloadTracks(trackURLOrSearch, dir, template string, limit int, tagged, checkSpace bool, format output.Format) error {
+	// Standard output only gets the JSON, so scripts don't have to tell it apart from the progress
+	var progress io.Writer = os.Stdout
+	if format == output.FormatJSON {
+		progress = os.Stderr
+	}
+
+	if limit <= 0 {
+		return errors.New("limit must be a positive integer")
+	}
+
+	dir, err := libraryDir(dir)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	client, err := newClient(chipmusic.WithProbing())
+	if err != nil {
+		return fmt.Errorf("failed to create chipmusic client: %w", err)
+	}
+
+	trackURLs := []string{trackURLOrSearch}
+	if !strings.HasPrefix(trackURLOrSearch, chipmusic.DefaultBaseURL) {
+		trackURLs, err = searchTrackURLs(client, trackURLOrSearch, limit)
+		if err != nil {
+			return err
+		}
+	}
+
+	var downloads []output.Download
+	if len(trackURLs) == 0 {
+		fmt.Fprintln(progress, "No tracks found")
+		return printDownloads(downloads, format)
+	}
+
+	tracks, err := resolveDownloads(client, trackURLs, dir, template, progress)
+	if err != nil {
+		return err
+	}
+
+	limitBytes := maxTotalSize(viper.GetInt64("max-total-size"))
+	if within := withinTotalSize(tracks, limitBytes); len(within) < len(tracks) {
+		fmt.Fprintf(progress, "Leaving out %d of %d tracks which would add up to more than %s\n", len(tracks)-len(within), len(tracks), formatSize(limitBytes))
+		tracks = within
+	}
+
+	if len(tracks) == 0 {
+		return printDownloads(downloads, format)
+	}
+
+	if checkSpace {
+		var spaceErr *diskSpaceError
+		err := checkDiskSpace(tracks, dir)
+		if errors.As(err, &spaceErr) && confirm(fmt.Sprintf("%v. Download anyway?", spaceErr)) {
+			err = nil
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(progress, "Downloading %s\n", formatTracksEstimate(tracks))
+	for _, track := range tracks {
+		path, err := downloadTrack(client, track, dir, template, tagged)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(progress, "Saved %s\n", path)
+		downloads = append(downloads, output.Download{Track: output.NewTrack(track), Path: path})
+	}
+
+	return printDownloads(downloads, format)
+}
+
+// printDownloads prints the tracks saved as JSON if format is FormatJSON. Otherwise they were already reported as they
+// were saved, so nothing is printed
+func printDownloads(downloads []output.Download, format output.Format) error {
+	if format != output.FormatJSON {
+		return nil
+	}
+
+	if downloads == nil {
+		downloads = []output.Download{}
+	}
+
+	if err := output.WriteJSON(os.Stdout, downloads); err != nil {
+		return fmt.Errorf("failed to write downloads: %w", err)
+	}
+
+	return nil
+}
+
+// resolveDownloads gets the info of the tracks at trackURLs, which includes their estimated size and duration. Tracks
+// which match the blocklist or were already saved in dir are reported to progress and left out
+func resolveDownloads(client *chipmusic.Client, trackURLs []string, dir, template string, progress io.Writer) ([]*chipmusic.Track, error) {
+	var tracks []*chipmusic.Track
+	for _, trackURL := range trackURLs {
+		ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+		track, err := client.GetTrackInfo(ctx, trackURL)
+		cancel()
+
+		if errors.Is(err, chipmusic.ErrBlockedTrack) {
+			fmt.Fprintf(progress, "Skipped %s because it matches the blocklist\n", trackURL)
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to get track info: %w", err)
+		}
+
+		path := filepath.Join(dir, trackFilename(template, track))
+		if _, err := os.Stat(path); err == nil {
+			fmt.Fprintf(progress, "Skipped %s because %s already exists\n", trackURL, path)
+			continue
+		}
+
+		tracks = append(tracks, track)
+	}
+
+	return tracks, nil
+}
+
+// searchTrackURLs returns the URLs of up to limit tracks found by searching for search
+func searchTrackURLs(client *chipmusic.Client, search string, limit int) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	trackURLs := make([]string, 0, limit)
+	it := client.SearchIterator(ctx, chipmusic.SearchOptions{Query: search, Filter: chipmusic.TrackFilterLatest})
+	for len(trackURLs) < limit && it.Next() {
+		for _, result := range it.Results() {
+			if len(trackURLs) == limit {
+				break
+			}
+
+			trackURLs = append(trackURLs, result.URL)
+		}
+	}
+
+	if err := it.Err(); err != nil {
+		return nil, fmt.Errorf("failed to search for tracks: %w", err)
+	}
+
+	return trackURLs, nil
+}
+
+// downloadTrack downloads a track returned by GetTrackInfo, saves it in dir, and returns the path of the file. If tagged
+// is true, MP3s are tagged with the metadata of the track
+func downloadTrack(client *chipmusic.Client, info *chipmusic.Track, dir, template string, tagged bool) (string, error) {
+	path := filepath.Join(dir, trackFilename(template, info))
+	if err := saveTrack(client, info, path, tagged); err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
+
+// saveTrack downloads a track returned by GetTrackInfo and saves it to path, replacing any file already there. If
+// tagged is true, MP3s are tagged with the metadata of the track
+func saveTrack(client *chipmusic.Client, info *chipmusic.Track, path string, tagged bool) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	if err := client.DownloadTrack(ctx, info); err != nil {
+		return fmt.Errorf("failed to download track: %w", err)
+	}
+
+	defer info.Close()
+
+	// Templates may place tracks in subdirectories, e.g. {artist}/{title}.{ext}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", path, err)
+	}
+
+	// Writing to a temporary file first keeps a failed download from leaving a partial file behind
+	file, err := ioutil.TempFile(filepath.Dir(path), ".chipmusic-*.download")
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+
+	defer os.Remove(file.Name())
+
+	_, err = io.Copy(file, info.Reader)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	if tagged && info.FileType == chipmusic.AudioFileTypeMP3 {
+		if err := tagTrack(file.Name(), info); err != nil {
+			return fmt.Errorf("failed to tag %s: %w", path, err)
+		}
+	}
+
+	if err := os.Rename(file.Name(), path); err != nil {
+		return fmt.Errorf("failed to save %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// tagTrack writes the metadata of track to the ID3 tag of the MP3 at path. Frames already in the file, e.g. cover art,
+// are kept
+func tagTrack(path string, track *chipmusic.Track) error {
+	current, err := tags.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	return tags.WriteFile(path, current.Merge(tags.FromTrack(track)))
+}
+
+// trackFilename returns the name of the file a track is saved to by filling in the placeholders of template
+func trackFilename(template string, track *chipmusic.Track) string {
+	replacer := strings.NewReplacer(
+		"{artist}", sanitizeFilename(track.Artist),
+		"{title}", sanitizeFilename(track.Title),
+		"{ext}", sanitizeFilename(string(track.FileType)),
+	)
+
+	return replacer.Replace(template)
+}
+
+// sanitizeFilename replaces characters which aren't allowed in file names on common file systems
+func sanitizeFilename(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r < ' ' || strings.ContainsRune(`<>:"/\|?*`, r) {
+			return '_'
+		}
+
+		return r
+	}, name)
+
+	return strings.Trim(name, " .")
+}
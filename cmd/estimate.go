@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/broar/chipmusic-cli/pkg/chipmusic"
+	"time"
+)
+
+// errMaxTotalSize is returned when the next track would take the tracks downloaded over --max-total-size
+var errMaxTotalSize = errors.New("maximum total size reached")
+
+// maxTotalSize returns the most bytes --max-total-size allows to be downloaded, or 0 if there is no limit
+func maxTotalSize(megabytes int64) int64 {
+	if megabytes <= 0 {
+		return 0
+	}
+
+	return megabytes * 1024 * 1024
+}
+
+// withinTotalSize returns the leading tracks whose sizes add up to at most limit bytes. Tracks of unknown size count as
+// 0 bytes. If limit is 0, every track is returned
+func withinTotalSize(tracks []*chipmusic.Track, limit int64) []*chipmusic.Track {
+	if limit <= 0 {
+		return tracks
+	}
+
+	var total int64
+	for i, track := range tracks {
+		total += track.Size
+		if total > limit {
+			return tracks[:i]
+		}
+	}
+
+	return tracks
+}
+
+// formatTracksEstimate summarizes the number of tracks and their estimated total size and duration, e.g.
+// "12 tracks, ~84.0 MB, ~1h02m". Tracks of unknown size or duration are left out of the totals
+func formatTracksEstimate(tracks []*chipmusic.Track) string {
+	var size int64
+	var duration time.Duration
+	for _, track := range tracks {
+		size += track.Size
+		duration += track.Duration
+	}
+
+	noun := "tracks"
+	if len(tracks) == 1 {
+		noun = "track"
+	}
+
+	return fmt.Sprintf("%d %s, ~%s, ~%s", len(tracks), noun, formatSize(size), formatLength(duration))
+}
+
+// formatSize formats a number of bytes in megabytes, or "-" if it is unknown
+func formatSize(size int64) string {
+	if size <= 0 {
+		return "-"
+	}
+
+	return fmt.Sprintf("%.1f MB", float64(size)/(1024*1024))
+}
+
+// formatEstimate formats an estimated duration to the second, or "-" if it is unknown
+func formatEstimate(duration time.Duration) string {
+	if duration <= 0 {
+		return "-"
+	}
+
+	return duration.Round(time.Second).String()
+}
+
+// formatLength formats a long duration in hours and minutes, or minutes and seconds if it is under an hour, e.g. 1h02m
+func formatLength(duration time.Duration) string {
+	duration = duration.Round(time.Second)
+	if duration >= time.Hour {
+		return fmt.Sprintf("%dh%02dm", int(duration.Hours()), int(duration.Minutes())%60)
+	}
+
+	return fmt.Sprintf("%dm%02ds", int(duration.Minutes()), int(duration.Seconds())%60)
+}
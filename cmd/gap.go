@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/broar/chipmusic-cli/pkg/events"
+	"github.com/broar/chipmusic-cli/pkg/player"
+	"time"
+)
+
+// fillGaps fills the gaps between the tracks of the session with silence lasting gap, and with a station ident from
+// identDir every identEvery tracks. If gap is 0 and identDir is empty, tracks play back to back
+func (s *session) fillGaps(gap time.Duration, identDir string, identEvery int) error {
+	if gap < 0 {
+		return errors.New("gap between tracks cannot be negative")
+	}
+
+	if identEvery < 1 {
+		return errors.New("idents must play at least every track")
+	}
+
+	s.gap = gap
+	s.identEvery = identEvery
+	if identDir == "" {
+		return nil
+	}
+
+	var err error
+	s.idents, err = player.LoadIdents(identDir, newRandom(time.Now()))
+	return err
+}
+
+// fillGap plays a station ident if one is due or else waits out the gap before the next track. Nothing happens before
+// the first track, or between tracks played back to back. Idents which fail to play are reported and replaced by the gap
+func (s *session) fillGap() {
+	identDue := s.idents != nil && s.played%s.identEvery == 0
+	if s.played == 0 || (!identDue && s.gap <= 0) {
+		return
+	}
+
+	// The gap comes after the current track, so it has to finish first instead of being followed by the next track
+	s.wait()
+	if identDue {
+		err := s.playIdent()
+		if err == nil {
+			return
+		}
+
+		s.bus.Publish(events.Error{Err: err})
+	}
+
+	if s.gap > 0 {
+		time.Sleep(s.gap)
+	}
+}
+
+// playIdent plays a random station ident and blocks until it is done playing. Idents aren't announced or recorded in
+// the listening history since they aren't tracks
+func (s *session) playIdent() error {
+	ident, err := s.idents.Next()
+	if err != nil {
+		return err
+	}
+
+	if err := s.player.Play(ident); err != nil {
+		ident.Close()
+		return fmt.Errorf("failed to play ident %s: %w", ident.Title, err)
+	}
+
+	<-s.player.Done()
+	return nil
+}
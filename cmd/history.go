@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/broar/chipmusic-cli/pkg/output"
+	"github.com/broar/chipmusic-cli/pkg/store"
+	"github.com/spf13/cobra"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	// historyTimeLayout is how the time each track started playing is shown in the table of the listening history
+	historyTimeLayout = "2006-01-02 15:04"
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "List the tracks you listened to",
+	Long: `List the tracks you listened to, most recently played first.
+
+Every track played is recorded in the store in the data directory along with when it started playing and how long it
+was listened to. The tracks listed can be narrowed down by artist, by text in their title or artist, by how recently
+they were played, and by how long they were listened to. They are printed as a table, or as a JSON array with
+--output=json. With --replay, the tracks listed are played again in the order they were first played instead.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		artist, _ := cmd.Flags().GetString("artist")
+		query, _ := cmd.Flags().GetString("search")
+		since, _ := cmd.Flags().GetDuration("since")
+		minListened, _ := cmd.Flags().GetDuration("min-listened")
+		limit, _ := cmd.Flags().GetInt("limit")
+		replay, _ := cmd.Flags().GetBool("replay")
+
+		filter := store.HistoryFilter{Artist: artistName(artist), Query: query, MinListened: minListened}
+		if since > 0 {
+			filter.Since = time.Now().Add(-since)
+		}
+
+		if replay {
+			if err := replayHistory(filter, limit); err != nil {
+				panic(err)
+			}
+
+			return
+		}
+
+		format, err := outputFormat(cmd)
+		if err != nil {
+			panic(err)
+		}
+
+		if err := printHistory(filter, limit, format); err != nil {
+			panic(err)
+		}
+	},
+	Args: cobra.NoArgs,
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+	historyCmd.Flags().String("artist", "", "only list tracks by this artist, given as a name or the URL of their profile page")
+	historyCmd.Flags().String("search", "", "only list tracks whose title or artist contains this text")
+	historyCmd.Flags().Duration("since", 0, "only list tracks played within this long, e.g. 24h. Use 0 to list tracks played at any time")
+	historyCmd.Flags().Duration("min-listened", 0, "only list tracks listened to for at least this long, e.g. 1m to leave out tracks skipped early")
+	historyCmd.Flags().Int("limit", 20, "list at most this many of the most recently played tracks. Use 0 to list every track")
+	historyCmd.Flags().Bool("replay", false, "play the tracks listed again instead of listing them")
+	historyCmd.Flags().Bool("json", false, "print the tracks as JSON instead of a table, like --output=json")
+}
+
+// filteredHistory returns up to limit tracks in the listening history selected by filter, most recently played first
+func filteredHistory(s store.Store, filter store.HistoryFilter, limit int) ([]store.HistoryEntry, error) {
+	history, err := s.History(0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get listening history: %w", err)
+	}
+
+	return store.FilterHistory(history, filter, limit), nil
+}
+
+func printHistory(filter store.HistoryFilter, limit int, format output.Format) error {
+	s, err := openStore()
+	if err != nil {
+		return err
+	}
+
+	defer s.Close()
+
+	entries, err := filteredHistory(s, filter, limit)
+	if err != nil {
+		return err
+	}
+
+	if format == output.FormatJSON {
+		if err := output.WriteJSON(os.Stdout, output.NewHistoryEntries(entries)); err != nil {
+			return fmt.Errorf("failed to write listening history: %w", err)
+		}
+
+		return nil
+	}
+
+	if len(entries) == 0 {
+		fmt.Println("No tracks found")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PLAYED\tLISTENED\tTITLE\tARTIST\tURL")
+	for _, entry := range entries {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", entry.PlayedAt.Local().Format(historyTimeLayout), formatLength(entry.Listened),
+			entry.Title, entry.Artist, entry.URL)
+	}
+
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write listening history: %w", err)
+	}
+
+	return nil
+}
+
+// replayHistory plays the tracks in the listening history selected by filter again, in the order they were first
+// played. Tracks played several times are only played once
+func replayHistory(filter store.HistoryFilter, limit int) error {
+	s, err := newSession()
+	if err != nil {
+		return err
+	}
+
+	defer s.close()
+
+	entries, err := filteredHistory(s.store, filter, limit)
+	if err != nil {
+		return err
+	}
+
+	tracks := make([]string, 0, len(entries))
+	replayed := map[string]bool{}
+	for i := len(entries) - 1; i >= 0; i-- {
+		if !replayed[entries[i].URL] {
+			replayed[entries[i].URL] = true
+			tracks = append(tracks, entries[i].URL)
+		}
+	}
+
+	if len(tracks) == 0 {
+		return errors.New("no tracks in the listening history match")
+	}
+
+	s.start()
+
+	if err := playTracks(tracks, s); err != nil && !errors.Is(err, errMaxTotalSize) {
+		return fmt.Errorf("failed to play tracks: %w", err)
+	}
+
+	s.wait()
+	return nil
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"context"
+	"github.com/broar/chipmusic-cli/pkg/events"
+	"github.com/broar/chipmusic-cli/pkg/integrations/hooks"
+	"github.com/spf13/viper"
+)
+
+// newHooks creates the hooks configured in the hooks section of the config file, e.g.
+//
+//	hooks:
+//	  on_track_start: notify-send "$CHIPMUSIC_TITLE" "$CHIPMUSIC_ARTIST"
+//
+// If no hook is configured, nil is returned
+func newHooks() (*hooks.Hooks, error) {
+	options := []hooks.Option{hooks.WithTimeout(viper.GetDuration("hook-timeout"))}
+	for name, command := range viper.GetStringMapString("hooks") {
+		options = append(options, hooks.WithHook(name, command))
+	}
+
+	h, err := hooks.NewHooks(options...)
+	if err != nil || h.Empty() {
+		return nil, err
+	}
+
+	return h, nil
+}
+
+// runHooks runs the hooks for every track started and finished and every error until ctx is done. Hooks run in the
+// background so a slow script never holds up playback, and hooks which fail are reported as errors
+func (s *session) runHooks(ctx context.Context) {
+	ch, unsubscribe := s.bus.Subscribe(0, events.NamePlaybackStarted, events.NamePlaybackFinished, events.NameError)
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-ch:
+			if !ok {
+				return
+			}
+
+			var run func() error
+			switch event := event.(type) {
+			case events.PlaybackStarted:
+				run = func() error { return s.hooks.TrackStarted(ctx, event.Track) }
+			case events.PlaybackFinished:
+				run = func() error { return s.hooks.TrackFinished(ctx, event.Track, event.Listened) }
+			case events.Error:
+				run = func() error { return s.hooks.Error(ctx, event.Err, event.Track) }
+			default:
+				continue
+			}
+
+			go func() {
+				if err := run(); err != nil {
+					s.bus.Publish(events.Error{Err: err})
+				}
+			}()
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"context"
+	"github.com/broar/chipmusic-cli/pkg/events"
+	"github.com/broar/chipmusic-cli/pkg/idle"
+	"time"
+)
+
+// pauseWhenIdle pauses playback once nobody has used a track control for timeout and the system reports the user away,
+// i.e. the screen is locked or the keyboard and mouse weren't used for timeout. If resume is true, playback resumes
+// when the user comes back, unless they took over in the meantime. It returns once ctx is done
+func (s *session) pauseWhenIdle(ctx context.Context, timeout time.Duration, resume bool) {
+	ch, unsubscribe := s.bus.Subscribe(0, events.NameActionPerformed)
+	defer unsubscribe()
+
+	changes := idle.NewMonitor(idle.DefaultInterval, timeout).Watch(ctx)
+
+	ticker := time.NewTicker(idle.DefaultInterval)
+	defer ticker.Stop()
+
+	lastAction := time.Now()
+	away, paused := false, false
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-ch:
+			if !ok {
+				return
+			}
+
+			// The user used a track control, so they decide whether to play from now on
+			if _, ok := event.(events.ActionPerformed); ok {
+				lastAction, paused = time.Now(), false
+			}
+
+			continue
+		case nowAway, ok := <-changes:
+			if !ok {
+				return
+			}
+
+			away = nowAway
+			if !away && paused {
+				paused = false
+				if resume && s.player.Paused() {
+					s.player.SetPaused(false)
+					s.bus.Publish(events.IdleResumed{})
+				}
+
+				continue
+			}
+		case <-ticker.C:
+		}
+
+		// The dashboard may be used over SSH while the system itself is idle, so both must be inactive
+		if away && !paused && time.Since(lastAction) >= timeout && playing(s.player) {
+			s.player.SetPaused(true)
+			paused = true
+			s.bus.Publish(events.IdlePaused{After: timeout})
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/broar/chipmusic-cli/pkg/chipmusic"
+	"github.com/broar/chipmusic-cli/pkg/output"
+	"github.com/spf13/cobra"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+var infoCmd = &cobra.Command{
+	Use:   "info track-url...",
+	Short: "Print the metadata of tracks without downloading their audio",
+	Long: `Print the metadata of tracks without downloading their audio.
+
+The title, artist, tags, file type, size, and duration of each track are printed as a table, or as a JSON array with
+--output=json. The size and duration are found by fetching only the start of each audio file. The duration is an
+estimate and is left out for chiptunes and tracker modules, which don't have a fixed length. Tracks which can't be
+looked up are reported and skipped.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		format, err := outputFormat(cmd)
+		if err != nil {
+			panic(err)
+		}
+
+		if err := printTrackInfo(args, format); err != nil {
+			panic(err)
+		}
+	},
+	Args: cobra.MinimumNArgs(1),
+}
+
+func init() {
+	rootCmd.AddCommand(infoCmd)
+	infoCmd.Flags().Bool("json", false, "print the metadata as JSON instead of a table, like --output=json")
+}
+
+func printTrackInfo(trackURLs []string, format output.Format) error {
+	client, err := newClient(chipmusic.WithProbing())
+	if err != nil {
+		return fmt.Errorf("failed to create chipmusic client: %w", err)
+	}
+
+	var tracks []*chipmusic.Track
+	for _, trackURL := range trackURLs {
+		track, err := client.GetTrackInfo(context.Background(), trackURL)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to get info for %s: %v\n", trackURL, err)
+			continue
+		}
+
+		tracks = append(tracks, track)
+	}
+
+	if format == output.FormatJSON {
+		infos := make([]output.Track, 0, len(tracks))
+		for _, track := range tracks {
+			infos = append(infos, output.NewTrack(track))
+		}
+
+		if err := output.WriteJSON(os.Stdout, infos); err != nil {
+			return fmt.Errorf("failed to write track info: %w", err)
+		}
+	} else {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "TITLE\tARTIST\tTYPE\tSIZE\tDURATION\tTAGS\tURL")
+		for _, track := range tracks {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", track.Title, track.Artist, track.FileType,
+				formatSize(track.Size), formatEstimate(track.Duration), strings.Join(track.Tags, ", "), track.PageURL)
+		}
+
+		if err := w.Flush(); err != nil {
+			return fmt.Errorf("failed to write track info: %w", err)
+		}
+	}
+
+	if failed := len(trackURLs) - len(tracks); failed > 0 {
+		return fmt.Errorf("failed to get info for %d of %d tracks", failed, len(trackURLs))
+	}
+
+	return nil
+}
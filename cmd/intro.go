@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/broar/chipmusic-cli/pkg/chipmusic"
+	"github.com/broar/chipmusic-cli/pkg/store"
+	"github.com/spf13/cobra"
+	"time"
+)
+
+var introSkipCmd = &cobra.Command{
+	Use:   "intro-skip duration",
+	Short: "Skip the first part of every track by an artist or of a single track. A duration of 0 removes the rule",
+	Run: func(cmd *cobra.Command, args []string) {
+		artist, _ := cmd.Flags().GetString("artist")
+		track, _ := cmd.Flags().GetString("track")
+		if err := setIntroSkip(args[0], artist, track); err != nil {
+			panic(err)
+		}
+	},
+	Args: cobra.ExactArgs(1),
+}
+
+func init() {
+	rootCmd.AddCommand(introSkipCmd)
+	introSkipCmd.Flags().String("artist", "", "Apply the rule to every track by this artist")
+	introSkipCmd.Flags().String("track", "", "Apply the rule to the track with this exact URL from chipmusic.org")
+}
+
+func setIntroSkip(duration, artist, track string) error {
+	if (artist == "") == (track == "") {
+		return errors.New("exactly one of --artist or --track must be set")
+	}
+
+	skip, err := time.ParseDuration(duration)
+	if err != nil {
+		return fmt.Errorf("failed to parse duration %s: %w", duration, err)
+	}
+
+	s, err := openStore()
+	if err != nil {
+		return fmt.Errorf("failed to open store: %w", err)
+	}
+
+	defer s.Close()
+
+	if artist != "" {
+		return s.SetArtistIntroSkip(artist, skip)
+	}
+
+	return s.SetTrackIntroSkip(track, skip)
+}
+
+func introSkip(s store.Store, track *chipmusic.Track) time.Duration {
+	skip, err := s.IntroSkip(track.PageURL, track.Artist)
+	if err != nil {
+		fmt.Printf("failed to get intro skip for %s: %v\n", track.Title, err)
+		return 0
+	}
+
+	return skip
+}
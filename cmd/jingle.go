@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/broar/chipmusic-cli/pkg/events"
+	"github.com/broar/chipmusic-cli/pkg/player"
+	"time"
+)
+
+// jingleTimeout is how much longer than its duration a jingle may take to play before audio output is considered
+// stuck, e.g. because the audio device is gone
+const jingleTimeout = 2 * time.Second
+
+// playJingle plays a jingle and blocks until it is done playing
+func playJingle(tp *player.TrackPlayer, jingle player.Jingle) error {
+	if err := tp.PlayJingle(jingle); err != nil {
+		return fmt.Errorf("failed to play jingle: %w", err)
+	}
+
+	select {
+	case <-tp.Done():
+		return nil
+	case <-time.After(jingle.Duration() + jingleTimeout):
+		return errors.New("jingle did not finish playing, so audio output is stuck")
+	}
+}
+
+// playJingles plays the startup jingle now and the shutdown jingle when the session is closed, before the player is
+func (s *session) playJingles() {
+	if err := playJingle(s.player, player.StartupJingle); err != nil {
+		s.bus.Publish(events.Error{Err: err})
+	}
+
+	s.closers = append(s.closers, func() {
+		// The session is over, so there is nothing left to report a failure to
+		_ = playJingle(s.player, player.ShutdownJingle)
+	})
+}
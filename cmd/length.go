@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/broar/chipmusic-cli/pkg/chipmusic"
+	"github.com/broar/chipmusic-cli/pkg/player"
+	"io"
+	"time"
+)
+
+const (
+	// maxTrackActionSkip skips tracks longer than the maximum track length without playing them
+	maxTrackActionSkip = "skip"
+
+	// maxTrackActionFade plays tracks longer than the maximum track length until the maximum and fades them out
+	maxTrackActionFade = "fade"
+
+	// maxTrackFadeOut is how long a track takes to fade out once it reaches the maximum track length
+	maxTrackFadeOut = 5 * time.Second
+)
+
+// limitTrackLength limits how long each track of the session plays. Tracks longer than max are skipped or faded out
+// depending on action. If max is 0 or less, tracks are played in full
+func (s *session) limitTrackLength(max time.Duration, action string) error {
+	if action != maxTrackActionSkip && action != maxTrackActionFade {
+		return fmt.Errorf("unknown max track action %q. Allowed actions: [%s, %s]", action, maxTrackActionSkip, maxTrackActionFade)
+	}
+
+	s.maxTrackLength = max
+	s.maxTrackAction = action
+	return nil
+}
+
+// tooLong returns true if the track should be skipped because it is longer than the maximum track length
+func (s *session) tooLong(track *chipmusic.Track) bool {
+	if s.maxTrackLength <= 0 || s.maxTrackAction != maxTrackActionSkip {
+		return false
+	}
+
+	length, err := trackLength(track)
+	return err == nil && length > s.maxTrackLength
+}
+
+// fadeOutLongTrack fades the current track of the given length out so it stops at the maximum track length, unless ctx
+// is done first because the track finished. Pausing the track pauses the countdown as well since the position of the
+// track is used rather than the wall clock
+func (s *session) fadeOutLongTrack(ctx context.Context, length time.Duration) {
+	if s.maxTrackLength <= 0 || s.maxTrackAction != maxTrackActionFade || length <= s.maxTrackLength {
+		return
+	}
+
+	fadeAt := s.maxTrackLength - maxTrackFadeOut
+	if fadeAt < 0 {
+		fadeAt = 0
+	}
+
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if s.player.CurrentTime() >= fadeAt {
+				s.player.FadeOut(s.maxTrackLength - fadeAt)
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// trackLength decodes the audio of a track to find out how long it is and then rewinds the Reader of the track so it
+// can be played from the start
+func trackLength(track *chipmusic.Track) (time.Duration, error) {
+	// Decoding closes the reader along with the stream, which must not happen before the track is played
+	decoded := *track
+	decoded.Reader = &chipmusic.ReadSeekNopCloser{Reader: track.Reader}
+
+	stream, format, err := player.Decode(&decoded)
+	if err != nil {
+		return 0, fmt.Errorf("failed to decode track audio: %w", err)
+	}
+
+	length := format.SampleRate.D(stream.Len())
+	stream.Close()
+
+	if _, err := track.Reader.Seek(0, io.SeekStart); err != nil {
+		return 0, fmt.Errorf("failed to rewind track: %w", err)
+	}
+
+	return length, nil
+}
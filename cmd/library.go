@@ -0,0 +1,436 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/broar/chipmusic-cli/pkg/chipmusic"
+	"github.com/broar/chipmusic-cli/pkg/chipmusic/tags"
+	"github.com/broar/chipmusic-cli/pkg/library"
+	"github.com/broar/chipmusic-cli/pkg/player"
+	"github.com/mitchellh/go-homedir"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	defaultLibraryDirName = "library"
+
+	// defaultWatchDirName is the folder in the home directory watched for new audio files, where browsers save
+	// downloads by default
+	defaultWatchDirName = "Downloads"
+)
+
+var libraryCmd = &cobra.Command{
+	Use:   "library",
+	Short: "Manage the library of tracks stored on disk",
+}
+
+var libraryRetagCmd = &cobra.Command{
+	Use:   "retag",
+	Short: "Refresh the ID3 tags of stored tracks from the latest metadata on chipmusic.org",
+	Long: `Refresh the ID3 tags of stored tracks from the latest metadata on chipmusic.org.
+
+Only MP3 files whose tags contain the URL of their track page are refreshed. Titles, artists, and the tags of the
+track are updated; every other tag, such as artwork, is kept as is.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		dir, _ := cmd.Flags().GetString("library-dir")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		if err := retagLibrary(dir, dryRun); err != nil {
+			panic(err)
+		}
+	},
+}
+
+var libraryWatchCmd = &cobra.Command{
+	Use:   "watch [folder]",
+	Short: "Import audio files into the library as they appear in a folder such as your downloads folder",
+	Long: `Import audio files into the library as they appear in a folder such as your downloads folder.
+
+The folder is given as an argument or with watch-dir in the config file, and defaults to the Downloads folder in your
+home directory. Files the player can play are copied into the library once they finish downloading, or moved with
+--move. They are named with --template like downloaded tracks, where the artist and title come from the ID3 tag of
+MP3s, the header of chiptunes and tracker modules, or else the name of the file. Only files added while watching are
+imported unless --existing is given. Watching continues until the command is interrupted.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		dir, _ := cmd.Flags().GetString("library-dir")
+		folder := viper.GetString("watch-dir")
+		if len(args) > 0 {
+			folder = args[0]
+		}
+
+		if err := watchFolder(folder, dir); err != nil {
+			panic(err)
+		}
+	},
+	Args: cobra.MaximumNArgs(1),
+}
+
+var libraryRmCmd = &cobra.Command{
+	Use:   "rm file...",
+	Short: "Move tracks out of the library into its trash",
+	Long: `Move tracks out of the library into its trash.
+
+Removed files are kept in the .trash directory of the library along with a tombstone recording their title, artist,
+and the URL of their track page, so they can be brought back with library redownload.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		dir, _ := cmd.Flags().GetString("library-dir")
+		if err := removeTracks(dir, args); err != nil {
+			panic(err)
+		}
+	},
+	Args: cobra.MinimumNArgs(1),
+}
+
+var libraryRedownloadCmd = &cobra.Command{
+	Use:   "redownload file...",
+	Short: "Download tracks in the library again from the URL they were downloaded from",
+	Long: `Download tracks in the library again from the URL they were downloaded from, e.g. when files got corrupted or
+badly transcoded.
+
+The URL of a track is read from the ID3 tag of MP3s saved by the download command, or from the tombstone of a file
+removed with library rm. The file is replaced by the new download. If the track was removed, it is restored and its
+copy in the trash is deleted.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		dir, _ := cmd.Flags().GetString("library-dir")
+		noTags, _ := cmd.Flags().GetBool("no-tags")
+		if err := redownloadTracks(dir, args, !noTags); err != nil {
+			panic(err)
+		}
+	},
+	Args: cobra.MinimumNArgs(1),
+}
+
+func init() {
+	rootCmd.AddCommand(libraryCmd)
+	libraryCmd.AddCommand(libraryRetagCmd)
+	libraryCmd.AddCommand(libraryWatchCmd)
+	libraryCmd.AddCommand(libraryRmCmd)
+	libraryCmd.AddCommand(libraryRedownloadCmd)
+	libraryCmd.PersistentFlags().String("library-dir", "", "directory where tracks are stored (default is the library directory in the data directory)")
+	libraryRetagCmd.Flags().Bool("dry-run", false, "print the tags which would change without writing them")
+	libraryWatchCmd.Flags().String("watch-dir", "", "folder to watch for new audio files (default is the Downloads folder in the home directory)")
+	libraryWatchCmd.Flags().Duration("watch-interval", library.DefaultWatchInterval, "how often the folder is checked for new files")
+	libraryWatchCmd.Flags().String("watch-template", defaultFilenameTemplate, "template for the names of imported files. Placeholders: [{artist}, {title}, {ext}]")
+	libraryWatchCmd.Flags().Bool("move", false, "move imported files into the library instead of copying them")
+	libraryWatchCmd.Flags().Bool("existing", false, "also import the files already in the folder")
+	libraryRedownloadCmd.Flags().Bool("no-tags", false, "save MP3s without writing ID3 tags")
+
+	if err := viper.BindPFlags(libraryWatchCmd.Flags()); err != nil {
+		panic(fmt.Errorf("failed to bind flags: %w", err))
+	}
+}
+
+// libraryDir returns the directory where tracks are stored, which defaults to a directory in the data directory
+func libraryDir(dir string) (string, error) {
+	if dir != "" {
+		return dir, nil
+	}
+
+	data, err := dataDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve data directory: %w", err)
+	}
+
+	return filepath.Join(data, defaultLibraryDirName), nil
+}
+
+// libraryFiles returns the paths of every MP3 file in the library, leaving out the files in its trash
+func libraryFiles(dir string) ([]string, error) {
+	paths := make([]string, 0)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() && path == filepath.Join(dir, library.TrashDirName) {
+			return filepath.SkipDir
+		}
+
+		if !info.IsDir() && strings.EqualFold(filepath.Ext(path), "."+string(chipmusic.AudioFileTypeMP3)) {
+			paths = append(paths, path)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to list library %s: %w", dir, err)
+	}
+
+	return paths, nil
+}
+
+func retagLibrary(dir string, dryRun bool) error {
+	dir, err := libraryDir(dir)
+	if err != nil {
+		return err
+	}
+
+	paths, err := libraryFiles(dir)
+	if err != nil {
+		return err
+	}
+
+	// The metadata of tracks is cached in the store, but retagging works without it, e.g. while a session holds the store
+	var options []chipmusic.Option
+	if s, err := openStore(); err == nil {
+		defer s.Close()
+		options = metadataCacheOptions(s)
+	}
+
+	client, err := newClient(options...)
+	if err != nil {
+		return fmt.Errorf("failed to create chipmusic client: %w", err)
+	}
+
+	changed := 0
+	for _, path := range paths {
+		current, err := tags.ReadFile(path)
+		if err != nil {
+			fmt.Printf("%s: %v\n", path, err)
+			continue
+		}
+
+		if current.Source == "" {
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+		track, err := client.GetTrackInfo(ctx, current.Source)
+		cancel()
+		if err != nil {
+			fmt.Printf("%s: failed to get track info: %v\n", path, err)
+			continue
+		}
+
+		updated := current.Merge(tags.FromTrack(track))
+		changes := tags.Diff(current, updated)
+		if len(changes) == 0 {
+			continue
+		}
+
+		changed++
+		fmt.Println(path)
+		for _, change := range changes {
+			fmt.Printf("  %s: %q -> %q\n", change.Field, change.Old, change.New)
+		}
+
+		if dryRun {
+			continue
+		}
+
+		if err := tags.WriteFile(path, updated); err != nil {
+			return fmt.Errorf("failed to retag %s: %w", path, err)
+		}
+	}
+
+	if dryRun {
+		fmt.Printf("%d of %d tracks would be retagged\n", changed, len(paths))
+	} else {
+		fmt.Printf("Retagged %d of %d tracks\n", changed, len(paths))
+	}
+
+	return nil
+}
+
+func watchFolder(folder, dir string) error {
+	if folder == "" {
+		home, err := homedir.Dir()
+		if err != nil {
+			return fmt.Errorf("failed to find home directory: %w", err)
+		}
+
+		folder = filepath.Join(home, defaultWatchDirName)
+	}
+
+	dir, err := libraryDir(dir)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create library directory: %w", err)
+	}
+
+	watcher := library.NewWatcher(folder, viper.GetBool("existing"), func(path string) bool {
+		return player.IsSupportedFormat(chipmusic.FileTypeFromPath(path))
+	})
+
+	fmt.Printf("Watching %s for audio files to import into %s\n", folder, dir)
+	template, move := viper.GetString("watch-template"), viper.GetBool("move")
+	return watcher.Watch(context.Background(), viper.GetDuration("watch-interval"), func(path string) {
+		imported, err := importFile(path, dir, template, move)
+		if err != nil {
+			fmt.Printf("%s: %v\n", path, err)
+			return
+		}
+
+		if imported == "" {
+			fmt.Printf("Skipped %s because it is already in the library\n", path)
+			return
+		}
+
+		fmt.Printf("Imported %s to %s\n", path, imported)
+	})
+}
+
+// importFile copies or moves the audio file at path into the library in dir and returns its path in the library. The
+// file is named by template from its metadata. If a file with the same name is already in the library, nothing is
+// imported and an empty path is returned
+func importFile(path, dir, template string, move bool) (string, error) {
+	imported := filepath.Join(dir, trackFilename(template, library.ReadMetadata(path)))
+	if _, err := os.Stat(imported); err == nil {
+		return "", nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(imported), 0700); err != nil {
+		return "", fmt.Errorf("failed to create directory for %s: %w", imported, err)
+	}
+
+	// Moving within the same file system is a rename, but across file systems the file must be copied
+	if move && os.Rename(path, imported) == nil {
+		return imported, nil
+	}
+
+	src, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file: %w", err)
+	}
+
+	defer src.Close()
+
+	// Writing to a temporary file first keeps a failed copy from leaving a partial file in the library
+	file, err := ioutil.TempFile(dir, ".chipmusic-*.import")
+	if err != nil {
+		return "", fmt.Errorf("failed to create file: %w", err)
+	}
+
+	defer os.Remove(file.Name())
+
+	_, err = io.Copy(file, src)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", imported, err)
+	}
+
+	if err := os.Rename(file.Name(), imported); err != nil {
+		return "", fmt.Errorf("failed to save %s: %w", imported, err)
+	}
+
+	if move {
+		if err := os.Remove(path); err != nil {
+			return "", fmt.Errorf("failed to remove file after importing it: %w", err)
+		}
+	}
+
+	return imported, nil
+}
+
+// removeTracks moves the files at paths out of the library in dir into its trash
+func removeTracks(dir string, paths []string) error {
+	dir, err := libraryDir(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, path := range paths {
+		tombstone, err := library.Trash(dir, path, time.Now())
+		if err != nil {
+			return fmt.Errorf("failed to remove %s: %w", path, err)
+		}
+
+		if tombstone.URL == "" {
+			fmt.Printf("Removed %s. It has no recorded URL, so it can't be redownloaded\n", path)
+			continue
+		}
+
+		fmt.Printf("Removed %s\n", path)
+	}
+
+	return nil
+}
+
+// redownloadTracks downloads the files at paths in the library in dir again from the URLs of their track pages, which
+// are read from the files or from the tombstones of removed files. If tagged is true, MP3s are tagged with the metadata
+// of the tracks
+func redownloadTracks(dir string, paths []string, tagged bool) error {
+	dir, err := libraryDir(dir)
+	if err != nil {
+		return err
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return fmt.Errorf("failed to create chipmusic client: %w", err)
+	}
+
+	for _, path := range paths {
+		saved, err := redownloadTrack(client, dir, path, tagged)
+		if err != nil {
+			return fmt.Errorf("failed to redownload %s: %w", path, err)
+		}
+
+		fmt.Printf("Saved %s\n", saved)
+	}
+
+	return nil
+}
+
+// redownloadTrack downloads the file at path in the library in dir again and returns the path it was saved to. The
+// file keeps its name unless the track page now has audio of another file type, in which case the extension changes
+// and the old file is deleted
+func redownloadTrack(client *chipmusic.Client, dir, path string, tagged bool) (string, error) {
+	tombstone, err := library.FindTombstone(dir, path)
+	if err != nil {
+		return "", err
+	}
+
+	trackURL := ""
+	if _, err := os.Stat(path); err == nil {
+		trackURL = library.ReadMetadata(path).PageURL
+	} else if tombstone != nil {
+		trackURL = tombstone.URL
+	} else {
+		return "", fmt.Errorf("no such file in the library or its trash")
+	}
+
+	if trackURL == "" {
+		return "", errors.New("no URL of a track page is recorded for it")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	info, err := client.GetTrackInfo(ctx, trackURL)
+	cancel()
+	if err != nil {
+		return "", fmt.Errorf("failed to get track info: %w", err)
+	}
+
+	saved := strings.TrimSuffix(path, filepath.Ext(path)) + "." + string(info.FileType)
+	if err := saveTrack(client, info, saved, tagged); err != nil {
+		return "", err
+	}
+
+	if saved != path {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return "", fmt.Errorf("failed to delete the old file: %w", err)
+		}
+	}
+
+	if tombstone != nil {
+		if err := library.Forget(dir, tombstone); err != nil {
+			return "", err
+		}
+	}
+
+	return saved, nil
+}
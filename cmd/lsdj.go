@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/broar/chipmusic-cli/pkg/chipmusic"
+	"github.com/broar/chipmusic-cli/pkg/lsdj"
+	"github.com/broar/chipmusic-cli/pkg/player"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// lsdjRenderTimeout is how long the render command may take to render a project file
+	lsdjRenderTimeout = 5 * time.Minute
+)
+
+var lsdjCmd = &cobra.Command{
+	Use:   "lsdj file...",
+	Short: "Print the songs in LSDj project files (experimental)",
+	Long: `Print the songs in Little Sound Dj .sav and .lsdsng project files. This command is experimental.
+
+Songs can't be played directly since that means emulating LSDj itself. Instead, --preview renders a project file to a
+WAV file with the tool given by --lsdj-render and plays it. {input} in the command is replaced by the path of the
+project file and {output} by the path the WAV file should be written to, e.g. "lsdj-render --out {output} {input}"`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := inspectLSDj(args); err != nil {
+			panic(err)
+		}
+	},
+	Args: cobra.MinimumNArgs(1),
+}
+
+func init() {
+	rootCmd.AddCommand(lsdjCmd)
+	lsdjCmd.Flags().Bool("preview", false, "render and play each project file with the render command")
+	lsdjCmd.Flags().String("lsdj-render", "", "command which renders an LSDj project file at {input} to a WAV file at {output}")
+
+	if err := viper.BindPFlags(lsdjCmd.Flags()); err != nil {
+		panic(fmt.Errorf("failed to bind flags: %w", err))
+	}
+}
+
+func inspectLSDj(paths []string) error {
+	var renderer *lsdj.Renderer
+	if viper.GetBool("preview") {
+		var err error
+		renderer, err = lsdj.NewRenderer(viper.GetString("lsdj-render"))
+		if err != nil {
+			return fmt.Errorf("failed to create renderer: %w", err)
+		}
+	}
+
+	for _, path := range paths {
+		if err := printLSDj(path); err != nil {
+			return err
+		}
+
+		if renderer != nil {
+			if err := previewLSDj(renderer, path); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// printLSDj prints the songs in the project file at path, which is read as a save or a song by its extension
+func printLSDj(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read project file %s: %w", path, err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".sav":
+		save, err := lsdj.ParseSave(data)
+		if err != nil {
+			return fmt.Errorf("failed to parse save %s: %w", path, err)
+		}
+
+		fmt.Printf("%s: save with %d songs, %d blocks free\n", path, len(save.Songs), save.FreeBlocks)
+		for i, song := range save.Songs {
+			active := ""
+			if i == save.Active {
+				active = " (working song)"
+			}
+
+			fmt.Printf("  %-8s v%02X  %3d blocks%s\n", song.Name, song.Version, song.Blocks, active)
+		}
+	case ".lsdsng":
+		song, err := lsdj.ParseSong(data)
+		if err != nil {
+			return fmt.Errorf("failed to parse song %s: %w", path, err)
+		}
+
+		fmt.Printf("%s: song %s v%02X, %d blocks\n", path, song.Name, song.Version, song.Blocks)
+	default:
+		return fmt.Errorf("%s is not an LSDj project file, which end in .sav or .lsdsng", path)
+	}
+
+	return nil
+}
+
+// previewLSDj renders the project file at path to a temporary WAV file and plays it until it is done
+func previewLSDj(renderer *lsdj.Renderer, path string) error {
+	dir, err := ioutil.TempDir("", "chipmusic-lsdj-*")
+	if err != nil {
+		return fmt.Errorf("failed to create render directory: %w", err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	ctx, cancel := context.WithTimeout(context.Background(), lsdjRenderTimeout)
+	defer cancel()
+
+	output := filepath.Join(dir, "preview.wav")
+	if err := renderer.Render(ctx, path, output); err != nil {
+		return err
+	}
+
+	file, err := os.Open(output)
+	if err != nil {
+		return fmt.Errorf("failed to open rendered audio: %w", err)
+	}
+
+	name := filepath.Base(path)
+	track := &chipmusic.Track{
+		Title:    strings.TrimSuffix(name, filepath.Ext(name)),
+		Reader:   file,
+		FileType: chipmusic.AudioFileTypeWAV,
+	}
+
+	defer track.Close()
+
+	var options []player.Option
+	if viper.IsSet("volume") {
+		options = append(options, player.WithVolume(viper.GetInt("volume")))
+	}
+
+	tp, err := player.NewTrackPlayer(options...)
+	if err != nil {
+		return fmt.Errorf("failed to create track player: %w", err)
+	}
+
+	defer tp.Close()
+
+	if err := tp.Play(track); err != nil {
+		if errors.Is(err, player.ErrCorruptTrack) {
+			return fmt.Errorf("render command didn't write a WAV file: %w", err)
+		}
+
+		return fmt.Errorf("failed to play preview of %s: %w", path, err)
+	}
+
+	fmt.Printf("Playing a preview of %s\n", path)
+	<-tp.Done()
+	return nil
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"github.com/broar/chipmusic-cli/pkg/chipmusic"
+	"github.com/broar/chipmusic-cli/pkg/store"
+	"github.com/spf13/viper"
+	"time"
+)
+
+// defaultMetadataTTL is how long the metadata of a track stays cached unless configured otherwise. Artists rarely edit
+// their tracks once posted, so it is long
+const defaultMetadataTTL = 7 * 24 * time.Hour
+
+// storeMetadataCache caches the metadata of tracks in a store, where it stays fresh for ttl
+type storeMetadataCache struct {
+	store store.Store
+	ttl   time.Duration
+	now   func() time.Time
+}
+
+// TrackMetadata returns a Track holding the metadata cached for the track page at trackPageURL. If none is cached or
+// it is older than the ttl, nil is returned
+func (c *storeMetadataCache) TrackMetadata(trackPageURL string) (*chipmusic.Track, error) {
+	metadata, err := c.store.TrackMetadata(trackPageURL)
+	if err != nil || metadata == nil {
+		return nil, err
+	}
+
+	if c.now().Sub(metadata.CachedAt) > c.ttl {
+		return nil, nil
+	}
+
+	return &chipmusic.Track{
+		Title:       metadata.Title,
+		Artist:      metadata.Artist,
+		PageURL:     metadata.URL,
+		DownloadURL: metadata.DownloadURL,
+		Tags:        metadata.Tags,
+		PostedAt:    metadata.PostedAt,
+		Description: metadata.Description,
+	}, nil
+}
+
+// SetTrackMetadata caches the metadata of track
+func (c *storeMetadataCache) SetTrackMetadata(track *chipmusic.Track) error {
+	return c.store.SetTrackMetadata(store.TrackMetadata{
+		URL:         track.PageURL,
+		Title:       track.Title,
+		Artist:      track.Artist,
+		DownloadURL: track.DownloadURL,
+		Tags:        track.Tags,
+		PostedAt:    track.PostedAt,
+		Description: track.Description,
+		CachedAt:    c.now(),
+	})
+}
+
+// metadataCacheOptions returns the client options caching the metadata of tracks in s for as long as configured. If
+// caching is disabled, no options are returned
+func metadataCacheOptions(s store.Store) []chipmusic.Option {
+	ttl := viper.GetDuration("metadata-ttl")
+	if ttl <= 0 {
+		return nil
+	}
+
+	return []chipmusic.Option{chipmusic.WithMetadataCache(&storeMetadataCache{store: s, ttl: ttl, now: time.Now})}
+}
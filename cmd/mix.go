@@ -0,0 +1,206 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/broar/chipmusic-cli/pkg/chipmusic"
+	"github.com/broar/chipmusic-cli/pkg/shuffle"
+	"github.com/broar/chipmusic-cli/pkg/store"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+const (
+	// mixRelatedArtists is how many of the most played artists are searched for related tracks
+	mixRelatedArtists = 5
+)
+
+var mixCmd = &cobra.Command{
+	Use:   "mix",
+	Short: "Play a mix blending favorites, tracks related to your listening history, and fresh uploads",
+	Run: func(cmd *cobra.Command, args []string) {
+		length, _ := cmd.Flags().GetDuration("length")
+		favoritesShare, _ := cmd.Flags().GetFloat64("favorites-share")
+		relatedShare, _ := cmd.Flags().GetFloat64("related-share")
+		freshShare, _ := cmd.Flags().GetFloat64("fresh-share")
+		if err := playMix(length, favoritesShare, relatedShare, freshShare); err != nil {
+			panic(err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(mixCmd)
+	mixCmd.Flags().Duration("length", time.Hour, "Stop starting new tracks once the mix has played for this long")
+	mixCmd.Flags().Float64("favorites-share", 0.3, "Share of the mix taken from favorites")
+	mixCmd.Flags().Float64("related-share", 0.4, "Share of the mix taken from other tracks by the most played artists")
+	mixCmd.Flags().Float64("fresh-share", 0.3, "Share of the mix taken from the latest uploads")
+}
+
+func playMix(length time.Duration, favoritesShare, relatedShare, freshShare float64) error {
+	s, err := newSession()
+	if err != nil {
+		return err
+	}
+
+	defer s.close()
+
+	if err := s.limitTrackLength(viper.GetDuration("max-track-length"), viper.GetString("max-track-action")); err != nil {
+		return err
+	}
+
+	if err := s.artistCooldown(viper.GetInt("artist-cooldown-tracks"), viper.GetDuration("artist-cooldown")); err != nil {
+		return err
+	}
+
+	if err := s.fillGaps(viper.GetDuration("gap"), viper.GetString("ident-dir"), viper.GetInt("ident-every")); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	random := newRandom(now)
+	sources, err := mixSources(s, random, favoritesShare, relatedShare, freshShare)
+	if err != nil {
+		return err
+	}
+
+	tracks := shuffle.Mix(sources, random)
+	if len(tracks) == 0 {
+		return errors.New("nothing to mix: add favorites or play some tracks first")
+	}
+
+	s.start()
+
+	for _, trackURL := range tracks {
+		if time.Since(now) >= length {
+			break
+		}
+
+		if err := playTracks([]string{trackURL}, s); errors.Is(err, errMaxTotalSize) {
+			break
+		} else if err != nil {
+			return fmt.Errorf("failed to play tracks: %w", err)
+		}
+	}
+
+	s.wait()
+	return nil
+}
+
+// mixSources gathers the tracks for each part of the mix. Tracks within each source are shuffled so every mix is
+// different
+func mixSources(s *session, random *rand.Rand, favoritesShare, relatedShare, freshShare float64) ([]shuffle.MixSource, error) {
+	history, err := s.store.History(0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get listening history: %w", err)
+	}
+
+	favorites, err := s.store.Favorites()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get favorites: %w", err)
+	}
+
+	favoriteURLs := make([]string, 0, len(favorites))
+	for _, favorite := range favorites {
+		favoriteURLs = append(favoriteURLs, favorite.URL)
+	}
+
+	related, err := relatedTracks(s.client, history, favorites)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	results, err := s.client.Search(ctx, chipmusic.SearchOptions{Filter: chipmusic.TrackFilterLatest})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search for fresh tracks: %w", err)
+	}
+
+	latest := chipmusic.SearchResultURLs(results)
+
+	played := map[string]bool{}
+	for _, entry := range history {
+		played[entry.URL] = true
+	}
+
+	sources := []shuffle.MixSource{
+		{Name: "favorites", Tracks: favoriteURLs, Proportion: favoritesShare},
+		{Name: "related", Tracks: unplayed(related, played), Proportion: relatedShare},
+		{Name: "fresh", Tracks: unplayed(latest, played), Proportion: freshShare},
+	}
+
+	for _, source := range sources {
+		random.Shuffle(len(source.Tracks), func(i, j int) {
+			source.Tracks[i], source.Tracks[j] = source.Tracks[j], source.Tracks[i]
+		})
+	}
+
+	return sources, nil
+}
+
+// relatedTracks searches for other tracks by the artists which were played the most or marked as favorites
+func relatedTracks(client *chipmusic.Client, history []store.HistoryEntry, favorites []store.Favorite) ([]string, error) {
+	plays := map[string]int{}
+	for _, entry := range history {
+		plays[entry.Artist]++
+	}
+
+	for _, favorite := range favorites {
+		plays[favorite.Artist]++
+	}
+
+	artists := make([]string, 0, len(plays))
+	for artist := range plays {
+		if artist != "" {
+			artists = append(artists, artist)
+		}
+	}
+
+	sort.Slice(artists, func(i, j int) bool {
+		if plays[artists[i]] == plays[artists[j]] {
+			return artists[i] < artists[j]
+		}
+
+		return plays[artists[i]] > plays[artists[j]]
+	})
+
+	if len(artists) > mixRelatedArtists {
+		artists = artists[:mixRelatedArtists]
+	}
+
+	return artistTracks(client, artists, 1)
+}
+
+// artistTracks returns the tracks on the page of the latest tracks of each of artists, one artist after the other
+func artistTracks(client *chipmusic.Client, artists []string, page int) ([]string, error) {
+	tracks := make([]string, 0)
+	for _, artist := range artists {
+		ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+		results, err := client.Search(ctx, chipmusic.SearchOptions{Query: chipmusic.ArtistQuery(artist), Filter: chipmusic.TrackFilterLatest, Page: page})
+		cancel()
+		if err != nil {
+			return nil, fmt.Errorf("failed to search for tracks by %s: %w", artist, err)
+		}
+
+		tracks = append(tracks, chipmusic.SearchResultURLs(results)...)
+	}
+
+	return tracks, nil
+}
+
+func unplayed(trackURLs []string, played map[string]bool) []string {
+	filtered := make([]string, 0, len(trackURLs))
+	for _, trackURL := range trackURLs {
+		if !played[trackURL] {
+			filtered = append(filtered, trackURL)
+		}
+	}
+
+	return filtered
+}
@@ -0,0 +1,17 @@
+package cmd
+
+import (
+	"github.com/broar/chipmusic-cli/pkg/output"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// outputFormat returns the format cmd prints its output in, which is set with --output. Commands which had a --json
+// flag before --output existed keep it as a shorthand for --output=json
+func outputFormat(cmd *cobra.Command) (output.Format, error) {
+	if asJSON, err := cmd.Flags().GetBool("json"); err == nil && asJSON {
+		return output.FormatJSON, nil
+	}
+
+	return output.ParseFormat(viper.GetString("output"))
+}
@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"encoding/json"
+	"github.com/broar/chipmusic-cli/pkg/output"
+	"github.com/broar/chipmusic-cli/pkg/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestOutputJSON_WithConfigFile(t *testing.T) {
+	flags, dataDir, cleanup := tempConfig(t)
+	defer cleanup()
+
+	s, err := store.OpenBoltStore(filepath.Join(dataDir, store.DefaultFileName))
+	require.NoError(t, err)
+
+	entry := store.HistoryEntry{URL: "some.url", Title: "some.title", Artist: "some.artist", PlayedAt: time.Now()}
+	require.NoError(t, s.AddHistory(entry))
+	require.NoError(t, s.Close())
+
+	// Scripts pipe the output into e.g. jq, so nothing but JSON may be printed even though a config file is used
+	printed := executeCommand(t, append(flags, "--store", store.BackendBolt, "--output", "json", "history")...)
+
+	var entries []output.HistoryEntry
+	require.NoError(t, json.Unmarshal([]byte(printed), &entries), "output should be JSON: %s", printed)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "some.title", entries[0].Title)
+}
@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/broar/chipmusic-cli/pkg/events"
+	"github.com/broar/chipmusic-cli/pkg/integrations/overlay"
+	"github.com/spf13/viper"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"time"
+)
+
+// overlayShutdownTimeout is how long the overlay server waits for requests in flight when the session ends
+const overlayShutdownTimeout = time.Second
+
+// newOverlay creates the overlay configured from flags and the config file. If no address to serve it on is
+// configured, nil is returned
+func newOverlay() (*overlay.Overlay, error) {
+	if viper.GetString("overlay-addr") == "" {
+		return nil, nil
+	}
+
+	options := []overlay.Option{overlay.WithRefresh(viper.GetDuration("overlay-refresh"))}
+	if path := viper.GetString("overlay-template"); path != "" {
+		text, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read overlay template %s: %w", path, err)
+		}
+
+		options = append(options, overlay.WithTemplate(string(text)))
+	}
+
+	return overlay.NewOverlay(options...)
+}
+
+// runOverlay serves the overlay and shows every track played on it until ctx is done
+func (s *session) runOverlay(ctx context.Context) {
+	// Subscribing first keeps tracks which start while listening from missing the overlay
+	ch, unsubscribe := s.bus.Subscribe(0, events.NamePlaybackStarted)
+	defer unsubscribe()
+
+	addr := viper.GetString("overlay-addr")
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		s.bus.Publish(events.Error{Err: fmt.Errorf("failed to serve overlay on %s: %w", addr, err)})
+		return
+	}
+
+	server := &http.Server{Handler: s.overlay}
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.bus.Publish(events.Error{Err: fmt.Errorf("failed to serve overlay: %w", err)})
+		}
+	}()
+
+	defer func() {
+		// Streams of events never end on their own, so the overlay is closed to end them before shutting down
+		s.overlay.Close()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), overlayShutdownTimeout)
+		defer cancel()
+
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			server.Close()
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-ch:
+			if !ok {
+				return
+			}
+
+			if event, ok := event.(events.PlaybackStarted); ok {
+				s.overlay.SetTrack(event.Track)
+			}
+		}
+	}
+}
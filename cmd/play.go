@@ -3,8 +3,8 @@ package cmd
 import (
 	"context"
 	"fmt"
-	"github.com/broar/chipmusic-cli/pkg/chipmusic"
 	"github.com/broar/chipmusic-cli/pkg/dashboard"
+	"github.com/broar/chipmusic-cli/pkg/events"
 	"github.com/broar/chipmusic-cli/pkg/player"
 	"github.com/spf13/cobra"
 	"time"
@@ -30,88 +30,87 @@ func init() {
 }
 
 func playTrack(trackPageURL string) error {
-	client, err := chipmusic.NewClient()
+	s, err := newSession()
 	if err != nil {
-		return fmt.Errorf("failed to create chipmusic client: %w", err)
+		return err
 	}
 
+	defer s.close()
+
 	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
 	defer cancel()
 
-	tp, err := player.NewTrackPlayer()
+	s.start()
+
+	track, err := s.client.GetTrack(ctx, trackPageURL)
 	if err != nil {
-		return fmt.Errorf("failed to create track player: %w", err)
+		return fmt.Errorf("failed to download track: %w", err)
 	}
 
-	defer tp.Close()
+	s.bus.Publish(events.TrackResolved{Track: track})
 
-	db, err := dashboard.NewTerminalDashboard()
-	if err != nil {
-		return fmt.Errorf("failed to create terminal dashboard: %w", err)
+	if err := s.play(track); err != nil {
+		return fmt.Errorf("failed to play track %s: %w", track.Title, err)
 	}
 
-	defer db.Close()
+	return nil
+}
 
-	actions := db.Actions()
-	go func() {
-		if err := db.Start(); err != nil {
-			panic(err)
+func handleTrackControlActions(actions <-chan dashboard.Action, tp *player.TrackPlayer, bus *events.Bus) {
+	for action := range actions {
+		bus.Publish(events.ActionPerformed{Action: action.String()})
+		if err := applyTrackControl(action, tp); err != nil {
+			bus.Publish(events.Error{Err: fmt.Errorf("failed to handle track control %v: %w", action, err)})
 		}
-	}()
-
-	go handleTrackControlActions(actions, tp)
 
-	track, err := client.GetTrack(ctx, trackPageURL)
-	if err != nil {
-		return fmt.Errorf("failed to download track: %w", err)
+		switch action.(type) {
+		case dashboard.VolumeUpAction, dashboard.VolumeDownAction:
+			bus.Publish(events.VolumeChanged{Volume: tp.Volume()})
+		}
 	}
+}
 
-	db.UpdateCurrentTrack(track)
-
-	if err := tp.Play(track); err != nil {
-		return fmt.Errorf("failed to play track %s: %w", track.Title, err)
+func applyTrackControl(action dashboard.Action, tp *player.TrackPlayer) error {
+	switch action := action.(type) {
+	case dashboard.PlayAction:
+		tp.SetPaused(false)
+	case dashboard.PauseAction:
+		tp.Pause()
+	case dashboard.StopAction:
+		return tp.Stop()
+	case dashboard.LoopAction:
+		tp.Loop()
+	case dashboard.SkipAction:
+		return tp.Skip()
+	case dashboard.CrossfeedAction:
+		tp.Crossfeed()
+	case dashboard.NormalizeAction:
+		tp.Normalize()
+	case dashboard.VolumeUpAction:
+		tp.SetVolume(tp.Volume() + player.VolumeStep)
+	case dashboard.VolumeDownAction:
+		tp.SetVolume(tp.Volume() - player.VolumeStep)
+	case dashboard.SeekAction:
+		return tp.SeekRelative(action.Offset)
+	case dashboard.JumpAction:
+		return tp.JumpTo(action.Position - 1)
+	default:
+		return fmt.Errorf("unknown track control: %v", action)
 	}
 
-	go handleTrackTimer(tp, db)
-
-	<-tp.Done()
 	return nil
 }
 
-func handleTrackControlActions(actions <-chan string, tp *player.TrackPlayer) {
-	for {
-		select {
-		case action := <-actions:
-			var err error
-			switch action {
-			case dashboard.TrackControlPlay:
-				// Nothing to do
-			case dashboard.TrackControlPause:
-				tp.Pause()
-			case dashboard.TrackControlStop:
-				err = tp.Stop()
-			case dashboard.TrackControlLoop:
-				tp.Loop()
-			case dashboard.TrackControlSkip:
-				err = tp.Skip()
-			default:
-				fmt.Printf("received unknown track control: %v\n", action)
-			}
-
-			if err != nil {
-				fmt.Printf("failed to handle track control: %v: %v\n", action, err)
-			}
-		}
-	}
-}
+// handleTrackTimer updates the track timer of the dashboard every second until ctx is done
+func handleTrackTimer(ctx context.Context, tp *player.TrackPlayer, db *dashboard.TerminalDashboard) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
 
-func handleTrackTimer(tp *player.TrackPlayer, db *dashboard.TerminalDashboard) {
 	for {
-		ticker := time.NewTicker(time.Second)
 		select {
 		case <-ticker.C:
 			db.UpdateTrackTimer(tp.CurrentTime(), tp.TotalTime())
-		case <-tp.Done():
+		case <-ctx.Done():
 			return
 		}
 	}
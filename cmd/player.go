@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/broar/chipmusic-cli/pkg/events"
+	"github.com/broar/chipmusic-cli/pkg/player"
+	"github.com/broar/chipmusic-cli/pkg/store"
+	"github.com/spf13/viper"
+	"os"
+	"strconv"
+)
+
+// settingVolume is the name of the setting holding the last volume used
+const settingVolume = "volume"
+
+// newTrackPlayer creates a track player configured from flags and the config file. The volume is restored from st
+// unless --volume is given. The returned function closes the player and any files opened for it
+func newTrackPlayer(st store.Store) (*player.TrackPlayer, func(), error) {
+	volume, err := startupVolume(st)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	options := []player.Option{
+		player.WithCrossfeed(viper.GetBool("crossfeed")),
+		player.WithNormalization(viper.GetBool("normalize")),
+		player.WithVolume(volume),
+		player.WithRealtimePriority(viper.GetBool("realtime-audio")),
+	}
+
+	var files []*os.File
+	var tracer *player.Tracer
+	if path := viper.GetString("trace-audio"); path != "" {
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open audio trace file %s: %w", path, err)
+		}
+
+		files = append(files, file)
+		tracer = player.NewTracer(file)
+		options = append(options, player.WithTracer(tracer))
+	}
+
+	var sink *player.FileSink
+	if path := viper.GetString("render-to"); path != "" {
+		if sink, err = player.NewFileSink(path); err != nil {
+			closeTracer(tracer)
+			closeFiles(files)
+			return nil, nil, err
+		}
+
+		options = append(options, player.WithSink(sink))
+	}
+
+	tp, err := player.NewTrackPlayer(options...)
+	if err != nil {
+		if sink != nil {
+			sink.Close()
+		}
+
+		closeTracer(tracer)
+		closeFiles(files)
+		return nil, nil, err
+	}
+
+	return tp, func() {
+		tp.Close()
+		if sink != nil {
+			if err := sink.Close(); err != nil {
+				fmt.Fprintf(os.Stderr, "failed to render to %s: %v\n", viper.GetString("render-to"), err)
+			}
+		}
+
+		closeTracer(tracer)
+		closeFiles(files)
+	}, nil
+}
+
+// closeTracer writes the records still buffered by tracer before its file is closed. tracer may be nil
+func closeTracer(tracer *player.Tracer) {
+	if tracer != nil {
+		tracer.Close()
+	}
+}
+
+// closeFiles closes every file, ignoring errors since the files are only written by loggers
+func closeFiles(files []*os.File) {
+	for _, file := range files {
+		file.Close()
+	}
+}
+
+// startupVolume returns the volume given with --volume, which the player validates, or else the last volume used. A
+// saved volume which can't be parsed is ignored since it shouldn't keep tracks from playing
+func startupVolume(st store.Store) (int, error) {
+	if viper.IsSet("volume") {
+		return viper.GetInt("volume"), nil
+	}
+
+	saved, err := st.Setting(settingVolume)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get saved volume: %w", err)
+	}
+
+	volume, err := strconv.Atoi(saved)
+	if err != nil {
+		return player.DefaultVolume, nil
+	}
+
+	return player.ClampVolume(volume), nil
+}
+
+// saveVolume saves the volume each time it changes so the next session starts at the same volume
+func saveVolume(ch <-chan events.Event, st store.Store, bus *events.Bus) {
+	for event := range ch {
+		if event, ok := event.(events.VolumeChanged); ok {
+			if err := st.SetSetting(settingVolume, strconv.Itoa(event.Volume)); err != nil {
+				bus.Publish(events.Error{Err: fmt.Errorf("failed to save volume: %w", err)})
+			}
+		}
+	}
+}
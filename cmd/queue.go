@@ -0,0 +1,239 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/broar/chipmusic-cli/pkg/chipmusic"
+	"github.com/broar/chipmusic-cli/pkg/events"
+	"github.com/broar/chipmusic-cli/pkg/player"
+	"github.com/broar/chipmusic-cli/pkg/store"
+	"time"
+)
+
+// enqueue queues a track to play right after the current track without a gap and blocks until it starts playing, so
+// only the next track is downloaded and decoded ahead of time. The gap between tracks and any station ident due are
+// played first
+func (s *session) enqueue(track *chipmusic.Track) error {
+	s.fillGap()
+
+	s.mux.Lock()
+	s.tracks[track] = true
+	s.mux.Unlock()
+
+	if err := s.player.EnqueueFrom(track, introSkip(s.store, track), player.PriorityEnd); err != nil {
+		s.mux.Lock()
+		delete(s.tracks, track)
+		s.mux.Unlock()
+		return err
+	}
+
+	for queued(s.player, track) {
+		<-s.progress
+	}
+
+	s.played++
+	return nil
+}
+
+// queueTrackPage downloads the track at trackPageURL and queues it with priority, so it plays after the tracks in the
+// queue or right after the current track. Unlike enqueue, it doesn't wait for the track to start. If now is true, the
+// track plays right away instead, and the current track can be played again with Previous. Errors are published as
+// well as returned, so callers running it in the background can ignore them
+func (s *session) queueTrackPage(trackPageURL string, priority player.Priority, now bool) (*chipmusic.Track, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	track, err := s.client.GetTrack(ctx, trackPageURL)
+	if err != nil {
+		err = fmt.Errorf("failed to download track %s: %w", trackPageURL, err)
+		s.bus.Publish(events.Error{Err: err})
+		return nil, err
+	}
+
+	s.bus.Publish(events.TrackResolved{Track: track})
+
+	if !player.IsSupportedFormat(track.FileType) {
+		track.Close()
+		err := fmt.Errorf("skipped because format %q is not supported", track.FileType)
+		s.skip(track, err)
+		return nil, err
+	}
+
+	s.mux.Lock()
+	s.tracks[track] = true
+	s.mux.Unlock()
+
+	if err := s.player.EnqueueFrom(track, introSkip(s.store, track), priority); err != nil {
+		s.mux.Lock()
+		delete(s.tracks, track)
+		s.mux.Unlock()
+
+		track.Close()
+		err = fmt.Errorf("failed to queue track: %w", err)
+		s.bus.Publish(events.Error{Err: err, Track: track})
+		return nil, err
+	}
+
+	if !now {
+		message := fmt.Sprintf("Queued %s by %s", track.Title, track.Artist)
+		if priority == player.PriorityNext {
+			message = fmt.Sprintf("Playing %s by %s next", track.Title, track.Artist)
+		}
+
+		s.bus.Publish(events.Notice{Message: message})
+		return track, nil
+	}
+
+	// If nothing was playing, the track started right away instead of being queued
+	for i, queuedTrack := range s.player.Queue() {
+		if queuedTrack == track {
+			if err := s.player.JumpTo(i); err != nil {
+				err = fmt.Errorf("failed to play track: %w", err)
+				s.bus.Publish(events.Error{Err: err, Track: track})
+				return nil, err
+			}
+
+			return track, nil
+		}
+	}
+
+	return track, nil
+}
+
+// queued returns true if track is waiting in the queue of tp
+func queued(tp *player.TrackPlayer, track *chipmusic.Track) bool {
+	for _, t := range tp.Queue() {
+		if t == track {
+			return true
+		}
+	}
+
+	return false
+}
+
+// wait blocks until the player is done playing every queued track and the tracks are recorded in the listening history
+func (s *session) wait() {
+	<-s.player.Done()
+
+	// The player reports the last track finishing before Done is closed, so flushing its events handles it
+	flushed := make(chan struct{})
+	s.flush <- flushed
+	<-flushed
+}
+
+// watchPlayer reacts to the player starting and finishing the tracks queued by the session until ctx is done. Tracks
+// are announced when they start and recorded in the listening history when they finish, while idents and jingles are
+// only played. Errors of tracks failing while they play are published
+func (s *session) watchPlayer(ctx context.Context) {
+	cancelTrack := func() {}
+	defer func() { cancelTrack() }()
+
+	var playedAt time.Time
+	var length time.Duration
+	handle := func(event player.PlayerEvent) {
+		switch event := event.(type) {
+		case player.TrackStarted:
+			cancelTrack()
+			if !s.isTrack(event.Track) {
+				cancelTrack = func() {}
+				return
+			}
+
+			var trackCtx context.Context
+			trackCtx, cancelTrack = context.WithCancel(ctx)
+			playedAt, length = time.Now(), event.Length
+			s.bus.Publish(events.PlaybackStarted{Track: event.Track})
+			s.recordCooldown(event.Track, playedAt)
+
+			go handleTrackTimer(trackCtx, s.player, s.dashboard)
+			go s.fadeOutLongTrack(trackCtx, event.Length)
+		case player.Finished:
+			cancelTrack()
+			if s.isTrack(event.Track) {
+				listened := listenedTime(playedAt, length)
+				s.bus.Publish(events.PlaybackFinished{Track: event.Track, Listened: listened})
+				s.recordHistory(event.Track, playedAt, listened)
+			}
+		case player.QueueChanged:
+			queue, startsIn := s.sessionTracks(event.Queue, event.Lengths)
+			s.bus.Publish(events.QueueChanged{Queue: queue, StartsIn: startsIn})
+		case player.Error:
+			s.bus.Publish(events.Error{Err: event.Err})
+		case player.PriorityRaised:
+			if event.Err != nil {
+				s.bus.Publish(events.Error{Err: event.Err})
+			}
+		}
+
+		// Wake up enqueue so it can check whether its track started
+		select {
+		case s.progress <- struct{}{}:
+		default:
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-s.player.Events():
+			handle(event)
+		case flushed := <-s.flush:
+			for len(s.player.Events()) > 0 {
+				handle(<-s.player.Events())
+			}
+
+			close(flushed)
+		}
+	}
+}
+
+// sessionTracks returns the tracks among tracks which were queued by the session, leaving out idents and jingles, along
+// with how long after the current track ends each of them starts. lengths are the lengths of tracks
+func (s *session) sessionTracks(tracks []*chipmusic.Track, lengths []time.Duration) ([]*chipmusic.Track, []time.Duration) {
+	var queued []*chipmusic.Track
+	var startsIn []time.Duration
+	var elapsed time.Duration
+	for i, track := range tracks {
+		if s.isTrack(track) {
+			queued = append(queued, track)
+			startsIn = append(startsIn, elapsed)
+		}
+
+		if i < len(lengths) {
+			elapsed += lengths[i]
+		}
+	}
+
+	return queued, startsIn
+}
+
+// isTrack returns true if track was queued by the session rather than being an ident or a jingle
+func (s *session) isTrack(track *chipmusic.Track) bool {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return track != nil && s.tracks[track]
+}
+
+// recordHistory records that track finished playing in the listening history after being listened to for listened
+func (s *session) recordHistory(track *chipmusic.Track, playedAt time.Time, listened time.Duration) {
+	s.mux.Lock()
+	delete(s.tracks, track)
+	s.mux.Unlock()
+
+	entry := store.HistoryEntry{URL: track.PageURL, Title: track.Title, Artist: track.Artist, PlayedAt: playedAt, Listened: listened}
+	if err := s.store.AddHistory(entry); err != nil {
+		s.bus.Publish(events.Error{Err: fmt.Errorf("failed to record listening history: %w", err), Track: track})
+	}
+}
+
+// listenedTime returns how long a track of length which started playing at playedAt was listened to. Pausing the track
+// counts as listening, so the time listened is capped at the length of the track
+func listenedTime(playedAt time.Time, length time.Duration) time.Duration {
+	listened := time.Since(playedAt)
+	if length > 0 && listened > length {
+		listened = length
+	}
+
+	return listened
+}
@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/broar/chipmusic-cli/pkg/chipmusic"
+	"github.com/broar/chipmusic-cli/pkg/shuffle"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"math/rand"
+	"time"
+)
+
+const (
+	// radioRelatedArtists is how many of the artists the user likes most are searched for tracks in each round
+	radioRelatedArtists = 5
+)
+
+var radioCmd = &cobra.Command{
+	Use:   "radio",
+	Short: "Play an ongoing station around an artist and the artists you like",
+	Long: `Play an ongoing station around an artist and the artists you like.
+
+The station blends the catalog of the artist given with --like and tracks by the artists you like most, newest first
+and a page of each at a time. Artists count as liked for each of their tracks you marked as a favorite or listened to
+for at least --liked-listen, so tracks skipped early don't count. Tracks by artists you like which are in your
+listening history are left out. Once every page was played, the station starts over. It plays until it is
+interrupted.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		like, _ := cmd.Flags().GetString("like")
+		artistShare, _ := cmd.Flags().GetFloat64("artist-share")
+		likedShare, _ := cmd.Flags().GetFloat64("liked-share")
+		likedListen, _ := cmd.Flags().GetDuration("liked-listen")
+		if err := playRadio(artistName(like), artistShare, likedShare, likedListen); err != nil {
+			panic(err)
+		}
+	},
+	Args: cobra.NoArgs,
+}
+
+func init() {
+	rootCmd.AddCommand(radioCmd)
+	radioCmd.Flags().String("like", "", "artist the station is built around, given as a name or the URL of their profile page")
+	radioCmd.Flags().Float64("artist-share", 0.5, "share of the station taken from the catalog of the artist")
+	radioCmd.Flags().Float64("liked-share", 0.5, "share of the station taken from tracks by the artists you like")
+	radioCmd.Flags().Duration("liked-listen", shuffle.DefaultLikedListen, "how long a track in the listening history must have been listened to for its artist to count as liked")
+
+	if err := radioCmd.MarkFlagRequired("like"); err != nil {
+		panic(fmt.Errorf("failed to mark flag as required: %w", err))
+	}
+}
+
+func playRadio(like string, artistShare, likedShare float64, likedListen time.Duration) error {
+	s, err := newSession()
+	if err != nil {
+		return err
+	}
+
+	defer s.close()
+
+	if err := s.limitTrackLength(viper.GetDuration("max-track-length"), viper.GetString("max-track-action")); err != nil {
+		return err
+	}
+
+	if err := s.artistCooldown(viper.GetInt("artist-cooldown-tracks"), viper.GetDuration("artist-cooldown"), like); err != nil {
+		return err
+	}
+
+	if err := s.fillGaps(viper.GetDuration("gap"), viper.GetString("ident-dir"), viper.GetInt("ident-every")); err != nil {
+		return err
+	}
+
+	history, err := s.store.History(0)
+	if err != nil {
+		return fmt.Errorf("failed to get listening history: %w", err)
+	}
+
+	favorites, err := s.store.Favorites()
+	if err != nil {
+		return fmt.Errorf("failed to get favorites: %w", err)
+	}
+
+	liked := shuffle.LikedArtists(history, favorites, like, likedListen)
+	if len(liked) > radioRelatedArtists {
+		liked = liked[:radioRelatedArtists]
+	}
+
+	listened := map[string]bool{}
+	for _, entry := range history {
+		listened[entry.URL] = true
+	}
+
+	random := newRandom(time.Now())
+	played := map[string]bool{}
+	started := false
+	for page := 1; ; page++ {
+		sources, err := radioSources(s.client, like, liked, page, artistShare, likedShare, listened, played, random)
+		if err != nil {
+			return err
+		}
+
+		tracks := shuffle.Mix(sources, random)
+		if len(tracks) == 0 {
+			if page == 1 && !started {
+				return fmt.Errorf("no tracks found by %s", like)
+			} else if page == 1 {
+				break
+			}
+
+			// Every page was played, so the station starts over from the newest tracks
+			played = map[string]bool{}
+			page = 0
+			continue
+		}
+
+		if !started {
+			s.start()
+			s.dashboard.UpdateNotice(fmt.Sprintf("Radio like %s", like))
+			started = true
+		}
+
+		for _, trackURL := range tracks {
+			played[trackURL] = true
+		}
+
+		if err := playTracks(s.dropUnreachable(tracks), s); errors.Is(err, errMaxTotalSize) {
+			break
+		} else if err != nil {
+			return fmt.Errorf("failed to play tracks: %w", err)
+		}
+	}
+
+	s.wait()
+	return nil
+}
+
+// radioSources gathers the tracks of a round of the station: the page of the catalog of like, and the pages of the
+// tracks by the liked artists which aren't in the listening history. Tracks already played by the station are left out
+func radioSources(client *chipmusic.Client, like string, liked []string, page int, artistShare, likedShare float64, listened, played map[string]bool, random *rand.Rand) ([]shuffle.MixSource, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	results, err := client.Search(ctx, chipmusic.SearchOptions{Query: chipmusic.ArtistQuery(like), Filter: chipmusic.TrackFilterLatest, Page: page})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search for tracks by %s: %w", like, err)
+	}
+
+	related, err := artistTracks(client, liked, page)
+	if err != nil {
+		return nil, err
+	}
+
+	sources := []shuffle.MixSource{
+		{Name: "artist", Tracks: unplayed(chipmusic.SearchResultURLs(results), played), Proportion: artistShare},
+		{Name: "liked", Tracks: unplayed(unplayed(related, listened), played), Proportion: likedShare},
+	}
+
+	for _, source := range sources {
+		random.Shuffle(len(source.Tracks), func(i, j int) {
+			source.Tracks[i], source.Tracks[j] = source.Tracks[j], source.Tracks[i]
+		})
+	}
+
+	return sources, nil
+}
@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/broar/chipmusic-cli/pkg/chipmusic"
+	"github.com/broar/chipmusic-cli/pkg/player"
+	"github.com/broar/chipmusic-cli/pkg/player/chip"
+	"github.com/faiface/beep"
+	"github.com/spf13/cobra"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var renderCmd = &cobra.Command{
+	Use:   "render file",
+	Short: "Render a chiptune or tracker module to a WAV file",
+	Long: `Render an NSF, SPC, or SID chiptune, or any other format the player can decode such as a tracker module, to a
+WAV file. Rendering is done offline as fast as the sound chip can be emulated, e.g. to prepare an upload.
+
+Chiptunes often hold many songs, of which --track selects the one rendered. Chiptunes don't say how long songs are,
+so they play for a length which depends on the format and then fade out, or end once they fall silent. Use --length
+to change when they start fading out.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		out, _ := cmd.Flags().GetString("out")
+		track, _ := cmd.Flags().GetInt("track")
+		length, _ := cmd.Flags().GetDuration("length")
+		if err := render(args[0], out, track, length); err != nil {
+			panic(err)
+		}
+	},
+	Args: cobra.ExactArgs(1),
+}
+
+func init() {
+	rootCmd.AddCommand(renderCmd)
+	renderCmd.Flags().Int("track", 0, "song of a chiptune to render, counting from 1 (default is the song the chiptune starts with)")
+	renderCmd.Flags().String("out", "", "path of the WAV file to write (default is the input file with a .wav extension)")
+	renderCmd.Flags().Duration("length", 0, "how long chiptunes play before fading out (default depends on the format)")
+}
+
+func render(input, out string, track int, length time.Duration) error {
+	if out == "" {
+		out = strings.TrimSuffix(input, filepath.Ext(input)) + ".wav"
+	}
+
+	switch chipmusic.FileTypeFromPath(out) {
+	case chipmusic.AudioFileTypeWAV:
+	case chipmusic.AudioFileTypeMP3:
+		return errors.New("rendering to MP3 isn't supported, so render to WAV and encode it with an MP3 encoder such as LAME")
+	default:
+		return fmt.Errorf("output file %s must be a WAV file", out)
+	}
+
+	stream, format, description, err := decodeForRender(input, track, length)
+	if err != nil {
+		return err
+	}
+
+	defer stream.Close()
+
+	f, err := os.Create(out)
+	if err != nil {
+		return fmt.Errorf("failed to create output file %s: %w", out, err)
+	}
+
+	if err := player.RenderWAV(f, stream, format); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to render %s: %w", input, err)
+	}
+
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write output file %s: %w", out, err)
+	}
+
+	rendered := format.SampleRate.D(stream.Position()).Round(time.Second)
+	fmt.Printf("Rendered %s (%s) to %s\n", description, rendered, out)
+	return nil
+}
+
+// decodeForRender decodes the file at path along with a description of what is rendered. Chiptunes are loaded directly
+// so their song and length can be chosen, while every other format is decoded like a track
+func decodeForRender(path string, track int, length time.Duration) (beep.StreamSeekCloser, beep.Format, string, error) {
+	fileType := chipmusic.FileTypeFromPath(path)
+	switch fileType {
+	case chipmusic.AudioFileTypeNSF, chipmusic.AudioFileTypeSPC, chipmusic.AudioFileTypeSID:
+		return decodeChiptuneForRender(path, track, length)
+	}
+
+	if track != 0 {
+		return nil, beep.Format{}, "", fmt.Errorf("only chiptunes hold more than one song, but %s is %s", path, fileType)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, beep.Format{}, "", fmt.Errorf("failed to open %s: %w", path, err)
+	}
+
+	stream, format, err := player.Decode(&chipmusic.Track{Reader: file, FileType: fileType})
+	if err != nil {
+		file.Close()
+		return nil, beep.Format{}, "", fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+
+	return stream, format, filepath.Base(path), nil
+}
+
+// decodeChiptuneForRender loads the chiptune at path and selects the song and length to render. A song or length of 0
+// keeps the default of the chiptune
+func decodeChiptuneForRender(path string, song int, length time.Duration) (beep.StreamSeekCloser, beep.Format, string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, beep.Format{}, "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	tune, err := chip.Load(data)
+	if err != nil {
+		return nil, beep.Format{}, "", fmt.Errorf("failed to load chiptune %s: %w", path, err)
+	}
+
+	if song != 0 {
+		if err := tune.SelectSong(song); err != nil {
+			return nil, beep.Format{}, "", err
+		}
+	}
+
+	if length > 0 {
+		tune.Length = length
+	}
+
+	title := tune.Title
+	if title == "" {
+		title = filepath.Base(path)
+	}
+
+	if tune.Artist != "" {
+		title += " by " + tune.Artist
+	}
+
+	description := fmt.Sprintf("song %d of %d of %s", tune.Song, tune.Songs, title)
+	format := beep.Format{SampleRate: beep.SampleRate(tune.SampleRate), NumChannels: 2, Precision: 2}
+	return chip.NewStream(tune), format, description, nil
+}
@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/broar/chipmusic-cli/pkg/dashboard"
+	"github.com/broar/chipmusic-cli/pkg/events"
+	"github.com/spf13/cobra"
+	"os"
+	"time"
+)
+
+var replayCmd = &cobra.Command{
+	Use:   "replay file",
+	Short: "Replay a session recorded with --record",
+	Long: `Replay a session recorded with --record. Every track played in the session is played again and every track
+control is applied at the same point in the track it was originally used, which is useful for reproducing bugs.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := replay(args[0]); err != nil {
+			panic(err)
+		}
+	},
+	Args: cobra.ExactArgs(1),
+}
+
+func init() {
+	rootCmd.AddCommand(replayCmd)
+}
+
+// replayedTrack is a track played during a recorded session along with the track controls used while it was playing
+type replayedTrack struct {
+	url     string
+	actions []replayedAction
+}
+
+// replayedAction is a track control along with how long after the start of the track it was used
+type replayedAction struct {
+	action string
+	after  time.Duration
+}
+
+func replay(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open session file %s: %w", path, err)
+	}
+
+	records, err := events.ReadRecords(f)
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("failed to read session file %s: %w", path, err)
+	}
+
+	tracks := replayedTracks(records)
+	if len(tracks) == 0 {
+		return fmt.Errorf("session file %s does not contain any played tracks", path)
+	}
+
+	s, err := newSession()
+	if err != nil {
+		return err
+	}
+
+	defer s.close()
+
+	s.start()
+
+	for _, rt := range tracks {
+		if err := replayTrack(s, rt); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// replayedTracks groups the track controls in records by the track which was playing when they were used
+func replayedTracks(records []events.Record) []replayedTrack {
+	tracks := make([]replayedTrack, 0)
+	var startedAt time.Time
+	for _, record := range records {
+		switch record.Name {
+		case events.NamePlaybackStarted:
+			tracks = append(tracks, replayedTrack{url: record.URL})
+			startedAt = record.Time
+		case events.NameActionPerformed:
+			if len(tracks) == 0 {
+				continue
+			}
+
+			current := &tracks[len(tracks)-1]
+			current.actions = append(current.actions, replayedAction{action: record.Action, after: record.Time.Sub(startedAt)})
+		}
+	}
+
+	return tracks
+}
+
+func replayTrack(s *session, rt replayedTrack) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	track, err := s.client.GetTrack(ctx, rt.url)
+	if err != nil {
+		return fmt.Errorf("failed to download track %s: %w", rt.url, err)
+	}
+
+	s.bus.Publish(events.TrackResolved{Track: track})
+
+	// Track controls are scheduled when playback starts so they line up with the original session
+	unsubscribe := scheduleActions(s, rt.actions)
+	defer unsubscribe()
+
+	if err := s.play(track); err != nil {
+		return fmt.Errorf("failed to play track %s: %w", track.Title, err)
+	}
+
+	return nil
+}
+
+// scheduleActions applies actions relative to the next PlaybackStarted event. The returned function cancels any action
+// which has not been applied yet
+func scheduleActions(s *session, actions []replayedAction) func() {
+	ch, unsubscribe := s.bus.Subscribe(0, events.NamePlaybackStarted)
+	timers := make(chan []*time.Timer, 1)
+	go func() {
+		scheduled := make([]*time.Timer, 0, len(actions))
+		defer func() { timers <- scheduled }()
+
+		for event := range ch {
+			if _, ok := event.(events.PlaybackStarted); !ok {
+				continue
+			}
+
+			for _, a := range actions {
+				recorded := a.action
+				scheduled = append(scheduled, time.AfterFunc(a.after, func() {
+					action, err := dashboard.ParseAction(recorded)
+					if err != nil {
+						s.bus.Publish(events.Error{Err: fmt.Errorf("failed to replay track control %q: %w", recorded, err)})
+						return
+					}
+
+					s.bus.Publish(events.ActionPerformed{Action: recorded})
+					if err := applyTrackControl(action, s.player); err != nil {
+						s.bus.Publish(events.Error{Err: fmt.Errorf("failed to replay track control %v: %w", action, err)})
+					}
+				}))
+			}
+
+			return
+		}
+	}()
+
+	return func() {
+		unsubscribe()
+		for _, timer := range <-timers {
+			timer.Stop()
+		}
+	}
+}
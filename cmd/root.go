@@ -2,10 +2,18 @@ package cmd
 
 import (
 	"fmt"
+	"github.com/broar/chipmusic-cli/pkg/chipmusic"
+	"github.com/broar/chipmusic-cli/pkg/dashboard"
+	"github.com/broar/chipmusic-cli/pkg/integrations/bot"
+	"github.com/broar/chipmusic-cli/pkg/integrations/hooks"
+	"github.com/broar/chipmusic-cli/pkg/integrations/overlay"
+	"github.com/broar/chipmusic-cli/pkg/output"
+	"github.com/broar/chipmusic-cli/pkg/player"
 	"github.com/mitchellh/go-homedir"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"os"
+	"strings"
 )
 
 var cfgFile string
@@ -25,7 +33,75 @@ func Execute() {
 func init() {
 	cobra.OnInitialize(initConfig)
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.chipmusic.yaml)")
+	rootCmd.PersistentFlags().String("data-dir", "", "directory for local state (default is $HOME/.chipmusic)")
+	rootCmd.PersistentFlags().Int("volume", player.DefaultVolume, "volume to play tracks at, from 0 to 100 (default is the last volume used). Use + and - to change it while playing")
+	rootCmd.PersistentFlags().Bool("jingles", false, "play a short chiptune jingle when starting and finishing")
+	rootCmd.PersistentFlags().Bool("crossfeed", false, "blend a portion of each channel into the other for headphone listening")
+	rootCmd.PersistentFlags().Bool("normalize", false, "continuously adjust the gain of tracks so quiet and loud uploads play at a similar loudness. Press N to toggle it while playing")
+	rootCmd.PersistentFlags().String("theme", dashboard.ThemeDefault, "colors of the dashboard. Colors of the theme can be replaced in the theme-colors section of the config file. Allowed themes: ["+strings.Join(dashboard.ThemeNames(), ", ")+"]")
+	rootCmd.PersistentFlags().Duration("toast-duration", dashboard.DefaultToastDuration, "how long errors and notices are shown in the dashboard")
+	rootCmd.PersistentFlags().Int("log-lines", dashboard.DefaultLogLines, "how many of the latest errors, skips, and retries the log pane of the dashboard shows. Press L to show or hide it")
+	rootCmd.PersistentFlags().String("render-to", "", "render tracks to this WAV file instead of playing them on the speaker, e.g. to convert tracker modules or archive a shuffle. Tracks are rendered as fast as they decode")
+	rootCmd.PersistentFlags().Bool("realtime-audio", false, "raise the priority of the audio thread where the system allows it, to reduce buffer underruns on loaded systems. Run doctor to check whether it can be raised")
+	rootCmd.PersistentFlags().String("trace-audio", "", "log buffer fill levels, decode timings, and underruns to this file")
+	rootCmd.PersistentFlags().String("spool-dir", "", "directory where tracks are spooled while downloading (default is the system temporary directory)")
+	rootCmd.PersistentFlags().Bool("stream", false, "stream tracks with ranged requests instead of downloading them before playback")
+	rootCmd.PersistentFlags().String("record", "", "record searches, tracks, and track controls to this session file so the session can be replayed")
+	rootCmd.PersistentFlags().Bool("follow-device", false, "move playback to the default audio device when it changes, e.g. when headphones are plugged in")
+	rootCmd.PersistentFlags().Duration("idle-pause", 0, "pause playback once no track control was used and the screen was locked or the keyboard and mouse were idle for this long, e.g. 30m. Use 0 to keep playing")
+	rootCmd.PersistentFlags().Bool("idle-resume", true, "resume playback paused by --idle-pause when the keyboard or mouse is used again")
+	rootCmd.PersistentFlags().Bool("dedupe", false, "skip tracks whose audio matches a track already played, e.g. a song uploaded both as a single and in a release")
+	rootCmd.PersistentFlags().Bool("sfw", false, "skip tracks whose title or tags contain explicit markers, e.g. when streaming on public channels")
+	rootCmd.PersistentFlags().StringSlice("blocklist", nil, "skip tracks whose title or tags contain any of these terms")
+	rootCmd.PersistentFlags().Duration("max-track-length", 0, "in shuffles and mixes, skip or fade out tracks longer than this, e.g. 8m")
+	rootCmd.PersistentFlags().String("max-track-action", maxTrackActionFade, "what happens to tracks longer than the max track length. Allowed actions: [fade, skip]")
+	rootCmd.PersistentFlags().Int("artist-cooldown-tracks", 0, "in shuffles, mixes, and radio, don't play an artist again within this many tracks. Use 0 to disable it")
+	rootCmd.PersistentFlags().Duration("artist-cooldown", 0, "in shuffles, mixes, and radio, don't play an artist again within this long, e.g. 30m. Use 0 to disable it")
+	rootCmd.PersistentFlags().Duration("gap", 0, "in shuffles and mixes, wait this long between tracks, e.g. 2s")
+	rootCmd.PersistentFlags().Int64("max-total-size", 0, "in downloads, shuffles, and mixes, stop before the tracks downloaded add up to more than this many megabytes. Use 0 to disable the limit")
+	rootCmd.PersistentFlags().Int("prefetch", chipmusic.DefaultPrefetch, "in shuffles, download this many tracks ahead of the next track in the background so slow downloads don't leave silence between tracks")
+	rootCmd.PersistentFlags().Bool("check-links", false, "in shuffles, check every page of tracks for removed track pages and audio files before queueing them and drop the dead links, instead of failing midway through playback")
+	rootCmd.PersistentFlags().Int64("seed", 0, "seed the order of shuffles and mixes so they can be reproduced. Tracks picked by chipmusic.org, e.g. with the random filter, can still differ. Use 0 for a new order every time")
+	rootCmd.PersistentFlags().String("ident-dir", "", "in shuffles and mixes, play a random station ident from this directory of audio clips between tracks")
+	rootCmd.PersistentFlags().Int("ident-every", 1, "play a station ident after every this many tracks, with the gap between the others")
+	rootCmd.PersistentFlags().Bool("data-saver", false, "minimize network usage on metered or tethered connections by downloading with fewer concurrent requests, prefetching at most 1 track, and playing cached tracks without checking them for changes. Artwork is never downloaded either way")
+	rootCmd.PersistentFlags().Int("max-conns-per-host", chipmusic.DefaultWorkers, "maximum requests in flight to each host, shared by every download. Use 0 to disable the limit")
+	rootCmd.PersistentFlags().Float64("rate-limit", 2, "maximum requests per second sent to each host. Use 0 to disable the limit")
+	rootCmd.PersistentFlags().Int("rate-burst", chipmusic.DefaultRateBurst, "number of requests which can be sent to a host at once before the rate limit applies")
+	rootCmd.PersistentFlags().StringSlice("dns-servers", nil, "resolve hosts with these DNS servers instead of the system resolver, e.g. 1.1.1.1,8.8.8.8")
+	rootCmd.PersistentFlags().String("ip-version", "", "only connect over this IP version. Allowed versions: [ipv4, ipv6]")
+	rootCmd.PersistentFlags().Duration("dial-timeout", chipmusic.DefaultDialTimeout, "how long connecting to a host may take. Use 0 to disable the timeout")
+	rootCmd.PersistentFlags().Duration("tls-handshake-timeout", chipmusic.DefaultTLSHandshakeTimeout, "how long the TLS handshake with a host may take. Use 0 to disable the timeout")
+	rootCmd.PersistentFlags().Duration("response-header-timeout", chipmusic.DefaultResponseHeaderTimeout, "how long a host may take to start responding to a request. Use 0 to disable the timeout")
+	rootCmd.PersistentFlags().Int("chunk-retries", chipmusic.DefaultChunkRetries, "how many times a failed chunk of a track is retried before the track is downloaded with a single request instead")
+	rootCmd.PersistentFlags().Duration("download-deadline", chipmusic.DefaultDownloadDeadline, "how long downloading a track may take as a whole, including retries. Use 0 to disable the deadline")
+	rootCmd.PersistentFlags().String("tls-ca-file", "", "also trust the CA certificates in this PEM file, e.g. the CA of a corporate interception proxy")
+	rootCmd.PersistentFlags().String("tls-min-version", "", "minimum TLS version to connect with. Allowed versions: [1.0, 1.1, 1.2, 1.3]")
+	rootCmd.PersistentFlags().Bool("tls-insecure-skip-verify", false, "don't verify TLS certificates. Only use this to debug with a proxy")
+	rootCmd.PersistentFlags().String("debug-http", "", "log the headers of every HTTP request and response to this file, with credentials and cookies redacted")
+	rootCmd.PersistentFlags().Bool("debug-http-bodies", false, "also log the bodies of HTML pages to the HTTP debug log")
+	rootCmd.PersistentFlags().String("cache-dir", "", "directory where downloaded tracks are cached (default is the cache directory within the data directory)")
+	rootCmd.PersistentFlags().Int64("cache-size", defaultCacheSizeMB, "maximum size of the track cache in megabytes. Use 0 to disable the cache")
+	rootCmd.PersistentFlags().Duration("metadata-ttl", defaultMetadataTTL, "how long the title, artist, tags, and download URL parsed from a track page are cached in the store before the page is fetched again. Use 0 to disable the cache")
+	rootCmd.PersistentFlags().String("discord-webhook", "", "announce every track played to the Discord channel of this webhook URL")
+	rootCmd.PersistentFlags().String("irc-server", "", "announce every track played on IRC and accept !np and !skip commands, e.g. irc.libera.chat:6697")
+	rootCmd.PersistentFlags().String("irc-channel", "", "IRC channel the bot joins, e.g. #chipmusic")
+	rootCmd.PersistentFlags().String("irc-nick", bot.DefaultNick, "nick the bot uses on IRC")
+	rootCmd.PersistentFlags().Bool("irc-tls", false, "connect to the IRC server over TLS")
+	rootCmd.PersistentFlags().StringSlice("bot-operators", nil, "nicks allowed to skip tracks with !skip")
+	rootCmd.PersistentFlags().String("overlay-addr", "", "serve the current track at /overlay on this address for OBS browser sources, e.g. localhost:8090. Pages which update themselves can stream it from /overlay/events")
+	rootCmd.PersistentFlags().String("overlay-template", "", "html/template file the overlay is rendered with, e.g. to style it or show the tags of the track")
+	rootCmd.PersistentFlags().Duration("overlay-refresh", overlay.DefaultRefresh, "how often the overlay page reloads itself")
+	rootCmd.PersistentFlags().Duration("hook-timeout", hooks.DefaultTimeout, "how long the commands of hooks configured in the hooks section of the config file may run before they are killed")
+	rootCmd.PersistentFlags().Bool("terminal-title", false, "show the track playing as \"♪ Artist – Title\" in the title of the terminal window, e.g. for tmux and window switchers")
+	rootCmd.PersistentFlags().String("control-socket", "", "unix socket the daemon listens on for commands from ctl and queue (default is control.sock in the data directory)")
+	rootCmd.PersistentFlags().String("output", string(output.FormatPlain), "format of what the search, info, download, status, ctl, and queue commands print, e.g. json for scripts. Allowed formats: [plain, json]")
+	rootCmd.PersistentFlags().String("store", "bolt", "storage backend for local state. Allowed backends: [bolt, sqlite, memory]")
 	rootCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+
+	if err := viper.BindPFlags(rootCmd.PersistentFlags()); err != nil {
+		panic(fmt.Errorf("failed to bind flags: %w", err))
+	}
 }
 
 func initConfig() {
@@ -44,7 +120,8 @@ func initConfig() {
 
 	viper.AutomaticEnv()
 
+	// Stdout is kept for the output of commands, which scripts and tmux parse
 	if err := viper.ReadInConfig(); err == nil {
-		fmt.Println("Using config file:", viper.ConfigFileUsed())
+		fmt.Fprintln(os.Stderr, "Using config file:", viper.ConfigFileUsed())
 	}
 }
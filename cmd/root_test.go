@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// executeCommand runs the command line args and returns what it printed to stdout
+func executeCommand(t *testing.T, args ...string) string {
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	printed := make(chan string)
+	go func() {
+		content, _ := ioutil.ReadAll(r)
+		printed <- string(content)
+	}()
+
+	rootCmd.SetArgs(args)
+	err = rootCmd.Execute()
+	require.NoError(t, w.Close())
+
+	output := <-printed
+	require.NoError(t, err)
+	return output
+}
+
+// tempConfig creates a data directory along with a config file and returns the flags which use them
+func tempConfig(t *testing.T) ([]string, string, func()) {
+	dir, err := ioutil.TempDir("", "chipmusic-cmd")
+	require.NoError(t, err)
+
+	config := filepath.Join(dir, "config.yaml")
+	require.NoError(t, ioutil.WriteFile(config, []byte("crossfeed: true\n"), 0600))
+
+	dataDir := filepath.Join(dir, "data")
+	require.NoError(t, os.Mkdir(dataDir, 0700))
+
+	return []string{"--config", config, "--data-dir", dataDir}, dataDir, func() { os.RemoveAll(dir) }
+}
@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/broar/chipmusic-cli/pkg/chipmusic"
+	"github.com/broar/chipmusic-cli/pkg/dashboard"
+	"github.com/broar/chipmusic-cli/pkg/events"
+	"github.com/broar/chipmusic-cli/pkg/output"
+	"github.com/broar/chipmusic-cli/pkg/player"
+	"github.com/spf13/cobra"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+var searchCmd = &cobra.Command{
+	Use:   "search query",
+	Short: "List the tracks on chipmusic.org matching a search",
+	Long: `List the tracks on chipmusic.org matching a search.
+
+The index, title, artist, and URL of each track on a page of results are printed as a table, or as a JSON array with
+--output=json, so they can be piped to other commands, e.g. to play the first result:
+
+	chipmusic play "$(chipmusic search --output=json lsdj | jq -r '.[0].url')"`,
+	Run: func(cmd *cobra.Command, args []string) {
+		filter, _ := cmd.Flags().GetString("filter")
+		page, _ := cmd.Flags().GetInt("page")
+		format, err := outputFormat(cmd)
+		if err != nil {
+			panic(err)
+		}
+
+		if err := printSearchResults(strings.Join(args, " "), chipmusic.TrackFilter(filter), page, format); err != nil {
+			panic(err)
+		}
+	},
+	Args: cobra.MinimumNArgs(1),
+}
+
+func init() {
+	rootCmd.AddCommand(searchCmd)
+	searchCmd.Flags().String("filter", string(chipmusic.TrackFilterLatest), "Set a filter for the search. Allowed filters: [latest, random, featured, popular]")
+	searchCmd.Flags().Int("page", 1, "page of results to list, starting at 1")
+	searchCmd.Flags().Bool("json", false, "print the results as JSON instead of a table, like --output=json")
+}
+
+func printSearchResults(query string, filter chipmusic.TrackFilter, page int, format output.Format) error {
+	switch filter {
+	case chipmusic.TrackFilterLatest, chipmusic.TrackFilterRandom, chipmusic.TrackFilterFeatured, chipmusic.TrackFilterHighRatings:
+	default:
+		return fmt.Errorf("unknown filter %q. Allowed filters: [latest, random, featured, popular]", filter)
+	}
+
+	if page < 1 {
+		return errors.New("page must be a positive integer")
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return fmt.Errorf("failed to create chipmusic client: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	results, err := client.Search(ctx, chipmusic.SearchOptions{Query: query, Filter: filter, Page: page})
+	if err != nil {
+		return fmt.Errorf("failed to search for %q: %w", query, err)
+	}
+
+	if format == output.FormatJSON {
+		if err := output.WriteJSON(os.Stdout, output.NewSearchResults(results)); err != nil {
+			return fmt.Errorf("failed to write search results: %w", err)
+		}
+
+		return nil
+	}
+
+	if len(results) == 0 {
+		fmt.Println("No tracks found")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "#\tTITLE\tARTIST\tURL")
+	for i, result := range results {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", i+1, result.Title, result.Artist, result.URL)
+	}
+
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write search results: %w", err)
+	}
+
+	return nil
+}
+
+// handleSearchActions runs the searches made from the dashboard and queues the search results picked. Every other
+// action is passed on to the returned channel, which is closed once actions is closed
+func (s *session) handleSearchActions(actions <-chan dashboard.Action) <-chan dashboard.Action {
+	others := make(chan dashboard.Action)
+	go func() {
+		defer close(others)
+		for action := range actions {
+			switch action := action.(type) {
+			case dashboard.SearchAction:
+				s.bus.Publish(events.ActionPerformed{Action: action.String()})
+				go s.searchFromDashboard(action.Query, 1)
+			case dashboard.SearchMoreAction:
+				s.bus.Publish(events.ActionPerformed{Action: action.String()})
+				go s.searchFromDashboard(action.Query, action.Page)
+			case dashboard.EnqueueAction:
+				s.bus.Publish(events.ActionPerformed{Action: action.String()})
+				go s.queueTrackPage(action.URL, player.PriorityEnd, false)
+			case dashboard.PlayNextAction:
+				s.bus.Publish(events.ActionPerformed{Action: action.String()})
+				go s.queueTrackPage(action.URL, player.PriorityNext, false)
+			case dashboard.PlayNowAction:
+				s.bus.Publish(events.ActionPerformed{Action: action.String()})
+				go s.queueTrackPage(action.URL, player.PriorityNext, true)
+			default:
+				others <- action
+			}
+		}
+	}()
+
+	return others
+}
+
+// searchFromDashboard searches chipmusic.org for a page of the latest tracks matching query and lists them in the
+// dashboard. The first page replaces the results listed, while later pages are added to them. If a later page fails,
+// it is added as an empty page so the dashboard stops asking for more
+func (s *session) searchFromDashboard(query string, page int) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	options := chipmusic.SearchOptions{Query: query, Filter: chipmusic.TrackFilterLatest, Page: page}
+	results, err := s.client.Search(ctx, options)
+	if err != nil {
+		s.bus.Publish(events.Error{Err: fmt.Errorf("failed to search for %q: %w", query, err)})
+		if page > 1 {
+			s.dashboard.AppendSearchResults(query, page, nil)
+		}
+
+		return
+	}
+
+	s.bus.Publish(events.SearchPerformed{Search: query, Filter: string(options.Filter), Page: page, Results: chipmusic.SearchResultURLs(results)})
+	if page > 1 {
+		s.dashboard.AppendSearchResults(query, page, results)
+		return
+	}
+
+	s.dashboard.ShowSearchResults(results)
+}
@@ -0,0 +1,18 @@
+package cmd
+
+import (
+	"github.com/spf13/viper"
+	"math/rand"
+	"time"
+)
+
+// newRandom returns a source of randomness seeded from --seed, so the order of shuffles and mixes can be reproduced.
+// Without a seed, it is seeded with now
+func newRandom(now time.Time) *rand.Rand {
+	seed := viper.GetInt64("seed")
+	if seed == 0 {
+		seed = now.UnixNano()
+	}
+
+	return rand.New(rand.NewSource(seed))
+}
@@ -0,0 +1,335 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/broar/chipmusic-cli/pkg/chipmusic"
+	"github.com/broar/chipmusic-cli/pkg/dashboard"
+	"github.com/broar/chipmusic-cli/pkg/events"
+	"github.com/broar/chipmusic-cli/pkg/fingerprint"
+	"github.com/broar/chipmusic-cli/pkg/integrations/bot"
+	"github.com/broar/chipmusic-cli/pkg/integrations/hooks"
+	"github.com/broar/chipmusic-cli/pkg/integrations/overlay"
+	"github.com/broar/chipmusic-cli/pkg/integrations/title"
+	"github.com/broar/chipmusic-cli/pkg/player"
+	"github.com/broar/chipmusic-cli/pkg/shuffle"
+	"github.com/broar/chipmusic-cli/pkg/store"
+	"github.com/spf13/viper"
+	"os"
+	"sync"
+	"time"
+)
+
+// session bundles everything needed to play tracks with the dashboard. Components communicate through the event bus
+// instead of calling each other directly
+type session struct {
+	client    *chipmusic.Client
+	store     store.Store
+	player    *player.TrackPlayer
+	dashboard *dashboard.TerminalDashboard
+	bus       *events.Bus
+	closers   []func()
+
+	// bot announces tracks in chat and accepts commands from it. If nil, no chat is configured
+	bot *bot.Bot
+
+	// overlay shows the current track on a page for OBS browser sources. If nil, no overlay is served
+	overlay *overlay.Overlay
+
+	// hooks runs the commands configured by the user when tracks start and finish and when errors happen. If nil, no
+	// hooks are configured
+	hooks *hooks.Hooks
+
+	// title shows the current track in the title of the terminal window. If nil, the title is left alone
+	title *title.Title
+
+	// fingerprints holds the fingerprints of tracks played during the session. If nil, duplicates are not detected
+	fingerprints *fingerprint.Index
+
+	// maxTrackLength is how long a track may play before it is skipped or faded out. If 0, tracks are played in full
+	maxTrackLength time.Duration
+
+	// maxTrackAction is what happens to tracks longer than maxTrackLength, either maxTrackActionSkip or
+	// maxTrackActionFade
+	maxTrackAction string
+
+	// cooldown keeps an artist from playing again too soon after it last played. If nil, artists may play back to back
+	cooldown *shuffle.Cooldown
+
+	// gap is how long the session waits between tracks. If 0, tracks play back to back
+	gap time.Duration
+
+	// idents are played between tracks every identEvery tracks. If nil, no idents are played
+	idents     *player.Idents
+	identEvery int
+
+	// played is the number of tracks played during the session
+	played int
+
+	// maxTotalSize is how many bytes of tracks the session may download before it stops. If 0, there is no limit
+	maxTotalSize int64
+
+	// downloaded is the total size of the tracks queued during the session
+	downloaded int64
+
+	// tracks are the tracks queued by the session which haven't finished playing. Idents and jingles also go through
+	// the player, so tracks tells them apart
+	mux    sync.Mutex
+	tracks map[*chipmusic.Track]bool
+
+	// progress signals enqueue that a track started or finished, and flush asks watchPlayer to handle every pending
+	// player event
+	progress chan struct{}
+	flush    chan chan struct{}
+}
+
+// newSession creates every component of a session. options are applied to the dashboard after the ones configured by
+// the user. Call close to release them when the session is over
+func newSession(options ...dashboard.Option) (*session, error) {
+	s := &session{
+		bus:      events.NewBus(),
+		tracks:   map[*chipmusic.Track]bool{},
+		progress: make(chan struct{}, 1),
+		flush:    make(chan chan struct{}),
+	}
+
+	if viper.GetBool("dedupe") {
+		s.fingerprints = fingerprint.NewIndex()
+	}
+
+	s.closers = append(s.closers, func() { s.bus.Close() })
+
+	if err := s.record(viper.GetString("record")); err != nil {
+		s.close()
+		return nil, err
+	}
+
+	var err error
+	s.store, err = openStore()
+	if err != nil {
+		s.close()
+		return nil, fmt.Errorf("failed to open store: %w", err)
+	}
+
+	s.closers = append(s.closers, func() { s.store.Close() })
+
+	clientOptions := []chipmusic.Option{
+		chipmusic.WithProgressFunc(func(downloadURL string, downloaded, total int64) {
+			s.bus.Publish(events.DownloadProgress{URL: downloadURL, Downloaded: downloaded, Total: total})
+		}),
+		chipmusic.WithRetryFunc(func(downloadURL string, attempt int, err error) {
+			s.bus.Publish(events.Retry{URL: downloadURL, Attempt: attempt, Err: err})
+		}),
+	}
+
+	// Probing finds the size of each track before it is downloaded
+	s.maxTotalSize = maxTotalSize(viper.GetInt64("max-total-size"))
+	if s.maxTotalSize > 0 {
+		clientOptions = append(clientOptions, chipmusic.WithProbing())
+	}
+
+	s.client, err = newClient(append(clientOptions, metadataCacheOptions(s.store)...)...)
+	if err != nil {
+		s.close()
+		return nil, fmt.Errorf("failed to create chipmusic client: %w", err)
+	}
+
+	var closePlayer func()
+	s.player, closePlayer, err = newTrackPlayer(s.store)
+	if err != nil {
+		s.close()
+		return nil, fmt.Errorf("failed to create track player: %w", err)
+	}
+
+	s.closers = append(s.closers, closePlayer)
+
+	keymap, err := dashboard.ParseKeymap(viper.GetStringMapString("keymap"))
+	if err != nil {
+		s.close()
+		return nil, fmt.Errorf("failed to parse keymap: %w", err)
+	}
+
+	theme, err := dashboard.ParseTheme(viper.GetString("theme"), viper.GetStringMapString("theme-colors"))
+	if err != nil {
+		s.close()
+		return nil, fmt.Errorf("failed to parse theme: %w", err)
+	}
+
+	dashboardOptions := []dashboard.Option{
+		dashboard.WithKeymap(keymap),
+		dashboard.WithTheme(theme),
+		dashboard.WithSettings(s.settings),
+		dashboard.WithToastDuration(viper.GetDuration("toast-duration")),
+		dashboard.WithLogLines(viper.GetInt("log-lines")),
+	}
+
+	s.dashboard, err = dashboard.NewTerminalDashboard(append(dashboardOptions, options...)...)
+	if err != nil {
+		s.close()
+		return nil, fmt.Errorf("failed to create terminal dashboard: %w", err)
+	}
+
+	s.closers = append(s.closers, func() { s.dashboard.Close() })
+
+	s.bot, err = newBot()
+	if err != nil {
+		s.close()
+		return nil, fmt.Errorf("failed to create chat bot: %w", err)
+	}
+
+	if s.bot != nil {
+		s.closers = append(s.closers, func() { s.bot.Close() })
+	}
+
+	s.overlay, err = newOverlay()
+	if err != nil {
+		s.close()
+		return nil, fmt.Errorf("failed to create overlay: %w", err)
+	}
+
+	s.hooks, err = newHooks()
+	if err != nil {
+		s.close()
+		return nil, fmt.Errorf("failed to create hooks: %w", err)
+	}
+
+	s.title, err = newTitle()
+	if err != nil {
+		s.close()
+		return nil, fmt.Errorf("failed to create terminal title: %w", err)
+	}
+
+	if s.title != nil {
+		s.closers = append(s.closers, func() { s.title.Close() })
+	}
+
+	return s, nil
+}
+
+// record writes every event published during the session to the session file at path so the session can be replayed
+// later. If path is empty, nothing is recorded
+func (s *session) record(path string) error {
+	if path == "" {
+		return nil
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create session file %s: %w", path, err)
+	}
+
+	// Every event is recorded, even during bursts such as the progress of prefetched downloads, so replays match
+	ch, unsubscribe := s.bus.SubscribeAll()
+	errs := events.NewRecorder(f).RecordAll(ch)
+	s.closers = append(s.closers, func() {
+		unsubscribe()
+		for err := range errs {
+			fmt.Printf("failed to record session: %v\n", err)
+		}
+
+		f.Close()
+	})
+
+	return nil
+}
+
+// start starts the dashboard and the goroutines reacting to track controls and events
+func (s *session) start() {
+	actions := s.dashboard.Actions()
+	go func() {
+		if err := s.dashboard.Start(); err != nil {
+			panic(err)
+		}
+	}()
+
+	go handleTrackControlActions(s.handleSearchActions(actions), s.player, s.bus)
+
+	// The dashboard shows the progress of downloads, which is published often enough to fill any buffer, so it
+	// receives every event to never miss a track or volume change. It receives until the bus is closed
+	dashboardEvents, _ := s.bus.SubscribeAll()
+	go s.dashboard.HandleEvents(dashboardEvents)
+
+	volumeEvents, _ := s.bus.Subscribe(0, events.NameVolumeChanged)
+	go saveVolume(volumeEvents, s.store, s.bus)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.closers = append(s.closers, cancel)
+	go s.watchPlayback(ctx)
+	go s.watchPlayer(ctx)
+	go s.writeStatus(ctx)
+
+	if viper.GetBool("follow-device") {
+		go s.followDevice(ctx)
+	}
+
+	if timeout := viper.GetDuration("idle-pause"); timeout > 0 {
+		go s.pauseWhenIdle(ctx, timeout, viper.GetBool("idle-resume"))
+	}
+
+	if s.bot != nil {
+		go s.runBot(ctx)
+	}
+
+	if s.overlay != nil {
+		go s.runOverlay(ctx)
+	}
+
+	if s.hooks != nil {
+		go s.runHooks(ctx)
+	}
+
+	if s.title != nil {
+		go s.runTitle(ctx)
+	}
+
+	if viper.GetBool("jingles") {
+		s.playJingles()
+	}
+}
+
+// play plays a track after any queued tracks, blocks until it is done playing, and records it in the listening history
+func (s *session) play(track *chipmusic.Track) error {
+	if err := s.enqueue(track); err != nil {
+		return err
+	}
+
+	s.wait()
+	return nil
+}
+
+// skip reports that a track was skipped because of err
+func (s *session) skip(track *chipmusic.Track, err error) {
+	s.bus.Publish(events.TrackSkipped{Track: track, Reason: err})
+}
+
+// settings returns the current settings of the session, which the help overlay of the dashboard lists
+func (s *session) settings() []dashboard.Setting {
+	idlePause := "off"
+	if timeout := viper.GetDuration("idle-pause"); timeout > 0 {
+		idlePause = timeout.String()
+	}
+
+	return []dashboard.Setting{
+		{Name: "Volume", Value: fmt.Sprintf("%d%%", s.player.Volume())},
+		{Name: "Crossfeed", Value: onOff(s.player.CrossfeedEnabled())},
+		{Name: "Normalize", Value: onOff(s.player.NormalizationEnabled())},
+		{Name: "Theme", Value: viper.GetString("theme")},
+		{Name: "Idle pause", Value: idlePause},
+	}
+}
+
+// onOff describes a setting which is turned on or off
+func onOff(enabled bool) string {
+	if enabled {
+		return "on"
+	}
+
+	return "off"
+}
+
+// close releases every component of the session in the reverse order they were created
+func (s *session) close() {
+	for i := len(s.closers) - 1; i >= 0; i-- {
+		s.closers[i]()
+	}
+}
@@ -5,10 +5,13 @@ import (
 	"errors"
 	"fmt"
 	"github.com/broar/chipmusic-cli/pkg/chipmusic"
-	"github.com/broar/chipmusic-cli/pkg/dashboard"
+	"github.com/broar/chipmusic-cli/pkg/events"
 	"github.com/broar/chipmusic-cli/pkg/player"
+	"github.com/broar/chipmusic-cli/pkg/shuffle"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"strings"
+	"time"
 )
 
 // shuffleCmd represents the shuffle command
@@ -16,7 +19,7 @@ var shuffleCmd = &cobra.Command{
 	Use:   "shuffle",
 	Short: "Play a shuffle of songs from chipmusic.org",
 	Run: func(cmd *cobra.Command, args []string) {
-		if err := shuffle(); err != nil {
+		if err := playShuffle(); err != nil {
 			panic(err)
 		}
 	},
@@ -26,93 +29,220 @@ func init() {
 	rootCmd.AddCommand(shuffleCmd)
 	shuffleCmd.Flags().String("search", "", "Add search text to the shuffle to limit results")
 	shuffleCmd.Flags().String("filter", "", "Set a filter for the shuffle. Allowed filters: [latest, random, featured, popular]")
+	shuffleCmd.Flags().StringSlice("format", nil, "Only play tracks made with these formats, e.g. lsdj, 2a03, or sid")
+	shuffleCmd.Flags().String("artist", "", "Only play tracks by this artist, given as a name or the URL of their profile page")
+	shuffleCmd.Flags().StringSlice("tag", nil, "Only play tracks with these tags")
+	shuffleCmd.Flags().Bool("fresh", false, "Only play tracks posted since the last fresh shuffle with the same search")
+	shuffleCmd.Flags().String("strategy", shuffle.StrategyPureRandom, "Set how the tracks of each page are ordered using listening history. Allowed strategies: [balanced, pure-random, discovery]")
 
 	if err := viper.BindPFlags(shuffleCmd.Flags()); err != nil {
 		panic(fmt.Errorf("failed to bind flags: %w", err))
 	}
 }
 
-func shuffle() error {
-	client, err := chipmusic.NewClient()
+func playShuffle() error {
+	s, err := newSession()
 	if err != nil {
-		return fmt.Errorf("failed to create chipmusic client: %w", err)
+		return err
 	}
 
-	tp, err := player.NewTrackPlayer()
+	defer s.close()
+
+	shuffler, err := newShuffler(s)
 	if err != nil {
-		return fmt.Errorf("failed to create track player: %w", err)
+		return err
 	}
 
-	defer tp.Close()
+	if err := s.limitTrackLength(viper.GetDuration("max-track-length"), viper.GetString("max-track-action")); err != nil {
+		return err
+	}
 
-	db, err := dashboard.NewTerminalDashboard()
-	if err != nil {
-		return fmt.Errorf("failed to create terminal dashboard: %w", err)
+	// Every track of a shuffle of an artist is by them, so only the other artists featured are cooled down
+	if err := s.artistCooldown(viper.GetInt("artist-cooldown-tracks"), viper.GetDuration("artist-cooldown"), artistName(viper.GetString("artist"))); err != nil {
+		return err
 	}
 
-	defer db.Close()
+	if err := s.fillGaps(viper.GetDuration("gap"), viper.GetString("ident-dir"), viper.GetInt("ident-every")); err != nil {
+		return err
+	}
 
-	actions := db.Actions()
-	go func() {
-		if err := db.Start(); err != nil {
-			panic(err)
-		}
-	}()
+	s.start()
 
-	go handleTrackControlActions(actions, tp)
+	if viper.GetBool("fresh") {
+		return shuffleFresh(s, shuffler)
+	}
 
-	var tracks []string
-	page := 1
-	for {
-		err, done := getAndPlayTracks(tracks, page, client, tp, db)
-		if err != nil {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	options := shuffleSearchOptions()
+	it := s.client.SearchIterator(ctx, options)
+	for it.Next() {
+		tracks := chipmusic.SearchResultURLs(it.Results())
+		s.bus.Publish(events.SearchPerformed{Search: options.Query, Filter: string(options.Filter), Page: it.Page(), Results: tracks})
+
+		if err := playTracks(shuffler.Shuffle(s.dropUnreachable(tracks)), s); errors.Is(err, errMaxTotalSize) {
+			break
+		} else if err != nil {
 			return fmt.Errorf("failed to play tracks: %w", err)
 		}
+	}
 
-		if done {
-			return nil
-		}
+	if err := it.Err(); err != nil {
+		return fmt.Errorf("failed to search for tracks: %w", err)
+	}
 
-		page++
+	s.wait()
+	return nil
+}
+
+// newShuffler returns a Shuffler for the configured strategy which is weighted by the listening history in the store
+func newShuffler(s *session) (*shuffle.Shuffler, error) {
+	history, err := s.store.History(0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get listening history: %w", err)
+	}
+
+	now := time.Now()
+	return shuffle.NewShuffler(viper.GetString("strategy"), history, now, newRandom(now))
+}
+
+// shuffleSearchOptions returns the options for searching the shuffle from flags and the config file
+func shuffleSearchOptions() chipmusic.SearchOptions {
+	options := chipmusic.SearchOptions{
+		Query:  viper.GetString("search"),
+		Filter: chipmusic.TrackFilter(viper.GetString("filter")),
+		Tags:   viper.GetStringSlice("tag"),
+	}
+
+	if artist := viper.GetString("artist"); artist != "" {
+		options.Query = strings.TrimSpace(artistSearch(artist) + " " + options.Query)
+	}
+
+	for _, format := range viper.GetStringSlice("format") {
+		options.Formats = append(options.Formats, chipmusic.Format(strings.ToLower(format)))
 	}
+
+	return options
 }
 
-func getAndPlayTracks(tracks []string, page int, client *chipmusic.Client, tp *player.TrackPlayer, db *dashboard.TerminalDashboard) (error, bool) {
+func shuffleFresh(s *session, shuffler *shuffle.Shuffler) error {
 	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
 	defer cancel()
 
-	tracks, err := client.Search(ctx, viper.GetString("search"), viper.GetString("filter"), page)
+	search := viper.GetString("search")
+	results, err := s.client.SearchFresh(ctx, s.store, search)
 	if err != nil {
-		cancel()
-		return fmt.Errorf("failed to download track: %w", err), false
+		return fmt.Errorf("failed to search for fresh tracks: %w", err)
 	}
 
-	if len(tracks) == 0 {
-		return nil, true
+	tracks := chipmusic.SearchResultURLs(results)
+
+	s.bus.Publish(events.SearchPerformed{Search: search, Filter: string(chipmusic.TrackFilterLatest), Results: tracks})
+
+	if err := playTracks(shuffler.Shuffle(s.dropUnreachable(tracks)), s); err != nil && !errors.Is(err, errMaxTotalSize) {
+		return fmt.Errorf("failed to play tracks: %w", err)
 	}
 
+	s.wait()
+	return nil
+}
+
+// dropUnreachable returns tracks without the tracks whose track page or audio is gone, which are reported as skipped,
+// so dead links don't stop a shuffle midway. Up to chipmusic.DefaultReachabilityChecks tracks are checked at once. If
+// --check-links is off, tracks are returned as they are
+func (s *session) dropUnreachable(tracks []string) []string {
+	if !viper.GetBool("check-links") || len(tracks) == 0 {
+		return tracks
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	reachable, unreachable := s.client.FilterReachable(ctx, tracks, chipmusic.DefaultReachabilityChecks)
 	for _, trackURL := range tracks {
-		ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
-		track, err := client.GetTrack(ctx, trackURL)
-		if err != nil {
-			cancel()
-			return fmt.Errorf("failed to download track: %w", err), false
+		if err, ok := unreachable[trackURL]; ok {
+			s.skip(nil, fmt.Errorf("skipped %s: %w", trackURL, err))
+		}
+	}
+
+	s.bus.Publish(events.LinksChecked{Checked: len(tracks), Dropped: len(unreachable)})
+	return reachable
+}
+
+// playTracks downloads each track and queues it once the track before it starts playing, so tracks play back to back.
+// Up to --prefetch tracks after the queued one, or 1 with --data-saver, are downloaded in the background meanwhile.
+// Tracks are reordered or left out so no artist plays again within the artist cooldown. It returns once the last track
+// starts playing, or errMaxTotalSize once the next track would take the session over --max-total-size
+func playTracks(tracks []string, s *session) error {
+	prefetcher := chipmusic.NewPrefetcher(s.client, s.coolDown(tracks), chipmusic.PrefetchOptions{
+		Ahead:   prefetchAhead(),
+		Timeout: defaultTimeout,
+		ShouldDownload: func(track *chipmusic.Track) bool {
+			return player.IsSupportedFormat(track.FileType)
+		},
+	})
+
+	defer prefetcher.Close()
+
+	for {
+		result, ok := prefetcher.Next()
+		if !ok {
+			return nil
 		}
 
-		cancel()
+		track, err := result.Track, result.Err
+		if errors.Is(err, chipmusic.ErrBlockedTrack) {
+			s.skip(nil, fmt.Errorf("skipped %s because it matches the blocklist", result.URL))
+			continue
+		} else if track == nil {
+			return err
+		}
 
-		db.UpdateCurrentTrack(track)
+		s.bus.Publish(events.TrackResolved{Track: track})
 
-		if err := tp.Play(track); errors.Is(err, player.ErrUnknownFileFormat) {
+		if !player.IsSupportedFormat(track.FileType) {
+			s.skip(track, fmt.Errorf("skipped because format %q is not supported", track.FileType))
+			continue
+		}
+
+		if errors.Is(err, chipmusic.ErrEmptyTrack) {
+			s.skip(track, errors.New("skipped because download is empty"))
 			continue
 		} else if err != nil {
-			return fmt.Errorf("failed to play track %s: %w", track.Title, err), false
+			return err
 		}
 
-		go handleTrackTimer(tp, db)
+		if s.maxTotalSize > 0 && s.downloaded+track.Size > s.maxTotalSize {
+			track.Close()
+			s.skip(track, fmt.Errorf("stopped because it would take the tracks downloaded over %s", formatSize(s.maxTotalSize)))
+			return errMaxTotalSize
+		}
 
-		<-tp.Done()
-	}
+		if duplicate := s.duplicateOf(track); duplicate != "" {
+			track.Close()
+			s.skip(track, fmt.Errorf("skipped because it duplicates %s", duplicate))
+			continue
+		}
 
-	return nil, false
+		if s.tooLong(track) {
+			track.Close()
+			s.skip(track, fmt.Errorf("skipped because it is longer than %s", s.maxTrackLength))
+			continue
+		}
+
+		if err := s.enqueue(track); errors.Is(err, player.ErrUnknownFileFormat) {
+			continue
+		} else if errors.Is(err, player.ErrCorruptTrack) {
+			// The audio is purged from the cache so it is downloaded again instead of replayed from the cache next time
+			track.Close()
+			s.client.PurgeCached(track.DownloadURL)
+			s.skip(track, errors.New("skipped because audio is corrupt"))
+			continue
+		} else if err != nil {
+			return fmt.Errorf("failed to play track %s: %w", track.Title, err)
+		}
+
+		s.downloaded += track.Size
+	}
 }
@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/broar/chipmusic-cli/pkg/chipmusic"
+	"github.com/broar/chipmusic-cli/pkg/events"
+	"github.com/broar/chipmusic-cli/pkg/integrations/status"
+	"github.com/broar/chipmusic-cli/pkg/output"
+	"github.com/spf13/cobra"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// statusInterval is how often a session writes the status file even if nothing changed, so readers can tell it is
+	// still running
+	statusInterval = 5 * time.Second
+
+	// statusMaxAge is how old the status file may be before the session writing it is considered gone
+	statusMaxAge = 3 * statusInterval
+)
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Print the track a running session is playing",
+	Long: `Print the track a running session is playing.
+
+With --tmux, the track is printed as a short line for the status line of tmux, with nothing printed while nothing is
+playing, e.g. in .tmux.conf:
+
+	set -g status-right '#(chipmusic status --tmux)'
+
+Running sessions keep the track in a small status file in the data directory, so polling this command often costs the
+session nothing. With --output=json, the whole status is printed as JSON instead.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		tmux, _ := cmd.Flags().GetBool("tmux")
+		maxLength, _ := cmd.Flags().GetInt("max-length")
+		format, err := outputFormat(cmd)
+		if err != nil {
+			panic(err)
+		}
+
+		if err := printStatus(tmux, maxLength, format); err != nil {
+			panic(err)
+		}
+	},
+	Args: cobra.NoArgs,
+}
+
+func init() {
+	rootCmd.AddCommand(statusCmd)
+	statusCmd.Flags().Bool("tmux", false, "print the track as a short line escaped for the status line of tmux")
+	statusCmd.Flags().Int("max-length", status.DefaultMaxLength, "how many characters the line printed with --tmux is cut to. Use 0 for no limit")
+}
+
+// statusPath returns the path of the status file in the data directory
+func statusPath() (string, error) {
+	dir, err := dataDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve data directory: %w", err)
+	}
+
+	return filepath.Join(dir, status.FileName), nil
+}
+
+func printStatus(tmux bool, maxLength int, format output.Format) error {
+	path, err := statusPath()
+	if err != nil {
+		return err
+	}
+
+	current, err := status.Read(path)
+	if err != nil {
+		return err
+	}
+
+	if current.Stale(time.Now(), statusMaxAge) {
+		current = status.Status{}
+	}
+
+	switch {
+	case tmux:
+		fmt.Println(status.Tmux(current, maxLength))
+	case format == output.FormatJSON:
+		return output.WriteJSON(os.Stdout, current)
+	default:
+		fmt.Println(formatStatus(current))
+	}
+
+	return nil
+}
+
+// formatStatus returns the status as a line of text for people
+func formatStatus(current status.Status) string {
+	switch {
+	case !current.Playing:
+		return "Nothing is playing"
+	case current.Paused:
+		return fmt.Sprintf("Paused: %s by %s", current.Title, current.Artist)
+	default:
+		return fmt.Sprintf("Playing: %s by %s", current.Title, current.Artist)
+	}
+}
+
+// writeStatus keeps the status file up to date with the track playing until ctx is done, when the status file is
+// removed again. Errors are only reported once, since the status file is written over and over
+func (s *session) writeStatus(ctx context.Context) {
+	path, err := statusPath()
+	if err != nil {
+		s.bus.Publish(events.Error{Err: err})
+		return
+	}
+
+	ch, unsubscribe := s.bus.Subscribe(0,
+		events.NamePlaybackStarted,
+		events.NamePlaybackFinished,
+		events.NameActionPerformed,
+		events.NameIdlePaused,
+		events.NameIdleResumed,
+		events.NamePlaybackInterrupted,
+	)
+	defer unsubscribe()
+
+	ticker := time.NewTicker(statusInterval)
+	defer ticker.Stop()
+
+	defer os.Remove(path)
+
+	var current *chipmusic.Track
+	failed := false
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-ch:
+			if !ok {
+				return
+			}
+
+			switch event := event.(type) {
+			case events.PlaybackStarted:
+				current = event.Track
+			case events.PlaybackFinished:
+				current = nil
+			case events.ActionPerformed, events.IdlePaused, events.IdleResumed, events.PlaybackInterrupted:
+			default:
+				continue
+			}
+		case <-ticker.C:
+		}
+
+		err := status.Write(path, status.NewStatus(current, s.player.Paused(), time.Now()))
+		if err != nil && !failed {
+			s.bus.Publish(events.Error{Err: err})
+		}
+
+		failed = err != nil
+	}
+}
@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"github.com/broar/chipmusic-cli/pkg/integrations/status"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestStatus_Tmux(t *testing.T) {
+	flags, dataDir, cleanup := tempConfig(t)
+	defer cleanup()
+
+	current := status.Status{
+		Playing:   true,
+		Title:     strings.Repeat("some.title.", 10),
+		Artist:    "some.artist",
+		UpdatedAt: time.Now(),
+	}
+
+	require.NoError(t, status.Write(filepath.Join(dataDir, status.FileName), current))
+
+	// tmux shows whatever is printed, so nothing else, e.g. which config file is used, may be printed
+	printed := executeCommand(t, append(flags, "status", "--tmux", "--max-length", "30")...)
+	require.True(t, strings.HasSuffix(printed, "\n"))
+
+	lines := strings.Split(strings.TrimSuffix(printed, "\n"), "\n")
+	require.Len(t, lines, 1)
+	assert.Equal(t, 30, utf8.RuneCountInString(lines[0]))
+	assert.True(t, strings.HasPrefix(lines[0], "♪ some.artist – some.title."))
+}
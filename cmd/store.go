@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/broar/chipmusic-cli/pkg/store"
+	"github.com/mitchellh/go-homedir"
+	"github.com/spf13/viper"
+	"path/filepath"
+)
+
+const (
+	defaultDataDirName = ".chipmusic"
+)
+
+func dataDir() (string, error) {
+	if dir := viper.GetString("data-dir"); dir != "" {
+		return dir, nil
+	}
+
+	home, err := homedir.Dir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find home directory: %w", err)
+	}
+
+	return filepath.Join(home, defaultDataDirName), nil
+}
+
+func openStore() (store.Store, error) {
+	dir, err := dataDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve data directory: %w", err)
+	}
+
+	s, err := store.Open(viper.GetString("store"), dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open store: %w", err)
+	}
+
+	return s, nil
+}
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/broar/chipmusic-cli/pkg/events"
+	"github.com/broar/chipmusic-cli/pkg/player"
+	"time"
+)
+
+const (
+	// stalledPlaybackTimeout is how long an unpaused track may stay at the same position before the audio device is
+	// considered gone
+	stalledPlaybackTimeout = 3 * time.Second
+
+	// runawayPlaybackFactor is how much faster than real time a track may move before the audio device is considered
+	// gone. Without a device to block on, the speaker consumes samples as fast as it can decode them
+	runawayPlaybackFactor = 4
+)
+
+// watchPlayback pauses playback when the system is suspended or the audio device stops playing and reinitializes the
+// speaker so the track can be resumed, instead of producing a wall of errors. It returns once ctx is done
+func (s *session) watchPlayback(ctx context.Context) {
+	resumes := player.NewSuspendDetector(player.DefaultSuspendInterval, player.DefaultSuspendThreshold).Watch(ctx)
+
+	ticker := time.NewTicker(player.DefaultSuspendInterval)
+	defer ticker.Stop()
+
+	last := time.Duration(player.NoCurrentTrack)
+	var stalledSince time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case suspended, ok := <-resumes:
+			if !ok {
+				return
+			}
+
+			s.interruptPlayback(fmt.Errorf("the system was suspended for %s", suspended.Round(time.Second)))
+			last, stalledSince = s.player.CurrentTime(), time.Time{}
+		case now := <-ticker.C:
+			position := s.player.CurrentTime()
+			moved := position - last
+			last = position
+
+			// Tracks changing, seeking, or looping move the position arbitrarily, so only steady playback is judged
+			if !playing(s.player) || moved < 0 {
+				stalledSince = time.Time{}
+				continue
+			}
+
+			if moved > runawayPlaybackFactor*player.DefaultSuspendInterval {
+				s.interruptPlayback(errors.New("the audio device stopped playing"))
+				continue
+			}
+
+			if moved > 0 {
+				stalledSince = time.Time{}
+				continue
+			}
+
+			if stalledSince.IsZero() {
+				stalledSince = now
+			} else if now.Sub(stalledSince) >= stalledPlaybackTimeout {
+				s.interruptPlayback(errors.New("the audio device stopped playing"))
+				stalledSince = time.Time{}
+			}
+		}
+	}
+}
+
+// interruptPlayback pauses the current track because of reason and reinitializes the speaker so it can be resumed
+func (s *session) interruptPlayback(reason error) {
+	s.player.SetPaused(true)
+	if err := s.player.Reinit(); err != nil {
+		s.bus.Publish(events.Error{Err: fmt.Errorf("failed to recover audio: %w", err)})
+	}
+
+	s.bus.Publish(events.PlaybackInterrupted{Reason: reason})
+}
+
+// playing returns true if a track is playing and not paused
+func playing(tp *player.TrackPlayer) bool {
+	if tp.Paused() || tp.CurrentTime() == player.NoCurrentTrack {
+		return false
+	}
+
+	select {
+	case <-tp.Done():
+		return false
+	default:
+		return true
+	}
+}
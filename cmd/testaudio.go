@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/broar/chipmusic-cli/pkg/player"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"time"
+)
+
+// testTone is a tone played by test-audio along with what it checks
+type testTone struct {
+	name string
+	note player.Note
+}
+
+var testAudioCmd = &cobra.Command{
+	Use:   "test-audio",
+	Short: "Play test tones to check audio output without network access",
+	Long: `Play generated square, triangle, and noise tones through the same pipeline as tracks, then a tone on each
+channel alone. Each tone is reported as OK if it played in about as long as it lasts, which checks the audio device
+keeps up with the buffer size. Listen for the left and right tones coming out of the matching speaker to check the
+channel balance`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := testAudio(); err != nil {
+			panic(err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(testAudioCmd)
+	testAudioCmd.Flags().Duration("buffer-size", player.DefaultBufferSize, "size of the playback buffer to test")
+	testAudioCmd.Flags().Duration("tone-length", time.Second, "how long each test tone plays")
+
+	if err := viper.BindPFlags(testAudioCmd.Flags()); err != nil {
+		panic(fmt.Errorf("failed to bind flags: %w", err))
+	}
+}
+
+// testTones returns the tones test-audio plays, each lasting length
+func testTones(length time.Duration) []testTone {
+	return []testTone{
+		{"Square wave", player.Note{Frequency: 440, Duration: length}},
+		{"Triangle wave", player.Note{Frequency: 220, Duration: length, Waveform: player.WaveformTriangle}},
+		{"Noise", player.Note{Frequency: 8000, Duration: length, Waveform: player.WaveformNoise}},
+		{"Left channel", player.Note{Frequency: 440, Duration: length, Pan: -1}},
+		{"Right channel", player.Note{Frequency: 440, Duration: length, Pan: 1}},
+	}
+}
+
+// testAudio plays each test tone and reports how long it took. Crossfeed is left off so the left and right tones stay
+// on their own channel
+func testAudio() error {
+	length := viper.GetDuration("tone-length")
+	if length <= 0 {
+		return errors.New("tone length must be greater than 0")
+	}
+
+	options := []player.Option{player.WithBufferSize(viper.GetDuration("buffer-size"))}
+	if viper.IsSet("volume") {
+		options = append(options, player.WithVolume(viper.GetInt("volume")))
+	}
+
+	tp, err := player.NewTrackPlayer(options...)
+	if err != nil {
+		fmt.Printf("Speaker: FAIL (%v)\n", err)
+		return nil
+	}
+
+	defer tp.Close()
+	fmt.Printf("Speaker: OK (buffer size %s)\n", viper.GetDuration("buffer-size"))
+
+	for _, tone := range testTones(length) {
+		start := time.Now()
+		if err := playJingle(tp, player.Jingle{tone.note}); err != nil {
+			fmt.Printf("%s: FAIL (%v)\n", tone.name, err)
+			continue
+		}
+
+		// The player is done once the last samples are buffered, so a tone may finish up to a buffer early. Finishing
+		// much earlier means samples are being consumed faster than real time and nothing is actually playing them
+		elapsed := time.Since(start)
+		if elapsed < length-viper.GetDuration("buffer-size")-length/10 {
+			fmt.Printf("%s: FAIL (played in %s instead of %s)\n", tone.name, elapsed.Round(time.Millisecond), length)
+			continue
+		}
+
+		fmt.Printf("%s: OK (played in %s)\n", tone.name, elapsed.Round(time.Millisecond))
+	}
+
+	return nil
+}
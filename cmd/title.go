@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"context"
+	"github.com/broar/chipmusic-cli/pkg/events"
+	"github.com/broar/chipmusic-cli/pkg/integrations/title"
+	"github.com/spf13/viper"
+	"os"
+)
+
+// newTitle creates the title showing every track played in the terminal window if it is turned on. If it is turned
+// off, or standard output is not a terminal the escape sequences could be written to, nil is returned
+func newTitle() (*title.Title, error) {
+	if !viper.GetBool("terminal-title") {
+		return nil, nil
+	}
+
+	info, err := os.Stdout.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return nil, nil
+	}
+
+	return title.NewTitle()
+}
+
+// runTitle shows every track played in the title of the terminal window until ctx is done
+func (s *session) runTitle(ctx context.Context) {
+	ch, unsubscribe := s.bus.Subscribe(0, events.NamePlaybackStarted)
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-ch:
+			if !ok {
+				return
+			}
+
+			if event, ok := event.(events.PlaybackStarted); ok {
+				if err := s.title.SetTrack(event.Track); err != nil {
+					s.bus.Publish(events.Error{Err: err})
+				}
+			}
+		}
+	}
+}
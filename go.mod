@@ -7,10 +7,12 @@ require (
 	github.com/faiface/beep v1.0.2
 	github.com/gdamore/tcell/v2 v2.1.0
 	github.com/golang/mock v1.3.1
+	github.com/mattn/go-sqlite3 v1.14.5
 	github.com/mitchellh/go-homedir v1.1.0
 	github.com/spf13/cobra v1.1.1
 	github.com/spf13/viper v1.7.1
 	github.com/stretchr/testify v1.3.0
+	go.etcd.io/bbolt v1.3.5
 	golang.org/x/net v0.0.0-20200202094626-16171245cfb2
 	golang.org/x/sync v0.0.0-20201207232520-09787c993a3a
 )
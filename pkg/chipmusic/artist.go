@@ -0,0 +1,141 @@
+package chipmusic
+
+import (
+	"context"
+	"fmt"
+	"github.com/PuerkitoBio/goquery"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Artist is a profile of an artist on chipmusic.org along with a page of their tracks
+type Artist struct {
+
+	// Name is the name of the artist
+	Name string
+
+	// URL is the URL of the profile page of the artist on chipmusic.org
+	URL string
+
+	// Location is where the artist says they are from. It is empty if the artist did not fill it in
+	Location string
+
+	// Bio is the text the artist wrote about themselves. Paragraphs are separated by newlines
+	Bio string
+
+	// Tracks is the first page of tracks by the artist, newest first. Use GetArtistTracks for the following pages
+	Tracks []SearchResult
+}
+
+// GetArtist takes a URL to the profile page of an artist on chipmusic.org and returns the profile along with the first
+// page of their tracks
+func (c *Client) GetArtist(ctx context.Context, artistURL string) (*Artist, error) {
+	if !strings.HasPrefix(artistURL, c.baseURL) {
+		return nil, fmt.Errorf("%s is an invalid URL: must start with %s", artistURL, c.baseURL)
+	}
+
+	document, err := c.getArtistPageDocument(ctx, artistURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get artist page document: %w", err)
+	}
+
+	artist := parseArtist(document)
+	artist.URL = artistURL
+	if artist.Name == "" {
+		artist.Name = ArtistName(artistURL)
+	}
+
+	artist.Tracks, err = c.GetArtistTracks(ctx, artist.Name, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	return artist, nil
+}
+
+// GetArtistTracks returns a page of tracks by the artist with name, newest first. Pages start at 1. If there are no
+// other tracks, an empty slice is returned
+func (c *Client) GetArtistTracks(ctx context.Context, name string, page int) ([]SearchResult, error) {
+	results, err := c.Search(ctx, SearchOptions{Query: ArtistQuery(name), Filter: TrackFilterLatest, Page: page})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search for tracks by %s: %w", name, err)
+	}
+
+	return results, nil
+}
+
+func (c *Client) getArtistPageDocument(ctx context.Context, artistURL string) (*goquery.Document, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, artistURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request to get artist page: %w", err)
+	}
+
+	response, err := c.client.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get response when getting artist page: %w", err)
+	}
+
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("expected status code %d when getting artist page but got %d instead", http.StatusOK, response.StatusCode)
+	}
+
+	document, err := goquery.NewDocumentFromReader(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create parser when getting artist page: %w", err)
+	}
+
+	return document, nil
+}
+
+// ArtistQuery returns the search text which matches every track by the artist with name
+func ArtistQuery(name string) string {
+	return "by:" + name
+}
+
+// ArtistName returns the name of the artist from the URL of their profile page or one of their track pages, e.g.
+// "Hide Your Tigers" for https://chipmusic.org/Hide+Your+Tigers/music/virtues-lsdj
+func ArtistName(pageURL string) string {
+	u, err := url.Parse(pageURL)
+	if err != nil {
+		return ""
+	}
+
+	segment := strings.Split(strings.Trim(u.Path, "/"), "/")[0]
+	name, err := url.QueryUnescape(segment)
+	if err != nil {
+		return segment
+	}
+
+	return name
+}
+
+// parseArtist parses the profile of an artist. Profile fields are listed as pairs of terms and definitions, e.g.
+// <dt>Location</dt><dd>UK</dd>
+func parseArtist(document *goquery.Document) *Artist {
+	profile := document.Find("#artist_info")
+	artist := &Artist{
+		Name: strings.TrimSpace(profile.Find("h2").First().Text()),
+	}
+
+	profile.Find("dt").Each(func(_ int, term *goquery.Selection) {
+		label := strings.ToLower(strings.TrimSuffix(strings.TrimSpace(term.Text()), ":"))
+		definition := term.NextFiltered("dd")
+		switch label {
+		case "location":
+			artist.Location = strings.TrimSpace(definition.Text())
+		case "bio", "about":
+			paragraphs := make([]string, 0)
+			for _, line := range strings.Split(definition.Text(), "\n") {
+				if line = strings.TrimSpace(line); line != "" {
+					paragraphs = append(paragraphs, line)
+				}
+			}
+
+			artist.Bio = strings.Join(paragraphs, "\n")
+		}
+	})
+
+	return artist
+}
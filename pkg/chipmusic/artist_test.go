@@ -0,0 +1,106 @@
+package chipmusic
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+var (
+	defaultArtistPageFile = filepath.Join(testDataDir, "artist-page.html")
+)
+
+func newArtistServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		file := defaultArtistPageFile
+		if r.URL.Path == "/music" {
+			assert.Equal(t, "by:Fearofdark", r.URL.Query().Get("s"))
+			file = defaultSearchPageFile
+		}
+
+		raw, err := ioutil.ReadFile(file)
+		require.NoError(t, err, "failed to read content of %s as server response", file)
+
+		_, err = w.Write(raw)
+		require.NoError(t, err, "failed to write %s as server response", file)
+	}))
+}
+
+func TestGetArtist(t *testing.T) {
+	server := newArtistServer(t)
+	defer server.Close()
+
+	client, err := NewClient(WithBaseURL(server.URL), WithHTTPClient(server.Client()))
+	require.NoError(t, err, "failed to create client")
+
+	artistURL := fmt.Sprintf("%s/Fearofdark", server.URL)
+	artist, err := client.GetArtist(context.Background(), artistURL)
+	require.NoError(t, err)
+
+	assert.Equal(t, "Fearofdark", artist.Name)
+	assert.Equal(t, artistURL, artist.URL)
+	assert.Equal(t, "Cambridge, UK", artist.Location)
+	assert.Equal(t, "Chiptune artist from the UK.\nMostly 2a03 and LSDj.", artist.Bio)
+	assert.Len(t, artist.Tracks, 20)
+}
+
+func TestGetArtist_InvalidURL(t *testing.T) {
+	client, err := NewClient()
+	require.NoError(t, err, "failed to create client")
+
+	artist, err := client.GetArtist(context.Background(), "https://some.other.site/Fearofdark")
+	assert.Error(t, err)
+	assert.Nil(t, artist)
+}
+
+func TestGetArtist_NotStatusCodeOK(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	}))
+
+	defer server.Close()
+
+	client, err := NewClient(WithBaseURL(server.URL), WithHTTPClient(server.Client()))
+	require.NoError(t, err, "failed to create client")
+
+	artist, err := client.GetArtist(context.Background(), fmt.Sprintf("%s/Fearofdark", server.URL))
+	assert.Error(t, err)
+	assert.Nil(t, artist)
+}
+
+func TestGetArtistTracks(t *testing.T) {
+	server := newArtistServer(t)
+	defer server.Close()
+
+	client, err := NewClient(WithBaseURL(server.URL), WithHTTPClient(server.Client()))
+	require.NoError(t, err, "failed to create client")
+
+	tracks, err := client.GetArtistTracks(context.Background(), "Fearofdark", 2)
+	require.NoError(t, err)
+	assert.Len(t, tracks, 20)
+}
+
+func TestArtistName(t *testing.T) {
+	testCases := []struct {
+		name     string
+		pageURL  string
+		expected string
+	}{
+		{"ProfilePage", "https://chipmusic.org/Fearofdark", "Fearofdark"},
+		{"TrackPage", "https://chipmusic.org/Fearofdark/music/lovesickness-2a03", "Fearofdark"},
+		{"Spaces", "https://chipmusic.org/Hide+Your+Tigers/music/virtues-lsdj", "Hide Your Tigers"},
+		{"Invalid", "\n", ""},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			assert.Equal(tt, testCase.expected, ArtistName(testCase.pageURL))
+		})
+	}
+}
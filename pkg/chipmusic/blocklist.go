@@ -0,0 +1,105 @@
+package chipmusic
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+)
+
+var (
+	// ErrBlockedTrack is an error returned when the title or tags of a track match the blocklist of a client
+	ErrBlockedTrack = errors.New("track is blocked")
+
+	// DefaultBlocklistTerms are the markers artists commonly use for explicit tracks
+	DefaultBlocklistTerms = []string{"explicit", "nsfw", "18+", "parental advisory", "porn", "xxx"}
+)
+
+// Blocklist matches tracks whose title or tags contain a blocked term. Terms only match whole words and case is
+// ignored, e.g. "xxx" matches "XXX remix" but not "xxxtra"
+type Blocklist struct {
+	terms []string
+}
+
+// NewBlocklist creates a Blocklist for terms. Empty terms are ignored
+func NewBlocklist(terms []string) *Blocklist {
+	blocklist := &Blocklist{terms: make([]string, 0, len(terms))}
+	for _, term := range terms {
+		if term = strings.ToLower(strings.TrimSpace(term)); term != "" {
+			blocklist.terms = append(blocklist.terms, term)
+		}
+	}
+
+	return blocklist
+}
+
+// Matches returns true if title or any of tags contains a blocked term
+func (b *Blocklist) Matches(title string, tags []string) bool {
+	for _, text := range append([]string{title}, tags...) {
+		text = strings.ToLower(text)
+		for _, term := range b.terms {
+			if containsWord(text, term) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// containsWord returns true if term occurs in text without a letter or digit directly before or after it
+func containsWord(text, term string) bool {
+	for offset := 0; offset < len(text); {
+		i := strings.Index(text[offset:], term)
+		if i < 0 {
+			return false
+		}
+
+		start, end := offset+i, offset+i+len(term)
+		if !isWordByte(text, start-1) && !isWordByte(text, end) {
+			return true
+		}
+
+		offset = start + 1
+	}
+
+	return false
+}
+
+func isWordByte(text string, i int) bool {
+	if i < 0 || i >= len(text) {
+		return false
+	}
+
+	r := rune(text[i])
+	return r >= 0x80 || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// WithBlocklist allows filtering out tracks whose title or tags match blocklist. Blocked tracks are left out of search
+// results and GetTrackInfo returns ErrBlockedTrack for them, which is useful when playing music on public channels
+func WithBlocklist(blocklist *Blocklist) Option {
+	return func(c *Client) error {
+		if blocklist == nil {
+			return errors.New("blocklist cannot be nil")
+		}
+
+		c.blocklist = blocklist
+		return nil
+	}
+}
+
+// filterBlocked removes the results whose titles match the blocklist of the client. Search pages don't list tags, so
+// tracks with blocked tags are caught by GetTrackInfo instead
+func (c *Client) filterBlocked(results []SearchResult) []SearchResult {
+	if c.blocklist == nil {
+		return results
+	}
+
+	allowed := make([]SearchResult, 0, len(results))
+	for _, result := range results {
+		if !c.blocklist.Matches(result.Title, nil) {
+			allowed = append(allowed, result)
+		}
+	}
+
+	return allowed
+}
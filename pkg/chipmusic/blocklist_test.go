@@ -0,0 +1,94 @@
+package chipmusic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBlocklist_Matches(t *testing.T) {
+	blocklist := NewBlocklist(append([]string{" ", "Mariah Carey"}, DefaultBlocklistTerms...))
+
+	testCases := []struct {
+		name     string
+		title    string
+		tags     []string
+		expected bool
+	}{
+		{"NoMatch", "Lovesickness [2a03]", []string{"2a03", "nes"}, false},
+		{"TitleMatch", "Dirty Bass (EXPLICIT)", nil, true},
+		{"TagMatch", "Lovesickness", []string{"rock", "NSFW"}, true},
+		{"PunctuatedTerm", "Birthday 18+ mix", nil, true},
+		{"MultipleWords", "Wario-style Mariah Carey cover", nil, true},
+		{"PartOfWord", "Xxxtra Life", []string{"inexplicit"}, false},
+		{"RepeatedPartialMatch", "xxxx xxx", nil, true},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			assert.Equal(tt, testCase.expected, blocklist.Matches(testCase.title, testCase.tags))
+		})
+	}
+}
+
+func TestBlocklist_Empty(t *testing.T) {
+	assert.False(t, NewBlocklist(nil).Matches("Explicit", []string{"nsfw"}))
+}
+
+func TestWithBlocklist(t *testing.T) {
+	_, err := NewClient(WithBlocklist(nil))
+	assert.Error(t, err)
+}
+
+func TestSearch_Blocklist(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, err := ioutil.ReadFile(defaultSearchPageFile)
+		require.NoError(t, err, "failed to read content of %s as server response", defaultSearchPageFile)
+
+		_, err = w.Write(raw)
+		require.NoError(t, err, "failed to write %s as server response", defaultSearchPageFile)
+	}))
+
+	defer server.Close()
+
+	unfiltered, err := NewClient(WithBaseURL(server.URL), WithHTTPClient(server.Client()))
+	require.NoError(t, err, "failed to create client")
+
+	all, err := unfiltered.Search(context.Background(), SearchOptions{})
+	require.NoError(t, err)
+
+	client, err := NewClient(WithBaseURL(server.URL), WithHTTPClient(server.Client()), WithBlocklist(NewBlocklist([]string{"mariah carey"})))
+	require.NoError(t, err, "failed to create client")
+
+	results, err := client.Search(context.Background(), SearchOptions{})
+	require.NoError(t, err)
+
+	assert.Len(t, results, len(all)-1)
+	for _, result := range results {
+		assert.NotContains(t, result.Title, "Mariah Carey")
+	}
+}
+
+func TestGetTrackInfo_Blocklist(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, err := ioutil.ReadFile(defaultTrackPageFile)
+		require.NoError(t, err, "failed to read content of %s as server response", defaultTrackPageFile)
+
+		_, err = w.Write(raw)
+		require.NoError(t, err, "failed to write %s as server response", defaultTrackPageFile)
+	}))
+
+	defer server.Close()
+
+	client, err := NewClient(WithBaseURL(server.URL), WithHTTPClient(server.Client()), WithBlocklist(NewBlocklist([]string{"swing"})))
+	require.NoError(t, err, "failed to create client")
+
+	_, err = client.GetTrackInfo(context.Background(), fmt.Sprintf("%s/some.artist/music/some.music", server.URL))
+	assert.True(t, errors.Is(err, ErrBlockedTrack), "expected the track to be blocked by its tags")
+}
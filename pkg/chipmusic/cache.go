@@ -0,0 +1,347 @@
+package chipmusic
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	cacheFileExt     = ".track"
+	cacheMetaExt     = ".meta"
+	cacheTempPattern = "chipmusic-*.tmp"
+)
+
+// WithCache allows storing the audio of downloaded tracks in dir so downloading a track again, e.g. when replaying it
+// or running the same shuffle again, reads it from disk instead. Track pages are cached as well when the server sends
+// an ETag or Last-Modified header. Cached content is revalidated with a conditional request and only downloaded again
+// if it changed. Once the cache holds more than maxBytes, the least recently used entries are evicted. Streamed tracks
+// are not cached since they are never downloaded whole
+func WithCache(dir string, maxBytes int64) Option {
+	return func(c *Client) error {
+		if dir == "" {
+			return errors.New("cache directory cannot be empty")
+		}
+
+		if maxBytes <= 0 {
+			return errors.New("cache size must be a positive integer")
+		}
+
+		cache, err := openDiskCache(dir, maxBytes)
+		if err != nil {
+			return err
+		}
+
+		c.cache = cache
+		return nil
+	}
+}
+
+// WithPreferCache allows playing the cached audio of a track without revalidating it first, which saves a request per
+// track at the cost of missing changes to the audio. It has no effect unless a cache is set with WithCache
+func WithPreferCache() Option {
+	return func(c *Client) error {
+		c.preferCache = true
+		return nil
+	}
+}
+
+// diskCache is a cache of content keyed by URL. Each entry is stored in its own file, next to a file holding the
+// validators of the entry, and the least recently used entries are evicted once the cache grows over maxBytes. The
+// modification time of a file is its last use, so the order of eviction survives restarts
+type diskCache struct {
+	dir      string
+	maxBytes int64
+
+	mux     sync.Mutex
+	size    int64
+	lru     *list.List
+	entries map[string]*list.Element
+	purged  map[string]time.Time
+}
+
+// cacheEntry is content stored in the cache. Entries are ordered in the LRU list from most to least recently used
+type cacheEntry struct {
+	key  string
+	size int64
+}
+
+// openDiskCache creates dir if it doesn't exist and indexes the tracks already cached within it
+func openDiskCache(dir string, maxBytes int64) (*diskCache, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cache directory: %w", err)
+	}
+
+	cache := &diskCache{
+		dir:      dir,
+		maxBytes: maxBytes,
+		lru:      list.New(),
+		entries:  map[string]*list.Element{},
+		purged:   map[string]time.Time{},
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].ModTime().After(files[j].ModTime())
+	})
+
+	for _, file := range files {
+		name := file.Name()
+		if matched, _ := filepath.Match(cacheTempPattern, name); matched {
+			// Left behind by a write which was interrupted, e.g. by the process exiting
+			_ = os.Remove(filepath.Join(dir, name))
+			continue
+		}
+
+		if file.IsDir() || filepath.Ext(name) != cacheFileExt {
+			continue
+		}
+
+		key := strings.TrimSuffix(name, cacheFileExt)
+		cache.entries[key] = cache.lru.PushBack(&cacheEntry{key: key, size: file.Size()})
+		cache.size += file.Size()
+	}
+
+	cache.mux.Lock()
+	defer cache.mux.Unlock()
+	cache.evict()
+	return cache, nil
+}
+
+// cacheValidators are the headers used to check whether cached content is still up to date with a conditional request
+type cacheValidators struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+}
+
+// responseValidators returns the validators sent with response
+func responseValidators(response *http.Response) cacheValidators {
+	return cacheValidators{
+		ETag:         response.Header.Get("ETag"),
+		LastModified: response.Header.Get("Last-Modified"),
+	}
+}
+
+// empty returns true if there are no validators, in which case the content can't be revalidated
+func (v cacheValidators) empty() bool {
+	return v.ETag == "" && v.LastModified == ""
+}
+
+// apply makes request conditional so the server answers with 304 Not Modified if the content is unchanged
+func (v cacheValidators) apply(request *http.Request) {
+	if v.ETag != "" {
+		request.Header.Set("If-None-Match", v.ETag)
+	}
+
+	if v.LastModified != "" {
+		request.Header.Set("If-Modified-Since", v.LastModified)
+	}
+}
+
+// open returns a reader for the cached content of u along with its validators. If u isn't cached, ok is false
+func (c *diskCache) open(u string) (file *os.File, validators cacheValidators, ok bool) {
+	key := cacheKey(u)
+
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, validators, false
+	}
+
+	file, err := os.Open(c.path(key))
+	if err != nil {
+		// The file was removed behind the back of the cache so forget about it
+		c.remove(element)
+		return nil, validators, false
+	}
+
+	// Content without validators is still usable, it just can't be revalidated
+	if raw, err := ioutil.ReadFile(c.metaPath(key)); err == nil {
+		_ = json.Unmarshal(raw, &validators)
+	}
+
+	now := time.Now()
+	_ = os.Chtimes(file.Name(), now, now)
+	c.lru.MoveToFront(element)
+	return file, validators, true
+}
+
+// put stores size bytes read from r as the content of u along with its validators, evicting the least recently used
+// entries if the cache grows over its maximum size. Content larger than the whole cache is not stored, and neither is
+// content of u which was purged while it was being written
+func (c *diskCache) put(u string, r io.Reader, size int64, validators cacheValidators) error {
+	if size > c.maxBytes {
+		return nil
+	}
+
+	start := time.Now()
+
+	file, err := ioutil.TempFile(c.dir, cacheTempPattern)
+	if err != nil {
+		return fmt.Errorf("failed to create cache file: %w", err)
+	}
+
+	written, err := io.Copy(file, r)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil && written != size {
+		err = fmt.Errorf("%w: expected %d bytes but got %d instead", ErrIncompleteDownload, size, written)
+	}
+
+	if err != nil {
+		_ = os.Remove(file.Name())
+		return fmt.Errorf("failed to write cache file: %w", err)
+	}
+
+	key := cacheKey(u)
+
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	if purged, ok := c.purged[key]; ok {
+		delete(c.purged, key)
+		if !purged.Before(start) {
+			_ = os.Remove(file.Name())
+			return nil
+		}
+	}
+
+	if err := c.writeValidators(key, validators); err != nil {
+		_ = os.Remove(file.Name())
+		return err
+	}
+
+	if err := os.Rename(file.Name(), c.path(key)); err != nil {
+		_ = os.Remove(file.Name())
+		return fmt.Errorf("failed to store cache file: %w", err)
+	}
+
+	if element, ok := c.entries[key]; ok {
+		c.size -= element.Value.(*cacheEntry).size
+		c.lru.Remove(element)
+	}
+
+	c.entries[key] = c.lru.PushFront(&cacheEntry{key: key, size: size})
+	c.size += size
+	c.evict()
+	return nil
+}
+
+// writeValidators stores the validators of the entry for key, removing any stale ones if there are none. It must be
+// called with the lock held
+func (c *diskCache) writeValidators(key string, validators cacheValidators) error {
+	if validators.empty() {
+		if err := os.Remove(c.metaPath(key)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove cache validators: %w", err)
+		}
+
+		return nil
+	}
+
+	raw, err := json.Marshal(validators)
+	if err != nil {
+		return fmt.Errorf("failed to encode cache validators: %w", err)
+	}
+
+	if err := ioutil.WriteFile(c.metaPath(key), raw, 0600); err != nil {
+		return fmt.Errorf("failed to write cache validators: %w", err)
+	}
+
+	return nil
+}
+
+// evict removes the least recently used entries until the cache fits within its maximum size. It must be called with the
+// lock held
+func (c *diskCache) evict() {
+	for c.size > c.maxBytes && c.lru.Len() > 0 {
+		element := c.lru.Back()
+		key := element.Value.(*cacheEntry).key
+		_ = os.Remove(c.path(key))
+		_ = os.Remove(c.metaPath(key))
+		c.remove(element)
+	}
+}
+
+// purge removes the cached content of u, e.g. because it turned out to be corrupt. Content of u which is still being
+// written is not stored either
+func (c *diskCache) purge(u string) {
+	key := cacheKey(u)
+
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	c.purged[key] = time.Now()
+	_ = os.Remove(c.path(key))
+	_ = os.Remove(c.metaPath(key))
+	if element, ok := c.entries[key]; ok {
+		c.remove(element)
+	}
+}
+
+// remove forgets about a cache entry. It must be called with the lock held
+func (c *diskCache) remove(element *list.Element) {
+	entry := element.Value.(*cacheEntry)
+	c.size -= entry.size
+	c.lru.Remove(element)
+	delete(c.entries, entry.key)
+}
+
+func (c *diskCache) path(key string) string {
+	return filepath.Join(c.dir, key+cacheFileExt)
+}
+
+func (c *diskCache) metaPath(key string) string {
+	return filepath.Join(c.dir, key+cacheMetaExt)
+}
+
+// cacheSpool stores a spooled track as the audio of u once it has been downloaded. If the spool is closed first, the
+// track is not stored
+func (c *Client) cacheSpool(spool *SpoolReader, u string, validators cacheValidators) {
+	if err := spool.Wait(); err != nil {
+		return
+	}
+
+	// A track which can't be cached can still be played, so failing to cache it is not an error
+	_ = c.cache.put(u, io.NewSectionReader(spool.file, 0, spool.length), spool.length, validators)
+}
+
+// PurgeCached removes the cached audio of the track downloaded from downloadURL so it is downloaded again the next time,
+// e.g. because it turned out to be corrupt. It does nothing if the client has no cache
+func (c *Client) PurgeCached(downloadURL string) {
+	if c.cache != nil {
+		c.cache.purge(downloadURL)
+	}
+}
+
+// closeCached closes a cached file which is no longer needed. file may be nil
+func closeCached(file *os.File) {
+	if file != nil {
+		_ = file.Close()
+	}
+}
+
+// cacheKey returns the name of the cache file for u. URLs are hashed since they can't be used as file names
+func cacheKey(u string) string {
+	sum := sha256.Sum256([]byte(u))
+	return hex.EncodeToString(sum[:])
+}
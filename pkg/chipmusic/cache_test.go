@@ -0,0 +1,458 @@
+package chipmusic
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWithCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chipmusic-cache")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	testCases := []struct {
+		name     string
+		dir      string
+		maxBytes int64
+	}{
+		{"EmptyDir", "", 1024},
+		{"ZeroSize", dir, 0},
+		{"NegativeSize", dir, -1},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			client, err := NewClient(WithCache(testCase.dir, testCase.maxBytes))
+			assert.Error(tt, err)
+			assert.Nil(tt, client)
+		})
+	}
+}
+
+func TestDiskCache_Evict(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chipmusic-cache")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	cache, err := openDiskCache(dir, 10)
+	require.NoError(t, err)
+
+	for _, u := range []string{"a", "b", "c"} {
+		require.NoError(t, cache.put(u, bytes.NewReader([]byte(u+u+u+u)), 4, cacheValidators{}))
+	}
+
+	// a is the least recently used track so it is evicted to make room for c
+	assertCached(t, cache, "a", "")
+	assertCached(t, cache, "b", "bbbb")
+
+	// Using b makes c the least recently used track
+	require.NoError(t, cache.put("d", bytes.NewReader([]byte("dddd")), 4, cacheValidators{}))
+	assertCached(t, cache, "c", "")
+	assertCached(t, cache, "b", "bbbb")
+	assertCached(t, cache, "d", "dddd")
+	assert.Equal(t, int64(8), cache.size)
+}
+
+func TestDiskCache_TooLarge(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chipmusic-cache")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	cache, err := openDiskCache(dir, 4)
+	require.NoError(t, err)
+
+	require.NoError(t, cache.put("a", bytes.NewReader([]byte("aaaaa")), 5, cacheValidators{}))
+	assertCached(t, cache, "a", "")
+}
+
+func TestDiskCache_Incomplete(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chipmusic-cache")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	cache, err := openDiskCache(dir, 10)
+	require.NoError(t, err)
+
+	assert.Error(t, cache.put("a", bytes.NewReader([]byte("aa")), 4, cacheValidators{}))
+	assertCached(t, cache, "a", "")
+
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, files)
+}
+
+func TestDiskCache_Reopen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chipmusic-cache")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	cache, err := openDiskCache(dir, 10)
+	require.NoError(t, err)
+
+	require.NoError(t, cache.put("a", bytes.NewReader([]byte("aaaa")), 4, cacheValidators{}))
+	require.NoError(t, cache.put("b", bytes.NewReader([]byte("bbbb")), 4, cacheValidators{}))
+
+	// Files are ordered by modification time when the cache is reopened
+	old := time.Now().Add(-time.Hour)
+	require.NoError(t, os.Chtimes(cache.path(cacheKey("b")), old, old))
+
+	// Interrupted writes are cleaned up
+	temp, err := ioutil.TempFile(dir, cacheTempPattern)
+	require.NoError(t, err)
+	require.NoError(t, temp.Close())
+
+	reopened, err := openDiskCache(dir, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(8), reopened.size)
+
+	_, err = os.Stat(temp.Name())
+	assert.True(t, os.IsNotExist(err))
+
+	require.NoError(t, reopened.put("c", bytes.NewReader([]byte("cccc")), 4, cacheValidators{}))
+	assertCached(t, reopened, "b", "")
+	assertCached(t, reopened, "a", "aaaa")
+	assertCached(t, reopened, "c", "cccc")
+
+	// A smaller maximum size evicts tracks immediately
+	shrunk, err := openDiskCache(dir, 4)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), shrunk.size)
+}
+
+func TestDiskCache_Purge(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chipmusic-cache")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	client, err := NewClient(WithCache(dir, 10))
+	require.NoError(t, err)
+
+	cache := client.cache
+	require.NoError(t, cache.put("a", bytes.NewReader([]byte("aaaa")), 4, cacheValidators{ETag: `"a"`}))
+	require.NoError(t, cache.put("b", bytes.NewReader([]byte("bbbb")), 4, cacheValidators{}))
+
+	client.PurgeCached("a")
+	assertCached(t, cache, "a", "")
+	assertCached(t, cache, "b", "bbbb")
+	assert.Equal(t, int64(4), cache.size)
+
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, files, 1)
+
+	// Content which is still being written when it is purged isn't stored
+	r, w := io.Pipe()
+	done := make(chan error)
+	go func() {
+		done <- cache.put("c", r, 4, cacheValidators{})
+	}()
+
+	_, err = w.Write([]byte("cc"))
+	require.NoError(t, err)
+	client.PurgeCached("c")
+	_, err = w.Write([]byte("cc"))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	require.NoError(t, <-done)
+	assertCached(t, cache, "c", "")
+
+	// Content written after the purge is stored again
+	require.NoError(t, cache.put("c", bytes.NewReader([]byte("cccc")), 4, cacheValidators{}))
+	assertCached(t, cache, "c", "cccc")
+
+	// Purging without a cache does nothing
+	uncached, err := NewClient()
+	require.NoError(t, err)
+	uncached.PurgeCached("a")
+}
+
+func TestGetTrack_Cached(t *testing.T) {
+	testCases := []struct {
+		name  string
+		spool bool
+	}{
+		{"Memory", false},
+		{"Spooled", true},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			dir, err := ioutil.TempDir("", "chipmusic-cache")
+			require.NoError(tt, err)
+
+			defer os.RemoveAll(dir)
+
+			audio := randomAudio(tt, 10000)
+			server := newTrackServer(tt, audio, true)
+			defer server.Close()
+
+			downloads := int32(0)
+			transport := server.Client().Transport
+			httpClient := &http.Client{Transport: roundTripperFunc(func(request *http.Request) (*http.Response, error) {
+				if request.URL.Path == testAudioPath {
+					atomic.AddInt32(&downloads, 1)
+				}
+
+				return transport.RoundTrip(request)
+			})}
+
+			options := []Option{WithBaseURL(server.URL), WithHTTPClient(httpClient), WithCache(filepath.Join(dir, "cache"), 1<<20)}
+			if testCase.spool {
+				options = append(options, WithSpoolDir(dir))
+			}
+
+			client, err := NewClient(options...)
+			require.NoError(tt, err)
+
+			pageURL := fmt.Sprintf("%s/some.artist/music/some.music", server.URL)
+			track, err := client.GetTrack(context.Background(), pageURL)
+			require.NoError(tt, err)
+
+			content, err := ioutil.ReadAll(track.Reader)
+			require.NoError(tt, err)
+			assert.Equal(tt, audio, content)
+			assert.Equal(tt, int64(len(audio)), track.Size)
+			assert.False(tt, track.FromCache)
+			assert.True(tt, track.DownloadDuration > 0)
+
+			// Spooled tracks are cached in the background once downloaded
+			waitCached(tt, client.cache, server.URL+testAudioPath)
+			require.NoError(tt, track.Close())
+			requests := atomic.LoadInt32(&downloads)
+			require.True(tt, requests > 0)
+
+			cached, err := client.GetTrack(context.Background(), pageURL)
+			require.NoError(tt, err)
+
+			defer cached.Close()
+
+			content, err = ioutil.ReadAll(cached.Reader)
+			require.NoError(tt, err)
+			assert.Equal(tt, audio, content)
+			assert.Equal(tt, requests, atomic.LoadInt32(&downloads), "cached track should not be downloaded again")
+			assert.Equal(tt, int64(len(audio)), cached.Size)
+			assert.True(tt, cached.FromCache)
+		})
+	}
+}
+
+func TestDiskCache_Validators(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chipmusic-cache")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	cache, err := openDiskCache(dir, 10)
+	require.NoError(t, err)
+
+	validators := cacheValidators{ETag: `"v1"`, LastModified: "Wed, 21 Oct 2015 07:28:00 GMT"}
+	require.NoError(t, cache.put("a", bytes.NewReader([]byte("aaaa")), 4, validators))
+
+	file, actual, ok := cache.open("a")
+	require.True(t, ok)
+	require.NoError(t, file.Close())
+	assert.Equal(t, validators, actual)
+
+	// Storing content without validators forgets the old ones
+	require.NoError(t, cache.put("a", bytes.NewReader([]byte("aaaa")), 4, cacheValidators{}))
+	file, actual, ok = cache.open("a")
+	require.True(t, ok)
+	require.NoError(t, file.Close())
+	assert.True(t, actual.empty())
+
+	// Evicting content removes its validators
+	require.NoError(t, cache.put("b", bytes.NewReader([]byte("bbbb")), 4, validators))
+	require.NoError(t, cache.put("c", bytes.NewReader([]byte("cccccccc")), 8, cacheValidators{}))
+	_, err = os.Stat(cache.metaPath(cacheKey("b")))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestGetTrack_Revalidated(t *testing.T) {
+	testCases := []struct {
+		name     string
+		changed  bool
+		expected int32
+	}{
+		{"Unchanged", false, 0},
+		{"Changed", true, 1},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			dir, err := ioutil.TempDir("", "chipmusic-cache")
+			require.NoError(tt, err)
+
+			defer os.RemoveAll(dir)
+
+			server := newValidatingTrackServer(tt, randomAudio(tt, 10000))
+			defer server.Close()
+
+			client, err := NewClient(WithBaseURL(server.URL), WithHTTPClient(server.Client()), WithWorkers(1), WithCache(dir, 1<<20))
+			require.NoError(tt, err)
+
+			pageURL := fmt.Sprintf("%s/some.artist/music/some.music", server.URL)
+			track, err := client.GetTrack(context.Background(), pageURL)
+			require.NoError(tt, err)
+			require.NoError(tt, track.Close())
+			assert.Equal(tt, int32(1), server.pages)
+			assert.Equal(tt, int32(1), server.downloads)
+
+			server.pages, server.downloads = 0, 0
+			if testCase.changed {
+				server.setAudio(randomAudio(tt, 5000), `"v2"`)
+			}
+
+			track, err = client.GetTrack(context.Background(), pageURL)
+			require.NoError(tt, err)
+
+			defer track.Close()
+
+			content, err := ioutil.ReadAll(track.Reader)
+			require.NoError(tt, err)
+			assert.Equal(tt, server.audio, content)
+			assert.Equal(tt, int32(0), server.pages, "unchanged page should not be sent again")
+			assert.Equal(tt, testCase.expected, server.downloads)
+			assert.Equal(tt, int64(len(server.audio)), track.Size)
+			assert.Equal(tt, !testCase.changed, track.FromCache)
+		})
+	}
+}
+
+func TestGetTrack_PreferCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chipmusic-cache")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	audio := randomAudio(t, 10000)
+	server := newValidatingTrackServer(t, audio)
+	defer server.Close()
+
+	client, err := NewClient(WithBaseURL(server.URL), WithHTTPClient(server.Client()), WithWorkers(1), WithCache(dir, 1<<20), WithPreferCache())
+	require.NoError(t, err)
+
+	pageURL := fmt.Sprintf("%s/some.artist/music/some.music", server.URL)
+	track, err := client.GetTrack(context.Background(), pageURL)
+	require.NoError(t, err)
+	require.NoError(t, track.Close())
+
+	// The cached audio is played even though it changed since it was cached
+	server.downloads = 0
+	server.setAudio(randomAudio(t, 5000), `"v2"`)
+
+	track, err = client.GetTrack(context.Background(), pageURL)
+	require.NoError(t, err)
+
+	defer track.Close()
+
+	content, err := ioutil.ReadAll(track.Reader)
+	require.NoError(t, err)
+	assert.Equal(t, audio, content)
+	assert.Equal(t, int32(0), server.downloads)
+	assert.True(t, track.FromCache)
+}
+
+// validatingTrackServer serves the track page fixture and audio with ETags, answering conditional requests for
+// unchanged content with 304 Not Modified. It counts how many times the page and audio are sent
+type validatingTrackServer struct {
+	*httptest.Server
+
+	mux       sync.Mutex
+	audio     []byte
+	etag      string
+	pages     int32
+	downloads int32
+}
+
+func newValidatingTrackServer(t *testing.T, audio []byte) *validatingTrackServer {
+	raw, err := ioutil.ReadFile(defaultTrackPageFile)
+	require.NoError(t, err, "failed to read content of %s", defaultTrackPageFile)
+
+	server := &validatingTrackServer{audio: audio, etag: `"v1"`}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.mux.Lock()
+		defer server.mux.Unlock()
+
+		if r.URL.Path == testAudioPath {
+			w.Header().Set("ETag", server.etag)
+			if r.Method == http.MethodGet && r.Header.Get("If-None-Match") != server.etag {
+				server.downloads++
+			}
+
+			http.ServeContent(w, r, "some.track.mp3", time.Time{}, bytes.NewReader(server.audio))
+			return
+		}
+
+		w.Header().Set("ETag", `"page"`)
+		if r.Header.Get("If-None-Match") == `"page"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		server.pages++
+		page := strings.Replace(string(raw), defaultTrackLink, server.URL+testAudioPath, 1)
+		_, err := w.Write([]byte(page))
+		require.NoError(t, err, "failed to write %s as server response", defaultTrackPageFile)
+	}))
+
+	return server
+}
+
+func (s *validatingTrackServer) setAudio(audio []byte, etag string) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.audio, s.etag = audio, etag
+}
+
+// assertCached asserts the cached audio of u is expected. An empty expected audio asserts u isn't cached
+func assertCached(t *testing.T, cache *diskCache, u string, expected string) {
+	file, _, ok := cache.open(u)
+	if expected == "" {
+		assert.False(t, ok, "%s should not be cached", u)
+		return
+	}
+
+	require.True(t, ok, "%s should be cached", u)
+	defer file.Close()
+
+	content, err := ioutil.ReadAll(file)
+	require.NoError(t, err)
+	assert.Equal(t, expected, string(content))
+}
+
+// waitCached blocks until u is cached or the test times out
+func waitCached(t *testing.T, cache *diskCache, u string) {
+	deadline := time.Now().Add(defaultTestTimeout)
+	for time.Now().Before(deadline) {
+		cache.mux.Lock()
+		_, ok := cache.entries[cacheKey(u)]
+		cache.mux.Unlock()
+		if ok {
+			return
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatalf("%s was not cached within %s", u, defaultTestTimeout)
+}
@@ -3,6 +3,7 @@ package chipmusic
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"github.com/PuerkitoBio/goquery"
@@ -12,9 +13,10 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
-	"path/filepath"
+	"os"
 	"strconv"
 	"strings"
+	"time"
 )
 
 const (
@@ -26,23 +28,81 @@ const (
 	// AudioFileTypeMP3 is the expected extension for an MP3 audio file
 	AudioFileTypeMP3 AudioFileType = "mp3"
 
+	// AudioFileTypeWAV is the expected extension for a WAV audio file
+	AudioFileTypeWAV AudioFileType = "wav"
+
+	// AudioFileTypeOGG is the expected extension for an OGG/Vorbis audio file
+	AudioFileTypeOGG AudioFileType = "ogg"
+
+	// AudioFileTypeFLAC is the expected extension for a FLAC audio file
+	AudioFileTypeFLAC AudioFileType = "flac"
+
+	// AudioFileTypeMOD is the expected extension for a ProTracker module
+	AudioFileTypeMOD AudioFileType = "mod"
+
+	// AudioFileTypeS3M is the expected extension for a Scream Tracker 3 module
+	AudioFileTypeS3M AudioFileType = "s3m"
+
+	// AudioFileTypeXM is the expected extension for a FastTracker 2 extended module
+	AudioFileTypeXM AudioFileType = "xm"
+
+	// AudioFileTypeIT is the expected extension for an Impulse Tracker module
+	AudioFileTypeIT AudioFileType = "it"
+
+	// AudioFileTypeNSF is the expected extension for an NES Sound Format file
+	AudioFileTypeNSF AudioFileType = "nsf"
+
+	// AudioFileTypeSPC is the expected extension for a dump of the sound module of a SNES
+	AudioFileTypeSPC AudioFileType = "spc"
+
+	// AudioFileTypeSID is the expected extension for a Commodore 64 SID tune
+	AudioFileTypeSID AudioFileType = "sid"
+
 	// TrackFilterNone does not filter for any particular track; instead, it returns the most recently posted tracks
-	TrackFilterLatest = "latest"
+	TrackFilterLatest TrackFilter = "latest"
 
 	// TrackFilterRandom filters for random tracks
-	TrackFilterRandom = "random"
+	TrackFilterRandom TrackFilter = "random"
 
 	// TrackFilterFeatured filters for featured tracks
-	TrackFilterFeatured = "featured"
+	TrackFilterFeatured TrackFilter = "featured"
 
 	// TrackFilterFeatured filters for tracks with high ratings
-	TrackFilterHighRatings = "popular"
+	TrackFilterHighRatings TrackFilter = "popular"
 
 	defaultTrackFilter = "8"
+
+	// postedAtLayout is the layout of the date a track was posted as shown on its track page
+	postedAtLayout = "Jan 2, 2006 3:04 pm"
 )
 
 var (
-	filters = map[string]string{
+	// ErrEmptyTrack is an error returned when the audio file of a track is empty
+	ErrEmptyTrack = errors.New("track is empty")
+
+	// ErrIncompleteDownload is an error returned when fewer or more bytes are downloaded than the audio file of a track
+	// contains
+	ErrIncompleteDownload = errors.New("download is incomplete")
+
+	// ErrTrackChanged is an error returned when the audio file of a track changes while it is being downloaded in
+	// chunks, so the chunks can't be stitched back together
+	ErrTrackChanged = errors.New("track changed during download")
+
+	supportedFileTypes = []AudioFileType{
+		AudioFileTypeMP3,
+		AudioFileTypeWAV,
+		AudioFileTypeOGG,
+		AudioFileTypeFLAC,
+		AudioFileTypeMOD,
+		AudioFileTypeS3M,
+		AudioFileTypeXM,
+		AudioFileTypeIT,
+		AudioFileTypeNSF,
+		AudioFileTypeSPC,
+		AudioFileTypeSID,
+	}
+
+	filters = map[TrackFilter]string{
 		TrackFilterLatest:      "0",
 		TrackFilterRandom:      defaultTrackFilter,
 		TrackFilterFeatured:    "9",
@@ -53,6 +113,16 @@ var (
 // AudioFileType is an enumeration of possible audio file types
 type AudioFileType string
 
+// TrackFilter is an enumeration of the filters chipmusic.org applies to searches
+type TrackFilter string
+
+// SupportedFileTypes returns the audio file types recognized by the client
+func SupportedFileTypes() []AudioFileType {
+	fileTypes := make([]AudioFileType, len(supportedFileTypes))
+	copy(fileTypes, supportedFileTypes)
+	return fileTypes
+}
+
 // Client is a struct capable of interacting with chipmusic.org
 type Client struct {
 	// baseURL is the base URL of the chipmusic.org forums. This defaults to DefaultBaseURL
@@ -63,14 +133,104 @@ type Client struct {
 
 	// workers is the number of goroutines to spin up when downloading a track. This defaults to 10
 	workers int
+
+	// spoolDir is the directory where tracks are spooled while downloading. If empty, tracks are downloaded into memory
+	spoolDir string
+
+	// streamWindow is the number of bytes fetched by each ranged request when streaming tracks. If 0, tracks are not
+	// streamed
+	streamWindow int64
+
+	// progress is called while tracks download. If nil, progress is not reported
+	progress ProgressFunc
+
+	// blocklist filters out tracks whose title or tags match it. If nil, no tracks are filtered out
+	blocklist *Blocklist
+
+	// rateLimit is the maximum number of requests per second sent to each host. If 0, requests are not limited
+	rateLimit float64
+
+	// rateBurst is the number of requests which can be sent to a host at once before the rate limit applies. This
+	// defaults to DefaultRateBurst
+	rateBurst int
+
+	// maxConnsPerHost is the maximum number of requests in flight to each host. If 0, requests are not limited
+	maxConnsPerHost int
+
+	// dnsServers are the addresses of the DNS servers used to resolve hosts. If empty, the resolver of the system is used
+	dnsServers []string
+
+	// ipVersion is the IP version the client connects over. This defaults to IPAny
+	ipVersion IPVersion
+
+	// cache stores the audio of downloaded tracks. If nil, tracks are always downloaded
+	cache *diskCache
+
+	// preferCache is whether cached audio is used without checking whether it changed
+	preferCache bool
+
+	// metadata stores the metadata of tracks parsed from their track pages. If nil, track pages are always fetched
+	metadata MetadataCache
+
+	// dialTimeout limits how long connecting to a host may take. This defaults to DefaultDialTimeout
+	dialTimeout time.Duration
+
+	// tlsHandshakeTimeout limits how long the TLS handshake with a host may take. This defaults to
+	// DefaultTLSHandshakeTimeout
+	tlsHandshakeTimeout time.Duration
+
+	// responseHeaderTimeout limits how long a host may take to send the headers of a response. This defaults to
+	// DefaultResponseHeaderTimeout
+	responseHeaderTimeout time.Duration
+
+	// tlsConfig is the TLS configuration used to connect to hosts. If nil, the configuration of the transport is used
+	tlsConfig *tls.Config
+
+	// debugLog is where requests and responses are written for debugging. If nil, nothing is written
+	debugLog io.Writer
+
+	// debugBodies is true if the bodies of HTML responses are written to debugLog too
+	debugBodies bool
+
+	// probe is true if GetTrackInfo estimates the size and duration of tracks with ProbeTrack
+	probe bool
+
+	// chunkRetries is how many times a failed chunk is retried with a Range request. This defaults to
+	// DefaultChunkRetries
+	chunkRetries int
+
+	// retry is called before a failed download is tried again. If nil, retries aren't reported
+	retry RetryFunc
+
+	// downloadDeadline limits how long downloading the audio of a track may take as a whole. If 0, downloads are only
+	// limited by their context. This defaults to DefaultDownloadDeadline
+	downloadDeadline time.Duration
+
+	// singleStreamHosts are the hosts whose downloads with Range requests failed repeatedly, so tracks from them are
+	// downloaded with a single request
+	singleStreamHosts *hostSet
+
+	// pages and downloads coalesce concurrent requests for the same track page and the same audio
+	pages     *flightGroup
+	downloads *flightGroup
 }
 
 // NewClient creates a new Client object that is configured with a list of Options
 func NewClient(options ...Option) (*Client, error) {
 	client := &Client{
-		baseURL: DefaultBaseURL,
-		client:  http.DefaultClient,
-		workers: DefaultWorkers,
+		baseURL:           DefaultBaseURL,
+		client:            http.DefaultClient,
+		workers:           DefaultWorkers,
+		rateBurst:         DefaultRateBurst,
+		chunkRetries:      DefaultChunkRetries,
+		downloadDeadline:  DefaultDownloadDeadline,
+		singleStreamHosts: newHostSet(),
+		pages:             newFlightGroup(),
+		downloads:         newFlightGroup(),
+
+		dialTimeout:           DefaultDialTimeout,
+		tlsHandshakeTimeout:   DefaultTLSHandshakeTimeout,
+		responseHeaderTimeout: DefaultResponseHeaderTimeout,
 	}
 
 	for _, option := range options {
@@ -79,6 +239,49 @@ func NewClient(options ...Option) (*Client, error) {
 		}
 	}
 
+	// The dialer, TLS config, and timeouts replace the base transport, so they must be configured before the transport is wrapped
+	if len(client.dnsServers) > 0 || client.ipVersion != IPAny {
+		configured, err := client.withDialer(client.client)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create client: %v", err)
+		}
+
+		client.client = configured
+	}
+
+	if client.tlsConfig != nil {
+		configured, err := client.withTLSConfig(client.client)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create client: %v", err)
+		}
+
+		client.client = configured
+	}
+
+	client.client = client.withTimeouts(client.client)
+
+	// The debug log wraps the base transport directly so it only shows requests when they are actually sent
+	if client.debugLog != nil {
+		client.client = wrapTransport(client.client, func(base http.RoundTripper) http.RoundTripper {
+			return &debugTransport{base: base, bodies: client.debugBodies, now: time.Now, w: client.debugLog}
+		})
+	}
+
+	// Requests wait for the rate limit before taking a connection slot so slots aren't held while waiting
+	if client.maxConnsPerHost > 0 {
+		limiter := newHostLimiter(client.maxConnsPerHost)
+		client.client = wrapTransport(client.client, func(base http.RoundTripper) http.RoundTripper {
+			return &connLimitedTransport{base: base, limiter: limiter}
+		})
+	}
+
+	if client.rateLimit > 0 {
+		limiter := newRateLimiter(client.rateLimit, client.rateBurst)
+		client.client = wrapTransport(client.client, func(base http.RoundTripper) http.RoundTripper {
+			return &rateLimitedTransport{base: base, limiter: limiter}
+		})
+	}
+
 	return client, nil
 }
 
@@ -125,6 +328,43 @@ func WithWorkers(workers int) Option {
 	}
 }
 
+// WithSpoolDir allows downloading tracks into a spool file within dir instead of memory. The reader of a spooled track
+// is returned before the download finishes and blocks until the bytes being read have arrived. Tracks whose length the
+// server doesn't send are still downloaded into memory
+func WithSpoolDir(dir string) Option {
+	return func(client *Client) error {
+		if dir == "" {
+			return errors.New("spool directory cannot be empty")
+		}
+
+		info, err := os.Stat(dir)
+		if err != nil {
+			return fmt.Errorf("failed to stat spool directory: %w", err)
+		}
+
+		if !info.IsDir() {
+			return fmt.Errorf("%s is not a directory", dir)
+		}
+
+		client.spoolDir = dir
+		return nil
+	}
+}
+
+// WithStreaming allows streaming tracks with ranged requests of window bytes instead of downloading them. Only one
+// window of a track is held in memory at a time. If the server does not accept Range requests, tracks are downloaded
+// as usual
+func WithStreaming(window int64) Option {
+	return func(client *Client) error {
+		if window <= 0 {
+			return errors.New("stream window must be a positive integer")
+		}
+
+		client.streamWindow = window
+		return nil
+	}
+}
+
 // Track is song from chipmusic.org. It contains metadata related to the song along with a reader of the track itself
 type Track struct {
 
@@ -134,12 +374,42 @@ type Track struct {
 	// Artist is the name of the author who composed the track
 	Artist string
 
+	// PageURL is the URL of the track page on chipmusic.org
+	PageURL string
+
+	// DownloadURL is the URL of the audio file for the track
+	DownloadURL string
+
 	// Reader reads the body of the track. It is also able to seek to any point within the track
 	Reader ReadSeekCloser
 
 	// FileType represents the type of audio file for this track. This should be used to determine how to interpret and
 	// play the content returned from Reader
 	FileType AudioFileType
+
+	// Tags are the tags the artist added to the track, such as the genre or the hardware used to make it
+	Tags []string
+
+	// PostedAt is when the track was posted to chipmusic.org. It is the zero time if the date could not be parsed
+	PostedAt time.Time
+
+	// Description is the text the artist wrote about the track. Paragraphs are separated by newlines
+	Description string
+
+	// Size is the size of the audio file in bytes. It is 0 until ProbeTrack or DownloadTrack finds it or if the server
+	// doesn't say
+	Size int64
+
+	// FromCache is whether DownloadTrack read the audio from the cache instead of downloading it
+	FromCache bool
+
+	// DownloadDuration is how long DownloadTrack took to get the audio. Spooled and streamed tracks keep downloading
+	// afterwards, so for them it is how long it took until the audio could be read
+	DownloadDuration time.Duration
+
+	// Duration is how long the track plays for, estimated by ProbeTrack from the start of the audio file. It is 0 if
+	// it is unknown, e.g. for chiptunes and tracker modules which don't have a fixed length
+	Duration time.Duration
 }
 
 func (t *Track) Close() error {
@@ -170,97 +440,68 @@ func (r *ReadSeekNopCloser) Close() error {
 	return nil
 }
 
-// Search performs a search against chipmusic.org, returning a list of URLs to tracks which match. If a search returns
-// more tracks than can be returned in a single call, you can use the page parameter to paginate through the additional
-// tracks. To iterate through all tracks for a particular search, start with page = 1 and increment it for subsequent
-// calls. The order of the tracks returned is undefined. If no tracks are found or there are no other tracks, an empty
-// slice is returned
-func (c *Client) Search(ctx context.Context, search, filter string, page int) ([]string, error) {
-	if page <= 0 {
-		page = 1
-	}
-
-	resolved, ok := filters[filter]
-	if !ok {
-		resolved = defaultTrackFilter
-	}
-
-	u, err := url.Parse(fmt.Sprintf("%s/music#", c.baseURL))
+// GetTrack takes a URL to a track page for chipmusic.org and returns a Track. The returned struct contains metadata
+// about the track and a reader which can be used to download the track itself for playback. Use FileType in the Track
+// to determine how to use the the content returned from the reader
+func (c *Client) GetTrack(ctx context.Context, trackPageURL string) (*Track, error) {
+	track, err := c.GetTrackInfo(ctx, trackPageURL)
 	if err != nil {
-		return nil, fmt.Errorf("failed to build search URL: %w", err)
+		return nil, err
 	}
 
-	params := url.Values(map[string][]string{
-		"s": {search},
-		"p":  {strconv.Itoa(page)},
-		"f":  {resolved},
-	})
-
-	u.RawQuery = params.Encode()
-
-	document, err := c.getSearchPageDocument(ctx, u.String())
-	if err != nil {
-		return nil, fmt.Errorf("failed to get search page document: %w", err)
+	if err := c.DownloadTrack(ctx, track); err != nil {
+		return nil, fmt.Errorf("failed to download track: %w", err)
 	}
 
-	return c.parseTracksFromSearch(document), nil
+	return track, nil
 }
 
-func (c *Client) getSearchPageDocument(ctx context.Context, url string) (*goquery.Document, error) {
-	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to build request to search for tracks: %w", err)
-	}
-
-	response, err := c.client.Do(request)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get response when searching for tracks: %w", err)
+// GetTrackInfo takes a URL to a track page for chipmusic.org and returns a Track containing only metadata about the
+// track. The audio is not downloaded and the Reader is nil. This is useful for deciding whether a track should be
+// downloaded at all, e.g. by checking its FileType. If the client was created with WithProbing, the Size and Duration
+// of the track are estimated too. Use DownloadTrack to download the audio afterwards
+func (c *Client) GetTrackInfo(ctx context.Context, trackPageURL string) (*Track, error) {
+	if !strings.HasPrefix(trackPageURL, c.baseURL) {
+		return nil, fmt.Errorf("%s is an invalid URL: must start with %s", trackPageURL, c.baseURL)
 	}
 
-	defer response.Body.Close()
-	if response.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("expected status code %d when searching for tracks but got %d instead", http.StatusOK, response.StatusCode)
-	}
+	// Concurrent calls for the same track, e.g. by a prefetch and the dashboard, share a single request
+	track, err := c.pages.do(ctx, trackPageURL, trackInfoResult, func(ctx context.Context) (interface{}, error) {
+		return c.getTrackInfo(ctx, trackPageURL)
+	})
 
-	document, err := goquery.NewDocumentFromReader(response.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create parser when searching for tracks: %w", err)
+		return nil, err
 	}
 
-	return document, nil
+	return track.(*Track), nil
 }
 
-func (c *Client) parseTracksFromSearch(document *goquery.Document) []string {
-	tracks := make([]string, 0, 0)
-	links := document.Find("#music_list .item-subject .hn a")
-	for _, node := range links.Nodes {
-		for _, attribute := range node.Attr {
-			if attribute.Key == "href" {
-				tracks = append(tracks, attribute.Val)
-				break
-			}
+// getTrackInfo gets and parses the track page at trackPageURL, unless its metadata is cached
+func (c *Client) getTrackInfo(ctx context.Context, trackPageURL string) (*Track, error) {
+	track := c.cachedTrackInfo(trackPageURL)
+	if track == nil {
+		document, err := c.getTrackPageDocument(ctx, trackPageURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get track page document: %w", err)
 		}
-	}
 
-	return tracks
-}
+		track, err = c.parseTrack(document)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse track: %w", err)
+		}
 
-// GetTrack takes a URL to a track page for chipmusic.org and returns a Track. The returned struct contains metadata
-// about the track and a reader which can be used to download the track itself for playback. Use FileType in the Track
-// to determine how to use the the content returned from the reader
-func (c *Client) GetTrack(ctx context.Context, trackPageURL string) (*Track, error) {
-	if !strings.HasPrefix(trackPageURL, c.baseURL) {
-		return nil, fmt.Errorf("%s is an invalid URL: must start with %s", trackPageURL, c.baseURL)
+		track.PageURL = trackPageURL
+		c.cacheTrackInfo(track)
 	}
 
-	document, err := c.getTrackPageDocument(ctx, trackPageURL)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get track page document: %w", err)
+	if c.blocklist != nil && c.blocklist.Matches(track.Title, track.Tags) {
+		return nil, fmt.Errorf("%w: %s", ErrBlockedTrack, trackPageURL)
 	}
 
-	track, err := c.parseTrack(document)
-	if err != nil {
-		return nil, fmt.Errorf("failed to download track: %w", err)
+	// The size and duration are only estimates, so a track whose audio can't be probed is still returned
+	if c.probe {
+		_ = c.ProbeTrack(ctx, track)
 	}
 
 	return track, nil
@@ -272,17 +513,46 @@ func (c *Client) getTrackPageDocument(ctx context.Context, trackPageURL string)
 		return nil, fmt.Errorf("failed to build request to get track page: %w", err)
 	}
 
+	var cached *os.File
+	if c.cache != nil {
+		if file, validators, ok := c.cache.open(trackPageURL); ok {
+			cached = file
+			validators.apply(request)
+		}
+	}
+
+	defer closeCached(cached)
+
 	response, err := c.client.Do(request)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get response when getting track page: %w", err)
 	}
 
 	defer response.Body.Close()
-	if response.StatusCode != http.StatusOK {
+
+	var body io.Reader = response.Body
+	switch {
+	case response.StatusCode == http.StatusNotModified && cached != nil:
+		body = cached
+	case gone(response.StatusCode):
+		return nil, fmt.Errorf("%w: track page responded with status code %d", ErrUnreachableTrack, response.StatusCode)
+	case response.StatusCode != http.StatusOK:
 		return nil, fmt.Errorf("expected status code %d when getting track page but got %d instead", http.StatusOK, response.StatusCode)
+	case c.cache != nil:
+		// Pages without validators can't be revalidated, so caching them would not save a request
+		if validators := responseValidators(response); !validators.empty() {
+			content, err := ioutil.ReadAll(response.Body)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read track page: %w", err)
+			}
+
+			// A page which can't be cached can still be parsed, so failing to cache it is not an error
+			_ = c.cache.put(trackPageURL, bytes.NewReader(content), int64(len(content)), validators)
+			body = bytes.NewReader(content)
+		}
 	}
 
-	document, err := goquery.NewDocumentFromReader(response.Body)
+	document, err := goquery.NewDocumentFromReader(body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create parser when getting track page: %w", err)
 	}
@@ -293,48 +563,189 @@ func (c *Client) getTrackPageDocument(ctx context.Context, trackPageURL string)
 func (c *Client) parseTrack(document *goquery.Document) (*Track, error) {
 	info := document.Find("#item_info")
 	track := c.parseTrackMetadata(info)
+	track.Tags = parseTrackTags(document.Find("#item_tags"))
 	trackDownloadURL, err := parseTrackDownloadURL(info)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse track download: %w", err)
 	}
 
-	track.FileType = AudioFileType(strings.TrimPrefix(filepath.Ext(trackDownloadURL), "."))
+	track.DownloadURL = trackDownloadURL
+	track.FileType = fileTypeFromURL(trackDownloadURL)
+	return track, nil
+}
 
-	request, err := http.NewRequestWithContext(context.Background(), http.MethodHead, trackDownloadURL, nil)
+// DownloadTrack downloads the audio of a track returned by GetTrackInfo and sets the Reader of the track. If the track
+// is cached and unchanged, it is read from the cache instead
+func (c *Client) DownloadTrack(ctx context.Context, track *Track) error {
+	if track == nil {
+		return errors.New("track cannot be nil")
+	}
+
+	// Concurrent downloads of the same audio share a single download, and each gets a reader of its own
+	downloaded, err := c.downloads.do(ctx, track.DownloadURL, trackAudioResult, func(ctx context.Context) (interface{}, error) {
+		download := &Track{DownloadURL: track.DownloadURL, FileType: track.FileType}
+		start := time.Now()
+		if err := c.downloadTrackAudio(ctx, download); err != nil {
+			return nil, err
+		}
+
+		download.DownloadDuration = time.Since(start)
+		return download, nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	download := downloaded.(*Track)
+	track.Reader, track.FileType = download.Reader, download.FileType
+	track.FromCache, track.DownloadDuration = download.FromCache, download.DownloadDuration
+	if download.Size > 0 {
+		track.Size = download.Size
+	}
+
+	return nil
+}
+
+// downloadTrackAudio downloads the audio of a track and sets its Reader, FileType, Size, and FromCache
+func (c *Client) downloadTrackAudio(ctx context.Context, track *Track) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodHead, track.DownloadURL, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get response when downloading track: %w", err)
+		return fmt.Errorf("failed to get response when downloading track: %w", err)
+	}
+
+	var cached *os.File
+	if c.cache != nil {
+		file, validators, ok := c.cache.open(track.DownloadURL)
+		if ok && (validators.empty() || c.preferCache) {
+			track.Reader, track.Size, track.FromCache = file, fileSize(file), true
+			return nil
+		}
+
+		if ok {
+			cached = file
+			validators.apply(request)
+		}
 	}
 
 	response, err := c.client.Do(request)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get response when downloading track: %w", err)
+		closeCached(cached)
+		return fmt.Errorf("failed to get response when downloading track: %w", err)
 	}
 
 	defer response.Body.Close()
 
+	if response.StatusCode == http.StatusNotModified && cached != nil {
+		track.Reader, track.Size, track.FromCache = cached, fileSize(cached), true
+		return nil
+	}
+
+	// The track changed since it was cached so it is downloaded again
+	closeCached(cached)
+
 	if response.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("expected status code %d when downloading track but got %d instead", http.StatusOK, response.StatusCode)
+		return fmt.Errorf("expected status code %d when downloading track but got %d instead", http.StatusOK, response.StatusCode)
+	}
+
+	if response.Header.Get("Content-Length") == "0" {
+		return ErrEmptyTrack
 	}
 
-	reader, err := c.downloadTrack(response)
+	// The extension of the download URL can be missing or wrong, but a specific Content-Type is trustworthy
+	if fileType, ok := fileTypeFromContentType(response.Header.Get("Content-Type")); ok {
+		track.FileType = fileType
+	}
+
+	if c.streamWindow > 0 {
+		stream, err := c.streamTrack(response)
+		if err != nil {
+			return fmt.Errorf("failed to stream track: %w", err)
+		}
+
+		if stream != nil {
+			track.Reader, track.Size = stream, stream.length
+			return nil
+		}
+	}
+
+	progress := newProgressTracker(c.progress, track.DownloadURL, response.ContentLength)
+	// A spool file is sized up front, so a track whose length the server doesn't send is downloaded into memory instead
+	if c.spoolDir != "" && response.ContentLength >= 0 {
+		spool, err := c.spoolTrack(response, progress)
+		if err != nil {
+			return fmt.Errorf("failed to spool track: %w", err)
+		}
+
+		if c.cache != nil {
+			go c.cacheSpool(spool, track.DownloadURL, responseValidators(response))
+		}
+
+		track.Reader, track.Size = spool, spool.length
+		return nil
+	}
+
+	reader, err := c.downloadTrack(ctx, response, progress)
 	if err != nil {
-		return nil, fmt.Errorf("faild to download track: %w", err)
+		return fmt.Errorf("faild to download track: %w", err)
 	}
 
-	track.Reader = &ReadSeekNopCloser{Reader: reader}
+	if reader.Size() == 0 {
+		return ErrEmptyTrack
+	}
 
-	return track, nil
+	if c.cache != nil {
+		// A track which can't be cached can still be played, so failing to cache it is not an error
+		_ = c.cache.put(track.DownloadURL, io.NewSectionReader(reader, 0, reader.Size()), reader.Size(), responseValidators(response))
+	}
+
+	track.Reader, track.Size = &ReadSeekNopCloser{Reader: reader}, reader.Size()
+
+	return nil
 }
 
-func (c *Client) downloadTrack(downloadMetadataResponse *http.Response) (io.ReadSeeker, error) {
-	// The server accepts Range requests so we should use them to provide greater throughput
-	if downloadMetadataResponse.Header.Get("Accept-Ranges") == "bytes" {
-		return c.downloadTrackWithWorkers(downloadMetadataResponse)
+// fileSize returns the size of a file, or 0 if it can't be found
+func fileSize(file *os.File) int64 {
+	info, err := file.Stat()
+	if err != nil {
+		return 0
 	}
 
-	// The server does not accept Range requests so we'll gracefully degrade to a single download request for the whole file
+	return info.Size()
+}
+
+// downloadTrack downloads the whole audio of a track into memory. Cancelling ctx or reaching the download deadline
+// aborts every request of the download
+func (c *Client) downloadTrack(ctx context.Context, downloadMetadataResponse *http.Response, progress *progressTracker) (*bytes.Reader, error) {
+	downloadCtx, cancel := c.withDownloadDeadline(ctx)
+	defer cancel()
+
+	reader, err := c.downloadTrackWithFallback(downloadCtx, downloadMetadataResponse, progress)
+	return reader, c.deadlineError(ctx, downloadCtx, err)
+}
+
+// downloadTrackWithFallback downloads the whole audio of a track into memory. If a chunk keeps failing with Range
+// requests, the track is downloaded with a single request instead, as is every track from the same host afterwards
+func (c *Client) downloadTrackWithFallback(ctx context.Context, downloadMetadataResponse *http.Response, progress *progressTracker) (*bytes.Reader, error) {
 	u := downloadMetadataResponse.Request.URL.String()
-	request, err := http.NewRequest(http.MethodGet, u, nil)
+
+	// The server accepts Range requests so we should use them to provide greater throughput, as long as it sends the
+	// length of the track to split into ranges
+	ranged := downloadMetadataResponse.Header.Get("Accept-Ranges") == "bytes" && downloadMetadataResponse.ContentLength >= 0
+	if ranged && !c.singleStreamHosts.has(u) {
+		reader, err := c.downloadTrackWithWorkers(ctx, downloadMetadataResponse, progress)
+		if err == nil || ctx.Err() != nil {
+			return reader, err
+		}
+
+		c.reportRetry(progress.url, c.chunkRetries+1, err)
+		c.singleStreamHosts.add(u)
+		progress.reset()
+	}
+
+	// The server does not accept Range requests, or mishandles them, so we'll gracefully degrade to a single download
+	// request for the whole file
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create track download request: %w", err)
 	}
@@ -346,55 +757,48 @@ func (c *Client) downloadTrack(downloadMetadataResponse *http.Response) (io.Read
 
 	defer response.Body.Close()
 
-	content, err := ioutil.ReadAll(response.Body)
+	content, err := ioutil.ReadAll(progress.reader(response.Body))
 	if err != nil {
 		return nil,  fmt.Errorf("failed to read response for track download: %w", err)
 	}
 
+	if expected := downloadMetadataResponse.ContentLength; expected >= 0 && int64(len(content)) != expected {
+		return nil, fmt.Errorf("%w: expected %d bytes but got %d instead", ErrIncompleteDownload, expected, len(content))
+	}
+
 	return bytes.NewReader(content), nil
 }
 
-func (c *Client) downloadTrackWithWorkers(downloadMetadataResponse *http.Response) (io.ReadSeeker, error) {
+// downloadTrackWithWorkers downloads chunks of the audio of a track concurrently with Range requests. A failed chunk is
+// retried up to the chunk retries of the client. If it keeps failing or ctx is cancelled, the requests for the other
+// chunks are aborted
+func (c *Client) downloadTrackWithWorkers(ctx context.Context, downloadMetadataResponse *http.Response, progress *progressTracker) (*bytes.Reader, error) {
 	length, err := strconv.ParseInt(downloadMetadataResponse.Header.Get("Content-Length"), 10, 64)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse Content-Length header: %w", err)
 	}
 
-	// TODO: We can lose some bytes from the division
-	content := make([]byte, length, length)
-	size := int(length / int64(c.workers))
-	group := errgroup.Group{}
-	for i := 0; i < c.workers; i++ {
-		start := i * size
-		end := (i + 1) * size
-
-		// We want to always start with offset of 1 byte so our chunks never overlap except for the first chunk
-		if start != 0 {
-			start++
-		}
-
+	u := downloadMetadataResponse.Request.URL.String()
+	validator := downloadValidator(downloadMetadataResponse)
+	content := make([]byte, length)
+	group, groupCtx := errgroup.WithContext(ctx)
+	for _, r := range splitRanges(length, c.workers) {
+		r := r
 		group.Go(func() error {
-			u := downloadMetadataResponse.Request.URL.String()
-			request, err := http.NewRequest(http.MethodGet, u, nil)
-			if err != nil {
-				return fmt.Errorf("failed to create track download request: %w", err)
-			}
-
-			request.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", start, end))
+			chunk, err := c.downloadChunk(groupCtx, u, r, validator, progress)
+			for attempt := 0; err != nil && groupCtx.Err() == nil && attempt < c.chunkRetries; attempt++ {
+				c.reportRetry(progress.url, attempt+1, err)
 
-			response, err := c.client.Do(request)
-			if err != nil {
-				return fmt.Errorf("failed to get response for track download: %w", err)
+				// The bytes of the failed attempt are downloaded again, so they are taken back out of the progress
+				progress.forget(int64(len(chunk)))
+				chunk, err = c.downloadChunk(groupCtx, u, r, validator, progress)
 			}
 
-			defer response.Body.Close()
-
-			chunk, err := ioutil.ReadAll(response.Body)
 			if err != nil {
-				return fmt.Errorf("failed to read response for track download: %w", err)
+				return err
 			}
 
-			copy(content[start:start+len(chunk)], chunk)
+			copy(content[r.start:r.end], chunk)
 			return nil
 		})
 	}
@@ -406,6 +810,111 @@ func (c *Client) downloadTrackWithWorkers(downloadMetadataResponse *http.Respons
 	return bytes.NewReader(content), nil
 }
 
+// downloadChunk downloads the range r of the audio of a track with a Range request. The bytes received before an error
+// are returned along with it
+func (c *Client) downloadChunk(ctx context.Context, u string, r byteRange, validator string, progress *progressTracker) ([]byte, error) {
+	request, err := newRangeRequest(ctx, u, r, validator)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create track download request: %w", err)
+	}
+
+	response, err := c.client.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get response for track download: %w", err)
+	}
+
+	defer response.Body.Close()
+
+	if err := checkRangeResponse(response, r); err != nil {
+		return nil, err
+	}
+
+	// Reading one byte past the chunk detects a server which sends more than the range asked for
+	chunk, err := ioutil.ReadAll(io.LimitReader(progress.reader(response.Body), r.len()+1))
+	if err != nil {
+		return chunk, fmt.Errorf("failed to read response for track download: %w", err)
+	}
+
+	if int64(len(chunk)) != r.len() {
+		return chunk, fmt.Errorf("%w: expected %d bytes for %s but got %d instead", ErrIncompleteDownload, r.len(), r, len(chunk))
+	}
+
+	return chunk, nil
+}
+
+// byteRange is a range of bytes within a file from start up to but not including end
+type byteRange struct {
+	start int64
+	end   int64
+}
+
+func (r byteRange) len() int64 {
+	return r.end - r.start
+}
+
+// String formats the range as the value of a Range header, whose end is inclusive
+func (r byteRange) String() string {
+	return fmt.Sprintf("bytes=%d-%d", r.start, r.end-1)
+}
+
+// splitRanges splits length bytes into at most workers contiguous ranges which cover every byte exactly once. Ranges
+// differ in length by at most one byte
+func splitRanges(length int64, workers int) []byteRange {
+	count := int64(workers)
+	if count > length {
+		count = length
+	}
+
+	ranges := make([]byteRange, 0, count)
+	for i := int64(0); i < count; i++ {
+		ranges = append(ranges, byteRange{start: length * i / count, end: length * (i + 1) / count})
+	}
+
+	return ranges
+}
+
+// downloadValidator returns the validator sent with If-Range so chunks of a file which changes mid-download are not
+// stitched together. The ETag is preferred since Last-Modified only has a resolution of one second
+func downloadValidator(response *http.Response) string {
+	if etag := response.Header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+
+	return response.Header.Get("Last-Modified")
+}
+
+func newRangeRequest(ctx context.Context, u string, r byteRange, validator string) (*http.Request, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	request.Header.Set("Range", r.String())
+	if validator != "" {
+		request.Header.Set("If-Range", validator)
+	}
+
+	return request, nil
+}
+
+// checkRangeResponse ensures a server answered a Range request with exactly the range asked for. A server answers with
+// the whole file instead when the If-Range validator no longer matches
+func checkRangeResponse(response *http.Response, r byteRange) error {
+	switch response.StatusCode {
+	case http.StatusPartialContent:
+	case http.StatusOK:
+		return fmt.Errorf("%w: expected %s but got the whole file", ErrTrackChanged, r)
+	default:
+		return fmt.Errorf("expected status code %d for track download but got %d instead", http.StatusPartialContent, response.StatusCode)
+	}
+
+	if contentRange := response.Header.Get("Content-Range"); contentRange != "" && !strings.HasPrefix(contentRange, strings.Replace(r.String(), "=", " ", 1)+"/") {
+		return fmt.Errorf("%w: expected %s but got %s", ErrIncompleteDownload, r, contentRange)
+	}
+
+	return nil
+}
+
 func (c *Client) parseTrackMetadata(info *goquery.Selection) *Track {
 	track := &Track{}
 	content := info.Find("#item_content_block")
@@ -421,16 +930,56 @@ func (c *Client) parseTrackMetadata(info *goquery.Selection) *Track {
 			}
 
 			track.Artist = strings.TrimPrefix(child.FirstChild.Data, "By ")
-		}
-
-		if track.Title != "" && track.Artist != "" {
-			break
+			if posted := child.NextSibling; posted != nil {
+				track.PostedAt = parsePostedAt(posted.Data)
+			}
 		}
 	}
 
+	track.Description = parseTrackDescription(content)
 	return track
 }
 
+func parsePostedAt(text string) time.Time {
+	text = strings.TrimPrefix(strings.TrimSpace(text), "on ")
+	postedAt, err := time.Parse(postedAtLayout, text)
+	if err != nil {
+		return time.Time{}
+	}
+
+	return postedAt
+}
+
+// parseTrackDescription returns the text of the track description. The description is a paragraph containing more
+// paragraphs, which HTML does not allow, so the parser closes the description early and its paragraphs end up as
+// siblings which follow it
+func parseTrackDescription(content *goquery.Selection) string {
+	description := content.Find("#item_description")
+	paragraphs := make([]string, 0)
+	for _, selection := range []*goquery.Selection{description, description.NextUntil("#item_play_options").Filter("p")} {
+		selection.Each(func(_ int, paragraph *goquery.Selection) {
+			if text := strings.TrimSpace(paragraph.Text()); text != "" {
+				paragraphs = append(paragraphs, text)
+			}
+		})
+	}
+
+	return strings.Join(paragraphs, "\n")
+}
+
+func parseTrackTags(tags *goquery.Selection) []string {
+	parsed := make([]string, 0)
+
+	// The last link browses other tracks by the artist rather than a tag
+	tags.Find("a").Not(".artist").Each(func(_ int, tag *goquery.Selection) {
+		if text := strings.TrimSpace(tag.Text()); text != "" {
+			parsed = append(parsed, text)
+		}
+	})
+
+	return parsed
+}
+
 func parseTrackDownloadURL(info *goquery.Selection) (string, error) {
 	download := info.Find("#item_play_options #item_download")
 	for _, node := range download.Nodes {
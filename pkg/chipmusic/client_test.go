@@ -1,6 +1,7 @@
 package chipmusic
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -11,11 +12,15 @@ import (
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 const (
-	testDataDir = "data"
+	testDataDir        = "data"
+	defaultTestTimeout = 3 * time.Second
 )
 
 var (
@@ -67,28 +72,25 @@ func TestWithWorkers(t *testing.T) {
 }
 
 func TestGetTrack(t *testing.T) {
-	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		file, err := os.Open(defaultTrackPageFile)
-		require.NoError(t, err, "failed to open %s and send as server response", defaultTrackPageFile)
-
-		raw, err := ioutil.ReadAll(file)
-		require.NoError(t, err, "failed to read content of %s as server response", defaultTrackPageFile)
-
-		_, err = w.Write(raw)
-		require.NoError(t, err, "failed to write %s as server response", defaultTrackPageFile)
-	}))
-
+	audio := randomAudio(t, 1000)
+	server := newTrackServer(t, audio, true)
 	defer server.Close()
 
 	client, err := NewClient(WithBaseURL(server.URL), WithHTTPClient(server.Client()), WithWorkers(DefaultWorkers))
 	require.NoError(t, err, "failed to create client")
 
-	track, err := client.GetTrack(context.Background(), fmt.Sprintf("%s/some.artist/music/some.music", server.URL))
+	trackPageURL := fmt.Sprintf("%s/some.artist/music/some.music", server.URL)
+	track, err := client.GetTrack(context.Background(), trackPageURL)
 	require.NoError(t, err, "should not have received an error when getting track")
 	assert.Equal(t, "Lovesickness [2a03]", track.Title)
 	assert.Equal(t, "Fearofdark", track.Artist)
+	assert.Equal(t, trackPageURL, track.PageURL)
 	assert.NotNil(t, track.Reader)
 	assert.Equal(t, AudioFileTypeMP3, track.FileType)
+
+	content, err := ioutil.ReadAll(track.Reader)
+	require.NoError(t, err, "failed to read track")
+	assert.Equal(t, audio, content)
 }
 
 func TestGetTrack_NotStatusCodeOK(t *testing.T) {
@@ -161,7 +163,7 @@ func TestSearch(t *testing.T) {
 	client, err := NewClient(WithBaseURL(server.URL), WithHTTPClient(server.Client()))
 	require.NoError(t, err, "failed to create client")
 
-	tracks, err := client.Search(context.Background(), "some.search", TrackFilterRandom, 0)
+	tracks, err := client.Search(context.Background(), SearchOptions{Query: "some.search", Filter: TrackFilterRandom})
 	assert.NoError(t, err)
 	assert.Len(t, tracks, 20)
 
@@ -188,7 +190,18 @@ func TestSearch(t *testing.T) {
 		"https://chipmusic.org/Feryl/music/svanholm",
 	}
 
-	assert.ElementsMatch(t, expected, tracks)
+	assert.ElementsMatch(t, expected, SearchResultURLs(tracks))
+
+	expectedFirst := SearchResult{
+		URL:      "https://chipmusic.org/sloopygoop/music/actually-i-want-everything-wario-style-mariah-carey-cover",
+		Title:    "Actually, I Want Everything (Wario-style Mariah Carey cover)",
+		Artist:   "sloopygoop",
+		PostedAt: time.Date(2020, time.December, 19, 21, 53, 0, 0, time.UTC),
+		Views:    4,
+		Comments: 0,
+	}
+
+	assert.Equal(t, expectedFirst, tracks[0])
 }
 
 func TestSearch_NotStatusCodeOK(t *testing.T) {
@@ -201,7 +214,7 @@ func TestSearch_NotStatusCodeOK(t *testing.T) {
 	client, err := NewClient(WithBaseURL(server.URL), WithHTTPClient(server.Client()))
 	require.NoError(t, err, "failed to create client")
 
-	tracks, err := client.Search(context.Background(), "some.search", TrackFilterRandom, 0)
+	tracks, err := client.Search(context.Background(), SearchOptions{Query: "some.search", Filter: TrackFilterRandom})
 	assert.Error(t, err)
 	assert.Nil(t, tracks)
 }
@@ -217,7 +230,7 @@ func TestSearch_ResponseIsNotHTML(t *testing.T) {
 	client, err := NewClient(WithBaseURL(server.URL), WithHTTPClient(server.Client()))
 	require.NoError(t, err, "failed to create client")
 
-	tracks, err := client.Search(context.Background(), "some.search", TrackFilterRandom, 0)
+	tracks, err := client.Search(context.Background(), SearchOptions{Query: "some.search", Filter: TrackFilterRandom})
 	assert.NoError(t, err)
 	assert.Empty(t, tracks)
 }
@@ -230,7 +243,7 @@ func TestSearch_ErrorReturnedFromHTTPClient(t *testing.T) {
 	client, err := NewClient(WithHTTPClient(httpClient))
 	require.NoError(t, err, "failed to create client")
 
-	tracks, err := client.Search(context.Background(), "some.search", TrackFilterRandom, 0)
+	tracks, err := client.Search(context.Background(), SearchOptions{Query: "some.search", Filter: TrackFilterRandom})
 	assert.Error(t, err)
 	assert.Nil(t, tracks)
 }
@@ -249,4 +262,406 @@ func NewMockTransport(response *http.Response, err error) *MockTransport {
 
 func (m *MockTransport) RoundTrip(_ *http.Request) (*http.Response, error) {
 	return m.response, m.err
-}
\ No newline at end of file
+}
+
+func TestSupportedFileTypes(t *testing.T) {
+	fileTypes := SupportedFileTypes()
+	assert.Contains(t, fileTypes, AudioFileTypeMP3)
+	assert.Contains(t, fileTypes, AudioFileTypeWAV)
+	assert.Contains(t, fileTypes, AudioFileTypeOGG)
+	assert.Contains(t, fileTypes, AudioFileTypeFLAC)
+
+	// Modifying the returned slice should not modify the supported file types
+	fileTypes[0] = "some.type"
+	assert.Contains(t, SupportedFileTypes(), AudioFileTypeMP3)
+}
+
+func TestGetTrackInfo(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, err := ioutil.ReadFile(defaultTrackPageFile)
+		require.NoError(t, err, "failed to read content of %s as server response", defaultTrackPageFile)
+
+		_, err = w.Write(raw)
+		require.NoError(t, err, "failed to write %s as server response", defaultTrackPageFile)
+	}))
+
+	defer server.Close()
+
+	client, err := NewClient(WithBaseURL(server.URL), WithHTTPClient(server.Client()))
+	require.NoError(t, err, "failed to create client")
+
+	trackPageURL := fmt.Sprintf("%s/some.artist/music/some.music", server.URL)
+	track, err := client.GetTrackInfo(context.Background(), trackPageURL)
+	require.NoError(t, err, "should not have received an error when getting track info")
+	assert.Equal(t, "Lovesickness [2a03]", track.Title)
+	assert.Equal(t, "Fearofdark", track.Artist)
+	assert.Equal(t, trackPageURL, track.PageURL)
+	assert.Equal(t, "https://chipmusic.s3.amazonaws.com/music/2015/01/fearofdark_lovesickness-[2a03].mp3", track.DownloadURL)
+	assert.Equal(t, AudioFileTypeMP3, track.FileType)
+	assert.Equal(t, []string{"2a03", "chiptune", "nes", "nsf", "rock", "swing"}, track.Tags)
+	assert.Equal(t, time.Date(2015, time.January, 25, 23, 43, 0, 0, time.UTC), track.PostedAt)
+	assert.Equal(t, "Maybe I should start uploading here again...\nOpening track from The Coffee Zone: http://fearofdark.bandcamp.com/album/the-coffee-zone", track.Description)
+	assert.Nil(t, track.Reader)
+}
+
+func TestParsePostedAt(t *testing.T) {
+	testCases := []struct {
+		name     string
+		text     string
+		expected time.Time
+	}{
+		{"Morning", " on Dec 3, 2020 9:05 am", time.Date(2020, time.December, 3, 9, 5, 0, 0, time.UTC)},
+		{"Evening", " on Dec 19, 2020 9:53 pm", time.Date(2020, time.December, 19, 21, 53, 0, 0, time.UTC)},
+		{"Invalid", "on some.date", time.Time{}},
+		{"Empty", "", time.Time{}},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			assert.Equal(tt, testCase.expected, parsePostedAt(testCase.text))
+		})
+	}
+}
+
+func TestDownloadTrack_NilTrack(t *testing.T) {
+	client, err := NewClient()
+	require.NoError(t, err, "failed to create client")
+
+	err = client.DownloadTrack(context.Background(), nil)
+	assert.Error(t, err)
+}
+
+func TestGetTrack_EmptyTrack(t *testing.T) {
+	testCases := []struct {
+		name    string
+		ranges  bool
+		options []Option
+	}{
+		{"InMemory", false, nil},
+		{"InMemoryWithRanges", true, nil},
+		{"Spooled", false, []Option{WithSpoolDir(os.TempDir())}},
+		{"SpooledWithRanges", true, []Option{WithSpoolDir(os.TempDir())}},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			server := newTrackServer(tt, []byte{}, testCase.ranges)
+			defer server.Close()
+
+			options := append([]Option{WithBaseURL(server.URL), WithHTTPClient(server.Client())}, testCase.options...)
+			client, err := NewClient(options...)
+			require.NoError(tt, err, "failed to create client")
+
+			track, err := client.GetTrack(context.Background(), fmt.Sprintf("%s/some.artist/music/some.music", server.URL))
+			assert.True(tt, errors.Is(err, ErrEmptyTrack))
+			assert.Nil(tt, track)
+		})
+	}
+}
+
+// newStalledTrackServer returns a server like newTrackServer whose audio downloads never send a body. Every download
+// request blocks until the client aborts it and started receives a value once the request arrives
+func newStalledTrackServer(t *testing.T, length int, ranges bool, started chan<- struct{}, aborted *sync.WaitGroup) *httptest.Server {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != testAudioPath {
+			raw, err := ioutil.ReadFile(defaultTrackPageFile)
+			require.NoError(t, err, "failed to read content of %s as server response", defaultTrackPageFile)
+
+			_, err = w.Write([]byte(strings.Replace(string(raw), defaultTrackLink, server.URL+testAudioPath, 1)))
+			require.NoError(t, err, "failed to write %s as server response", defaultTrackPageFile)
+			return
+		}
+
+		w.Header().Set("Content-Length", fmt.Sprint(length))
+		if ranges {
+			w.Header().Set("Accept-Ranges", "bytes")
+		}
+
+		if r.Method != http.MethodGet {
+			return
+		}
+
+		aborted.Add(1)
+		defer aborted.Done()
+
+		started <- struct{}{}
+		<-r.Context().Done()
+	}))
+
+	return server
+}
+
+func TestDownloadTrack_Cancelled(t *testing.T) {
+	testCases := []struct {
+		name     string
+		ranges   bool
+		requests int
+	}{
+		{"WithWorkers", true, DefaultWorkers},
+		{"WithoutRanges", false, 1},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			started := make(chan struct{}, testCase.requests)
+			aborted := &sync.WaitGroup{}
+			server := newStalledTrackServer(tt, 1000, testCase.ranges, started, aborted)
+			defer server.Close()
+
+			client, err := NewClient(WithBaseURL(server.URL), WithHTTPClient(server.Client()))
+			require.NoError(tt, err, "failed to create client")
+
+			ctx, cancel := context.WithCancel(context.Background())
+			errs := make(chan error, 1)
+			go func() {
+				_, err := client.GetTrack(ctx, fmt.Sprintf("%s/some.artist/music/some.music", server.URL))
+				errs <- err
+			}()
+
+			for i := 0; i < testCase.requests; i++ {
+				select {
+				case <-started:
+				case <-time.After(defaultTestTimeout):
+					tt.Fatalf("only %d of %d download requests started", i, testCase.requests)
+				}
+			}
+
+			cancel()
+
+			select {
+			case err := <-errs:
+				assert.True(tt, errors.Is(err, context.Canceled), "expected the download to be cancelled but got %v", err)
+			case <-time.After(defaultTestTimeout):
+				tt.Fatal("download did not stop after being cancelled")
+			}
+
+			// Every in-flight request is aborted rather than left running in the background
+			done := make(chan struct{})
+			go func() {
+				aborted.Wait()
+				close(done)
+			}()
+
+			select {
+			case <-done:
+			case <-time.After(defaultTestTimeout):
+				tt.Fatal("download requests were not aborted after being cancelled")
+			}
+		})
+	}
+}
+
+func TestSplitRanges(t *testing.T) {
+	testCases := []struct {
+		name     string
+		length   int64
+		workers  int
+		expected []byteRange
+	}{
+		{"Even", 9, 3, []byteRange{{0, 3}, {3, 6}, {6, 9}}},
+		{"Remainder", 10, 3, []byteRange{{0, 3}, {3, 6}, {6, 10}}},
+		{"MoreWorkersThanBytes", 2, 40, []byteRange{{0, 1}, {1, 2}}},
+		{"Empty", 0, 40, []byteRange{}},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			assert.Equal(tt, testCase.expected, splitRanges(testCase.length, testCase.workers))
+		})
+	}
+}
+
+func TestSplitRanges_CoversEveryByte(t *testing.T) {
+	for _, length := range []int64{1, 39, 40, 41, 1000, 12345, 1 << 20} {
+		for _, workers := range []int{1, 3, 7, DefaultWorkers} {
+			next := int64(0)
+			for _, r := range splitRanges(length, workers) {
+				require.Equal(t, next, r.start, "expected range to start where the previous range ended")
+				require.True(t, r.len() > 0, "expected range %s to be non-empty", r)
+				next = r.end
+			}
+
+			assert.Equal(t, length, next, "expected ranges to cover all %d bytes with %d workers", length, workers)
+		}
+	}
+}
+
+func TestDownloadTrack_Integrity(t *testing.T) {
+	for _, length := range []int{1, 39, 41, 1001, 65537} {
+		for _, spooled := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%d/spooled=%t", length, spooled), func(tt *testing.T) {
+				audio := randomAudio(tt, length)
+				server := newTrackServer(tt, audio, true)
+				defer server.Close()
+
+				options := []Option{WithBaseURL(server.URL), WithHTTPClient(server.Client())}
+				if spooled {
+					options = append(options, WithSpoolDir(os.TempDir()))
+				}
+
+				client, err := NewClient(options...)
+				require.NoError(tt, err, "failed to create client")
+
+				track, err := client.GetTrack(context.Background(), fmt.Sprintf("%s/some.artist/music/some.music", server.URL))
+				require.NoError(tt, err, "failed to get track")
+
+				defer track.Close()
+
+				content, err := ioutil.ReadAll(track.Reader)
+				require.NoError(tt, err, "failed to read track")
+				assert.Equal(tt, audio, content, "expected every byte of the track to be downloaded")
+				assert.Equal(tt, int64(length), track.Size)
+				assert.False(tt, track.FromCache)
+			})
+		}
+	}
+}
+
+// newCorruptTrackServer returns a server like newTrackServer whose audio is served by serveAudio
+func newCorruptTrackServer(t *testing.T, serveAudio http.HandlerFunc) *httptest.Server {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == testAudioPath {
+			serveAudio(w, r)
+			return
+		}
+
+		raw, err := ioutil.ReadFile(defaultTrackPageFile)
+		require.NoError(t, err, "failed to read content of %s as server response", defaultTrackPageFile)
+
+		_, err = w.Write([]byte(strings.Replace(string(raw), defaultTrackLink, server.URL+testAudioPath, 1)))
+		require.NoError(t, err, "failed to write %s as server response", defaultTrackPageFile)
+	}))
+
+	return server
+}
+
+func TestDownloadTrack_Corrupt(t *testing.T) {
+	// Servers which mishandle Range requests are recovered from by downloading the track with a single request, so
+	// only corrupt downloads without ranges fail
+	audio := randomAudio(t, 1000)
+	testCases := []struct {
+		name       string
+		serveAudio http.HandlerFunc
+		expected   error
+	}{
+		{
+			name: "ShortChunk",
+			serveAudio: func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Range") == "bytes=500-999" {
+					w.Header().Set("Content-Range", "bytes 500-999/1000")
+					w.Header().Set("Content-Length", "10")
+					w.WriteHeader(http.StatusPartialContent)
+					w.Write(audio[500:510])
+					return
+				}
+
+				http.ServeContent(w, r, "some.track.mp3", time.Time{}, bytes.NewReader(audio))
+			},
+			expected: nil,
+		},
+		{
+			name: "LongChunk",
+			serveAudio: func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Range") == "bytes=0-499" {
+					w.Header().Set("Content-Range", "bytes 0-499/1000")
+					w.WriteHeader(http.StatusPartialContent)
+					w.Write(audio)
+					return
+				}
+
+				http.ServeContent(w, r, "some.track.mp3", time.Time{}, bytes.NewReader(audio))
+			},
+			expected: nil,
+		},
+		{
+			name: "WrongRange",
+			serveAudio: func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Range") == "bytes=500-999" {
+					r.Header.Set("Range", "bytes=0-499")
+				}
+
+				http.ServeContent(w, r, "some.track.mp3", time.Time{}, bytes.NewReader(audio))
+			},
+			expected: nil,
+		},
+		{
+			name: "ChangedDuringDownload",
+			serveAudio: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("ETag", `"v1"`)
+				if r.Method == http.MethodGet {
+					w.Header().Set("ETag", `"v2"`)
+				}
+
+				http.ServeContent(w, r, "some.track.mp3", time.Time{}, bytes.NewReader(audio))
+			},
+			expected: nil,
+		},
+		{
+			name: "ShortEverywhere",
+			serveAudio: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Accept-Ranges", "bytes")
+				if r.Method == http.MethodHead {
+					w.Header().Set("Content-Length", "1000")
+					return
+				}
+
+				if r.Header.Get("Range") == "" {
+					w.Write(audio[:600])
+					return
+				}
+
+				w.Header().Set("Content-Range", "bytes 0-499/1000")
+				w.Header().Set("Content-Length", "10")
+				w.WriteHeader(http.StatusPartialContent)
+				w.Write(audio[:10])
+			},
+			expected: ErrIncompleteDownload,
+		},
+		{
+			name: "ShortWithoutRanges",
+			serveAudio: func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodHead {
+					w.Header().Set("Content-Length", "1000")
+					return
+				}
+
+				w.Write(audio[:600])
+			},
+			expected: ErrIncompleteDownload,
+		},
+	}
+
+	for _, testCase := range testCases {
+		for _, spooled := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s/spooled=%t", testCase.name, spooled), func(tt *testing.T) {
+				server := newCorruptTrackServer(tt, testCase.serveAudio)
+				defer server.Close()
+
+				options := []Option{WithBaseURL(server.URL), WithHTTPClient(server.Client()), WithWorkers(2)}
+				if spooled {
+					options = append(options, WithSpoolDir(os.TempDir()))
+				}
+
+				client, err := NewClient(options...)
+				require.NoError(tt, err, "failed to create client")
+
+				track, err := client.GetTrack(context.Background(), fmt.Sprintf("%s/some.artist/music/some.music", server.URL))
+				var content []byte
+				if err == nil {
+					defer track.Close()
+					content, err = ioutil.ReadAll(track.Reader)
+				}
+
+				if testCase.expected == nil {
+					require.NoError(tt, err)
+					assert.Equal(tt, audio, content)
+					return
+				}
+
+				assert.True(tt, errors.Is(err, testCase.expected), "expected %v but got %v", testCase.expected, err)
+			})
+		}
+	}
+}
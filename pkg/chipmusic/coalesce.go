@@ -0,0 +1,185 @@
+package chipmusic
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// flightGroup coalesces concurrent calls for the same key, e.g. when a prefetch and the dashboard ask for the same track
+// at once, so the work is only done once and every caller shares its result
+type flightGroup struct {
+	mux     sync.Mutex
+	flights map[string]*flight
+}
+
+// flight is a call in progress or done whose result wasn't taken by every caller yet
+type flight struct {
+	done   chan struct{}
+	cancel context.CancelFunc
+	val    interface{}
+	err    error
+
+	// waiters is the number of callers which wait for the result or, once it is done, which didn't take it yet
+	waiters int
+}
+
+// flightResult describes how to share the result of a flight between callers
+type flightResult struct {
+
+	// take returns the copy of the result for a caller, e.g. a reader of its own. If nil, the result is shared as is
+	take func(val interface{}) (interface{}, error)
+
+	// release releases the resources of the result once every caller took a copy of it. If nil, there is nothing to
+	// release
+	release func(val interface{})
+}
+
+func newFlightGroup() *flightGroup {
+	return &flightGroup{flights: map[string]*flight{}}
+}
+
+// do calls fn once for concurrent calls with the same key and returns its result to every caller. fn runs with a
+// context which isn't cancelled when one caller gives up but only once every caller did, so callers which are still
+// waiting get the result
+func (g *flightGroup) do(ctx context.Context, key string, result flightResult, fn func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	g.mux.Lock()
+	f, ok := g.flights[key]
+	if !ok {
+		flightCtx, cancel := context.WithCancel(context.Background())
+		f = &flight{done: make(chan struct{}), cancel: cancel}
+		g.flights[key] = f
+		go g.run(flightCtx, key, f, result, fn)
+	}
+
+	f.waiters++
+	g.mux.Unlock()
+
+	select {
+	case <-f.done:
+	case <-ctx.Done():
+	}
+
+	g.mux.Lock()
+	select {
+	case <-f.done:
+	default:
+		// The caller gave up before the result was ready. Once every caller gave up, the work is cancelled and the next
+		// call starts over
+		f.waiters--
+		if f.waiters == 0 {
+			f.cancel()
+			if g.flights[key] == f {
+				delete(g.flights, key)
+			}
+		}
+
+		g.mux.Unlock()
+		return nil, ctx.Err()
+	}
+
+	val, err := f.val, f.err
+	switch {
+	case err != nil:
+	case ctx.Err() != nil:
+		val, err = nil, ctx.Err()
+	case result.take != nil:
+		val, err = result.take(val)
+	}
+
+	// The result is released once every caller took its copy, which is outside of the lock since releasing it may
+	// block, e.g. until a download stops
+	f.waiters--
+	release := f.waiters == 0 && f.err == nil && result.release != nil
+	g.mux.Unlock()
+
+	if release {
+		result.release(f.val)
+	}
+
+	return val, err
+}
+
+// run calls fn for a flight and stores its result
+func (g *flightGroup) run(ctx context.Context, key string, f *flight, result flightResult, fn func(ctx context.Context) (interface{}, error)) {
+	val, err := fn(ctx)
+
+	g.mux.Lock()
+	f.val, f.err = val, err
+	if g.flights[key] == f {
+		delete(g.flights, key)
+	}
+
+	close(f.done)
+	release := f.waiters == 0 && err == nil && result.release != nil
+	g.mux.Unlock()
+
+	f.cancel()
+
+	// Every caller gave up before the result was ready, so nobody will take it
+	if release {
+		result.release(val)
+	}
+}
+
+// trackAudioResult shares the audio of a track downloaded by DownloadTrack. Every caller gets a reader of its own which
+// reads from the start
+var trackAudioResult = flightResult{
+	take: func(val interface{}) (interface{}, error) {
+		downloaded := val.(*Track)
+		reader, err := shareReader(downloaded.Reader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to share track audio: %w", err)
+		}
+
+		track := *downloaded
+		track.Reader = reader
+		return &track, nil
+	},
+	release: func(val interface{}) {
+		_ = val.(*Track).Close()
+	},
+}
+
+// trackInfoResult shares the info of a track returned by GetTrackInfo. Every caller gets a copy of its own since tracks
+// are changed by their callers, e.g. when their audio is downloaded
+var trackInfoResult = flightResult{
+	take: func(val interface{}) (interface{}, error) {
+		track := *val.(*Track)
+		track.Tags = append([]string(nil), track.Tags...)
+		return &track, nil
+	},
+}
+
+// shareReader returns another reader of the same audio as reader which reads from the start. Closing either reader
+// doesn't affect the other
+func shareReader(reader ReadSeekCloser) (ReadSeekCloser, error) {
+	switch r := reader.(type) {
+	case *SpoolReader:
+		shared, err := r.share()
+		if err != nil {
+			return nil, err
+		}
+
+		return shared, nil
+	case *RangeReader:
+		return newRangeReader(r.client, r.url, r.length, r.window), nil
+	case *os.File:
+		// Cached tracks are read from the cache file, which is opened again
+		file, err := os.Open(r.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		return file, nil
+	case *ReadSeekNopCloser:
+		if content, ok := r.Reader.(*bytes.Reader); ok {
+			return &ReadSeekNopCloser{Reader: io.NewSectionReader(content, 0, content.Size())}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("can't share a reader of type %T", reader)
+}
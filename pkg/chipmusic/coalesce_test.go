@@ -0,0 +1,286 @@
+package chipmusic
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFlightGroup(t *testing.T) {
+	g := newFlightGroup()
+	release := make(chan struct{})
+	var calls, taken, released int32
+	result := flightResult{
+		take: func(val interface{}) (interface{}, error) {
+			atomic.AddInt32(&taken, 1)
+			return val, nil
+		},
+		release: func(val interface{}) {
+			atomic.AddInt32(&released, 1)
+		},
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			val, err := g.do(context.Background(), "some.key", result, func(ctx context.Context) (interface{}, error) {
+				atomic.AddInt32(&calls, 1)
+				<-release
+				return "some.value", nil
+			})
+
+			assert.NoError(t, err)
+			assert.Equal(t, "some.value", val)
+		}()
+	}
+
+	waitForWaiters(t, g, "some.key", 3)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), calls)
+	assert.Equal(t, int32(3), taken)
+	assert.Equal(t, int32(1), released)
+	assert.Empty(t, g.flights)
+}
+
+func TestFlightGroup_CallerGivesUp(t *testing.T) {
+	g := newFlightGroup()
+	release := make(chan struct{})
+	cancelled := make(chan bool, 1)
+	fn := func(ctx context.Context) (interface{}, error) {
+		select {
+		case <-release:
+			cancelled <- false
+			return "some.value", nil
+		case <-ctx.Done():
+			cancelled <- true
+			return nil, ctx.Err()
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error, 1)
+	go func() {
+		_, err := g.do(ctx, "some.key", flightResult{}, fn)
+		errs <- err
+	}()
+
+	results := make(chan interface{}, 1)
+	go func() {
+		val, _ := g.do(context.Background(), "some.key", flightResult{}, fn)
+		results <- val
+	}()
+
+	waitForWaiters(t, g, "some.key", 2)
+
+	// The work goes on for the caller which is still waiting
+	cancel()
+	assert.True(t, errors.Is(<-errs, context.Canceled))
+	close(release)
+	assert.Equal(t, "some.value", <-results)
+	assert.False(t, <-cancelled)
+}
+
+func TestFlightGroup_EveryCallerGivesUp(t *testing.T) {
+	g := newFlightGroup()
+	started := make(chan struct{})
+	cancelled := make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-started
+		cancel()
+	}()
+
+	_, err := g.do(ctx, "some.key", flightResult{}, func(ctx context.Context) (interface{}, error) {
+		close(started)
+		<-ctx.Done()
+		close(cancelled)
+		return nil, ctx.Err()
+	})
+
+	assert.True(t, errors.Is(err, context.Canceled))
+	select {
+	case <-cancelled:
+	case <-time.After(defaultTestTimeout):
+		t.Fatal("work was not cancelled after every caller gave up")
+	}
+
+	// The next call starts over
+	val, err := g.do(context.Background(), "some.key", flightResult{}, func(ctx context.Context) (interface{}, error) {
+		return "some.value", nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "some.value", val)
+}
+
+func TestClient_CoalescesRequests(t *testing.T) {
+	audio := randomAudio(t, 100000)
+	tracks := newTrackServer(t, audio, true)
+	defer tracks.Close()
+
+	spoolDir, err := ioutil.TempDir("", "chipmusic-coalesce")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(spoolDir)
+
+	testCases := []struct {
+		name    string
+		options []Option
+	}{
+		{"Memory", nil},
+		{"Spool", []Option{WithSpoolDir(spoolDir)}},
+		{"Stream", []Option{WithStreaming(DefaultStreamWindow)}},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			// Requests for the track page and the audio metadata wait until every caller asked for them
+			pageGate, headGate := make(chan struct{}), make(chan struct{})
+			var pages, heads int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.URL.Path != testAudioPath:
+					atomic.AddInt32(&pages, 1)
+					<-pageGate
+				case r.Method == http.MethodHead:
+					atomic.AddInt32(&heads, 1)
+					<-headGate
+				}
+
+				tracks.Config.Handler.ServeHTTP(w, r)
+			}))
+
+			defer server.Close()
+
+			client, err := NewClient(append(testCase.options, WithBaseURL(server.URL), WithHTTPClient(server.Client()))...)
+			require.NoError(tt, err)
+
+			trackURL := server.URL + "/some.artist/music/some.track"
+			infos := make([]*Track, 2)
+			var wg sync.WaitGroup
+			for i := range infos {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					info, err := client.GetTrackInfo(context.Background(), trackURL)
+					assert.NoError(tt, err)
+					infos[i] = info
+				}(i)
+			}
+
+			waitForWaiters(tt, client.pages, trackURL, 2)
+			close(pageGate)
+			wg.Wait()
+
+			require.NotNil(tt, infos[0])
+			require.NotNil(tt, infos[1])
+			assert.Equal(tt, int32(1), atomic.LoadInt32(&pages))
+			assert.Equal(tt, infos[0], infos[1])
+			assert.False(tt, infos[0] == infos[1], "every caller should get a track of its own")
+
+			// The page links to the audio on the track server, so it is downloaded through the gated server instead
+			for _, info := range infos {
+				info.DownloadURL = server.URL + testAudioPath
+				wg.Add(1)
+				go func(info *Track) {
+					defer wg.Done()
+					assert.NoError(tt, client.DownloadTrack(context.Background(), info))
+				}(info)
+			}
+
+			waitForWaiters(tt, client.downloads, infos[0].DownloadURL, 2)
+			close(headGate)
+			wg.Wait()
+
+			assert.Equal(tt, int32(1), atomic.LoadInt32(&heads))
+
+			// Each track has a reader of its own, so closing one doesn't affect the other
+			first, err := ioutil.ReadAll(infos[0].Reader)
+			require.NoError(tt, err)
+			assert.Equal(tt, audio, first)
+			require.NoError(tt, infos[0].Close())
+
+			second, err := ioutil.ReadAll(infos[1].Reader)
+			require.NoError(tt, err)
+			assert.Equal(tt, audio, second)
+			require.NoError(tt, infos[1].Close())
+
+			spools, err := filepath.Glob(filepath.Join(spoolDir, "*.spool"))
+			require.NoError(tt, err)
+			assert.Empty(tt, spools, "the spool file should be removed once every reader is closed")
+		})
+	}
+}
+
+// waitForWaiters waits until waiters callers wait for the flight with key
+func waitForWaiters(t *testing.T, g *flightGroup, key string, waiters int) {
+	deadline := time.Now().Add(defaultTestTimeout)
+	for time.Now().Before(deadline) {
+		g.mux.Lock()
+		f, ok := g.flights[key]
+		ready := ok && f.waiters == waiters
+		g.mux.Unlock()
+
+		if ready {
+			return
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	t.Fatalf("%d callers did not wait for %s after %s", waiters, key, defaultTestTimeout)
+}
+
+func TestShareReader(t *testing.T) {
+	file, err := ioutil.TempFile("", "chipmusic-share")
+	require.NoError(t, err)
+
+	defer os.Remove(file.Name())
+
+	_, err = file.Write([]byte("some.audio"))
+	require.NoError(t, err)
+
+	tests := map[string]struct {
+		reader ReadSeekCloser
+		isErr  bool
+	}{
+		"file":    {reader: file},
+		"memory":  {reader: &ReadSeekNopCloser{Reader: bytes.NewReader([]byte("some.audio"))}},
+		"unknown": {reader: &ReadSeekNopCloser{Reader: strings.NewReader("some.audio")}, isErr: true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(tt *testing.T) {
+			shared, err := shareReader(test.reader)
+			if test.isErr {
+				assert.Error(tt, err)
+				assert.Nil(tt, shared)
+				return
+			}
+
+			require.NoError(tt, err)
+			require.NoError(tt, test.reader.Close())
+
+			content, err := ioutil.ReadAll(shared)
+			assert.NoError(tt, err)
+			assert.Equal(tt, "some.audio", string(content))
+			assert.NoError(tt, shared.Close())
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package chipmusic
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// WithMaxConnsPerHost allows limiting how many requests can be in flight to each host at once. The limit is shared by
+// every request of the client, so downloading several tracks at once doesn't multiply the number of open connections.
+// A request holds its slot until its response body is closed
+func WithMaxConnsPerHost(conns int) Option {
+	return func(c *Client) error {
+		if conns <= 0 {
+			return errors.New("max connections per host must be a positive integer")
+		}
+
+		c.maxConnsPerHost = conns
+		return nil
+	}
+}
+
+// hostLimiter is a semaphore per host
+type hostLimiter struct {
+	conns int
+
+	mux   sync.Mutex
+	slots map[string]chan struct{}
+}
+
+func newHostLimiter(conns int) *hostLimiter {
+	return &hostLimiter{conns: conns, slots: map[string]chan struct{}{}}
+}
+
+// acquire blocks until a slot for host is free or ctx is done. The returned function releases the slot
+func (l *hostLimiter) acquire(ctx context.Context, host string) (func(), error) {
+	l.mux.Lock()
+	slots, ok := l.slots[host]
+	if !ok {
+		slots = make(chan struct{}, l.conns)
+		l.slots[host] = slots
+	}
+
+	l.mux.Unlock()
+
+	select {
+	case slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	once := sync.Once{}
+	return func() {
+		once.Do(func() { <-slots })
+	}, nil
+}
+
+// connLimitedTransport is an http.RoundTripper which waits for a free slot for the host before sending each request
+type connLimitedTransport struct {
+	base    http.RoundTripper
+	limiter *hostLimiter
+}
+
+func (t *connLimitedTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	release, err := t.limiter.acquire(request.Context(), request.URL.Host)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := t.base.RoundTrip(request)
+	if err != nil {
+		release()
+		return nil, err
+	}
+
+	// HEAD responses have no body to read, so the connection is free as soon as the headers arrive
+	if request.Method == http.MethodHead || response.Body == nil {
+		release()
+		return response, nil
+	}
+
+	response.Body = &releasingBody{ReadCloser: response.Body, release: release}
+	return response, nil
+}
+
+// releasingBody releases the slot of its request once the body is fully read or closed
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releasingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err == io.EOF {
+		b.release()
+	}
+
+	return n, err
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
@@ -0,0 +1,92 @@
+package chipmusic
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWithMaxConnsPerHost(t *testing.T) {
+	for _, conns := range []int{0, -1} {
+		_, err := NewClient(WithMaxConnsPerHost(conns))
+		assert.Error(t, err)
+	}
+}
+
+func TestHostLimiter(t *testing.T) {
+	limiter := newHostLimiter(1)
+	release, err := limiter.acquire(context.Background(), "chipmusic.org")
+	require.NoError(t, err)
+
+	// Other hosts have their own slots
+	releaseOther, err := limiter.acquire(context.Background(), "chipmusic.s3.amazonaws.com")
+	require.NoError(t, err)
+	releaseOther()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err = limiter.acquire(ctx, "chipmusic.org")
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), "expected to wait for the taken slot")
+
+	// Releasing twice must not free a slot held by another request
+	release()
+	release()
+
+	release, err = limiter.acquire(context.Background(), "chipmusic.org")
+	require.NoError(t, err)
+	defer release()
+
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err = limiter.acquire(ctx, "chipmusic.org")
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+}
+
+func TestClient_MaxConnsPerHost(t *testing.T) {
+	for _, conns := range []int{1, 3} {
+		t.Run(fmt.Sprint(conns), func(tt *testing.T) {
+			audio := randomAudio(tt, 1000)
+			var inFlight, maxInFlight int32
+			server := newCorruptTrackServer(tt, func(w http.ResponseWriter, r *http.Request) {
+				current := atomic.AddInt32(&inFlight, 1)
+				defer atomic.AddInt32(&inFlight, -1)
+				for {
+					highest := atomic.LoadInt32(&maxInFlight)
+					if current <= highest || atomic.CompareAndSwapInt32(&maxInFlight, highest, current) {
+						break
+					}
+				}
+
+				time.Sleep(time.Millisecond)
+				http.ServeContent(w, r, "some.track.mp3", time.Time{}, bytes.NewReader(audio))
+			})
+
+			defer server.Close()
+
+			client, err := NewClient(WithBaseURL(server.URL), WithHTTPClient(server.Client()), WithMaxConnsPerHost(conns))
+			require.NoError(tt, err, "failed to create client")
+
+			ctx, cancel := context.WithTimeout(context.Background(), defaultTestTimeout)
+			defer cancel()
+
+			track, err := client.GetTrack(ctx, fmt.Sprintf("%s/some.artist/music/some.music", server.URL))
+			require.NoError(tt, err, "failed to get track")
+
+			content, err := ioutil.ReadAll(track.Reader)
+			require.NoError(tt, err, "failed to read track")
+
+			assert.Equal(tt, audio, content)
+			assert.True(tt, atomic.LoadInt32(&maxInFlight) <= int32(conns), "expected at most %d requests in flight but got %d", conns, maxInFlight)
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package chipmusic
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// maxDebugBodySize is the maximum number of bytes of a body written to the debug log. Longer bodies are truncated
+	maxDebugBodySize = 1 << 20
+
+	redacted = "[REDACTED]"
+)
+
+var (
+	// sensitiveHeaders are headers whose values are never written to the debug log
+	sensitiveHeaders = map[string]bool{
+		"Authorization":       true,
+		"Cookie":              true,
+		"Proxy-Authorization": true,
+		"Set-Cookie":          true,
+	}
+)
+
+// WithHTTPDebugLog allows writing the method, URL, status, and headers of every request and response to w, which helps
+// diagnose scraping problems. Credentials and cookies are redacted. If bodies is true, the bodies of HTML responses are
+// written as well, truncated to 1 MiB
+func WithHTTPDebugLog(w io.Writer, bodies bool) Option {
+	return func(c *Client) error {
+		if w == nil {
+			return errors.New("debug log cannot be nil")
+		}
+
+		c.debugLog = w
+		c.debugBodies = bodies
+		return nil
+	}
+}
+
+// debugTransport is an http.RoundTripper which writes every request and response it sends to a debug log. Each request
+// is numbered so concurrent requests can be told apart
+type debugTransport struct {
+	base   http.RoundTripper
+	bodies bool
+	now    func() time.Time
+	next   int64
+
+	mux sync.Mutex
+	w   io.Writer
+}
+
+func (t *debugTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	id := atomic.AddInt64(&t.next, 1)
+	buffer := &bytes.Buffer{}
+	fmt.Fprintf(buffer, "> #%d %s %s\n", id, request.Method, request.URL.Redacted())
+	writeDebugHeaders(buffer, ">", request.Header)
+
+	start := t.now()
+	response, err := t.base.RoundTrip(request)
+	elapsed := t.now().Sub(start).Round(time.Millisecond)
+	if err != nil {
+		fmt.Fprintf(buffer, "< #%d failed after %s: %v\n", id, elapsed, err)
+		t.write(buffer.Bytes())
+		return nil, err
+	}
+
+	fmt.Fprintf(buffer, "< #%d %s %s after %s\n", id, response.Proto, response.Status, elapsed)
+	writeDebugHeaders(buffer, "<", response.Header)
+
+	if t.bodies && isHTML(response) {
+		body, err := ioutil.ReadAll(io.LimitReader(response.Body, maxDebugBodySize))
+		if err != nil {
+			response.Body.Close()
+			fmt.Fprintf(buffer, "< #%d failed to read body: %v\n", id, err)
+			t.write(buffer.Bytes())
+			return nil, err
+		}
+
+		fmt.Fprintf(buffer, "< #%d body (%d bytes):\n%s\n", id, len(body), body)
+
+		// The body is put back together so the caller reads it as if it was never logged
+		response.Body = &struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), response.Body), response.Body}
+	}
+
+	t.write(buffer.Bytes())
+	return response, nil
+}
+
+// write writes an entry to the debug log. Entries are written whole so entries of concurrent requests don't interleave
+func (t *debugTransport) write(entry []byte) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+
+	// Failing to write the debug log must not fail the request
+	_, _ = t.w.Write(entry)
+}
+
+// writeDebugHeaders writes headers sorted by name with the values of sensitive headers redacted
+func writeDebugHeaders(w io.Writer, prefix string, header http.Header) {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	for _, name := range names {
+		value := strings.Join(header[name], ", ")
+		if sensitiveHeaders[http.CanonicalHeaderKey(name)] {
+			value = redacted
+		}
+
+		fmt.Fprintf(w, "%s   %s: %s\n", prefix, name, value)
+	}
+}
+
+func isHTML(response *http.Response) bool {
+	mediaType, _, err := mime.ParseMediaType(response.Header.Get("Content-Type"))
+	return err == nil && mediaType == "text/html"
+}
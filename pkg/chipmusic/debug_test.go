@@ -0,0 +1,107 @@
+package chipmusic
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWithHTTPDebugLog(t *testing.T) {
+	client, err := NewClient(WithHTTPDebugLog(nil, false))
+	assert.Error(t, err)
+	assert.Nil(t, client)
+}
+
+func TestDebugTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Set-Cookie", "session=secret")
+		if r.URL.Path == "/page" {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			_, _ = w.Write([]byte("<html>page</html>"))
+			return
+		}
+
+		w.Header().Set("Content-Type", "audio/mpeg")
+		_, _ = w.Write([]byte("audio"))
+	}))
+	defer server.Close()
+
+	testCases := []struct {
+		name        string
+		path        string
+		bodies      bool
+		body        string
+		expectLog   []string
+		unexpectLog []string
+	}{
+		{
+			name:        "Metadata",
+			path:        "/page",
+			body:        "<html>page</html>",
+			expectLog:   []string{"> #1 GET " + server.URL + "/page", "Cookie: [REDACTED]", "Set-Cookie: [REDACTED]", "< #1 HTTP/1.1 200 OK"},
+			unexpectLog: []string{"secret", "<html>page</html>"},
+		},
+		{
+			name:      "HTMLBody",
+			path:      "/page",
+			bodies:    true,
+			body:      "<html>page</html>",
+			expectLog: []string{"< #1 body (17 bytes):\n<html>page</html>"},
+		},
+		{
+			name:        "AudioBody",
+			path:        "/audio",
+			bodies:      true,
+			body:        "audio",
+			expectLog:   []string{"Content-Type: audio/mpeg"},
+			unexpectLog: []string{"body"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			log := &bytes.Buffer{}
+			client, err := NewClient(WithHTTPClient(server.Client()), WithHTTPDebugLog(log, testCase.bodies))
+			require.NoError(tt, err)
+
+			request, err := http.NewRequest(http.MethodGet, server.URL+testCase.path, nil)
+			require.NoError(tt, err)
+			request.Header.Set("Cookie", "session=secret")
+
+			response, err := client.client.Do(request)
+			require.NoError(tt, err)
+
+			defer response.Body.Close()
+
+			// Logging the body must not consume it
+			body, err := ioutil.ReadAll(response.Body)
+			require.NoError(tt, err)
+			assert.Equal(tt, testCase.body, string(body))
+
+			for _, expected := range testCase.expectLog {
+				assert.Contains(tt, log.String(), expected)
+			}
+
+			for _, unexpected := range testCase.unexpectLog {
+				assert.NotContains(tt, log.String(), unexpected)
+			}
+		})
+	}
+}
+
+func TestDebugTransport_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.Close()
+
+	log := &bytes.Buffer{}
+	client, err := NewClient(WithHTTPClient(&http.Client{}), WithHTTPDebugLog(log, false))
+	require.NoError(t, err)
+
+	_, err = client.client.Get(server.URL)
+	assert.Error(t, err)
+	assert.Contains(t, log.String(), "< #1 failed after")
+}
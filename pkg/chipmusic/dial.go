@@ -0,0 +1,180 @@
+package chipmusic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// IPAny lets the client connect over either IPv4 or IPv6
+	IPAny IPVersion = ""
+
+	// IPv4 forces the client to only connect over IPv4
+	IPv4 IPVersion = "ipv4"
+
+	// IPv6 forces the client to only connect over IPv6
+	IPv6 IPVersion = "ipv6"
+
+	// DefaultAudioHost is the host which serves the audio files of tracks on chipmusic.org
+	DefaultAudioHost = "chipmusic.s3.amazonaws.com"
+
+	defaultDNSPort = "53"
+	dnsDialTimeout = 5 * time.Second
+)
+
+// IPVersion is an enumeration of the IP versions the client can be forced to connect over
+type IPVersion string
+
+// WithDNSServers allows resolving hosts with these DNS servers instead of the resolver of the system, which is useful
+// when the resolver of the system breaks on the audio host. Servers are given as IP addresses with an optional port,
+// e.g. 1.1.1.1 or [2606:4700:4700::1111]:53. Servers are tried in order until one answers
+func WithDNSServers(servers ...string) Option {
+	return func(c *Client) error {
+		if len(servers) == 0 {
+			return errors.New("DNS servers cannot be empty")
+		}
+
+		normalized := make([]string, 0, len(servers))
+		for _, server := range servers {
+			address, err := dnsServerAddress(server)
+			if err != nil {
+				return err
+			}
+
+			normalized = append(normalized, address)
+		}
+
+		c.dnsServers = normalized
+		return nil
+	}
+}
+
+// WithIPVersion allows forcing the client to connect over IPv4 or IPv6 only
+func WithIPVersion(version IPVersion) Option {
+	return func(c *Client) error {
+		switch version {
+		case IPAny, IPv4, IPv6:
+			c.ipVersion = version
+			return nil
+		default:
+			return fmt.Errorf("unknown IP version %q. Allowed versions: [%s, %s]", version, IPv4, IPv6)
+		}
+	}
+}
+
+// dnsServerAddress adds the default DNS port to server if it doesn't have one
+func dnsServerAddress(server string) (string, error) {
+	host, port, err := net.SplitHostPort(server)
+	if err != nil {
+		host, port = strings.Trim(server, "[]"), defaultDNSPort
+	}
+
+	if net.ParseIP(host) == nil {
+		return "", fmt.Errorf("DNS server %q must be an IP address", server)
+	}
+
+	return net.JoinHostPort(host, port), nil
+}
+
+// LookupHost resolves host the same way the client does when connecting to it, honoring the configured DNS servers and
+// IP version. It is useful for diagnosing connection problems
+func (c *Client) LookupHost(ctx context.Context, host string) ([]string, error) {
+	addresses, err := c.resolver().LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up %s: %w", host, err)
+	}
+
+	ips := make([]string, 0, len(addresses))
+	for _, address := range addresses {
+		isIPv4 := address.IP.To4() != nil
+		if c.ipVersion == IPv4 && !isIPv4 || c.ipVersion == IPv6 && isIPv4 {
+			continue
+		}
+
+		ips = append(ips, address.IP.String())
+	}
+
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("failed to look up %s: no %s addresses", host, c.ipVersion)
+	}
+
+	return ips, nil
+}
+
+// resolver returns the resolver for the configured DNS servers. The Go resolver is used with custom servers since the
+// resolver of the system can't be pointed at other servers
+func (c *Client) resolver() *net.Resolver {
+	if len(c.dnsServers) == 0 {
+		return net.DefaultResolver
+	}
+
+	servers := c.dnsServers
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			dialer := net.Dialer{Timeout: dnsDialTimeout}
+			var err error
+			for _, server := range servers {
+				var conn net.Conn
+				if conn, err = dialer.DialContext(ctx, network, server); err == nil {
+					return conn, nil
+				}
+			}
+
+			return nil, err
+		},
+	}
+}
+
+// network returns the network to dial for the configured IP version
+func (c *Client) network(network string) string {
+	switch c.ipVersion {
+	case IPv4:
+		return network + "4"
+	case IPv6:
+		return network + "6"
+	default:
+		return network
+	}
+}
+
+// withDialer returns a copy of client whose transport dials with the configured DNS servers and IP version
+func (c *Client) withDialer(client *http.Client) (*http.Client, error) {
+	dialer := &net.Dialer{
+		Timeout:   c.dialTimeout,
+		KeepAlive: keepAlive,
+		Resolver:  c.resolver(),
+	}
+
+	return configureTransport(client, func(transport *http.Transport) {
+		transport.DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+			return dialer.DialContext(ctx, c.network(network), address)
+		}
+	})
+}
+
+// configureTransport returns a copy of client whose transport is a copy of the original one modified by configure.
+// Only *http.Transport can be configured, so other transports are rejected
+func configureTransport(client *http.Client, configure func(transport *http.Transport)) (*http.Client, error) {
+	base := client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	transport, ok := base.(*http.Transport)
+	if !ok {
+		return nil, fmt.Errorf("configuring the transport requires an *http.Transport but got %T", base)
+	}
+
+	configured := transport.Clone()
+	configure(configured)
+
+	wrapped := *client
+	wrapped.Transport = configured
+	return &wrapped, nil
+}
@@ -0,0 +1,228 @@
+package chipmusic
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWithDNSServers(t *testing.T) {
+	tests := map[string]struct {
+		servers  []string
+		expected []string
+		isErr    bool
+	}{
+		"default port": {
+			servers:  []string{"1.1.1.1", "2606:4700:4700::1111"},
+			expected: []string{"1.1.1.1:53", "[2606:4700:4700::1111]:53"},
+		},
+		"explicit port": {
+			servers:  []string{"127.0.0.1:5353", "[::1]:5353"},
+			expected: []string{"127.0.0.1:5353", "[::1]:5353"},
+		},
+		"empty": {
+			isErr: true,
+		},
+		"hostname": {
+			servers: []string{"dns.google"},
+			isErr:   true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(tt *testing.T) {
+			client, err := NewClient(WithDNSServers(test.servers...))
+			if test.isErr {
+				assert.Error(tt, err)
+				return
+			}
+
+			require.NoError(tt, err)
+			assert.Equal(tt, test.expected, client.dnsServers)
+		})
+	}
+}
+
+func TestWithIPVersion(t *testing.T) {
+	for _, version := range []IPVersion{IPAny, IPv4, IPv6} {
+		_, err := NewClient(WithIPVersion(version))
+		assert.NoError(t, err)
+	}
+
+	_, err := NewClient(WithIPVersion("ipv5"))
+	assert.Error(t, err)
+}
+
+func TestNewClient_DialerRequiresHTTPTransport(t *testing.T) {
+	client := &http.Client{Transport: roundTripperFunc(func(request *http.Request) (*http.Response, error) {
+		return nil, nil
+	})}
+
+	_, err := NewClient(WithHTTPClient(client), WithIPVersion(IPv4))
+	assert.Error(t, err)
+}
+
+func TestClient_LookupHost(t *testing.T) {
+	server := newDNSServer(t, map[string][]net.IP{
+		"audio.test.": {net.ParseIP("127.0.0.1"), net.ParseIP("::1")},
+	})
+
+	tests := map[string]struct {
+		version  IPVersion
+		expected []string
+	}{
+		"any":  {version: IPAny, expected: []string{"127.0.0.1", "::1"}},
+		"ipv4": {version: IPv4, expected: []string{"127.0.0.1"}},
+		"ipv6": {version: IPv6, expected: []string{"::1"}},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(tt *testing.T) {
+			client, err := NewClient(WithDNSServers(server), WithIPVersion(test.version))
+			require.NoError(tt, err)
+
+			addresses, err := client.LookupHost(context.Background(), "audio.test.")
+			require.NoError(tt, err)
+			assert.ElementsMatch(tt, test.expected, addresses)
+		})
+	}
+}
+
+func TestClient_LookupHost_NoAddresses(t *testing.T) {
+	server := newDNSServer(t, map[string][]net.IP{
+		"audio.test.": {net.ParseIP("127.0.0.1")},
+	})
+
+	client, err := NewClient(WithDNSServers(server), WithIPVersion(IPv6))
+	require.NoError(t, err)
+
+	_, err = client.LookupHost(context.Background(), "audio.test.")
+	assert.Error(t, err)
+}
+
+func TestClient_DialsWithDNSServers(t *testing.T) {
+	audio := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		_, _ = writer.Write([]byte("audio"))
+	}))
+	defer audio.Close()
+
+	_, port, err := net.SplitHostPort(strings.TrimPrefix(audio.URL, "http://"))
+	require.NoError(t, err)
+
+	server := newDNSServer(t, map[string][]net.IP{
+		"audio.test.": {net.ParseIP("127.0.0.1")},
+	})
+
+	client, err := NewClient(WithDNSServers(server), WithIPVersion(IPv4))
+	require.NoError(t, err)
+
+	response, err := client.client.Get("http://audio.test.:" + port)
+	require.NoError(t, err)
+	defer response.Body.Close()
+
+	body, err := ioutil.ReadAll(response.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "audio", string(body))
+}
+
+func TestClient_DialsWithIPVersion(t *testing.T) {
+	audio := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {}))
+	defer audio.Close()
+
+	client, err := NewClient(WithIPVersion(IPv6))
+	require.NoError(t, err)
+
+	// The test server only listens on IPv4, so forcing IPv6 can't reach it
+	_, err = client.client.Get(audio.URL)
+	assert.Error(t, err)
+
+	client, err = NewClient(WithIPVersion(IPv4))
+	require.NoError(t, err)
+
+	response, err := client.client.Get(audio.URL)
+	require.NoError(t, err)
+	assert.NoError(t, response.Body.Close())
+}
+
+// newDNSServer starts a DNS server over UDP which answers A and AAAA questions with records and returns its address
+func newDNSServer(t *testing.T, records map[string][]net.IP) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	go func() {
+		buffer := make([]byte, 512)
+		for {
+			n, address, err := conn.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+
+			if answer, err := answerDNS(buffer[:n], records); err == nil {
+				_, _ = conn.WriteTo(answer, address)
+			}
+		}
+	}()
+
+	return conn.LocalAddr().String()
+}
+
+func answerDNS(query []byte, records map[string][]net.IP) ([]byte, error) {
+	var parser dnsmessage.Parser
+	header, err := parser.Start(query)
+	if err != nil {
+		return nil, err
+	}
+
+	question, err := parser.Question()
+	if err != nil {
+		return nil, err
+	}
+
+	builder := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: header.ID, Response: true, Authoritative: true})
+	if err := builder.StartQuestions(); err != nil {
+		return nil, err
+	}
+
+	if err := builder.Question(question); err != nil {
+		return nil, err
+	}
+
+	if err := builder.StartAnswers(); err != nil {
+		return nil, err
+	}
+
+	resource := dnsmessage.ResourceHeader{Name: question.Name, Class: dnsmessage.ClassINET, TTL: 60}
+	for _, ip := range records[question.Name.String()] {
+		switch {
+		case question.Type == dnsmessage.TypeA && ip.To4() != nil:
+			a := dnsmessage.AResource{}
+			copy(a.A[:], ip.To4())
+			err = builder.AResource(resource, a)
+		case question.Type == dnsmessage.TypeAAAA && ip.To4() == nil:
+			aaaa := dnsmessage.AAAAResource{}
+			copy(aaaa.AAAA[:], ip.To16())
+			err = builder.AAAAResource(resource, aaaa)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return builder.Finish()
+}
+
+// roundTripperFunc is an http.RoundTripper which isn't an *http.Transport
+type roundTripperFunc func(request *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(request *http.Request) (*http.Response, error) {
+	return f(request)
+}
@@ -0,0 +1,249 @@
+package chipmusic
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"time"
+)
+
+const (
+	// mp3SearchLength is how far past any ID3v2 tag the first MP3 frame is searched for
+	mp3SearchLength = 4096
+
+	// oggTailLength is how much of the end of an Ogg file is searched for the last page, which is at most 64KiB
+	oggTailLength = 65307
+)
+
+var (
+	// mp3Bitrates are the bitrates in kbit/s of layer III frames by bitrate index for MPEG-1 and for MPEG-2 and 2.5
+	mp3Bitrates = [2][16]int{
+		{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 0},
+		{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0},
+	}
+
+	// mp3SampleRates are the sample rates of MPEG-1 frames by sample rate index. MPEG-2 halves them and MPEG-2.5
+	// quarters them
+	mp3SampleRates = [3]int{44100, 48000, 32000}
+)
+
+// estimateDuration estimates how long an audio file of size bytes plays for by reading its headers from r. It returns
+// 0 if the duration can't be estimated, e.g. because the file type has no fixed length or the headers are missing
+func estimateDuration(fileType AudioFileType, r io.ReaderAt, size int64) time.Duration {
+	switch fileType {
+	case AudioFileTypeMP3:
+		return mp3Duration(r, size)
+	case AudioFileTypeWAV:
+		return wavDuration(r, size)
+	case AudioFileTypeFLAC:
+		return flacDuration(r)
+	case AudioFileTypeOGG:
+		return oggDuration(r, size)
+	default:
+		return 0
+	}
+}
+
+// readAt reads up to n bytes at off from r. Fewer bytes are returned if r ends or fails before then
+func readAt(r io.ReaderAt, off int64, n int) []byte {
+	if off < 0 || n <= 0 {
+		return nil
+	}
+
+	buf := make([]byte, n)
+	read, _ := r.ReadAt(buf, off)
+	return buf[:read]
+}
+
+// samplesDuration returns how long samples play for at sampleRate
+func samplesDuration(samples int64, sampleRate int) time.Duration {
+	if samples <= 0 || sampleRate <= 0 {
+		return 0
+	}
+
+	return time.Duration(samples) * time.Second / time.Duration(sampleRate)
+}
+
+// mp3Duration reads the number of frames from the Xing or VBRI header of the first frame. Files without one are
+// assumed to have a constant bitrate, so the duration follows from the size and the bitrate of the first frame
+func mp3Duration(r io.ReaderAt, size int64) time.Duration {
+	var offset int64
+	if tag := readAt(r, 0, 10); len(tag) == 10 && string(tag[:3]) == "ID3" {
+		offset = 10 + (int64(tag[6]&0x7F)<<21 | int64(tag[7]&0x7F)<<14 | int64(tag[8]&0x7F)<<7 | int64(tag[9]&0x7F))
+		if tag[5]&0x10 != 0 {
+			offset += 10
+		}
+	}
+
+	head := readAt(r, offset, mp3SearchLength)
+	for i := 0; i+4 <= len(head); i++ {
+		frame, ok := parseMP3Frame(head[i:])
+		if !ok {
+			continue
+		}
+
+		if frames := frame.vbrFrames(head[i:]); frames > 0 {
+			return samplesDuration(frames*int64(frame.samples), frame.sampleRate)
+		}
+
+		audio := size - offset - int64(i)
+		if audio <= 0 {
+			return 0
+		}
+
+		return time.Duration(audio*8) * time.Second / time.Duration(frame.bitrate*1000)
+	}
+
+	return 0
+}
+
+// mp3Frame is the header of an MPEG audio layer III frame
+type mp3Frame struct {
+	mpeg1      bool
+	mono       bool
+	bitrate    int
+	sampleRate int
+	samples    int
+}
+
+// parseMP3Frame parses the frame header at the start of b. If b doesn't start with a valid layer III header, ok is
+// false
+func parseMP3Frame(b []byte) (frame mp3Frame, ok bool) {
+	if len(b) < 4 || b[0] != 0xFF || b[1]&0xE0 != 0xE0 {
+		return mp3Frame{}, false
+	}
+
+	version := b[1] >> 3 & 0x03
+	layer := b[1] >> 1 & 0x03
+	bitrateIndex := b[2] >> 4
+	rateIndex := b[2] >> 2 & 0x03
+	if version == 1 || layer != 1 || bitrateIndex == 0 || bitrateIndex == 15 || rateIndex == 3 {
+		return mp3Frame{}, false
+	}
+
+	frame = mp3Frame{mpeg1: version == 3, mono: b[3]>>6 == 3, sampleRate: mp3SampleRates[rateIndex]}
+	if frame.mpeg1 {
+		frame.bitrate = mp3Bitrates[0][bitrateIndex]
+		frame.samples = 1152
+	} else {
+		frame.bitrate = mp3Bitrates[1][bitrateIndex]
+		frame.samples = 576
+		frame.sampleRate /= 2
+		if version == 0 {
+			frame.sampleRate /= 2
+		}
+	}
+
+	return frame, true
+}
+
+// vbrFrames returns the number of frames in the file from the Xing or VBRI header in the frame at the start of b, or 0
+// if it has neither. Encoders write them into the first frame in place of audio
+func (f mp3Frame) vbrFrames(b []byte) int64 {
+	sideInfo := 17
+	switch {
+	case f.mpeg1 && !f.mono:
+		sideInfo = 32
+	case !f.mpeg1 && f.mono:
+		sideInfo = 9
+	}
+
+	if xing := 4 + sideInfo; len(b) >= xing+12 {
+		tag := string(b[xing : xing+4])
+		flags := binary.BigEndian.Uint32(b[xing+4:])
+		if (tag == "Xing" || tag == "Info") && flags&0x01 != 0 {
+			return int64(binary.BigEndian.Uint32(b[xing+8:]))
+		}
+	}
+
+	if vbri := 4 + 32; len(b) >= vbri+18 && string(b[vbri:vbri+4]) == "VBRI" {
+		return int64(binary.BigEndian.Uint32(b[vbri+14:]))
+	}
+
+	return 0
+}
+
+// wavDuration divides the size of the data chunk by the byte rate from the fmt chunk. Streamed files may not know the
+// size of their data chunk, in which case the rest of the file is assumed to be data
+func wavDuration(r io.ReaderAt, size int64) time.Duration {
+	if header := readAt(r, 0, 12); len(header) < 12 || string(header[:4]) != "RIFF" || string(header[8:]) != "WAVE" {
+		return 0
+	}
+
+	var byteRate int64
+	offset := int64(12)
+	for {
+		chunk := readAt(r, offset, 20)
+		if len(chunk) < 8 {
+			return 0
+		}
+
+		length := int64(binary.LittleEndian.Uint32(chunk[4:]))
+		switch string(chunk[:4]) {
+		case "fmt ":
+			if len(chunk) < 20 {
+				return 0
+			}
+
+			byteRate = int64(binary.LittleEndian.Uint32(chunk[16:]))
+		case "data":
+			if byteRate == 0 {
+				return 0
+			}
+
+			if remaining := size - offset - 8; length == 0 || length == 0xFFFFFFFF || (size > 0 && length > remaining) {
+				length = remaining
+			}
+
+			if length <= 0 {
+				return 0
+			}
+
+			return time.Duration(length) * time.Second / time.Duration(byteRate)
+		}
+
+		// Chunks are padded to an even length
+		offset += 8 + length + length&1
+	}
+}
+
+// flacDuration divides the total samples by the sample rate, both from the STREAMINFO block which always comes first
+func flacDuration(r io.ReaderAt) time.Duration {
+	b := readAt(r, 0, 26)
+	if len(b) < 26 || string(b[:4]) != "fLaC" || b[4]&0x7F != 0 {
+		return 0
+	}
+
+	sampleRate := int(b[18])<<12 | int(b[19])<<4 | int(b[20])>>4
+	samples := int64(b[21]&0x0F)<<32 | int64(binary.BigEndian.Uint32(b[22:]))
+	return samplesDuration(samples, sampleRate)
+}
+
+// oggDuration divides the granule position of the last page, which is the number of samples in the stream, by the
+// sample rate from the Vorbis identification header on the first page
+func oggDuration(r io.ReaderAt, size int64) time.Duration {
+	first := readAt(r, 0, 27+255+16)
+	if len(first) < 27 || string(first[:4]) != "OggS" {
+		return 0
+	}
+
+	identification := 27 + int(first[26])
+	if len(first) < identification+16 || string(first[identification:identification+7]) != "\x01vorbis" {
+		return 0
+	}
+
+	sampleRate := int(binary.LittleEndian.Uint32(first[identification+12:]))
+
+	start := size - oggTailLength
+	if start < 0 {
+		start = 0
+	}
+
+	tail := readAt(r, start, int(size-start))
+	last := bytes.LastIndex(tail, []byte("OggS"))
+	if last < 0 || len(tail) < last+14 {
+		return 0
+	}
+
+	return samplesDuration(int64(binary.LittleEndian.Uint64(tail[last+6:])), sampleRate)
+}
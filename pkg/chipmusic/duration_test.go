@@ -0,0 +1,144 @@
+package chipmusic
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// testMP3FrameHeader is the header of an MPEG-1 layer III frame at 128kbit/s, 44.1kHz, and joint stereo
+var testMP3FrameHeader = []byte{0xFF, 0xFB, 0x90, 0x64}
+
+// newTestMP3 returns a constant bitrate MP3 which plays for 128kbit/s * length, preceded by an ID3v2 tag of tagLength
+// bytes if tagLength is greater than 0
+func newTestMP3(tagLength int, length time.Duration) []byte {
+	var mp3 []byte
+	if tagLength > 0 {
+		tag := make([]byte, 10+tagLength)
+		copy(tag, "ID3\x04\x00\x00")
+		for i := 0; i < 4; i++ {
+			tag[9-i] = byte(tagLength >> (7 * i) & 0x7F)
+		}
+
+		mp3 = append(mp3, tag...)
+	}
+
+	audio := make([]byte, int(length.Seconds()*128000/8))
+	copy(audio, testMP3FrameHeader)
+	return append(mp3, audio...)
+}
+
+// newTestVBRMP3 returns an MP3 whose first frame has a Xing header counting frames
+func newTestVBRMP3(frames uint32) []byte {
+	mp3 := make([]byte, 2048)
+	copy(mp3, testMP3FrameHeader)
+	copy(mp3[4+32:], "Xing")
+	binary.BigEndian.PutUint32(mp3[4+32+4:], 0x01)
+	binary.BigEndian.PutUint32(mp3[4+32+8:], frames)
+	return mp3
+}
+
+// newTestWAV returns a 16 bit stereo WAV at 44.1kHz with an extra chunk before the audio
+func newTestWAV(length time.Duration) []byte {
+	var wav bytes.Buffer
+	data := int(length.Seconds() * 44100 * 4)
+	wav.WriteString("RIFF")
+	binary.Write(&wav, binary.LittleEndian, uint32(4+24+8+3+1+8+data))
+	wav.WriteString("WAVEfmt ")
+	binary.Write(&wav, binary.LittleEndian, []uint32{16, 0x00020001, 44100, 44100 * 4, 0x00100004})
+	wav.WriteString("LIST")
+	binary.Write(&wav, binary.LittleEndian, uint32(3))
+	wav.Write([]byte{1, 2, 3, 0})
+	wav.WriteString("data")
+	binary.Write(&wav, binary.LittleEndian, uint32(data))
+	wav.Write(make([]byte, data))
+	return wav.Bytes()
+}
+
+// newTestFLAC returns the start of a FLAC file with a STREAMINFO block
+func newTestFLAC(samples int64, sampleRate int) []byte {
+	flac := make([]byte, 8+34)
+	copy(flac, "fLaC")
+	flac[7] = 34
+	flac[18] = byte(sampleRate >> 12)
+	flac[19] = byte(sampleRate >> 4)
+	flac[20] = byte(sampleRate<<4) | 0x02
+	flac[21] = 0xF0 | byte(samples>>32&0x0F)
+	binary.BigEndian.PutUint32(flac[22:], uint32(samples))
+	return flac
+}
+
+// newTestOGG returns an Ogg Vorbis file with an identification header on the first page and samples as the granule
+// position of the last page
+func newTestOGG(samples int64, sampleRate int) []byte {
+	page := func(granule int64, packet []byte) []byte {
+		header := make([]byte, 27)
+		copy(header, "OggS")
+		binary.LittleEndian.PutUint64(header[6:], uint64(granule))
+		header[26] = 1
+		return append(append(header, byte(len(packet))), packet...)
+	}
+
+	identification := make([]byte, 30)
+	copy(identification, "\x01vorbis")
+	identification[11] = 2
+	binary.LittleEndian.PutUint32(identification[12:], uint32(sampleRate))
+
+	ogg := page(0, identification)
+	ogg = append(ogg, make([]byte, 1000)...)
+	return append(ogg, page(samples, make([]byte, 100))...)
+}
+
+func TestEstimateDuration(t *testing.T) {
+	testCases := []struct {
+		name     string
+		fileType AudioFileType
+		audio    []byte
+		expected time.Duration
+	}{
+		{"MP3", AudioFileTypeMP3, newTestMP3(0, 3*time.Second), 3 * time.Second},
+		{"MP3WithTag", AudioFileTypeMP3, newTestMP3(5000, 2*time.Second), 2 * time.Second},
+		{"MP3WithXing", AudioFileTypeMP3, newTestVBRMP3(1000), 1000 * 1152 * time.Second / 44100},
+		{"MP3WithoutFrames", AudioFileTypeMP3, make([]byte, 1000), 0},
+		{"WAV", AudioFileTypeWAV, newTestWAV(1500 * time.Millisecond), 1500 * time.Millisecond},
+		{"WAVTruncated", AudioFileTypeWAV, newTestWAV(time.Second)[:20], 0},
+		{"FLAC", AudioFileTypeFLAC, newTestFLAC(96000*90, 96000), 90 * time.Second},
+		{"OGG", AudioFileTypeOGG, newTestOGG(44100*42, 44100), 42 * time.Second},
+		{"NotOGG", AudioFileTypeOGG, newTestWAV(time.Second), 0},
+		{"Module", AudioFileTypeXM, make([]byte, 1000), 0},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			duration := estimateDuration(testCase.fileType, bytes.NewReader(testCase.audio), int64(len(testCase.audio)))
+			assert.Equal(tt, testCase.expected, duration)
+		})
+	}
+}
+
+func TestParseMP3Frame(t *testing.T) {
+	testCases := []struct {
+		name     string
+		header   []byte
+		expected mp3Frame
+		ok       bool
+	}{
+		{"MPEG1", testMP3FrameHeader, mp3Frame{mpeg1: true, bitrate: 128, sampleRate: 44100, samples: 1152}, true},
+		{"MPEG2Mono", []byte{0xFF, 0xF3, 0x84, 0xC0}, mp3Frame{mono: true, bitrate: 64, sampleRate: 24000, samples: 576}, true},
+		{"MPEG25", []byte{0xFF, 0xE3, 0x18, 0x00}, mp3Frame{bitrate: 8, sampleRate: 8000, samples: 576}, true},
+		{"Layer2", []byte{0xFF, 0xFD, 0x90, 0x64}, mp3Frame{}, false},
+		{"FreeBitrate", []byte{0xFF, 0xFB, 0x00, 0x64}, mp3Frame{}, false},
+		{"NoSync", []byte{0xFF, 0x1B, 0x90, 0x64}, mp3Frame{}, false},
+		{"Short", []byte{0xFF, 0xFB}, mp3Frame{}, false},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			frame, ok := parseMP3Frame(testCase.header)
+			assert.Equal(tt, testCase.ok, ok)
+			assert.Equal(tt, testCase.expected, frame)
+		})
+	}
+}
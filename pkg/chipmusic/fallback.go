@@ -0,0 +1,40 @@
+package chipmusic
+
+import (
+	"net/url"
+	"sync"
+)
+
+// hostSet is a set of hosts which is safe for concurrent use
+type hostSet struct {
+	mux   sync.Mutex
+	hosts map[string]bool
+}
+
+func newHostSet() *hostSet {
+	return &hostSet{hosts: map[string]bool{}}
+}
+
+// add adds the host of u to the set
+func (s *hostSet) add(u string) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.hosts[urlHost(u)] = true
+}
+
+// has returns true if the host of u is in the set
+func (s *hostSet) has(u string) bool {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.hosts[urlHost(u)]
+}
+
+// urlHost returns the host of u including the port, or u itself if it can't be parsed
+func urlHost(u string) string {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return u
+	}
+
+	return parsed.Host
+}
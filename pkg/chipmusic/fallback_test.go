@@ -0,0 +1,74 @@
+package chipmusic
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDownloadTrack_SingleStreamFallback(t *testing.T) {
+	audio := randomAudio(t, 1000)
+	for _, spooled := range []bool{false, true} {
+		t.Run(fmt.Sprintf("spooled=%t", spooled), func(tt *testing.T) {
+			// The server advertises Range requests but fails every one of them
+			var ranged, single int32
+			server := newCorruptTrackServer(tt, func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodGet && r.Header.Get("Range") != "" {
+					atomic.AddInt32(&ranged, 1)
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+
+				if r.Method == http.MethodGet {
+					atomic.AddInt32(&single, 1)
+				}
+
+				http.ServeContent(w, r, "some.track.mp3", time.Time{}, bytes.NewReader(audio))
+			})
+
+			defer server.Close()
+
+			options := []Option{WithBaseURL(server.URL), WithHTTPClient(server.Client()), WithWorkers(2)}
+			if spooled {
+				options = append(options, WithSpoolDir(os.TempDir()))
+			}
+
+			client, err := NewClient(options...)
+			require.NoError(tt, err, "failed to create client")
+
+			for i := 0; i < 2; i++ {
+				track, err := client.GetTrack(context.Background(), fmt.Sprintf("%s/some.artist/music/some.music", server.URL))
+				require.NoError(tt, err)
+
+				content, err := ioutil.ReadAll(track.Reader)
+				require.NoError(tt, err)
+				assert.Equal(tt, audio, content)
+				track.Close()
+			}
+
+			// Range requests are only tried for the first track, after which the host is remembered
+			assert.True(tt, atomic.LoadInt32(&ranged) >= DefaultChunkRetries+1)
+			assert.True(tt, atomic.LoadInt32(&ranged) <= 2*(DefaultChunkRetries+1))
+			assert.Equal(tt, int32(2), atomic.LoadInt32(&single), "each track should be downloaded with one request for the whole file")
+			assert.True(tt, client.singleStreamHosts.has(server.URL+testAudioPath))
+		})
+	}
+}
+
+func TestHostSet(t *testing.T) {
+	hosts := newHostSet()
+	assert.False(t, hosts.has("https://cdn.example.com/a.mp3"))
+
+	hosts.add("https://cdn.example.com/a.mp3")
+	assert.True(t, hosts.has("https://cdn.example.com/b.mp3"))
+	assert.False(t, hosts.has("https://cdn.example.com:8443/b.mp3"))
+	assert.False(t, hosts.has("https://example.com/a.mp3"))
+}
@@ -0,0 +1,76 @@
+package chipmusic
+
+import (
+	"mime"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	// fileTypeAliases maps other extensions used for audio files to the file type they contain
+	fileTypeAliases = map[string]AudioFileType{
+		"oga":  AudioFileTypeOGG,
+		"wave": AudioFileTypeWAV,
+	}
+
+	// contentTypes maps the media types audio files are served with to their file type
+	contentTypes = map[string]AudioFileType{
+		"audio/mpeg":      AudioFileTypeMP3,
+		"audio/mp3":       AudioFileTypeMP3,
+		"audio/wav":       AudioFileTypeWAV,
+		"audio/wave":      AudioFileTypeWAV,
+		"audio/x-wav":     AudioFileTypeWAV,
+		"audio/vnd.wave":  AudioFileTypeWAV,
+		"audio/ogg":       AudioFileTypeOGG,
+		"audio/vorbis":    AudioFileTypeOGG,
+		"application/ogg": AudioFileTypeOGG,
+		"audio/flac":      AudioFileTypeFLAC,
+		"audio/x-flac":    AudioFileTypeFLAC,
+		"audio/mod":       AudioFileTypeMOD,
+		"audio/x-mod":     AudioFileTypeMOD,
+		"audio/s3m":       AudioFileTypeS3M,
+		"audio/x-s3m":     AudioFileTypeS3M,
+		"audio/xm":        AudioFileTypeXM,
+		"audio/x-xm":      AudioFileTypeXM,
+		"audio/it":        AudioFileTypeIT,
+		"audio/x-it":      AudioFileTypeIT,
+		"audio/x-nsf":     AudioFileTypeNSF,
+		"audio/x-spc":     AudioFileTypeSPC,
+		"audio/prs.sid":   AudioFileTypeSID,
+		"audio/x-sid":     AudioFileTypeSID,
+	}
+)
+
+// fileTypeFromURL returns the file type of an audio file from the extension of its URL. Unknown extensions are
+// returned as is so they can be reported
+func fileTypeFromURL(u string) AudioFileType {
+	if parsed, err := url.Parse(u); err == nil {
+		u = parsed.Path
+	}
+
+	return FileTypeFromPath(u)
+}
+
+// FileTypeFromPath returns the file type of an audio file from the extension of its path, e.g. a local file. Unknown
+// extensions are returned as is so they can be reported
+func FileTypeFromPath(p string) AudioFileType {
+	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(p), "."))
+	if fileType, ok := fileTypeAliases[ext]; ok {
+		return fileType
+	}
+
+	return AudioFileType(ext)
+}
+
+// fileTypeFromContentType returns the file type of an audio file from the Content-Type it is served with. If the
+// Content-Type is missing or generic, e.g. application/octet-stream, ok is false
+func fileTypeFromContentType(contentType string) (fileType AudioFileType, ok bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", false
+	}
+
+	fileType, ok = contentTypes[mediaType]
+	return fileType, ok
+}
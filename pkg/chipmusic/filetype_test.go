@@ -0,0 +1,120 @@
+package chipmusic
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFileTypeFromURL(t *testing.T) {
+	testCases := []struct {
+		name     string
+		url      string
+		expected AudioFileType
+	}{
+		{"MP3", "https://chipmusic.s3.amazonaws.com/music/2020/01/track.mp3", AudioFileTypeMP3},
+		{"UpperCase", "https://chipmusic.s3.amazonaws.com/music/2020/01/TRACK.FLAC", AudioFileTypeFLAC},
+		{"Alias", "https://chipmusic.s3.amazonaws.com/music/2020/01/track.oga", AudioFileTypeOGG},
+		{"Query", "https://chipmusic.s3.amazonaws.com/music/2020/01/track.wav?version=2", AudioFileTypeWAV},
+		{"Module", "https://chipmusic.s3.amazonaws.com/music/2020/01/track.XM", AudioFileTypeXM},
+		{"Chiptune", "https://chipmusic.s3.amazonaws.com/music/2020/01/track.nsf", AudioFileTypeNSF},
+		{"Unknown", "https://chipmusic.s3.amazonaws.com/music/2020/01/track.vgm", "vgm"},
+		{"NoExtension", "https://chipmusic.s3.amazonaws.com/music/2020/01/track", ""},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			assert.Equal(tt, testCase.expected, fileTypeFromURL(testCase.url))
+		})
+	}
+}
+
+func TestFileTypeFromPath(t *testing.T) {
+	testCases := []struct {
+		name     string
+		path     string
+		expected AudioFileType
+	}{
+		{"WAV", "/home/user/idents/station.wav", AudioFileTypeWAV},
+		{"Alias", "idents/station.WAVE", AudioFileTypeWAV},
+		{"Dots", "idents/station.id.ogg", AudioFileTypeOGG},
+		{"NoExtension", "idents/station", ""},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			assert.Equal(tt, testCase.expected, FileTypeFromPath(testCase.path))
+		})
+	}
+}
+
+func TestFileTypeFromContentType(t *testing.T) {
+	testCases := []struct {
+		name        string
+		contentType string
+		expected    AudioFileType
+		ok          bool
+	}{
+		{"MP3", "audio/mpeg", AudioFileTypeMP3, true},
+		{"Parameters", "audio/ogg; codecs=vorbis", AudioFileTypeOGG, true},
+		{"FLAC", "audio/x-flac", AudioFileTypeFLAC, true},
+		{"Module", "audio/x-mod", AudioFileTypeMOD, true},
+		{"Chiptune", "audio/prs.sid", AudioFileTypeSID, true},
+		{"Generic", "application/octet-stream", "", false},
+		{"Missing", "", "", false},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			fileType, ok := fileTypeFromContentType(testCase.contentType)
+			assert.Equal(tt, testCase.ok, ok)
+			assert.Equal(tt, testCase.expected, fileType)
+		})
+	}
+}
+
+func TestDownloadTrack_ContentType(t *testing.T) {
+	testCases := []struct {
+		name        string
+		contentType string
+		expected    AudioFileType
+	}{
+		{"Specific", "audio/flac", AudioFileTypeFLAC},
+		{"Generic", "application/octet-stream", AudioFileTypeMP3},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			audio := randomAudio(tt, 1024)
+			tracks := newTrackServer(tt, audio, false)
+			defer tracks.Close()
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == testAudioPath {
+					w.Header().Set("Content-Type", testCase.contentType)
+				}
+
+				tracks.Config.Handler.ServeHTTP(w, r)
+			}))
+			defer server.Close()
+
+			client, err := NewClient(WithBaseURL(server.URL), WithHTTPClient(server.Client()))
+			require.NoError(tt, err, "failed to create client")
+
+			track, err := client.GetTrackInfo(context.Background(), server.URL+"/some.artist/music/some.music")
+			require.NoError(tt, err, "failed to get track info")
+
+			// The download link of the track page points at the server serving the page
+			track.DownloadURL = server.URL + testAudioPath
+			assert.Equal(tt, AudioFileTypeMP3, track.FileType)
+
+			require.NoError(tt, client.DownloadTrack(context.Background(), track), "failed to download track")
+			defer track.Close()
+
+			assert.Equal(tt, testCase.expected, track.FileType)
+		})
+	}
+}
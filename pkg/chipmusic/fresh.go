@@ -0,0 +1,75 @@
+package chipmusic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+const (
+	// DefaultMaxFreshPages is the maximum number of search pages walked by SearchFresh when looking for the newest
+	// track seen by a previous search
+	DefaultMaxFreshPages = 5
+)
+
+// SeenStore is an interface for persisting the newest track seen for a search. It allows SearchFresh to remember
+// where the previous run stopped
+type SeenStore interface {
+
+	// LastSeen returns the URL of the newest track seen for key. If key has never been seen, an empty string is returned
+	LastSeen(key string) (string, error)
+
+	// SetLastSeen records url as the newest track seen for key
+	SetLastSeen(key, url string) error
+}
+
+// SearchFresh performs a search against chipmusic.org for the latest tracks, returning only tracks which have been
+// posted since the previous call with the same search text. Results are returned newest first. The first call for a
+// particular search has nothing to compare against, so it returns the first page of results. At most
+// DefaultMaxFreshPages pages are walked when looking for the newest track seen by the previous call
+func (c *Client) SearchFresh(ctx context.Context, seen SeenStore, search string) ([]SearchResult, error) {
+	if seen == nil {
+		return nil, errors.New("seen store cannot be nil")
+	}
+
+	key := freshSearchKey(search)
+	last, err := seen.LastSeen(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last seen track for %q: %w", search, err)
+	}
+
+	fresh := make([]SearchResult, 0)
+	for page := 1; page <= DefaultMaxFreshPages; page++ {
+		results, err := c.Search(ctx, SearchOptions{Query: search, Filter: TrackFilterLatest, Page: page})
+		if err != nil {
+			return nil, fmt.Errorf("failed to search for fresh tracks: %w", err)
+		}
+
+		found := false
+		for _, result := range results {
+			if result.URL == last {
+				found = true
+				break
+			}
+
+			fresh = append(fresh, result)
+		}
+
+		// There is no previous run to compare against so the first page is considered fresh
+		if found || last == "" || len(results) == 0 {
+			break
+		}
+	}
+
+	if len(fresh) > 0 {
+		if err := seen.SetLastSeen(key, fresh[0].URL); err != nil {
+			return nil, fmt.Errorf("failed to set last seen track for %q: %w", search, err)
+		}
+	}
+
+	return fresh, nil
+}
+
+func freshSearchKey(search string) string {
+	return fmt.Sprintf("%s:%s", TrackFilterLatest, search)
+}
@@ -0,0 +1,136 @@
+package chipmusic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type MockSeenStore struct {
+	seen map[string]string
+	err  error
+}
+
+func NewMockSeenStore() *MockSeenStore {
+	return &MockSeenStore{seen: map[string]string{}}
+}
+
+func (m *MockSeenStore) LastSeen(key string) (string, error) {
+	return m.seen[key], m.err
+}
+
+func (m *MockSeenStore) SetLastSeen(key, url string) error {
+	m.seen[key] = url
+	return m.err
+}
+
+// newSearchServer returns a server which responds to searches with pages of tracks. Each page is a list of track URLs
+func newSearchServer(t *testing.T, pages [][]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, err := strconv.Atoi(r.URL.Query().Get("p"))
+		require.NoError(t, err, "failed to parse page from search request")
+
+		var tracks []string
+		if page <= len(pages) {
+			tracks = pages[page-1]
+		}
+
+		_, err = w.Write([]byte(renderSearchPage(tracks)))
+		require.NoError(t, err, "failed to write server response")
+	}))
+}
+
+func renderSearchPage(tracks []string) string {
+	builder := strings.Builder{}
+	builder.WriteString(`<html><body><div id="music_list">`)
+	for _, track := range tracks {
+		builder.WriteString(fmt.Sprintf(`<div class="main-item"><div class="item-subject"><h3 class="hn"><a href="%s">track</a></h3></div></div>`, track))
+	}
+
+	builder.WriteString(`</div></body></html>`)
+	return builder.String()
+}
+
+func TestSearchFresh(t *testing.T) {
+	testCases := []struct {
+		name     string
+		last     string
+		pages    [][]string
+		expected []string
+	}{
+		{"FirstRunReturnsFirstPage", "", [][]string{{"a", "b"}, {"c", "d"}}, []string{"a", "b"}},
+		{"NothingNew", "a", [][]string{{"a", "b"}, {"c", "d"}}, []string{}},
+		{"NewOnFirstPage", "b", [][]string{{"a", "b"}, {"c", "d"}}, []string{"a"}},
+		{"NewAcrossPages", "d", [][]string{{"a", "b"}, {"c", "d"}}, []string{"a", "b", "c"}},
+		{"LastSeenIsGone", "z", [][]string{{"a", "b"}, {"c", "d"}}, []string{"a", "b", "c", "d"}},
+		{"NoTracks", "", [][]string{}, []string{}},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			server := newSearchServer(tt, testCase.pages)
+			defer server.Close()
+
+			client, err := NewClient(WithBaseURL(server.URL), WithHTTPClient(server.Client()))
+			require.NoError(tt, err, "failed to create client")
+
+			seen := NewMockSeenStore()
+			seen.seen[freshSearchKey("some.search")] = testCase.last
+
+			tracks, err := client.SearchFresh(context.Background(), seen, "some.search")
+			require.NoError(tt, err)
+			assert.Equal(tt, testCase.expected, SearchResultURLs(tracks))
+
+			if len(testCase.expected) > 0 {
+				assert.Equal(tt, testCase.expected[0], seen.seen[freshSearchKey("some.search")])
+			} else {
+				assert.Equal(tt, testCase.last, seen.seen[freshSearchKey("some.search")])
+			}
+		})
+	}
+}
+
+func TestSearchFresh_SubsequentRun(t *testing.T) {
+	server := newSearchServer(t, [][]string{{"a", "b"}})
+	defer server.Close()
+
+	client, err := NewClient(WithBaseURL(server.URL), WithHTTPClient(server.Client()))
+	require.NoError(t, err, "failed to create client")
+
+	seen := NewMockSeenStore()
+	tracks, err := client.SearchFresh(context.Background(), seen, "some.search")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, SearchResultURLs(tracks))
+
+	tracks, err = client.SearchFresh(context.Background(), seen, "some.search")
+	require.NoError(t, err)
+	assert.Empty(t, tracks)
+}
+
+func TestSearchFresh_NilStore(t *testing.T) {
+	client, err := NewClient()
+	require.NoError(t, err, "failed to create client")
+
+	tracks, err := client.SearchFresh(context.Background(), nil, "some.search")
+	assert.Error(t, err)
+	assert.Nil(t, tracks)
+}
+
+func TestSearchFresh_StoreError(t *testing.T) {
+	client, err := NewClient()
+	require.NoError(t, err, "failed to create client")
+
+	seen := NewMockSeenStore()
+	seen.err = errors.New("an error occurred")
+
+	tracks, err := client.SearchFresh(context.Background(), seen, "some.search")
+	assert.Error(t, err)
+	assert.Nil(t, tracks)
+}
@@ -0,0 +1,56 @@
+package chipmusic
+
+import (
+	"errors"
+)
+
+// MetadataCache stores the metadata parsed from track pages so GetTrackInfo doesn't have to fetch a page again, e.g.
+// when building a big queue of tracks whose audio is already cached. Implementations decide how long metadata stays
+// fresh
+type MetadataCache interface {
+
+	// TrackMetadata returns a Track holding the metadata cached for the track page at trackPageURL. If none is cached or
+	// it is too old to be used, nil is returned
+	TrackMetadata(trackPageURL string) (*Track, error)
+
+	// SetTrackMetadata caches the metadata of track, which was just parsed from its track page
+	SetTrackMetadata(track *Track) error
+}
+
+// WithMetadataCache allows GetTrackInfo to read the metadata of tracks from cache instead of fetching their track pages
+// and to store the metadata of every track page it fetches in it. Errors of the cache are ignored, so a broken cache
+// only means track pages are fetched
+func WithMetadataCache(cache MetadataCache) Option {
+	return func(c *Client) error {
+		if cache == nil {
+			return errors.New("metadata cache cannot be nil")
+		}
+
+		c.metadata = cache
+		return nil
+	}
+}
+
+// cachedTrackInfo returns the track with the metadata cached for the track page at trackPageURL. It returns nil if
+// there is no metadata cache or the metadata isn't cached
+func (c *Client) cachedTrackInfo(trackPageURL string) *Track {
+	if c.metadata == nil {
+		return nil
+	}
+
+	track, err := c.metadata.TrackMetadata(trackPageURL)
+	if err != nil || track == nil || track.DownloadURL == "" {
+		return nil
+	}
+
+	track.PageURL = trackPageURL
+	track.FileType = fileTypeFromURL(track.DownloadURL)
+	return track
+}
+
+// cacheTrackInfo stores the metadata of track in the metadata cache, if there is one
+func (c *Client) cacheTrackInfo(track *Track) {
+	if c.metadata != nil {
+		_ = c.metadata.SetTrackMetadata(track)
+	}
+}
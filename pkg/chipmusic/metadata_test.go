@@ -0,0 +1,82 @@
+package chipmusic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// memoryMetadataCache is a MetadataCache keeping metadata in a map
+type memoryMetadataCache struct {
+	mux    sync.Mutex
+	tracks map[string]Track
+	err    error
+}
+
+func (m *memoryMetadataCache) TrackMetadata(trackPageURL string) (*Track, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	track, ok := m.tracks[trackPageURL]
+	if !ok {
+		return nil, m.err
+	}
+
+	return &track, m.err
+}
+
+func (m *memoryMetadataCache) SetTrackMetadata(track *Track) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	m.tracks[track.PageURL] = *track
+	return m.err
+}
+
+func TestGetTrackInfo_MetadataCache(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		raw, err := ioutil.ReadFile(defaultTrackPageFile)
+		require.NoError(t, err, "failed to read content of %s as server response", defaultTrackPageFile)
+
+		_, err = w.Write(raw)
+		require.NoError(t, err, "failed to write %s as server response", defaultTrackPageFile)
+	}))
+
+	defer server.Close()
+
+	cache := &memoryMetadataCache{tracks: map[string]Track{}}
+	client, err := NewClient(WithBaseURL(server.URL), WithHTTPClient(server.Client()), WithMetadataCache(cache))
+	require.NoError(t, err, "failed to create client")
+
+	trackPageURL := fmt.Sprintf("%s/some.artist/music/some.music", server.URL)
+	fetched, err := client.GetTrackInfo(context.Background(), trackPageURL)
+	require.NoError(t, err)
+	assert.Contains(t, cache.tracks, trackPageURL, "expected the metadata of the fetched page to be cached")
+
+	cached, err := client.GetTrackInfo(context.Background(), trackPageURL)
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests), "expected the track page to be fetched once")
+	assert.Equal(t, fetched, cached)
+
+	// A broken cache falls back to fetching the track page
+	cache.err = errors.New("some.error")
+	cache.tracks = map[string]Track{}
+	_, err = client.GetTrackInfo(context.Background(), trackPageURL)
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+}
+
+func TestWithMetadataCache(t *testing.T) {
+	_, err := NewClient(WithMetadataCache(nil))
+	assert.Error(t, err)
+}
@@ -0,0 +1,27 @@
+package chipmusic
+
+import (
+	"regexp"
+	"strings"
+)
+
+// trackPageURLPattern matches the URL of a track page, e.g. https://chipmusic.org/Hide+Your+Tigers/music/virtues-lsdj
+var trackPageURLPattern = regexp.MustCompile(`https?://(?:www\.)?chipmusic\.org/[^/\s"'<>]+/music/[^/\s"'<>?#]+`)
+
+// FindTrackPageURLs returns the URLs of the track pages on chipmusic.org found in text in the order they appear, e.g.
+// in text copied from a browser or a chat. Each URL is only returned once, and the query and fragment of URLs are left
+// out
+func FindTrackPageURLs(text string) []string {
+	seen := map[string]bool{}
+	var urls []string
+	for _, match := range trackPageURLPattern.FindAllString(text, -1) {
+		// URLs in prose are often followed by punctuation which isn't part of them
+		match = strings.TrimRight(match, ".,;:!)]")
+		if !seen[match] {
+			seen[match] = true
+			urls = append(urls, match)
+		}
+	}
+
+	return urls
+}
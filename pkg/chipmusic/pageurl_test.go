@@ -0,0 +1,32 @@
+package chipmusic
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestFindTrackPageURLs(t *testing.T) {
+	testCases := []struct {
+		name     string
+		text     string
+		expected []string
+	}{
+		{"Empty", "", nil},
+		{"URL", "https://chipmusic.org/Hide+Your+Tigers/music/virtues-lsdj", []string{"https://chipmusic.org/Hide+Your+Tigers/music/virtues-lsdj"}},
+		{"Whitespace", "  https://chipmusic.org/some.artist/music/some.title\n", []string{"https://chipmusic.org/some.artist/music/some.title"}},
+		{"Prose", "listen to this (https://chipmusic.org/some.artist/music/some.title).", []string{"https://chipmusic.org/some.artist/music/some.title"}},
+		{"QueryAndFragment", "https://chipmusic.org/some.artist/music/some.title?page=2#comments", []string{"https://chipmusic.org/some.artist/music/some.title"}},
+		{"Several", "https://chipmusic.org/a/music/first https://www.chipmusic.org/b/music/second https://chipmusic.org/a/music/first", []string{
+			"https://chipmusic.org/a/music/first",
+			"https://www.chipmusic.org/b/music/second",
+		}},
+		{"ArtistPage", "https://chipmusic.org/some.artist", nil},
+		{"OtherSite", "https://example.com/some.artist/music/some.title", nil},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			assert.Equal(tt, testCase.expected, FindTrackPageURLs(testCase.text))
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package chipmusic
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// DefaultPrefetch is the default number of tracks a Prefetcher fetches ahead of the track being used
+const DefaultPrefetch = 1
+
+// PrefetchOptions configure a Prefetcher
+type PrefetchOptions struct {
+
+	// Ahead is how many tracks are fetched ahead of the last track returned by Next. If 0 or less, DefaultPrefetch is
+	// used
+	Ahead int
+
+	// Timeout limits how long getting the info of each track and downloading it may take. If 0, there is no limit
+	Timeout time.Duration
+
+	// ShouldDownload decides whether the audio of a track is downloaded once its info is known, e.g. to skip tracks
+	// which can't be played. If nil, every track is downloaded
+	ShouldDownload func(track *Track) bool
+}
+
+// PrefetchResult is a track fetched by a Prefetcher
+type PrefetchResult struct {
+
+	// URL is the URL of the track page the track was fetched from
+	URL string
+
+	// Track is the track with its audio downloaded. If the info of the track couldn't be found, it is nil. If the
+	// download failed or ShouldDownload returned false, its Reader is nil
+	Track *Track
+
+	// Err is why the track couldn't be fetched, if it couldn't
+	Err error
+}
+
+// Prefetcher gets the info of tracks and downloads them in the background ahead of when they are needed, so the next
+// track is ready as soon as the current one finishes. Tracks are returned by Next in the order of their URLs
+type Prefetcher struct {
+	cancel  context.CancelFunc
+	results chan chan PrefetchResult
+}
+
+// NewPrefetcher starts fetching the tracks at trackURLs with client. Call Close to stop once the tracks are no longer
+// needed
+func NewPrefetcher(client *Client, trackURLs []string, options PrefetchOptions) *Prefetcher {
+	if options.Ahead <= 0 {
+		options.Ahead = DefaultPrefetch
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &Prefetcher{cancel: cancel, results: make(chan chan PrefetchResult, options.Ahead)}
+	go func() {
+		defer close(p.results)
+
+		for _, trackURL := range trackURLs {
+			// The buffer of results limits how many tracks are fetched before Next is called for them
+			result := make(chan PrefetchResult, 1)
+			select {
+			case p.results <- result:
+			case <-ctx.Done():
+				return
+			}
+
+			go func(trackURL string) {
+				result <- prefetch(ctx, client, trackURL, options)
+			}(trackURL)
+		}
+	}()
+
+	return p
+}
+
+// prefetch gets the info of the track at trackURL and downloads it if it should be
+func prefetch(ctx context.Context, client *Client, trackURL string, options PrefetchOptions) PrefetchResult {
+	if options.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, options.Timeout)
+		defer cancel()
+	}
+
+	track, err := client.GetTrackInfo(ctx, trackURL)
+	if err != nil {
+		return PrefetchResult{URL: trackURL, Err: fmt.Errorf("failed to get track info: %w", err)}
+	}
+
+	if options.ShouldDownload != nil && !options.ShouldDownload(track) {
+		return PrefetchResult{URL: trackURL, Track: track}
+	}
+
+	if err := client.DownloadTrack(ctx, track); err != nil {
+		return PrefetchResult{URL: trackURL, Track: track, Err: fmt.Errorf("failed to download track: %w", err)}
+	}
+
+	return PrefetchResult{URL: trackURL, Track: track}
+}
+
+// Next blocks until the next track is fetched and returns it. The caller owns the track and must close it. If every
+// track was returned, ok is false
+func (p *Prefetcher) Next() (result PrefetchResult, ok bool) {
+	next, ok := <-p.results
+	if !ok {
+		return PrefetchResult{}, false
+	}
+
+	return <-next, true
+}
+
+// Close stops fetching tracks and releases those fetched but not returned by Next. Next must not be called afterwards
+func (p *Prefetcher) Close() {
+	p.cancel()
+	for next := range p.results {
+		if result := <-next; result.Track != nil && result.Track.Reader != nil {
+			result.Track.Close()
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package chipmusic
+
+import (
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newCountingTrackServer returns a server like newTrackServer which counts the requests for track pages. Audio is
+// served by a second server which is closed along with the test
+func newCountingTrackServer(t *testing.T, audio []byte, pages *int32) *httptest.Server {
+	tracks := newTrackServer(t, audio, true)
+	t.Cleanup(tracks.Close)
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != testAudioPath {
+			atomic.AddInt32(pages, 1)
+		}
+
+		tracks.Config.Handler.ServeHTTP(w, r)
+	}))
+}
+
+func TestPrefetcher(t *testing.T) {
+	audio := randomAudio(t, 1000)
+	server := newTrackServer(t, audio, true)
+	defer server.Close()
+
+	client, err := NewClient(WithBaseURL(server.URL), WithHTTPClient(server.Client()))
+	require.NoError(t, err, "failed to create client")
+
+	var trackURLs []string
+	for i := 0; i < 4; i++ {
+		trackURLs = append(trackURLs, fmt.Sprintf("%s/some.artist/music/%d", server.URL, i))
+	}
+
+	prefetcher := NewPrefetcher(client, trackURLs, PrefetchOptions{Ahead: 2, Timeout: time.Minute})
+	defer prefetcher.Close()
+
+	for _, trackURL := range trackURLs {
+		result, ok := prefetcher.Next()
+		require.True(t, ok)
+		require.NoError(t, result.Err)
+		assert.Equal(t, trackURL, result.URL)
+		assert.Equal(t, trackURL, result.Track.PageURL)
+
+		content, err := ioutil.ReadAll(result.Track.Reader)
+		require.NoError(t, err)
+		assert.Equal(t, audio, content)
+		result.Track.Close()
+	}
+
+	_, ok := prefetcher.Next()
+	assert.False(t, ok)
+}
+
+func TestPrefetcher_Ahead(t *testing.T) {
+	var pages int32
+	server := newCountingTrackServer(t, randomAudio(t, 1000), &pages)
+	defer server.Close()
+
+	client, err := NewClient(WithBaseURL(server.URL), WithHTTPClient(server.Client()))
+	require.NoError(t, err, "failed to create client")
+
+	trackURLs := []string{server.URL + "/a/music/1", server.URL + "/a/music/2", server.URL + "/a/music/3", server.URL + "/a/music/4"}
+	prefetcher := NewPrefetcher(client, trackURLs, PrefetchOptions{Ahead: 2})
+
+	// Only the tracks ahead are fetched until Next is called
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&pages))
+
+	result, ok := prefetcher.Next()
+	require.True(t, ok)
+	result.Track.Close()
+
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&pages))
+
+	// Closing releases the fetched tracks and stops fetching the rest
+	prefetcher.Close()
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&pages))
+}
+
+func TestPrefetcher_ShouldDownload(t *testing.T) {
+	server := newTrackServer(t, randomAudio(t, 1000), true)
+	defer server.Close()
+
+	client, err := NewClient(WithBaseURL(server.URL), WithHTTPClient(server.Client()))
+	require.NoError(t, err, "failed to create client")
+
+	prefetcher := NewPrefetcher(client, []string{server.URL + "/a/music/1"}, PrefetchOptions{
+		ShouldDownload: func(track *Track) bool { return false },
+	})
+
+	defer prefetcher.Close()
+
+	result, ok := prefetcher.Next()
+	require.True(t, ok)
+	require.NoError(t, result.Err)
+	require.NotNil(t, result.Track)
+	assert.Nil(t, result.Track.Reader)
+}
+
+func TestPrefetcher_Error(t *testing.T) {
+	server := newTrackServer(t, []byte{}, true)
+	defer server.Close()
+
+	client, err := NewClient(WithBaseURL(server.URL), WithHTTPClient(server.Client()))
+	require.NoError(t, err, "failed to create client")
+
+	prefetcher := NewPrefetcher(client, []string{"https://example.com/a/music/1", server.URL + "/a/music/2"}, PrefetchOptions{})
+	defer prefetcher.Close()
+
+	result, ok := prefetcher.Next()
+	require.True(t, ok)
+	assert.Error(t, result.Err)
+	assert.Nil(t, result.Track)
+
+	// Tracks whose info is found but whose download fails are returned with the error
+	result, ok = prefetcher.Next()
+	require.True(t, ok)
+	assert.True(t, errors.Is(result.Err, ErrEmptyTrack))
+	require.NotNil(t, result.Track)
+	assert.Nil(t, result.Track.Reader)
+}
@@ -0,0 +1,155 @@
+package chipmusic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// probeLength is how much of the start of an audio file is fetched by ProbeTrack. It covers the headers of most files,
+// so estimating their duration takes a single request
+const probeLength = 8192
+
+// WithProbing makes GetTrackInfo estimate the Size and Duration of every track with ProbeTrack, which takes another
+// request or two for each track
+func WithProbing() Option {
+	return func(c *Client) error {
+		c.probe = true
+		return nil
+	}
+}
+
+// ProbeTrack sets the Size and Duration of a track returned by GetTrackInfo without downloading its audio. Only the
+// start of the audio file is fetched with a Range request, along with the end for file types which keep their length
+// there. If the track is cached, it is read from the cache instead
+func (c *Client) ProbeTrack(ctx context.Context, track *Track) error {
+	if track == nil {
+		return errors.New("track cannot be nil")
+	}
+
+	if c.cache != nil {
+		if file, _, ok := c.cache.open(track.DownloadURL); ok {
+			defer file.Close()
+
+			info, err := file.Stat()
+			if err != nil {
+				return fmt.Errorf("failed to get size of cached track: %w", err)
+			}
+
+			track.Size = info.Size()
+			track.Duration = estimateDuration(track.FileType, file, track.Size)
+			return nil
+		}
+	}
+
+	r := &rangeReaderAt{ctx: ctx, client: c.client, url: track.DownloadURL}
+	response, head, err := r.fetch(0, probeLength)
+	if err != nil {
+		return fmt.Errorf("failed to probe track: %w", err)
+	}
+
+	r.head = head
+	r.ranges = response.StatusCode == http.StatusPartialContent
+	track.Size = responseSize(response)
+	if fileType, ok := fileTypeFromContentType(response.Header.Get("Content-Type")); ok {
+		track.FileType = fileType
+	}
+
+	track.Duration = estimateDuration(track.FileType, r, track.Size)
+	return nil
+}
+
+// responseSize returns the size of the whole file from a response to a Range request, or 0 if the server doesn't say
+func responseSize(response *http.Response) int64 {
+	if response.StatusCode == http.StatusOK {
+		if response.ContentLength < 0 {
+			return 0
+		}
+
+		return response.ContentLength
+	}
+
+	contentRange := response.Header.Get("Content-Range")
+	size, err := strconv.ParseInt(contentRange[strings.LastIndex(contentRange, "/")+1:], 10, 64)
+	if err != nil {
+		return 0
+	}
+
+	return size
+}
+
+// rangeReaderAt is an io.ReaderAt which reads an audio file with Range requests. The start of the file fetched first
+// is kept since most reads are of the headers there
+type rangeReaderAt struct {
+	ctx    context.Context
+	client *http.Client
+	url    string
+	head   []byte
+
+	// ranges is whether the server accepts Range requests. If not, only the head can be read
+	ranges bool
+}
+
+// ReadAt reads len(p) bytes at off from the head or else with a Range request
+func (r *rangeReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off+int64(len(p)) <= int64(len(r.head)) {
+		return copy(p, r.head[off:]), nil
+	}
+
+	if !r.ranges {
+		if off >= int64(len(r.head)) {
+			return 0, io.EOF
+		}
+
+		return copy(p, r.head[off:]), io.EOF
+	}
+
+	_, body, err := r.fetch(off, len(p))
+	if err != nil {
+		return 0, err
+	}
+
+	n := copy(p, body)
+	if n < len(p) {
+		return n, io.EOF
+	}
+
+	return n, nil
+}
+
+// fetch requests length bytes at off. Servers which don't accept Range requests send the whole file, so only the
+// first length bytes of it are read before the response is closed
+func (r *rangeReaderAt) fetch(off int64, length int) (*http.Response, []byte, error) {
+	request, err := newRangeRequest(r.ctx, r.url, byteRange{start: off, end: off + int64(length)}, "")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	response, err := r.client.Do(request)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get response: %w", err)
+	}
+
+	defer response.Body.Close()
+
+	switch {
+	case response.StatusCode == http.StatusRequestedRangeNotSatisfiable:
+		return response, nil, nil
+	case response.StatusCode == http.StatusOK && off > 0:
+		return nil, nil, fmt.Errorf("expected status code %d but got %d instead", http.StatusPartialContent, response.StatusCode)
+	case response.StatusCode != http.StatusOK && response.StatusCode != http.StatusPartialContent:
+		return nil, nil, fmt.Errorf("expected status code %d or %d but got %d instead", http.StatusOK, http.StatusPartialContent, response.StatusCode)
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(response.Body, int64(length)))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	return response, body, nil
+}
@@ -0,0 +1,112 @@
+package chipmusic
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestProbeTrack(t *testing.T) {
+	testCases := []struct {
+		name     string
+		audio    []byte
+		ranges   bool
+		expected time.Duration
+	}{
+		{"WithRanges", newTestMP3(0, 4*time.Second), true, 4 * time.Second},
+		{"WithoutRanges", newTestMP3(0, 4*time.Second), false, 4 * time.Second},
+		{"LargeTagWithRanges", newTestMP3(2*probeLength, 4*time.Second), true, 4 * time.Second},
+		{"LargeTagWithoutRanges", newTestMP3(2*probeLength, 4*time.Second), false, 0},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			server := newTrackServer(tt, testCase.audio, testCase.ranges)
+			defer server.Close()
+
+			client, err := NewClient(WithBaseURL(server.URL), WithHTTPClient(server.Client()))
+			require.NoError(tt, err, "failed to create client")
+
+			track, err := client.GetTrackInfo(context.Background(), fmt.Sprintf("%s/some.artist/music/some.music", server.URL))
+			require.NoError(tt, err)
+
+			require.NoError(tt, client.ProbeTrack(context.Background(), track))
+			assert.Equal(tt, int64(len(testCase.audio)), track.Size)
+			assert.Equal(tt, testCase.expected, track.Duration)
+			assert.Equal(tt, AudioFileTypeMP3, track.FileType)
+			assert.Nil(tt, track.Reader)
+		})
+	}
+}
+
+func TestProbeTrack_Cached(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chipmusic-cache")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	audio := newTestMP3(0, 2*time.Second)
+	server := newTrackServer(t, audio, false)
+	client, err := NewClient(WithBaseURL(server.URL), WithHTTPClient(server.Client()), WithCache(dir, 1<<20))
+	require.NoError(t, err, "failed to create client")
+
+	track, err := client.GetTrack(context.Background(), fmt.Sprintf("%s/some.artist/music/some.music", server.URL))
+	require.NoError(t, err)
+	track.Close()
+
+	// The server is gone, so the track can only be probed from the cache
+	server.Close()
+	track.Size, track.Duration = 0, 0
+	require.NoError(t, client.ProbeTrack(context.Background(), track))
+	assert.Equal(t, int64(len(audio)), track.Size)
+	assert.Equal(t, 2*time.Second, track.Duration)
+}
+
+func TestProbeTrack_NotStatusCodeOK(t *testing.T) {
+	client, err := NewClient(WithHTTPClient(&http.Client{Transport: NewMockTransport(&http.Response{StatusCode: http.StatusNotFound, Body: http.NoBody}, nil)}))
+	require.NoError(t, err, "failed to create client")
+
+	err = client.ProbeTrack(context.Background(), &Track{DownloadURL: "http://localhost/some.track.mp3"})
+	assert.Error(t, err)
+}
+
+func TestProbeTrack_NilTrack(t *testing.T) {
+	client, err := NewClient()
+	require.NoError(t, err, "failed to create client")
+	assert.Error(t, client.ProbeTrack(context.Background(), nil))
+}
+
+func TestWithProbing(t *testing.T) {
+	audio := newTestMP3(0, 3*time.Second)
+	server := newTrackServer(t, audio, true)
+	defer server.Close()
+
+	testCases := []struct {
+		name     string
+		options  []Option
+		size     int64
+		duration time.Duration
+	}{
+		{"Probing", []Option{WithProbing()}, int64(len(audio)), 3 * time.Second},
+		{"NotProbing", nil, 0, 0},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			options := append([]Option{WithBaseURL(server.URL), WithHTTPClient(server.Client())}, testCase.options...)
+			client, err := NewClient(options...)
+			require.NoError(tt, err, "failed to create client")
+
+			track, err := client.GetTrackInfo(context.Background(), fmt.Sprintf("%s/some.artist/music/some.music", server.URL))
+			require.NoError(tt, err)
+			assert.Equal(tt, testCase.size, track.Size)
+			assert.Equal(tt, testCase.duration, track.Duration)
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package chipmusic
+
+import (
+	"errors"
+	"io"
+	"sync"
+)
+
+// ProgressFunc is called while the audio of a track downloads. downloadURL is the DownloadURL of the track, downloaded
+// is the number of bytes received so far, and total is the size of the download or -1 if the size is unknown. Calls
+// are never made concurrently, even when the track is downloaded by many workers
+type ProgressFunc func(downloadURL string, downloaded, total int64)
+
+// WithProgressFunc allows reporting the progress of track downloads, e.g. to display a progress bar. To keep the
+// overhead low, progress is only reported when another percent of the track has been downloaded. Streamed tracks are
+// read on demand and do not report progress
+func WithProgressFunc(progress ProgressFunc) Option {
+	return func(client *Client) error {
+		if progress == nil {
+			return errors.New("progress function cannot be nil")
+		}
+
+		client.progress = progress
+		return nil
+	}
+}
+
+// progressTracker sums the bytes received by every request downloading a track and reports them to a ProgressFunc
+type progressTracker struct {
+	mux        sync.Mutex
+	fn         ProgressFunc
+	url        string
+	downloaded int64
+	total      int64
+	reported   int64
+}
+
+// newProgressTracker returns a tracker for a download of total bytes. If fn is nil, the tracker does nothing
+func newProgressTracker(fn ProgressFunc, url string, total int64) *progressTracker {
+	if total < 0 {
+		total = -1
+	}
+
+	return &progressTracker{fn: fn, url: url, total: total, reported: -1}
+}
+
+// add records n more downloaded bytes, reporting them if the download crossed another percent or finished
+func (p *progressTracker) add(n int64) {
+	if p.fn == nil || n <= 0 {
+		return
+	}
+
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	p.downloaded += n
+
+	// The size of the download is unknown so there are no percents to wait for
+	if p.total <= 0 {
+		p.fn(p.url, p.downloaded, p.total)
+		return
+	}
+
+	percent := p.downloaded * 100 / p.total
+	if percent == p.reported {
+		return
+	}
+
+	p.reported = percent
+	p.fn(p.url, p.downloaded, p.total)
+}
+
+// forget takes n bytes back out of the bytes recorded so far, e.g. when a failed chunk is downloaded again. The next
+// report is made once the download crosses another percent again
+func (p *progressTracker) forget(n int64) {
+	if p.fn == nil || n <= 0 {
+		return
+	}
+
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	p.downloaded -= n
+}
+
+// reset forgets every byte recorded so far, e.g. when a failed download starts over
+func (p *progressTracker) reset() {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	p.downloaded = 0
+	p.reported = -1
+}
+
+// reader wraps r so every byte read from it is recorded by the tracker
+func (p *progressTracker) reader(r io.Reader) io.Reader {
+	if p.fn == nil {
+		return r
+	}
+
+	return &progressReader{reader: r, tracker: p}
+}
+
+type progressReader struct {
+	reader  io.Reader
+	tracker *progressTracker
+}
+
+func (r *progressReader) Read(b []byte) (int, error) {
+	n, err := r.reader.Read(b)
+	r.tracker.add(int64(n))
+	return n, err
+}
@@ -0,0 +1,119 @@
+package chipmusic
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestWithProgressFunc_Nil(t *testing.T) {
+	client, err := NewClient(WithProgressFunc(nil))
+	assert.Error(t, err)
+	assert.Nil(t, client)
+}
+
+func TestGetTrack_Progress(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chipmusic-progress")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	testCases := []struct {
+		name    string
+		ranges  bool
+		options []Option
+	}{
+		{"SingleRequest", false, nil},
+		{"Workers", true, []Option{WithWorkers(4)}},
+		{"Spooled", true, []Option{WithSpoolDir(dir), WithWorkers(4)}},
+		{"SpooledSingleRequest", false, []Option{WithSpoolDir(dir)}},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			audio := randomAudio(tt, 1000)
+			server := newTrackServer(tt, audio, testCase.ranges)
+			defer server.Close()
+
+			mux := sync.Mutex{}
+			reported := make([]int64, 0)
+			var reportedURL string
+			var reportedTotal int64
+			progress := func(downloadURL string, downloaded, total int64) {
+				mux.Lock()
+				defer mux.Unlock()
+				reported = append(reported, downloaded)
+				reportedURL, reportedTotal = downloadURL, total
+			}
+
+			options := append([]Option{WithBaseURL(server.URL), WithHTTPClient(server.Client()), WithProgressFunc(progress)}, testCase.options...)
+			client, err := NewClient(options...)
+			require.NoError(tt, err, "failed to create client")
+
+			track, err := client.GetTrack(context.Background(), fmt.Sprintf("%s/some.artist/music/some.music", server.URL))
+			require.NoError(tt, err)
+
+			defer track.Close()
+
+			if spool, ok := track.Reader.(*SpoolReader); ok {
+				require.NoError(tt, spool.Wait())
+			}
+
+			mux.Lock()
+			defer mux.Unlock()
+
+			require.NotEmpty(tt, reported)
+			assert.Equal(tt, track.DownloadURL, reportedURL)
+			assert.Equal(tt, int64(len(audio)), reportedTotal)
+			assert.Equal(tt, int64(len(audio)), reported[len(reported)-1])
+			for i := 1; i < len(reported); i++ {
+				assert.True(tt, reported[i] > reported[i-1], "progress should only increase")
+			}
+		})
+	}
+}
+
+func TestProgressTracker(t *testing.T) {
+	testCases := []struct {
+		name     string
+		total    int64
+		adds     []int64
+		expected []int64
+	}{
+		{"EveryPercent", 100, []int64{1, 1, 1}, []int64{1, 2, 3}},
+		{"WithinPercent", 1000, []int64{1, 5, 4, 990}, []int64{1, 10, 1000}},
+		{"UnknownTotal", -1, []int64{1, 1, 1}, []int64{1, 2, 3}},
+		{"NothingRead", 100, []int64{0}, []int64{}},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			reported := make([]int64, 0)
+			tracker := newProgressTracker(func(downloadURL string, downloaded, total int64) {
+				assert.Equal(tt, "some.url", downloadURL)
+				assert.Equal(tt, testCase.total, total)
+				reported = append(reported, downloaded)
+			}, "some.url", testCase.total)
+
+			for _, n := range testCase.adds {
+				tracker.add(n)
+			}
+
+			assert.Equal(tt, testCase.expected, reported)
+		})
+	}
+}
+
+func TestProgressTracker_NilFunc(t *testing.T) {
+	tracker := newProgressTracker(nil, "some.url", 100)
+	tracker.add(100)
+
+	reader := strings.NewReader("some.audio")
+	assert.Equal(t, reader, tracker.reader(reader))
+}
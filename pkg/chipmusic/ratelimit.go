@@ -0,0 +1,153 @@
+package chipmusic
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultRateBurst is the default number of requests which can be sent to a host at once before the rate limit
+	// applies. It matches DefaultWorkers so downloading a track with workers is not slowed down
+	DefaultRateBurst = DefaultWorkers
+)
+
+// WithRateLimit allows limiting how many requests per second are sent to each host. Requests over the limit wait
+// until they are allowed, which keeps modes that search constantly, such as shuffle, from hammering chipmusic.org. Use
+// WithRateBurst to allow short bursts over the limit
+func WithRateLimit(rps float64) Option {
+	return func(c *Client) error {
+		if rps <= 0 {
+			return errors.New("rate limit must be greater than 0")
+		}
+
+		c.rateLimit = rps
+		return nil
+	}
+}
+
+// WithRateBurst allows overriding how many requests can be sent to a host at once before the rate limit applies. It
+// has no effect unless a rate limit is set with WithRateLimit
+func WithRateBurst(burst int) Option {
+	return func(c *Client) error {
+		if burst <= 0 {
+			return errors.New("rate burst must be a positive integer")
+		}
+
+		c.rateBurst = burst
+		return nil
+	}
+}
+
+// rateLimiter is a token bucket per host. Each bucket holds up to burst tokens and refills at rps tokens per second
+type rateLimiter struct {
+	rps   float64
+	burst int
+	now   func() time.Time
+
+	mux     sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(rps float64, burst int) *rateLimiter {
+	return &rateLimiter{
+		rps:     rps,
+		burst:   burst,
+		now:     time.Now,
+		buckets: map[string]*tokenBucket{},
+	}
+}
+
+// reserve takes a token from the bucket of host and returns how long to wait until the token is available
+func (l *rateLimiter) reserve(host string) time.Duration {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	now := l.now()
+	bucket, ok := l.buckets[host]
+	if !ok {
+		bucket = &tokenBucket{tokens: float64(l.burst), last: now}
+		l.buckets[host] = bucket
+	}
+
+	bucket.tokens += now.Sub(bucket.last).Seconds() * l.rps
+	if bucket.tokens > float64(l.burst) {
+		bucket.tokens = float64(l.burst)
+	}
+
+	bucket.last = now
+	bucket.tokens--
+	if bucket.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(-bucket.tokens / l.rps * float64(time.Second))
+}
+
+// cancel gives back a token reserved from the bucket of host by a request which was never sent
+func (l *rateLimiter) cancel(host string) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	if bucket, ok := l.buckets[host]; ok {
+		bucket.tokens++
+		if bucket.tokens > float64(l.burst) {
+			bucket.tokens = float64(l.burst)
+		}
+	}
+}
+
+// wait blocks until a request to host is allowed or ctx is done. If ctx is done first, the token reserved for the
+// request is given back so cancelled requests, e.g. of a skipped download, don't delay the requests after them
+func (l *rateLimiter) wait(ctx context.Context, host string) error {
+	delay := l.reserve(host)
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		l.cancel(host)
+		return ctx.Err()
+	}
+}
+
+// rateLimitedTransport is an http.RoundTripper which waits for the rate limiter before sending each request. Limiting
+// at the transport applies to every request of the client, including ranged requests made while streaming
+type rateLimitedTransport struct {
+	base    http.RoundTripper
+	limiter *rateLimiter
+}
+
+func (t *rateLimitedTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	if err := t.limiter.wait(request.Context(), request.URL.Host); err != nil {
+		return nil, err
+	}
+
+	return t.base.RoundTrip(request)
+}
+
+// wrapTransport returns a copy of client whose transport is wrapped by wrap. The original client is left untouched since
+// it may be shared, e.g. http.DefaultClient
+func wrapTransport(client *http.Client, wrap func(base http.RoundTripper) http.RoundTripper) *http.Client {
+	base := client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	wrapped := *client
+	wrapped.Transport = wrap(base)
+	return &wrapped
+}
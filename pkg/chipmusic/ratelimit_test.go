@@ -0,0 +1,110 @@
+package chipmusic
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWithRateLimit(t *testing.T) {
+	for _, rps := range []float64{0, -1} {
+		_, err := NewClient(WithRateLimit(rps))
+		assert.Error(t, err)
+	}
+}
+
+func TestWithRateBurst(t *testing.T) {
+	for _, burst := range []int{0, -1} {
+		_, err := NewClient(WithRateBurst(burst))
+		assert.Error(t, err)
+	}
+}
+
+func TestRateLimiter_Reserve(t *testing.T) {
+	now := time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)
+	limiter := newRateLimiter(2, 2)
+	limiter.now = func() time.Time { return now }
+
+	// The burst is available immediately
+	assert.Zero(t, limiter.reserve("chipmusic.org"))
+	assert.Zero(t, limiter.reserve("chipmusic.org"))
+
+	// Then each request waits for the bucket to refill
+	assert.Equal(t, 500*time.Millisecond, limiter.reserve("chipmusic.org"))
+	assert.Equal(t, time.Second, limiter.reserve("chipmusic.org"))
+
+	// Other hosts have their own bucket
+	assert.Zero(t, limiter.reserve("chipmusic.s3.amazonaws.com"))
+
+	// The bucket refills over time but never beyond the burst
+	now = now.Add(time.Hour)
+	assert.Zero(t, limiter.reserve("chipmusic.org"))
+	assert.Zero(t, limiter.reserve("chipmusic.org"))
+	assert.Equal(t, 500*time.Millisecond, limiter.reserve("chipmusic.org"))
+}
+
+func TestRateLimiter_WaitCancelled(t *testing.T) {
+	limiter := newRateLimiter(0.001, 1)
+	require.NoError(t, limiter.wait(context.Background(), "chipmusic.org"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := limiter.wait(ctx, "chipmusic.org")
+	assert.True(t, errors.Is(err, context.Canceled))
+}
+
+func TestRateLimiter_WaitCancelledGivesBackToken(t *testing.T) {
+	now := time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)
+	limiter := newRateLimiter(2, 1)
+	limiter.now = func() time.Time { return now }
+	require.NoError(t, limiter.wait(context.Background(), "chipmusic.org"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// Requests cancelled while they wait, e.g. the chunks of a skipped download, leave the bucket as it was
+	for i := 0; i < DefaultWorkers; i++ {
+		err := limiter.wait(ctx, "chipmusic.org")
+		assert.True(t, errors.Is(err, context.Canceled))
+	}
+
+	assert.Equal(t, 500*time.Millisecond, limiter.reserve("chipmusic.org"))
+}
+
+func TestClient_RateLimit(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+	}))
+
+	defer server.Close()
+
+	httpClient := server.Client()
+	client, err := NewClient(WithBaseURL(server.URL), WithHTTPClient(httpClient), WithRateLimit(20), WithRateBurst(1))
+	require.NoError(t, err, "failed to create client")
+	assert.NotEqual(t, httpClient, client.client, "expected the given HTTP client to be left untouched")
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		_, err := client.Search(context.Background(), SearchOptions{})
+		require.NoError(t, err)
+	}
+
+	// The first request is sent immediately and each of the others waits 50ms
+	assert.True(t, time.Since(start) >= 100*time.Millisecond, "expected requests to be rate limited")
+	assert.Equal(t, int32(3), atomic.LoadInt32(&requests))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = client.Search(ctx, SearchOptions{})
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.Equal(t, int32(3), atomic.LoadInt32(&requests), "expected a cancelled request to never be sent")
+}
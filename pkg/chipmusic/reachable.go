@@ -0,0 +1,92 @@
+package chipmusic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// DefaultReachabilityChecks is how many tracks FilterReachable checks at once
+const DefaultReachabilityChecks = 8
+
+// ErrUnreachableTrack is returned when the server says a track page or the audio file of a track is gone, e.g. because
+// an old upload was removed
+var ErrUnreachableTrack = errors.New("track is unreachable")
+
+// CheckReachable gets the info of the track at trackPageURL and sends a HEAD request for its audio file without
+// downloading it. It returns an error wrapping ErrUnreachableTrack if the server says either is gone. Other errors,
+// e.g. timeouts, are returned as they are since the track may still play later. Cached audio is always reachable
+func (c *Client) CheckReachable(ctx context.Context, trackPageURL string) error {
+	track, err := c.GetTrackInfo(ctx, trackPageURL)
+	if err != nil {
+		return err
+	}
+
+	if c.cache != nil {
+		if file, _, ok := c.cache.open(track.DownloadURL); ok {
+			file.Close()
+			return nil
+		}
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodHead, track.DownloadURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to build request to check track: %w", err)
+	}
+
+	response, err := c.client.Do(request)
+	if err != nil {
+		return fmt.Errorf("failed to get response when checking track: %w", err)
+	}
+
+	response.Body.Close()
+	if gone(response.StatusCode) {
+		return fmt.Errorf("%w: audio file responded with status code %d", ErrUnreachableTrack, response.StatusCode)
+	}
+
+	return nil
+}
+
+// FilterReachable checks the tracks at trackPageURLs with CheckReachable, up to parallel at a time, and returns the URLs
+// of the tracks which are not unreachable in their original order, along with why each unreachable track is. Tracks
+// whose check failed for another reason are kept. If parallel is 0 or less, DefaultReachabilityChecks is used
+func (c *Client) FilterReachable(ctx context.Context, trackPageURLs []string, parallel int) ([]string, map[string]error) {
+	if parallel <= 0 {
+		parallel = DefaultReachabilityChecks
+	}
+
+	errs := make([]error, len(trackPageURLs))
+	semaphore := make(chan struct{}, parallel)
+	wg := sync.WaitGroup{}
+	for i, trackPageURL := range trackPageURLs {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(i int, trackPageURL string) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			errs[i] = c.CheckReachable(ctx, trackPageURL)
+		}(i, trackPageURL)
+	}
+
+	wg.Wait()
+
+	reachable := make([]string, 0, len(trackPageURLs))
+	unreachable := map[string]error{}
+	for i, trackPageURL := range trackPageURLs {
+		if errors.Is(errs[i], ErrUnreachableTrack) {
+			unreachable[trackPageURL] = errs[i]
+			continue
+		}
+
+		reachable = append(reachable, trackPageURL)
+	}
+
+	return reachable, unreachable
+}
+
+// gone returns true if statusCode means the requested resource doesn't exist
+func gone(statusCode int) bool {
+	return statusCode == http.StatusNotFound || statusCode == http.StatusGone
+}
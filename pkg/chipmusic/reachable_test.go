@@ -0,0 +1,57 @@
+package chipmusic
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestFilterReachable(t *testing.T) {
+	tracks := newTrackServer(t, randomAudio(t, 1000), true)
+	defer tracks.Close()
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/removed.page"):
+			w.WriteHeader(http.StatusNotFound)
+		case strings.HasPrefix(r.URL.Path, "/unavailable.page"):
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case r.URL.Path == "/removed.mp3":
+			w.WriteHeader(http.StatusGone)
+		case strings.HasPrefix(r.URL.Path, "/removed.audio"):
+			raw, err := ioutil.ReadFile(defaultTrackPageFile)
+			require.NoError(t, err, "failed to read content of %s as server response", defaultTrackPageFile)
+
+			_, err = w.Write([]byte(strings.Replace(string(raw), defaultTrackLink, server.URL+"/removed.mp3", 1)))
+			require.NoError(t, err, "failed to write %s as server response", defaultTrackPageFile)
+		default:
+			tracks.Config.Handler.ServeHTTP(w, r)
+		}
+	}))
+
+	defer server.Close()
+
+	client, err := NewClient(WithBaseURL(server.URL), WithHTTPClient(server.Client()))
+	require.NoError(t, err, "failed to create client")
+
+	trackURLs := []string{
+		server.URL + "/some.artist/music/first",
+		server.URL + "/removed.page/music/some.track",
+		server.URL + "/unavailable.page/music/some.track",
+		server.URL + "/removed.audio/music/some.track",
+		server.URL + "/some.artist/music/second",
+	}
+
+	reachable, unreachable := client.FilterReachable(context.Background(), trackURLs, 2)
+	assert.Equal(t, []string{trackURLs[0], trackURLs[2], trackURLs[4]}, reachable, "expected tracks failing for other reasons to be kept")
+	require.Len(t, unreachable, 2)
+	assert.True(t, errors.Is(unreachable[trackURLs[1]], ErrUnreachableTrack))
+	assert.True(t, errors.Is(unreachable[trackURLs[3]], ErrUnreachableTrack))
+}
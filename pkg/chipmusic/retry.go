@@ -0,0 +1,93 @@
+package chipmusic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// DefaultChunkRetries is the default number of times a chunk of a track is retried with a Range request before the
+	// track is downloaded with a single request instead
+	DefaultChunkRetries = 1
+
+	// DefaultDownloadDeadline is the default limit on how long downloading the audio of a track may take as a whole
+	DefaultDownloadDeadline = 10 * time.Minute
+)
+
+// ErrDownloadDeadline is an error returned when downloading the audio of a track takes longer than the download deadline
+var ErrDownloadDeadline = errors.New("download deadline exceeded")
+
+// RetryFunc is called before a failed download of the audio of a track is tried again. downloadURL is the DownloadURL
+// of the track, attempt is how many attempts failed so far, and err is why the last one failed. Falling back to a
+// single request for the whole file counts as trying again. Calls may be made concurrently by the workers downloading
+// a track
+type RetryFunc func(downloadURL string, attempt int, err error)
+
+// WithChunkRetries allows overriding how many times a chunk of a track is retried with a Range request before the
+// track is downloaded with a single request instead. Only the failed chunk is retried, so the chunks which already
+// arrived are kept. Use 0 to fall back to a single request as soon as a chunk fails
+func WithChunkRetries(retries int) Option {
+	return func(c *Client) error {
+		if retries < 0 {
+			return errors.New("chunk retries cannot be negative")
+		}
+
+		c.chunkRetries = retries
+		return nil
+	}
+}
+
+// WithRetryFunc allows reporting failed downloads which are tried again, e.g. to explain a slow download in a log
+func WithRetryFunc(retry RetryFunc) Option {
+	return func(c *Client) error {
+		if retry == nil {
+			return errors.New("retry function cannot be nil")
+		}
+
+		c.retry = retry
+		return nil
+	}
+}
+
+// WithDownloadDeadline allows overriding how long downloading the audio of a track may take as a whole, including
+// retries and the fallback to a single request. Unlike the context given to the client, the deadline also applies to
+// spooled tracks which keep downloading in the background, so one stuck chunk can't hang a download forever. Streamed
+// tracks are fetched as they are read and aren't limited. Use 0 to wait for as long as the context allows
+func WithDownloadDeadline(deadline time.Duration) Option {
+	return func(c *Client) error {
+		if deadline < 0 {
+			return errors.New("download deadline cannot be negative")
+		}
+
+		c.downloadDeadline = deadline
+		return nil
+	}
+}
+
+// reportRetry calls the retry function of the client, if any, before the download of downloadURL is tried again
+func (c *Client) reportRetry(downloadURL string, attempt int, err error) {
+	if c.retry != nil {
+		c.retry(downloadURL, attempt, err)
+	}
+}
+
+// withDownloadDeadline returns a context which is done when ctx is or once the download deadline passes
+func (c *Client) withDownloadDeadline(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.downloadDeadline <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, c.downloadDeadline)
+}
+
+// deadlineError wraps err in ErrDownloadDeadline if the download it came from was cut off by the download deadline of
+// ctx rather than by parent
+func (c *Client) deadlineError(parent, ctx context.Context, err error) error {
+	if err == nil || parent.Err() != nil || !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return err
+	}
+
+	return fmt.Errorf("%w after %s: %v", ErrDownloadDeadline, c.downloadDeadline, err)
+}
@@ -0,0 +1,141 @@
+package chipmusic
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWithChunkRetries(t *testing.T) {
+	client, err := NewClient(WithChunkRetries(-1))
+	assert.Error(t, err)
+	assert.Nil(t, client)
+
+	client, err = NewClient(WithChunkRetries(0))
+	require.NoError(t, err)
+	assert.Equal(t, 0, client.chunkRetries)
+}
+
+func TestWithDownloadDeadline(t *testing.T) {
+	client, err := NewClient(WithDownloadDeadline(-time.Second))
+	assert.Error(t, err)
+	assert.Nil(t, client)
+
+	client, err = NewClient()
+	require.NoError(t, err)
+	assert.Equal(t, DefaultDownloadDeadline, client.downloadDeadline)
+}
+
+func TestWithRetryFunc(t *testing.T) {
+	client, err := NewClient(WithRetryFunc(nil))
+	assert.Error(t, err)
+	assert.Nil(t, client)
+}
+
+func TestDownloadTrack_ChunkRetries(t *testing.T) {
+	audio := randomAudio(t, 1000)
+	for _, spooled := range []bool{false, true} {
+		t.Run(fmt.Sprintf("spooled=%t", spooled), func(tt *testing.T) {
+			// The first request for each range fails, so every chunk needs one retry
+			var mux sync.Mutex
+			failed := map[string]bool{}
+			server := newCorruptTrackServer(tt, func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodGet && r.Header.Get("Range") != "" {
+					mux.Lock()
+					first := !failed[r.Header.Get("Range")]
+					failed[r.Header.Get("Range")] = true
+					mux.Unlock()
+
+					if first {
+						w.WriteHeader(http.StatusServiceUnavailable)
+						return
+					}
+				}
+
+				http.ServeContent(w, r, "some.track.mp3", time.Time{}, bytes.NewReader(audio))
+			})
+
+			defer server.Close()
+
+			var retries []string
+			retry := func(downloadURL string, attempt int, err error) {
+				mux.Lock()
+				defer mux.Unlock()
+				retries = append(retries, fmt.Sprintf("%s %d %t", downloadURL, attempt, err != nil))
+			}
+
+			options := []Option{WithBaseURL(server.URL), WithHTTPClient(server.Client()), WithWorkers(4), WithChunkRetries(1), WithRetryFunc(retry)}
+			if spooled {
+				options = append(options, WithSpoolDir(os.TempDir()))
+			}
+
+			client, err := NewClient(options...)
+			require.NoError(tt, err, "failed to create client")
+
+			track, err := client.GetTrack(context.Background(), fmt.Sprintf("%s/some.artist/music/some.music", server.URL))
+			require.NoError(tt, err)
+
+			defer track.Close()
+
+			content, err := ioutil.ReadAll(track.Reader)
+			require.NoError(tt, err)
+			assert.Equal(tt, audio, content)
+			assert.False(tt, client.singleStreamHosts.has(server.URL+testAudioPath), "expected retried chunks to keep using Range requests")
+
+			mux.Lock()
+			defer mux.Unlock()
+
+			expected := fmt.Sprintf("%s 1 true", track.DownloadURL)
+			assert.Equal(tt, []string{expected, expected, expected, expected}, retries, "expected the retry of every chunk to be reported")
+		})
+	}
+}
+
+func TestDownloadTrack_Deadline(t *testing.T) {
+	audio := randomAudio(t, 1000)
+	for _, spooled := range []bool{false, true} {
+		t.Run(fmt.Sprintf("spooled=%t", spooled), func(tt *testing.T) {
+			// The second half of the track never arrives
+			server := newCorruptTrackServer(tt, func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodGet && r.Header.Get("Range") != "bytes=500-999" {
+					http.ServeContent(w, r, "some.track.mp3", time.Time{}, bytes.NewReader(audio))
+					return
+				}
+
+				if r.Method == http.MethodGet {
+					<-r.Context().Done()
+					return
+				}
+
+				http.ServeContent(w, r, "some.track.mp3", time.Time{}, bytes.NewReader(audio))
+			})
+
+			defer server.Close()
+
+			options := []Option{WithBaseURL(server.URL), WithHTTPClient(server.Client()), WithWorkers(2), WithDownloadDeadline(100 * time.Millisecond)}
+			if spooled {
+				options = append(options, WithSpoolDir(os.TempDir()))
+			}
+
+			client, err := NewClient(options...)
+			require.NoError(tt, err, "failed to create client")
+
+			track, err := client.GetTrack(context.Background(), fmt.Sprintf("%s/some.artist/music/some.music", server.URL))
+			if err == nil {
+				defer track.Close()
+				_, err = ioutil.ReadAll(track.Reader)
+			}
+
+			assert.True(tt, errors.Is(err, ErrDownloadDeadline), "expected the download deadline to be exceeded but got %v", err)
+		})
+	}
+}
@@ -0,0 +1,323 @@
+package chipmusic
+
+import (
+	"context"
+	"fmt"
+	"github.com/PuerkitoBio/goquery"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// FormatLSDJ matches tracks made with Little Sound DJ on a Game Boy
+	FormatLSDJ Format = "lsdj"
+
+	// FormatNanoloop matches tracks made with Nanoloop
+	FormatNanoloop Format = "nanoloop"
+
+	// Format2A03 matches tracks made for the sound chip of the NES
+	Format2A03 Format = "2a03"
+
+	// FormatSID matches tracks made for the sound chip of the Commodore 64
+	FormatSID Format = "sid"
+
+	// FormatFamitracker matches tracks made with FamiTracker
+	FormatFamitracker Format = "famitracker"
+
+	// FormatDeflemask matches tracks made with DefleMask
+	FormatDeflemask Format = "deflemask"
+
+	// SortDefault keeps the order chipmusic.org returns results in
+	SortDefault SortOrder = ""
+
+	// SortNewest orders results from the most to the least recently posted
+	SortNewest SortOrder = "newest"
+
+	// SortOldest orders results from the least to the most recently posted
+	SortOldest SortOrder = "oldest"
+
+	// SortMostViewed orders results from the most to the least viewed
+	SortMostViewed SortOrder = "views"
+
+	// SortMostCommented orders results from the most to the least commented
+	SortMostCommented SortOrder = "comments"
+)
+
+// Format is the hardware or software a track was made with. chipmusic.org tracks formats as tags, so a format filter
+// is a tag filter with a well-known name
+type Format string
+
+// SortOrder is the order search results are returned in. Sorting applies to the results of a single page
+type SortOrder string
+
+// SearchOptions configures a search against chipmusic.org
+type SearchOptions struct {
+
+	// Query is free text to search for. If empty, every track matching the other options is returned
+	Query string
+
+	// Filter is the filter chipmusic.org applies to the search. If empty or unknown, TrackFilterRandom is used
+	Filter TrackFilter
+
+	// Formats limits results to tracks made with these formats
+	Formats []Format
+
+	// Tags limits results to tracks with these tags
+	Tags []string
+
+	// Sort is the order results are returned in. Defaults to SortDefault
+	Sort SortOrder
+
+	// Page is the page of results to return, starting at 1. If 0 or less, the first page is returned
+	Page int
+
+	// Limit is the maximum number of results to return. If 0 or less, every result on the page is returned
+	Limit int
+}
+
+// SearchResult is a track listed by a search. It contains what the search page shows about the track; use GetTrack
+// with URL to get the rest
+type SearchResult struct {
+
+	// URL is the URL of the track page on chipmusic.org
+	URL string
+
+	// Title is the name of the track
+	Title string
+
+	// Artist is the name of the author who composed the track
+	Artist string
+
+	// PostedAt is when the track was posted to chipmusic.org. It is the zero time if the date could not be parsed
+	PostedAt time.Time
+
+	// Views is how many times the track page was viewed
+	Views int
+
+	// Comments is how many comments were posted on the track page
+	Comments int
+}
+
+// SearchResultURLs returns the URLs of the track pages of results in the same order
+func SearchResultURLs(results []SearchResult) []string {
+	urls := make([]string, 0, len(results))
+	for _, result := range results {
+		urls = append(urls, result.URL)
+	}
+
+	return urls
+}
+
+// Search performs a search against chipmusic.org, returning the tracks which match. If a search returns more tracks
+// than can be returned in a single call, you can use the Page option to paginate through the additional tracks. To
+// iterate through all tracks for a particular search, start with page 1 and increment it for subsequent calls. Unless
+// a SortOrder is given, the order of the tracks returned is undefined. If no tracks are found or there are no other
+// tracks, an empty slice is returned
+func (c *Client) Search(ctx context.Context, options SearchOptions) ([]SearchResult, error) {
+	page := options.Page
+	if page <= 0 {
+		page = 1
+	}
+
+	resolved, ok := filters[options.Filter]
+	if !ok {
+		resolved = defaultTrackFilter
+	}
+
+	u, err := url.Parse(fmt.Sprintf("%s/music#", c.baseURL))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build search URL: %w", err)
+	}
+
+	params := url.Values(map[string][]string{
+		"s": {searchQuery(options)},
+		"p": {strconv.Itoa(page)},
+		"f": {resolved},
+	})
+
+	u.RawQuery = params.Encode()
+
+	document, err := c.getSearchPageDocument(ctx, u.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get search page document: %w", err)
+	}
+
+	results := c.filterBlocked(parseSearchResults(document))
+	sortSearchResults(results, options.Sort)
+	if options.Limit > 0 && len(results) > options.Limit {
+		results = results[:options.Limit]
+	}
+
+	return results, nil
+}
+
+// searchQuery builds the search text understood by chipmusic.org, which matches tags with a "tag:" prefix
+func searchQuery(options SearchOptions) string {
+	terms := make([]string, 0, len(options.Formats)+len(options.Tags)+1)
+	for _, format := range options.Formats {
+		terms = append(terms, "tag:"+string(format))
+	}
+
+	for _, tag := range options.Tags {
+		terms = append(terms, "tag:"+tag)
+	}
+
+	if options.Query != "" {
+		terms = append(terms, options.Query)
+	}
+
+	return strings.Join(terms, " ")
+}
+
+func (c *Client) getSearchPageDocument(ctx context.Context, url string) (*goquery.Document, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request to search for tracks: %w", err)
+	}
+
+	response, err := c.client.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get response when searching for tracks: %w", err)
+	}
+
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("expected status code %d when searching for tracks but got %d instead", http.StatusOK, response.StatusCode)
+	}
+
+	document, err := goquery.NewDocumentFromReader(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create parser when searching for tracks: %w", err)
+	}
+
+	return document, nil
+}
+
+func parseSearchResults(document *goquery.Document) []SearchResult {
+	results := make([]SearchResult, 0)
+	document.Find("#music_list .main-item").Each(func(_ int, item *goquery.Selection) {
+		link := item.Find(".item-subject .hn a")
+		href, ok := link.Attr("href")
+		if !ok {
+			return
+		}
+
+		results = append(results, SearchResult{
+			URL:      href,
+			Title:    strings.TrimSpace(link.Text()),
+			Artist:   strings.TrimSpace(item.Find(".item-starter cite").Text()),
+			PostedAt: parsePostedAt(item.Find(".info-lastpost strong").Text()),
+			Views:    parseCount(item.Find(".info-views strong").Text()),
+			Comments: parseCount(item.Find(".info-replies strong").Text()),
+		})
+	})
+
+	return results
+}
+
+func parseCount(text string) int {
+	count, err := strconv.Atoi(strings.ReplaceAll(strings.TrimSpace(text), ",", ""))
+	if err != nil {
+		return 0
+	}
+
+	return count
+}
+
+func sortSearchResults(results []SearchResult, order SortOrder) {
+	var less func(i, j int) bool
+	switch order {
+	case SortNewest:
+		less = func(i, j int) bool { return results[i].PostedAt.After(results[j].PostedAt) }
+	case SortOldest:
+		less = func(i, j int) bool { return results[i].PostedAt.Before(results[j].PostedAt) }
+	case SortMostViewed:
+		less = func(i, j int) bool { return results[i].Views > results[j].Views }
+	case SortMostCommented:
+		less = func(i, j int) bool { return results[i].Comments > results[j].Comments }
+	default:
+		return
+	}
+
+	sort.SliceStable(results, less)
+}
+
+// SearchIterator walks the pages of a search until a page has no results. Use Next to fetch each page:
+//
+//	it := client.SearchIterator(ctx, options)
+//	for it.Next() {
+//		results := it.Results()
+//	}
+//
+//	if err := it.Err(); err != nil {
+//		return err
+//	}
+type SearchIterator struct {
+	client  *Client
+	ctx     context.Context
+	options SearchOptions
+	page    int
+	results []SearchResult
+	err     error
+	done    bool
+}
+
+// SearchIterator returns an iterator over the pages of a search, starting at the Page option. Cancelling ctx stops the
+// iteration and Err returns the reason
+func (c *Client) SearchIterator(ctx context.Context, options SearchOptions) *SearchIterator {
+	page := options.Page
+	if page <= 0 {
+		page = 1
+	}
+
+	return &SearchIterator{client: c, ctx: ctx, options: options, page: page - 1}
+}
+
+// Next fetches the next page of results. It returns false once a page has no results, ctx is cancelled, or a search
+// fails
+func (it *SearchIterator) Next() bool {
+	if it.done {
+		return false
+	}
+
+	if err := it.ctx.Err(); err != nil {
+		it.err, it.done = err, true
+		return false
+	}
+
+	options := it.options
+	options.Page = it.page + 1
+	results, err := it.client.Search(it.ctx, options)
+	if err != nil {
+		it.err, it.done = fmt.Errorf("failed to search page %d: %w", options.Page, err), true
+		return false
+	}
+
+	if len(results) == 0 {
+		it.results, it.done = nil, true
+		return false
+	}
+
+	it.page, it.results = options.Page, results
+	return true
+}
+
+// Results returns the results of the page fetched by the last call to Next
+func (it *SearchIterator) Results() []SearchResult {
+	return it.results
+}
+
+// Page returns the number of the page fetched by the last call to Next
+func (it *SearchIterator) Page() int {
+	return it.page
+}
+
+// Err returns the error which stopped the iteration. It is nil if the iteration stopped because the search was
+// exhausted
+func (it *SearchIterator) Err() error {
+	return it.err
+}
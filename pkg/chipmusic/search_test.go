@@ -0,0 +1,175 @@
+package chipmusic
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSearch_Options(t *testing.T) {
+	testCases := []struct {
+		name           string
+		options        SearchOptions
+		expectedQuery  string
+		expectedPage   string
+		expectedFilter string
+	}{
+		{"Defaults", SearchOptions{}, "", "1", defaultTrackFilter},
+		{"Query", SearchOptions{Query: "some.search", Filter: TrackFilterLatest, Page: 2}, "some.search", "2", "0"},
+		{"UnknownFilter", SearchOptions{Filter: "some.filter"}, "", "1", defaultTrackFilter},
+		{"Formats", SearchOptions{Query: "some.search", Formats: []Format{FormatLSDJ, Format2A03}}, "tag:lsdj tag:2a03 some.search", "1", defaultTrackFilter},
+		{"Tags", SearchOptions{Formats: []Format{FormatSID}, Tags: []string{"rock"}}, "tag:sid tag:rock", "1", defaultTrackFilter},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(tt, testCase.expectedQuery, r.URL.Query().Get("s"))
+				assert.Equal(tt, testCase.expectedPage, r.URL.Query().Get("p"))
+				assert.Equal(tt, testCase.expectedFilter, r.URL.Query().Get("f"))
+			}))
+
+			defer server.Close()
+
+			client, err := NewClient(WithBaseURL(server.URL), WithHTTPClient(server.Client()))
+			require.NoError(tt, err, "failed to create client")
+
+			results, err := client.Search(context.Background(), testCase.options)
+			require.NoError(tt, err)
+			assert.Empty(tt, results)
+		})
+	}
+}
+
+func TestSearch_SortAndLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, err := ioutil.ReadFile(defaultSearchPageFile)
+		require.NoError(t, err, "failed to read content of %s as server response", defaultSearchPageFile)
+
+		_, err = w.Write(raw)
+		require.NoError(t, err, "failed to write %s as server response", defaultSearchPageFile)
+	}))
+
+	defer server.Close()
+
+	client, err := NewClient(WithBaseURL(server.URL), WithHTTPClient(server.Client()))
+	require.NoError(t, err, "failed to create client")
+
+	testCases := []struct {
+		name  string
+		sort  SortOrder
+		limit int
+		less  func(a, b SearchResult) bool
+	}{
+		{"Newest", SortNewest, 0, func(a, b SearchResult) bool { return !a.PostedAt.Before(b.PostedAt) }},
+		{"Oldest", SortOldest, 0, func(a, b SearchResult) bool { return !a.PostedAt.After(b.PostedAt) }},
+		{"MostViewed", SortMostViewed, 5, func(a, b SearchResult) bool { return a.Views >= b.Views }},
+		{"MostCommented", SortMostCommented, 1, func(a, b SearchResult) bool { return a.Comments >= b.Comments }},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			results, err := client.Search(context.Background(), SearchOptions{Sort: testCase.sort, Limit: testCase.limit})
+			require.NoError(tt, err)
+
+			if testCase.limit > 0 {
+				assert.Len(tt, results, testCase.limit)
+			} else {
+				assert.Len(tt, results, 20)
+			}
+
+			for i := 1; i < len(results); i++ {
+				assert.True(tt, testCase.less(results[i-1], results[i]), "results should be sorted")
+			}
+		})
+	}
+}
+
+func TestSearchResultURLs(t *testing.T) {
+	results := []SearchResult{{URL: "some.url"}, {URL: "some.other.url"}}
+	assert.Equal(t, []string{"some.url", "some.other.url"}, SearchResultURLs(results))
+	assert.Empty(t, SearchResultURLs(nil))
+}
+
+func TestParseCount(t *testing.T) {
+	assert.Equal(t, 4, parseCount(" 4 "))
+	assert.Equal(t, 1234, parseCount("1,234"))
+	assert.Equal(t, 0, parseCount("some.count"))
+}
+
+func TestSearchIterator(t *testing.T) {
+	pages := [][]string{
+		{"https://chipmusic.org/a/music/1", "https://chipmusic.org/a/music/2"},
+		{"https://chipmusic.org/a/music/3"},
+	}
+
+	testCases := []struct {
+		name          string
+		start         int
+		expectedPages []int
+		expectedURLs  []string
+	}{
+		{"FirstPage", 0, []int{1, 2}, append(append([]string{}, pages[0]...), pages[1]...)},
+		{"LaterPage", 2, []int{2}, pages[1]},
+		{"PastEnd", 3, []int{}, []string{}},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			server := newSearchServer(tt, pages)
+			defer server.Close()
+
+			client, err := NewClient(WithBaseURL(server.URL), WithHTTPClient(server.Client()))
+			require.NoError(tt, err, "failed to create client")
+
+			it := client.SearchIterator(context.Background(), SearchOptions{Page: testCase.start})
+			walked, urls := make([]int, 0), make([]string, 0)
+			for it.Next() {
+				walked = append(walked, it.Page())
+				urls = append(urls, SearchResultURLs(it.Results())...)
+			}
+
+			require.NoError(tt, it.Err())
+			assert.Equal(tt, testCase.expectedPages, walked)
+			assert.Equal(tt, testCase.expectedURLs, urls)
+			assert.False(tt, it.Next(), "expected an exhausted iterator to stay exhausted")
+		})
+	}
+}
+
+func TestSearchIterator_Cancelled(t *testing.T) {
+	server := newSearchServer(t, [][]string{{"https://chipmusic.org/a/music/1"}, {"https://chipmusic.org/a/music/2"}})
+	defer server.Close()
+
+	client, err := NewClient(WithBaseURL(server.URL), WithHTTPClient(server.Client()))
+	require.NoError(t, err, "failed to create client")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	it := client.SearchIterator(ctx, SearchOptions{})
+	require.True(t, it.Next(), "expected the first page")
+
+	cancel()
+	assert.False(t, it.Next(), "expected iteration to stop once cancelled")
+	assert.True(t, errors.Is(it.Err(), context.Canceled))
+}
+
+func TestSearchIterator_SearchError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+
+	defer server.Close()
+
+	client, err := NewClient(WithBaseURL(server.URL), WithHTTPClient(server.Client()))
+	require.NoError(t, err, "failed to create client")
+
+	it := client.SearchIterator(context.Background(), SearchOptions{})
+	assert.False(t, it.Next())
+	assert.Error(t, it.Err())
+	assert.Nil(t, it.Results())
+}
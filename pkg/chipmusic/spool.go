@@ -0,0 +1,452 @@
+package chipmusic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+)
+
+var (
+	// ErrSpoolClosed is an error returned when reading from a SpoolReader which has been closed
+	ErrSpoolClosed = errors.New("spool is closed")
+)
+
+// SpoolReader is a ReadSeekCloser for a track which is still being downloaded to a spool file. Reads block until the
+// requested bytes have been downloaded, so a decoder can start reading before the download is complete without the
+// whole track being held in memory. Closing the last reader of a spool file stops the download and removes the file
+type SpoolReader struct {
+	*spoolFile
+
+	// offset and closed belong to this reader, but are guarded by the lock of the spool file
+	offset int64
+	closed bool
+}
+
+// spoolFile is the spool file of a track along with the state of its download, which is shared by every SpoolReader of
+// the track
+type spoolFile struct {
+	file   *os.File
+	length int64
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mux    sync.Mutex
+	cond   *sync.Cond
+	chunks []*spoolChunk
+	err    error
+
+	// readers is the number of readers which weren't closed yet
+	readers int
+}
+
+// spoolChunk is a contiguous range of the spool file written by a single download request
+type spoolChunk struct {
+	start   int64
+	end     int64
+	written int64
+}
+
+func newSpoolReader(dir string, length int64, cancel context.CancelFunc) (*SpoolReader, error) {
+	file, err := ioutil.TempFile(dir, "chipmusic-*.spool")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create spool file: %w", err)
+	}
+
+	if err := file.Truncate(length); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, fmt.Errorf("failed to size spool file: %w", err)
+	}
+
+	spool := &spoolFile{
+		file:    file,
+		length:  length,
+		cancel:  cancel,
+		readers: 1,
+	}
+
+	spool.cond = sync.NewCond(&spool.mux)
+	return &SpoolReader{spoolFile: spool}, nil
+}
+
+// share returns another reader of the spool file which reads from the start. The spool file is only removed once every
+// reader is closed. Sharing a closed reader fails
+func (s *SpoolReader) share() (*SpoolReader, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if s.closed {
+		return nil, ErrSpoolClosed
+	}
+
+	s.readers++
+	return &SpoolReader{spoolFile: s.spoolFile}, nil
+}
+
+// Len returns the total length of the track in bytes
+func (s *SpoolReader) Len() int64 {
+	return s.length
+}
+
+// Read reads from the spool file, blocking until at least one byte at the current offset has been downloaded
+func (s *SpoolReader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	s.mux.Lock()
+	offset := s.offset
+	if offset >= s.length {
+		s.mux.Unlock()
+		return 0, io.EOF
+	}
+
+	available := s.available(offset)
+	for available <= 0 {
+		if s.closed {
+			s.mux.Unlock()
+			return 0, ErrSpoolClosed
+		}
+
+		if s.err != nil {
+			err := s.err
+			s.mux.Unlock()
+			return 0, err
+		}
+
+		s.cond.Wait()
+		available = s.available(offset)
+	}
+
+	s.mux.Unlock()
+
+	if int64(len(p)) > available {
+		p = p[:available]
+	}
+
+	n, err := s.file.ReadAt(p, offset)
+
+	s.mux.Lock()
+	s.offset = offset + int64(n)
+	s.mux.Unlock()
+
+	// Reaching the end of the file is reported on the next call to Read
+	if errors.Is(err, io.EOF) && n > 0 {
+		err = nil
+	}
+
+	return n, err
+}
+
+// available returns the number of contiguous downloaded bytes starting at offset. It must be called with the lock held
+func (s *spoolFile) available(offset int64) int64 {
+	for _, chunk := range s.chunks {
+		if offset >= chunk.start && offset < chunk.end {
+			return chunk.start + chunk.written - offset
+		}
+	}
+
+	return 0
+}
+
+// Seek sets the offset for the next Read. Seeking never blocks; a subsequent Read will block if the new offset has not
+// been downloaded yet
+func (s *SpoolReader) Seek(offset int64, whence int) (int64, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += s.offset
+	case io.SeekEnd:
+		offset += s.length
+	default:
+		return 0, fmt.Errorf("invalid whence %d", whence)
+	}
+
+	if offset < 0 {
+		return 0, errors.New("negative position")
+	}
+
+	s.offset = offset
+	return offset, nil
+}
+
+// Close closes the reader. Closing the last reader of the spool file stops the download and removes the file
+func (s *SpoolReader) Close() error {
+	s.mux.Lock()
+	if s.closed {
+		s.mux.Unlock()
+		return nil
+	}
+
+	s.closed = true
+	s.readers--
+	last := s.readers == 0
+	s.cond.Broadcast()
+	s.mux.Unlock()
+
+	if !last {
+		return nil
+	}
+
+	s.cancel()
+	s.wg.Wait()
+
+	if err := s.file.Close(); err != nil {
+		return fmt.Errorf("failed to close spool file: %w", err)
+	}
+
+	if err := os.Remove(s.file.Name()); err != nil {
+		return fmt.Errorf("failed to remove spool file: %w", err)
+	}
+
+	return nil
+}
+
+// Wait blocks until the download finishes, returning the error which stopped the download if any
+func (s *SpoolReader) Wait() error {
+	s.wg.Wait()
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.err
+}
+
+func (s *spoolFile) addChunk(start, end int64) *spoolChunk {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	chunk := &spoolChunk{start: start, end: end}
+	s.chunks = append(s.chunks, chunk)
+	return chunk
+}
+
+func (s *spoolFile) fail(err error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if s.err == nil {
+		s.err = err
+	}
+
+	s.cond.Broadcast()
+}
+
+// chunkWriter writes the body of a download request into its chunk of the spool file and wakes up blocked readers
+type chunkWriter struct {
+	spool *SpoolReader
+	chunk *spoolChunk
+}
+
+func (w *chunkWriter) Write(p []byte) (int, error) {
+	w.spool.mux.Lock()
+	offset := w.chunk.start + w.chunk.written
+	w.spool.mux.Unlock()
+
+	if remaining := w.chunk.end - offset; int64(len(p)) > remaining {
+		return 0, fmt.Errorf("%w: received more bytes than requested for range %d-%d", ErrIncompleteDownload, w.chunk.start, w.chunk.end-1)
+	}
+
+	n, err := w.spool.file.WriteAt(p, offset)
+
+	w.spool.mux.Lock()
+	w.chunk.written += int64(n)
+	w.spool.cond.Broadcast()
+	w.spool.mux.Unlock()
+
+	return n, err
+}
+
+// spoolTrack starts downloading a track into a spool file in the background and returns a reader for it immediately
+func (c *Client) spoolTrack(downloadMetadataResponse *http.Response, progress *progressTracker) (*SpoolReader, error) {
+	length, err := strconv.ParseInt(downloadMetadataResponse.Header.Get("Content-Length"), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Content-Length header: %w", err)
+	}
+
+	// The download must outlive the request which created it, so it is only cancelled when the reader is closed or the
+	// download deadline passes
+	ctx, cancel := c.withDownloadDeadline(context.Background())
+	spool, err := newSpoolReader(c.spoolDir, length, cancel)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	u := downloadMetadataResponse.Request.URL.String()
+
+	// The server does not accept Range requests, or mishandles them, so we'll gracefully degrade to a single download
+	// request for the whole file
+	if downloadMetadataResponse.Header.Get("Accept-Ranges") != "bytes" || length == 0 || c.singleStreamHosts.has(u) {
+		spool.addChunk(0, length)
+		spool.wg.Add(1)
+		go func() {
+			defer spool.wg.Done()
+			if err := c.downloadSpoolRest(ctx, spool, u, progress); err != nil {
+				spool.fail(c.deadlineError(context.Background(), ctx, err))
+			}
+		}()
+
+		return spool, nil
+	}
+
+	c.downloadSpoolChunks(ctx, spool, u, downloadValidator(downloadMetadataResponse), progress)
+	return spool, nil
+}
+
+// downloadSpoolChunks downloads chunks of a track into the spool concurrently with Range requests. A failed chunk is
+// retried up to the chunk retries of the client. The first chunk which keeps failing stops the other Range requests and
+// the rest of the track is downloaded with a single request for the whole file instead, as is every track from the
+// same host afterwards
+func (c *Client) downloadSpoolChunks(ctx context.Context, spool *SpoolReader, u string, validator string, progress *progressTracker) {
+	rangedCtx, cancelRanged := context.WithCancel(ctx)
+	var ranged sync.WaitGroup
+	var fallback sync.Once
+	for _, r := range splitRanges(spool.length, c.workers) {
+		chunk := spool.addChunk(r.start, r.end)
+		ranged.Add(1)
+		spool.wg.Add(1)
+		go func() {
+			defer spool.wg.Done()
+			err := c.downloadSpoolChunkWithRetries(rangedCtx, spool, u, chunk, validator, progress)
+			stopped := rangedCtx.Err() != nil && ctx.Err() == nil
+			ranged.Done()
+			if err == nil || stopped {
+				// Either the chunk is complete or another chunk fell back to a single request
+				return
+			}
+
+			if ctx.Err() != nil {
+				spool.fail(c.deadlineError(context.Background(), ctx, err))
+				return
+			}
+
+			fallback.Do(func() {
+				c.reportRetry(progress.url, c.chunkRetries+1, err)
+				c.singleStreamHosts.add(u)
+				cancelRanged()
+
+				// The single request skips the bytes written by Range requests, which must have stopped writing first
+				ranged.Wait()
+				if err := c.downloadSpoolRest(ctx, spool, u, progress); err != nil {
+					spool.fail(c.deadlineError(context.Background(), ctx, err))
+				}
+			})
+		}()
+	}
+
+	go func() {
+		ranged.Wait()
+		cancelRanged()
+	}()
+}
+
+// downloadSpoolChunkWithRetries downloads a chunk of a track into the spool with Range requests, retrying up to the
+// chunk retries of the client. Each retry resumes after the bytes written by earlier attempts
+func (c *Client) downloadSpoolChunkWithRetries(ctx context.Context, spool *SpoolReader, u string, chunk *spoolChunk, validator string, progress *progressTracker) error {
+	err := c.downloadSpoolChunk(ctx, spool, u, chunk, validator, progress)
+	for attempt := 0; err != nil && ctx.Err() == nil && attempt < c.chunkRetries; attempt++ {
+		c.reportRetry(progress.url, attempt+1, err)
+		err = c.downloadSpoolChunk(ctx, spool, u, chunk, validator, progress)
+	}
+
+	return err
+}
+
+// downloadSpoolChunk downloads the rest of a chunk of a track into the spool with a Range request, resuming after any
+// bytes written by an earlier attempt. The request only succeeds while the track still matches validator
+func (c *Client) downloadSpoolChunk(ctx context.Context, spool *SpoolReader, u string, chunk *spoolChunk, validator string, progress *progressTracker) error {
+	spool.mux.Lock()
+	r := byteRange{start: chunk.start + chunk.written, end: chunk.end}
+	spool.mux.Unlock()
+
+	request, err := newRangeRequest(ctx, u, r, validator)
+	if err != nil {
+		return fmt.Errorf("failed to create track download request: %w", err)
+	}
+
+	response, err := c.client.Do(request)
+	if err != nil {
+		return fmt.Errorf("failed to get response for track download: %w", err)
+	}
+
+	defer response.Body.Close()
+
+	if err := checkRangeResponse(response, r); err != nil {
+		return err
+	}
+
+	return writeSpoolChunk(spool, chunk, r, response.Body, progress)
+}
+
+// downloadSpoolRest downloads the rest of every chunk of a track into the spool with a single request for the whole
+// file. The bytes which were already written to the spool are skipped
+func (c *Client) downloadSpoolRest(ctx context.Context, spool *SpoolReader, u string, progress *progressTracker) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create track download request: %w", err)
+	}
+
+	response, err := c.client.Do(request)
+	if err != nil {
+		return fmt.Errorf("failed to get response for track download: %w", err)
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("expected status code %d for track download but got %d instead", http.StatusOK, response.StatusCode)
+	}
+
+	spool.mux.Lock()
+	chunks := append([]*spoolChunk(nil), spool.chunks...)
+	spool.mux.Unlock()
+
+	var position int64
+	for i, chunk := range chunks {
+		spool.mux.Lock()
+		r := byteRange{start: chunk.start + chunk.written, end: chunk.end}
+		spool.mux.Unlock()
+
+		if _, err := io.CopyN(ioutil.Discard, response.Body, r.start-position); err != nil {
+			return fmt.Errorf("failed to skip to %s of track download: %w", r, err)
+		}
+
+		// The bytes after the chunk belong to other chunks. The last chunk reads to the end of the file so a server
+		// which sends more than the file is detected
+		body := io.Reader(response.Body)
+		if i < len(chunks)-1 {
+			body = io.LimitReader(body, r.len())
+		}
+
+		if err := writeSpoolChunk(spool, chunk, r, body, progress); err != nil {
+			return err
+		}
+
+		position = r.end
+	}
+
+	return nil
+}
+
+// writeSpoolChunk writes body into the range r of a chunk of the spool, which must be the rest of the chunk
+func writeSpoolChunk(spool *SpoolReader, chunk *spoolChunk, r byteRange, body io.Reader, progress *progressTracker) error {
+	written, err := io.Copy(&chunkWriter{spool: spool, chunk: chunk}, progress.reader(body))
+	if err != nil {
+		return fmt.Errorf("failed to write track download to spool: %w", err)
+	}
+
+	if written != r.len() {
+		return fmt.Errorf("%w: expected %d bytes for %s but got %d instead", ErrIncompleteDownload, r.len(), r, written)
+	}
+
+	return nil
+}
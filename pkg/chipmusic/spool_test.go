@@ -0,0 +1,317 @@
+package chipmusic
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	testAudioPath    = "/music/some.track.mp3"
+	defaultTrackLink = "https://chipmusic.s3.amazonaws.com/music/2015/01/fearofdark_lovesickness-[2a03].mp3"
+)
+
+// newTrackServer returns a server which serves the track page fixture for every path except testAudioPath, which
+// serves audio. The download link on the track page points back to the server. If ranges is false, the server does
+// not advertise support for Range requests
+func newTrackServer(t *testing.T, audio []byte, ranges bool) *httptest.Server {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == testAudioPath {
+			if !ranges {
+				w.Header().Set("Content-Length", fmt.Sprint(len(audio)))
+				if r.Method == http.MethodGet {
+					_, err := w.Write(audio)
+					require.NoError(t, err, "failed to write audio as server response")
+				}
+
+				return
+			}
+
+			http.ServeContent(w, r, "some.track.mp3", time.Time{}, bytes.NewReader(audio))
+			return
+		}
+
+		raw, err := ioutil.ReadFile(defaultTrackPageFile)
+		require.NoError(t, err, "failed to read content of %s as server response", defaultTrackPageFile)
+
+		page := strings.Replace(string(raw), defaultTrackLink, server.URL+testAudioPath, 1)
+		_, err = w.Write([]byte(page))
+		require.NoError(t, err, "failed to write %s as server response", defaultTrackPageFile)
+	}))
+
+	return server
+}
+
+func randomAudio(t *testing.T, length int) []byte {
+	audio := make([]byte, length)
+	_, err := rand.New(rand.NewSource(int64(length))).Read(audio)
+	require.NoError(t, err, "failed to generate audio")
+	return audio
+}
+
+func TestWithSpoolDir(t *testing.T) {
+	file, err := ioutil.TempFile("", "chipmusic-spool")
+	require.NoError(t, err)
+
+	defer os.Remove(file.Name())
+	file.Close()
+
+	testCases := []struct {
+		name string
+		dir  string
+	}{
+		{"EmptyDir", ""},
+		{"MissingDir", "/some/missing/dir"},
+		{"NotADir", file.Name()},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			client, err := NewClient(WithSpoolDir(testCase.dir))
+			assert.Error(tt, err)
+			assert.Nil(tt, client)
+		})
+	}
+}
+
+func TestGetTrack_Spooled(t *testing.T) {
+	testCases := []struct {
+		name    string
+		length  int
+		workers int
+		ranges  bool
+	}{
+		{"SingleRequest", 10000, DefaultWorkers, false},
+		{"OneWorker", 10000, 1, true},
+		{"DefaultWorkers", 10000, DefaultWorkers, true},
+		{"UnevenChunks", 9999, 7, true},
+		{"MoreWorkersThanBytes", 3, DefaultWorkers, true},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			dir, err := ioutil.TempDir("", "chipmusic-spool")
+			require.NoError(tt, err)
+
+			defer os.RemoveAll(dir)
+
+			audio := randomAudio(tt, testCase.length)
+			server := newTrackServer(tt, audio, testCase.ranges)
+			defer server.Close()
+
+			client, err := NewClient(WithBaseURL(server.URL), WithHTTPClient(server.Client()), WithWorkers(testCase.workers), WithSpoolDir(dir))
+			require.NoError(tt, err, "failed to create client")
+
+			track, err := client.GetTrack(context.Background(), fmt.Sprintf("%s/some.artist/music/some.music", server.URL))
+			require.NoError(tt, err)
+
+			content, err := ioutil.ReadAll(track.Reader)
+			require.NoError(tt, err)
+			assert.Equal(tt, audio, content)
+
+			// Seeking backwards re-reads the spool file
+			_, err = track.Reader.Seek(1, io.SeekStart)
+			require.NoError(tt, err)
+
+			content, err = ioutil.ReadAll(track.Reader)
+			require.NoError(tt, err)
+			assert.Equal(tt, audio[1:], content)
+
+			require.NoError(tt, track.Close())
+
+			files, err := ioutil.ReadDir(dir)
+			require.NoError(tt, err)
+			assert.Empty(tt, files, "spool file should be removed when the track is closed")
+		})
+	}
+}
+
+func TestGetTrack_SpooledUnknownLength(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chipmusic-spool")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	audio := randomAudio(t, 10000)
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != testAudioPath {
+			raw, err := ioutil.ReadFile(defaultTrackPageFile)
+			require.NoError(t, err, "failed to read content of %s as server response", defaultTrackPageFile)
+
+			page := strings.Replace(string(raw), defaultTrackLink, server.URL+testAudioPath, 1)
+			_, err = w.Write([]byte(page))
+			require.NoError(t, err, "failed to write %s as server response", defaultTrackPageFile)
+			return
+		}
+
+		// Flushing before writing the audio sends it chunked, without a Content-Length header
+		w.Header().Set("Accept-Ranges", "bytes")
+		w.(http.Flusher).Flush()
+		if r.Method == http.MethodGet {
+			_, err := w.Write(audio)
+			require.NoError(t, err, "failed to write audio as server response")
+		}
+	}))
+
+	defer server.Close()
+
+	client, err := NewClient(WithBaseURL(server.URL), WithHTTPClient(server.Client()), WithSpoolDir(dir))
+	require.NoError(t, err, "failed to create client")
+
+	track, err := client.GetTrack(context.Background(), fmt.Sprintf("%s/some.artist/music/some.music", server.URL))
+	require.NoError(t, err)
+
+	defer track.Close()
+
+	content, err := ioutil.ReadAll(track.Reader)
+	require.NoError(t, err)
+	assert.Equal(t, audio, content)
+}
+
+func TestSpoolReader_ReadBlocksUntilDownloaded(t *testing.T) {
+	audio := randomAudio(t, 1000)
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", fmt.Sprint(len(audio)))
+		if r.Method == http.MethodHead {
+			return
+		}
+
+		<-release
+		_, err := w.Write(audio)
+		require.NoError(t, err, "failed to write audio as server response")
+	}))
+
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "chipmusic-spool")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	client, err := NewClient(WithHTTPClient(server.Client()), WithSpoolDir(dir))
+	require.NoError(t, err, "failed to create client")
+
+	response, err := server.Client().Head(server.URL)
+	require.NoError(t, err)
+	response.Body.Close()
+
+	spool, err := client.spoolTrack(response, newProgressTracker(nil, "", 0))
+	require.NoError(t, err)
+
+	defer spool.Close()
+
+	read := make(chan []byte)
+	go func() {
+		content, err := ioutil.ReadAll(spool)
+		require.NoError(t, err)
+		read <- content
+	}()
+
+	select {
+	case <-read:
+		t.Fatal("read should block until the download starts")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	assert.Equal(t, audio, <-read)
+	assert.NoError(t, spool.Wait())
+}
+
+func TestSpoolReader_DownloadError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "1000")
+		if r.Method == http.MethodHead {
+			return
+		}
+
+		http.Error(w, "an error occurred", http.StatusInternalServerError)
+	}))
+
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "chipmusic-spool")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	client, err := NewClient(WithHTTPClient(server.Client()), WithSpoolDir(dir))
+	require.NoError(t, err, "failed to create client")
+
+	response, err := server.Client().Head(server.URL)
+	require.NoError(t, err)
+	response.Body.Close()
+
+	spool, err := client.spoolTrack(response, newProgressTracker(nil, "", 0))
+	require.NoError(t, err)
+
+	defer spool.Close()
+
+	_, err = ioutil.ReadAll(spool)
+	assert.Error(t, err)
+	assert.Error(t, spool.Wait())
+}
+
+func TestSpoolReader_Close(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chipmusic-spool")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	spool, err := newSpoolReader(dir, 10, func() {})
+	require.NoError(t, err)
+
+	spool.addChunk(0, 10)
+	require.NoError(t, spool.Close())
+	require.NoError(t, spool.Close())
+
+	_, err = spool.Read(make([]byte, 10))
+	assert.Equal(t, ErrSpoolClosed, err)
+}
+
+func TestSpoolReader_Seek(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chipmusic-spool")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	spool, err := newSpoolReader(dir, 10, func() {})
+	require.NoError(t, err)
+
+	defer spool.Close()
+
+	testCases := []struct {
+		name     string
+		offset   int64
+		whence   int
+		expected int64
+	}{
+		{"Start", 2, io.SeekStart, 2},
+		{"Current", 2, io.SeekCurrent, 4},
+		{"End", -1, io.SeekEnd, 9},
+	}
+
+	for _, testCase := range testCases {
+		position, err := spool.Seek(testCase.offset, testCase.whence)
+		require.NoError(t, err, testCase.name)
+		assert.Equal(t, testCase.expected, position, testCase.name)
+	}
+
+	_, err = spool.Seek(-1, io.SeekStart)
+	assert.Error(t, err)
+	assert.Equal(t, int64(10), spool.Len())
+}
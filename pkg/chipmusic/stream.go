@@ -0,0 +1,153 @@
+package chipmusic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+const (
+	// DefaultStreamWindow is the default number of bytes fetched by each ranged request when streaming a track
+	DefaultStreamWindow = 256 * 1024
+)
+
+// RangeReader is a ReadSeekCloser for a track which is read directly from the server with ranged HTTP requests. Only a
+// single window of the track is held in memory at a time, so memory stays bounded no matter how long the track is.
+// Note that some decoders, such as the MP3 decoder, scan the whole file before playback starts, which reads the track
+// from start to end once
+type RangeReader struct {
+	client *http.Client
+	url    string
+	length int64
+	window int64
+	offset int64
+
+	buffer      []byte
+	bufferStart int64
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func newRangeReader(client *http.Client, url string, length, window int64) *RangeReader {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &RangeReader{
+		client: client,
+		url:    url,
+		length: length,
+		window: window,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// Len returns the total length of the track in bytes
+func (r *RangeReader) Len() int64 {
+	return r.length
+}
+
+// Read reads from the current window, fetching a new window from the server if the offset is outside of it
+func (r *RangeReader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	if r.offset >= r.length {
+		return 0, io.EOF
+	}
+
+	if r.offset < r.bufferStart || r.offset >= r.bufferStart+int64(len(r.buffer)) {
+		if err := r.fetch(r.offset); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, r.buffer[r.offset-r.bufferStart:])
+	r.offset += int64(n)
+	return n, nil
+}
+
+func (r *RangeReader) fetch(start int64) error {
+	end := start + r.window
+	if end > r.length {
+		end = r.length
+	}
+
+	request, err := http.NewRequestWithContext(r.ctx, http.MethodGet, r.url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create track stream request: %w", err)
+	}
+
+	request.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", start, end-1))
+
+	response, err := r.client.Do(request)
+	if err != nil {
+		return fmt.Errorf("failed to get response for track stream: %w", err)
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusPartialContent {
+		return fmt.Errorf("expected status code %d for track stream but got %d instead", http.StatusPartialContent, response.StatusCode)
+	}
+
+	size := int(end - start)
+	if cap(r.buffer) < size {
+		r.buffer = make([]byte, size)
+	}
+
+	r.buffer = r.buffer[:size]
+	if _, err := io.ReadFull(response.Body, r.buffer); err != nil {
+		r.buffer = r.buffer[:0]
+		return fmt.Errorf("failed to read response for track stream: %w", err)
+	}
+
+	r.bufferStart = start
+	return nil
+}
+
+// Seek sets the offset for the next Read. Seeking never makes a request by itself
+func (r *RangeReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.offset
+	case io.SeekEnd:
+		offset += r.length
+	default:
+		return 0, fmt.Errorf("invalid whence %d", whence)
+	}
+
+	if offset < 0 {
+		return 0, errors.New("negative position")
+	}
+
+	r.offset = offset
+	return offset, nil
+}
+
+// Close aborts any request in progress and releases the window
+func (r *RangeReader) Close() error {
+	r.cancel()
+	r.buffer = nil
+	return nil
+}
+
+// streamTrack returns a RangeReader for the track described by the download metadata response. If the server does not
+// accept Range requests, nil is returned
+func (c *Client) streamTrack(downloadMetadataResponse *http.Response) (*RangeReader, error) {
+	if downloadMetadataResponse.Header.Get("Accept-Ranges") != "bytes" {
+		return nil, nil
+	}
+
+	length, err := strconv.ParseInt(downloadMetadataResponse.Header.Get("Content-Length"), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Content-Length header: %w", err)
+	}
+
+	u := downloadMetadataResponse.Request.URL.String()
+	return newRangeReader(c.client, u, length, c.streamWindow), nil
+}
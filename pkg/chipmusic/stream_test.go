@@ -0,0 +1,142 @@
+package chipmusic
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWithStreaming(t *testing.T) {
+	testCases := []struct {
+		name   string
+		window int64
+	}{
+		{"NegativeWindow", -1},
+		{"ZeroWindow", 0},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			client, err := NewClient(WithStreaming(testCase.window))
+			assert.Error(tt, err)
+			assert.Nil(tt, client)
+		})
+	}
+}
+
+func TestGetTrack_Streamed(t *testing.T) {
+	testCases := []struct {
+		name   string
+		length int
+		window int64
+	}{
+		{"SingleWindow", 1000, DefaultStreamWindow},
+		{"ExactWindows", 1000, 100},
+		{"UnevenWindows", 1000, 333},
+		{"OneByteWindows", 10, 1},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			audio := randomAudio(tt, testCase.length)
+			server := newTrackServer(tt, audio, true)
+			defer server.Close()
+
+			client, err := NewClient(WithBaseURL(server.URL), WithHTTPClient(server.Client()), WithStreaming(testCase.window))
+			require.NoError(tt, err, "failed to create client")
+
+			track, err := client.GetTrack(context.Background(), fmt.Sprintf("%s/some.artist/music/some.music", server.URL))
+			require.NoError(tt, err)
+
+			defer track.Close()
+
+			stream, ok := track.Reader.(*RangeReader)
+			require.True(tt, ok, "track should be streamed")
+			assert.Equal(tt, int64(testCase.length), stream.Len())
+
+			content, err := ioutil.ReadAll(track.Reader)
+			require.NoError(tt, err)
+			assert.Equal(tt, audio, content)
+			assert.True(tt, int64(cap(stream.buffer)) <= testCase.window, "only one window should be held in memory")
+
+			position, err := track.Reader.Seek(-5, io.SeekEnd)
+			require.NoError(tt, err)
+
+			content, err = ioutil.ReadAll(track.Reader)
+			require.NoError(tt, err)
+			assert.Equal(tt, audio[position:], content)
+		})
+	}
+}
+
+func TestGetTrack_StreamedWithoutRanges(t *testing.T) {
+	audio := randomAudio(t, 1000)
+	server := newTrackServer(t, audio, false)
+	defer server.Close()
+
+	client, err := NewClient(WithBaseURL(server.URL), WithHTTPClient(server.Client()), WithStreaming(DefaultStreamWindow))
+	require.NoError(t, err, "failed to create client")
+
+	track, err := client.GetTrack(context.Background(), fmt.Sprintf("%s/some.artist/music/some.music", server.URL))
+	require.NoError(t, err)
+
+	defer track.Close()
+
+	_, ok := track.Reader.(*RangeReader)
+	assert.False(t, ok, "track should be downloaded when the server does not accept ranges")
+
+	content, err := ioutil.ReadAll(track.Reader)
+	require.NoError(t, err)
+	assert.Equal(t, audio, content)
+}
+
+func TestRangeReader_NotPartialContent(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "an error occurred", http.StatusInternalServerError)
+	}))
+
+	defer server.Close()
+
+	reader := newRangeReader(server.Client(), server.URL, 10, DefaultStreamWindow)
+	defer reader.Close()
+
+	_, err := reader.Read(make([]byte, 10))
+	assert.Error(t, err)
+}
+
+func TestRangeReader_Seek(t *testing.T) {
+	reader := newRangeReader(http.DefaultClient, "some.url", 10, DefaultStreamWindow)
+	defer reader.Close()
+
+	testCases := []struct {
+		name     string
+		offset   int64
+		whence   int
+		expected int64
+	}{
+		{"Start", 2, io.SeekStart, 2},
+		{"Current", 2, io.SeekCurrent, 4},
+		{"End", -1, io.SeekEnd, 9},
+	}
+
+	for _, testCase := range testCases {
+		position, err := reader.Seek(testCase.offset, testCase.whence)
+		require.NoError(t, err, testCase.name)
+		assert.Equal(t, testCase.expected, position, testCase.name)
+	}
+
+	_, err := reader.Seek(-1, io.SeekStart)
+	assert.Error(t, err)
+
+	_, err = reader.Seek(0, io.SeekEnd)
+	require.NoError(t, err)
+
+	_, err = reader.Read(make([]byte, 1))
+	assert.Equal(t, io.EOF, err)
+}
@@ -0,0 +1,443 @@
+package tags
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/broar/chipmusic-cli/pkg/chipmusic"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf16"
+)
+
+const (
+	// FrameTitle is the ID of the ID3v2 frame holding the title of a track
+	FrameTitle = "TIT2"
+
+	// FrameArtist is the ID of the ID3v2 frame holding the artist of a track
+	FrameArtist = "TPE1"
+
+	// FrameGenre is the ID of the ID3v2 frame holding the genre of a track
+	FrameGenre = "TCON"
+
+	// FrameSource is the ID of the ID3v2 frame holding the URL of the page the audio came from
+	FrameSource = "WOAS"
+
+	// FrameComment is the ID of the ID3v2 frame holding a comment about a track
+	FrameComment = "COMM"
+
+	// commentLanguage is the language of written comments. Comments are URLs, so English is as good as any
+	commentLanguage = "eng"
+
+	headerSize = 10
+
+	encodingISO88591 = 0
+	encodingUTF16    = 1
+	encodingUTF16BE  = 2
+	encodingUTF8     = 3
+
+	flagUnsynchronisation = 0x80
+	flagExtendedHeader    = 0x40
+	flagFooter            = 0x10
+)
+
+var (
+	// ErrInvalidTag is an error returned when an ID3v2 tag is malformed
+	ErrInvalidTag = errors.New("invalid ID3v2 tag")
+)
+
+// Tags are the ID3v2 tags of an audio file. Only the fields used by chipmusic.org are exposed; every other frame is
+// kept as is when the tags are written back
+type Tags struct {
+
+	// Title is the name of the track
+	Title string
+
+	// Artist is the name of the author who composed the track
+	Artist string
+
+	// Genre is the genre of the track. chipmusic.org does not have genres, so the tags of the track are used instead
+	Genre string
+
+	// Source is the URL of the track page on chipmusic.org the audio came from
+	Source string
+
+	// Comment is the comment without a description. Players which don't show Source show this instead, so it holds the
+	// URL of the track page too
+	Comment string
+
+	// frames are the other frames of the tag
+	frames []frame
+}
+
+type frame struct {
+	id   string
+	body []byte
+}
+
+// FromTrack returns the tags for the audio of a track
+func FromTrack(track *chipmusic.Track) *Tags {
+	return &Tags{
+		Title:   track.Title,
+		Artist:  track.Artist,
+		Genre:   strings.Join(track.Tags, ", "),
+		Source:  track.PageURL,
+		Comment: track.PageURL,
+	}
+}
+
+// Merge returns a copy of t with every field which is set in update replaced. Other frames of t are kept
+func (t *Tags) Merge(update *Tags) *Tags {
+	merged := *t
+	merged.frames = append([]frame{}, t.frames...)
+	for _, field := range []struct {
+		value  string
+		target *string
+	}{
+		{update.Title, &merged.Title},
+		{update.Artist, &merged.Artist},
+		{update.Genre, &merged.Genre},
+		{update.Source, &merged.Source},
+		{update.Comment, &merged.Comment},
+	} {
+		if field.value != "" {
+			*field.target = field.value
+		}
+	}
+
+	return &merged
+}
+
+// Change is a field which differs between two sets of tags
+type Change struct {
+	Field string
+	Old   string
+	New   string
+}
+
+// Diff returns the fields which differ between old and new
+func Diff(old, new *Tags) []Change {
+	changes := make([]Change, 0)
+	for _, field := range []struct {
+		name     string
+		old, new string
+	}{
+		{"title", old.Title, new.Title},
+		{"artist", old.Artist, new.Artist},
+		{"genre", old.Genre, new.Genre},
+		{"source", old.Source, new.Source},
+		{"comment", old.Comment, new.Comment},
+	} {
+		if field.old != field.new {
+			changes = append(changes, Change{Field: field.name, Old: field.old, New: field.new})
+		}
+	}
+
+	return changes
+}
+
+// Read reads the ID3v2 tag at the start of r. If there is no tag, empty Tags are returned. The size of the tag in
+// bytes, including its header, is returned so the audio which follows can be found
+func Read(r io.Reader) (*Tags, int64, error) {
+	header := make([]byte, headerSize)
+	if _, err := io.ReadFull(r, header); errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return &Tags{}, 0, nil
+	} else if err != nil {
+		return nil, 0, fmt.Errorf("failed to read tag header: %w", err)
+	}
+
+	if string(header[:3]) != "ID3" {
+		return &Tags{}, 0, nil
+	}
+
+	version, flags := header[3], header[5]
+	if version < 3 || version > 4 {
+		return nil, 0, fmt.Errorf("%w: unsupported version 2.%d", ErrInvalidTag, version)
+	}
+
+	size := int64(synchsafe(header[6:10]))
+	body := make([]byte, size)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, 0, fmt.Errorf("%w: failed to read tag body: %v", ErrInvalidTag, err)
+	}
+
+	total := headerSize + size
+	if flags&flagFooter != 0 {
+		total += headerSize
+	}
+
+	if flags&flagUnsynchronisation != 0 && version == 3 {
+		body = bytes.ReplaceAll(body, []byte{0xFF, 0x00}, []byte{0xFF})
+	}
+
+	if flags&flagExtendedHeader != 0 {
+		if len(body) < 4 {
+			return nil, 0, fmt.Errorf("%w: truncated extended header", ErrInvalidTag)
+		}
+
+		extended := int(binary.BigEndian.Uint32(body[:4])) + 4
+		if version == 4 {
+			extended = synchsafe(body[:4])
+		}
+
+		if extended > len(body) {
+			return nil, 0, fmt.Errorf("%w: truncated extended header", ErrInvalidTag)
+		}
+
+		body = body[extended:]
+	}
+
+	tags := &Tags{}
+	for len(body) >= headerSize && body[0] != 0 {
+		id := string(body[:4])
+		frameSize := int(binary.BigEndian.Uint32(body[4:8]))
+		if version == 4 {
+			frameSize = synchsafe(body[4:8])
+		}
+
+		if headerSize+frameSize > len(body) {
+			return nil, 0, fmt.Errorf("%w: frame %s is truncated", ErrInvalidTag, id)
+		}
+
+		// Frames which are compressed or encrypted can't be kept since their flags are not written back
+		frameFlags := body[9]
+		content := body[headerSize : headerSize+frameSize]
+		body = body[headerSize+frameSize:]
+		if frameFlags&0x0F != 0 && version == 4 || frameFlags&0xC0 != 0 && version == 3 {
+			continue
+		}
+
+		var err error
+		switch id {
+		case FrameTitle:
+			tags.Title, err = decodeText(content)
+		case FrameArtist:
+			tags.Artist, err = decodeText(content)
+		case FrameGenre:
+			tags.Genre, err = decodeText(content)
+		case FrameSource:
+			tags.Source = strings.TrimRight(string(content), "\x00")
+		case FrameComment:
+			var description, text string
+			description, text, err = decodeComment(content)
+
+			// Only the comment without a description is exposed. Comments with one are kept as other frames
+			if err == nil && description == "" && tags.Comment == "" {
+				tags.Comment = text
+			} else if err == nil {
+				tags.frames = append(tags.frames, frame{id: id, body: content})
+			}
+		default:
+			tags.frames = append(tags.frames, frame{id: id, body: content})
+		}
+
+		if err != nil {
+			return nil, 0, fmt.Errorf("%w: failed to decode frame %s: %v", ErrInvalidTag, id, err)
+		}
+	}
+
+	return tags, total, nil
+}
+
+// ReadFile reads the ID3v2 tag of the file at path
+func ReadFile(path string) (*Tags, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+
+	defer f.Close()
+
+	tags, _, err := Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tags of %s: %w", path, err)
+	}
+
+	return tags, nil
+}
+
+// Encode returns the tags as an ID3v2.4 tag. Text is encoded as UTF-8
+func (t *Tags) Encode() []byte {
+	body := &bytes.Buffer{}
+	for _, text := range []struct {
+		id    string
+		value string
+	}{
+		{FrameTitle, t.Title},
+		{FrameArtist, t.Artist},
+		{FrameGenre, t.Genre},
+	} {
+		if text.value != "" {
+			writeFrame(body, text.id, append([]byte{encodingUTF8}, text.value...))
+		}
+	}
+
+	if t.Source != "" {
+		writeFrame(body, FrameSource, []byte(t.Source))
+	}
+
+	if t.Comment != "" {
+		// The empty description is terminated by a single null byte in UTF-8
+		comment := append([]byte{encodingUTF8}, commentLanguage...)
+		writeFrame(body, FrameComment, append(append(comment, 0), t.Comment...))
+	}
+
+	for _, f := range t.frames {
+		writeFrame(body, f.id, f.body)
+	}
+
+	tag := &bytes.Buffer{}
+	tag.WriteString("ID3")
+	tag.Write([]byte{4, 0, 0})
+	tag.Write(encodeSynchsafe(body.Len()))
+	tag.Write(body.Bytes())
+	return tag.Bytes()
+}
+
+func writeFrame(w *bytes.Buffer, id string, content []byte) {
+	w.WriteString(id)
+	w.Write(encodeSynchsafe(len(content)))
+	w.Write([]byte{0, 0})
+	w.Write(content)
+}
+
+// WriteFile replaces the ID3v2 tag of the file at path with t, keeping the audio which follows it. The file is
+// replaced atomically so an interrupted write can't corrupt it
+func WriteFile(path string, t *Tags) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+
+	defer f.Close()
+
+	_, size, err := Read(f)
+	if err != nil {
+		return fmt.Errorf("failed to read tags of %s: %w", path, err)
+	}
+
+	if _, err := f.Seek(size, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek past tags of %s: %w", path, err)
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".retag-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file for %s: %w", path, err)
+	}
+
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(t.Encode()); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write tags of %s: %w", path, err)
+	}
+
+	if _, err := io.Copy(tmp, f); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to copy audio of %s: %w", path, err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temporary file for %s: %w", path, err)
+	}
+
+	if info, err := f.Stat(); err == nil {
+		os.Chmod(tmp.Name(), info.Mode())
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+
+	return nil
+}
+
+func decodeText(content []byte) (string, error) {
+	if len(content) == 0 {
+		return "", nil
+	}
+
+	encoding, text := content[0], content[1:]
+	switch encoding {
+	case encodingISO88591:
+		runes := make([]rune, 0, len(text))
+		for _, b := range text {
+			runes = append(runes, rune(b))
+		}
+
+		return strings.TrimRight(string(runes), "\x00"), nil
+	case encodingUTF16, encodingUTF16BE:
+		var order binary.ByteOrder = binary.BigEndian
+		if encoding == encodingUTF16 && len(text) >= 2 {
+			if text[0] == 0xFF && text[1] == 0xFE {
+				order = binary.LittleEndian
+			}
+
+			text = text[2:]
+		}
+
+		units := make([]uint16, 0, len(text)/2)
+		for i := 0; i+1 < len(text); i += 2 {
+			units = append(units, order.Uint16(text[i:]))
+		}
+
+		return strings.TrimRight(string(utf16.Decode(units)), "\x00"), nil
+	case encodingUTF8:
+		return strings.TrimRight(string(text), "\x00"), nil
+	default:
+		return "", fmt.Errorf("unknown text encoding %d", encoding)
+	}
+}
+
+// decodeComment decodes the body of a COMM frame, which holds a language, a description, and the text of the comment
+func decodeComment(content []byte) (string, string, error) {
+	if len(content) < 4 {
+		return "", "", errors.New("comment is truncated")
+	}
+
+	encoding, text := content[0], content[4:]
+
+	// The description is terminated by a null character, which is two bytes wide in UTF-16
+	terminator := []byte{0}
+	if encoding == encodingUTF16 || encoding == encodingUTF16BE {
+		terminator = []byte{0, 0}
+	}
+
+	end := -1
+	for i := 0; i+len(terminator) <= len(text); i += len(terminator) {
+		if bytes.Equal(text[i:i+len(terminator)], terminator) {
+			end = i
+			break
+		}
+	}
+
+	if end < 0 {
+		return "", "", errors.New("comment description is not terminated")
+	}
+
+	description, err := decodeText(append([]byte{encoding}, text[:end]...))
+	if err != nil {
+		return "", "", err
+	}
+
+	// Each string of the frame has its own byte order mark in UTF-16
+	body := text[end+len(terminator):]
+	comment, err := decodeText(append([]byte{encoding}, body...))
+	if err != nil {
+		return "", "", err
+	}
+
+	return description, comment, nil
+}
+
+func synchsafe(b []byte) int {
+	return int(b[0])<<21 | int(b[1])<<14 | int(b[2])<<7 | int(b[3])
+}
+
+func encodeSynchsafe(n int) []byte {
+	return []byte{byte(n >> 21 & 0x7F), byte(n >> 14 & 0x7F), byte(n >> 7 & 0x7F), byte(n & 0x7F)}
+}
@@ -0,0 +1,216 @@
+package tags
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/broar/chipmusic-cli/pkg/chipmusic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// v23Tag builds an ID3v2.3 tag, which uses plain frame sizes unlike ID3v2.4
+func v23Tag(frames map[string][]byte, order []string) []byte {
+	body := &bytes.Buffer{}
+	for _, id := range order {
+		size := make([]byte, 4)
+		binary.BigEndian.PutUint32(size, uint32(len(frames[id])))
+		body.WriteString(id)
+		body.Write(size)
+		body.Write([]byte{0, 0})
+		body.Write(frames[id])
+	}
+
+	// Padding is allowed after the last frame
+	body.Write(make([]byte, 16))
+
+	tag := &bytes.Buffer{}
+	tag.WriteString("ID3")
+	tag.Write([]byte{3, 0, 0})
+	tag.Write(encodeSynchsafe(body.Len()))
+	tag.Write(body.Bytes())
+	return tag.Bytes()
+}
+
+func TestRead(t *testing.T) {
+	utf16Title := []byte{encodingUTF16, 0xFF, 0xFE, 'V', 0, 'i', 0, 'r', 0, 't', 0, 'u', 0, 'e', 0, 's', 0, 0, 0}
+	raw := v23Tag(map[string][]byte{
+		FrameTitle:   utf16Title,
+		FrameArtist:  append([]byte{encodingISO88591}, "H\xe9de"...),
+		FrameGenre:   append([]byte{encodingUTF8}, "lsdj"...),
+		FrameSource:  []byte("https://chipmusic.org/track"),
+		FrameComment: append([]byte{encodingUTF8}, "eng\x00https://chipmusic.org/track"...),
+		"APIC":       {0, 'i', 'm', 'g'},
+	}, []string{FrameTitle, FrameArtist, FrameGenre, FrameSource, FrameComment, "APIC"})
+
+	audio := []byte("audio")
+	tags, size, err := Read(bytes.NewReader(append(raw, audio...)))
+	require.NoError(t, err, "failed to read tags")
+
+	assert.Equal(t, int64(len(raw)), size)
+	assert.Equal(t, "Virtues", tags.Title)
+	assert.Equal(t, "Héde", tags.Artist)
+	assert.Equal(t, "lsdj", tags.Genre)
+	assert.Equal(t, "https://chipmusic.org/track", tags.Source)
+	assert.Equal(t, "https://chipmusic.org/track", tags.Comment)
+	assert.Equal(t, []frame{{id: "APIC", body: []byte{0, 'i', 'm', 'g'}}}, tags.frames)
+}
+
+func TestRead_Comment(t *testing.T) {
+	testCases := []struct {
+		name          string
+		body          []byte
+		expectComment string
+		expectFrames  []frame
+	}{
+		{
+			name:          "UTF16",
+			body:          []byte{encodingUTF16, 'e', 'n', 'g', 0xFF, 0xFE, 0, 0, 0xFF, 0xFE, 'h', 0, 'i', 0},
+			expectComment: "hi",
+		},
+		{
+			name:         "Description",
+			body:         append([]byte{encodingUTF8}, "engiTunNORM\x00 0000"...),
+			expectFrames: []frame{{id: FrameComment, body: append([]byte{encodingUTF8}, "engiTunNORM\x00 0000"...)}},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			raw := v23Tag(map[string][]byte{FrameComment: testCase.body}, []string{FrameComment})
+
+			tags, _, err := Read(bytes.NewReader(raw))
+			require.NoError(tt, err, "failed to read tags")
+
+			assert.Equal(tt, testCase.expectComment, tags.Comment)
+			assert.Equal(tt, testCase.expectFrames, tags.frames)
+		})
+	}
+}
+
+func TestRead_NoTag(t *testing.T) {
+	for name, raw := range map[string][]byte{
+		"NoTag": []byte("\xff\xfb\x90\x00 audio frames"),
+		"Empty": {},
+	} {
+		t.Run(name, func(tt *testing.T) {
+			tags, size, err := Read(bytes.NewReader(raw))
+			require.NoError(tt, err, "failed to read tags")
+
+			assert.Equal(tt, &Tags{}, tags)
+			assert.Equal(tt, int64(0), size)
+		})
+	}
+}
+
+func TestRead_InvalidTag(t *testing.T) {
+	tests := map[string][]byte{
+		"UnsupportedVersion": append([]byte("ID3\x02\x00\x00"), encodeSynchsafe(0)...),
+		"TruncatedBody":      append([]byte("ID3\x04\x00\x00"), encodeSynchsafe(100)...),
+		"TruncatedFrame":     append(append([]byte("ID3\x04\x00\x00"), encodeSynchsafe(14)...), "TIT2\x00\x00\x00\x7f\x00\x00\x03abc"...),
+	}
+
+	for name, raw := range tests {
+		t.Run(name, func(tt *testing.T) {
+			_, _, err := Read(bytes.NewReader(raw))
+			assert.True(tt, err != nil, "expected an error")
+		})
+	}
+}
+
+func TestEncode(t *testing.T) {
+	tags := &Tags{
+		Title:   "Virtues",
+		Artist:  "Hide Your Tigers",
+		Genre:   "lsdj, game boy",
+		Source:  "https://chipmusic.org/Hide+Your+Tigers/music/virtues-lsdj",
+		Comment: "https://chipmusic.org/Hide+Your+Tigers/music/virtues-lsdj",
+		frames:  []frame{{id: "APIC", body: []byte{0, 'i', 'm', 'g'}}},
+	}
+
+	raw := tags.Encode()
+	assert.Equal(t, []byte("ID3\x04\x00\x00"), raw[:6])
+
+	decoded, size, err := Read(bytes.NewReader(raw))
+	require.NoError(t, err, "failed to read encoded tags")
+
+	assert.Equal(t, int64(len(raw)), size)
+	assert.Equal(t, tags, decoded)
+}
+
+func TestWriteFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tags")
+	require.NoError(t, err, "failed to create temporary directory")
+	defer os.RemoveAll(dir)
+
+	audio := []byte("\xff\xfb\x90\x00 audio frames")
+	tests := map[string][]byte{
+		"ExistingTag": append(v23Tag(map[string][]byte{FrameTitle: append([]byte{encodingUTF8}, "Old"...)}, []string{FrameTitle}), audio...),
+		"NoTag":       audio,
+	}
+
+	for name, raw := range tests {
+		t.Run(name, func(tt *testing.T) {
+			path := filepath.Join(dir, name+".mp3")
+			require.NoError(tt, ioutil.WriteFile(path, raw, 0644), "failed to write track")
+
+			require.NoError(tt, WriteFile(path, &Tags{Title: "New", Artist: "Fearofdark"}), "failed to write tags")
+
+			content, err := ioutil.ReadFile(path)
+			require.NoError(tt, err, "failed to read track")
+
+			tags, size, err := Read(bytes.NewReader(content))
+			require.NoError(tt, err, "failed to read tags")
+
+			assert.Equal(tt, "New", tags.Title)
+			assert.Equal(tt, "Fearofdark", tags.Artist)
+			assert.Equal(tt, audio, content[size:])
+		})
+	}
+}
+
+func TestMerge(t *testing.T) {
+	current := &Tags{Title: "Old", Artist: "Fearofdark", Genre: "lsdj", frames: []frame{{id: "APIC"}}}
+	merged := current.Merge(&Tags{Title: "New", Source: "https://chipmusic.org/track"})
+
+	assert.Equal(t, &Tags{
+		Title:  "New",
+		Artist: "Fearofdark",
+		Genre:  "lsdj",
+		Source: "https://chipmusic.org/track",
+		frames: []frame{{id: "APIC"}},
+	}, merged)
+	assert.Equal(t, "Old", current.Title, "expected the original tags to be left unchanged")
+}
+
+func TestDiff(t *testing.T) {
+	old := &Tags{Title: "Old", Artist: "Fearofdark", Genre: "lsdj"}
+	new := &Tags{Title: "New", Artist: "Fearofdark", Genre: "lsdj, nanoloop", Comment: "https://chipmusic.org/track"}
+
+	assert.Equal(t, []Change{
+		{Field: "title", Old: "Old", New: "New"},
+		{Field: "genre", Old: "lsdj", New: "lsdj, nanoloop"},
+		{Field: "comment", Old: "", New: "https://chipmusic.org/track"},
+	}, Diff(old, new))
+	assert.Empty(t, Diff(old, old))
+}
+
+func TestFromTrack(t *testing.T) {
+	track := &chipmusic.Track{
+		Title:   "Virtues",
+		Artist:  "Hide Your Tigers",
+		Tags:    []string{"lsdj", "game boy"},
+		PageURL: "https://chipmusic.org/Hide+Your+Tigers/music/virtues-lsdj",
+	}
+
+	assert.Equal(t, &Tags{
+		Title:   "Virtues",
+		Artist:  "Hide Your Tigers",
+		Genre:   "lsdj, game boy",
+		Source:  "https://chipmusic.org/Hide+Your+Tigers/music/virtues-lsdj",
+		Comment: "https://chipmusic.org/Hide+Your+Tigers/music/virtues-lsdj",
+	}, FromTrack(track))
+}
@@ -0,0 +1,82 @@
+package chipmusic
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"time"
+)
+
+const (
+	// DefaultDialTimeout is the default limit on how long connecting to a host may take
+	DefaultDialTimeout = 30 * time.Second
+
+	// DefaultTLSHandshakeTimeout is the default limit on how long the TLS handshake with a host may take
+	DefaultTLSHandshakeTimeout = 10 * time.Second
+
+	// DefaultResponseHeaderTimeout is the default limit on how long a host may take to send the headers of a response
+	// once a request is sent. It doesn't limit reading the body, so long downloads aren't cut off
+	DefaultResponseHeaderTimeout = 30 * time.Second
+
+	keepAlive = 30 * time.Second
+)
+
+// WithDialTimeout allows overriding how long connecting to a host may take. Use 0 to wait for as long as the context of
+// the request allows
+func WithDialTimeout(timeout time.Duration) Option {
+	return func(c *Client) error {
+		if timeout < 0 {
+			return errors.New("dial timeout cannot be negative")
+		}
+
+		c.dialTimeout = timeout
+		return nil
+	}
+}
+
+// WithTLSHandshakeTimeout allows overriding how long the TLS handshake with a host may take. Use 0 to wait for as long
+// as the context of the request allows
+func WithTLSHandshakeTimeout(timeout time.Duration) Option {
+	return func(c *Client) error {
+		if timeout < 0 {
+			return errors.New("TLS handshake timeout cannot be negative")
+		}
+
+		c.tlsHandshakeTimeout = timeout
+		return nil
+	}
+}
+
+// WithResponseHeaderTimeout allows overriding how long a host may take to send the headers of a response. Use 0 to
+// wait for as long as the context of the request allows
+func WithResponseHeaderTimeout(timeout time.Duration) Option {
+	return func(c *Client) error {
+		if timeout < 0 {
+			return errors.New("response header timeout cannot be negative")
+		}
+
+		c.responseHeaderTimeout = timeout
+		return nil
+	}
+}
+
+// withTimeouts returns a copy of client whose transport uses the configured timeouts. The timeouts are only applied to
+// an *http.Transport, so other transports given with WithHTTPClient are returned untouched
+func (c *Client) withTimeouts(client *http.Client) *http.Client {
+	configured, err := configureTransport(client, func(transport *http.Transport) {
+		// A dialer configured for DNS servers or an IP version already uses the dial timeout
+		if len(c.dnsServers) == 0 && c.ipVersion == IPAny {
+			dialer := &net.Dialer{Timeout: c.dialTimeout, KeepAlive: keepAlive}
+			transport.DialContext = dialer.DialContext
+		}
+
+		transport.TLSHandshakeTimeout = c.tlsHandshakeTimeout
+		transport.ResponseHeaderTimeout = c.responseHeaderTimeout
+	})
+
+	if err != nil {
+		return client
+	}
+
+	return configured
+}
@@ -0,0 +1,100 @@
+package chipmusic
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNewClient_Timeouts(t *testing.T) {
+	tests := map[string]struct {
+		options  []Option
+		expected [3]time.Duration
+		isErr    bool
+	}{
+		"defaults": {
+			expected: [3]time.Duration{DefaultDialTimeout, DefaultTLSHandshakeTimeout, DefaultResponseHeaderTimeout},
+		},
+		"overridden": {
+			options: []Option{
+				WithDialTimeout(time.Second),
+				WithTLSHandshakeTimeout(2 * time.Second),
+				WithResponseHeaderTimeout(3 * time.Second),
+			},
+			expected: [3]time.Duration{time.Second, 2 * time.Second, 3 * time.Second},
+		},
+		"disabled": {
+			options:  []Option{WithDialTimeout(0), WithTLSHandshakeTimeout(0), WithResponseHeaderTimeout(0)},
+			expected: [3]time.Duration{0, 0, 0},
+		},
+		"negative dial timeout": {
+			options: []Option{WithDialTimeout(-time.Second)},
+			isErr:   true,
+		},
+		"negative TLS handshake timeout": {
+			options: []Option{WithTLSHandshakeTimeout(-time.Second)},
+			isErr:   true,
+		},
+		"negative response header timeout": {
+			options: []Option{WithResponseHeaderTimeout(-time.Second)},
+			isErr:   true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(tt *testing.T) {
+			client, err := NewClient(test.options...)
+			if test.isErr {
+				assert.Error(tt, err)
+				return
+			}
+
+			require.NoError(tt, err)
+			assert.Equal(tt, test.expected, [3]time.Duration{client.dialTimeout, client.tlsHandshakeTimeout, client.responseHeaderTimeout})
+
+			transport, ok := client.client.Transport.(*http.Transport)
+			require.True(tt, ok)
+			assert.Equal(tt, test.expected[1], transport.TLSHandshakeTimeout)
+			assert.Equal(tt, test.expected[2], transport.ResponseHeaderTimeout)
+		})
+	}
+
+	// The default client is shared, so it must not be modified
+	assert.Nil(t, http.DefaultClient.Transport)
+}
+
+func TestNewClient_TimeoutsSkipOtherTransports(t *testing.T) {
+	httpClient := &http.Client{Transport: roundTripperFunc(func(request *http.Request) (*http.Response, error) {
+		return nil, nil
+	})}
+
+	client, err := NewClient(WithHTTPClient(httpClient))
+	require.NoError(t, err)
+
+	_, ok := client.client.Transport.(roundTripperFunc)
+	assert.True(t, ok)
+}
+
+func TestClient_ResponseHeaderTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client, err := NewClient(WithHTTPClient(server.Client()), WithResponseHeaderTimeout(50*time.Millisecond))
+	require.NoError(t, err)
+
+	request, err := http.NewRequestWithContext(context.Background(), http.MethodGet, server.URL, nil)
+	require.NoError(t, err)
+
+	start := time.Now()
+	_, err = client.client.Do(request)
+	assert.Error(t, err)
+	assert.True(t, time.Since(start) < 5*time.Second)
+}
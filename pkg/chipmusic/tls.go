@@ -0,0 +1,66 @@
+package chipmusic
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+)
+
+// WithTLSConfig allows overriding the TLS configuration used to connect to hosts, e.g. to trust the CA of an
+// interception proxy on a corporate network. The configuration is copied, so changing it afterwards has no effect
+func WithTLSConfig(config *tls.Config) Option {
+	return func(c *Client) error {
+		if config == nil {
+			return errors.New("TLS config cannot be nil")
+		}
+
+		c.tlsConfig = config.Clone()
+		return nil
+	}
+}
+
+// LoadCABundle reads a PEM encoded bundle of CA certificates and returns a pool containing them along with the CAs
+// trusted by the system, so hosts which don't go through a proxy are still trusted
+func LoadCABundle(path string) (*x509.CertPool, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+
+	if !pool.AppendCertsFromPEM(raw) {
+		return nil, fmt.Errorf("failed to parse CA bundle: no PEM encoded certificates in %s", path)
+	}
+
+	return pool, nil
+}
+
+// ParseTLSVersion converts a TLS version such as 1.2 to its value in crypto/tls
+func ParseTLSVersion(version string) (uint16, error) {
+	switch version {
+	case "1.0":
+		return tls.VersionTLS10, nil
+	case "1.1":
+		return tls.VersionTLS11, nil
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("unknown TLS version %q. Allowed versions: [1.0, 1.1, 1.2, 1.3]", version)
+	}
+}
+
+// withTLSConfig returns a copy of client whose transport uses the configured TLS configuration
+func (c *Client) withTLSConfig(client *http.Client) (*http.Client, error) {
+	return configureTransport(client, func(transport *http.Transport) {
+		transport.TLSClientConfig = c.tlsConfig.Clone()
+	})
+}
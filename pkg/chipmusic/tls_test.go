@@ -0,0 +1,118 @@
+package chipmusic
+
+import (
+	"crypto/tls"
+	"encoding/pem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWithTLSConfig(t *testing.T) {
+	client, err := NewClient(WithTLSConfig(nil))
+	assert.Error(t, err)
+	assert.Nil(t, client)
+
+	// Other transports can't be given a TLS config
+	httpClient := &http.Client{Transport: roundTripperFunc(func(request *http.Request) (*http.Response, error) {
+		return nil, nil
+	})}
+
+	_, err = NewClient(WithHTTPClient(httpClient), WithTLSConfig(&tls.Config{}))
+	assert.Error(t, err)
+}
+
+func TestParseTLSVersion(t *testing.T) {
+	testCases := []struct {
+		version  string
+		expected uint16
+		isErr    bool
+	}{
+		{"1.0", tls.VersionTLS10, false},
+		{"1.1", tls.VersionTLS11, false},
+		{"1.2", tls.VersionTLS12, false},
+		{"1.3", tls.VersionTLS13, false},
+		{"", 0, true},
+		{"2.0", 0, true},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.version, func(tt *testing.T) {
+			version, err := ParseTLSVersion(testCase.version)
+			if testCase.isErr {
+				assert.Error(tt, err)
+				return
+			}
+
+			require.NoError(tt, err)
+			assert.Equal(tt, testCase.expected, version)
+		})
+	}
+}
+
+func TestLoadCABundle(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chipmusic-tls")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	invalid := filepath.Join(dir, "invalid.pem")
+	require.NoError(t, ioutil.WriteFile(invalid, []byte("not a certificate"), 0600))
+
+	_, err = LoadCABundle(filepath.Join(dir, "missing.pem"))
+	assert.Error(t, err)
+
+	_, err = LoadCABundle(invalid)
+	assert.Error(t, err)
+}
+
+func TestClient_TLSConfig(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.TLS = &tls.Config{MaxVersion: tls.VersionTLS12}
+	server.StartTLS()
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "chipmusic-tls")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	bundle := filepath.Join(dir, "ca.pem")
+	raw := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	require.NoError(t, ioutil.WriteFile(bundle, raw, 0600))
+
+	pool, err := LoadCABundle(bundle)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name   string
+		config *tls.Config
+		isErr  bool
+	}{
+		{"UntrustedCA", &tls.Config{}, true},
+		{"TrustedCA", &tls.Config{RootCAs: pool}, false},
+		{"InsecureSkipVerify", &tls.Config{InsecureSkipVerify: true}, false},
+		{"MinVersionTooHigh", &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS13}, true},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			client, err := NewClient(WithHTTPClient(&http.Client{}), WithTLSConfig(testCase.config))
+			require.NoError(tt, err)
+
+			response, err := client.client.Get(server.URL)
+			if testCase.isErr {
+				assert.Error(tt, err)
+				return
+			}
+
+			require.NoError(tt, err)
+			assert.NoError(tt, response.Body.Close())
+		})
+	}
+}
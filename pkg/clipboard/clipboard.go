@@ -0,0 +1,67 @@
+package clipboard
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// DefaultInterval is how often the clipboard is checked for changes
+const DefaultInterval = 500 * time.Millisecond
+
+// ErrUnsupported is returned when the clipboard cannot be read on this system, e.g. because no clipboard tool is
+// installed
+var ErrUnsupported = errors.New("reading the clipboard is not supported on this system")
+
+// Watcher detects when the text on the clipboard changes by reading it periodically. Operating systems don't share a
+// way to be notified of clipboard changes, so polling is the only portable option
+type Watcher struct {
+	interval time.Duration
+
+	// read returns the text on the clipboard
+	read func() (string, error)
+}
+
+// NewWatcher creates a Watcher which reads the clipboard every interval
+func NewWatcher(interval time.Duration) *Watcher {
+	return &Watcher{
+		interval: interval,
+		read:     Read,
+	}
+}
+
+// Watch returns a channel receiving the text on the clipboard each time it changes. The text on the clipboard when
+// watching starts is not sent. The channel is closed once ctx is done. Errors reading the clipboard are ignored since
+// the clipboard may briefly be unavailable, e.g. while another application owns it
+func (w *Watcher) Watch(ctx context.Context) <-chan string {
+	changes := make(chan string)
+	current, _ := w.read()
+	go func() {
+		defer close(changes)
+
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			text, err := w.read()
+			if err != nil || text == "" || text == current {
+				continue
+			}
+
+			current = text
+			select {
+			case changes <- text:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return changes
+}
@@ -0,0 +1,51 @@
+package clipboard
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+	"time"
+)
+
+const defaultTestTimeout = 5 * time.Second
+
+func TestWatcher_Watch(t *testing.T) {
+	mux := sync.Mutex{}
+	text, err := "copied before watching", error(nil)
+	watcher := &Watcher{
+		interval: time.Millisecond,
+		read: func() (string, error) {
+			mux.Lock()
+			defer mux.Unlock()
+			return text, err
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	changes := watcher.Watch(ctx)
+
+	// The clipboard is briefly unavailable while another application owns it
+	mux.Lock()
+	text, err = "", errors.New("clipboard is busy")
+	mux.Unlock()
+	time.Sleep(10 * time.Millisecond)
+
+	mux.Lock()
+	text, err = "https://chipmusic.org/some.artist/music/some.title", nil
+	mux.Unlock()
+
+	select {
+	case changed := <-changes:
+		assert.Equal(t, "https://chipmusic.org/some.artist/music/some.title", changed)
+	case <-time.After(defaultTestTimeout):
+		require.FailNow(t, "clipboard change was not detected")
+	}
+
+	cancel()
+	for range changes {
+		require.FailNow(t, "only one clipboard change should be detected")
+	}
+}
@@ -0,0 +1,18 @@
+// +build darwin
+
+package clipboard
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// Read returns the text on the clipboard, which is read with pbpaste
+func Read() (string, error) {
+	output, err := exec.Command("pbpaste").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to read clipboard with pbpaste: %w", err)
+	}
+
+	return string(output), nil
+}
@@ -0,0 +1,38 @@
+// +build linux
+
+package clipboard
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// Read returns the text on the clipboard. Linux has no clipboard of its own, so it is read with wl-paste on Wayland or
+// with xclip or xsel on X11, whichever is installed. If none is, ErrUnsupported is returned
+func Read() (string, error) {
+	var commands [][]string
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		commands = append(commands, []string{"wl-paste", "--no-newline"})
+	}
+
+	commands = append(commands,
+		[]string{"xclip", "-selection", "clipboard", "-out"},
+		[]string{"xsel", "--clipboard", "--output"},
+	)
+
+	for _, command := range commands {
+		if _, err := exec.LookPath(command[0]); err != nil {
+			continue
+		}
+
+		output, err := exec.Command(command[0], command[1:]...).Output()
+		if err != nil {
+			return "", fmt.Errorf("failed to read clipboard with %s: %w", command[0], err)
+		}
+
+		return string(output), nil
+	}
+
+	return "", ErrUnsupported
+}
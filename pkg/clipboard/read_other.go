@@ -0,0 +1,9 @@
+// +build !linux,!darwin,!windows
+
+package clipboard
+
+// Read returns the text on the clipboard. Reading it is only supported on Linux, macOS, and Windows, so
+// ErrUnsupported is always returned
+func Read() (string, error) {
+	return "", ErrUnsupported
+}
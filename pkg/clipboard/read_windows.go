@@ -0,0 +1,20 @@
+// +build windows
+
+package clipboard
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Read returns the text on the clipboard, which is read with the Get-Clipboard cmdlet of PowerShell
+func Read() (string, error) {
+	output, err := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", "Get-Clipboard -Raw").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to read clipboard with PowerShell: %w", err)
+	}
+
+	// PowerShell ends its output with a line break which isn't part of the clipboard
+	return strings.TrimSuffix(string(output), "\r\n"), nil
+}
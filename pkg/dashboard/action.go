@@ -0,0 +1,288 @@
+package dashboard
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// TrackControlSeek is the name of SeekAction
+	TrackControlSeek = "seek"
+
+	// SeekStep is how far the seek bindings move the current track
+	SeekStep = 10 * time.Second
+)
+
+// Action is an interface for everything sent on the channel returned by TerminalDashboard.Actions. Receivers should use
+// a type switch to handle every action
+type Action interface {
+
+	// Name returns the name of the track control the action performs, e.g. TrackControlPause
+	Name() string
+
+	// String returns the action as text which ParseAction turns back into the action, e.g. "jump 3"
+	String() string
+}
+
+// PlayAction resumes the current track
+type PlayAction struct{}
+
+func (a PlayAction) Name() string {
+	return TrackControlPlay
+}
+
+func (a PlayAction) String() string {
+	return a.Name()
+}
+
+// PauseAction pauses or resumes the current track
+type PauseAction struct{}
+
+func (a PauseAction) Name() string {
+	return TrackControlPause
+}
+
+func (a PauseAction) String() string {
+	return a.Name()
+}
+
+// StopAction stops the current track and rewinds it to its start
+type StopAction struct{}
+
+func (a StopAction) Name() string {
+	return TrackControlStop
+}
+
+func (a StopAction) String() string {
+	return a.Name()
+}
+
+// LoopAction turns looping of the current track on or off
+type LoopAction struct{}
+
+func (a LoopAction) Name() string {
+	return TrackControlLoop
+}
+
+func (a LoopAction) String() string {
+	return a.Name()
+}
+
+// SkipAction skips the rest of the current track
+type SkipAction struct{}
+
+func (a SkipAction) Name() string {
+	return TrackControlSkip
+}
+
+func (a SkipAction) String() string {
+	return a.Name()
+}
+
+// CrossfeedAction turns crossfeed on or off
+type CrossfeedAction struct{}
+
+func (a CrossfeedAction) Name() string {
+	return TrackControlCrossfeed
+}
+
+func (a CrossfeedAction) String() string {
+	return a.Name()
+}
+
+// NormalizeAction turns loudness normalization on or off
+type NormalizeAction struct{}
+
+func (a NormalizeAction) Name() string {
+	return TrackControlNormalize
+}
+
+func (a NormalizeAction) String() string {
+	return a.Name()
+}
+
+// VolumeUpAction turns the volume up by a step
+type VolumeUpAction struct{}
+
+func (a VolumeUpAction) Name() string {
+	return TrackControlVolumeUp
+}
+
+func (a VolumeUpAction) String() string {
+	return a.Name()
+}
+
+// VolumeDownAction turns the volume down by a step
+type VolumeDownAction struct{}
+
+func (a VolumeDownAction) Name() string {
+	return TrackControlVolumeDown
+}
+
+func (a VolumeDownAction) String() string {
+	return a.Name()
+}
+
+// SeekAction moves the current track by Offset, where a negative Offset moves it back
+type SeekAction struct {
+	Offset time.Duration
+}
+
+func (a SeekAction) Name() string {
+	return TrackControlSeek
+}
+
+func (a SeekAction) String() string {
+	return fmt.Sprintf("%s %s", a.Name(), a.Offset)
+}
+
+// JumpAction plays the upcoming track at Position in the queue right away, where 1 is the next track
+type JumpAction struct {
+	Position int
+}
+
+func (a JumpAction) Name() string {
+	return TrackControlJump
+}
+
+func (a JumpAction) String() string {
+	return fmt.Sprintf("%s %d", a.Name(), a.Position)
+}
+
+// SearchAction searches chipmusic.org for Query. The results are shown with ShowSearchResults
+type SearchAction struct {
+	Query string
+}
+
+func (a SearchAction) Name() string {
+	return TrackControlSearch
+}
+
+func (a SearchAction) String() string {
+	return a.Name() + " " + a.Query
+}
+
+// SearchMoreAction searches chipmusic.org for Page of the results for Query, after the pages shown already. The
+// results are added with AppendSearchResults
+type SearchMoreAction struct {
+	Query string
+	Page  int
+}
+
+func (a SearchMoreAction) Name() string {
+	return TrackControlSearchMore
+}
+
+func (a SearchMoreAction) String() string {
+	return fmt.Sprintf("%s %d %s", a.Name(), a.Page, a.Query)
+}
+
+// EnqueueAction plays the track with the track page at URL after the tracks in the queue
+type EnqueueAction struct {
+	URL string
+}
+
+func (a EnqueueAction) Name() string {
+	return TrackControlEnqueue
+}
+
+func (a EnqueueAction) String() string {
+	return a.Name() + " " + a.URL
+}
+
+// PlayNextAction plays the track with the track page at URL right after the current track, in front of the tracks in
+// the queue
+type PlayNextAction struct {
+	URL string
+}
+
+func (a PlayNextAction) Name() string {
+	return TrackControlPlayNext
+}
+
+func (a PlayNextAction) String() string {
+	return a.Name() + " " + a.URL
+}
+
+// PlayNowAction plays the track with the track page at URL right away
+type PlayNowAction struct {
+	URL string
+}
+
+func (a PlayNowAction) Name() string {
+	return TrackControlPlayNow
+}
+
+func (a PlayNowAction) String() string {
+	return a.Name() + " " + a.URL
+}
+
+// controlActions are the actions without parameters by name
+var controlActions = map[string]Action{
+	TrackControlPlay:       PlayAction{},
+	TrackControlPause:      PauseAction{},
+	TrackControlStop:       StopAction{},
+	TrackControlLoop:       LoopAction{},
+	TrackControlSkip:       SkipAction{},
+	TrackControlCrossfeed:  CrossfeedAction{},
+	TrackControlNormalize:  NormalizeAction{},
+	TrackControlVolumeUp:   VolumeUpAction{},
+	TrackControlVolumeDown: VolumeDownAction{},
+}
+
+// ParseAction returns the action written as text by its String method, e.g. in a recorded session
+func ParseAction(text string) (Action, error) {
+	name, argument := text, ""
+	if i := strings.Index(text, " "); i >= 0 {
+		name, argument = text[:i], strings.TrimSpace(text[i+1:])
+	}
+
+	if action, ok := controlActions[name]; ok {
+		if argument != "" {
+			return nil, fmt.Errorf("action %s takes no argument", name)
+		}
+
+		return action, nil
+	}
+
+	if argument == "" {
+		return nil, fmt.Errorf("unknown action %q", text)
+	}
+
+	switch name {
+	case TrackControlSeek:
+		offset, err := time.ParseDuration(argument)
+		if err != nil {
+			return nil, fmt.Errorf("invalid seek offset: %w", err)
+		}
+
+		return SeekAction{Offset: offset}, nil
+	case TrackControlJump:
+		position, err := strconv.Atoi(argument)
+		if err != nil || position < 1 {
+			return nil, fmt.Errorf("invalid position to jump to: %q", argument)
+		}
+
+		return JumpAction{Position: position}, nil
+	case TrackControlSearch:
+		return SearchAction{Query: argument}, nil
+	case TrackControlSearchMore:
+		fields := strings.SplitN(argument, " ", 2)
+		page, err := strconv.Atoi(fields[0])
+		if err != nil || page < 2 || len(fields) < 2 || strings.TrimSpace(fields[1]) == "" {
+			return nil, fmt.Errorf("invalid page of search results: %q", argument)
+		}
+
+		return SearchMoreAction{Query: strings.TrimSpace(fields[1]), Page: page}, nil
+	case TrackControlEnqueue:
+		return EnqueueAction{URL: argument}, nil
+	case TrackControlPlayNext:
+		return PlayNextAction{URL: argument}, nil
+	case TrackControlPlayNow:
+		return PlayNowAction{URL: argument}, nil
+	default:
+		return nil, fmt.Errorf("unknown action %q", text)
+	}
+}
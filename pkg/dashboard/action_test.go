@@ -0,0 +1,54 @@
+package dashboard
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestParseAction(t *testing.T) {
+	testCases := []Action{
+		PauseAction{},
+		SkipAction{},
+		VolumeDownAction{},
+		SeekAction{Offset: -10 * time.Second},
+		JumpAction{Position: 12},
+		SearchAction{Query: "some query"},
+		SearchMoreAction{Query: "some query", Page: 2},
+		EnqueueAction{URL: "some.url"},
+		PlayNextAction{URL: "some.url"},
+		PlayNowAction{URL: "some.url"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.String(), func(tt *testing.T) {
+			action, err := ParseAction(testCase.String())
+			require.NoError(tt, err)
+			assert.Equal(tt, testCase, action)
+		})
+	}
+}
+
+func TestParseAction_Invalid(t *testing.T) {
+	testCases := []string{
+		"",
+		"some.action",
+		"skip 3",
+		"jump",
+		"jump 0",
+		"jump some.position",
+		"seek some.offset",
+		"search ",
+		"search-more 2",
+		"search-more 1 some query",
+		"search-more some.page some query",
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase, func(tt *testing.T) {
+			_, err := ParseAction(testCase)
+			assert.Error(tt, err)
+		})
+	}
+}
@@ -4,31 +4,44 @@ import (
 	"errors"
 	"fmt"
 	"github.com/broar/chipmusic-cli/pkg/chipmusic"
+	"github.com/broar/chipmusic-cli/pkg/events"
 	"github.com/gdamore/tcell/v2"
 	"strings"
+	"sync"
 	"time"
 )
 
 const (
-	TrackControlPlay  = "play"
-	TrackControlPause = "pause"
-	TrackControlStop  = "stop"
-	TrackControlLoop  = "loop"
-	TrackControlSkip  = "skip"
+	TrackControlPlay      = "play"
+	TrackControlPause     = "pause"
+	TrackControlStop      = "stop"
+	TrackControlLoop      = "loop"
+	TrackControlSkip      = "skip"
+	TrackControlCrossfeed = "crossfeed"
+
+	// TrackControlVolumeUp, TrackControlVolumeDown, and TrackControlNormalize are sent by keys instead of being
+	// selected among the track controls
+	TrackControlVolumeUp   = "volume-up"
+	TrackControlVolumeDown = "volume-down"
+	TrackControlNormalize  = "normalize"
 
 	currentlyPlayingID = "currently-playing"
 	trackTimerID       = "time"
 	progressBarID      = "progress"
+	noticeID           = "notice"
 
 	progressBarLength = 32
+
+	// statsOverlayY is the row where the statistics overlay is drawn, below the notice
+	statsOverlayY = 6
 )
 
 var (
 	// ErrNilTrack is an error returned when attempting to use a nil Screen for a TerminalDashboard
 	ErrNilScreen           = errors.New("screen cannot be nil")
 
-	selectedTrackControlStyle = tcell.StyleDefault.Foreground(tcell.ColorBlack).Background(tcell.ColorWhite)
-	defaultTextStyle          = tcell.StyleDefault.Foreground(tcell.ColorReset).Background(tcell.ColorReset)
+	// ErrNilKeymap is an error returned when attempting to use a nil Keymap for a TerminalDashboard
+	ErrNilKeymap = errors.New("keymap cannot be nil")
 
 	trackControls = []string{
 		TrackControlPlay,
@@ -36,6 +49,7 @@ var (
 		TrackControlStop,
 		TrackControlLoop,
 		TrackControlSkip,
+		TrackControlCrossfeed,
 	}
 
 	initialProgressBar = strings.Repeat("▒", progressBarLength)
@@ -46,7 +60,82 @@ type TerminalDashboard struct {
 	screen   tcell.Screen
 	widgets  map[string]*TextWidget
 	selected string
-	actions  chan string
+	actions  chan Action
+	keymap   Keymap
+	theme    Theme
+
+	statsMux     sync.Mutex
+	stats        SessionStats
+	statsVisible bool
+	statsOverlay *Widget
+	now          func() time.Time
+
+	// network is the download throughput and the tracks downloaded ahead, which the network widget shows
+	networkMux sync.Mutex
+	network    NetworkStats
+
+	// current is the track playing and queue are the tracks playing after it, which are listed in the queue pane.
+	// queueStartsIn holds how long after current ends each track in queue starts, and trackPosition and trackTotal are
+	// the position and length of current. queueCursor is the index in queue of the track under the cursor
+	queueMux      sync.Mutex
+	current       *chipmusic.Track
+	queue         []*chipmusic.Track
+	queueStartsIn []time.Duration
+	trackPosition time.Duration
+	trackTotal    time.Duration
+	queueCursor   int
+	queuePane     *ListWidget
+
+	// searchState is whether the search box is closed, typed into, or browsing searchResults, and searchCursor is the
+	// index in searchResults of the result selected. searchResults holds the first searchPage pages of results for
+	// searchQuery. searchLoading is true while the next page is searched for, and searchExhausted once there are no
+	// more pages
+	searchMux       sync.Mutex
+	searchState     int
+	searchInput     *InputWidget
+	searchQuery     string
+	searchResults   []chipmusic.SearchResult
+	searchPage      int
+	searchLoading   bool
+	searchExhausted bool
+	searchCursor    int
+	searchPane      *ListWidget
+
+	// detailsPane shows when the current track was posted, its tags, and its description
+	detailsMux  sync.Mutex
+	detailsPane *ListWidget
+
+	// log holds the latest logLines errors, skips, and retries, which logPane lists while logVisible is true.
+	// logUnseen is how many lines were logged while the log pane was hidden
+	logMux     sync.Mutex
+	log        []string
+	logLines   int
+	logVisible bool
+	logUnseen  int
+	logPane    *ListWidget
+
+	// mouseButtons are the mouse buttons held down as of the last mouse event, so holding a button isn't taken for
+	// clicking it over and over
+	mouseButtons tcell.ButtonMask
+
+	// modal is the window open over the dashboard, which gets every key until it is closed. It is nil if no modal is
+	// open
+	modalMux sync.Mutex
+	modal    Modal
+
+	// toasts are the messages shown in the toast area until they expire, and toastArea is what was drawn for them last
+	toastMux      sync.Mutex
+	toasts        []toast
+	toastDuration time.Duration
+	toastArea     *Widget
+
+	// settings returns the current settings of the session listed in the help overlay. If nil, no settings are listed
+	settings func() []Setting
+
+	// tooSmall is true while the terminal is smaller than the minimum size, so a message is shown instead of the
+	// dashboard
+	sizeMux  sync.Mutex
+	tooSmall bool
 }
 
 // Option is an alias for a function that modifies a TerminalDashboard. An Option is used to override the default values of TerminalDashboard
@@ -72,31 +161,64 @@ func NewTerminalDashboard(options ...Option) (*TerminalDashboard, error) {
 	}
 
 	dashboard := &TerminalDashboard{
-		screen: screen,
-		widgets: map[string]*TextWidget{
-			currentlyPlayingID: NewTextWidget(0, 0, "", defaultTextStyle),
-			progressBarID:      NewTextWidget(0, 1, initialProgressBar, defaultTextStyle),
-			trackTimerID:       NewTextWidget(0, 2, formatTrackTimer(0, 0), defaultTextStyle),
-		},
+		screen:   screen,
 		selected: TrackControlPlay,
-		actions:  make(chan string),
+		actions:  make(chan Action),
+		keymap:   DefaultKeymap(),
+		theme:    *DefaultTheme(),
+		now:      time.Now,
+
+		toastDuration: DefaultToastDuration,
+		logLines:      DefaultLogLines,
+	}
+
+	for _, option := range options {
+		if err := option(dashboard); err != nil {
+			return nil, err
+		}
+	}
+
+	// The widgets are created once the options are applied so they are drawn with the theme
+	theme := dashboard.theme
+	dashboard.widgets = map[string]*TextWidget{
+		currentlyPlayingID: NewTextWidget(0, 0, "", theme.Accent),
+		progressBarID:      NewTextWidget(0, 1, initialProgressBar, theme.Accent),
+		trackTimerID:       NewTextWidget(0, 2, formatTrackTimer(0, 0), theme.Text),
+		networkID:          NewTextWidget(networkX, 2, formatNetwork(&NetworkStats{}, time.Time{}), theme.Text),
+		noticeID:           NewTextWidget(0, 4, "", theme.Text),
+		queueTitleID:       NewTextWidget(0, queuePaneY, "Up next (↑/↓ to select, J or 1-9 to jump)", theme.Accent),
+		detailsTitleID:     NewTextWidget(detailsPaneX, 0, "", theme.Accent),
+		logTitleID:         NewTextWidget(detailsPaneX, logPaneY, formatLogTitle(false, 0), theme.Accent),
 	}
 
+	dashboard.statsOverlay = NewWidget(0, statsOverlayY, nil, theme.Text)
+	dashboard.queuePane = NewListWidget(0, queuePaneY+1, queuePaneHeight, theme.Text, theme.Highlight)
+	dashboard.searchInput = NewInputWidget(0, searchPaneY, searchPrompt, theme.Text)
+	dashboard.searchPane = NewListWidget(0, searchPaneY+1, searchPaneHeight, theme.Text, theme.Highlight)
+	dashboard.detailsPane = NewListWidget(detailsPaneX, 1, detailsPaneHeight, theme.Text, theme.Text)
+	dashboard.logPane = NewListWidget(detailsPaneX, logPaneY+1, dashboard.logLines, theme.Text, theme.Text)
+
 	previous := ""
 	x := 0
 	for i, trackControl := range trackControls {
 		x += len(previous)
-		dashboard.widgets[trackControl] = NewTextWidget(x+(i*2), 3, trackControl, defaultTextStyle)
+		dashboard.widgets[trackControl] = NewTextWidget(x+(i*2), 3, trackControl, theme.Text)
 		previous = trackControl
 	}
 
-	for _, option := range options {
-		if err := option(dashboard); err != nil {
-			return nil, err
+	return dashboard, nil
+}
+
+// WithKeymap allows clients to override the keys bound to track controls
+func WithKeymap(keymap Keymap) Option {
+	return func(dashboard *TerminalDashboard) error {
+		if keymap == nil {
+			return ErrNilKeymap
 		}
-	}
 
-	return dashboard, nil
+		dashboard.keymap = keymap
+		return nil
+	}
 }
 
 func (d *TerminalDashboard) Start() error {
@@ -105,32 +227,63 @@ func (d *TerminalDashboard) Start() error {
 	}
 
 	for {
-		d.screen.Show()
+		d.show()
 		event := d.screen.PollEvent()
 
 		var err error
 		switch event := event.(type) {
 		case *tcell.EventResize:
-			d.screen.Sync()
+			d.resize(event.Size())
+		case *tcell.EventMouse:
+			if !d.isTooSmall() {
+				d.handleMouse(event)
+			}
 		case *tcell.EventKey:
+			if event.Key() != tcell.KeyCtrlC && d.handleModalKey(event) {
+				break
+			}
+
+			if d.handleSearchKey(event) {
+				break
+			}
+
 			switch event.Key() {
 			case tcell.KeyEscape, tcell.KeyCtrlC:
 				d.screen.Fini()
 				return nil
 			case tcell.KeyEnter:
-				d.actions <- d.selected
+				d.actions <- controlActions[d.selected]
+			case tcell.KeyRune:
+				if binding, ok := d.keymap[event.Rune()]; ok {
+					if binding == BindingQuit {
+						d.screen.Fini()
+						return nil
+					}
+
+					d.handleBinding(binding)
+				} else if event.Rune() >= '1' && event.Rune() <= '9' {
+					d.actions <- JumpAction{Position: int(event.Rune() - '0')}
+				}
+			case tcell.KeyUp:
+				d.MoveQueueCursor(-1)
+			case tcell.KeyDown:
+				d.MoveQueueCursor(1)
+			case tcell.KeyPgUp:
+				d.ScrollQueue(-queuePaneHeight)
+			case tcell.KeyPgDn:
+				d.ScrollQueue(queuePaneHeight)
 			case tcell.KeyLeft:
 				old := d.widgets[d.selected]
-				old.SetStyle(defaultTextStyle)
+				old.SetStyle(d.theme.Text)
 				selected := d.previousTrackControl()
-				selected.SetStyle(selectedTrackControlStyle)
+				selected.SetStyle(d.theme.Highlight)
 				old.Draw(d.screen)
 				selected.Draw(d.screen)
 			case tcell.KeyRight:
 				old := d.widgets[d.selected]
-				old.SetStyle(defaultTextStyle)
+				old.SetStyle(d.theme.Text)
 				selected := d.nextTrackControl()
-				selected.SetStyle(selectedTrackControlStyle)
+				selected.SetStyle(d.theme.Highlight)
 				old.Draw(d.screen)
 				selected.Draw(d.screen)
 			}
@@ -147,6 +300,10 @@ func (d *TerminalDashboard) init() error {
 		return fmt.Errorf("failed to initialize screen: %w", err)
 	}
 
+	d.screen.EnableMouse()
+
+	// Cells without a widget are cleared to the style of text so the background of the theme fills the screen
+	d.screen.SetStyle(d.theme.Text)
 	d.screen.Clear()
 
 	for _, widget := range d.widgets {
@@ -161,6 +318,13 @@ func (d *TerminalDashboard) UpdateCurrentTrack(track *chipmusic.Track) {
 		return
 	}
 
+	d.queueMux.Lock()
+	d.current = track
+	d.trackPosition, d.trackTotal = 0, 0
+	d.queueMux.Unlock()
+	d.refreshQueue()
+	d.UpdateDetails(track)
+
 	currentlyPlaying := d.widgets[currentlyPlayingID]
 	currentlyPlaying.Clear(d.screen)
 	currentlyPlaying.SetText(fmt.Sprintf("Now playing: %s by %s", track.Title, track.Artist))
@@ -170,7 +334,7 @@ func (d *TerminalDashboard) UpdateCurrentTrack(track *chipmusic.Track) {
 	progressBar.SetText(initialProgressBar)
 	progressBar.Draw(d.screen)
 
-	d.screen.Show()
+	d.show()
 }
 
 func (d *TerminalDashboard) UpdateTrackTimer(current, total time.Duration) {
@@ -178,8 +342,17 @@ func (d *TerminalDashboard) UpdateTrackTimer(current, total time.Duration) {
 	trackTimer.SetText(formatTrackTimer(current, total))
 	trackTimer.Draw(d.screen)
 
+	// The throughput drops once downloads stop, even though no more events arrive
+	d.refreshNetwork()
+
+	// The queue pane estimates when the upcoming tracks start from the time left of the current track
+	d.queueMux.Lock()
+	d.trackPosition, d.trackTotal = current, total
+	d.queueMux.Unlock()
+
 	if total == 0 {
-		d.screen.Show()
+		d.refreshQueue()
+		d.show()
 		return
 	}
 
@@ -188,7 +361,145 @@ func (d *TerminalDashboard) UpdateTrackTimer(current, total time.Duration) {
 	progressBar.SetText(progressBarText)
 	progressBar.Draw(d.screen)
 
-	d.screen.Show()
+	d.refreshQueue()
+	d.show()
+}
+
+// UpdateNotice displays a short message below the track controls, e.g. when a track is skipped. An empty message
+// clears the notice
+func (d *TerminalDashboard) UpdateNotice(text string) {
+	notice := d.widgets[noticeID]
+	notice.Clear(d.screen)
+	notice.SetText(text)
+	notice.Draw(d.screen)
+
+	d.show()
+}
+
+// HandleEvents updates the dashboard for every event received on ch until ch is closed. Events which the dashboard
+// does not display are ignored
+func (d *TerminalDashboard) HandleEvents(ch <-chan events.Event) {
+	for event := range ch {
+		d.statsMux.Lock()
+		d.stats.Add(event, d.now())
+		d.statsMux.Unlock()
+		d.refreshStats()
+
+		d.networkMux.Lock()
+		d.network.Add(event, d.now())
+		d.networkMux.Unlock()
+
+		if level, message, ok := logEntry(event); ok {
+			d.Log(level, message)
+		}
+
+		switch event := event.(type) {
+		case events.PlaybackStarted:
+			d.refreshNetwork()
+			d.UpdateCurrentTrack(event.Track)
+			d.UpdateNotice("")
+		case events.DownloadProgress:
+			d.refreshNetwork()
+			d.UpdateNotice(formatDownloadProgress(event))
+		case events.Error:
+			d.ShowToast(formatError(event), ToastError)
+		case events.TrackSkipped:
+			d.refreshNetwork()
+			d.ShowToast(formatTrackSkipped(event), ToastError)
+		case events.Notice:
+			d.ShowToast(event.Message, ToastInfo)
+		case events.PlaybackInterrupted:
+			d.UpdateNotice(fmt.Sprintf("Paused because %v. Select play to resume", event.Reason))
+		case events.VolumeChanged:
+			d.UpdateNotice(fmt.Sprintf("Volume: %d%%", event.Volume))
+		case events.QueueChanged:
+			d.UpdateQueue(event.Queue, event.StartsIn)
+		case events.AudioDeviceChanged:
+			d.UpdateNotice(fmt.Sprintf("Playing on %s", event.Device))
+		case events.LinksChecked:
+			if event.Dropped > 0 {
+				d.UpdateNotice(fmt.Sprintf("Dropped %d of %d tracks with dead links", event.Dropped, event.Checked))
+			}
+		case events.IdlePaused:
+			d.UpdateNotice(fmt.Sprintf("Paused after %s without activity. Select play to resume", event.After))
+		case events.IdleResumed:
+			d.UpdateNotice("Welcome back! Resumed playback")
+		}
+	}
+}
+
+// ToggleStats shows the statistics of the session over the dashboard. If the statistics are already shown, this method
+// hides them
+func (d *TerminalDashboard) ToggleStats() {
+	d.statsMux.Lock()
+	d.statsVisible = !d.statsVisible
+	visible := d.statsVisible
+	if !visible {
+		d.statsOverlay.Clear(d.screen)
+	}
+
+	d.statsMux.Unlock()
+
+	// The queue pane is under the overlay, so it is drawn again once the overlay is hidden
+	if visible {
+		d.refreshStats()
+	} else {
+		d.refreshQueue()
+	}
+
+	d.show()
+}
+
+// Stats returns a copy of the statistics of the session collected so far
+func (d *TerminalDashboard) Stats() SessionStats {
+	d.statsMux.Lock()
+	defer d.statsMux.Unlock()
+	stats := d.stats
+	stats.downloads = nil
+	return stats
+}
+
+// refreshStats redraws the statistics overlay if it is shown
+func (d *TerminalDashboard) refreshStats() {
+	d.statsMux.Lock()
+	defer d.statsMux.Unlock()
+	if !d.statsVisible {
+		return
+	}
+
+	d.statsOverlay.Clear(d.screen)
+	d.statsOverlay.drawing = formatStatsOverlay(&d.stats, d.now())
+	d.statsOverlay.Draw(d.screen)
+}
+
+// formatDownloadProgress describes how much of a track has been downloaded. Once the download is complete, the notice is
+// cleared
+func formatDownloadProgress(event events.DownloadProgress) string {
+	if event.Total <= 0 {
+		return fmt.Sprintf("Downloading: %d KB", event.Downloaded/1024)
+	}
+
+	if event.Downloaded >= event.Total {
+		return ""
+	}
+
+	return fmt.Sprintf("Downloading: %d%%", event.Downloaded*100/event.Total)
+}
+
+func formatError(event events.Error) string {
+	if event.Track == nil {
+		return fmt.Sprintf("Error: %v", event.Err)
+	}
+
+	return fmt.Sprintf("%s by %s: %v", event.Track.Title, event.Track.Artist, event.Err)
+}
+
+func formatTrackSkipped(event events.TrackSkipped) string {
+	if event.Track == nil {
+		return fmt.Sprintf("Skipped: %v", event.Reason)
+	}
+
+	return fmt.Sprintf("%s by %s: %v", event.Track.Title, event.Track.Artist, event.Reason)
 }
 
 func formatTrackTimer(current, total time.Duration) string {
@@ -211,6 +522,8 @@ func (d *TerminalDashboard) nextTrackControl() *TextWidget {
 	case TrackControlLoop:
 		d.selected = TrackControlSkip
 	case TrackControlSkip:
+		d.selected = TrackControlCrossfeed
+	case TrackControlCrossfeed:
 		d.selected = TrackControlPlay
 	default:
 		d.selected = TrackControlPlay
@@ -222,7 +535,7 @@ func (d *TerminalDashboard) nextTrackControl() *TextWidget {
 func (d *TerminalDashboard) previousTrackControl() *TextWidget {
 	switch d.selected {
 	case TrackControlPlay:
-		d.selected = TrackControlSkip
+		d.selected = TrackControlCrossfeed
 	case TrackControlPause:
 		d.selected = TrackControlPlay
 	case TrackControlStop:
@@ -231,6 +544,8 @@ func (d *TerminalDashboard) previousTrackControl() *TextWidget {
 		d.selected = TrackControlStop
 	case TrackControlSkip:
 		d.selected = TrackControlLoop
+	case TrackControlCrossfeed:
+		d.selected = TrackControlSkip
 	default:
 		d.selected = TrackControlPlay
 	}
@@ -238,7 +553,7 @@ func (d *TerminalDashboard) previousTrackControl() *TextWidget {
 	return d.widgets[d.selected]
 }
 
-func (d *TerminalDashboard) Actions() <-chan string {
+func (d *TerminalDashboard) Actions() <-chan Action {
 	return d.actions
 }
 
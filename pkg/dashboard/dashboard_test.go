@@ -1,7 +1,9 @@
 package dashboard
 
 import (
+	"errors"
 	"github.com/broar/chipmusic-cli/pkg/chipmusic"
+	"github.com/broar/chipmusic-cli/pkg/events"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"testing"
@@ -70,3 +72,144 @@ func TestTerminalDashboard_UpdateTrackTimer(t *testing.T) {
 func TestTerminalDashboard_Start(t *testing.T) {
 
 }
+
+func TestTerminalDashboard_NextTrackControl(t *testing.T) {
+	db, err := NewTerminalDashboard(WithScreen(&MockScreen{}))
+	require.NoError(t, err)
+
+	defer db.Close()
+
+	for i := range trackControls {
+		expected := trackControls[(i+1)%len(trackControls)]
+		assert.Equal(t, db.widgets[expected], db.nextTrackControl())
+		assert.Equal(t, expected, db.selected)
+	}
+}
+
+func TestTerminalDashboard_PreviousTrackControl(t *testing.T) {
+	db, err := NewTerminalDashboard(WithScreen(&MockScreen{}))
+	require.NoError(t, err)
+
+	defer db.Close()
+
+	for i := len(trackControls); i > 0; i-- {
+		expected := trackControls[i-1]
+		assert.Equal(t, db.widgets[expected], db.previousTrackControl())
+		assert.Equal(t, expected, db.selected)
+	}
+}
+
+func TestTerminalDashboard_UpdateNotice(t *testing.T) {
+	db, err := NewTerminalDashboard(WithScreen(&MockScreen{}))
+	require.NoError(t, err)
+
+	defer db.Close()
+
+	db.UpdateNotice("some.notice")
+	widget, ok := db.widgets[noticeID]
+	require.True(t, ok)
+
+	assert.Equal(t, []string{"some.notice"}, widget.base.drawing)
+}
+
+func TestTerminalDashboard_HandleEvents(t *testing.T) {
+	testCases := []struct {
+		name     string
+		event    events.Event
+		widgetID string
+		expected string
+	}{
+		{"PlaybackStarted", events.PlaybackStarted{Track: &chipmusic.Track{Title: "some.title", Artist: "some.artist"}}, currentlyPlayingID, "Now playing: some.title by some.artist"},
+		{"DownloadProgress", events.DownloadProgress{URL: "some.url", Downloaded: 420, Total: 1000}, noticeID, "Downloading: 42%"},
+		{"DownloadProgressUnknownTotal", events.DownloadProgress{URL: "some.url", Downloaded: 2048, Total: -1}, noticeID, "Downloading: 2 KB"},
+		{"DownloadProgressComplete", events.DownloadProgress{URL: "some.url", Downloaded: 1000, Total: 1000}, noticeID, ""},
+		{"PlaybackInterrupted", events.PlaybackInterrupted{Reason: errors.New("the system was suspended")}, noticeID, "Paused because the system was suspended. Select play to resume"},
+		{"AudioDeviceChanged", events.AudioDeviceChanged{Device: "headphones"}, noticeID, "Playing on headphones"},
+		{"VolumeChanged", events.VolumeChanged{Volume: 40}, noticeID, "Volume: 40%"},
+		{"IgnoredEvent", events.TrackResolved{Track: &chipmusic.Track{Title: "some.title"}}, currentlyPlayingID, ""},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			db, err := NewTerminalDashboard(WithScreen(&MockScreen{}))
+			require.NoError(tt, err)
+
+			defer db.Close()
+
+			ch := make(chan events.Event, 1)
+			ch <- testCase.event
+			close(ch)
+
+			db.HandleEvents(ch)
+			widget, ok := db.widgets[testCase.widgetID]
+			require.True(tt, ok)
+
+			assert.Equal(tt, []string{testCase.expected}, widget.base.drawing)
+		})
+	}
+}
+
+func TestTerminalDashboard_HandleEventsProgressBurst(t *testing.T) {
+	db, err := NewTerminalDashboard(WithScreen(&MockScreen{}))
+	require.NoError(t, err)
+
+	defer db.Close()
+
+	// The session subscribes the dashboard this way, so a burst of progress must not crowd out the track started
+	bus := events.NewBus()
+	ch, _ := bus.SubscribeAll()
+	for i := 0; i < 10*events.DefaultBufferSize; i++ {
+		bus.Publish(events.DownloadProgress{URL: "some.url", Downloaded: int64(i), Total: -1})
+	}
+
+	bus.Publish(events.PlaybackStarted{Track: &chipmusic.Track{Title: "some.title", Artist: "some.artist"}})
+	for i := 0; i < 10*events.DefaultBufferSize; i++ {
+		bus.Publish(events.DownloadProgress{URL: "some.url", Downloaded: int64(i), Total: -1})
+	}
+
+	require.NoError(t, bus.Close())
+	db.HandleEvents(ch)
+
+	widget, ok := db.widgets[currentlyPlayingID]
+	require.True(t, ok)
+
+	assert.Equal(t, []string{"Now playing: some.title by some.artist"}, widget.base.drawing)
+}
+
+func TestTerminalDashboard_ToggleStats(t *testing.T) {
+	db, err := NewTerminalDashboard(WithScreen(&MockScreen{}))
+	require.NoError(t, err)
+
+	defer db.Close()
+
+	now := time.Unix(1600000000, 0)
+	db.now = func() time.Time {
+		return now
+	}
+
+	ch := make(chan events.Event, 3)
+	ch <- events.PlaybackStarted{Track: &chipmusic.Track{Title: "some.title", Artist: "some.artist"}}
+	ch <- events.TrackSkipped{Reason: errors.New("skipped because audio is corrupt")}
+	ch <- events.DownloadProgress{URL: "some.url", Downloaded: 2048, Total: 4096}
+	close(ch)
+
+	db.HandleEvents(ch)
+	assert.Empty(t, db.statsOverlay.drawing, "expected the overlay to be hidden by default")
+
+	now = now.Add(90 * time.Second)
+	db.ToggleStats()
+	assert.Equal(t, []string{
+		"+- Session ----------------+",
+		"| Tracks played: 1         |",
+		"| Total time: 1:30         |",
+		"| Skips: 1                 |",
+		"| Downloaded: 2.0 KB       |",
+		"| Throughput: 0 B/s        |",
+		"| Cache hits: 0/0          |",
+		"+--------------------------+",
+	}, db.statsOverlay.drawing)
+
+	db.ToggleStats()
+	assert.False(t, db.statsVisible)
+	assert.Equal(t, 1, db.Stats().TracksPlayed)
+}
@@ -0,0 +1,127 @@
+package dashboard
+
+import (
+	"fmt"
+	"github.com/broar/chipmusic-cli/pkg/chipmusic"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// BindingDetailsUp and BindingDetailsDown scroll the details pane by a line
+	BindingDetailsUp   = "details-up"
+	BindingDetailsDown = "details-down"
+
+	detailsTitleID = "details"
+	detailsTitle   = "About this track ([/] to scroll)"
+
+	// detailsPaneX is the column of the details pane, to the right of the queue pane
+	detailsPaneX = 72
+
+	// detailsPaneWidth is how many columns the text of the details pane is wrapped to
+	detailsPaneWidth = 44
+
+	// detailsPaneHeight is how many lines of the details pane are shown at a time, down to the end of the queue pane
+	detailsPaneHeight = queuePaneY + queuePaneHeight - 1
+
+	// postedAtLayout is how the date a track was posted is shown
+	postedAtLayout = "Jan 2, 2006"
+)
+
+// UpdateDetails shows when track was posted, its tags, and its description in the details pane, scrolled to the top
+func (d *TerminalDashboard) UpdateDetails(track *chipmusic.Track) {
+	d.detailsMux.Lock()
+	d.detailsPane.SetItems(formatDetails(track, detailsPaneWidth), -1)
+	d.detailsPane.ScrollTo(0)
+	d.detailsPane.Draw(d.screen)
+	d.detailsMux.Unlock()
+
+	title := d.widgets[detailsTitleID]
+	title.SetText(detailsTitle)
+	title.Draw(d.screen)
+
+	d.show()
+}
+
+// ScrollDetails scrolls the details pane by delta lines, where a positive delta scrolls down
+func (d *TerminalDashboard) ScrollDetails(delta int) {
+	d.detailsMux.Lock()
+	d.detailsPane.Scroll(delta)
+	d.detailsPane.Draw(d.screen)
+	d.detailsMux.Unlock()
+
+	d.show()
+}
+
+// refreshDetails redraws the details pane, e.g. after something was drawn over it
+func (d *TerminalDashboard) refreshDetails() {
+	d.detailsMux.Lock()
+	defer d.detailsMux.Unlock()
+
+	d.detailsPane.Draw(d.screen)
+}
+
+// formatDetails lists when track was posted, its tags, and the paragraphs of its description wrapped to width
+func formatDetails(track *chipmusic.Track, width int) []string {
+	var lines []string
+	if !track.PostedAt.IsZero() {
+		lines = append(lines, fmt.Sprintf("Posted: %s", track.PostedAt.Format(postedAtLayout)))
+	}
+
+	if len(track.Tags) > 0 {
+		lines = append(lines, wrapText("Tags: "+strings.Join(track.Tags, ", "), width)...)
+	}
+
+	for _, paragraph := range strings.Split(track.Description, "\n") {
+		if strings.TrimSpace(paragraph) == "" {
+			continue
+		}
+
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+
+		lines = append(lines, wrapText(paragraph, width)...)
+	}
+
+	if len(lines) == 0 {
+		return []string{"No description"}
+	}
+
+	return lines
+}
+
+// wrapText breaks text into lines of at most width characters between words. Words longer than width are broken
+// wherever the line is full
+func wrapText(text string, width int) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(text) {
+		for utf8.RuneCountInString(word) > width {
+			if line != "" {
+				lines = append(lines, line)
+				line = ""
+			}
+
+			runes := []rune(word)
+			lines = append(lines, string(runes[:width]))
+			word = string(runes[width:])
+		}
+
+		switch {
+		case line == "":
+			line = word
+		case utf8.RuneCountInString(line)+1+utf8.RuneCountInString(word) <= width:
+			line += " " + word
+		default:
+			lines = append(lines, line)
+			line = word
+		}
+	}
+
+	if line != "" {
+		lines = append(lines, line)
+	}
+
+	return lines
+}
@@ -0,0 +1,91 @@
+package dashboard
+
+import (
+	"github.com/broar/chipmusic-cli/pkg/chipmusic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWrapText(t *testing.T) {
+	testCases := []struct {
+		name     string
+		text     string
+		width    int
+		expected []string
+	}{
+		{"Empty", "  ", 10, nil},
+		{"Short", "some text", 10, []string{"some text"}},
+		{"Wrapped", "some more text to wrap", 10, []string{"some more", "text to", "wrap"}},
+		{"ExactWidth", "abcde fghij", 5, []string{"abcde", "fghij"}},
+		{"LongWord", "a abcdefghijkl b", 5, []string{"a", "abcde", "fghij", "kl b"}},
+		{"Runes", "ääää öööö", 4, []string{"ääää", "öööö"}},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			assert.Equal(tt, testCase.expected, wrapText(testCase.text, testCase.width))
+		})
+	}
+}
+
+func TestFormatDetails(t *testing.T) {
+	testCases := []struct {
+		name     string
+		track    *chipmusic.Track
+		expected []string
+	}{
+		{"Empty", &chipmusic.Track{}, []string{"No description"}},
+		{
+			"Everything",
+			&chipmusic.Track{
+				PostedAt:    time.Date(2020, 9, 6, 12, 0, 0, 0, time.UTC),
+				Tags:        []string{"lsdj", "gameboy"},
+				Description: "first paragraph\n\n\nsecond paragraph is long",
+			},
+			[]string{"Posted: Sep 6, 2020", "Tags: lsdj,", "gameboy", "", "first", "paragraph", "", "second", "paragraph", "is long"},
+		},
+		{"DescriptionOnly", &chipmusic.Track{Description: "some text"}, []string{"some text"}},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			assert.Equal(tt, testCase.expected, formatDetails(testCase.track, 11))
+		})
+	}
+}
+
+func TestTerminalDashboard_UpdateDetails(t *testing.T) {
+	db, err := NewTerminalDashboard(WithScreen(&MockScreen{}))
+	require.NoError(t, err)
+
+	defer db.Close()
+
+	words := make([]string, 0, detailsPaneWidth*detailsPaneHeight)
+	for i := 0; i < cap(words); i++ {
+		words = append(words, strconv.Itoa(i))
+	}
+
+	description := strings.Join(words, " ")
+	db.UpdateCurrentTrack(&chipmusic.Track{Title: "some.title", Artist: "some.artist", Description: description})
+	assert.Equal(t, detailsTitle, db.widgets[detailsTitleID].base.drawing[0])
+
+	visible := db.detailsPane.Visible()
+	require.Len(t, visible, detailsPaneHeight)
+	for _, line := range visible {
+		assert.True(t, len(line) <= detailsPaneWidth, "expected %q to fit the pane", line)
+	}
+
+	db.ScrollDetails(1)
+	assert.Equal(t, visible[1:], db.detailsPane.Visible()[:detailsPaneHeight-1])
+	assert.NotEqual(t, visible[0], db.detailsPane.Visible()[0])
+
+	db.ScrollDetails(-5)
+	assert.Equal(t, visible, db.detailsPane.Visible())
+
+	db.UpdateDetails(&chipmusic.Track{})
+	assert.Equal(t, []string{"No description"}, db.detailsPane.Visible())
+}
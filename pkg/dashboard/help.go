@@ -0,0 +1,116 @@
+package dashboard
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	helpTitle = "Help"
+
+	// helpModalHeight is how many lines of the help overlay are shown at a time
+	helpModalHeight = 14
+)
+
+// ErrNilSettings is an error returned when attempting to use a nil settings function for a TerminalDashboard
+var ErrNilSettings = errors.New("settings cannot be nil")
+
+// Setting is a setting of the session listed in the help overlay, e.g. the volume
+type Setting struct {
+	Name  string
+	Value string
+}
+
+// fixedKeys are the keys of the dashboard which aren't part of the keymap, with what they do
+var fixedKeys = []struct {
+	keys        string
+	description string
+}{
+	{"Left/Right", "select a track control"},
+	{"Enter", "perform the selected track control"},
+	{"Up/Down", "move the cursor of the queue"},
+	{"PgUp/PgDn", "scroll the queue"},
+	{"1-9", "jump to an upcoming track"},
+	{"Esc", "quit, or close the search box"},
+}
+
+// WithSettings allows listing the current settings of the session in the help overlay. settings is called each time
+// the overlay is opened
+func WithSettings(settings func() []Setting) Option {
+	return func(dashboard *TerminalDashboard) error {
+		if settings == nil {
+			return ErrNilSettings
+		}
+
+		dashboard.settings = settings
+		return nil
+	}
+}
+
+// ToggleHelp opens the help overlay, which lists every key binding and the current settings. If a modal is already
+// open, this method closes it instead
+func (d *TerminalDashboard) ToggleHelp() {
+	d.modalMux.Lock()
+	open := d.modal != nil
+	d.modalMux.Unlock()
+
+	if open {
+		d.CloseModal()
+		return
+	}
+
+	var settings []Setting
+	if d.settings != nil {
+		settings = d.settings()
+	}
+
+	lines := formatHelp(d.keymap, settings)
+	d.OpenModal(NewTextModal(0, statsOverlayY, helpModalHeight, helpTitle, lines, d.theme.Text, '?', 'q'))
+}
+
+// formatHelp lists the keys bound to each binding of keymap, then the keys outside of the keymap, then settings
+func formatHelp(keymap Keymap, settings []Setting) []string {
+	keys := map[string][]string{}
+	for key, binding := range keymap {
+		keys[binding] = append(keys[binding], formatKey(key))
+	}
+
+	names := make([]string, 0, len(keys))
+	for binding := range keys {
+		sort.Strings(keys[binding])
+		names = append(names, binding)
+	}
+
+	sort.Strings(names)
+
+	lines := []string{"Keys"}
+	for _, binding := range names {
+		lines = append(lines, fmt.Sprintf("  %-12s %s", strings.Join(keys[binding], " "), binding))
+	}
+
+	for _, key := range fixedKeys {
+		lines = append(lines, fmt.Sprintf("  %-12s %s", key.keys, key.description))
+	}
+
+	if len(settings) == 0 {
+		return lines
+	}
+
+	lines = append(lines, "", "Settings")
+	for _, setting := range settings {
+		lines = append(lines, fmt.Sprintf("  %-12s %s", setting.Name, setting.Value))
+	}
+
+	return lines
+}
+
+// formatKey returns how key is written in keymap configurations
+func formatKey(key rune) string {
+	if key == ' ' {
+		return keySpace
+	}
+
+	return string(key)
+}
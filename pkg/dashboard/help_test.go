@@ -0,0 +1,42 @@
+package dashboard
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestFormatHelp(t *testing.T) {
+	keymap := Keymap{' ': TrackControlPause, 'p': TrackControlPause, 'q': BindingQuit}
+	lines := formatHelp(keymap, []Setting{{Name: "Volume", Value: "40%"}})
+
+	assert.Equal(t, []string{
+		"Keys",
+		"  p space      pause",
+		"  q            quit",
+	}, lines[:3])
+	assert.Len(t, lines, 3+len(fixedKeys)+3)
+	assert.Equal(t, []string{"", "Settings", "  Volume       40%"}, lines[len(lines)-3:])
+
+	assert.Len(t, formatHelp(keymap, nil), 3+len(fixedKeys), "expected no settings section without settings")
+}
+
+func TestTerminalDashboard_ToggleHelp(t *testing.T) {
+	_, err := NewTerminalDashboard(WithScreen(&MockScreen{}), WithSettings(nil))
+	assert.Equal(t, ErrNilSettings, err)
+
+	db, err := NewTerminalDashboard(WithScreen(&MockScreen{}), WithSettings(func() []Setting {
+		return []Setting{{Name: "Volume", Value: "40%"}}
+	}))
+	require.NoError(t, err)
+
+	defer db.Close()
+
+	db.handleBinding(BindingHelp)
+	modal, ok := db.modal.(*TextModal)
+	require.True(t, ok, "expected the help overlay to be open")
+	assert.Contains(t, modal.lines, "  Volume       40%")
+
+	db.ToggleHelp()
+	assert.Nil(t, db.modal)
+}
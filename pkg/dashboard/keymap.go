@@ -0,0 +1,170 @@
+package dashboard
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// BindingQuit, BindingStats, BindingSearch, BindingJump, and BindingHelp are bound to keys like the track controls,
+	// but they are handled by the dashboard instead of being sent as actions. BindingJump plays the upcoming track under
+	// the cursor of the queue pane
+	BindingQuit   = "quit"
+	BindingStats  = "stats"
+	BindingSearch = "search"
+	BindingJump   = "jump"
+	BindingHelp   = "help"
+
+	// BindingSeekBack and BindingSeekForward move the current track back and forward by SeekStep
+	BindingSeekBack    = "seek-back"
+	BindingSeekForward = "seek-forward"
+
+	// keySpace is the name of the space bar in keymap configurations, since a space is hard to read and is trimmed by
+	// config formats
+	keySpace = "space"
+)
+
+var (
+	// bindings are every name a key can be bound to in a Keymap
+	bindings = map[string]bool{
+		TrackControlPlay:       true,
+		TrackControlPause:      true,
+		TrackControlStop:       true,
+		TrackControlLoop:       true,
+		TrackControlSkip:       true,
+		TrackControlCrossfeed:  true,
+		TrackControlNormalize:  true,
+		TrackControlVolumeUp:   true,
+		TrackControlVolumeDown: true,
+		BindingQuit:            true,
+		BindingStats:           true,
+		BindingSearch:          true,
+		BindingJump:            true,
+		BindingHelp:            true,
+		BindingDetailsUp:       true,
+		BindingDetailsDown:     true,
+		BindingLog:             true,
+		BindingSeekBack:        true,
+		BindingSeekForward:     true,
+	}
+)
+
+// Keymap binds keys to track controls and to the bindings of the dashboard, e.g. ' ' to TrackControlPause. Keys are
+// case-sensitive. The arrow keys, Enter, Escape, and the digits jumping to upcoming tracks are not part of the keymap
+type Keymap map[rune]string
+
+// DefaultKeymap returns the keys the dashboard uses unless they are configured
+func DefaultKeymap() Keymap {
+	return Keymap{
+		' ': TrackControlPause,
+		's': TrackControlStop,
+		'l': TrackControlLoop,
+		'n': TrackControlSkip,
+		'c': TrackControlCrossfeed,
+		'N': TrackControlNormalize,
+		'+': TrackControlVolumeUp,
+		'=': TrackControlVolumeUp,
+		'-': TrackControlVolumeDown,
+		'_': TrackControlVolumeDown,
+		'q': BindingQuit,
+		'S': BindingStats,
+		'/': BindingSearch,
+		'j': BindingJump,
+		'J': BindingJump,
+		'?': BindingHelp,
+		'[': BindingDetailsUp,
+		']': BindingDetailsDown,
+		'L': BindingLog,
+		',': BindingSeekBack,
+		'.': BindingSeekForward,
+	}
+}
+
+// ParseKeymap returns the default keymap with the keys of some bindings replaced. overrides maps the name of a binding
+// to a comma-separated list of the keys bound to it, where each key is a single character or "space", e.g.
+// {"pause": "space,p"}. An empty list unbinds every key of the binding. A key bound to another binding by default is
+// taken from it
+func ParseKeymap(overrides map[string]string) (Keymap, error) {
+	keymap := DefaultKeymap()
+
+	// Sorting the overrides makes the result the same when two of them bind the same key
+	names := make([]string, 0, len(overrides))
+	for name := range overrides {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	for _, name := range names {
+		if !bindings[name] {
+			return nil, fmt.Errorf("unknown key binding %q", name)
+		}
+
+		for key, bound := range keymap {
+			if bound == name {
+				delete(keymap, key)
+			}
+		}
+
+		for _, key := range strings.Split(overrides[name], ",") {
+			if strings.TrimSpace(key) == "" {
+				continue
+			}
+
+			r, err := parseKey(key)
+			if err != nil {
+				return nil, fmt.Errorf("invalid key for %s: %w", name, err)
+			}
+
+			keymap[r] = name
+		}
+	}
+
+	return keymap, nil
+}
+
+// parseKey returns the rune typed by key, which is a single character or "space"
+func parseKey(key string) (rune, error) {
+	if strings.EqualFold(strings.TrimSpace(key), keySpace) {
+		return ' ', nil
+	}
+
+	key = strings.TrimSpace(key)
+	if utf8.RuneCountInString(key) != 1 {
+		return 0, fmt.Errorf("%q is not a single character", key)
+	}
+
+	r, _ := utf8.DecodeRuneInString(key)
+	return r, nil
+}
+
+// handleBinding performs what a key bound to binding does, other than quitting. Track controls are sent as actions
+func (d *TerminalDashboard) handleBinding(binding string) {
+	switch binding {
+	case BindingSeekBack:
+		d.actions <- SeekAction{Offset: -SeekStep}
+	case BindingSeekForward:
+		d.actions <- SeekAction{Offset: SeekStep}
+	case BindingStats:
+		d.ToggleStats()
+	case BindingSearch:
+		d.OpenSearch()
+	case BindingHelp:
+		d.ToggleHelp()
+	case BindingDetailsUp:
+		d.ScrollDetails(-1)
+	case BindingDetailsDown:
+		d.ScrollDetails(1)
+	case BindingLog:
+		d.ToggleLog()
+	case BindingJump:
+		if action, ok := d.cursorJumpAction(); ok {
+			d.actions <- action
+		}
+	default:
+		if action, ok := controlActions[binding]; ok {
+			d.actions <- action
+		}
+	}
+}
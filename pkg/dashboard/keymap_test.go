@@ -0,0 +1,76 @@
+package dashboard
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestParseKeymap(t *testing.T) {
+	keymap, err := ParseKeymap(nil)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultKeymap(), keymap)
+
+	keymap, err = ParseKeymap(map[string]string{
+		TrackControlPause: "p, space",
+		TrackControlSkip:  ">",
+		TrackControlStop:  "n",
+		BindingStats:      "",
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, TrackControlPause, keymap['p'])
+	assert.Equal(t, TrackControlPause, keymap[' '])
+	assert.Equal(t, TrackControlSkip, keymap['>'])
+	assert.Equal(t, TrackControlStop, keymap['n'], "expected a key bound by default to be taken by the override")
+	assert.NotContains(t, keymap, 's', "expected the default key of an override to be unbound")
+	assert.NotContains(t, keymap, 'S', "expected an empty override to unbind every key")
+	assert.Equal(t, TrackControlVolumeUp, keymap['+'], "expected other bindings to keep their default keys")
+}
+
+func TestParseKeymap_Invalid(t *testing.T) {
+	testCases := []struct {
+		name      string
+		overrides map[string]string
+	}{
+		{"UnknownBinding", map[string]string{"some.binding": "x"}},
+		{"SeveralCharacters", map[string]string{TrackControlPause: "xy"}},
+		{"UnknownKeyName", map[string]string{TrackControlPause: "enter"}},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			_, err := ParseKeymap(testCase.overrides)
+			assert.Error(tt, err)
+		})
+	}
+}
+
+func TestTerminalDashboard_HandleBinding(t *testing.T) {
+	db, err := NewTerminalDashboard(WithScreen(&MockScreen{}))
+	require.NoError(t, err)
+
+	defer db.Close()
+
+	actions := make(chan Action, 1)
+	go func() {
+		for action := range db.Actions() {
+			actions <- action
+		}
+	}()
+
+	db.handleBinding(db.keymap[' '])
+	assert.Equal(t, PauseAction{}, <-actions)
+
+	db.handleBinding(db.keymap['n'])
+	assert.Equal(t, SkipAction{}, <-actions)
+
+	db.handleBinding(db.keymap[','])
+	assert.Equal(t, SeekAction{Offset: -SeekStep}, <-actions)
+
+	db.handleBinding(db.keymap['S'])
+	assert.True(t, db.statsVisible, "expected stats to be shown instead of sending an action")
+
+	_, err = NewTerminalDashboard(WithScreen(&MockScreen{}), WithKeymap(nil))
+	assert.Equal(t, ErrNilKeymap, err)
+}
@@ -0,0 +1,127 @@
+package dashboard
+
+import (
+	"errors"
+	"fmt"
+	"github.com/broar/chipmusic-cli/pkg/events"
+)
+
+const (
+	// BindingLog shows or hides the log pane
+	BindingLog = "log"
+
+	// DefaultLogLines is how many lines the log pane shows unless it is configured
+	DefaultLogLines = 8
+
+	logTitleID = "log"
+
+	// logPaneY is the row of the title of the log pane, below the details pane. The lines of the log are listed below it
+	logPaneY = 1 + detailsPaneHeight + 1
+
+	// logTimeLayout is how the time of each line of the log is shown
+	logTimeLayout = "15:04:05"
+)
+
+// ErrInvalidLogLines is an error returned when attempting to show fewer than one line in the log pane
+var ErrInvalidLogLines = errors.New("log lines must be greater than 0")
+
+// WithLogLines allows overriding how many of the latest lines the log pane shows
+func WithLogLines(lines int) Option {
+	return func(dashboard *TerminalDashboard) error {
+		if lines <= 0 {
+			return ErrInvalidLogLines
+		}
+
+		dashboard.logLines = lines
+		return nil
+	}
+}
+
+// Log adds a line to the log pane, e.g. "12:04:05 retry: ...". Only the latest lines are kept. While the pane is
+// hidden, its title counts the lines added since it was last shown
+func (d *TerminalDashboard) Log(level, message string) {
+	d.logMux.Lock()
+	line := truncateText(fmt.Sprintf("%s %s: %s", d.now().Format(logTimeLayout), level, message), detailsPaneWidth)
+	d.log = append(d.log, line)
+	if len(d.log) > d.logLines {
+		d.log = d.log[len(d.log)-d.logLines:]
+	}
+
+	if !d.logVisible {
+		d.logUnseen++
+	}
+
+	d.refreshLog()
+	d.logMux.Unlock()
+
+	d.show()
+}
+
+// ToggleLog shows the log pane. If the log pane is already shown, this method hides it
+func (d *TerminalDashboard) ToggleLog() {
+	d.logMux.Lock()
+	d.logVisible = !d.logVisible
+	d.logUnseen = 0
+	d.refreshLog()
+	d.logMux.Unlock()
+
+	d.show()
+}
+
+// refreshLog redraws the title of the log pane and its lines if it is shown. The log must be locked by the caller
+func (d *TerminalDashboard) refreshLog() {
+	title := d.widgets[logTitleID]
+	title.Clear(d.screen)
+	title.SetText(formatLogTitle(d.logVisible, d.logUnseen))
+	title.Draw(d.screen)
+
+	if !d.logVisible {
+		d.logPane.Clear(d.screen)
+		return
+	}
+
+	d.logPane.SetItems(d.log, -1)
+	d.logPane.Draw(d.screen)
+}
+
+// formatLogTitle returns the title of the log pane, which says how to show or hide it and how many lines were added
+// while it was hidden
+func formatLogTitle(visible bool, unseen int) string {
+	switch {
+	case visible:
+		return "▾ Log (L to hide)"
+	case unseen > 0:
+		return fmt.Sprintf("▸ Log, %d new (L to show)", unseen)
+	default:
+		return "▸ Log (L to show)"
+	}
+}
+
+// logEntry returns the level and message of the line logged for event. It returns false for events which aren't
+// logged
+func logEntry(event events.Event) (string, string, bool) {
+	switch event := event.(type) {
+	case events.Error:
+		return "error", formatError(event), true
+	case events.TrackSkipped:
+		return "skip", formatTrackSkipped(event), true
+	case events.Retry:
+		return "retry", fmt.Sprintf("attempt %d of %s failed: %v", event.Attempt, event.URL, event.Err), true
+	case events.PlaybackInterrupted:
+		return "pause", event.Reason.Error(), true
+	case events.Notice:
+		return "info", event.Message, true
+	default:
+		return "", "", false
+	}
+}
+
+// truncateText cuts text to at most width characters, ending it with an ellipsis if anything was cut
+func truncateText(text string, width int) string {
+	runes := []rune(text)
+	if len(runes) <= width {
+		return text
+	}
+
+	return string(runes[:width-1]) + "…"
+}
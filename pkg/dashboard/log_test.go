@@ -0,0 +1,81 @@
+package dashboard
+
+import (
+	"errors"
+	"github.com/broar/chipmusic-cli/pkg/chipmusic"
+	"github.com/broar/chipmusic-cli/pkg/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithLogLines(t *testing.T) {
+	db, err := NewTerminalDashboard(WithScreen(&MockScreen{}), WithLogLines(0))
+	assert.Equal(t, ErrInvalidLogLines, err)
+	assert.Nil(t, db)
+
+	db, err = NewTerminalDashboard(WithScreen(&MockScreen{}), WithLogLines(3))
+	require.NoError(t, err)
+	assert.Equal(t, 3, db.logLines)
+	assert.Equal(t, 3, db.logPane.height)
+}
+
+func TestTerminalDashboard_Log(t *testing.T) {
+	db, err := NewTerminalDashboard(WithScreen(&MockScreen{}), WithLogLines(2))
+	require.NoError(t, err)
+
+	defer db.Close()
+
+	db.now = func() time.Time {
+		return time.Date(2020, 9, 13, 12, 4, 5, 0, time.UTC)
+	}
+
+	title := db.widgets[logTitleID]
+	assert.Equal(t, []string{"▸ Log (L to show)"}, title.base.drawing, "expected the log pane to be hidden by default")
+
+	db.Log("error", "first")
+	db.Log("skip", "second")
+	db.Log("retry", "third")
+	assert.Equal(t, []string{"▸ Log, 3 new (L to show)"}, title.base.drawing)
+	assert.Empty(t, db.logPane.Visible(), "expected the lines not to be listed while the pane is hidden")
+
+	db.ToggleLog()
+	assert.Equal(t, []string{"▾ Log (L to hide)"}, title.base.drawing)
+	assert.Equal(t, []string{"12:04:05 skip: second", "12:04:05 retry: third"}, db.logPane.Visible(), "expected only the latest lines to be kept")
+
+	db.ToggleLog()
+	assert.Equal(t, []string{"▸ Log (L to show)"}, title.base.drawing, "expected lines seen before hiding the pane not to count as new")
+
+	db.Log("info", strings.Repeat("a", 2*detailsPaneWidth))
+	assert.Len(t, []rune(db.log[len(db.log)-1]), detailsPaneWidth, "expected long lines to be cut to the width of the pane")
+	assert.True(t, strings.HasSuffix(db.log[len(db.log)-1], "…"))
+}
+
+func TestLogEntry(t *testing.T) {
+	track := &chipmusic.Track{Title: "some.title", Artist: "some.artist"}
+	testCases := []struct {
+		name            string
+		event           events.Event
+		expectedLevel   string
+		expectedMessage string
+		expectedOK      bool
+	}{
+		{"Error", events.Error{Err: errors.New("some.error")}, "error", "Error: some.error", true},
+		{"TrackSkipped", events.TrackSkipped{Track: track, Reason: errors.New("skipped because download is empty")}, "skip", "some.title by some.artist: skipped because download is empty", true},
+		{"Retry", events.Retry{URL: "some.url", Attempt: 2, Err: errors.New("503 Service Unavailable")}, "retry", "attempt 2 of some.url failed: 503 Service Unavailable", true},
+		{"PlaybackInterrupted", events.PlaybackInterrupted{Reason: errors.New("the system was suspended")}, "pause", "the system was suspended", true},
+		{"Notice", events.Notice{Message: "Queued some.title by some.artist"}, "info", "Queued some.title by some.artist", true},
+		{"IgnoredEvent", events.PlaybackStarted{Track: track}, "", "", false},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			level, message, ok := logEntry(testCase.event)
+			assert.Equal(tt, testCase.expectedLevel, level)
+			assert.Equal(tt, testCase.expectedMessage, message)
+			assert.Equal(tt, testCase.expectedOK, ok)
+		})
+	}
+}
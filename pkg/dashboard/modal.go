@@ -0,0 +1,246 @@
+package dashboard
+
+import (
+	"fmt"
+	"github.com/gdamore/tcell/v2"
+	"strings"
+)
+
+const (
+	// modalMinWidth is the narrowest a TextModal is drawn, so short text still gets a readable box
+	modalMinWidth = 28
+
+	// modalScrollHint is shown in the bottom border of a TextModal whose text doesn't fit in it
+	modalScrollHint = " Up/Down to scroll "
+)
+
+// Modal is a window drawn over the dashboard. While a modal is open it captures every key except Ctrl+C, so keys don't
+// control playback until it is closed
+type Modal interface {
+	Drawer
+
+	// HandleKey handles a key pressed while the modal is open. It returns false once the modal should be closed
+	HandleKey(event *tcell.EventKey) bool
+}
+
+// OpenModal draws modal over the dashboard and sends it every key until it is closed. A modal which is already open is
+// replaced
+func (d *TerminalDashboard) OpenModal(modal Modal) {
+	d.modalMux.Lock()
+	previous := d.modal
+	d.modal = modal
+	d.modalMux.Unlock()
+
+	if previous != nil {
+		previous.Clear(d.screen)
+		d.redraw()
+	}
+
+	d.show()
+}
+
+// CloseModal closes the open modal and draws the dashboard under it again. If no modal is open, this method does
+// nothing
+func (d *TerminalDashboard) CloseModal() {
+	d.modalMux.Lock()
+	modal := d.modal
+	d.modal = nil
+	d.modalMux.Unlock()
+
+	if modal == nil {
+		return
+	}
+
+	modal.Clear(d.screen)
+	d.redraw()
+	d.show()
+}
+
+// handleModalKey sends a key to the open modal and closes the modal once it is done. It returns false if no modal is
+// open, so the key should be handled as usual
+func (d *TerminalDashboard) handleModalKey(event *tcell.EventKey) bool {
+	d.modalMux.Lock()
+	modal := d.modal
+	d.modalMux.Unlock()
+
+	if modal == nil {
+		return false
+	}
+
+	if !modal.HandleKey(event) {
+		d.CloseModal()
+		return true
+	}
+
+	d.show()
+	return true
+}
+
+// show draws the open modal over the dashboard, since the dashboard may have been updated under it, and shows the
+// screen. While the terminal is too small, whatever was drawn is replaced by a message asking for a bigger terminal
+func (d *TerminalDashboard) show() {
+	if d.isTooSmall() {
+		d.drawTooSmall()
+		d.screen.Show()
+		return
+	}
+
+	d.modalMux.Lock()
+	if d.modal != nil {
+		d.modal.Draw(d.screen)
+	}
+
+	d.modalMux.Unlock()
+
+	d.screen.Show()
+}
+
+// redraw draws every part of the dashboard again, e.g. once a modal covering it is closed
+func (d *TerminalDashboard) redraw() {
+	for _, widget := range d.widgets {
+		widget.Draw(d.screen)
+	}
+
+	d.refreshQueue()
+	d.refreshStats()
+
+	d.logMux.Lock()
+	d.refreshLog()
+	d.logMux.Unlock()
+
+	d.toastMux.Lock()
+	d.refreshToasts()
+	d.toastMux.Unlock()
+
+	d.searchMux.Lock()
+	if d.searchState != searchClosed {
+		d.searchInput.Draw(d.screen)
+		d.searchPane.Draw(d.screen)
+	}
+
+	d.searchMux.Unlock()
+}
+
+// TextModal is a Modal showing lines of text in a box with a title. Text longer than the box is scrolled with the
+// arrow keys, and Escape or any of the close keys closes it
+type TextModal struct {
+	Coordinate
+	title     string
+	lines     []string
+	height    int
+	offset    int
+	closeKeys []rune
+	style     tcell.Style
+
+	// drawn is what was drawn last, so it can be cleared even after scrolling
+	drawn *Widget
+}
+
+// NewTextModal returns a TextModal drawn with style at the x-y offset which shows height lines of text at a time
+func NewTextModal(x, y, height int, title string, lines []string, style tcell.Style, closeKeys ...rune) *TextModal {
+	return &TextModal{
+		Coordinate: Coordinate{x, y},
+		title:      title,
+		lines:      lines,
+		height:     height,
+		closeKeys:  closeKeys,
+		style:      style,
+	}
+}
+
+func (m *TextModal) HandleKey(event *tcell.EventKey) bool {
+	switch event.Key() {
+	case tcell.KeyEscape:
+		return false
+	case tcell.KeyUp:
+		m.Scroll(-1)
+	case tcell.KeyDown:
+		m.Scroll(1)
+	case tcell.KeyPgUp:
+		m.Scroll(-m.height)
+	case tcell.KeyPgDn:
+		m.Scroll(m.height)
+	case tcell.KeyRune:
+		for _, key := range m.closeKeys {
+			if event.Rune() == key {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// Scroll moves the lines shown by delta, where a positive delta scrolls down. The text can't be scrolled past its first
+// or last line
+func (m *TextModal) Scroll(delta int) {
+	m.offset += delta
+	if max := len(m.lines) - m.height; m.offset > max {
+		m.offset = max
+	}
+
+	if m.offset < 0 {
+		m.offset = 0
+	}
+}
+
+// Visible returns the lines shown at the current scroll position
+func (m *TextModal) Visible() []string {
+	end := m.offset + m.height
+	if end > len(m.lines) {
+		end = len(m.lines)
+	}
+
+	return m.lines[m.offset:end]
+}
+
+func (m *TextModal) Draw(screen tcell.Screen) {
+	m.Clear(screen)
+	m.drawn = NewWidget(m.X, m.Y, m.drawing(), m.style)
+	m.drawn.Draw(screen)
+}
+
+func (m *TextModal) Clear(screen tcell.Screen) {
+	if m.drawn == nil {
+		return
+	}
+
+	m.drawn.Clear(screen)
+	m.drawn = nil
+}
+
+// drawing returns the box with the visible lines in it. The box is as wide as the longest line so it doesn't change
+// width while scrolling
+func (m *TextModal) drawing() []string {
+	width := modalMinWidth
+	for _, line := range m.lines {
+		if n := len([]rune(line)) + 4; n > width {
+			width = n
+		}
+	}
+
+	if n := len([]rune(m.title)) + 6; n > width {
+		width = n
+	}
+
+	drawing := []string{modalBorder(" "+m.title+" ", width)}
+	for _, line := range m.Visible() {
+		drawing = append(drawing, fmt.Sprintf("| %-*s |", width-4, line))
+	}
+
+	hint := ""
+	if len(m.lines) > m.height {
+		hint = modalScrollHint
+	}
+
+	return append(drawing, modalBorder(hint, width))
+}
+
+// modalBorder returns a horizontal border of width with label near its start
+func modalBorder(label string, width int) string {
+	if label == "" || len([]rune(label))+4 > width {
+		return "+" + strings.Repeat("-", width-2) + "+"
+	}
+
+	return "+-" + label + strings.Repeat("-", width-len([]rune(label))-3) + "+"
+}
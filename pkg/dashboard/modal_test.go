@@ -0,0 +1,44 @@
+package dashboard
+
+import (
+	"github.com/gdamore/tcell/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestTextModal(t *testing.T) {
+	modal := NewTextModal(0, 0, 2, "some.title", []string{"first", "second", "third"}, tcell.StyleDefault, 'q')
+	assert.Equal(t, []string{"first", "second"}, modal.Visible())
+	assert.Equal(t, []string{
+		"+- some.title -------------+",
+		"| first                    |",
+		"| second                   |",
+		"+- Up/Down to scroll ------+",
+	}, modal.drawing())
+
+	assert.True(t, modal.HandleKey(tcell.NewEventKey(tcell.KeyDown, 0, tcell.ModNone)))
+	assert.True(t, modal.HandleKey(tcell.NewEventKey(tcell.KeyDown, 0, tcell.ModNone)))
+	assert.Equal(t, []string{"second", "third"}, modal.Visible(), "expected scrolling to stop at the last line")
+
+	assert.True(t, modal.HandleKey(tcell.NewEventKey(tcell.KeyRune, 'x', tcell.ModNone)))
+	assert.False(t, modal.HandleKey(tcell.NewEventKey(tcell.KeyRune, 'q', tcell.ModNone)))
+	assert.False(t, modal.HandleKey(tcell.NewEventKey(tcell.KeyEscape, 0, tcell.ModNone)))
+}
+
+func TestTerminalDashboard_Modal(t *testing.T) {
+	db, err := NewTerminalDashboard(WithScreen(&MockScreen{}))
+	require.NoError(t, err)
+
+	defer db.Close()
+
+	assert.False(t, db.handleModalKey(tcell.NewEventKey(tcell.KeyRune, 'a', tcell.ModNone)), "expected keys to control playback without a modal")
+
+	db.OpenModal(NewTextModal(0, 0, 2, "some.title", []string{"some.line"}, tcell.StyleDefault))
+	assert.True(t, db.handleModalKey(tcell.NewEventKey(tcell.KeyRune, ' ', tcell.ModNone)), "expected the modal to capture keys")
+	assert.NotNil(t, db.modal)
+
+	assert.True(t, db.handleModalKey(tcell.NewEventKey(tcell.KeyEscape, 0, tcell.ModNone)))
+	assert.Nil(t, db.modal, "expected Escape to close the modal")
+	assert.False(t, db.handleModalKey(tcell.NewEventKey(tcell.KeyRune, 'a', tcell.ModNone)))
+}
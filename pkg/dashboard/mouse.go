@@ -0,0 +1,150 @@
+package dashboard
+
+import (
+	"github.com/gdamore/tcell/v2"
+)
+
+// mouseScrollStep is how many rows a turn of the mouse wheel scrolls
+const mouseScrollStep = 1
+
+// handleMouse scrolls the pane under the pointer with the mouse wheel and handles clicks. Clicking a track control
+// performs it, and clicking an upcoming track or a search result selects it. While a modal is open, the wheel scrolls
+// the modal and clicks are ignored
+func (d *TerminalDashboard) handleMouse(event *tcell.EventMouse) {
+	x, y := event.Position()
+	buttons := event.Buttons()
+
+	// A button reports being pressed for as long as it is held, so only the press itself counts as a click
+	clicked := buttons&tcell.Button1 != 0 && d.mouseButtons&tcell.Button1 == 0
+	d.mouseButtons = buttons
+
+	delta := 0
+	switch {
+	case buttons&tcell.WheelUp != 0:
+		delta = -mouseScrollStep
+	case buttons&tcell.WheelDown != 0:
+		delta = mouseScrollStep
+	case !clicked:
+		return
+	}
+
+	switch {
+	case d.scrollModal(delta):
+	case delta != 0:
+		d.scrollAt(x, y, delta)
+	default:
+		d.clickAt(x, y)
+	}
+}
+
+// scrollModal scrolls the open modal by delta lines if it can be scrolled. It returns false if no modal is open, so
+// the mouse event should be handled as usual
+func (d *TerminalDashboard) scrollModal(delta int) bool {
+	d.modalMux.Lock()
+	modal := d.modal
+	d.modalMux.Unlock()
+
+	if modal == nil {
+		return false
+	}
+
+	if scroller, ok := modal.(interface{ Scroll(delta int) }); ok && delta != 0 {
+		scroller.Scroll(delta)
+		d.show()
+	}
+
+	return true
+}
+
+// scrollAt scrolls the pane at x-y by delta rows. Scrolling the search results moves their cursor, so the next page of
+// results is asked for as the wheel nears the end
+func (d *TerminalDashboard) scrollAt(x, y int, delta int) {
+	switch d.paneAt(x, y) {
+	case d.searchPane:
+		d.MoveSearchCursor(delta)
+	case d.detailsPane:
+		d.ScrollDetails(delta)
+	case d.queuePane:
+		d.ScrollQueue(delta)
+	}
+}
+
+// clickAt performs the track control or selects the upcoming track or search result at x-y
+func (d *TerminalDashboard) clickAt(x, y int) {
+	switch d.paneAt(x, y) {
+	case d.searchPane:
+		d.searchMux.Lock()
+		index, ok := d.searchPane.ItemAt(x, y)
+		delta := index - d.searchCursor
+		d.searchMux.Unlock()
+
+		if ok {
+			d.MoveSearchCursor(delta)
+		}
+
+		return
+	case d.queuePane:
+		d.queueMux.Lock()
+		index, ok := d.queuePane.ItemAt(x, y)
+		if d.current != nil {
+			// The current track is listed first, so it can't be selected
+			index--
+		}
+
+		delta := index - d.queueCursor
+		d.queueMux.Unlock()
+
+		if ok && index >= 0 {
+			d.MoveQueueCursor(delta)
+		}
+
+		return
+	case d.detailsPane:
+		return
+	}
+
+	for _, control := range trackControls {
+		widget := d.widgets[control]
+		if !widget.Contains(x, y) {
+			continue
+		}
+
+		old := d.widgets[d.selected]
+		old.SetStyle(d.theme.Text)
+		old.Draw(d.screen)
+		d.selected = control
+		widget.SetStyle(d.theme.Highlight)
+		widget.Draw(d.screen)
+		d.show()
+
+		d.actions <- controlActions[control]
+		return
+	}
+}
+
+// paneAt returns the pane drawn at x-y, or nil if there is none. The details pane is drawn over long entries of the
+// queue pane, so it comes first, and the search results only count while they are shown
+func (d *TerminalDashboard) paneAt(x, y int) *ListWidget {
+	d.searchMux.Lock()
+	inSearch := d.searchState == searchBrowsing && d.searchPane.Contains(x, y)
+	d.searchMux.Unlock()
+
+	d.detailsMux.Lock()
+	inDetails := d.detailsPane.Contains(x, y)
+	d.detailsMux.Unlock()
+
+	d.queueMux.Lock()
+	inQueue := d.queuePane.Contains(x, y)
+	d.queueMux.Unlock()
+
+	switch {
+	case inSearch:
+		return d.searchPane
+	case inDetails:
+		return d.detailsPane
+	case inQueue:
+		return d.queuePane
+	default:
+		return nil
+	}
+}
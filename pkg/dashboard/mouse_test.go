@@ -0,0 +1,135 @@
+package dashboard
+
+import (
+	"github.com/broar/chipmusic-cli/pkg/chipmusic"
+	"github.com/gdamore/tcell/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func mouseEvent(x, y int, buttons tcell.ButtonMask) *tcell.EventMouse {
+	return tcell.NewEventMouse(x, y, buttons, tcell.ModNone)
+}
+
+func TestTerminalDashboard_HandleMouse_Queue(t *testing.T) {
+	db, err := NewTerminalDashboard(WithScreen(&MockScreen{}))
+	require.NoError(t, err)
+
+	defer db.Close()
+
+	queue := make([]*chipmusic.Track, 0, 20)
+	for i := 0; i < cap(queue); i++ {
+		queue = append(queue, &chipmusic.Track{Title: strconv.Itoa(i), Artist: "some.artist"})
+	}
+
+	db.UpdateCurrentTrack(&chipmusic.Track{Title: "current.title", Artist: "current.artist"})
+	db.UpdateQueue(queue, nil)
+
+	db.handleMouse(mouseEvent(0, queuePaneY+1, tcell.WheelDown))
+	db.handleMouse(mouseEvent(0, queuePaneY+1, tcell.WheelDown))
+	assert.Equal(t, 2, db.queuePane.offset)
+	assert.Equal(t, 0, db.queueCursor, "expected the wheel to leave the cursor alone")
+
+	db.handleMouse(mouseEvent(0, queuePaneY+1, tcell.WheelUp))
+	assert.Equal(t, 1, db.queuePane.offset)
+
+	// The first row now shows the upcoming track at index 0, since the current track was scrolled away
+	db.handleMouse(mouseEvent(0, queuePaneY+3, tcell.Button1))
+	assert.Equal(t, 2, db.queueCursor)
+
+	db.handleMouse(mouseEvent(0, queuePaneY+4, tcell.Button1))
+	assert.Equal(t, 2, db.queueCursor, "expected holding the button not to click again")
+
+	db.handleMouse(mouseEvent(0, queuePaneY+4, tcell.ButtonNone))
+	db.handleMouse(mouseEvent(0, queuePaneY+4, tcell.Button1))
+	assert.Equal(t, 3, db.queueCursor)
+
+	db.handleMouse(mouseEvent(0, 0, tcell.WheelDown))
+	assert.Equal(t, 1, db.queuePane.offset, "expected the wheel outside of a pane to do nothing")
+}
+
+func TestTerminalDashboard_HandleMouse_Search(t *testing.T) {
+	db, err := NewTerminalDashboard(WithScreen(&MockScreen{}))
+	require.NoError(t, err)
+
+	defer db.Close()
+
+	actions := make(chan Action, 1)
+	go func() {
+		for action := range db.Actions() {
+			actions <- action
+		}
+	}()
+
+	db.OpenSearch()
+	db.handleSearchKey(tcell.NewEventKey(tcell.KeyRune, 'a', tcell.ModNone))
+	db.handleSearchKey(tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModNone))
+	<-actions
+
+	results := make([]chipmusic.SearchResult, 0, 10)
+	for i := 0; i < cap(results); i++ {
+		results = append(results, chipmusic.SearchResult{URL: strconv.Itoa(i)})
+	}
+
+	db.ShowSearchResults(results)
+	db.handleMouse(mouseEvent(5, searchPaneY+1, tcell.WheelDown))
+	assert.Equal(t, 1, db.searchCursor)
+
+	db.handleMouse(mouseEvent(5, searchPaneY+5, tcell.Button1))
+	assert.Equal(t, 4, db.searchCursor)
+
+	db.handleMouse(mouseEvent(5, searchPaneY+5, tcell.ButtonNone))
+	db.handleMouse(mouseEvent(5, searchPaneY+8, tcell.Button1))
+	assert.Equal(t, 7, db.searchCursor)
+	assert.Equal(t, SearchMoreAction{Query: "a", Page: 2}, <-actions)
+}
+
+func TestTerminalDashboard_HandleMouse_TrackControl(t *testing.T) {
+	db, err := NewTerminalDashboard(WithScreen(&MockScreen{}))
+	require.NoError(t, err)
+
+	defer db.Close()
+
+	actions := make(chan Action, 1)
+	go func() {
+		for action := range db.Actions() {
+			actions <- action
+		}
+	}()
+
+	skip := db.widgets[TrackControlSkip].base
+	db.handleMouse(mouseEvent(skip.X+1, skip.Y, tcell.Button1))
+	assert.Equal(t, SkipAction{}, <-actions)
+	assert.Equal(t, TrackControlSkip, db.selected)
+
+	db.handleMouse(mouseEvent(skip.X+1, skip.Y, tcell.ButtonNone))
+	db.handleMouse(mouseEvent(skip.X+len(TrackControlSkip), skip.Y, tcell.Button1))
+	select {
+	case action := <-actions:
+		t.Errorf("expected a click next to a track control to do nothing but got %v", action)
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
+func TestTerminalDashboard_HandleMouse_Modal(t *testing.T) {
+	db, err := NewTerminalDashboard(WithScreen(&MockScreen{}))
+	require.NoError(t, err)
+
+	defer db.Close()
+
+	lines := make([]string, 0, 20)
+	for i := 0; i < cap(lines); i++ {
+		lines = append(lines, strconv.Itoa(i))
+	}
+
+	modal := NewTextModal(0, 0, 5, "some.title", lines, tcell.StyleDefault)
+	db.OpenModal(modal)
+	db.handleMouse(mouseEvent(1, 1, tcell.WheelDown))
+	assert.Equal(t, "1", modal.Visible()[0])
+
+	db.handleMouse(mouseEvent(1, 1, tcell.WheelUp))
+	assert.Equal(t, "0", modal.Visible()[0])
+}
@@ -0,0 +1,136 @@
+package dashboard
+
+import (
+	"fmt"
+	"github.com/broar/chipmusic-cli/pkg/events"
+	"time"
+)
+
+const (
+	networkID = "network"
+
+	// networkX is the column of the network widget, to the right of the track timer
+	networkX = 16
+
+	// networkWindow is how far back the download throughput is averaged over, which smooths out the bursts of progress
+	// events while still dropping to zero soon after downloads stop
+	networkWindow = 3 * time.Second
+)
+
+// NetworkStats are the current download throughput and how many downloaded tracks are waiting to play, collected from
+// the progress events of downloads
+type NetworkStats struct {
+
+	// samples are the bytes downloaded at each progress event within the window
+	samples []networkSample
+
+	// busySince is when downloading last started after the network was idle for the whole window
+	busySince time.Time
+
+	// downloads is how many bytes were downloaded so far for each download URL still downloading, and ready holds the
+	// download URLs of tracks which finished downloading but haven't started playing
+	downloads map[string]int64
+	ready     map[string]bool
+}
+
+type networkSample struct {
+	at    time.Time
+	bytes int64
+}
+
+// Add updates the statistics with an event received at now. Events which don't affect the statistics are ignored
+func (n *NetworkStats) Add(event events.Event, now time.Time) {
+	switch event := event.(type) {
+	case events.DownloadProgress:
+		if n.downloads == nil {
+			n.downloads = map[string]int64{}
+			n.ready = map[string]bool{}
+		}
+
+		// Progress is reported as a running total, so a total lower than before means the URL is downloading again
+		previous := n.downloads[event.URL]
+		if event.Downloaded < previous {
+			previous = 0
+		}
+
+		n.prune(now)
+		if len(n.samples) == 0 {
+			n.busySince = now
+		}
+
+		n.samples = append(n.samples, networkSample{at: now, bytes: event.Downloaded - previous})
+		n.downloads[event.URL] = event.Downloaded
+
+		if event.Total > 0 && event.Downloaded >= event.Total {
+			delete(n.downloads, event.URL)
+			n.ready[event.URL] = true
+		}
+	case events.PlaybackStarted:
+		if event.Track != nil {
+			delete(n.ready, event.Track.DownloadURL)
+		}
+	case events.TrackSkipped:
+		if event.Track != nil {
+			delete(n.ready, event.Track.DownloadURL)
+		}
+	}
+}
+
+// Throughput returns how many bytes per second were downloaded at now, averaged over the last few seconds
+func (n *NetworkStats) Throughput(now time.Time) float64 {
+	n.prune(now)
+
+	var total int64
+	for _, sample := range n.samples {
+		total += sample.bytes
+	}
+
+	if total == 0 {
+		return 0
+	}
+
+	// Averaging over the whole window would understate the throughput of downloads which just started
+	span := now.Sub(n.busySince)
+	if span > networkWindow {
+		span = networkWindow
+	}
+
+	if span < time.Second {
+		span = time.Second
+	}
+
+	return float64(total) / span.Seconds()
+}
+
+// Queued returns how many tracks finished downloading but haven't started playing, e.g. tracks prefetched ahead of the
+// current one
+func (n *NetworkStats) Queued() int {
+	return len(n.ready)
+}
+
+// prune drops the samples which are older than the window at now
+func (n *NetworkStats) prune(now time.Time) {
+	i := 0
+	for i < len(n.samples) && now.Sub(n.samples[i].at) > networkWindow {
+		i++
+	}
+
+	n.samples = n.samples[i:]
+}
+
+// refreshNetwork redraws the network widget with the throughput at the current time
+func (d *TerminalDashboard) refreshNetwork() {
+	d.networkMux.Lock()
+	text := formatNetwork(&d.network, d.now())
+	d.networkMux.Unlock()
+
+	widget := d.widgets[networkID]
+	widget.Clear(d.screen)
+	widget.SetText(text)
+	widget.Draw(d.screen)
+}
+
+// formatNetwork describes the throughput and the tracks waiting to play, e.g. "↓ 1.2 MB/s, 2 queued"
+func formatNetwork(stats *NetworkStats, now time.Time) string {
+	return fmt.Sprintf("↓ %s/s, %d queued", formatBytes(int64(stats.Throughput(now))), stats.Queued())
+}
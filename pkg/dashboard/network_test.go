@@ -0,0 +1,81 @@
+package dashboard
+
+import (
+	"errors"
+	"github.com/broar/chipmusic-cli/pkg/chipmusic"
+	"github.com/broar/chipmusic-cli/pkg/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestNetworkStats_Throughput(t *testing.T) {
+	start := time.Unix(1600000000, 0)
+	stats := &NetworkStats{}
+
+	assert.Zero(t, stats.Throughput(start))
+
+	stats.Add(events.DownloadProgress{URL: "some.url", Downloaded: 1000, Total: 10000}, start)
+	assert.Equal(t, float64(1000), stats.Throughput(start), "expected a download which just started to be averaged over a second")
+
+	stats.Add(events.DownloadProgress{URL: "some.url", Downloaded: 5000, Total: 10000}, start.Add(2*time.Second))
+	assert.Equal(t, float64(2500), stats.Throughput(start.Add(2*time.Second)))
+
+	stats.Add(events.DownloadProgress{URL: "some.url", Downloaded: 2000, Total: 10000}, start.Add(3*time.Second))
+	assert.Equal(t, float64(7000)/3, stats.Throughput(start.Add(3*time.Second)), "expected a repeated download of a URL to be counted again")
+
+	assert.Equal(t, float64(2000)/3, stats.Throughput(start.Add(5500*time.Millisecond)), "expected samples older than the window to be dropped")
+	assert.Zero(t, stats.Throughput(start.Add(time.Minute)))
+}
+
+func TestNetworkStats_Queued(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	played := &chipmusic.Track{DownloadURL: "played.url"}
+	skipped := &chipmusic.Track{DownloadURL: "skipped.url"}
+	received := []events.Event{
+		events.DownloadProgress{URL: "played.url", Downloaded: 100, Total: 100},
+		events.DownloadProgress{URL: "skipped.url", Downloaded: 100, Total: 100},
+		events.DownloadProgress{URL: "prefetched.url", Downloaded: 100, Total: 100},
+		events.DownloadProgress{URL: "downloading.url", Downloaded: 50, Total: 100},
+		events.DownloadProgress{URL: "unknown.url", Downloaded: 50, Total: -1},
+	}
+
+	stats := &NetworkStats{}
+	for _, event := range received {
+		stats.Add(event, now)
+	}
+
+	assert.Equal(t, 3, stats.Queued(), "expected only finished downloads to be queued")
+
+	stats.Add(events.PlaybackStarted{Track: played}, now)
+	stats.Add(events.TrackSkipped{Track: skipped, Reason: errors.New("skipped because download is empty")}, now)
+	stats.Add(events.TrackSkipped{Reason: errors.New("skipped because it matches the blocklist")}, now)
+	assert.Equal(t, 1, stats.Queued())
+}
+
+func TestTerminalDashboard_HandleEventsNetwork(t *testing.T) {
+	db, err := NewTerminalDashboard(WithScreen(&MockScreen{}))
+	require.NoError(t, err)
+
+	defer db.Close()
+
+	now := time.Unix(1600000000, 0)
+	db.now = func() time.Time {
+		return now
+	}
+
+	assert.Equal(t, []string{"↓ 0 B/s, 0 queued"}, db.widgets[networkID].base.drawing)
+
+	ch := make(chan events.Event, 2)
+	ch <- events.DownloadProgress{URL: "some.url", Downloaded: 1258291, Total: 1258291}
+	ch <- events.DownloadProgress{URL: "other.url", Downloaded: 1024, Total: 4096}
+	close(ch)
+
+	db.HandleEvents(ch)
+	assert.Equal(t, []string{"↓ 1.2 MB/s, 1 queued"}, db.widgets[networkID].base.drawing)
+
+	now = now.Add(time.Minute)
+	db.UpdateTrackTimer(time.Second, time.Minute)
+	assert.Equal(t, []string{"↓ 0 B/s, 1 queued"}, db.widgets[networkID].base.drawing, "expected the throughput to drop once downloads stop")
+}
@@ -0,0 +1,153 @@
+package dashboard
+
+import (
+	"fmt"
+	"github.com/broar/chipmusic-cli/pkg/chipmusic"
+	"time"
+)
+
+const (
+	// TrackControlJump is the name of JumpAction
+	TrackControlJump = "jump"
+
+	queueTitleID = "queue"
+
+	// queuePaneY is the row where the queue pane is drawn, below the notice. The statistics overlay is drawn over it
+	queuePaneY = statsOverlayY
+
+	// queuePaneHeight is how many entries of the queue are shown at a time
+	queuePaneHeight = 8
+)
+
+// UpdateQueue shows the upcoming tracks in the queue pane along with when they start. startsIn holds how long after
+// the current track ends each track starts, or nil if that is unknown. The cursor stays on the same position, or the
+// last track if the queue got shorter
+func (d *TerminalDashboard) UpdateQueue(queue []*chipmusic.Track, startsIn []time.Duration) {
+	d.queueMux.Lock()
+	d.queue = queue
+	d.queueStartsIn = startsIn
+	if d.queueCursor >= len(queue) {
+		d.queueCursor = len(queue) - 1
+	}
+
+	if d.queueCursor < 0 {
+		d.queueCursor = 0
+	}
+
+	d.queueMux.Unlock()
+
+	d.refreshQueue()
+	d.show()
+}
+
+// MoveQueueCursor moves the cursor of the queue pane by delta tracks, where a positive delta moves it down, and scrolls
+// the pane to keep it in view. The cursor can't move past the first or last upcoming track
+func (d *TerminalDashboard) MoveQueueCursor(delta int) {
+	d.queueMux.Lock()
+	d.queueCursor += delta
+	if d.queueCursor >= len(d.queue) {
+		d.queueCursor = len(d.queue) - 1
+	}
+
+	if d.queueCursor < 0 {
+		d.queueCursor = 0
+	}
+
+	d.queuePane.ScrollTo(d.queueEntry(d.queueCursor))
+	d.queueMux.Unlock()
+
+	d.refreshQueue()
+	d.show()
+}
+
+// ScrollQueue scrolls the queue pane by delta entries without moving the cursor
+func (d *TerminalDashboard) ScrollQueue(delta int) {
+	d.queueMux.Lock()
+	d.queuePane.Scroll(delta)
+	d.queueMux.Unlock()
+
+	d.refreshQueue()
+	d.show()
+}
+
+// cursorJumpAction returns the action which jumps to the track under the cursor of the queue pane. It returns false if
+// the queue is empty
+func (d *TerminalDashboard) cursorJumpAction() (JumpAction, bool) {
+	d.queueMux.Lock()
+	defer d.queueMux.Unlock()
+
+	if len(d.queue) == 0 {
+		return JumpAction{}, false
+	}
+
+	return JumpAction{Position: d.queueCursor + 1}, true
+}
+
+// queueEntry returns the index of the entry in the queue pane of the upcoming track at index. The current track, if
+// any, is listed first. The queue must be locked by the caller
+func (d *TerminalDashboard) queueEntry(index int) int {
+	if d.current == nil {
+		return index
+	}
+
+	return index + 1
+}
+
+// refreshQueue redraws the queue pane along with the statistics overlay over it if it is shown. Long entries run under
+// the details pane, so it is drawn again too
+func (d *TerminalDashboard) refreshQueue() {
+	d.queueMux.Lock()
+	entries, highlighted := formatQueue(d.current, d.queue, d.queueStartsIn, d.remaining(), d.queueCursor)
+	d.queuePane.SetItems(entries, highlighted)
+	d.queuePane.Draw(d.screen)
+	d.queueMux.Unlock()
+
+	d.refreshStats()
+	d.refreshDetails()
+}
+
+// remaining returns how long the current track plays until it ends, or -1 if its length is unknown. The queue must be
+// locked by the caller
+func (d *TerminalDashboard) remaining() time.Duration {
+	if d.trackTotal <= 0 {
+		return -1
+	}
+
+	if d.trackPosition >= d.trackTotal {
+		return 0
+	}
+
+	return d.trackTotal - d.trackPosition
+}
+
+// formatQueue lists the current track, which is highlighted, followed by the numbered upcoming tracks with the one
+// under the cursor marked. Each upcoming track is estimated to start remaining plus its startsIn from now. If
+// remaining is negative or startsIn is missing the track, no estimate is shown
+func formatQueue(current *chipmusic.Track, queue []*chipmusic.Track, startsIn []time.Duration, remaining time.Duration, cursor int) ([]string, int) {
+	entries := make([]string, 0, len(queue)+1)
+	highlighted := -1
+	if current != nil {
+		highlighted = 0
+		entries = append(entries, fmt.Sprintf("  ▶ %s", formatQueueTrack(current)))
+	}
+
+	for i, track := range queue {
+		marker := " "
+		if i == cursor {
+			marker = ">"
+		}
+
+		entry := fmt.Sprintf("%s %d. %s", marker, i+1, formatQueueTrack(track))
+		if remaining >= 0 && i < len(startsIn) {
+			entry += fmt.Sprintf(" (in %s)", formatStopwatchTime(remaining+startsIn[i]))
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, highlighted
+}
+
+func formatQueueTrack(track *chipmusic.Track) string {
+	return fmt.Sprintf("%s — %s", track.Title, track.Artist)
+}
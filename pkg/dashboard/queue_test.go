@@ -0,0 +1,114 @@
+package dashboard
+
+import (
+	"github.com/broar/chipmusic-cli/pkg/chipmusic"
+	"github.com/broar/chipmusic-cli/pkg/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestFormatQueue(t *testing.T) {
+	current := &chipmusic.Track{Title: "current.title", Artist: "current.artist"}
+	queue := []*chipmusic.Track{
+		{Title: "first.title", Artist: "first.artist"},
+		{Title: "second.title", Artist: "second.artist"},
+	}
+
+	entries, highlighted := formatQueue(current, queue, nil, -1, 1)
+	assert.Equal(t, []string{
+		"  ▶ current.title — current.artist",
+		"  1. first.title — first.artist",
+		"> 2. second.title — second.artist",
+	}, entries)
+	assert.Equal(t, 0, highlighted)
+
+	entries, highlighted = formatQueue(nil, queue, nil, -1, 0)
+	assert.Equal(t, []string{
+		"> 1. first.title — first.artist",
+		"  2. second.title — second.artist",
+	}, entries)
+	assert.Equal(t, -1, highlighted)
+}
+
+func TestFormatQueue_StartsIn(t *testing.T) {
+	queue := []*chipmusic.Track{
+		{Title: "first.title", Artist: "first.artist"},
+		{Title: "second.title", Artist: "second.artist"},
+	}
+
+	entries, _ := formatQueue(nil, queue, []time.Duration{0, 3 * time.Minute}, 30*time.Second, -1)
+	assert.Equal(t, []string{
+		"  1. first.title — first.artist (in 0:30)",
+		"  2. second.title — second.artist (in 3:30)",
+	}, entries)
+
+	entries, _ = formatQueue(nil, queue, []time.Duration{0}, 30*time.Second, -1)
+	assert.Equal(t, "  2. second.title — second.artist", entries[1], "expected no estimate for a track of unknown start")
+}
+
+func TestTerminalDashboard_QueueStartsIn(t *testing.T) {
+	db, err := NewTerminalDashboard(WithScreen(&MockScreen{}))
+	require.NoError(t, err)
+
+	defer db.Close()
+
+	db.UpdateCurrentTrack(&chipmusic.Track{Title: "current", Artist: "some.artist"})
+	db.UpdateQueue([]*chipmusic.Track{{Title: "next", Artist: "some.artist"}}, []time.Duration{0})
+	assert.Equal(t, "> 1. next — some.artist", db.queuePane.Visible()[1], "expected no estimate before the length of the current track is known")
+
+	db.UpdateTrackTimer(time.Minute, 3*time.Minute)
+	assert.Equal(t, "> 1. next — some.artist (in 2:00)", db.queuePane.Visible()[1])
+
+	// The estimate counts down as the current track plays
+	db.UpdateTrackTimer(2*time.Minute, 3*time.Minute)
+	assert.Equal(t, "> 1. next — some.artist (in 1:00)", db.queuePane.Visible()[1])
+}
+
+func TestTerminalDashboard_QueuePane(t *testing.T) {
+	db, err := NewTerminalDashboard(WithScreen(&MockScreen{}))
+	require.NoError(t, err)
+
+	defer db.Close()
+
+	_, ok := db.cursorJumpAction()
+	assert.False(t, ok, "expected nothing to jump to in an empty queue")
+
+	var queue []*chipmusic.Track
+	for _, title := range []string{"1", "2", "3", "4", "5", "6", "7", "8", "9", "10"} {
+		queue = append(queue, &chipmusic.Track{Title: title, Artist: "some.artist"})
+	}
+
+	ch := make(chan events.Event, 2)
+	ch <- events.PlaybackStarted{Track: &chipmusic.Track{Title: "current", Artist: "some.artist"}}
+	ch <- events.QueueChanged{Queue: queue}
+	close(ch)
+
+	db.HandleEvents(ch)
+	visible := db.queuePane.Visible()
+	require.Len(t, visible, queuePaneHeight)
+	assert.Equal(t, "  ▶ current — some.artist", visible[0])
+	assert.Equal(t, "> 1. 1 — some.artist", visible[1])
+
+	// Moving the cursor past the bottom of the pane scrolls it
+	db.MoveQueueCursor(8)
+	visible = db.queuePane.Visible()
+	assert.Equal(t, "> 9. 9 — some.artist", visible[len(visible)-1])
+	action, ok := db.cursorJumpAction()
+	require.True(t, ok)
+	assert.Equal(t, JumpAction{Position: 9}, action)
+
+	db.MoveQueueCursor(100)
+	action, _ = db.cursorJumpAction()
+	assert.Equal(t, JumpAction{Position: 10}, action)
+
+	db.ScrollQueue(-100)
+	assert.Equal(t, "  ▶ current — some.artist", db.queuePane.Visible()[0])
+
+	// The cursor stays on the last track once the queue gets shorter
+	db.UpdateQueue(queue[:3], nil)
+	action, _ = db.cursorJumpAction()
+	assert.Equal(t, JumpAction{Position: 3}, action)
+	assert.Len(t, db.queuePane.Visible(), 4)
+}
@@ -0,0 +1,267 @@
+package dashboard
+
+import (
+	"fmt"
+	"github.com/broar/chipmusic-cli/pkg/chipmusic"
+	"github.com/gdamore/tcell/v2"
+	"strings"
+)
+
+const (
+	// TrackControlSearch, TrackControlSearchMore, TrackControlEnqueue, TrackControlPlayNext, and TrackControlPlayNow are
+	// the names of SearchAction, SearchMoreAction, EnqueueAction, PlayNextAction, and PlayNowAction
+	TrackControlSearch     = "search"
+	TrackControlSearchMore = "search-more"
+	TrackControlEnqueue    = "enqueue"
+	TrackControlPlayNext   = "play-next"
+	TrackControlPlayNow    = "play-now"
+
+	searchPrompt = "Search: "
+
+	// searchPaneY is the row where the search box is drawn, below the queue pane. The results are listed below it
+	searchPaneY = queuePaneY + queuePaneHeight + 2
+
+	// searchPaneHeight is how many search results are shown at a time
+	searchPaneHeight = 8
+
+	// searchPrefetchRows is how close to the last search result the cursor gets before the next page of results is
+	// searched for, so it has usually arrived by the time the cursor gets there
+	searchPrefetchRows = 3
+)
+
+const (
+	// searchClosed means the search box is hidden, so keys control playback
+	searchClosed = iota
+
+	// searchTyping means keys are typed into the search box
+	searchTyping
+
+	// searchBrowsing means the arrow keys select among the search results
+	searchBrowsing
+)
+
+// OpenSearch shows the search box so keys are typed into it. Enter searches for the text typed and Escape hides the
+// search box again
+func (d *TerminalDashboard) OpenSearch() {
+	d.searchMux.Lock()
+	d.searchState = searchTyping
+	d.searchInput.Clear(d.screen)
+	d.searchInput.Reset()
+	d.searchInput.Draw(d.screen)
+	d.searchMux.Unlock()
+
+	d.show()
+}
+
+// CloseSearch hides the search box along with the search results
+func (d *TerminalDashboard) CloseSearch() {
+	d.searchMux.Lock()
+	d.searchState = searchClosed
+	d.searchResults = nil
+	d.searchInput.Clear(d.screen)
+	d.searchPane.Clear(d.screen)
+	d.searchMux.Unlock()
+
+	d.show()
+}
+
+// ShowSearchResults lists results below the search box so they can be selected with the arrow keys. Enter plays the
+// selected track after the queue, N plays it right after the current track, and P plays it right away. Once the cursor
+// nears the last result, a SearchMoreAction asks for the next page of results. If the search box was closed in the
+// meantime, the results are dropped
+func (d *TerminalDashboard) ShowSearchResults(results []chipmusic.SearchResult) {
+	d.searchMux.Lock()
+	if d.searchState == searchClosed {
+		d.searchMux.Unlock()
+		return
+	}
+
+	d.searchState = searchBrowsing
+	d.searchResults = results
+	d.searchPage = 1
+	d.searchLoading = false
+	d.searchExhausted = len(results) == 0
+	d.searchCursor = 0
+	d.searchPane.ScrollTo(0)
+	d.refreshSearchResults()
+	d.searchMux.Unlock()
+
+	if len(results) == 0 {
+		d.UpdateNotice("No tracks found. Press / to search again")
+		return
+	}
+
+	d.show()
+}
+
+// AppendSearchResults adds results, which are the page of results after the ones listed, to the end of the list. An
+// empty page means there are no more results, so no more pages are asked for. If the search box was closed or another
+// search was made in the meantime, the results are dropped
+func (d *TerminalDashboard) AppendSearchResults(query string, page int, results []chipmusic.SearchResult) {
+	d.searchMux.Lock()
+	if d.searchState != searchBrowsing || query != d.searchQuery || page != d.searchPage+1 {
+		d.searchMux.Unlock()
+		return
+	}
+
+	d.searchResults = append(d.searchResults, results...)
+	d.searchPage = page
+	d.searchLoading = false
+	d.searchExhausted = len(results) == 0
+	d.refreshSearchResults()
+	d.searchMux.Unlock()
+
+	d.show()
+}
+
+// MoveSearchCursor selects the search result delta results away from the selected one, where a positive delta moves
+// down, and scrolls the results to keep it in view
+func (d *TerminalDashboard) MoveSearchCursor(delta int) {
+	d.searchMux.Lock()
+	d.searchCursor += delta
+	if d.searchCursor >= len(d.searchResults) {
+		d.searchCursor = len(d.searchResults) - 1
+	}
+
+	if d.searchCursor < 0 {
+		d.searchCursor = 0
+	}
+
+	d.searchPane.ScrollTo(d.searchCursor)
+	d.refreshSearchResults()
+
+	// The action is sent once the search is unlocked, since whoever receives it may be showing results at the moment
+	var more *SearchMoreAction
+	if !d.searchLoading && !d.searchExhausted && d.searchCursor >= len(d.searchResults)-searchPrefetchRows {
+		d.searchLoading = true
+		more = &SearchMoreAction{Query: d.searchQuery, Page: d.searchPage + 1}
+	}
+
+	d.searchMux.Unlock()
+
+	d.show()
+	if more != nil {
+		d.actions <- *more
+	}
+}
+
+// handleSearchKey handles a key pressed while the search box is open. It returns false if the key has nothing to do
+// with searching, so it should control playback as usual
+func (d *TerminalDashboard) handleSearchKey(event *tcell.EventKey) bool {
+	d.searchMux.Lock()
+	state := d.searchState
+	d.searchMux.Unlock()
+
+	switch state {
+	case searchTyping:
+		return d.handleSearchTypingKey(event)
+	case searchBrowsing:
+		return d.handleSearchBrowsingKey(event)
+	default:
+		return false
+	}
+}
+
+func (d *TerminalDashboard) handleSearchTypingKey(event *tcell.EventKey) bool {
+	switch event.Key() {
+	case tcell.KeyEscape:
+		d.CloseSearch()
+	case tcell.KeyEnter:
+		d.searchMux.Lock()
+		query := strings.TrimSpace(d.searchInput.Text())
+		d.searchMux.Unlock()
+		if query == "" {
+			return true
+		}
+
+		d.searchMux.Lock()
+		d.searchQuery = query
+		d.searchMux.Unlock()
+
+		d.UpdateNotice(fmt.Sprintf("Searching for %q...", query))
+		d.actions <- SearchAction{Query: query}
+	case tcell.KeyBackspace, tcell.KeyBackspace2:
+		d.editSearch(func(input *InputWidget) { input.Erase() })
+	case tcell.KeyRune:
+		d.editSearch(func(input *InputWidget) { input.Type(event.Rune()) })
+	default:
+		return false
+	}
+
+	return true
+}
+
+func (d *TerminalDashboard) handleSearchBrowsingKey(event *tcell.EventKey) bool {
+	switch event.Key() {
+	case tcell.KeyEscape:
+		d.CloseSearch()
+	case tcell.KeyUp:
+		d.MoveSearchCursor(-1)
+	case tcell.KeyDown:
+		d.MoveSearchCursor(1)
+	case tcell.KeyEnter:
+		if result, ok := d.selectedSearchResult(); ok {
+			d.actions <- EnqueueAction{URL: result.URL}
+		}
+	case tcell.KeyRune:
+		switch event.Rune() {
+		case '/':
+			d.OpenSearch()
+		case 'N', 'n':
+			if result, ok := d.selectedSearchResult(); ok {
+				d.actions <- PlayNextAction{URL: result.URL}
+			}
+		case 'P', 'p':
+			if result, ok := d.selectedSearchResult(); ok {
+				d.actions <- PlayNowAction{URL: result.URL}
+			}
+		default:
+			return false
+		}
+	default:
+		return false
+	}
+
+	return true
+}
+
+// editSearch changes the text of the search box with edit and draws it again
+func (d *TerminalDashboard) editSearch(edit func(input *InputWidget)) {
+	d.searchMux.Lock()
+	d.searchInput.Clear(d.screen)
+	edit(d.searchInput)
+	d.searchInput.Draw(d.screen)
+	d.searchMux.Unlock()
+
+	d.show()
+}
+
+// selectedSearchResult returns the search result under the cursor. It returns false if there are no results
+func (d *TerminalDashboard) selectedSearchResult() (chipmusic.SearchResult, bool) {
+	d.searchMux.Lock()
+	defer d.searchMux.Unlock()
+
+	if d.searchCursor >= len(d.searchResults) {
+		return chipmusic.SearchResult{}, false
+	}
+
+	return d.searchResults[d.searchCursor], true
+}
+
+// refreshSearchResults redraws the search results with the selected one highlighted. Only the results shown are
+// formatted, since pages of results keep being added as the cursor moves down. The search must be locked by the caller
+func (d *TerminalDashboard) refreshSearchResults() {
+	d.searchPane.SetSource(searchResultSource(d.searchResults), d.searchCursor)
+	d.searchPane.Draw(d.screen)
+}
+
+// searchResultSource is a ListSource showing the title and artist of search results
+type searchResultSource []chipmusic.SearchResult
+
+func (s searchResultSource) Len() int {
+	return len(s)
+}
+
+func (s searchResultSource) Item(index int) string {
+	return fmt.Sprintf("%s — %s", s[index].Title, s[index].Artist)
+}
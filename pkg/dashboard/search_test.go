@@ -0,0 +1,113 @@
+package dashboard
+
+import (
+	"github.com/broar/chipmusic-cli/pkg/chipmusic"
+	"github.com/gdamore/tcell/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestTerminalDashboard_Search(t *testing.T) {
+	db, err := NewTerminalDashboard(WithScreen(&MockScreen{}))
+	require.NoError(t, err)
+
+	defer db.Close()
+
+	actions := make(chan Action, 1)
+	go func() {
+		for action := range db.Actions() {
+			actions <- action
+		}
+	}()
+
+	assert.False(t, db.handleSearchKey(tcell.NewEventKey(tcell.KeyRune, 'a', tcell.ModNone)), "expected keys to control playback with the search box closed")
+
+	db.OpenSearch()
+	for _, r := range "lsdjx" {
+		assert.True(t, db.handleSearchKey(tcell.NewEventKey(tcell.KeyRune, r, tcell.ModNone)))
+	}
+
+	assert.True(t, db.handleSearchKey(tcell.NewEventKey(tcell.KeyBackspace2, 0, tcell.ModNone)))
+	assert.False(t, db.handleSearchKey(tcell.NewEventKey(tcell.KeyCtrlC, 0, tcell.ModNone)), "expected Ctrl+C to still quit")
+
+	assert.True(t, db.handleSearchKey(tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModNone)))
+	assert.Equal(t, SearchAction{Query: "lsdj"}, <-actions)
+
+	results := []chipmusic.SearchResult{
+		{URL: "first.url", Title: "first.title", Artist: "first.artist"},
+		{URL: "second.url", Title: "second.title", Artist: "second.artist"},
+	}
+
+	db.ShowSearchResults(results)
+	assert.Equal(t, []string{"first.title — first.artist", "second.title — second.artist"}, db.searchPane.Visible())
+
+	db.handleSearchKey(tcell.NewEventKey(tcell.KeyDown, 0, tcell.ModNone))
+	assert.Equal(t, SearchMoreAction{Query: "lsdj", Page: 2}, <-actions, "expected the next page near the last result")
+
+	db.handleSearchKey(tcell.NewEventKey(tcell.KeyDown, 0, tcell.ModNone))
+	assert.True(t, db.handleSearchKey(tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModNone)))
+	assert.Equal(t, EnqueueAction{URL: "second.url"}, <-actions, "expected no other page to be asked for while one is searched for")
+
+	assert.True(t, db.handleSearchKey(tcell.NewEventKey(tcell.KeyRune, 'n', tcell.ModNone)))
+	assert.Equal(t, PlayNextAction{URL: "second.url"}, <-actions)
+
+	db.handleSearchKey(tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModNone))
+	assert.True(t, db.handleSearchKey(tcell.NewEventKey(tcell.KeyRune, 'p', tcell.ModNone)))
+	assert.Equal(t, PlayNowAction{URL: "first.url"}, <-actions)
+
+	assert.False(t, db.handleSearchKey(tcell.NewEventKey(tcell.KeyRune, '+', tcell.ModNone)), "expected other keys to control playback while browsing results")
+
+	assert.True(t, db.handleSearchKey(tcell.NewEventKey(tcell.KeyEscape, 0, tcell.ModNone)))
+	assert.False(t, db.handleSearchKey(tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModNone)))
+
+	// Results which arrive once the search box is closed are dropped
+	db.ShowSearchResults(results)
+	_, ok := db.selectedSearchResult()
+	assert.False(t, ok)
+}
+
+func TestTerminalDashboard_AppendSearchResults(t *testing.T) {
+	db, err := NewTerminalDashboard(WithScreen(&MockScreen{}))
+	require.NoError(t, err)
+
+	defer db.Close()
+
+	actions := make(chan Action, 1)
+	go func() {
+		for action := range db.Actions() {
+			actions <- action
+		}
+	}()
+
+	db.OpenSearch()
+	for _, r := range "lsdj" {
+		db.handleSearchKey(tcell.NewEventKey(tcell.KeyRune, r, tcell.ModNone))
+	}
+
+	db.handleSearchKey(tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModNone))
+	<-actions
+
+	db.ShowSearchResults([]chipmusic.SearchResult{{URL: "first.url", Title: "first.title", Artist: "first.artist"}})
+	db.AppendSearchResults("other", 2, []chipmusic.SearchResult{{URL: "other.url"}})
+	db.AppendSearchResults("lsdj", 3, []chipmusic.SearchResult{{URL: "other.url"}})
+	assert.Equal(t, 1, db.searchPane.Len(), "expected pages of other searches and out of order pages to be dropped")
+
+	db.AppendSearchResults("lsdj", 2, []chipmusic.SearchResult{{URL: "second.url", Title: "second.title", Artist: "second.artist"}})
+	assert.Equal(t, []string{"first.title — first.artist", "second.title — second.artist"}, db.searchPane.Visible())
+
+	db.MoveSearchCursor(1)
+	assert.Equal(t, SearchMoreAction{Query: "lsdj", Page: 3}, <-actions)
+
+	db.AppendSearchResults("lsdj", 3, nil)
+	db.MoveSearchCursor(1)
+	result, ok := db.selectedSearchResult()
+	require.True(t, ok)
+	assert.Equal(t, "second.url", result.URL)
+
+	select {
+	case action := <-actions:
+		t.Errorf("expected no more pages after an empty page but got %v", action)
+	default:
+	}
+}
@@ -0,0 +1,52 @@
+package dashboard
+
+import (
+	"fmt"
+)
+
+const (
+	// minWidth and minHeight are the size of the smallest terminal the track controls and the queue pane are usable in.
+	// Panes further right or down are cut off in terminals between this and their full size
+	minWidth  = 60
+	minHeight = 12
+)
+
+// resize handles the terminal being resized to width by height. Below the minimum size, the dashboard is replaced by a
+// message asking for a bigger terminal, since the layout would only draw over itself. Once the terminal is big enough
+// again, the whole dashboard is drawn again
+func (d *TerminalDashboard) resize(width, height int) {
+	tooSmall := width < minWidth || height < minHeight
+
+	d.sizeMux.Lock()
+	restored := d.tooSmall && !tooSmall
+	d.tooSmall = tooSmall
+	d.sizeMux.Unlock()
+
+	if restored {
+		d.screen.Clear()
+		d.redraw()
+	}
+
+	d.screen.Sync()
+}
+
+// isTooSmall returns true while the terminal is smaller than the minimum size
+func (d *TerminalDashboard) isTooSmall() bool {
+	d.sizeMux.Lock()
+	defer d.sizeMux.Unlock()
+
+	return d.tooSmall
+}
+
+// drawTooSmall clears the screen of anything drawn by the dashboard and draws the message asking for a bigger terminal
+// in its place
+func (d *TerminalDashboard) drawTooSmall() {
+	d.screen.Clear()
+	NewTextWidget(0, 0, formatTooSmall(), d.theme.Text).Draw(d.screen)
+}
+
+// formatTooSmall returns the message shown in place of the dashboard while the terminal is smaller than the minimum
+// size
+func formatTooSmall() string {
+	return fmt.Sprintf("terminal too small (need %dx%d)", minWidth, minHeight)
+}
@@ -0,0 +1,46 @@
+package dashboard
+
+import (
+	"github.com/gdamore/tcell/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+// screenRow returns the text in row y of a simulation screen
+func screenRow(screen tcell.SimulationScreen, y int) string {
+	cells, width, _ := screen.GetContents()
+	row := make([]rune, 0, width)
+	for _, cell := range cells[y*width : (y+1)*width] {
+		row = append(row, cell.Runes...)
+	}
+
+	return strings.TrimRight(string(row), " ")
+}
+
+func TestTerminalDashboard_Resize(t *testing.T) {
+	screen := tcell.NewSimulationScreen("")
+	db, err := NewTerminalDashboard(WithScreen(screen))
+	require.NoError(t, err)
+	require.NoError(t, db.init())
+
+	defer screen.Fini()
+
+	screen.SetSize(40, 10)
+	db.resize(40, 10)
+	db.show()
+	assert.True(t, db.isTooSmall())
+	assert.Equal(t, "terminal too small (need 60x12)", screenRow(screen, 0))
+	assert.Empty(t, screenRow(screen, 3), "expected the dashboard not to be drawn")
+
+	db.UpdateNotice("some.notice")
+	assert.Equal(t, "terminal too small (need 60x12)", screenRow(screen, 0), "expected updates not to be drawn over the message")
+
+	screen.SetSize(120, 40)
+	db.resize(120, 40)
+	db.show()
+	assert.False(t, db.isTooSmall())
+	assert.NotContains(t, screenRow(screen, 0), "terminal too small", "expected the message to be cleared")
+	assert.NotEmpty(t, screenRow(screen, 3), "expected the dashboard to be drawn again")
+}
@@ -0,0 +1,138 @@
+package dashboard
+
+import (
+	"fmt"
+	"github.com/broar/chipmusic-cli/pkg/events"
+	"strings"
+	"time"
+)
+
+const (
+	statsOverlayTitle = " Session "
+	statsOverlayWidth = 28
+)
+
+// SessionStats are statistics about the tracks played during a session, collected from the events of the session
+type SessionStats struct {
+
+	// TracksPlayed is how many tracks started playing
+	TracksPlayed int
+
+	// Skips is how many tracks were skipped, either by the user or because they could not be played
+	Skips int
+
+	// Downloaded is how many bytes of audio were downloaded
+	Downloaded int64
+
+	// Started is when the first track started playing. It is the zero time until then
+	Started time.Time
+
+	// Resolved is how many tracks had their audio downloaded or read from the cache, and CacheHits is how many of them
+	// were read from the cache
+	Resolved  int
+	CacheHits int
+
+	// Fetched is how many bytes of audio were downloaded for tracks which weren't cached, and FetchTime is how long
+	// getting their audio took. Together they are the throughput of downloads
+	Fetched   int64
+	FetchTime time.Duration
+
+	// downloads is how many bytes were downloaded so far for each download URL
+	downloads map[string]int64
+}
+
+// Add updates the statistics with an event received at now. Events which don't affect the statistics are ignored
+func (s *SessionStats) Add(event events.Event, now time.Time) {
+	switch event := event.(type) {
+	case events.TrackResolved:
+		if event.Track == nil || event.Track.Reader == nil {
+			return
+		}
+
+		s.Resolved++
+		if event.Track.FromCache {
+			s.CacheHits++
+		} else if event.Track.DownloadDuration > 0 {
+			s.Fetched += event.Track.Size
+			s.FetchTime += event.Track.DownloadDuration
+		}
+	case events.PlaybackStarted:
+		s.TracksPlayed++
+		if s.Started.IsZero() {
+			s.Started = now
+		}
+	case events.TrackSkipped:
+		s.Skips++
+	case events.ActionPerformed:
+		if event.Action == TrackControlSkip {
+			s.Skips++
+		}
+	case events.DownloadProgress:
+		if s.downloads == nil {
+			s.downloads = map[string]int64{}
+		}
+
+		// Progress is reported as a running total, so a total lower than before means the URL is downloading again
+		previous := s.downloads[event.URL]
+		if event.Downloaded < previous {
+			previous = 0
+		}
+
+		s.Downloaded += event.Downloaded - previous
+		s.downloads[event.URL] = event.Downloaded
+	}
+}
+
+// Elapsed returns how long the session has been playing tracks at now
+func (s *SessionStats) Elapsed(now time.Time) time.Duration {
+	if s.Started.IsZero() {
+		return 0
+	}
+
+	return now.Sub(s.Started)
+}
+
+// Throughput returns how many bytes of audio were downloaded per second, or 0 if nothing was downloaded
+func (s *SessionStats) Throughput() float64 {
+	if s.FetchTime <= 0 {
+		return 0
+	}
+
+	return float64(s.Fetched) / s.FetchTime.Seconds()
+}
+
+// formatStatsOverlay draws the statistics as a box of text
+func formatStatsOverlay(stats *SessionStats, now time.Time) []string {
+	lines := []string{
+		fmt.Sprintf("Tracks played: %d", stats.TracksPlayed),
+		fmt.Sprintf("Total time: %s", formatStopwatchTime(stats.Elapsed(now))),
+		fmt.Sprintf("Skips: %d", stats.Skips),
+		fmt.Sprintf("Downloaded: %s", formatBytes(stats.Downloaded)),
+		fmt.Sprintf("Throughput: %s/s", formatBytes(int64(stats.Throughput()))),
+		fmt.Sprintf("Cache hits: %d/%d", stats.CacheHits, stats.Resolved),
+	}
+
+	border := "+" + strings.Repeat("-", statsOverlayWidth-2) + "+"
+	drawing := []string{"+-" + statsOverlayTitle + border[len(statsOverlayTitle)+2:]}
+	for _, line := range lines {
+		drawing = append(drawing, fmt.Sprintf("| %-*s |", statsOverlayWidth-4, line))
+	}
+
+	return append(drawing, border)
+}
+
+// formatBytes formats a number of bytes with the largest unit which keeps it above 1, e.g. 1.5 MB
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	value, exponent := float64(n)/unit, 0
+	for value >= unit && exponent < 2 {
+		value /= unit
+		exponent++
+	}
+
+	return fmt.Sprintf("%.1f %cB", value, "KMG"[exponent])
+}
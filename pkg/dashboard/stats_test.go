@@ -0,0 +1,71 @@
+package dashboard
+
+import (
+	"errors"
+	"github.com/broar/chipmusic-cli/pkg/chipmusic"
+	"github.com/broar/chipmusic-cli/pkg/events"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestSessionStats_Add(t *testing.T) {
+	start := time.Unix(1600000000, 0)
+	track := &chipmusic.Track{Title: "some.title"}
+	downloaded := &chipmusic.Track{Reader: &chipmusic.ReadSeekNopCloser{}, Size: 3000, DownloadDuration: 2 * time.Second}
+	cached := &chipmusic.Track{Reader: &chipmusic.ReadSeekNopCloser{}, Size: 5000, FromCache: true}
+	received := []events.Event{
+		events.TrackResolved{Track: track},
+		events.TrackResolved{Track: downloaded},
+		events.TrackResolved{Track: cached},
+		events.DownloadProgress{URL: "some.url", Downloaded: 100, Total: 300},
+		events.DownloadProgress{URL: "some.url", Downloaded: 300, Total: 300},
+		events.PlaybackStarted{Track: track},
+		events.ActionPerformed{Action: TrackControlPause},
+		events.ActionPerformed{Action: TrackControlSkip},
+		events.TrackSkipped{Track: track, Reason: errors.New("skipped because download is empty")},
+		events.DownloadProgress{URL: "other.url", Downloaded: 50, Total: -1},
+		events.DownloadProgress{URL: "some.url", Downloaded: 20, Total: 300},
+		events.PlaybackStarted{Track: track},
+	}
+
+	stats := &SessionStats{}
+	for i, event := range received {
+		stats.Add(event, start.Add(time.Duration(i)*time.Minute))
+	}
+
+	assert.Equal(t, 2, stats.TracksPlayed)
+	assert.Equal(t, 2, stats.Skips)
+	assert.Equal(t, int64(370), stats.Downloaded, "expected a repeated download of a URL to be counted again")
+	assert.Equal(t, start.Add(5*time.Minute), stats.Started)
+	assert.Equal(t, 5*time.Minute, stats.Elapsed(start.Add(10*time.Minute)))
+	assert.Equal(t, 2, stats.Resolved, "expected tracks without audio not to be counted")
+	assert.Equal(t, 1, stats.CacheHits)
+	assert.Equal(t, float64(1500), stats.Throughput(), "expected cached tracks not to count towards throughput")
+}
+
+func TestSessionStats_ElapsedBeforePlayback(t *testing.T) {
+	stats := &SessionStats{}
+	assert.Zero(t, stats.Elapsed(time.Now()))
+	assert.Zero(t, stats.Throughput())
+}
+
+func TestFormatBytes(t *testing.T) {
+	testCases := []struct {
+		bytes    int64
+		expected string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1536, "1.5 KB"},
+		{5 * 1024 * 1024, "5.0 MB"},
+		{3 * 1024 * 1024 * 1024, "3.0 GB"},
+		{2048 * 1024 * 1024 * 1024, "2048.0 GB"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.expected, func(tt *testing.T) {
+			assert.Equal(tt, testCase.expected, formatBytes(testCase.bytes))
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package dashboard
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gdamore/tcell/v2"
+	"sort"
+	"strings"
+)
+
+const (
+	// ThemeDefault uses the colors of the terminal with the selected track control in black on white
+	ThemeDefault = "default"
+
+	// ThemeGameBoy, ThemeNES, and ThemeC64 use the palettes of the consoles and computers chiptunes are made with
+	ThemeGameBoy = "gameboy"
+	ThemeNES     = "nes"
+	ThemeC64     = "c64"
+
+	// ThemeColorForeground and the other ThemeColor constants name the colors of a Theme which can be set in the config
+	// file
+	ThemeColorForeground          = "foreground"
+	ThemeColorBackground          = "background"
+	ThemeColorHighlightForeground = "highlight-foreground"
+	ThemeColorHighlightBackground = "highlight-background"
+	ThemeColorAccent              = "accent"
+)
+
+// ErrNilTheme is an error returned when attempting to use a nil Theme for a TerminalDashboard
+var ErrNilTheme = errors.New("theme cannot be nil")
+
+// Theme is the styles every widget of the dashboard is drawn with
+type Theme struct {
+
+	// Text is the style of text and of the background of the whole screen
+	Text tcell.Style
+
+	// Highlight is the style of the selected track control and of the items under the cursor of lists
+	Highlight tcell.Style
+
+	// Accent is the style of the track playing, its progress bar, and the titles of panes
+	Accent tcell.Style
+}
+
+// themes are the built-in themes by name
+var themes = map[string]Theme{
+	ThemeDefault: {
+		Text:      tcell.StyleDefault.Foreground(tcell.ColorReset).Background(tcell.ColorReset),
+		Highlight: tcell.StyleDefault.Foreground(tcell.ColorBlack).Background(tcell.ColorWhite),
+		Accent:    tcell.StyleDefault.Foreground(tcell.ColorReset).Background(tcell.ColorReset),
+	},
+
+	// The four shades of green of the original Game Boy screen
+	ThemeGameBoy: newTheme(
+		tcell.NewHexColor(0x9bbc0f), tcell.NewHexColor(0x0f380f),
+		tcell.NewHexColor(0x0f380f), tcell.NewHexColor(0x8bac0f),
+		tcell.NewHexColor(0x306230)),
+	ThemeNES: newTheme(
+		tcell.NewHexColor(0xfcfcfc), tcell.NewHexColor(0x000000),
+		tcell.NewHexColor(0xfcfcfc), tcell.NewHexColor(0xd82800),
+		tcell.NewHexColor(0xfc9838)),
+	ThemeC64: newTheme(
+		tcell.NewHexColor(0x6c5eb5), tcell.NewHexColor(0x352879),
+		tcell.NewHexColor(0x352879), tcell.NewHexColor(0x6c5eb5),
+		tcell.NewHexColor(0xffffff)),
+}
+
+// newTheme returns a Theme with text in foreground on background, highlighted items in highlightForeground on
+// highlightBackground, and accents in accent on background
+func newTheme(foreground, background, highlightForeground, highlightBackground, accent tcell.Color) Theme {
+	return Theme{
+		Text:      tcell.StyleDefault.Foreground(foreground).Background(background),
+		Highlight: tcell.StyleDefault.Foreground(highlightForeground).Background(highlightBackground),
+		Accent:    tcell.StyleDefault.Foreground(accent).Background(background),
+	}
+}
+
+// DefaultTheme returns the theme the dashboard uses unless another theme is configured
+func DefaultTheme() *Theme {
+	theme := themes[ThemeDefault]
+	return &theme
+}
+
+// ThemeNames returns the names of the built-in themes in alphabetical order
+func ThemeNames() []string {
+	names := make([]string, 0, len(themes))
+	for name := range themes {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// ParseTheme returns the built-in theme with name with some of its colors replaced. colors maps the names of colors,
+// e.g. ThemeColorAccent, to a color name like "red" or a hex color like "#9bbc0f". An empty name is ThemeDefault
+func ParseTheme(name string, colors map[string]string) (*Theme, error) {
+	if name == "" {
+		name = ThemeDefault
+	}
+
+	theme, ok := themes[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown theme %q. Allowed themes: [%s]", name, strings.Join(ThemeNames(), ", "))
+	}
+
+	for key, value := range colors {
+		color, err := parseColor(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid color for %s: %w", key, err)
+		}
+
+		switch key {
+		case ThemeColorForeground:
+			theme.Text = theme.Text.Foreground(color)
+		case ThemeColorBackground:
+			theme.Text = theme.Text.Background(color)
+			theme.Accent = theme.Accent.Background(color)
+		case ThemeColorHighlightForeground:
+			theme.Highlight = theme.Highlight.Foreground(color)
+		case ThemeColorHighlightBackground:
+			theme.Highlight = theme.Highlight.Background(color)
+		case ThemeColorAccent:
+			theme.Accent = theme.Accent.Foreground(color)
+		default:
+			return nil, fmt.Errorf("unknown theme color %q", key)
+		}
+	}
+
+	return &theme, nil
+}
+
+// parseColor returns the color with a name known to tcell or written in hex. "default" is the color of the terminal
+func parseColor(value string) (tcell.Color, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == ThemeDefault {
+		return tcell.ColorReset, nil
+	}
+
+	color := tcell.GetColor(value)
+	if color == tcell.ColorDefault {
+		return tcell.ColorDefault, fmt.Errorf("%q is not a color name or a hex color like #9bbc0f", value)
+	}
+
+	return color, nil
+}
+
+// WithTheme allows clients to override the styles the dashboard is drawn with
+func WithTheme(theme *Theme) Option {
+	return func(dashboard *TerminalDashboard) error {
+		if theme == nil {
+			return ErrNilTheme
+		}
+
+		dashboard.theme = *theme
+		return nil
+	}
+}
@@ -0,0 +1,68 @@
+package dashboard
+
+import (
+	"github.com/gdamore/tcell/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestParseTheme(t *testing.T) {
+	theme, err := ParseTheme("", nil)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultTheme(), theme)
+
+	theme, err = ParseTheme("GameBoy", map[string]string{
+		ThemeColorAccent:              "red",
+		ThemeColorBackground:          "#000000",
+		ThemeColorHighlightForeground: "default",
+	})
+	require.NoError(t, err)
+
+	foreground, background, _ := theme.Text.Decompose()
+	assert.Equal(t, tcell.NewHexColor(0x9bbc0f), foreground, "expected colors which weren't replaced to be kept")
+	assert.Equal(t, tcell.NewHexColor(0x000000), background)
+
+	foreground, background, _ = theme.Accent.Decompose()
+	assert.Equal(t, tcell.ColorRed, foreground)
+	assert.Equal(t, tcell.NewHexColor(0x000000), background, "expected accents to share the background of text")
+
+	foreground, _, _ = theme.Highlight.Decompose()
+	assert.Equal(t, tcell.ColorReset, foreground)
+}
+
+func TestParseTheme_Invalid(t *testing.T) {
+	testCases := []struct {
+		name   string
+		theme  string
+		colors map[string]string
+	}{
+		{"UnknownTheme", "amiga", nil},
+		{"UnknownColor", ThemeNES, map[string]string{"border": "red"}},
+		{"InvalidColor", ThemeNES, map[string]string{ThemeColorAccent: "some.color"}},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			_, err := ParseTheme(testCase.theme, testCase.colors)
+			assert.Error(tt, err)
+		})
+	}
+}
+
+func TestNewTerminalDashboard_WithTheme(t *testing.T) {
+	theme, err := ParseTheme(ThemeC64, nil)
+	require.NoError(t, err)
+
+	db, err := NewTerminalDashboard(WithScreen(&MockScreen{}), WithTheme(theme))
+	require.NoError(t, err)
+
+	defer db.Close()
+
+	assert.Equal(t, theme.Accent, db.widgets[currentlyPlayingID].style)
+	assert.Equal(t, theme.Text, db.widgets[TrackControlPlay].style)
+	assert.Equal(t, theme.Highlight, db.queuePane.highlightStyle)
+
+	_, err = NewTerminalDashboard(WithScreen(&MockScreen{}), WithTheme(nil))
+	assert.Equal(t, ErrNilTheme, err)
+}
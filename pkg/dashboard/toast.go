@@ -0,0 +1,121 @@
+package dashboard
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	// ToastInfo toasts tell the user that something worked, and ToastError toasts that something went wrong
+	ToastInfo ToastLevel = iota
+	ToastError
+)
+
+const (
+	// DefaultToastDuration is how long a toast is shown unless it is configured
+	DefaultToastDuration = 5 * time.Second
+
+	// maxToasts is how many toasts are shown at once. The oldest toast makes room for a new one
+	maxToasts = 3
+
+	// toastAreaY is the row of the first toast, below the search results
+	toastAreaY = searchPaneY + searchPaneHeight + 1
+)
+
+// ErrInvalidToastDuration is an error returned when attempting to show toasts for a duration which isn't positive
+var ErrInvalidToastDuration = errors.New("toast duration must be greater than 0")
+
+// ToastLevel is how a toast is styled
+type ToastLevel int
+
+// toast is a message shown in the toast area until it expires
+type toast struct {
+	text    string
+	level   ToastLevel
+	expires time.Time
+}
+
+// WithToastDuration allows overriding how long toasts are shown
+func WithToastDuration(duration time.Duration) Option {
+	return func(dashboard *TerminalDashboard) error {
+		if duration <= 0 {
+			return ErrInvalidToastDuration
+		}
+
+		dashboard.toastDuration = duration
+		return nil
+	}
+}
+
+// ShowToast shows text in the toast area for a few seconds, e.g. an error which shouldn't stay on the screen until the
+// next notice. Toasts are listed oldest first and each expires on its own
+func (d *TerminalDashboard) ShowToast(text string, level ToastLevel) {
+	d.toastMux.Lock()
+	d.toasts = append(d.toasts, toast{text: text, level: level, expires: d.now().Add(d.toastDuration)})
+	if len(d.toasts) > maxToasts {
+		d.toasts = d.toasts[len(d.toasts)-maxToasts:]
+	}
+
+	d.refreshToasts()
+	d.toastMux.Unlock()
+
+	time.AfterFunc(d.toastDuration, d.expireToasts)
+	d.show()
+}
+
+// Toasts returns the text of the toasts shown, oldest first
+func (d *TerminalDashboard) Toasts() []string {
+	d.toastMux.Lock()
+	defer d.toastMux.Unlock()
+
+	texts := make([]string, 0, len(d.toasts))
+	for _, toast := range d.toasts {
+		texts = append(texts, toast.text)
+	}
+
+	return texts
+}
+
+// expireToasts removes the toasts which were shown for long enough
+func (d *TerminalDashboard) expireToasts() {
+	d.toastMux.Lock()
+	now := d.now()
+	shown := d.toasts[:0]
+	for _, toast := range d.toasts {
+		if now.Before(toast.expires) {
+			shown = append(shown, toast)
+		}
+	}
+
+	expired := len(shown) < len(d.toasts)
+	d.toasts = shown
+	if expired {
+		d.refreshToasts()
+	}
+
+	d.toastMux.Unlock()
+
+	if expired {
+		d.show()
+	}
+}
+
+// refreshToasts redraws the toast area. The toasts must be locked by the caller
+func (d *TerminalDashboard) refreshToasts() {
+	if d.toastArea != nil {
+		d.toastArea.Clear(d.screen)
+	}
+
+	drawing := make([]string, 0, len(d.toasts))
+	for i, toast := range d.toasts {
+		style := d.theme.Text
+		if toast.level == ToastError {
+			style = d.theme.Accent
+		}
+
+		drawing = append(drawing, toast.text)
+		NewWidget(0, toastAreaY+i, []string{toast.text}, style).Draw(d.screen)
+	}
+
+	d.toastArea = NewWidget(0, toastAreaY, drawing, d.theme.Text)
+}
@@ -0,0 +1,73 @@
+package dashboard
+
+import (
+	"errors"
+	"github.com/broar/chipmusic-cli/pkg/chipmusic"
+	"github.com/broar/chipmusic-cli/pkg/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestWithToastDuration(t *testing.T) {
+	_, err := NewTerminalDashboard(WithScreen(&MockScreen{}), WithToastDuration(0))
+	assert.Equal(t, ErrInvalidToastDuration, err)
+}
+
+func TestTerminalDashboard_ShowToast(t *testing.T) {
+	db, err := NewTerminalDashboard(WithScreen(&MockScreen{}), WithToastDuration(time.Hour))
+	require.NoError(t, err)
+
+	defer db.Close()
+
+	now := time.Unix(1600000000, 0)
+	db.now = func() time.Time {
+		return now
+	}
+
+	db.ShowToast("first", ToastInfo)
+	now = now.Add(time.Minute)
+	for _, text := range []string{"second", "third", "fourth"} {
+		db.ShowToast(text, ToastError)
+	}
+
+	assert.Equal(t, []string{"second", "third", "fourth"}, db.Toasts(), "expected the oldest toast to make room")
+	assert.Equal(t, []string{"second", "third", "fourth"}, db.toastArea.drawing)
+
+	now = now.Add(time.Hour)
+	db.expireToasts()
+	assert.Empty(t, db.Toasts())
+	assert.Empty(t, db.toastArea.drawing)
+}
+
+func TestTerminalDashboard_HandleEvents_Toasts(t *testing.T) {
+	testCases := []struct {
+		name     string
+		event    events.Event
+		expected string
+	}{
+		{"ErrorWithoutTrack", events.Error{Err: errors.New("an error occurred")}, "Error: an error occurred"},
+		{"ErrorWithTrack", events.Error{Err: errors.New("an error occurred"), Track: &chipmusic.Track{Title: "some.title", Artist: "some.artist"}}, "some.title by some.artist: an error occurred"},
+		{"TrackSkipped", events.TrackSkipped{Reason: errors.New("skipped some.url"), Track: &chipmusic.Track{Title: "some.title", Artist: "some.artist"}}, "some.title by some.artist: skipped some.url"},
+		{"TrackSkippedWithoutTrack", events.TrackSkipped{Reason: errors.New("skipped some.url")}, "Skipped: skipped some.url"},
+		{"Notice", events.Notice{Message: "some.message"}, "some.message"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			db, err := NewTerminalDashboard(WithScreen(&MockScreen{}))
+			require.NoError(tt, err)
+
+			defer db.Close()
+
+			ch := make(chan events.Event, 1)
+			ch <- testCase.event
+			close(ch)
+
+			db.HandleEvents(ch)
+			assert.Equal(tt, []string{testCase.expected}, db.Toasts())
+			assert.Equal(tt, []string{""}, db.widgets[noticeID].base.drawing, "expected the notice to be left alone")
+		})
+	}
+}
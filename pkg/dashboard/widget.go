@@ -53,7 +53,7 @@ func (w *Widget) Draw(screen tcell.Screen) {
 
 func (w *Widget) Clear(screen tcell.Screen) {
 	for y := range w.drawing {
-		for x := range w.drawing[y] {
+		for x := range []rune(w.drawing[y]) {
 			screen.SetContent(w.X+x, w.Y+y, ' ', nil, w.style)
 		}
 	}
@@ -94,6 +94,215 @@ func (t *TextWidget) SetText(text string) {
 	t.base.drawing = []string{text}
 }
 
+// Contains returns true if the cell at x-y is part of the text
+func (t *TextWidget) Contains(x, y int) bool {
+	if t.base == nil || y != t.base.Y || x < t.base.X {
+		return false
+	}
+
+	for _, row := range t.base.drawing {
+		if x < t.base.X+len([]rune(row)) {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (t *TextWidget) SetStyle(style tcell.Style) {
 	t.base.style = style
 }
+
+// ListSource provides the items of a ListWidget as they are shown, so a list of thousands of items only formats the
+// few rows on the screen
+type ListSource interface {
+
+	// Len returns how many items there are
+	Len() int
+
+	// Item returns the text of the item at index, which is between 0 and Len
+	Item(index int) string
+}
+
+// stringSource is a ListSource of items which are already formatted
+type stringSource []string
+
+func (s stringSource) Len() int {
+	return len(s)
+}
+
+func (s stringSource) Item(index int) string {
+	return s[index]
+}
+
+// ListWidget draws a list of items, one per row, at an x-y offset. Only height rows are shown at a time, so the list
+// scrolls to show the items past them. One item can be highlighted with another style
+type ListWidget struct {
+	Coordinate
+	source         ListSource
+	height         int
+	offset         int
+	highlighted    int
+	style          tcell.Style
+	highlightStyle tcell.Style
+
+	// drawn is what was drawn last, so it can be cleared even after the items changed
+	drawn *Widget
+}
+
+// NewListWidget returns a ListWidget which shows height rows at the x-y offset. No item is highlighted until
+// SetItems is called
+func NewListWidget(x, y, height int, style, highlightStyle tcell.Style) *ListWidget {
+	return &ListWidget{
+		Coordinate:     Coordinate{x, y},
+		source:         stringSource(nil),
+		height:         height,
+		highlighted:    -1,
+		style:          style,
+		highlightStyle: highlightStyle,
+	}
+}
+
+// SetItems replaces the items of the list and highlights the item at highlighted. If highlighted is negative, no item
+// is highlighted. The list keeps its scroll position as long as it still has items past it
+func (l *ListWidget) SetItems(items []string, highlighted int) {
+	l.SetSource(stringSource(items), highlighted)
+}
+
+// SetSource replaces the items of the list with the items of source, which are only asked for once they are shown.
+// Otherwise it is the same as SetItems
+func (l *ListWidget) SetSource(source ListSource, highlighted int) {
+	l.source = source
+	l.highlighted = highlighted
+	l.Scroll(0)
+}
+
+// Len returns how many items the list has, including the ones which aren't shown
+func (l *ListWidget) Len() int {
+	return l.source.Len()
+}
+
+// Scroll moves the rows shown by delta items, where a positive delta scrolls down. The list can't be scrolled past its
+// first or last item
+func (l *ListWidget) Scroll(delta int) {
+	l.offset += delta
+	if max := l.source.Len() - l.height; l.offset > max {
+		l.offset = max
+	}
+
+	if l.offset < 0 {
+		l.offset = 0
+	}
+}
+
+// ScrollTo scrolls the list as little as possible so the item at index is shown
+func (l *ListWidget) ScrollTo(index int) {
+	switch {
+	case index < l.offset:
+		l.Scroll(index - l.offset)
+	case index >= l.offset+l.height:
+		l.Scroll(index - l.offset - l.height + 1)
+	}
+}
+
+// Contains returns true if the cell at x-y is in one of the rows of the list, even if no item is shown in it. Rows span
+// every column from the x offset on, since items have no fixed width
+func (l *ListWidget) Contains(x, y int) bool {
+	return x >= l.X && y >= l.Y && y < l.Y+l.height
+}
+
+// ItemAt returns the index of the item shown at x-y. It returns false if no item is shown there
+func (l *ListWidget) ItemAt(x, y int) (int, bool) {
+	if !l.Contains(x, y) {
+		return 0, false
+	}
+
+	index := l.offset + y - l.Y
+	return index, index < l.source.Len()
+}
+
+// Visible returns the items shown at the current scroll position
+func (l *ListWidget) Visible() []string {
+	end := l.offset + l.height
+	if end > l.source.Len() {
+		end = l.source.Len()
+	}
+
+	visible := make([]string, 0, end-l.offset)
+	for i := l.offset; i < end; i++ {
+		visible = append(visible, l.source.Item(i))
+	}
+
+	return visible
+}
+
+func (l *ListWidget) Draw(screen tcell.Screen) {
+	l.Clear(screen)
+
+	visible := l.Visible()
+	l.drawn = NewWidget(l.X, l.Y, visible, l.style)
+	for i, item := range visible {
+		style := l.style
+		if l.offset+i == l.highlighted {
+			style = l.highlightStyle
+		}
+
+		NewWidget(l.X, l.Y+i, []string{item}, style).Draw(screen)
+	}
+}
+
+func (l *ListWidget) Clear(screen tcell.Screen) {
+	if l.drawn == nil {
+		return
+	}
+
+	l.drawn.Clear(screen)
+	l.drawn = nil
+}
+
+// InputWidget draws a line of text typed by the user after a prompt at an x-y offset. Text is only typed and erased at
+// the end of the line
+type InputWidget struct {
+	TextWidget
+	prompt string
+	text   []rune
+}
+
+// NewInputWidget returns an empty InputWidget which shows prompt before the text typed
+func NewInputWidget(x, y int, prompt string, style tcell.Style) *InputWidget {
+	input := &InputWidget{TextWidget: *NewTextWidget(x, y, "", style), prompt: prompt}
+	input.update()
+	return input
+}
+
+// Type adds r to the end of the text
+func (i *InputWidget) Type(r rune) {
+	i.text = append(i.text, r)
+	i.update()
+}
+
+// Erase removes the last rune of the text. If the text is empty, this method does nothing
+func (i *InputWidget) Erase() {
+	if len(i.text) == 0 {
+		return
+	}
+
+	i.text = i.text[:len(i.text)-1]
+	i.update()
+}
+
+// Reset removes all of the text
+func (i *InputWidget) Reset() {
+	i.text = nil
+	i.update()
+}
+
+// Text returns the text typed so far
+func (i *InputWidget) Text() string {
+	return string(i.text)
+}
+
+// update sets the drawing to the prompt and the text followed by a cursor
+func (i *InputWidget) update() {
+	i.SetText(i.prompt + string(i.text) + "_")
+}
@@ -3,6 +3,7 @@ package dashboard
 import (
 	"github.com/gdamore/tcell/v2"
 	"github.com/stretchr/testify/assert"
+	"strconv"
 	"testing"
 )
 
@@ -137,3 +138,106 @@ func TestCoordinate_String(t *testing.T) {
 		})
 	}
 }
+
+func TestListWidget_Scroll(t *testing.T) {
+	testCases := []struct {
+		name     string
+		scroll   int
+		expected []string
+	}{
+		{"Top", 0, []string{"a", "b"}},
+		{"Down", 1, []string{"b", "c"}},
+		{"PastLast", 5, []string{"c", "d"}},
+		{"PastFirst", -5, []string{"a", "b"}},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			widget := NewListWidget(0, 0, 2, tcell.StyleDefault, tcell.StyleDefault)
+			widget.SetItems([]string{"a", "b", "c", "d"}, -1)
+			widget.Scroll(testCase.scroll)
+			assert.Equal(tt, testCase.expected, widget.Visible())
+		})
+	}
+}
+
+func TestListWidget_ScrollTo(t *testing.T) {
+	widget := NewListWidget(0, 0, 2, tcell.StyleDefault, tcell.StyleDefault)
+	widget.SetItems([]string{"a", "b", "c", "d"}, 0)
+
+	widget.ScrollTo(3)
+	assert.Equal(t, []string{"c", "d"}, widget.Visible())
+
+	widget.ScrollTo(2)
+	assert.Equal(t, []string{"c", "d"}, widget.Visible(), "expected a shown item not to scroll the list")
+
+	widget.ScrollTo(0)
+	assert.Equal(t, []string{"a", "b"}, widget.Visible())
+
+	// Fewer items keep the list from being scrolled past the last one
+	widget.ScrollTo(3)
+	widget.SetItems([]string{"a"}, 0)
+	assert.Equal(t, []string{"a"}, widget.Visible())
+}
+
+func TestListWidget_DrawAndClear(t *testing.T) {
+	screen := &MockScreen{}
+	widget := NewListWidget(0, 0, 2, tcell.StyleDefault, tcell.StyleDefault)
+	widget.SetItems([]string{"ab", "c", "d"}, 0)
+
+	widget.Draw(screen)
+	assert.Equal(t, 3, screen.called, "expected only the shown items to be drawn")
+
+	widget.Draw(screen)
+	assert.Equal(t, 9, screen.called, "expected the previous drawing to be cleared first")
+
+	widget.Clear(screen)
+	widget.Clear(screen)
+	assert.Equal(t, 12, screen.called)
+}
+
+func TestInputWidget(t *testing.T) {
+	widget := NewInputWidget(0, 0, "> ", tcell.StyleDefault)
+	assert.Equal(t, []string{"> _"}, widget.base.drawing)
+
+	widget.Erase()
+	for _, r := range "chïp" {
+		widget.Type(r)
+	}
+
+	assert.Equal(t, "chïp", widget.Text())
+	assert.Equal(t, []string{"> chïp_"}, widget.base.drawing)
+
+	widget.Erase()
+	assert.Equal(t, "chï", widget.Text())
+
+	widget.Reset()
+	assert.Empty(t, widget.Text())
+	assert.Equal(t, []string{"> _"}, widget.base.drawing)
+}
+
+type countingSource struct {
+	items     int
+	formatted []int
+}
+
+func (s *countingSource) Len() int {
+	return s.items
+}
+
+func (s *countingSource) Item(index int) string {
+	s.formatted = append(s.formatted, index)
+	return strconv.Itoa(index)
+}
+
+func TestListWidget_SetSource(t *testing.T) {
+	source := &countingSource{items: 100000}
+	list := NewListWidget(0, 0, 3, tcell.StyleDefault, tcell.StyleDefault)
+	list.SetSource(source, -1)
+	list.ScrollTo(500)
+	list.Draw(&MockScreen{})
+
+	assert.Equal(t, 100000, list.Len())
+	assert.Equal(t, []string{"498", "499", "500"}, list.Visible())
+	assert.Equal(t, []int{498, 499, 500, 498, 499, 500}, source.formatted, "expected only the rows shown to be formatted")
+}
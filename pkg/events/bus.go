@@ -0,0 +1,224 @@
+package events
+
+import (
+	"sync"
+)
+
+const (
+	// DefaultBufferSize is the default number of events buffered for each subscriber
+	DefaultBufferSize = 64
+)
+
+// Bus is a lightweight publish/subscribe hub for Events. Publishing never blocks: if a subscriber's buffer is full, the
+// event is dropped for that subscriber so a slow subscriber can never stall playback. Subscribers which must not miss
+// any event use SubscribeAll instead, or subscribe only to the events they handle so that frequent events such as
+// DownloadProgress can't fill their buffer
+type Bus struct {
+	mux         sync.RWMutex
+	subscribers map[int]subscriber
+	queues      map[int]*eventQueue
+	next        int
+	closed      bool
+}
+
+// NewBus returns a Bus without any subscribers
+func NewBus() *Bus {
+	return &Bus{
+		subscribers: map[int]subscriber{},
+		queues:      map[int]*eventQueue{},
+	}
+}
+
+// Subscribe returns a channel receiving every event published after the call along with a function which unsubscribes
+// and closes the channel. The channel buffers up to buffer events; if buffer is 0 or less, DefaultBufferSize is used. If
+// names are given, only the events with one of these names are sent to the channel
+func (b *Bus) Subscribe(buffer int, names ...string) (<-chan Event, func()) {
+	if buffer <= 0 {
+		buffer = DefaultBufferSize
+	}
+
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	events := make(chan Event, buffer)
+	if b.closed {
+		close(events)
+		return events, func() {}
+	}
+
+	id := b.next
+	b.next++
+	b.subscribers[id] = subscriber{events: events, names: nameSet(names)}
+
+	once := sync.Once{}
+	return events, func() {
+		once.Do(func() {
+			b.mux.Lock()
+			defer b.mux.Unlock()
+
+			if subscriber, ok := b.subscribers[id]; ok {
+				delete(b.subscribers, id)
+				close(subscriber.events)
+			}
+		})
+	}
+}
+
+// SubscribeAll is like Subscribe, except that no event is ever dropped: events the subscriber hasn't received yet are
+// queued without a limit. Unsubscribing closes the channel once every event published before has been received, so the
+// subscriber must keep receiving until the channel is closed
+func (b *Bus) SubscribeAll() (<-chan Event, func()) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	events := make(chan Event)
+	if b.closed {
+		close(events)
+		return events, func() {}
+	}
+
+	id := b.next
+	b.next++
+	queue := newEventQueue()
+	b.queues[id] = queue
+	go queue.forward(events)
+
+	once := sync.Once{}
+	return events, func() {
+		once.Do(func() {
+			b.mux.Lock()
+			defer b.mux.Unlock()
+
+			if queue, ok := b.queues[id]; ok {
+				delete(b.queues, id)
+				queue.close()
+			}
+		})
+	}
+}
+
+// Publish sends event to every subscriber. Publishing to a closed Bus does nothing
+func (b *Bus) Publish(event Event) {
+	if event == nil {
+		return
+	}
+
+	b.mux.RLock()
+	defer b.mux.RUnlock()
+
+	for _, subscriber := range b.subscribers {
+		if !subscriber.wants(event) {
+			continue
+		}
+
+		select {
+		case subscriber.events <- event:
+		default:
+		}
+	}
+
+	for _, queue := range b.queues {
+		queue.push(event)
+	}
+}
+
+// Close closes the channels of every subscriber. Subscribing to a closed Bus returns a closed channel
+func (b *Bus) Close() error {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	if b.closed {
+		return nil
+	}
+
+	b.closed = true
+	for id, subscriber := range b.subscribers {
+		delete(b.subscribers, id)
+		close(subscriber.events)
+	}
+
+	for id, queue := range b.queues {
+		delete(b.queues, id)
+		queue.close()
+	}
+
+	return nil
+}
+
+// subscriber is the channel of a subscriber of Subscribe along with the names of the events it subscribed to. A nil
+// set of names subscribes to every event
+type subscriber struct {
+	events chan Event
+	names  map[string]bool
+}
+
+func (s subscriber) wants(event Event) bool {
+	return s.names == nil || s.names[event.Name()]
+}
+
+func nameSet(names []string) map[string]bool {
+	if len(names) == 0 {
+		return nil
+	}
+
+	set := make(map[string]bool, len(names))
+	for _, name := range names {
+		set[name] = true
+	}
+
+	return set
+}
+
+// eventQueue is an unbounded queue of the events published to a subscriber of SubscribeAll
+type eventQueue struct {
+	mux    sync.Mutex
+	cond   *sync.Cond
+	events []Event
+	closed bool
+}
+
+func newEventQueue() *eventQueue {
+	queue := &eventQueue{}
+	queue.cond = sync.NewCond(&queue.mux)
+	return queue
+}
+
+func (q *eventQueue) push(event Event) {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+
+	q.events = append(q.events, event)
+	q.cond.Signal()
+}
+
+func (q *eventQueue) close() {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+
+	q.closed = true
+	q.cond.Signal()
+}
+
+// forward sends the queued events to ch in order, and closes ch once the queue is closed and empty
+func (q *eventQueue) forward(ch chan<- Event) {
+	defer close(ch)
+
+	for {
+		q.mux.Lock()
+		for len(q.events) == 0 && !q.closed {
+			q.cond.Wait()
+		}
+
+		if len(q.events) == 0 {
+			q.mux.Unlock()
+			return
+		}
+
+		event := q.events[0]
+		q.events[0] = nil
+		q.events = q.events[1:]
+		q.mux.Unlock()
+
+		ch <- event
+	}
+}
@@ -0,0 +1,184 @@
+package events
+
+import (
+	"errors"
+	"github.com/broar/chipmusic-cli/pkg/chipmusic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestBus_Publish(t *testing.T) {
+	bus := NewBus()
+	defer bus.Close()
+
+	first, unsubscribeFirst := bus.Subscribe(0)
+	defer unsubscribeFirst()
+
+	second, unsubscribeSecond := bus.Subscribe(0)
+	defer unsubscribeSecond()
+
+	track := &chipmusic.Track{Title: "some.title"}
+	bus.Publish(PlaybackStarted{Track: track})
+
+	for _, subscriber := range []<-chan Event{first, second} {
+		event := <-subscriber
+		started, ok := event.(PlaybackStarted)
+		require.True(t, ok)
+		assert.Equal(t, track, started.Track)
+	}
+}
+
+func TestBus_PublishNil(t *testing.T) {
+	bus := NewBus()
+	defer bus.Close()
+
+	subscriber, unsubscribe := bus.Subscribe(1)
+	defer unsubscribe()
+
+	bus.Publish(nil)
+	assert.Empty(t, subscriber)
+}
+
+func TestBus_PublishDropsWhenBufferIsFull(t *testing.T) {
+	bus := NewBus()
+	defer bus.Close()
+
+	subscriber, unsubscribe := bus.Subscribe(1)
+	defer unsubscribe()
+
+	bus.Publish(TrackResolved{})
+	bus.Publish(PlaybackStarted{})
+
+	assert.Len(t, subscriber, 1)
+	assert.Equal(t, NameTrackResolved, (<-subscriber).Name())
+}
+
+func TestBus_SubscribeNames(t *testing.T) {
+	bus := NewBus()
+	defer bus.Close()
+
+	// The events handled by the hooks, which mustn't be crowded out by the progress of a download
+	subscriber, unsubscribe := bus.Subscribe(0, NamePlaybackStarted, NamePlaybackFinished, NameError)
+	defer unsubscribe()
+
+	track := &chipmusic.Track{Title: "some.title"}
+	for i := 0; i < 10*DefaultBufferSize; i++ {
+		bus.Publish(DownloadProgress{Downloaded: int64(i)})
+	}
+
+	bus.Publish(PlaybackStarted{Track: track})
+	for i := 0; i < 10*DefaultBufferSize; i++ {
+		bus.Publish(DownloadProgress{Downloaded: int64(i)})
+	}
+
+	require.Len(t, subscriber, 1)
+	assert.Equal(t, PlaybackStarted{Track: track}, <-subscriber)
+}
+
+func TestBus_SubscribeAll(t *testing.T) {
+	bus := NewBus()
+	defer bus.Close()
+
+	subscriber, unsubscribe := bus.SubscribeAll()
+
+	// Far more events are published than any buffer holds before the subscriber receives the first of them
+	count := 10 * DefaultBufferSize
+	for i := 0; i < count; i++ {
+		bus.Publish(DownloadProgress{Downloaded: int64(i)})
+	}
+
+	bus.Publish(PlaybackStarted{})
+	unsubscribe()
+	unsubscribe()
+
+	received := make([]Event, 0, count+1)
+	for event := range subscriber {
+		received = append(received, event)
+	}
+
+	require.Len(t, received, count+1)
+	for i := 0; i < count; i++ {
+		assert.Equal(t, DownloadProgress{Downloaded: int64(i)}, received[i])
+	}
+
+	assert.Equal(t, NamePlaybackStarted, received[count].Name())
+}
+
+func TestBus_SubscribeAllClosed(t *testing.T) {
+	bus := NewBus()
+	subscriber, unsubscribe := bus.SubscribeAll()
+	defer unsubscribe()
+
+	bus.Publish(TrackResolved{})
+	require.NoError(t, bus.Close())
+
+	assert.Equal(t, NameTrackResolved, (<-subscriber).Name())
+	_, ok := <-subscriber
+	assert.False(t, ok, "channel should be closed after closing the bus")
+
+	closed, _ := bus.SubscribeAll()
+	_, ok = <-closed
+	assert.False(t, ok, "subscribing to a closed bus should return a closed channel")
+}
+
+func TestBus_Unsubscribe(t *testing.T) {
+	bus := NewBus()
+	defer bus.Close()
+
+	subscriber, unsubscribe := bus.Subscribe(1)
+	unsubscribe()
+	unsubscribe()
+
+	bus.Publish(TrackResolved{})
+
+	_, ok := <-subscriber
+	assert.False(t, ok, "channel should be closed after unsubscribing")
+}
+
+func TestBus_Close(t *testing.T) {
+	bus := NewBus()
+	subscriber, unsubscribe := bus.Subscribe(1)
+
+	require.NoError(t, bus.Close())
+	require.NoError(t, bus.Close())
+	unsubscribe()
+
+	_, ok := <-subscriber
+	assert.False(t, ok, "channel should be closed after closing the bus")
+
+	subscriber, _ = bus.Subscribe(1)
+	_, ok = <-subscriber
+	assert.False(t, ok, "subscribing to a closed bus should return a closed channel")
+
+	bus.Publish(TrackResolved{})
+}
+
+func TestEvent_Name(t *testing.T) {
+	testCases := []struct {
+		event    Event
+		expected string
+	}{
+		{TrackResolved{}, NameTrackResolved},
+		{DownloadProgress{}, NameDownloadProgress},
+		{PlaybackStarted{}, NamePlaybackStarted},
+		{Error{}, NameError},
+		{SearchPerformed{}, NameSearchPerformed},
+		{ActionPerformed{}, NameActionPerformed},
+		{TrackSkipped{}, NameTrackSkipped},
+		{PlaybackInterrupted{}, NamePlaybackInterrupted},
+		{AudioDeviceChanged{}, NameAudioDeviceChanged},
+		{VolumeChanged{}, NameVolumeChanged},
+	}
+
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.expected, testCase.event.Name())
+	}
+}
+
+func TestError_Unwrap(t *testing.T) {
+	err := errors.New("an error occurred")
+	event := Error{Err: err}
+	assert.True(t, errors.Is(event, err))
+	assert.Equal(t, err.Error(), event.Error())
+}
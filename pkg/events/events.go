@@ -0,0 +1,250 @@
+package events
+
+import (
+	"github.com/broar/chipmusic-cli/pkg/chipmusic"
+	"time"
+)
+
+const (
+	// NameTrackResolved is the name of TrackResolved events
+	NameTrackResolved = "track-resolved"
+
+	// NameDownloadProgress is the name of DownloadProgress events
+	NameDownloadProgress = "download-progress"
+
+	// NamePlaybackStarted is the name of PlaybackStarted events
+	NamePlaybackStarted = "playback-started"
+
+	// NamePlaybackFinished is the name of PlaybackFinished events
+	NamePlaybackFinished = "playback-finished"
+
+	// NameError is the name of Error events
+	NameError = "error"
+
+	// NameSearchPerformed is the name of SearchPerformed events
+	NameSearchPerformed = "search-performed"
+
+	// NameActionPerformed is the name of ActionPerformed events
+	NameActionPerformed = "action-performed"
+
+	// NameTrackSkipped is the name of TrackSkipped events
+	NameTrackSkipped = "track-skipped"
+
+	// NamePlaybackInterrupted is the name of PlaybackInterrupted events
+	NamePlaybackInterrupted = "playback-interrupted"
+
+	// NameAudioDeviceChanged is the name of AudioDeviceChanged events
+	NameAudioDeviceChanged = "audio-device-changed"
+
+	// NameVolumeChanged is the name of VolumeChanged events
+	NameVolumeChanged = "volume-changed"
+
+	// NameQueueChanged is the name of QueueChanged events
+	NameQueueChanged = "queue-changed"
+
+	// NameLinksChecked is the name of LinksChecked events
+	NameLinksChecked = "links-checked"
+
+	// NameIdlePaused is the name of IdlePaused events
+	NameIdlePaused = "idle-paused"
+
+	// NameIdleResumed is the name of IdleResumed events
+	NameIdleResumed = "idle-resumed"
+
+	// NameNotice is the name of Notice events
+	NameNotice = "notice"
+
+	// NameRetry is the name of Retry events
+	NameRetry = "retry"
+)
+
+// Event is an interface for everything published on a Bus. Subscribers should use a type switch to handle the events
+// they are interested in and ignore the rest
+type Event interface {
+
+	// Name returns a short, stable name for the type of event
+	Name() string
+}
+
+// TrackResolved is published when the metadata of a track has been fetched from its track page
+type TrackResolved struct {
+	Track *chipmusic.Track
+}
+
+func (e TrackResolved) Name() string {
+	return NameTrackResolved
+}
+
+// DownloadProgress is published while the audio of a track is downloading. Total is -1 if the size of the download is
+// unknown
+type DownloadProgress struct {
+	URL        string
+	Downloaded int64
+	Total      int64
+}
+
+func (e DownloadProgress) Name() string {
+	return NameDownloadProgress
+}
+
+// PlaybackStarted is published when a track starts playing
+type PlaybackStarted struct {
+	Track *chipmusic.Track
+}
+
+func (e PlaybackStarted) Name() string {
+	return NamePlaybackStarted
+}
+
+// PlaybackFinished is published when a track played to its end. Listened is how long it was listened to, which is at
+// most the length of the track
+type PlaybackFinished struct {
+	Track    *chipmusic.Track
+	Listened time.Duration
+}
+
+func (e PlaybackFinished) Name() string {
+	return NamePlaybackFinished
+}
+
+// Error is published when something goes wrong which does not stop the application, e.g. a track which fails to
+// download and is skipped. Track is nil if the error is not related to a particular track
+type Error struct {
+	Err   error
+	Track *chipmusic.Track
+}
+
+func (e Error) Name() string {
+	return NameError
+}
+
+func (e Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e Error) Unwrap() error {
+	return e.Err
+}
+
+// SearchPerformed is published when a search against chipmusic.org returns
+type SearchPerformed struct {
+	Search  string
+	Filter  string
+	Page    int
+	Results []string
+}
+
+func (e SearchPerformed) Name() string {
+	return NameSearchPerformed
+}
+
+// ActionPerformed is published when the user performs a track control action, e.g. pausing the current track
+type ActionPerformed struct {
+	Action string
+}
+
+func (e ActionPerformed) Name() string {
+	return NameActionPerformed
+}
+
+// TrackSkipped is published when a track is skipped without being played, e.g. because its format is not supported.
+// Track is nil if the track was skipped before its metadata was fetched
+type TrackSkipped struct {
+	Track  *chipmusic.Track
+	Reason error
+}
+
+func (e TrackSkipped) Name() string {
+	return NameTrackSkipped
+}
+
+// PlaybackInterrupted is published when playback is paused because the audio backend broke, e.g. because the system
+// was suspended or the audio device disappeared. Playback resumes when the user plays the track again
+type PlaybackInterrupted struct {
+	Reason error
+}
+
+func (e PlaybackInterrupted) Name() string {
+	return NamePlaybackInterrupted
+}
+
+// AudioDeviceChanged is published when playback moved to a new default audio device, e.g. because headphones were
+// plugged in
+type AudioDeviceChanged struct {
+	Device string
+}
+
+func (e AudioDeviceChanged) Name() string {
+	return NameAudioDeviceChanged
+}
+
+// VolumeChanged is published when the user turns the volume up or down
+type VolumeChanged struct {
+	Volume int
+}
+
+func (e VolumeChanged) Name() string {
+	return NameVolumeChanged
+}
+
+// QueueChanged is published when tracks are added to or removed from the queue of upcoming tracks. StartsIn holds how
+// long after the current track ends each track in Queue starts, counting whatever plays before it such as station
+// idents. It is nil if the lengths of the tracks are unknown
+type QueueChanged struct {
+	Queue    []*chipmusic.Track
+	StartsIn []time.Duration
+}
+
+func (e QueueChanged) Name() string {
+	return NameQueueChanged
+}
+
+// LinksChecked is published once the tracks about to be queued were checked for dead links. Dropped of the Checked
+// tracks were left out of the queue because their track page or audio is gone
+type LinksChecked struct {
+	Checked int
+	Dropped int
+}
+
+func (e LinksChecked) Name() string {
+	return NameLinksChecked
+}
+
+// IdlePaused is published when playback is paused because nobody used the dashboard and the system was idle or locked
+// for After
+type IdlePaused struct {
+	After time.Duration
+}
+
+func (e IdlePaused) Name() string {
+	return NameIdlePaused
+}
+
+// IdleResumed is published when playback paused by IdlePaused resumes because the user came back
+type IdleResumed struct{}
+
+func (e IdleResumed) Name() string {
+	return NameIdleResumed
+}
+
+// Notice is published to tell the user that something worked which they can't see otherwise, e.g. a track picked from
+// the search results was queued
+type Notice struct {
+	Message string
+}
+
+func (e Notice) Name() string {
+	return NameNotice
+}
+
+// Retry is published when a failed download of the audio of a track is tried again. Attempt is how many attempts
+// failed so far and Err is why the last one failed
+type Retry struct {
+	URL     string
+	Attempt int
+	Err     error
+}
+
+func (e Retry) Name() string {
+	return NameRetry
+}
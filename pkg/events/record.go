@@ -0,0 +1,137 @@
+package events
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Record is a single event written to a session file. Only the fields relevant to the event are set
+type Record struct {
+	Time    time.Time `json:"time"`
+	Name    string    `json:"name"`
+	URL     string    `json:"url,omitempty"`
+	Title   string    `json:"title,omitempty"`
+	Artist  string    `json:"artist,omitempty"`
+	Search  string    `json:"search,omitempty"`
+	Filter  string    `json:"filter,omitempty"`
+	Page    int       `json:"page,omitempty"`
+	Results []string  `json:"results,omitempty"`
+	Action  string    `json:"action,omitempty"`
+	Error   string    `json:"error,omitempty"`
+
+	// Size, Cached, and DownloadMS describe how the audio of a resolved track was downloaded
+	Size       int64 `json:"size,omitempty"`
+	Cached     bool  `json:"cached,omitempty"`
+	DownloadMS int64 `json:"download_ms,omitempty"`
+}
+
+// NewRecord converts an event into a Record stamped with now. Events which are not worth recording, such as download
+// progress, return false
+func NewRecord(event Event, now time.Time) (Record, bool) {
+	record := Record{Time: now, Name: event.Name()}
+	switch event := event.(type) {
+	case TrackResolved:
+		if event.Track != nil {
+			record.URL, record.Title, record.Artist = event.Track.PageURL, event.Track.Title, event.Track.Artist
+			record.Size, record.Cached = event.Track.Size, event.Track.FromCache
+			record.DownloadMS = event.Track.DownloadDuration.Milliseconds()
+		}
+	case PlaybackStarted:
+		if event.Track != nil {
+			record.URL, record.Title, record.Artist = event.Track.PageURL, event.Track.Title, event.Track.Artist
+		}
+	case SearchPerformed:
+		record.Search, record.Filter, record.Page, record.Results = event.Search, event.Filter, event.Page, event.Results
+	case ActionPerformed:
+		record.Action = event.Action
+	case Error:
+		record.Error = event.Err.Error()
+		if event.Track != nil {
+			record.URL = event.Track.PageURL
+		}
+	case TrackSkipped:
+		record.Error = event.Reason.Error()
+		if event.Track != nil {
+			record.URL, record.Title, record.Artist = event.Track.PageURL, event.Track.Title, event.Track.Artist
+		}
+	default:
+		return Record{}, false
+	}
+
+	return record, true
+}
+
+// Recorder writes events to a session file as JSON lines so a session can be replayed later, e.g. to reproduce a bug
+type Recorder struct {
+	encoder *json.Encoder
+	now     func() time.Time
+}
+
+// NewRecorder returns a Recorder which writes to w
+func NewRecorder(w io.Writer) *Recorder {
+	return &Recorder{
+		encoder: json.NewEncoder(w),
+		now:     time.Now,
+	}
+}
+
+// Record writes a single event. Events which are not worth recording are ignored
+func (r *Recorder) Record(event Event) error {
+	record, ok := NewRecord(event, r.now())
+	if !ok {
+		return nil
+	}
+
+	if err := r.encoder.Encode(record); err != nil {
+		return fmt.Errorf("failed to write %s record: %w", record.Name, err)
+	}
+
+	return nil
+}
+
+// RecordAll writes every event received on ch until ch is closed. Errors are returned on the returned channel, which
+// is closed once ch is drained
+func (r *Recorder) RecordAll(ch <-chan Event) <-chan error {
+	errs := make(chan error, 1)
+	go func() {
+		defer close(errs)
+		for event := range ch {
+			if err := r.Record(event); err != nil {
+				select {
+				case errs <- err:
+				default:
+				}
+			}
+		}
+	}()
+
+	return errs
+}
+
+// ReadRecords reads every record from a session file written by a Recorder
+func ReadRecords(r io.Reader) ([]Record, error) {
+	records := make([]Record, 0)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		record := Record{}
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("failed to parse record on line %d: %w", line, err)
+		}
+
+		records = append(records, record)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read records: %w", err)
+	}
+
+	return records, nil
+}
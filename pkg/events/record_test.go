@@ -0,0 +1,77 @@
+package events
+
+import (
+	"bytes"
+	"errors"
+	"github.com/broar/chipmusic-cli/pkg/chipmusic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecorder(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	recorder := NewRecorder(buffer)
+
+	now := time.Unix(1600000000, 0).UTC()
+	recorder.now = func() time.Time {
+		return now
+	}
+
+	track := &chipmusic.Track{Title: "some.title", Artist: "some.artist", PageURL: "some.url", Size: 1024, FromCache: true, DownloadDuration: 1500 * time.Microsecond}
+	recorded := []Event{
+		SearchPerformed{Search: "some.search", Filter: "latest", Page: 1, Results: []string{"some.url"}},
+		TrackResolved{Track: track},
+		PlaybackStarted{Track: track},
+		DownloadProgress{URL: "some.url", Downloaded: 1, Total: 2},
+		ActionPerformed{Action: "pause"},
+		Error{Err: errors.New("an error occurred"), Track: track},
+		TrackSkipped{Track: track, Reason: errors.New("skipped because audio is corrupt")},
+	}
+
+	for _, event := range recorded {
+		require.NoError(t, recorder.Record(event))
+	}
+
+	records, err := ReadRecords(buffer)
+	require.NoError(t, err)
+
+	expected := []Record{
+		{Time: now, Name: NameSearchPerformed, Search: "some.search", Filter: "latest", Page: 1, Results: []string{"some.url"}},
+		{Time: now, Name: NameTrackResolved, URL: "some.url", Title: "some.title", Artist: "some.artist", Size: 1024, Cached: true, DownloadMS: 1},
+		{Time: now, Name: NamePlaybackStarted, URL: "some.url", Title: "some.title", Artist: "some.artist"},
+		{Time: now, Name: NameActionPerformed, Action: "pause"},
+		{Time: now, Name: NameError, URL: "some.url", Error: "an error occurred"},
+		{Time: now, Name: NameTrackSkipped, URL: "some.url", Title: "some.title", Artist: "some.artist", Error: "skipped because audio is corrupt"},
+	}
+
+	assert.Equal(t, expected, records)
+}
+
+func TestRecorder_RecordAll(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	recorder := NewRecorder(buffer)
+
+	ch := make(chan Event, 2)
+	ch <- ActionPerformed{Action: "pause"}
+	ch <- ActionPerformed{Action: "skip"}
+	close(ch)
+
+	for err := range recorder.RecordAll(ch) {
+		require.NoError(t, err)
+	}
+
+	records, err := ReadRecords(buffer)
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, "pause", records[0].Action)
+	assert.Equal(t, "skip", records[1].Action)
+}
+
+func TestReadRecords_Malformed(t *testing.T) {
+	records, err := ReadRecords(strings.NewReader("{\"name\": \"error\"}\n\nnot json\n"))
+	assert.Error(t, err)
+	assert.Nil(t, records)
+}
@@ -0,0 +1,198 @@
+package fingerprint
+
+import (
+	"errors"
+	"github.com/faiface/beep"
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultThreshold is the similarity above which two fingerprints are considered to be the same song
+	DefaultThreshold = 0.85
+
+	// frameDuration is how much audio is summarized by each frame of a fingerprint
+	frameDuration = time.Second / 8
+
+	// lowPassCutoff is the cutoff frequency in Hz of the low band compared by fingerprints
+	lowPassCutoff = 500
+
+	// silence is the energy below which a frame is considered silent
+	silence = 1e-6
+
+	// maxShift is the maximum number of frames two fingerprints are shifted against each other when comparing them.
+	// This accounts for slightly different amounts of silence at the start of two uploads of the same song
+	maxShift = 8
+
+	// minOverlap is the minimum number of frames which must overlap for two fingerprints to be compared
+	minOverlap = 16
+)
+
+var (
+	// ErrTooShort is an error returned when a stream does not contain enough audio to compute a fingerprint
+	ErrTooShort = errors.New("not enough audio to compute a fingerprint")
+)
+
+// Fingerprint summarizes the loudness contour of a song. Each frame records whether the overall energy and the energy
+// of the low band rose compared to the previous frame. Since only changes are recorded, the fingerprint is unaffected
+// by volume, encoding quality, and bitrate, so the same song uploaded twice has nearly the same fingerprint
+type Fingerprint []uint8
+
+// Compute reads streamer until it is drained and returns its fingerprint. Silence at the start and end of the stream
+// is ignored
+func Compute(streamer beep.Streamer, format beep.Format) (Fingerprint, error) {
+	frameSize := format.SampleRate.N(frameDuration)
+	if frameSize <= 0 {
+		return nil, errors.New("sample rate is too low to compute a fingerprint")
+	}
+
+	// A one-pole low-pass filter separates the low band from the rest of the signal
+	alpha := 1 - math.Exp(-2*math.Pi*lowPassCutoff/float64(format.SampleRate))
+	low := 0.0
+
+	energies := make([][2]float64, 0)
+	frame := [2]float64{}
+	count := 0
+	samples := make([][2]float64, 512)
+	for {
+		n, ok := streamer.Stream(samples)
+		for _, sample := range samples[:n] {
+			mono := (sample[0] + sample[1]) / 2
+			low += alpha * (mono - low)
+			frame[0] += mono * mono
+			frame[1] += low * low
+			count++
+
+			if count == frameSize {
+				energies = append(energies, [2]float64{frame[0] / float64(count), frame[1] / float64(count)})
+				frame = [2]float64{}
+				count = 0
+			}
+		}
+
+		if !ok {
+			break
+		}
+	}
+
+	if err, ok := streamer.(interface{ Err() error }); ok && err.Err() != nil {
+		return nil, err.Err()
+	}
+
+	energies = trimSilence(energies)
+	if len(energies) <= minOverlap {
+		return nil, ErrTooShort
+	}
+
+	fingerprint := make(Fingerprint, len(energies)-1)
+	for i := 1; i < len(energies); i++ {
+		var code uint8
+		if energies[i][0] > energies[i-1][0] {
+			code |= 1
+		}
+
+		if energies[i][1] > energies[i-1][1] {
+			code |= 2
+		}
+
+		fingerprint[i-1] = code
+	}
+
+	return fingerprint, nil
+}
+
+func trimSilence(energies [][2]float64) [][2]float64 {
+	start, end := 0, len(energies)
+	for start < end && energies[start][0] < silence {
+		start++
+	}
+
+	for end > start && energies[end-1][0] < silence {
+		end--
+	}
+
+	return energies[start:end]
+}
+
+// Similarity returns the fraction of matching bits between two fingerprints, from 0 to 1, at the alignment where they
+// match best. Unrelated songs score around 0.5. If the fingerprints overlap by too little to be compared, 0 is returned
+func (f Fingerprint) Similarity(other Fingerprint) float64 {
+	longer := len(f)
+	if len(other) > longer {
+		longer = len(other)
+	}
+
+	// Fingerprints must overlap by at least half of the longer one so a short snippet can't match a whole song
+	required := longer / 2
+	if required < minOverlap {
+		required = minOverlap
+	}
+
+	best := 0.0
+	for shift := -maxShift; shift <= maxShift; shift++ {
+		matching, compared := 0, 0
+		for i := range f {
+			j := i + shift
+			if j < 0 || j >= len(other) {
+				continue
+			}
+
+			diff := f[i] ^ other[j]
+			matching += 2 - int(diff&1) - int(diff>>1&1)
+			compared += 2
+		}
+
+		if compared/2 < required {
+			continue
+		}
+
+		if similarity := float64(matching) / float64(compared); similarity > best {
+			best = similarity
+		}
+	}
+
+	return best
+}
+
+// Matches returns true if the fingerprints are similar enough to be the same song
+func (f Fingerprint) Matches(other Fingerprint) bool {
+	return f.Similarity(other) >= DefaultThreshold
+}
+
+// Index remembers the fingerprints of songs by a key, such as the track page URL, to find duplicates. An Index is
+// safe for concurrent use
+type Index struct {
+	mux          sync.Mutex
+	keys         []string
+	fingerprints []Fingerprint
+}
+
+// NewIndex returns an empty Index
+func NewIndex() *Index {
+	return &Index{}
+}
+
+// Add adds a fingerprint under key unless it matches a fingerprint which is already in the index. If it matches, the
+// key of the existing fingerprint is returned along with true
+func (i *Index) Add(key string, fingerprint Fingerprint) (string, bool) {
+	i.mux.Lock()
+	defer i.mux.Unlock()
+
+	for j, existing := range i.fingerprints {
+		if existing.Matches(fingerprint) {
+			return i.keys[j], true
+		}
+	}
+
+	i.keys = append(i.keys, key)
+	i.fingerprints = append(i.fingerprints, fingerprint)
+	return "", false
+}
+
+// Len returns the number of fingerprints in the index
+func (i *Index) Len() int {
+	i.mux.Lock()
+	defer i.mux.Unlock()
+	return len(i.fingerprints)
+}
@@ -0,0 +1,114 @@
+package fingerprint
+
+import (
+	"github.com/faiface/beep"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+var (
+	testFormat = beep.Format{SampleRate: 8000, NumChannels: 2, Precision: 2}
+)
+
+// newSong generates a tone whose loudness and pitch change randomly every frame. The same seed always generates the
+// same song, while gain, noise, and silence vary how it was recorded
+func newSong(seed int64, seconds int, gain, noise float64, silence int) beep.Streamer {
+	melody := rand.New(rand.NewSource(seed))
+	hiss := rand.New(rand.NewSource(seed + 1000))
+	frameSize := testFormat.SampleRate.N(frameDuration)
+
+	samples := make([][2]float64, silence)
+	phase := 0.0
+	for frame := 0; frame < seconds*8; frame++ {
+		amplitude := 0.1 + 0.8*melody.Float64()
+		frequency := 100 + 1500*melody.Float64()
+		for i := 0; i < frameSize; i++ {
+			phase += 2 * math.Pi * frequency / float64(testFormat.SampleRate)
+			value := gain*amplitude*math.Sin(phase) + noise*(hiss.Float64()*2-1)
+			samples = append(samples, [2]float64{value, value})
+		}
+	}
+
+	return &sliceStreamer{samples: samples}
+}
+
+type sliceStreamer struct {
+	samples [][2]float64
+	pos     int
+}
+
+func (s *sliceStreamer) Stream(samples [][2]float64) (int, bool) {
+	if s.pos >= len(s.samples) {
+		return 0, false
+	}
+
+	n := copy(samples, s.samples[s.pos:])
+	s.pos += n
+	return n, true
+}
+
+func (s *sliceStreamer) Err() error {
+	return nil
+}
+
+func computeSong(t *testing.T, seed int64, seconds int, gain, noise float64, silence int) Fingerprint {
+	fingerprint, err := Compute(newSong(seed, seconds, gain, noise, silence), testFormat)
+	require.NoError(t, err)
+	return fingerprint
+}
+
+func TestCompute_TooShort(t *testing.T) {
+	fingerprint, err := Compute(newSong(1, 1, 1, 0, 0), testFormat)
+	assert.Equal(t, ErrTooShort, err)
+	assert.Nil(t, fingerprint)
+
+	fingerprint, err = Compute(&sliceStreamer{samples: make([][2]float64, 8000*10)}, testFormat)
+	assert.Equal(t, ErrTooShort, err, "silence should not have a fingerprint")
+	assert.Nil(t, fingerprint)
+}
+
+func TestFingerprint_Matches(t *testing.T) {
+	original := computeSong(t, 1, 30, 1, 0, 0)
+
+	testCases := []struct {
+		name     string
+		other    Fingerprint
+		expected bool
+	}{
+		{"Identical", computeSong(t, 1, 30, 1, 0, 0), true},
+		{"Quieter", computeSong(t, 1, 30, 0.5, 0, 0), true},
+		{"Noisy", computeSong(t, 1, 30, 1, 0.02, 0), true},
+		{"LeadingSilence", computeSong(t, 1, 30, 1, 0, 4000), true},
+		{"Shorter", computeSong(t, 1, 20, 1, 0, 0), true},
+		{"DifferentSong", computeSong(t, 2, 30, 1, 0, 0), false},
+		{"TooLittleOverlap", computeSong(t, 1, 5, 1, 0, 0), false},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			assert.Equal(tt, testCase.expected, original.Matches(testCase.other), "similarity was %f", original.Similarity(testCase.other))
+			assert.Equal(tt, testCase.expected, testCase.other.Matches(original), "similarity should be symmetric")
+		})
+	}
+}
+
+func TestIndex(t *testing.T) {
+	index := NewIndex()
+
+	duplicate, ok := index.Add("some.url", computeSong(t, 1, 30, 1, 0, 0))
+	assert.False(t, ok)
+	assert.Empty(t, duplicate)
+
+	duplicate, ok = index.Add("some.other.url", computeSong(t, 2, 30, 1, 0, 0))
+	assert.False(t, ok)
+	assert.Empty(t, duplicate)
+
+	duplicate, ok = index.Add("some.reupload.url", computeSong(t, 1, 30, 0.5, 0.01, 2000))
+	assert.True(t, ok)
+	assert.Equal(t, "some.url", duplicate)
+
+	assert.Equal(t, 2, index.Len())
+}
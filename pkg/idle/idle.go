@@ -0,0 +1,84 @@
+package idle
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// DefaultInterval is how often the system is checked for activity
+const DefaultInterval = 10 * time.Second
+
+// ErrUnsupported is returned when the activity of the user cannot be read on this system, e.g. because no idle tool is
+// installed
+var ErrUnsupported = errors.New("detecting inactivity is not supported on this system")
+
+// State is how active the user of the system is
+type State struct {
+
+	// Idle is how long ago the user last used the keyboard or mouse
+	Idle time.Duration
+
+	// Locked is true if the screen is locked. It is always false on systems which don't report it
+	Locked bool
+}
+
+// Monitor detects when the user goes away from the system and comes back by reading its State periodically
+type Monitor struct {
+	interval time.Duration
+	timeout  time.Duration
+
+	// read returns the State of the system
+	read func() (State, error)
+}
+
+// NewMonitor creates a Monitor which reads the State of the system every interval and considers the user away once the
+// screen is locked or they haven't used the keyboard or mouse for timeout
+func NewMonitor(interval, timeout time.Duration) *Monitor {
+	return &Monitor{
+		interval: interval,
+		timeout:  timeout,
+		read:     Read,
+	}
+}
+
+// Watch returns a channel receiving true each time the user goes away and false each time they come back. The user is
+// assumed to be around when watching starts. The channel is closed once ctx is done. Errors reading the State are
+// ignored so a tool failing once doesn't look like the user coming back
+func (m *Monitor) Watch(ctx context.Context) <-chan bool {
+	changes := make(chan bool)
+	go func() {
+		defer close(changes)
+
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+
+		away := false
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			state, err := m.read()
+			if err != nil || state.Away(m.timeout) == away {
+				continue
+			}
+
+			away = !away
+			select {
+			case changes <- away:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return changes
+}
+
+// Away returns true if the screen is locked or the user hasn't used the keyboard or mouse for timeout
+func (s State) Away(timeout time.Duration) bool {
+	return s.Locked || s.Idle >= timeout
+}
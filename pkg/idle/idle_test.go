@@ -0,0 +1,68 @@
+package idle
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+	"time"
+)
+
+const defaultTestTimeout = 5 * time.Second
+
+func TestMonitor_Watch(t *testing.T) {
+	mux := sync.Mutex{}
+	state, err := State{Idle: time.Second}, error(nil)
+	monitor := &Monitor{
+		interval: time.Millisecond,
+		timeout:  time.Minute,
+		read: func() (State, error) {
+			mux.Lock()
+			defer mux.Unlock()
+			return state, err
+		},
+	}
+
+	set := func(s State, e error) {
+		mux.Lock()
+		state, err = s, e
+		mux.Unlock()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	changes := monitor.Watch(ctx)
+
+	receive := func() bool {
+		select {
+		case away := <-changes:
+			return away
+		case <-time.After(defaultTestTimeout):
+			require.FailNow(t, "change of activity was not detected")
+			return false
+		}
+	}
+
+	set(State{Idle: time.Minute}, nil)
+	assert.True(t, receive(), "expected the user to be away once idle for the timeout")
+
+	// A tool failing once must not look like the user coming back
+	set(State{}, errors.New("xprintidle failed"))
+	time.Sleep(10 * time.Millisecond)
+	set(State{Idle: time.Second, Locked: true}, nil)
+	time.Sleep(10 * time.Millisecond)
+
+	set(State{Idle: time.Second}, nil)
+	assert.False(t, receive(), "expected the user to be back once active and unlocked")
+
+	cancel()
+	for range changes {
+	}
+}
+
+func TestState_Away(t *testing.T) {
+	assert.False(t, State{Idle: time.Second}.Away(time.Minute))
+	assert.True(t, State{Idle: time.Minute}.Away(time.Minute))
+	assert.True(t, State{Locked: true}.Away(time.Minute))
+}
@@ -0,0 +1,34 @@
+// +build darwin
+
+package idle
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+var hidIdleTimePattern = regexp.MustCompile(`"HIDIdleTime" = (\d+)`)
+
+// Read returns the State of the system. How long the user has been idle is read from the HID system with ioreg. macOS
+// doesn't report whether the screen is locked to other processes, so Locked is always false
+func Read() (State, error) {
+	output, err := exec.Command("ioreg", "-c", "IOHIDSystem", "-d", "4").Output()
+	if err != nil {
+		return State{}, fmt.Errorf("failed to read idle time with ioreg: %w", err)
+	}
+
+	match := hidIdleTimePattern.FindSubmatch(output)
+	if match == nil {
+		return State{}, ErrUnsupported
+	}
+
+	ns, err := strconv.ParseInt(string(match[1]), 10, 64)
+	if err != nil {
+		return State{}, fmt.Errorf("invalid idle time from ioreg: %w", err)
+	}
+
+	return State{Idle: time.Duration(ns)}, nil
+}
@@ -0,0 +1,86 @@
+// +build linux
+
+package idle
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Read returns the State of the system. How long the user has been idle is read with xprintidle on X11. Otherwise, and
+// to find out whether the screen is locked, the session is looked up with loginctl, which relies on the desktop
+// reporting its idle hint to logind. If neither tool is installed, ErrUnsupported is returned
+func Read() (State, error) {
+	state, supported := State{}, false
+	if _, err := exec.LookPath("loginctl"); err == nil {
+		output, err := exec.Command("loginctl", "show-session", session(), "-p", "IdleHint", "-p", "IdleSinceHint", "-p", "LockedHint").Output()
+		if err != nil {
+			return State{}, fmt.Errorf("failed to read session with loginctl: %w", err)
+		}
+
+		state, supported = parseLoginctl(string(output), time.Now()), true
+	}
+
+	if _, err := exec.LookPath("xprintidle"); err == nil && os.Getenv("DISPLAY") != "" {
+		output, err := exec.Command("xprintidle").Output()
+		if err != nil {
+			return State{}, fmt.Errorf("failed to read idle time with xprintidle: %w", err)
+		}
+
+		ms, err := strconv.ParseInt(strings.TrimSpace(string(output)), 10, 64)
+		if err != nil {
+			return State{}, fmt.Errorf("invalid idle time from xprintidle: %w", err)
+		}
+
+		state.Idle, supported = time.Duration(ms)*time.Millisecond, true
+	}
+
+	if !supported {
+		return State{}, ErrUnsupported
+	}
+
+	return state, nil
+}
+
+// session returns the logind session of the user, which is the session of the process unless it wasn't started from
+// one, e.g. in a systemd user service
+func session() string {
+	if id := os.Getenv("XDG_SESSION_ID"); id != "" {
+		return id
+	}
+
+	return "auto"
+}
+
+// parseLoginctl returns the State in the properties printed by loginctl show-session at now. IdleSinceHint is the
+// wall clock time in microseconds when the session became idle
+func parseLoginctl(output string, now time.Time) State {
+	properties := map[string]string{}
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		if i := strings.Index(scanner.Text(), "="); i >= 0 {
+			properties[scanner.Text()[:i]] = scanner.Text()[i+1:]
+		}
+	}
+
+	state := State{Locked: properties["LockedHint"] == "yes"}
+	if properties["IdleHint"] != "yes" {
+		return state
+	}
+
+	since, err := strconv.ParseInt(properties["IdleSinceHint"], 10, 64)
+	if err != nil || since == 0 {
+		return state
+	}
+
+	if idle := now.Sub(time.Unix(0, since*int64(time.Microsecond))); idle > 0 {
+		state.Idle = idle
+	}
+
+	return state
+}
@@ -0,0 +1,32 @@
+// +build linux
+
+package idle
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestParseLoginctl(t *testing.T) {
+	now := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	since := now.Add(-5*time.Minute).UnixNano() / int64(time.Microsecond)
+
+	testCases := []struct {
+		name   string
+		output string
+		state  State
+	}{
+		{"Active", "IdleHint=no\nIdleSinceHint=0\nLockedHint=no\n", State{}},
+		{"Idle", "IdleHint=yes\nIdleSinceHint=" + strconv.FormatInt(since, 10) + "\nLockedHint=no\n", State{Idle: 5 * time.Minute}},
+		{"Locked", "IdleHint=no\nIdleSinceHint=0\nLockedHint=yes\n", State{Locked: true}},
+		{"Missing", "", State{}},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			assert.Equal(tt, testCase.state, parseLoginctl(testCase.output, now))
+		})
+	}
+}
@@ -0,0 +1,9 @@
+// +build !linux,!darwin,!windows
+
+package idle
+
+// Read returns the State of the system. Detecting inactivity is only supported on Linux, macOS, and Windows, so
+// ErrUnsupported is always returned
+func Read() (State, error) {
+	return State{}, ErrUnsupported
+}
@@ -0,0 +1,36 @@
+// +build windows
+
+package idle
+
+import (
+	"fmt"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+var (
+	user32           = syscall.NewLazyDLL("user32.dll")
+	kernel32         = syscall.NewLazyDLL("kernel32.dll")
+	getLastInputInfo = user32.NewProc("GetLastInputInfo")
+	getTickCount     = kernel32.NewProc("GetTickCount")
+)
+
+// lastInputInfo is the LASTINPUTINFO structure filled in by GetLastInputInfo
+type lastInputInfo struct {
+	size uint32
+	time uint32
+}
+
+// Read returns the State of the system. How long the user has been idle is the time since the last input event of the
+// session. Windows doesn't report whether the screen is locked to other processes, so Locked is always false
+func Read() (State, error) {
+	info := lastInputInfo{size: uint32(unsafe.Sizeof(lastInputInfo{}))}
+	if ok, _, err := getLastInputInfo.Call(uintptr(unsafe.Pointer(&info))); ok == 0 {
+		return State{}, fmt.Errorf("failed to get last input: %w", err)
+	}
+
+	// Both tick counts wrap around after 49.7 days, which the unsigned subtraction accounts for
+	now, _, _ := getTickCount.Call()
+	return State{Idle: time.Duration(uint32(now)-info.time) * time.Millisecond}, nil
+}
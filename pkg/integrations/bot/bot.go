@@ -0,0 +1,259 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/broar/chipmusic-cli/pkg/chipmusic"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+const (
+	// CommandSkip is the name of the command which skips the current track
+	CommandSkip = "skip"
+
+	// DefaultNick is the nick the bot uses on IRC unless another nick is set
+	DefaultNick = "chipmusic"
+)
+
+// Command is a command sent to the bot from chat which changes playback, e.g. !skip
+type Command struct {
+	Name string
+
+	// From is the nick of the user who sent the command
+	From string
+}
+
+// Bot announces the tracks played during a session in chat and accepts simple commands from chat. Tracks are announced
+// to a Discord webhook, an IRC channel, or both. Commands are only accepted on IRC since Discord webhooks can't receive
+// messages. Anyone can ask what is playing with !np but only operators can !skip
+type Bot struct {
+	client         *http.Client
+	discordWebhook string
+	irc            *ircConfig
+	operators      map[string]bool
+	commands       chan Command
+
+	// closing is closed when the bot is closed so a command which is never received doesn't block forever
+	closing chan struct{}
+
+	// done is closed once the connection to IRC stops being handled
+	done chan struct{}
+
+	mux     sync.Mutex
+	current *chipmusic.Track
+	conn    *ircConn
+}
+
+// Option is an alias for a function that modifies a Bot. An Option is used to override the default values of Bot
+type Option func(b *Bot) error
+
+// WithHTTPClient allows overriding the HTTP client used to send messages to Discord
+func WithHTTPClient(client *http.Client) Option {
+	return func(b *Bot) error {
+		if client == nil {
+			return errors.New("client cannot be nil")
+		}
+
+		b.client = client
+		return nil
+	}
+}
+
+// WithDiscordWebhook allows announcing tracks to the Discord channel of a webhook
+func WithDiscordWebhook(webhookURL string) Option {
+	return func(b *Bot) error {
+		if !strings.HasPrefix(webhookURL, "https://") && !strings.HasPrefix(webhookURL, "http://") {
+			return fmt.Errorf("%s is an invalid Discord webhook URL: must be an HTTP URL", webhookURL)
+		}
+
+		b.discordWebhook = webhookURL
+		return nil
+	}
+}
+
+// WithIRC allows announcing tracks to an IRC channel and accepting commands from it. The server is a host:port
+// address. If nick is empty, DefaultNick is used
+func WithIRC(server, channel, nick string, useTLS bool) Option {
+	return func(b *Bot) error {
+		if server == "" {
+			return errors.New("IRC server cannot be empty")
+		}
+
+		if !strings.HasPrefix(channel, "#") {
+			return fmt.Errorf("%s is an invalid IRC channel: must start with #", channel)
+		}
+
+		if nick == "" {
+			nick = DefaultNick
+		}
+
+		b.irc = &ircConfig{server: server, channel: channel, nick: nick, tls: useTLS}
+		return nil
+	}
+}
+
+// WithOperators allows the users with nicks to send commands which change playback, e.g. !skip
+func WithOperators(nicks ...string) Option {
+	return func(b *Bot) error {
+		for _, nick := range nicks {
+			if nick == "" {
+				return errors.New("operator nick cannot be empty")
+			}
+
+			b.operators[strings.ToLower(nick)] = true
+		}
+
+		return nil
+	}
+}
+
+// NewBot creates a new Bot object that is configured with a list of Options. At least one of WithDiscordWebhook and
+// WithIRC is required
+func NewBot(options ...Option) (*Bot, error) {
+	b := &Bot{
+		client:    http.DefaultClient,
+		operators: map[string]bool{},
+		commands:  make(chan Command),
+		closing:   make(chan struct{}),
+	}
+
+	for _, option := range options {
+		if err := option(b); err != nil {
+			return nil, err
+		}
+	}
+
+	if b.discordWebhook == "" && b.irc == nil {
+		return nil, errors.New("bot needs a Discord webhook or an IRC channel")
+	}
+
+	return b, nil
+}
+
+// Start connects to IRC and handles messages from the channel until ctx is done or the connection is lost. If IRC is
+// not configured, this method does nothing
+func (b *Bot) Start(ctx context.Context) error {
+	if b.irc == nil {
+		return nil
+	}
+
+	conn, err := dialIRC(ctx, b.irc)
+	if err != nil {
+		return fmt.Errorf("failed to connect to IRC server %s: %w", b.irc.server, err)
+	}
+
+	b.mux.Lock()
+	b.conn = conn
+	b.done = make(chan struct{})
+	b.mux.Unlock()
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-b.closing:
+		}
+	}()
+
+	go func() {
+		defer close(b.done)
+
+		// The connection is gone once the loop ends, so there is nothing left to report the error to
+		_ = conn.handle(b.handleMessage)
+	}()
+
+	return nil
+}
+
+// Commands returns a channel receiving the commands sent by operators
+func (b *Bot) Commands() <-chan Command {
+	return b.commands
+}
+
+// Announce announces that track started playing to every configured chat
+func (b *Bot) Announce(ctx context.Context, track *chipmusic.Track) error {
+	if track == nil {
+		return errors.New("track cannot be nil")
+	}
+
+	b.mux.Lock()
+	b.current = track
+	conn := b.conn
+	b.mux.Unlock()
+
+	message := nowPlaying(track)
+	if b.discordWebhook != "" {
+		if err := postDiscordMessage(ctx, b.client, b.discordWebhook, message); err != nil {
+			return fmt.Errorf("failed to announce track to Discord: %w", err)
+		}
+	}
+
+	if conn != nil {
+		if err := conn.privmsg(b.irc.channel, message); err != nil {
+			return fmt.Errorf("failed to announce track to IRC: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Close disconnects from IRC and closes the channel returned by Commands
+func (b *Bot) Close() error {
+	close(b.closing)
+
+	b.mux.Lock()
+	conn, done := b.conn, b.done
+	b.mux.Unlock()
+
+	var err error
+	if conn != nil {
+		err = conn.Close()
+		<-done
+	}
+
+	close(b.commands)
+	return err
+}
+
+// handleMessage replies to the commands sent to the channel and returns the reply. Messages which are not commands
+// are ignored
+func (b *Bot) handleMessage(from, text string) string {
+	switch strings.ToLower(strings.TrimSpace(text)) {
+	case "!np":
+		b.mux.Lock()
+		current := b.current
+		b.mux.Unlock()
+
+		if current == nil {
+			return "Nothing is playing"
+		}
+
+		return nowPlaying(current)
+	case "!skip":
+		if !b.operators[strings.ToLower(from)] {
+			return fmt.Sprintf("%s: only operators can skip tracks", from)
+		}
+
+		select {
+		case b.commands <- Command{Name: CommandSkip, From: from}:
+			return fmt.Sprintf("%s skipped the track", from)
+		case <-b.closing:
+			return ""
+		}
+	default:
+		return ""
+	}
+}
+
+// nowPlaying formats the message announcing track
+func nowPlaying(track *chipmusic.Track) string {
+	message := fmt.Sprintf("Now playing: %s by %s", track.Title, track.Artist)
+	if track.PageURL != "" {
+		message += " " + track.PageURL
+	}
+
+	return message
+}
@@ -0,0 +1,199 @@
+package bot
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/broar/chipmusic-cli/pkg/chipmusic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const defaultTestTimeout = 3 * time.Second
+
+var testTrack = &chipmusic.Track{
+	Title:   "Virtues",
+	Artist:  "Hide Your Tigers",
+	PageURL: "https://chipmusic.org/Hide+Your+Tigers/music/virtues-lsdj",
+}
+
+// ircServer is a fake IRC server which accepts a single client
+type ircServer struct {
+	listener net.Listener
+	conn     net.Conn
+	reader   *bufio.Reader
+}
+
+func newIRCServer(t *testing.T) *ircServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err, "failed to listen")
+	return &ircServer{listener: listener}
+}
+
+// accept accepts the client and welcomes it once it registers
+func (s *ircServer) accept(t *testing.T) {
+	conn, err := s.listener.Accept()
+	require.NoError(t, err, "failed to accept client")
+
+	s.conn, s.reader = conn, bufio.NewReader(conn)
+	s.expect(t, "NICK chipmusic")
+	s.expect(t, "USER chipmusic 0 * :chipmusic")
+	s.send(t, "PING :irc.example.org")
+	s.send(t, ":irc.example.org 001 chipmusic :Welcome")
+}
+
+func (s *ircServer) send(t *testing.T, line string) {
+	_, err := fmt.Fprintf(s.conn, "%s\r\n", line)
+	require.NoError(t, err, "failed to send %s", line)
+}
+
+// expect reads the next line sent by the client, skipping pongs
+func (s *ircServer) expect(t *testing.T, expected string) {
+	require.NoError(t, s.conn.SetReadDeadline(time.Now().Add(defaultTestTimeout)))
+	for {
+		line, err := s.reader.ReadString('\n')
+		require.NoError(t, err, "failed to read %s", expected)
+
+		line = strings.TrimRight(line, "\r\n")
+		if strings.HasPrefix(line, "PONG") {
+			continue
+		}
+
+		assert.Equal(t, expected, line)
+		return
+	}
+}
+
+func (s *ircServer) Close() {
+	if s.conn != nil {
+		s.conn.Close()
+	}
+
+	s.listener.Close()
+}
+
+func TestNewBot(t *testing.T) {
+	testCases := []struct {
+		name        string
+		options     []Option
+		expectError bool
+	}{
+		{"Discord", []Option{WithDiscordWebhook("https://discord.com/api/webhooks/1/token")}, false},
+		{"IRC", []Option{WithIRC("irc.libera.chat:6697", "#chipmusic", "", true)}, false},
+		{"Nothing", nil, true},
+		{"InvalidWebhook", []Option{WithDiscordWebhook("discord.com/api/webhooks/1/token")}, true},
+		{"EmptyServer", []Option{WithIRC("", "#chipmusic", "", false)}, true},
+		{"InvalidChannel", []Option{WithIRC("irc.libera.chat:6697", "chipmusic", "", false)}, true},
+		{"EmptyOperator", []Option{WithDiscordWebhook("https://discord.com/api/webhooks/1/token"), WithOperators("")}, true},
+		{"NilHTTPClient", []Option{WithDiscordWebhook("https://discord.com/api/webhooks/1/token"), WithHTTPClient(nil)}, true},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			b, err := NewBot(testCase.options...)
+			if testCase.expectError {
+				assert.Error(tt, err)
+				assert.Nil(tt, b)
+				return
+			}
+
+			require.NoError(tt, err)
+			assert.NotNil(tt, b)
+		})
+	}
+}
+
+func TestBot_AnnounceDiscord(t *testing.T) {
+	messages := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var message discordMessage
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&message))
+		messages <- message.Content
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	b, err := NewBot(WithDiscordWebhook(server.URL), WithHTTPClient(server.Client()))
+	require.NoError(t, err)
+
+	defer b.Close()
+
+	require.NoError(t, b.Start(context.Background()))
+	require.NoError(t, b.Announce(context.Background(), testTrack))
+	assert.Equal(t, "Now playing: Virtues by Hide Your Tigers https://chipmusic.org/Hide+Your+Tigers/music/virtues-lsdj", <-messages)
+	assert.Error(t, b.Announce(context.Background(), nil))
+}
+
+func TestBot_IRC(t *testing.T) {
+	server := newIRCServer(t)
+	defer server.Close()
+
+	b, err := NewBot(WithIRC(server.listener.Addr().String(), "#chipmusic", "", false), WithOperators("Fearofdark"))
+	require.NoError(t, err)
+
+	started := make(chan error, 1)
+	go func() {
+		started <- b.Start(context.Background())
+	}()
+
+	server.accept(t)
+	server.expect(t, "JOIN #chipmusic")
+	require.NoError(t, <-started, "failed to start bot")
+
+	server.send(t, ":listener!user@host PRIVMSG #chipmusic :!np")
+	server.expect(t, "PRIVMSG #chipmusic :Nothing is playing")
+
+	require.NoError(t, b.Announce(context.Background(), testTrack))
+	server.expect(t, "PRIVMSG #chipmusic :Now playing: Virtues by Hide Your Tigers https://chipmusic.org/Hide+Your+Tigers/music/virtues-lsdj")
+
+	server.send(t, ":listener!user@host PRIVMSG #chipmusic :!skip")
+	server.expect(t, "PRIVMSG #chipmusic :listener: only operators can skip tracks")
+
+	// Messages to other targets and other chatter are ignored
+	server.send(t, ":listener!user@host PRIVMSG chipmusic :!np")
+	server.send(t, ":listener!user@host PRIVMSG #chipmusic :nice track")
+
+	server.send(t, ":fearofdark!user@host PRIVMSG #chipmusic :!skip")
+	select {
+	case command := <-b.Commands():
+		assert.Equal(t, Command{Name: CommandSkip, From: "fearofdark"}, command)
+	case <-time.After(defaultTestTimeout):
+		require.FailNow(t, "skip command was not received")
+	}
+
+	server.expect(t, "PRIVMSG #chipmusic :fearofdark skipped the track")
+
+	require.NoError(t, b.Close())
+	for range b.Commands() {
+		require.FailNow(t, "no more commands should be received")
+	}
+}
+
+func TestBot_IRCNickInUse(t *testing.T) {
+	server := newIRCServer(t)
+	defer server.Close()
+
+	b, err := NewBot(WithIRC(server.listener.Addr().String(), "#chipmusic", "", false))
+	require.NoError(t, err)
+
+	started := make(chan error, 1)
+	go func() {
+		started <- b.Start(context.Background())
+	}()
+
+	conn, err := server.listener.Accept()
+	require.NoError(t, err)
+
+	server.conn, server.reader = conn, bufio.NewReader(conn)
+	server.expect(t, "NICK chipmusic")
+	server.send(t, ":irc.example.org 433 * chipmusic :Nickname is already in use")
+
+	assert.Error(t, <-started)
+}
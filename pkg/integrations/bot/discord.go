@@ -0,0 +1,42 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// discordMessage is the body of a message sent to a Discord webhook
+type discordMessage struct {
+	Content string `json:"content"`
+}
+
+// postDiscordMessage sends content to the channel of a Discord webhook
+func postDiscordMessage(ctx context.Context, client *http.Client, webhookURL, content string) error {
+	body, err := json.Marshal(discordMessage{Content: content})
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %w", err)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build request to send message: %w", err)
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	response, err := client.Do(request)
+	if err != nil {
+		return fmt.Errorf("failed to get response when sending message: %w", err)
+	}
+
+	defer response.Body.Close()
+
+	// Webhooks answer 204 No Content unless asked to wait for the message to be created
+	if response.StatusCode != http.StatusOK && response.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("expected status code %d when sending message but got %d instead", http.StatusNoContent, response.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,45 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPostDiscordMessage(t *testing.T) {
+	testCases := []struct {
+		name        string
+		status      int
+		expectError bool
+	}{
+		{"NoContent", http.StatusNoContent, false},
+		{"OK", http.StatusOK, false},
+		{"NotFound", http.StatusNotFound, true},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			var received discordMessage
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(tt, http.MethodPost, r.Method)
+				assert.Equal(tt, "application/json", r.Header.Get("Content-Type"))
+				require.NoError(tt, json.NewDecoder(r.Body).Decode(&received))
+				w.WriteHeader(testCase.status)
+			}))
+			defer server.Close()
+
+			err := postDiscordMessage(context.Background(), server.Client(), server.URL, "Now playing")
+			if testCase.expectError {
+				assert.Error(tt, err)
+			} else {
+				assert.NoError(tt, err)
+			}
+
+			assert.Equal(tt, "Now playing", received.Content)
+		})
+	}
+}
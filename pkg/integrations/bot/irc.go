@@ -0,0 +1,207 @@
+package bot
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+)
+
+// ircConfig is where the bot connects to on IRC
+type ircConfig struct {
+	server  string
+	channel string
+	nick    string
+	tls     bool
+}
+
+// ircMessage is a single line sent by an IRC server
+type ircMessage struct {
+	// prefix is the source of the message, e.g. nick!user@host
+	prefix  string
+	command string
+	params  []string
+}
+
+// nick returns the nick of the user who sent the message
+func (m ircMessage) nick() string {
+	if i := strings.Index(m.prefix, "!"); i >= 0 {
+		return m.prefix[:i]
+	}
+
+	return m.prefix
+}
+
+// ircConn is a connection to an IRC server which has joined a channel
+type ircConn struct {
+	conn    net.Conn
+	reader  *bufio.Reader
+	channel string
+
+	mux sync.Mutex
+}
+
+// dialIRC connects to the server of config, registers the nick, and joins the channel
+func dialIRC(ctx context.Context, config *ircConfig) (*ircConn, error) {
+	dialer := &net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", config.server)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.tls {
+		host, _, err := net.SplitHostPort(config.server)
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to parse server address: %w", err)
+		}
+
+		conn = tls.Client(conn, &tls.Config{ServerName: host})
+	}
+
+	c := &ircConn{conn: conn, reader: bufio.NewReader(conn), channel: config.channel}
+	if err := c.register(config.nick); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if err := c.send("JOIN " + config.channel); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to join %s: %w", config.channel, err)
+	}
+
+	return c, nil
+}
+
+// register registers the nick and waits until the server welcomes the bot
+func (c *ircConn) register(nick string) error {
+	if err := c.send("NICK " + nick); err != nil {
+		return fmt.Errorf("failed to send nick: %w", err)
+	}
+
+	if err := c.send(fmt.Sprintf("USER %s 0 * :%s", nick, nick)); err != nil {
+		return fmt.Errorf("failed to send user: %w", err)
+	}
+
+	for {
+		message, err := c.read()
+		if err != nil {
+			return fmt.Errorf("failed to register nick %s: %w", nick, err)
+		}
+
+		switch message.command {
+		case "PING":
+			if err := c.pong(message); err != nil {
+				return err
+			}
+		case "001":
+			return nil
+		case "432", "433":
+			return fmt.Errorf("nick %s is invalid or already in use", nick)
+		case "ERROR":
+			return fmt.Errorf("server closed the connection: %s", strings.Join(message.params, " "))
+		}
+	}
+}
+
+// handle answers pings and replies to messages sent to the channel with the reply returned by handler until the
+// connection is closed. An empty reply is not sent
+func (c *ircConn) handle(handler func(from, text string) string) error {
+	for {
+		message, err := c.read()
+		if err != nil {
+			return err
+		}
+
+		switch message.command {
+		case "PING":
+			if err := c.pong(message); err != nil {
+				return err
+			}
+		case "PRIVMSG":
+			if len(message.params) < 2 || !strings.EqualFold(message.params[0], c.channel) {
+				continue
+			}
+
+			if reply := handler(message.nick(), message.params[1]); reply != "" {
+				if err := c.privmsg(c.channel, reply); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// privmsg sends text to target, which is a channel or a nick
+func (c *ircConn) privmsg(target, text string) error {
+	// Line breaks would end the message early and send the rest as a raw command
+	text = strings.NewReplacer("\r", " ", "\n", " ").Replace(text)
+	return c.send(fmt.Sprintf("PRIVMSG %s :%s", target, text))
+}
+
+func (c *ircConn) pong(ping ircMessage) error {
+	if len(ping.params) == 0 {
+		return c.send("PONG")
+	}
+
+	return c.send("PONG :" + ping.params[len(ping.params)-1])
+}
+
+func (c *ircConn) send(line string) error {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	_, err := c.conn.Write([]byte(line + "\r\n"))
+	return err
+}
+
+func (c *ircConn) read() (ircMessage, error) {
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		return ircMessage{}, err
+	}
+
+	return parseIRCMessage(strings.TrimRight(line, "\r\n"))
+}
+
+// Close closes the connection to the server
+func (c *ircConn) Close() error {
+	return c.conn.Close()
+}
+
+// parseIRCMessage parses a line sent by an IRC server, e.g. ":nick!user@host PRIVMSG #channel :!np"
+func parseIRCMessage(line string) (ircMessage, error) {
+	message := ircMessage{}
+	if strings.HasPrefix(line, ":") {
+		i := strings.Index(line, " ")
+		if i < 0 {
+			return ircMessage{}, fmt.Errorf("message %q has no command", line)
+		}
+
+		message.prefix, line = line[1:i], line[i+1:]
+	}
+
+	trailing := ""
+	hasTrailing := false
+	if i := strings.Index(line, " :"); i >= 0 {
+		line, trailing, hasTrailing = line[:i], line[i+2:], true
+	} else if strings.HasPrefix(line, ":") {
+		line, trailing, hasTrailing = "", line[1:], true
+	}
+
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return ircMessage{}, errors.New("message has no command")
+	}
+
+	message.command, message.params = strings.ToUpper(fields[0]), fields[1:]
+	if hasTrailing {
+		message.params = append(message.params, trailing)
+	}
+
+	return message, nil
+}
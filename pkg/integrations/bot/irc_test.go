@@ -0,0 +1,65 @@
+package bot
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestParseIRCMessage(t *testing.T) {
+	testCases := []struct {
+		name        string
+		line        string
+		expected    ircMessage
+		expectError bool
+	}{
+		{
+			name:     "Privmsg",
+			line:     ":fearofdark!user@host PRIVMSG #chipmusic :!np please",
+			expected: ircMessage{prefix: "fearofdark!user@host", command: "PRIVMSG", params: []string{"#chipmusic", "!np please"}},
+		},
+		{
+			name:     "Ping",
+			line:     "PING :irc.example.org",
+			expected: ircMessage{command: "PING", params: []string{"irc.example.org"}},
+		},
+		{
+			name:     "Numeric",
+			line:     ":irc.example.org 001 chipmusic :Welcome",
+			expected: ircMessage{prefix: "irc.example.org", command: "001", params: []string{"chipmusic", "Welcome"}},
+		},
+		{
+			name:     "NoParams",
+			line:     "quit",
+			expected: ircMessage{command: "QUIT", params: []string{}},
+		},
+		{
+			name:        "OnlyPrefix",
+			line:        ":irc.example.org",
+			expectError: true,
+		},
+		{
+			name:        "Empty",
+			line:        "",
+			expectError: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			message, err := parseIRCMessage(testCase.line)
+			if testCase.expectError {
+				assert.Error(tt, err)
+				return
+			}
+
+			require.NoError(tt, err)
+			assert.Equal(tt, testCase.expected, message)
+		})
+	}
+}
+
+func TestIRCMessage_Nick(t *testing.T) {
+	assert.Equal(t, "fearofdark", ircMessage{prefix: "fearofdark!user@host"}.nick())
+	assert.Equal(t, "irc.example.org", ircMessage{prefix: "irc.example.org"}.nick())
+}
@@ -0,0 +1,199 @@
+package control
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/broar/chipmusic-cli/pkg/integrations/status"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// SocketName is the name of the control socket in the data directory
+	SocketName = "control.sock"
+
+	// DefaultTimeout is how long a command may take, including the time to connect and to read the command
+	DefaultTimeout = 30 * time.Second
+
+	// maxCommandLength is the longest command accepted, which leaves plenty of room for a URL
+	maxCommandLength = 64 * 1024
+)
+
+var (
+	// ErrRunning is returned by Listen when another process is already listening on the control socket
+	ErrRunning = errors.New("a daemon is already running")
+
+	// ErrNotRunning is returned by Send when no process is listening on the control socket
+	ErrNotRunning = errors.New("no daemon is running. Start one with chipmusic daemon")
+)
+
+// Response is the reply to a command. If the command failed, OK is false and Error says why. Status is only set by
+// commands which report the status
+type Response struct {
+	OK      bool           `json:"ok"`
+	Message string         `json:"message,omitempty"`
+	Error   string         `json:"error,omitempty"`
+	Status  *status.Status `json:"status,omitempty"`
+}
+
+// Handler performs a command and returns the reply to it, e.g. "skip" or "add https://chipmusic.org/...". The context
+// is done once the command takes too long
+type Handler func(ctx context.Context, command string) Response
+
+// Server accepts commands over a unix socket, so scripts and key bindings can control a running daemon. Each
+// connection sends a command as a line of text and gets a Response as a line of JSON before it is closed. Unix sockets
+// are also supported by Windows 10 and later
+type Server struct {
+	listener net.Listener
+	handler  Handler
+	timeout  time.Duration
+
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+}
+
+// Listen creates the control socket at path and returns a Server which handles the commands sent to it with handler.
+// A socket left behind by a process which is gone is replaced, but if a process is still listening on it, ErrRunning
+// is returned
+func Listen(path string, handler Handler) (*Server, error) {
+	if _, err := os.Stat(path); err == nil {
+		if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+			conn.Close()
+			return nil, ErrRunning
+		}
+
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("failed to remove stale control socket: %w", err)
+		}
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on control socket %s: %w", path, err)
+	}
+
+	// Anyone who can connect can control playback, so only the user may
+	if err := os.Chmod(path, 0600); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to restrict access to control socket: %w", err)
+	}
+
+	return &Server{listener: listener, handler: handler, timeout: DefaultTimeout}, nil
+}
+
+// Serve handles commands until the server is closed, when nil is returned, or accepting connections fails
+func (s *Server) Serve() error {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Temporary() {
+				continue
+			}
+
+			if isClosed(err) {
+				return nil
+			}
+
+			return fmt.Errorf("failed to accept command: %w", err)
+		}
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handle(conn)
+		}()
+	}
+}
+
+// Close stops accepting commands, waits for the commands being handled, and removes the control socket
+func (s *Server) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		err = s.listener.Close()
+		s.wg.Wait()
+	})
+
+	return err
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	deadline, _ := ctx.Deadline()
+	_ = conn.SetDeadline(deadline)
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 0, 4096), maxCommandLength)
+
+	var response Response
+	if scanner.Scan() {
+		response = s.handler(ctx, strings.TrimSpace(scanner.Text()))
+	} else {
+		response = Response{Error: "failed to read command"}
+		if err := scanner.Err(); err != nil {
+			response.Error = fmt.Sprintf("failed to read command: %v", err)
+		}
+	}
+
+	_ = json.NewEncoder(conn).Encode(response)
+}
+
+// Send sends command to the process listening on the control socket at path and returns its reply. If the command
+// failed, the Response is returned along with an error
+func Send(ctx context.Context, path, command string) (Response, error) {
+	if strings.ContainsAny(command, "\r\n") {
+		return Response{}, errors.New("command must be a single line")
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "unix", path)
+	if err != nil {
+		if _, statErr := os.Stat(path); os.IsNotExist(statErr) {
+			return Response{}, ErrNotRunning
+		}
+
+		var opErr *net.OpError
+		if errors.As(err, &opErr) && opErr.Op == "dial" {
+			return Response{}, fmt.Errorf("%w: %v", ErrNotRunning, err)
+		}
+
+		return Response{}, fmt.Errorf("failed to connect to control socket: %w", err)
+	}
+
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	if _, err := fmt.Fprintln(conn, command); err != nil {
+		return Response{}, fmt.Errorf("failed to send command: %w", err)
+	}
+
+	var response Response
+	if err := json.NewDecoder(conn).Decode(&response); err != nil {
+		return Response{}, fmt.Errorf("failed to read reply to command: %w", err)
+	}
+
+	if !response.OK {
+		return response, errors.New(response.Error)
+	}
+
+	return response, nil
+}
+
+// isClosed returns true if err was returned because the listener was closed
+func isClosed(err error) bool {
+	// net.ErrClosed only exists as of Go 1.16
+	return strings.Contains(err.Error(), "use of closed network connection")
+}
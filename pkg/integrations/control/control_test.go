@@ -0,0 +1,98 @@
+package control
+
+import (
+	"context"
+	"errors"
+	"github.com/broar/chipmusic-cli/pkg/integrations/status"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func tempSocket(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "chipmusic-control")
+	require.NoError(t, err)
+
+	return filepath.Join(dir, SocketName), func() { os.RemoveAll(dir) }
+}
+
+func TestServer(t *testing.T) {
+	path, cleanup := tempSocket(t)
+	defer cleanup()
+
+	server, err := Listen(path, func(ctx context.Context, command string) Response {
+		switch command {
+		case "status":
+			return Response{OK: true, Status: &status.Status{Playing: true, Title: "some.title"}}
+		case "fail":
+			return Response{Error: "some.error"}
+		default:
+			return Response{OK: true, Message: "got " + command}
+		}
+	})
+
+	require.NoError(t, err)
+
+	served := make(chan error, 1)
+	go func() {
+		served <- server.Serve()
+	}()
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm(), "expected only the user to be able to connect")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	response, err := Send(ctx, path, "skip")
+	require.NoError(t, err)
+	assert.Equal(t, Response{OK: true, Message: "got skip"}, response)
+
+	response, err = Send(ctx, path, "status")
+	require.NoError(t, err)
+	assert.Equal(t, &status.Status{Playing: true, Title: "some.title"}, response.Status)
+
+	response, err = Send(ctx, path, "fail")
+	assert.EqualError(t, err, "some.error")
+	assert.False(t, response.OK)
+
+	_, err = Send(ctx, path, "two\nlines")
+	assert.Error(t, err)
+
+	_, err = Listen(path, nil)
+	assert.Equal(t, ErrRunning, err)
+
+	require.NoError(t, server.Close())
+	assert.NoError(t, <-served)
+
+	_, err = Send(ctx, path, "skip")
+	assert.True(t, errors.Is(err, ErrNotRunning), "expected no daemon to be running once closed, but got %v", err)
+}
+
+func TestListen_StaleSocket(t *testing.T) {
+	path, cleanup := tempSocket(t)
+	defer cleanup()
+
+	// A socket file nobody listens on is left behind by a daemon which was killed
+	listener, err := net.Listen("unix", path)
+	require.NoError(t, err)
+
+	listener.(*net.UnixListener).SetUnlinkOnClose(false)
+	listener.Close()
+
+	_, err = Send(context.Background(), path, "skip")
+	assert.True(t, errors.Is(err, ErrNotRunning), "expected no daemon to be running, but got %v", err)
+
+	server, err := Listen(path, func(ctx context.Context, command string) Response {
+		return Response{OK: true}
+	})
+
+	require.NoError(t, err)
+	assert.NoError(t, server.Close())
+}
@@ -0,0 +1,78 @@
+package control
+
+import (
+	"fmt"
+	"github.com/broar/chipmusic-cli/pkg/chipmusic"
+	"strings"
+)
+
+const (
+	// ExportText formats an exported queue as plain text, e.g. to paste into IRC
+	ExportText ExportFormat = "text"
+
+	// ExportMarkdown formats an exported queue as Markdown, e.g. to paste into Discord
+	ExportMarkdown ExportFormat = "markdown"
+
+	// emptyQueue is what an export of an empty queue says
+	emptyQueue = "The queue is empty"
+)
+
+// markdownEscaper escapes the characters Markdown would read as formatting in titles and names
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "~", `\~`, "|", `\|`,
+	"[", `\[`, "]", `\]`, "(", `\(`, ")", `\)`, "<", `\<`, ">", `\>`, "#", `\#`,
+)
+
+// ExportFormat is how ExportQueue formats the tracks of a queue
+type ExportFormat string
+
+// ParseExportFormat returns the ExportFormat named by name. An empty name is ExportText
+func ParseExportFormat(name string) (ExportFormat, error) {
+	switch ExportFormat(name) {
+	case "", ExportText:
+		return ExportText, nil
+	case ExportMarkdown:
+		return ExportMarkdown, nil
+	default:
+		return "", fmt.Errorf("unknown export format %q. Allowed formats: [%s, %s]", name, ExportText, ExportMarkdown)
+	}
+}
+
+// ExportQueue formats tracks as a numbered list of their titles, artists, and links to their track pages, one track per
+// line, e.g. to share the queue in chat. If there are no tracks, it says that the queue is empty instead
+func ExportQueue(tracks []*chipmusic.Track, format ExportFormat) string {
+	if len(tracks) == 0 {
+		return emptyQueue
+	}
+
+	lines := make([]string, 0, len(tracks))
+	for i, track := range tracks {
+		title, artist, link := track.Title, track.Artist, track.PageURL
+		if title == "" {
+			title = "Untitled"
+		}
+
+		if link == "" {
+			link = track.DownloadURL
+		}
+
+		var line string
+		if format == ExportMarkdown {
+			line = fmt.Sprintf("%d. [%s](<%s>)", i+1, markdownEscaper.Replace(title), link)
+			if artist != "" {
+				line += " by " + markdownEscaper.Replace(artist)
+			}
+		} else {
+			line = fmt.Sprintf("%d. %s", i+1, title)
+			if artist != "" {
+				line += " by " + artist
+			}
+
+			line += " - " + link
+		}
+
+		lines = append(lines, line)
+	}
+
+	return strings.Join(lines, "\n")
+}
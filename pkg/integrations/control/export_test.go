@@ -0,0 +1,68 @@
+package control
+
+import (
+	"github.com/broar/chipmusic-cli/pkg/chipmusic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestParseExportFormat(t *testing.T) {
+	testCases := []struct {
+		name     string
+		expected ExportFormat
+	}{
+		{"", ExportText},
+		{"text", ExportText},
+		{"markdown", ExportMarkdown},
+	}
+
+	for _, testCase := range testCases {
+		format, err := ParseExportFormat(testCase.name)
+		require.NoError(t, err)
+		assert.Equal(t, testCase.expected, format)
+	}
+
+	_, err := ParseExportFormat("html")
+	assert.Error(t, err)
+}
+
+func TestExportQueue(t *testing.T) {
+	tracks := []*chipmusic.Track{
+		{Title: "some_title [remix]", Artist: "some*artist", PageURL: "https://chipmusic.org/some.artist/music/some.title"},
+		{Title: "other.title", PageURL: "https://chipmusic.org/other.artist/music/other.title"},
+		{Artist: "third.artist", DownloadURL: "https://chipmusic.s3.amazonaws.com/music/third.mp3"},
+	}
+
+	testCases := []struct {
+		name     string
+		format   ExportFormat
+		expected string
+	}{
+		{
+			"Text",
+			ExportText,
+			"1. some_title [remix] by some*artist - https://chipmusic.org/some.artist/music/some.title\n" +
+				"2. other.title - https://chipmusic.org/other.artist/music/other.title\n" +
+				"3. Untitled by third.artist - https://chipmusic.s3.amazonaws.com/music/third.mp3",
+		},
+		{
+			"Markdown",
+			ExportMarkdown,
+			`1. [some\_title \[remix\]](<https://chipmusic.org/some.artist/music/some.title>) by some\*artist` + "\n" +
+				"2. [other.title](<https://chipmusic.org/other.artist/music/other.title>)\n" +
+				"3. [Untitled](<https://chipmusic.s3.amazonaws.com/music/third.mp3>) by third.artist",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			assert.Equal(tt, testCase.expected, ExportQueue(tracks, testCase.format))
+		})
+	}
+}
+
+func TestExportQueue_Empty(t *testing.T) {
+	assert.Equal(t, "The queue is empty", ExportQueue(nil, ExportText))
+	assert.Equal(t, "The queue is empty", ExportQueue(nil, ExportMarkdown))
+}
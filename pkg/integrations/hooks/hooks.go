@@ -0,0 +1,196 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/broar/chipmusic-cli/pkg/chipmusic"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// HookTrackStart, HookTrackEnd, and HookError are the names of the hooks run when a track starts playing, when it
+	// finishes playing, and when something goes wrong
+	HookTrackStart = "on_track_start"
+	HookTrackEnd   = "on_track_end"
+	HookError      = "on_error"
+
+	// DefaultTimeout is how long a hook may run before it is killed
+	DefaultTimeout = 30 * time.Second
+)
+
+// FailedError is returned when the command of a hook fails
+type FailedError struct {
+	Hook string
+	Err  error
+}
+
+func (e *FailedError) Error() string {
+	return fmt.Sprintf("hook %s failed: %v", e.Hook, e.Err)
+}
+
+func (e *FailedError) Unwrap() error {
+	return e.Err
+}
+
+// Hooks runs commands configured by the user when tracks start and finish playing and when errors happen, which lets
+// the user integrate with whatever they like, e.g. desktop notifications or a status bar. Commands are run by the
+// shell, sh on Unix and cmd on Windows, with the details of the track in environment variables:
+//
+//	CHIPMUSIC_HOOK          the name of the hook, e.g. on_track_start
+//	CHIPMUSIC_TITLE         the title of the track
+//	CHIPMUSIC_ARTIST        the artist of the track
+//	CHIPMUSIC_PAGE_URL      the URL of the track page on chipmusic.org
+//	CHIPMUSIC_DOWNLOAD_URL  the URL of the audio file of the track
+//	CHIPMUSIC_TAGS          the tags of the track, separated by commas
+//	CHIPMUSIC_LISTENED      how many seconds the track was listened to, only for on_track_end
+//	CHIPMUSIC_ERROR         the error, only for on_error
+//
+// The track variables are empty for errors which are not related to a track
+type Hooks struct {
+	commands map[string]string
+	timeout  time.Duration
+
+	// shell is the command and arguments the command of a hook is appended to
+	shell []string
+}
+
+// Option is an alias for a function that modifies Hooks. An Option is used to override the default values of Hooks
+type Option func(h *Hooks) error
+
+// WithHook allows running command when the hook with name is triggered, e.g. HookTrackStart. An empty command runs
+// nothing
+func WithHook(name, command string) Option {
+	return func(h *Hooks) error {
+		if name != HookTrackStart && name != HookTrackEnd && name != HookError {
+			return fmt.Errorf("unknown hook %q. Allowed hooks: [%s, %s, %s]", name, HookTrackStart, HookTrackEnd, HookError)
+		}
+
+		if strings.TrimSpace(command) == "" {
+			delete(h.commands, name)
+			return nil
+		}
+
+		h.commands[name] = command
+		return nil
+	}
+}
+
+// WithTimeout allows overriding how long a hook may run before it is killed
+func WithTimeout(timeout time.Duration) Option {
+	return func(h *Hooks) error {
+		if timeout <= 0 {
+			return errors.New("timeout must be positive")
+		}
+
+		h.timeout = timeout
+		return nil
+	}
+}
+
+// NewHooks creates Hooks which run the commands configured with a list of Options
+func NewHooks(options ...Option) (*Hooks, error) {
+	h := &Hooks{
+		commands: map[string]string{},
+		timeout:  DefaultTimeout,
+		shell:    []string{"sh", "-c"},
+	}
+
+	if runtime.GOOS == "windows" {
+		h.shell = []string{"cmd", "/C"}
+	}
+
+	for _, option := range options {
+		if err := option(h); err != nil {
+			return nil, err
+		}
+	}
+
+	return h, nil
+}
+
+// Empty returns true if no hook has a command, so there is nothing to run
+func (h *Hooks) Empty() bool {
+	return len(h.commands) == 0
+}
+
+// TrackStarted runs the HookTrackStart hook for track
+func (h *Hooks) TrackStarted(ctx context.Context, track *chipmusic.Track) error {
+	return h.run(ctx, HookTrackStart, trackEnv(track))
+}
+
+// TrackFinished runs the HookTrackEnd hook for track, which was listened to for listened
+func (h *Hooks) TrackFinished(ctx context.Context, track *chipmusic.Track, listened time.Duration) error {
+	env := append(trackEnv(track), "CHIPMUSIC_LISTENED="+strconv.Itoa(int(listened.Seconds())))
+	return h.run(ctx, HookTrackEnd, env)
+}
+
+// Error runs the HookError hook for err, which happened while track was playing. track may be nil. Errors of hooks
+// are ignored, since a failing error hook would otherwise run itself over and over
+func (h *Hooks) Error(ctx context.Context, err error, track *chipmusic.Track) error {
+	var hookErr *FailedError
+	if errors.As(err, &hookErr) {
+		return nil
+	}
+
+	return h.run(ctx, HookError, append(trackEnv(track), "CHIPMUSIC_ERROR="+err.Error()))
+}
+
+// run runs the command of hook with env added to the environment of the process. If hook has no command, nothing is
+// run. The output of the command is discarded, since the dashboard owns the terminal, except for its standard error
+// which is added to the error if it fails
+func (h *Hooks) run(ctx context.Context, hook string, env []string) error {
+	command, ok := h.commands[hook]
+	if !ok {
+		return nil
+	}
+
+	// Standard error goes to a file rather than a pipe, since a pipe stays open as long as any process started by the
+	// command is running, which would keep a command which was killed from ever finishing
+	stderr, err := ioutil.TempFile("", "chipmusic-hook")
+	if err != nil {
+		return &FailedError{Hook: hook, Err: err}
+	}
+
+	defer os.Remove(stderr.Name())
+	defer stderr.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	args := append(append([]string{}, h.shell[1:]...), command)
+	cmd := exec.CommandContext(ctx, h.shell[0], args...)
+	cmd.Env = append(append(os.Environ(), "CHIPMUSIC_HOOK="+hook), env...)
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		if output, readErr := ioutil.ReadFile(stderr.Name()); readErr == nil && len(bytes.TrimSpace(output)) > 0 {
+			err = fmt.Errorf("%w: %s", err, bytes.TrimSpace(output))
+		}
+
+		return &FailedError{Hook: hook, Err: err}
+	}
+
+	return nil
+}
+
+// trackEnv returns the environment variables describing track. They are empty if track is nil
+func trackEnv(track *chipmusic.Track) []string {
+	if track == nil {
+		track = &chipmusic.Track{}
+	}
+
+	return []string{
+		"CHIPMUSIC_TITLE=" + track.Title,
+		"CHIPMUSIC_ARTIST=" + track.Artist,
+		"CHIPMUSIC_PAGE_URL=" + track.PageURL,
+		"CHIPMUSIC_DOWNLOAD_URL=" + track.DownloadURL,
+		"CHIPMUSIC_TAGS=" + strings.Join(track.Tags, ","),
+	}
+}
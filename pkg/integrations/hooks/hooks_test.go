@@ -0,0 +1,96 @@
+package hooks
+
+import (
+	"context"
+	"errors"
+	"github.com/broar/chipmusic-cli/pkg/chipmusic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+var testTrack = &chipmusic.Track{
+	Title:   "Virtues",
+	Artist:  "Hide Your Tigers",
+	PageURL: "https://chipmusic.org/Hide+Your+Tigers/music/virtues-lsdj",
+	Tags:    []string{"lsdj", "electro"},
+}
+
+func TestHooks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hooks in this test are shell scripts")
+	}
+
+	dir, err := ioutil.TempDir("", "chipmusic-hooks")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	out := filepath.Join(dir, "out")
+	h, err := NewHooks(
+		WithHook(HookTrackStart, `echo "$CHIPMUSIC_HOOK $CHIPMUSIC_TITLE - $CHIPMUSIC_ARTIST $CHIPMUSIC_TAGS" > `+out),
+		WithHook(HookTrackEnd, `echo "$CHIPMUSIC_HOOK $CHIPMUSIC_PAGE_URL $CHIPMUSIC_LISTENED" > `+out),
+		WithHook(HookError, `echo "$CHIPMUSIC_HOOK $CHIPMUSIC_ERROR" > `+out),
+	)
+	require.NoError(t, err)
+	assert.False(t, h.Empty())
+
+	read := func() string {
+		text, err := ioutil.ReadFile(out)
+		require.NoError(t, err)
+		return string(text)
+	}
+
+	require.NoError(t, h.TrackStarted(context.Background(), testTrack))
+	assert.Equal(t, "on_track_start Virtues - Hide Your Tigers lsdj,electro\n", read())
+
+	require.NoError(t, h.TrackFinished(context.Background(), testTrack, 90*time.Second))
+	assert.Equal(t, "on_track_end https://chipmusic.org/Hide+Your+Tigers/music/virtues-lsdj 90\n", read())
+
+	require.NoError(t, h.Error(context.Background(), errors.New("some.error"), nil))
+	assert.Equal(t, "on_error some.error\n", read())
+}
+
+func TestHooks_Failing(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hooks in this test are shell scripts")
+	}
+
+	h, err := NewHooks(
+		WithHook(HookTrackStart, "echo some.problem >&2; exit 3"),
+		WithHook(HookError, "exit 1"),
+		WithTimeout(50*time.Millisecond),
+		WithHook(HookTrackEnd, "sleep 5"),
+	)
+	require.NoError(t, err)
+
+	err = h.TrackStarted(context.Background(), testTrack)
+	var hookErr *FailedError
+	require.True(t, errors.As(err, &hookErr))
+	assert.Equal(t, HookTrackStart, hookErr.Hook)
+	assert.Contains(t, err.Error(), "some.problem")
+
+	assert.NoError(t, h.Error(context.Background(), err, testTrack), "expected errors of hooks not to run the error hook")
+
+	start := time.Now()
+	assert.Error(t, h.TrackFinished(context.Background(), testTrack, time.Second), "expected hooks to be killed after the timeout")
+	assert.True(t, time.Since(start) < 5*time.Second)
+}
+
+func TestNewHooks_Invalid(t *testing.T) {
+	_, err := NewHooks(WithHook("on_some_event", "true"))
+	assert.Error(t, err)
+
+	_, err = NewHooks(WithTimeout(0))
+	assert.Error(t, err)
+
+	h, err := NewHooks(WithHook(HookTrackStart, " "))
+	require.NoError(t, err)
+	assert.True(t, h.Empty())
+	assert.NoError(t, h.TrackStarted(context.Background(), testTrack))
+}
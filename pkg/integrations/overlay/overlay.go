@@ -0,0 +1,263 @@
+package overlay
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/broar/chipmusic-cli/pkg/chipmusic"
+	"html/template"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// PathOverlay is the path of the HTML page showing the current track
+	PathOverlay = "/overlay"
+
+	// PathEvents is the path of the stream of server-sent events with the overlay of every track as it starts playing
+	PathEvents = "/overlay/events"
+
+	// DefaultRefresh is how often the overlay page reloads itself unless another interval is set
+	DefaultRefresh = 5 * time.Second
+
+	// DefaultTemplate is the template of the overlay unless another template is set
+	DefaultTemplate = `{{if .Playing}}<div class="track"><span class="title">{{.Title}}</span> <span class="artist">by {{.Artist}}</span></div>{{end}}`
+
+	// eventTrack is the name of the server-sent events sent when a track starts playing
+	eventTrack = "track"
+)
+
+// pageTemplate is the HTML page the overlay is embedded in. The background is transparent so only the text shows on
+// top of the stream
+var pageTemplate = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta http-equiv="refresh" content="{{.Refresh}}">
+<title>chipmusic</title>
+<style>
+body { margin: 0; background: transparent; color: #fff; font-family: sans-serif; text-shadow: 0 0 4px #000; }
+</style>
+</head>
+<body>
+{{.Overlay}}
+</body>
+</html>
+`))
+
+// NowPlaying is the data the template of the overlay is executed with. If nothing is playing, Playing is false and
+// the other fields are empty
+type NowPlaying struct {
+	Playing bool
+	Title   string
+	Artist  string
+	PageURL string
+	Tags    []string
+}
+
+// Overlay serves the current track as a small HTML page which can be added to OBS as a browser source. The page
+// reloads itself, or the overlay can be streamed as server-sent events to pages which update themselves
+type Overlay struct {
+	template *template.Template
+	refresh  time.Duration
+
+	// closing is closed when the overlay is closed so streams of events end
+	closing chan struct{}
+
+	mux         sync.Mutex
+	current     NowPlaying
+	subscribers map[chan struct{}]bool
+}
+
+// Option is an alias for a function that modifies an Overlay. An Option is used to override the default values of
+// Overlay
+type Option func(o *Overlay) error
+
+// WithTemplate allows overriding the template of the overlay. The template is an html/template which is executed with
+// NowPlaying
+func WithTemplate(text string) Option {
+	return func(o *Overlay) error {
+		tmpl, err := template.New("overlay").Parse(text)
+		if err != nil {
+			return fmt.Errorf("failed to parse overlay template: %w", err)
+		}
+
+		o.template = tmpl
+		return nil
+	}
+}
+
+// WithRefresh allows overriding how often the overlay page reloads itself
+func WithRefresh(d time.Duration) Option {
+	return func(o *Overlay) error {
+		if d < time.Second {
+			return errors.New("refresh interval must be at least one second")
+		}
+
+		o.refresh = d
+		return nil
+	}
+}
+
+// NewOverlay creates a new Overlay object that is configured with a list of Options
+func NewOverlay(options ...Option) (*Overlay, error) {
+	o := &Overlay{
+		template:    template.Must(template.New("overlay").Parse(DefaultTemplate)),
+		refresh:     DefaultRefresh,
+		closing:     make(chan struct{}),
+		subscribers: map[chan struct{}]bool{},
+	}
+
+	for _, option := range options {
+		if err := option(o); err != nil {
+			return nil, err
+		}
+	}
+
+	return o, nil
+}
+
+// SetTrack shows track on the overlay and sends it to every stream of events. If track is nil, the overlay shows that
+// nothing is playing
+func (o *Overlay) SetTrack(track *chipmusic.Track) {
+	current := NowPlaying{}
+	if track != nil {
+		current = NowPlaying{Playing: true, Title: track.Title, Artist: track.Artist, PageURL: track.PageURL, Tags: track.Tags}
+	}
+
+	o.mux.Lock()
+	defer o.mux.Unlock()
+
+	o.current = current
+	for subscriber := range o.subscribers {
+		// Subscribers only need to know something changed, so a pending notification is enough
+		select {
+		case subscriber <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// ServeHTTP serves the overlay page at PathOverlay and the stream of events at PathEvents
+func (o *Overlay) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	switch r.URL.Path {
+	case PathOverlay:
+		o.servePage(w)
+	case PathEvents:
+		o.serveEvents(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// Close ends every stream of events
+func (o *Overlay) Close() error {
+	close(o.closing)
+	return nil
+}
+
+// servePage serves the overlay embedded in a page which reloads itself
+func (o *Overlay) servePage(w http.ResponseWriter) {
+	overlay, err := o.render()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var page bytes.Buffer
+	data := struct {
+		Refresh int
+		Overlay template.HTML
+	}{int(o.refresh / time.Second), template.HTML(overlay)}
+
+	if err := pageTemplate.Execute(&page, data); err != nil {
+		http.Error(w, fmt.Sprintf("failed to render overlay page: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	_, _ = w.Write(page.Bytes())
+}
+
+// serveEvents streams the overlay as server-sent events, starting with the current track, until the client goes away
+// or the overlay is closed
+func (o *Overlay) serveEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	changed := make(chan struct{}, 1)
+	changed <- struct{}{}
+
+	o.mux.Lock()
+	o.subscribers[changed] = true
+	o.mux.Unlock()
+
+	defer func() {
+		o.mux.Lock()
+		delete(o.subscribers, changed)
+		o.mux.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-o.closing:
+			return
+		case <-changed:
+			overlay, err := o.render()
+			if err != nil {
+				return
+			}
+
+			if _, err := fmt.Fprint(w, formatEvent(eventTrack, overlay)); err != nil {
+				return
+			}
+
+			flusher.Flush()
+		}
+	}
+}
+
+// render executes the template of the overlay with the current track
+func (o *Overlay) render() (string, error) {
+	o.mux.Lock()
+	current := o.current
+	o.mux.Unlock()
+
+	var overlay bytes.Buffer
+	if err := o.template.Execute(&overlay, current); err != nil {
+		return "", fmt.Errorf("failed to render overlay: %w", err)
+	}
+
+	return overlay.String(), nil
+}
+
+// formatEvent formats a server-sent event. Every line of data needs its own field
+func formatEvent(name, data string) string {
+	var event strings.Builder
+	fmt.Fprintf(&event, "event: %s\n", name)
+	for _, line := range strings.Split(data, "\n") {
+		fmt.Fprintf(&event, "data: %s\n", line)
+	}
+
+	event.WriteString("\n")
+	return event.String()
+}
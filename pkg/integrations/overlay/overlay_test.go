@@ -0,0 +1,158 @@
+package overlay
+
+import (
+	"bufio"
+	"context"
+	"github.com/broar/chipmusic-cli/pkg/chipmusic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTrack() *chipmusic.Track {
+	return &chipmusic.Track{
+		Title:   "some.title",
+		Artist:  "some.artist",
+		PageURL: "https://chipmusic.org/some.artist/music/some.title",
+		Tags:    []string{"lsdj"},
+	}
+}
+
+// get requests path from o and returns the response and its body
+func get(t *testing.T, o *Overlay, method, path string) (*http.Response, string) {
+	w := httptest.NewRecorder()
+	o.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+
+	body, err := ioutil.ReadAll(w.Result().Body)
+	require.NoError(t, err)
+	return w.Result(), string(body)
+}
+
+func TestNewOverlay(t *testing.T) {
+	testCases := []struct {
+		name    string
+		options []Option
+		err     bool
+	}{
+		{"Default", nil, false},
+		{"Template", []Option{WithTemplate("{{.Title}}")}, false},
+		{"InvalidTemplate", []Option{WithTemplate("{{.Title")}, true},
+		{"Refresh", []Option{WithRefresh(time.Minute)}, false},
+		{"RefreshTooShort", []Option{WithRefresh(time.Millisecond)}, true},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			o, err := NewOverlay(testCase.options...)
+			if testCase.err {
+				assert.Error(tt, err)
+				assert.Nil(tt, o)
+			} else {
+				assert.NoError(tt, err)
+				assert.NotNil(tt, o)
+			}
+		})
+	}
+}
+
+func TestOverlay_Page(t *testing.T) {
+	o, err := NewOverlay(WithRefresh(10 * time.Second))
+	require.NoError(t, err)
+
+	resp, body := get(t, o, http.MethodGet, PathOverlay)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/html; charset=utf-8", resp.Header.Get("Content-Type"))
+	assert.Contains(t, body, `<meta http-equiv="refresh" content="10">`)
+	assert.NotContains(t, body, "some.title")
+
+	o.SetTrack(newTrack())
+	_, body = get(t, o, http.MethodGet, PathOverlay)
+	assert.Contains(t, body, `<span class="title">some.title</span> <span class="artist">by some.artist</span>`)
+
+	// Nothing is shown once the track is cleared
+	o.SetTrack(nil)
+	_, body = get(t, o, http.MethodGet, PathOverlay)
+	assert.NotContains(t, body, "some.title")
+}
+
+func TestOverlay_Template(t *testing.T) {
+	o, err := NewOverlay(WithTemplate(`<a href="{{.PageURL}}">{{.Title}}</a>{{range .Tags}} #{{.}}{{end}}`))
+	require.NoError(t, err)
+
+	track := newTrack()
+	track.Title = "<script>alert(1)</script>"
+	o.SetTrack(track)
+
+	// Titles are escaped since anyone can upload a track
+	_, body := get(t, o, http.MethodGet, PathOverlay)
+	assert.Contains(t, body, `<a href="https://chipmusic.org/some.artist/music/some.title">&lt;script&gt;alert(1)&lt;/script&gt;</a> #lsdj`)
+
+	o, err = NewOverlay(WithTemplate(`{{.Missing}}`))
+	require.NoError(t, err)
+
+	resp, _ := get(t, o, http.MethodGet, PathOverlay)
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+}
+
+func TestOverlay_NotFound(t *testing.T) {
+	o, err := NewOverlay()
+	require.NoError(t, err)
+
+	resp, _ := get(t, o, http.MethodGet, "/some.path")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp, _ = get(t, o, http.MethodPost, PathOverlay)
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+}
+
+func TestOverlay_Events(t *testing.T) {
+	o, err := NewOverlay(WithTemplate("{{.Title}}\n{{.Artist}}"))
+	require.NoError(t, err)
+
+	server := httptest.NewServer(o)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+PathEvents, nil)
+	require.NoError(t, err)
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+
+	defer resp.Body.Close()
+
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	// readEvent reads lines up to the blank line ending an event
+	reader := bufio.NewReader(resp.Body)
+	readEvent := func() string {
+		var event strings.Builder
+		for {
+			line, err := reader.ReadString('\n')
+			require.NoError(t, err)
+			if line == "\n" {
+				return event.String()
+			}
+
+			event.WriteString(line)
+		}
+	}
+
+	// The current track is sent straight away, then every track as it starts
+	assert.Equal(t, "event: track\ndata: \ndata: \n", readEvent())
+
+	o.SetTrack(newTrack())
+	assert.Equal(t, "event: track\ndata: some.title\ndata: some.artist\n", readEvent())
+
+	// Closing the overlay ends the stream
+	require.NoError(t, o.Close())
+	_, err = reader.ReadString('\n')
+	assert.Error(t, err)
+}
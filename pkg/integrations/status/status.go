@@ -0,0 +1,146 @@
+package status
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/broar/chipmusic-cli/pkg/chipmusic"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// FileName is the name of the status file in the data directory
+	FileName = "status.json"
+
+	// DefaultMaxLength is how many characters the status is cut to for tmux unless another length is given
+	DefaultMaxLength = 40
+
+	// ellipsis ends a status which was cut short
+	ellipsis = "…"
+)
+
+// Status is what a running session is playing. Sessions write it to the status file whenever it changes and every
+// now and then in between, so other processes polling it, e.g. tmux, read a small file instead of asking the session
+// and can tell from UpdatedAt when the session is gone
+type Status struct {
+	Playing   bool      `json:"playing"`
+	Paused    bool      `json:"paused"`
+	Title     string    `json:"title,omitempty"`
+	Artist    string    `json:"artist,omitempty"`
+	PageURL   string    `json:"pageUrl,omitempty"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// NewStatus returns the status of a session playing track, which may be nil if nothing is playing
+func NewStatus(track *chipmusic.Track, paused bool, now time.Time) Status {
+	if track == nil {
+		return Status{UpdatedAt: now}
+	}
+
+	return Status{
+		Playing:   true,
+		Paused:    paused,
+		Title:     track.Title,
+		Artist:    track.Artist,
+		PageURL:   track.PageURL,
+		UpdatedAt: now,
+	}
+}
+
+// Stale returns true if the status was last updated more than maxAge before now, which means the session writing it
+// is no longer running
+func (s Status) Stale(now time.Time, maxAge time.Duration) bool {
+	return now.Sub(s.UpdatedAt) > maxAge
+}
+
+// Write replaces the status file at path with status. The file is replaced atomically so readers never see half of it
+func Write(path string, status Status) error {
+	data, err := json.Marshal(status)
+	if err != nil {
+		return fmt.Errorf("failed to encode status: %w", err)
+	}
+
+	file, err := ioutil.TempFile(filepath.Dir(path), ".status-*")
+	if err != nil {
+		return fmt.Errorf("failed to create status file: %w", err)
+	}
+
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		_ = os.Remove(file.Name())
+		return fmt.Errorf("failed to write status file: %w", err)
+	}
+
+	if err := os.Rename(file.Name(), path); err != nil {
+		_ = os.Remove(file.Name())
+		return fmt.Errorf("failed to store status file: %w", err)
+	}
+
+	return nil
+}
+
+// Read returns the status in the status file at path. If there is no status file, no session is running, so the
+// status shows that nothing is playing
+func Read(path string) (Status, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return Status{}, nil
+	}
+
+	if err != nil {
+		return Status{}, fmt.Errorf("failed to read status file: %w", err)
+	}
+
+	var status Status
+	if err := json.Unmarshal(data, &status); err != nil {
+		return Status{}, fmt.Errorf("failed to decode status file %s: %w", path, err)
+	}
+
+	return status, nil
+}
+
+// Tmux formats status for the status line of tmux, e.g. "♪ some.artist – some.title", cut to at most maxLength
+// characters. Control characters are dropped and # is escaped, since tmux would run #(...) as a command and read
+// #[...] as a style. If nothing is playing, the text is empty so the status line shows nothing
+func Tmux(status Status, maxLength int) string {
+	if !status.Playing {
+		return ""
+	}
+
+	symbol := "♪"
+	if status.Paused {
+		symbol = "⏸"
+	}
+
+	text := truncate(fmt.Sprintf("%s %s – %s", symbol, sanitize(status.Artist), sanitize(status.Title)), maxLength)
+	return strings.ReplaceAll(text, "#", "##")
+}
+
+// truncate cuts text to at most maxLength characters, ending it with an ellipsis if anything was cut
+func truncate(text string, maxLength int) string {
+	if maxLength <= 0 || utf8.RuneCountInString(text) <= maxLength {
+		return text
+	}
+
+	runes := []rune(text)
+	return strings.TrimSpace(string(runes[:maxLength-1])) + ellipsis
+}
+
+func sanitize(text string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+
+		return r
+	}, text)
+}
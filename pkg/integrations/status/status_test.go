@@ -0,0 +1,66 @@
+package status
+
+import (
+	"github.com/broar/chipmusic-cli/pkg/chipmusic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWriteAndRead(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chipmusic-status")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, FileName)
+	status, err := Read(path)
+	require.NoError(t, err)
+	assert.Equal(t, Status{}, status, "expected nothing to be playing without a status file")
+
+	now := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	written := NewStatus(&chipmusic.Track{Title: "some.title", Artist: "some.artist", PageURL: "some.url"}, true, now)
+	require.NoError(t, Write(path, written))
+
+	status, err = Read(path)
+	require.NoError(t, err)
+	assert.Equal(t, Status{Playing: true, Paused: true, Title: "some.title", Artist: "some.artist", PageURL: "some.url", UpdatedAt: now}, status)
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("not json"), 0600))
+	_, err = Read(path)
+	assert.Error(t, err)
+}
+
+func TestStatus_Stale(t *testing.T) {
+	now := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	status := NewStatus(nil, false, now)
+	assert.False(t, status.Stale(now.Add(time.Minute), time.Minute))
+	assert.True(t, status.Stale(now.Add(time.Minute+time.Second), time.Minute))
+}
+
+func TestTmux(t *testing.T) {
+	testCases := []struct {
+		name      string
+		status    Status
+		maxLength int
+		expected  string
+	}{
+		{"NotPlaying", Status{Title: "some.title"}, 40, ""},
+		{"Playing", Status{Playing: true, Title: "some.title", Artist: "some.artist"}, 40, "♪ some.artist – some.title"},
+		{"Paused", Status{Playing: true, Paused: true, Title: "some.title", Artist: "some.artist"}, 40, "⏸ some.artist – some.title"},
+		{"Truncated", Status{Playing: true, Title: "some.title", Artist: "some.artist"}, 18, "♪ some.artist – s…"},
+		{"TruncatedAtSpace", Status{Playing: true, Title: "some.title", Artist: "some.artist"}, 17, "♪ some.artist –…"},
+		{"Unlimited", Status{Playing: true, Title: "some.title", Artist: "some.artist"}, 0, "♪ some.artist – some.title"},
+		{"Escaped", Status{Playing: true, Title: "#(rm -rf ~)\n", Artist: "#[fg=red]"}, 40, "♪ ##[fg=red] – ##(rm -rf ~)"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			assert.Equal(tt, testCase.expected, Tmux(testCase.status, testCase.maxLength))
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package title
+
+import (
+	"errors"
+	"fmt"
+	"github.com/broar/chipmusic-cli/pkg/chipmusic"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+const (
+	// setTitle is the OSC 0 escape sequence setting the title of the terminal window and its icon or tab
+	setTitle = "\x1b]0;%s\x07"
+
+	// pushTitle and popTitle save and restore the title of the terminal window on the stack of titles xterm and most
+	// terminals based on it keep. Terminals without the stack ignore them
+	pushTitle = "\x1b[22;0t"
+	popTitle  = "\x1b[23;0t"
+)
+
+// ErrNilWriter is returned when attempting to write titles to a nil writer
+var ErrNilWriter = errors.New("writer cannot be nil")
+
+// Title shows the track playing in the title of the terminal window, e.g. "♪ some.artist – some.title", which shows
+// up in tmux, in tabs, and in window switchers. The title the terminal had before is restored when Title is closed
+type Title struct {
+	out io.Writer
+
+	mux    sync.Mutex
+	pushed bool
+	closed bool
+}
+
+// Option is an alias for a function that modifies a Title. An Option is used to override the default values of Title
+type Option func(t *Title) error
+
+// WithWriter allows overriding where the escape sequences are written, which is standard output by default
+func WithWriter(w io.Writer) Option {
+	return func(t *Title) error {
+		if w == nil {
+			return ErrNilWriter
+		}
+
+		t.out = w
+		return nil
+	}
+}
+
+// NewTitle creates a new Title object that is configured with a list of Options
+func NewTitle(options ...Option) (*Title, error) {
+	t := &Title{out: os.Stdout}
+	for _, option := range options {
+		if err := option(t); err != nil {
+			return nil, err
+		}
+	}
+
+	return t, nil
+}
+
+// SetTrack shows track in the title of the terminal window. The title the terminal had is saved the first time, so
+// Close can restore it. Once Title is closed, this method does nothing
+func (t *Title) SetTrack(track *chipmusic.Track) error {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+
+	if t.closed {
+		return nil
+	}
+
+	// Everything is written at once so the escape sequences aren't split up by whatever else draws on the terminal
+	var b strings.Builder
+	if !t.pushed {
+		b.WriteString(pushTitle)
+	}
+
+	fmt.Fprintf(&b, setTitle, Format(track))
+	if _, err := io.WriteString(t.out, b.String()); err != nil {
+		return fmt.Errorf("failed to set terminal title: %w", err)
+	}
+
+	t.pushed = true
+	return nil
+}
+
+// Close restores the title the terminal had before the first track was shown
+func (t *Title) Close() error {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+
+	if t.closed {
+		return nil
+	}
+
+	t.closed = true
+	if !t.pushed {
+		return nil
+	}
+
+	if _, err := io.WriteString(t.out, popTitle); err != nil {
+		return fmt.Errorf("failed to restore terminal title: %w", err)
+	}
+
+	return nil
+}
+
+// Format returns the title showing track. Control characters are dropped from the title and the artist, since they
+// would end the escape sequence early and let the rest be run by the terminal
+func Format(track *chipmusic.Track) string {
+	return fmt.Sprintf("♪ %s – %s", sanitize(track.Artist), sanitize(track.Title))
+}
+
+func sanitize(text string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+
+		return r
+	}, text)
+}
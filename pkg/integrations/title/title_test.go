@@ -0,0 +1,77 @@
+package title
+
+import (
+	"bytes"
+	"errors"
+	"github.com/broar/chipmusic-cli/pkg/chipmusic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+type failingWriter struct{}
+
+func (w failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("some.error")
+}
+
+func TestWithWriter(t *testing.T) {
+	_, err := NewTitle(WithWriter(nil))
+	assert.Equal(t, ErrNilWriter, err)
+}
+
+func TestFormat(t *testing.T) {
+	testCases := []struct {
+		name     string
+		track    *chipmusic.Track
+		expected string
+	}{
+		{"Track", &chipmusic.Track{Title: "some.title", Artist: "some.artist"}, "♪ some.artist – some.title"},
+		{"ControlCharacters", &chipmusic.Track{Title: "some\x07.title\x1b]0;evil", Artist: "some.\u009dartist\n"}, "♪ some.artist – some.title]0;evil"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			assert.Equal(tt, testCase.expected, Format(testCase.track))
+		})
+	}
+}
+
+func TestTitle(t *testing.T) {
+	var out bytes.Buffer
+	title, err := NewTitle(WithWriter(&out))
+	require.NoError(t, err)
+
+	require.NoError(t, title.SetTrack(&chipmusic.Track{Title: "first.title", Artist: "first.artist"}))
+	assert.Equal(t, "\x1b[22;0t\x1b]0;♪ first.artist – first.title\x07", out.String(), "expected the old title to be saved first")
+
+	out.Reset()
+	require.NoError(t, title.SetTrack(&chipmusic.Track{Title: "second.title", Artist: "second.artist"}))
+	assert.Equal(t, "\x1b]0;♪ second.artist – second.title\x07", out.String())
+
+	out.Reset()
+	require.NoError(t, title.Close())
+	assert.Equal(t, "\x1b[23;0t", out.String(), "expected the old title to be restored")
+
+	out.Reset()
+	require.NoError(t, title.SetTrack(&chipmusic.Track{Title: "third.title", Artist: "third.artist"}))
+	require.NoError(t, title.Close())
+	assert.Empty(t, out.String(), "expected nothing to be written once closed")
+}
+
+func TestTitle_CloseWithoutTrack(t *testing.T) {
+	var out bytes.Buffer
+	title, err := NewTitle(WithWriter(&out))
+	require.NoError(t, err)
+
+	require.NoError(t, title.Close())
+	assert.Empty(t, out.String(), "expected no title to be restored if none was set")
+}
+
+func TestTitle_WriteFailed(t *testing.T) {
+	title, err := NewTitle(WithWriter(failingWriter{}))
+	require.NoError(t, err)
+
+	assert.Error(t, title.SetTrack(&chipmusic.Track{Title: "some.title", Artist: "some.artist"}))
+	assert.NoError(t, title.Close(), "expected nothing to be restored if the title was never set")
+}
@@ -0,0 +1,6 @@
+package library
+
+import "errors"
+
+// ErrFreeSpaceUnsupported is returned by FreeSpace on systems where the free space of a disk can't be found
+var ErrFreeSpaceUnsupported = errors.New("finding free disk space is not supported on this system")
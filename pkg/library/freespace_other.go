@@ -0,0 +1,9 @@
+// +build !linux,!darwin,!windows
+
+package library
+
+// FreeSpace returns how many bytes can still be written to the disk holding dir. Finding it is only supported on
+// Linux, macOS, and Windows, so ErrFreeSpaceUnsupported is always returned
+func FreeSpace(dir string) (int64, error) {
+	return 0, ErrFreeSpaceUnsupported
+}
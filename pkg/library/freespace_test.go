@@ -0,0 +1,29 @@
+package library
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFreeSpace(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chipmusic-library")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	free, err := FreeSpace(dir)
+	if errors.Is(err, ErrFreeSpaceUnsupported) {
+		t.Skip(err)
+	}
+
+	require.NoError(t, err)
+	assert.True(t, free > 0, "expected some free space in the temporary directory")
+
+	_, err = FreeSpace(filepath.Join(dir, "missing"))
+	assert.Error(t, err)
+}
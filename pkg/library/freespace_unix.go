@@ -0,0 +1,19 @@
+// +build linux darwin
+
+package library
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// FreeSpace returns how many bytes can still be written to the disk holding dir by the current user, which leaves out
+// the blocks reserved for root
+func FreeSpace(dir string) (int64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(dir, &stat); err != nil {
+		return 0, fmt.Errorf("failed to find free space of %s: %w", dir, err)
+	}
+
+	return int64(stat.Bavail) * int64(stat.Bsize), nil
+}
@@ -0,0 +1,30 @@
+// +build windows
+
+package library
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	kernel32           = syscall.NewLazyDLL("kernel32.dll")
+	getDiskFreeSpaceEx = kernel32.NewProc("GetDiskFreeSpaceExW")
+)
+
+// FreeSpace returns how many bytes can still be written to the disk holding dir by the current user, which takes disk
+// quotas into account
+func FreeSpace(dir string) (int64, error) {
+	path, err := syscall.UTF16PtrFromString(dir)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find free space of %s: %w", dir, err)
+	}
+
+	var available uint64
+	if ok, _, err := getDiskFreeSpaceEx.Call(uintptr(unsafe.Pointer(path)), uintptr(unsafe.Pointer(&available)), 0, 0); ok == 0 {
+		return 0, fmt.Errorf("failed to find free space of %s: %w", dir, err)
+	}
+
+	return int64(available), nil
+}
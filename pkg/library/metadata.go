@@ -0,0 +1,66 @@
+package library
+
+import (
+	"github.com/broar/chipmusic-cli/pkg/chipmusic"
+	"github.com/broar/chipmusic-cli/pkg/chipmusic/tags"
+	"github.com/broar/chipmusic-cli/pkg/player/chip"
+	"github.com/broar/chipmusic-cli/pkg/player/tracker"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+// UnknownArtist is the artist of files whose artist can't be found
+const UnknownArtist = "Unknown Artist"
+
+// ReadMetadata returns a track with the metadata of the audio file at path, without its audio. The title and artist
+// are read from the ID3 tag of MP3s and from the header of chiptunes and tracker modules, along with the URL of the
+// track page if the tag has one. Whatever is missing is taken from the name of the file, which is split into the artist
+// and title at " - " like the files saved by the download command
+func ReadMetadata(path string) *chipmusic.Track {
+	track := &chipmusic.Track{FileType: chipmusic.FileTypeFromPath(path)}
+	switch track.FileType {
+	case chipmusic.AudioFileTypeMP3:
+		if tag, err := tags.ReadFile(path); err == nil {
+			track.Title, track.Artist, track.PageURL = tag.Title, tag.Artist, tag.Source
+		}
+	case chipmusic.AudioFileTypeNSF, chipmusic.AudioFileTypeSPC, chipmusic.AudioFileTypeSID:
+		if data, err := ioutil.ReadFile(path); err == nil {
+			if tune, err := chip.Load(data); err == nil {
+				track.Title, track.Artist = tune.Title, tune.Artist
+			}
+		}
+	case chipmusic.AudioFileTypeMOD, chipmusic.AudioFileTypeS3M, chipmusic.AudioFileTypeXM, chipmusic.AudioFileTypeIT:
+		if data, err := ioutil.ReadFile(path); err == nil {
+			if module, err := tracker.Load(data); err == nil {
+				track.Title = module.Title
+			}
+		}
+	}
+
+	artist, title := splitFilename(path)
+	if strings.TrimSpace(track.Title) == "" {
+		track.Title = title
+	}
+
+	if strings.TrimSpace(track.Artist) == "" {
+		track.Artist = artist
+	}
+
+	if track.Artist == "" {
+		track.Artist = UnknownArtist
+	}
+
+	return track
+}
+
+// splitFilename splits the name of the file at path without its extension into an artist and a title. If the name
+// doesn't contain " - ", it is all title
+func splitFilename(path string) (artist, title string) {
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	if i := strings.Index(name, " - "); i >= 0 {
+		return strings.TrimSpace(name[:i]), strings.TrimSpace(name[i+3:])
+	}
+
+	return "", strings.TrimSpace(name)
+}
@@ -0,0 +1,68 @@
+package library
+
+import (
+	"github.com/broar/chipmusic-cli/pkg/chipmusic"
+	"github.com/broar/chipmusic-cli/pkg/chipmusic/tags"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadMetadata(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chipmusic-library")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	tagged := filepath.Join(dir, "download.mp3")
+	require.NoError(t, ioutil.WriteFile(tagged, []byte("some.audio"), 0600))
+	require.NoError(t, tags.WriteFile(tagged, &tags.Tags{
+		Title:  "some.title",
+		Artist: "some.artist",
+		Source: "https://chipmusic.org/some.artist/music/some.title",
+	}))
+
+	untitled := filepath.Join(dir, "some.artist - some.title.mp3")
+	require.NoError(t, ioutil.WriteFile(untitled, []byte("some.audio"), 0600))
+	require.NoError(t, tags.WriteFile(untitled, &tags.Tags{Artist: "tagged.artist"}))
+
+	tests := map[string]struct {
+		path     string
+		expected *chipmusic.Track
+	}{
+		"ID3 tag": {
+			path: tagged,
+			expected: &chipmusic.Track{
+				Title:    "some.title",
+				Artist:   "some.artist",
+				PageURL:  "https://chipmusic.org/some.artist/music/some.title",
+				FileType: chipmusic.AudioFileTypeMP3,
+			},
+		},
+		"missing title in tag": {
+			path:     untitled,
+			expected: &chipmusic.Track{Title: "some.title", Artist: "tagged.artist", FileType: chipmusic.AudioFileTypeMP3},
+		},
+		"file name": {
+			path:     filepath.Join(dir, "some.artist - some.title.ogg"),
+			expected: &chipmusic.Track{Title: "some.title", Artist: "some.artist", FileType: chipmusic.AudioFileTypeOGG},
+		},
+		"file name without artist": {
+			path:     filepath.Join(dir, "some.title.wav"),
+			expected: &chipmusic.Track{Title: "some.title", Artist: UnknownArtist, FileType: chipmusic.AudioFileTypeWAV},
+		},
+		"unreadable chiptune": {
+			path:     filepath.Join(dir, "some.title.nsf"),
+			expected: &chipmusic.Track{Title: "some.title", Artist: UnknownArtist, FileType: chipmusic.AudioFileTypeNSF},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(tt *testing.T) {
+			assert.Equal(tt, test.expected, ReadMetadata(test.path))
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package library
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// TrashDirName is the name of the directory within a library where removed files are kept
+	TrashDirName = ".trash"
+
+	// tombstoneExt is the extension of the tombstone kept next to each file in the trash
+	tombstoneExt = ".tombstone.json"
+)
+
+// ErrNotInLibrary is returned when a path given to Trash is outside of the library
+var ErrNotInLibrary = errors.New("file is not in the library")
+
+// Tombstone records the metadata of a file removed from a library, so it can be redownloaded after the file itself is
+// gone
+type Tombstone struct {
+
+	// Path is the path of the file relative to the library before it was removed
+	Path string `json:"path"`
+
+	// Title and Artist are the title and artist of the track read from the file
+	Title  string `json:"title"`
+	Artist string `json:"artist"`
+
+	// URL is the URL of the track page on chipmusic.org the file came from. It is empty if the file didn't record it
+	URL string `json:"url,omitempty"`
+
+	// DeletedAt is when the file was removed
+	DeletedAt time.Time `json:"deletedAt"`
+}
+
+// TrashPath returns the path of the removed file in the trash of the library in dir
+func (t *Tombstone) TrashPath(dir string) string {
+	return filepath.Join(dir, TrashDirName, t.Path)
+}
+
+// Trash moves the file at path out of the library in dir into its trash, keeping its relative path, and writes a
+// tombstone with its metadata next to it. A file removed before from the same path is replaced
+func Trash(dir, path string, now time.Time) (*Tombstone, error) {
+	rel, err := libraryPath(dir, path)
+	if err != nil {
+		return nil, err
+	}
+
+	if info, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("failed to find %s: %w", path, err)
+	} else if info.IsDir() {
+		return nil, fmt.Errorf("%s is a directory", path)
+	}
+
+	metadata := ReadMetadata(path)
+	tombstone := &Tombstone{Path: rel, Title: metadata.Title, Artist: metadata.Artist, URL: metadata.PageURL, DeletedAt: now}
+	trashed := tombstone.TrashPath(dir)
+	if err := os.MkdirAll(filepath.Dir(trashed), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create trash directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(tombstone, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode tombstone: %w", err)
+	}
+
+	if err := ioutil.WriteFile(trashed+tombstoneExt, data, 0600); err != nil {
+		return nil, fmt.Errorf("failed to write tombstone: %w", err)
+	}
+
+	if err := os.Rename(path, trashed); err != nil {
+		os.Remove(trashed + tombstoneExt)
+		return nil, fmt.Errorf("failed to move %s to the trash: %w", path, err)
+	}
+
+	return tombstone, nil
+}
+
+// FindTombstone returns the tombstone of the file which was at path in the library in dir. It returns nil if no file
+// was removed from path
+func FindTombstone(dir, path string) (*Tombstone, error) {
+	rel, err := libraryPath(dir, path)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, TrashDirName, rel) + tombstoneExt)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read tombstone: %w", err)
+	}
+
+	var tombstone Tombstone
+	if err := json.Unmarshal(data, &tombstone); err != nil {
+		return nil, fmt.Errorf("failed to decode tombstone of %s: %w", rel, err)
+	}
+
+	return &tombstone, nil
+}
+
+// Forget deletes the file a tombstone was written for from the trash of the library in dir along with the tombstone
+func Forget(dir string, tombstone *Tombstone) error {
+	trashed := tombstone.TrashPath(dir)
+	if err := os.Remove(trashed); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete %s: %w", trashed, err)
+	}
+
+	if err := os.Remove(trashed + tombstoneExt); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete tombstone of %s: %w", tombstone.Path, err)
+	}
+
+	return nil
+}
+
+// libraryPath returns path relative to the library in dir. Paths in the trash are not part of the library
+func libraryPath(dir, path string) (string, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve library directory: %w", err)
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", path, err)
+	}
+
+	rel, err := filepath.Rel(absDir, absPath)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s: %w", path, ErrNotInLibrary)
+	}
+
+	if rel == TrashDirName || strings.HasPrefix(rel, TrashDirName+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is in the trash: %w", path, ErrNotInLibrary)
+	}
+
+	return rel, nil
+}
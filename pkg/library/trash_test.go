@@ -0,0 +1,83 @@
+package library
+
+import (
+	"errors"
+	"github.com/broar/chipmusic-cli/pkg/chipmusic/tags"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTrash(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chipmusic-library")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "some.artist", "some.title.mp3")
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0700))
+	require.NoError(t, ioutil.WriteFile(path, []byte("some.audio"), 0600))
+	require.NoError(t, tags.WriteFile(path, &tags.Tags{
+		Title:  "some.title",
+		Artist: "some.artist",
+		Source: "https://chipmusic.org/some.artist/music/some.title",
+	}))
+
+	tombstone, err := FindTombstone(dir, path)
+	require.NoError(t, err)
+	assert.Nil(t, tombstone, "expected no tombstone for a file which wasn't removed")
+
+	now := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	trashed, err := Trash(dir, path, now)
+	require.NoError(t, err)
+	assert.Equal(t, &Tombstone{
+		Path:      filepath.Join("some.artist", "some.title.mp3"),
+		Title:     "some.title",
+		Artist:    "some.artist",
+		URL:       "https://chipmusic.org/some.artist/music/some.title",
+		DeletedAt: now,
+	}, trashed)
+
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err), "expected the file to be moved")
+	assert.FileExists(t, filepath.Join(dir, TrashDirName, "some.artist", "some.title.mp3"))
+
+	tombstone, err = FindTombstone(dir, path)
+	require.NoError(t, err)
+	assert.Equal(t, trashed, tombstone)
+
+	require.NoError(t, Forget(dir, tombstone))
+	_, err = os.Stat(tombstone.TrashPath(dir))
+	assert.True(t, os.IsNotExist(err), "expected the file to be deleted from the trash")
+
+	tombstone, err = FindTombstone(dir, path)
+	require.NoError(t, err)
+	assert.Nil(t, tombstone)
+}
+
+func TestTrash_Invalid(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chipmusic-library")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	outside, err := ioutil.TempFile("", "chipmusic-outside")
+	require.NoError(t, err)
+
+	outside.Close()
+	defer os.Remove(outside.Name())
+
+	_, err = Trash(dir, outside.Name(), time.Now())
+	assert.True(t, errors.Is(err, ErrNotInLibrary), "expected files outside of the library to be refused")
+	assert.FileExists(t, outside.Name())
+
+	_, err = Trash(dir, filepath.Join(dir, TrashDirName, "some.file"), time.Now())
+	assert.True(t, errors.Is(err, ErrNotInLibrary), "expected files in the trash to be refused")
+
+	_, err = Trash(dir, filepath.Join(dir, "missing.mp3"), time.Now())
+	assert.Error(t, err)
+}
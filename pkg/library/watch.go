@@ -0,0 +1,132 @@
+package library
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DefaultWatchInterval is the default time between checks of a watched folder
+const DefaultWatchInterval = 2 * time.Second
+
+// partialExtensions are the extensions browsers and download managers give files while they are still downloading
+var partialExtensions = map[string]bool{
+	".crdownload": true,
+	".download":   true,
+	".opdownload": true,
+	".part":       true,
+	".partial":    true,
+	".tmp":        true,
+}
+
+// fileState is what a Watcher compares between checks to tell whether a file is still being written
+type fileState struct {
+	size    int64
+	modTime time.Time
+}
+
+// Watcher finds new files in a folder, e.g. the downloads folder of a browser, by checking it periodically. A file is
+// only reported once it stopped changing between two checks so files which are still being written aren't imported
+// half way. Hidden files, files in subfolders, and files with the extensions of partial downloads are ignored
+type Watcher struct {
+	dir string
+
+	// filter decides which files are reported. If nil, every file is
+	filter func(path string) bool
+
+	// seen holds the files which were reported or which were in the folder before it was watched, and pending holds
+	// the new files along with their state at the last check
+	seen    map[string]bool
+	pending map[string]fileState
+	checked bool
+}
+
+// NewWatcher returns a Watcher for dir which reports the files filter returns true for. If existing is true, files
+// already in the folder are reported by the first check as well, or else only files added afterwards are
+func NewWatcher(dir string, existing bool, filter func(path string) bool) *Watcher {
+	return &Watcher{dir: dir, filter: filter, seen: map[string]bool{}, pending: map[string]fileState{}, checked: existing}
+}
+
+// Check checks the folder once and returns the paths of the files which are new and no longer changing. Files which are
+// removed from the folder are forgotten, so a file added again with the same name is reported again
+func (w *Watcher) Check() ([]string, error) {
+	infos, err := ioutil.ReadDir(w.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", w.dir, err)
+	}
+
+	present := map[string]bool{}
+	var found []string
+	for _, info := range infos {
+		name := info.Name()
+		if !info.Mode().IsRegular() || strings.HasPrefix(name, ".") || partialExtensions[strings.ToLower(filepath.Ext(name))] {
+			continue
+		}
+
+		path := filepath.Join(w.dir, name)
+		if w.filter != nil && !w.filter(path) {
+			continue
+		}
+
+		present[path] = true
+		if w.seen[path] {
+			continue
+		}
+
+		if !w.checked {
+			w.seen[path] = true
+			continue
+		}
+
+		state := fileState{size: info.Size(), modTime: info.ModTime()}
+		if previous, ok := w.pending[path]; ok && previous.size == state.size && previous.modTime.Equal(state.modTime) {
+			delete(w.pending, path)
+			w.seen[path] = true
+			found = append(found, path)
+			continue
+		}
+
+		w.pending[path] = state
+	}
+
+	for path := range w.seen {
+		if !present[path] {
+			delete(w.seen, path)
+		}
+	}
+
+	for path := range w.pending {
+		if !present[path] {
+			delete(w.pending, path)
+		}
+	}
+
+	w.checked = true
+	return found, nil
+}
+
+// Watch checks the folder every interval and calls found with every new file until ctx is done
+func (w *Watcher) Watch(ctx context.Context, interval time.Duration, found func(path string)) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		paths, err := w.Check()
+		if err != nil {
+			return err
+		}
+
+		for _, path := range paths {
+			found(path)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package library
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWatcher_Check(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chipmusic-watch")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+		return path
+	}
+
+	write("existing.mp3", "some.audio")
+	w := NewWatcher(dir, false, func(path string) bool {
+		return !strings.HasSuffix(path, ".txt")
+	})
+
+	// Files in the folder before it is watched aren't reported
+	found, err := w.Check()
+	require.NoError(t, err)
+	assert.Empty(t, found)
+
+	added := write("added.mp3", "some.audio")
+	write("partial.mp3.crdownload", "some.audio")
+	write(".hidden.mp3", "some.audio")
+	write("notes.txt", "some.notes")
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "folder.mp3"), 0700))
+
+	// New files are only reported once they stopped changing
+	found, err = w.Check()
+	require.NoError(t, err)
+	assert.Empty(t, found)
+
+	found, err = w.Check()
+	require.NoError(t, err)
+	assert.Equal(t, []string{added}, found)
+
+	found, err = w.Check()
+	require.NoError(t, err)
+	assert.Empty(t, found)
+
+	// A file which is still being written is reported once it is done
+	growing := write("growing.mp3", "some")
+	_, err = w.Check()
+	require.NoError(t, err)
+
+	write("growing.mp3", "some.audio")
+	found, err = w.Check()
+	require.NoError(t, err)
+	assert.Empty(t, found)
+
+	found, err = w.Check()
+	require.NoError(t, err)
+	assert.Equal(t, []string{growing}, found)
+
+	// A file which is removed and added again is reported again
+	require.NoError(t, os.Remove(added))
+	_, err = w.Check()
+	require.NoError(t, err)
+
+	write("added.mp3", "some.audio")
+	_, err = w.Check()
+	require.NoError(t, err)
+
+	found, err = w.Check()
+	require.NoError(t, err)
+	assert.Equal(t, []string{added}, found)
+}
+
+func TestWatcher_Check_Existing(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chipmusic-watch")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	existing := filepath.Join(dir, "existing.mp3")
+	require.NoError(t, ioutil.WriteFile(existing, []byte("some.audio"), 0600))
+
+	w := NewWatcher(dir, true, nil)
+	found, err := w.Check()
+	require.NoError(t, err)
+	assert.Empty(t, found)
+
+	found, err = w.Check()
+	require.NoError(t, err)
+	assert.Equal(t, []string{existing}, found)
+}
+
+func TestWatcher_Check_MissingFolder(t *testing.T) {
+	_, err := NewWatcher(filepath.Join(os.TempDir(), "chipmusic-missing-folder"), false, nil).Check()
+	assert.Error(t, err)
+}
+
+func TestWatcher_Watch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chipmusic-watch")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	added := filepath.Join(dir, "added.mp3")
+	require.NoError(t, ioutil.WriteFile(added, []byte("some.audio"), 0600))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var found []string
+	err = NewWatcher(dir, true, nil).Watch(ctx, 10*time.Millisecond, func(path string) {
+		found = append(found, path)
+		cancel()
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{added}, found)
+}
@@ -0,0 +1,138 @@
+// Package lsdj reads the metadata of Little Sound Dj (LSDj) project files. LSDj is a Game Boy tracker which keeps its
+// songs in the battery-backed RAM of the cartridge. Emulators and flash carts dump that RAM to .sav files, and single
+// songs are exported from them as .lsdsng files. This package is experimental: only the file system of a save is read,
+// not the song data itself
+package lsdj
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+const (
+	// SaveSize is the size of a .sav file, which holds the whole 128KB RAM of an LSDj cartridge
+	SaveSize = 0x20000
+
+	// BlockSize is the size of the blocks songs are stored in once compressed
+	BlockSize = 0x200
+
+	// NameLength is the maximum length of a song name
+	NameLength = 8
+
+	// MaxSongs is the number of songs a save can hold besides the working song
+	MaxSongs = 0x20
+
+	// headerOffset is where the file system of a save starts, after the working song
+	headerOffset = 0x8000
+
+	// versionsOffset is where the version of each song is stored
+	versionsOffset = headerOffset + MaxSongs*NameLength
+
+	// checkOffset is where the file system is marked as initialized
+	checkOffset = 0x813E
+
+	// activeOffset is where the index of the song loaded as the working song is stored
+	activeOffset = 0x8140
+
+	// allocationOffset is where the allocation table starts, which holds the index of the song each block belongs to
+	allocationOffset = 0x8141
+
+	// blockCount is the number of blocks in a save. The first block holds the file system, so it isn't in the table
+	blockCount = (SaveSize-headerOffset)/BlockSize - 1
+
+	// freeBlock marks blocks which don't belong to a song in the allocation table, and a save without an active song
+	freeBlock = 0xFF
+)
+
+// check marks the file system of a save as initialized
+var check = []byte("jk")
+
+// Song is a song stored in an LSDj project file
+type Song struct {
+	Name string
+
+	// Version is incremented by LSDj each time the song is saved
+	Version int
+
+	// Blocks is the number of blocks the song takes up once compressed
+	Blocks int
+}
+
+// Save is the content of an LSDj .sav file
+type Save struct {
+	// Songs are the songs stored in the save, in the order LSDj lists them
+	Songs []Song
+
+	// Active is the index in Songs of the song loaded as the working song. If -1, the working song was never saved
+	Active int
+
+	// FreeBlocks is the number of blocks available to store more songs
+	FreeBlocks int
+}
+
+// ParseSave reads the songs stored in the content of a .sav file
+func ParseSave(data []byte) (*Save, error) {
+	if len(data) != SaveSize {
+		return nil, fmt.Errorf("save must be %d bytes but is %d bytes", SaveSize, len(data))
+	}
+
+	if !bytes.Equal(data[checkOffset:checkOffset+len(check)], check) {
+		return nil, errors.New("save has no LSDj file system")
+	}
+
+	blocks := make([]int, MaxSongs)
+	free := 0
+	for _, owner := range data[allocationOffset : allocationOffset+blockCount] {
+		switch {
+		case owner == freeBlock:
+			free++
+		case int(owner) < MaxSongs:
+			blocks[owner]++
+		default:
+			return nil, fmt.Errorf("block belongs to song %d but a save holds only %d songs", owner, MaxSongs)
+		}
+	}
+
+	save := &Save{Active: -1, FreeBlocks: free}
+	for i := 0; i < MaxSongs; i++ {
+		if blocks[i] == 0 {
+			continue
+		}
+
+		if int(data[activeOffset]) == i {
+			save.Active = len(save.Songs)
+		}
+
+		save.Songs = append(save.Songs, Song{
+			Name:    parseName(data[headerOffset+i*NameLength : headerOffset+(i+1)*NameLength]),
+			Version: int(data[versionsOffset+i]),
+			Blocks:  blocks[i],
+		})
+	}
+
+	return save, nil
+}
+
+// ParseSong reads the content of a .lsdsng file, which is the name and version of a song followed by its blocks
+func ParseSong(data []byte) (*Song, error) {
+	header := NameLength + 1
+	if len(data) < header+BlockSize || (len(data)-header)%BlockSize != 0 {
+		return nil, fmt.Errorf("song of %d bytes isn't made up of %d byte blocks", len(data), BlockSize)
+	}
+
+	return &Song{
+		Name:    parseName(data[:NameLength]),
+		Version: int(data[NameLength]),
+		Blocks:  (len(data) - header) / BlockSize,
+	}, nil
+}
+
+// parseName returns the song name in b, which is padded with zeros if it is shorter than NameLength
+func parseName(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+
+	return string(b)
+}
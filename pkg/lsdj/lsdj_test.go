@@ -0,0 +1,104 @@
+package lsdj
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+// newSave returns the content of a save with songs, each taking up the given number of blocks, and active loaded
+func newSave(active byte, songs map[byte]int) []byte {
+	data := make([]byte, SaveSize)
+	copy(data[checkOffset:], check)
+	data[activeOffset] = active
+
+	block := allocationOffset
+	for i := allocationOffset; i < allocationOffset+blockCount; i++ {
+		data[i] = freeBlock
+	}
+
+	for index := byte(0); index < MaxSongs; index++ {
+		for i := 0; i < songs[index]; i++ {
+			data[block] = index
+			block++
+		}
+	}
+
+	return data
+}
+
+func TestParseSave(t *testing.T) {
+	data := newSave(3, map[byte]int{0: 2, 3: 5})
+	copy(data[headerOffset:], "CHIPTUNE")
+	copy(data[headerOffset+3*NameLength:], "LOOP")
+	data[versionsOffset+3] = 0x1A
+
+	save, err := ParseSave(data)
+	require.NoError(t, err)
+
+	expected := []Song{{Name: "CHIPTUNE", Version: 0, Blocks: 2}, {Name: "LOOP", Version: 0x1A, Blocks: 5}}
+	assert.Equal(t, expected, save.Songs)
+	assert.Equal(t, 1, save.Active)
+	assert.Equal(t, blockCount-7, save.FreeBlocks)
+}
+
+func TestParseSave_NoActiveSong(t *testing.T) {
+	save, err := ParseSave(newSave(freeBlock, nil))
+	require.NoError(t, err)
+
+	assert.Empty(t, save.Songs)
+	assert.Equal(t, -1, save.Active)
+	assert.Equal(t, blockCount, save.FreeBlocks)
+}
+
+func TestParseSave_Invalid(t *testing.T) {
+	uninitialized := make([]byte, SaveSize)
+	badBlock := newSave(0, map[byte]int{0: 1})
+	badBlock[allocationOffset] = MaxSongs
+
+	testCases := []struct {
+		name string
+		data []byte
+	}{
+		{"Empty", nil},
+		{"Truncated", newSave(0, nil)[:SaveSize-1]},
+		{"Uninitialized", uninitialized},
+		{"BadBlock", badBlock},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			save, err := ParseSave(testCase.data)
+			assert.Error(tt, err)
+			assert.Nil(tt, save)
+		})
+	}
+}
+
+func TestParseSong(t *testing.T) {
+	data := make([]byte, NameLength+1+3*BlockSize)
+	copy(data, "BEEP")
+	data[NameLength] = 7
+
+	song, err := ParseSong(data)
+	require.NoError(t, err)
+	assert.Equal(t, &Song{Name: "BEEP", Version: 7, Blocks: 3}, song)
+}
+
+func TestParseSong_Invalid(t *testing.T) {
+	testCases := []struct {
+		name string
+		size int
+	}{
+		{"HeaderOnly", NameLength + 1},
+		{"PartialBlock", NameLength + 1 + BlockSize + 1},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			song, err := ParseSong(make([]byte, testCase.size))
+			assert.Error(tt, err)
+			assert.Nil(tt, song)
+		})
+	}
+}
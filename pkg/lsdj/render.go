@@ -0,0 +1,65 @@
+package lsdj
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+const (
+	// PlaceholderInput is replaced by the path of the project file in render commands
+	PlaceholderInput = "{input}"
+
+	// PlaceholderOutput is replaced by the path the WAV file should be written to in render commands
+	PlaceholderOutput = "{output}"
+)
+
+// Renderer renders LSDj project files to WAV files with an external tool, e.g. a headless Game Boy emulator running
+// LSDj, since rendering a song means emulating LSDj itself
+type Renderer struct {
+	command []string
+}
+
+// NewRenderer returns a Renderer which runs command, e.g. "lsdj-render --out {output} {input}". The command is split on
+// whitespace, and PlaceholderInput and PlaceholderOutput are replaced in each of its arguments
+func NewRenderer(command string) (*Renderer, error) {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return nil, errors.New("render command cannot be empty")
+	}
+
+	if !strings.Contains(command, PlaceholderOutput) {
+		return nil, fmt.Errorf("render command must contain %s", PlaceholderOutput)
+	}
+
+	return &Renderer{command: fields}, nil
+}
+
+// Render renders the project file at input to a WAV file at output. The output of the tool is included in the error if
+// it fails
+func (r *Renderer) Render(ctx context.Context, input, output string) error {
+	args := make([]string, len(r.command))
+	for i, arg := range r.command {
+		arg = strings.ReplaceAll(arg, PlaceholderInput, input)
+		args[i] = strings.ReplaceAll(arg, PlaceholderOutput, output)
+	}
+
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to render %s with %s: %w: %s", input, args[0], err, strings.TrimSpace(string(out)))
+	}
+
+	info, err := os.Stat(output)
+	if err != nil {
+		return fmt.Errorf("failed to find rendered audio: %w", err)
+	}
+
+	if info.Size() == 0 {
+		return fmt.Errorf("%s rendered no audio", args[0])
+	}
+
+	return nil
+}
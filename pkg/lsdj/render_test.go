@@ -0,0 +1,60 @@
+package lsdj
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewRenderer_Invalid(t *testing.T) {
+	testCases := []struct {
+		name    string
+		command string
+	}{
+		{"Empty", " "},
+		{"NoOutput", "lsdj-render {input}"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			renderer, err := NewRenderer(testCase.command)
+			assert.Error(tt, err)
+			assert.Nil(tt, renderer)
+		})
+	}
+}
+
+func TestRenderer_Render(t *testing.T) {
+	if _, err := exec.LookPath("cp"); err != nil {
+		t.Skip("cp is not available")
+	}
+
+	dir, err := ioutil.TempDir("", "chipmusic-lsdj-*")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	input := filepath.Join(dir, "song.lsdsng")
+	empty := filepath.Join(dir, "empty.lsdsng")
+	require.NoError(t, ioutil.WriteFile(input, []byte("some.audio"), 0644))
+	require.NoError(t, ioutil.WriteFile(empty, nil, 0644))
+
+	// Copying the input stands in for a tool which renders it
+	renderer, err := NewRenderer("cp {input} {output}")
+	require.NoError(t, err)
+
+	output := filepath.Join(dir, "song.wav")
+	require.NoError(t, renderer.Render(context.Background(), input, output))
+
+	content, err := ioutil.ReadFile(output)
+	require.NoError(t, err)
+	assert.Equal(t, "some.audio", string(content))
+
+	assert.Error(t, renderer.Render(context.Background(), filepath.Join(dir, "missing.lsdsng"), output))
+	assert.Error(t, renderer.Render(context.Background(), empty, filepath.Join(dir, "empty.wav")))
+}
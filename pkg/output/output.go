@@ -0,0 +1,144 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/broar/chipmusic-cli/pkg/chipmusic"
+	"github.com/broar/chipmusic-cli/pkg/store"
+	"io"
+	"time"
+)
+
+const (
+	// FormatPlain prints output as text meant for people, e.g. tables
+	FormatPlain Format = "plain"
+
+	// FormatJSON prints output as JSON meant for scripts, e.g. to be queried with jq
+	FormatJSON Format = "json"
+)
+
+// Format is how commands print their output
+type Format string
+
+// ParseFormat returns the Format named by name. An empty name is FormatPlain
+func ParseFormat(name string) (Format, error) {
+	switch Format(name) {
+	case "", FormatPlain:
+		return FormatPlain, nil
+	case FormatJSON:
+		return FormatJSON, nil
+	default:
+		return "", fmt.Errorf("unknown output format %q. Allowed formats: [%s, %s]", name, FormatPlain, FormatJSON)
+	}
+}
+
+// The types below are what commands print as JSON. Their field names are relied on by scripts, so fields may be added
+// but never renamed or removed. Durations are in seconds and times are in RFC 3339
+
+// Track is the metadata of a track
+type Track struct {
+	URL         string     `json:"url"`
+	Title       string     `json:"title"`
+	Artist      string     `json:"artist"`
+	Tags        []string   `json:"tags"`
+	FileType    string     `json:"fileType"`
+	Size        int64      `json:"size,omitempty"`
+	Duration    float64    `json:"duration,omitempty"`
+	DownloadURL string     `json:"downloadUrl"`
+	PostedAt    *time.Time `json:"postedAt,omitempty"`
+}
+
+// NewTrack returns the metadata of track
+func NewTrack(track *chipmusic.Track) Track {
+	return Track{
+		URL:         track.PageURL,
+		Title:       track.Title,
+		Artist:      track.Artist,
+		Tags:        append([]string{}, track.Tags...),
+		FileType:    string(track.FileType),
+		Size:        track.Size,
+		Duration:    track.Duration.Seconds(),
+		DownloadURL: track.DownloadURL,
+		PostedAt:    optionalTime(track.PostedAt),
+	}
+}
+
+// SearchResult is a track listed by a search. Index is its position on the page of results, starting at 1
+type SearchResult struct {
+	Index    int        `json:"index"`
+	Title    string     `json:"title"`
+	Artist   string     `json:"artist"`
+	URL      string     `json:"url"`
+	PostedAt *time.Time `json:"postedAt,omitempty"`
+	Views    int        `json:"views"`
+	Comments int        `json:"comments"`
+}
+
+// NewSearchResults returns the tracks listed by a search in the same order
+func NewSearchResults(results []chipmusic.SearchResult) []SearchResult {
+	converted := make([]SearchResult, 0, len(results))
+	for i, result := range results {
+		converted = append(converted, SearchResult{
+			Index:    i + 1,
+			Title:    result.Title,
+			Artist:   result.Artist,
+			URL:      result.URL,
+			PostedAt: optionalTime(result.PostedAt),
+			Views:    result.Views,
+			Comments: result.Comments,
+		})
+	}
+
+	return converted
+}
+
+// HistoryEntry is a track in the listening history. Listened is how long it was listened to
+type HistoryEntry struct {
+	URL      string    `json:"url"`
+	Title    string    `json:"title"`
+	Artist   string    `json:"artist"`
+	PlayedAt time.Time `json:"playedAt"`
+	Listened float64   `json:"listened"`
+}
+
+// NewHistoryEntries returns the entries of the listening history in the same order
+func NewHistoryEntries(entries []store.HistoryEntry) []HistoryEntry {
+	converted := make([]HistoryEntry, 0, len(entries))
+	for _, entry := range entries {
+		converted = append(converted, HistoryEntry{
+			URL:      entry.URL,
+			Title:    entry.Title,
+			Artist:   entry.Artist,
+			PlayedAt: entry.PlayedAt,
+			Listened: entry.Listened.Seconds(),
+		})
+	}
+
+	return converted
+}
+
+// Download is a track saved to disk at Path
+type Download struct {
+	Track
+	Path string `json:"path"`
+}
+
+// WriteJSON writes v to w as indented JSON
+func WriteJSON(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		return fmt.Errorf("failed to write JSON: %w", err)
+	}
+
+	return nil
+}
+
+// optionalTime returns nil for the zero time, so unknown times are left out of the JSON
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+
+	return &t
+}
@@ -0,0 +1,87 @@
+package output
+
+import (
+	"bytes"
+	"github.com/broar/chipmusic-cli/pkg/chipmusic"
+	"github.com/broar/chipmusic-cli/pkg/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestParseFormat(t *testing.T) {
+	testCases := []struct {
+		name     string
+		expected Format
+		err      bool
+	}{
+		{"", FormatPlain, false},
+		{"plain", FormatPlain, false},
+		{"json", FormatJSON, false},
+		{"yaml", "", true},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			format, err := ParseFormat(testCase.name)
+			assert.Equal(tt, testCase.expected, format)
+			assert.Equal(tt, testCase.err, err != nil)
+		})
+	}
+}
+
+func TestWriteJSON_Track(t *testing.T) {
+	var b bytes.Buffer
+	track := &chipmusic.Track{
+		PageURL:     "some.url",
+		Title:       "some.title",
+		Artist:      "some.artist",
+		Tags:        []string{"lsdj"},
+		FileType:    chipmusic.AudioFileTypeMP3,
+		Size:        1024,
+		Duration:    90 * time.Second,
+		DownloadURL: "some.download.url",
+		PostedAt:    time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+
+	require.NoError(t, WriteJSON(&b, Download{Track: NewTrack(track), Path: "some.path"}))
+	assert.JSONEq(t, `{
+		"url": "some.url",
+		"title": "some.title",
+		"artist": "some.artist",
+		"tags": ["lsdj"],
+		"fileType": "mp3",
+		"size": 1024,
+		"duration": 90,
+		"downloadUrl": "some.download.url",
+		"postedAt": "2020-01-02T03:04:05Z",
+		"path": "some.path"
+	}`, b.String())
+}
+
+func TestWriteJSON_SearchResults(t *testing.T) {
+	var b bytes.Buffer
+	results := []chipmusic.SearchResult{
+		{URL: "first.url", Title: "first.title", Artist: "first.artist", Views: 10, Comments: 1},
+		{URL: "second.url", Title: "second.title", Artist: "second.artist", PostedAt: time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)},
+	}
+
+	require.NoError(t, WriteJSON(&b, NewSearchResults(results)))
+	assert.JSONEq(t, `[
+		{"index": 1, "title": "first.title", "artist": "first.artist", "url": "first.url", "views": 10, "comments": 1},
+		{"index": 2, "title": "second.title", "artist": "second.artist", "url": "second.url", "postedAt": "2020-01-02T00:00:00Z", "views": 0, "comments": 0}
+	]`, b.String())
+}
+
+func TestWriteJSON_HistoryEntries(t *testing.T) {
+	var b bytes.Buffer
+	entries := []store.HistoryEntry{
+		{URL: "some.url", Title: "some.title", Artist: "some.artist", PlayedAt: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC), Listened: 90 * time.Second},
+	}
+
+	require.NoError(t, WriteJSON(&b, NewHistoryEntries(entries)))
+	assert.JSONEq(t, `[
+		{"url": "some.url", "title": "some.title", "artist": "some.artist", "playedAt": "2020-01-02T03:04:05Z", "listened": 90}
+	]`, b.String())
+}
@@ -0,0 +1,553 @@
+package chip
+
+// region has the timings of the NES which differ between NTSC and PAL consoles
+type region struct {
+	clock         float64
+	frameCycles   int
+	noisePeriods  [16]int
+	dmcPeriods    [16]int
+	defaultPeriod int
+}
+
+var (
+	ntsc = &region{
+		clock:         1789773,
+		frameCycles:   7457,
+		noisePeriods:  [16]int{4, 8, 16, 32, 64, 96, 128, 160, 202, 254, 380, 508, 762, 1016, 2034, 4068},
+		dmcPeriods:    [16]int{428, 380, 340, 320, 286, 254, 226, 214, 190, 160, 142, 128, 106, 84, 72, 54},
+		defaultPeriod: 16639,
+	}
+
+	pal = &region{
+		clock:         1662607,
+		frameCycles:   8313,
+		noisePeriods:  [16]int{4, 8, 14, 30, 60, 88, 118, 148, 188, 236, 354, 472, 708, 944, 1890, 3778},
+		dmcPeriods:    [16]int{398, 354, 316, 298, 276, 236, 210, 198, 176, 148, 132, 118, 98, 78, 66, 50},
+		defaultPeriod: 19997,
+	}
+)
+
+var (
+	lengthTable = [32]int{
+		10, 254, 20, 2, 40, 4, 80, 6, 160, 8, 60, 10, 14, 12, 26, 14,
+		12, 16, 24, 18, 48, 20, 96, 22, 192, 24, 72, 26, 16, 28, 32, 30,
+	}
+
+	dutyTable = [4][8]byte{
+		{0, 1, 0, 0, 0, 0, 0, 0},
+		{0, 1, 1, 0, 0, 0, 0, 0},
+		{0, 1, 1, 1, 1, 0, 0, 0},
+		{1, 0, 0, 1, 1, 1, 1, 1},
+	}
+
+	triangleTable = [32]byte{
+		15, 14, 13, 12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1, 0,
+		0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15,
+	}
+)
+
+// envelope is the volume envelope of the pulse and noise channels, which decays from 15 or holds a constant volume
+type envelope struct {
+	start    bool
+	loop     bool
+	constant bool
+	volume   byte
+	divider  byte
+	decay    byte
+}
+
+func (e *envelope) set(v byte) {
+	e.loop = v&0x20 != 0
+	e.constant = v&0x10 != 0
+	e.volume = v & 0xF
+}
+
+func (e *envelope) clock() {
+	switch {
+	case e.start:
+		e.start = false
+		e.decay = 15
+		e.divider = e.volume
+	case e.divider > 0:
+		e.divider--
+	default:
+		e.divider = e.volume
+		if e.decay > 0 {
+			e.decay--
+		} else if e.loop {
+			e.decay = 15
+		}
+	}
+}
+
+func (e *envelope) output() byte {
+	if e.constant {
+		return e.volume
+	}
+
+	return e.decay
+}
+
+// pulse is one of the two square wave channels
+type pulse struct {
+	second   bool
+	envelope envelope
+	length   int
+	duty     byte
+	step     byte
+	period   int
+	timer    int
+
+	sweepEnabled bool
+	sweepNegate  bool
+	sweepReload  bool
+	sweepPeriod  byte
+	sweepShift   byte
+	sweepDivider byte
+}
+
+func (p *pulse) write(reg uint16, v byte, enabled bool) {
+	switch reg {
+	case 0:
+		p.duty = v >> 6
+		p.envelope.set(v)
+	case 1:
+		p.sweepEnabled = v&0x80 != 0
+		p.sweepPeriod = v >> 4 & 7
+		p.sweepNegate = v&0x08 != 0
+		p.sweepShift = v & 7
+		p.sweepReload = true
+	case 2:
+		p.period = p.period&0x700 | int(v)
+	case 3:
+		p.period = p.period&0xFF | int(v&7)<<8
+		if enabled {
+			p.length = lengthTable[v>>3]
+		}
+
+		p.step = 0
+		p.envelope.start = true
+	}
+}
+
+func (p *pulse) clock(cycles int) {
+	p.timer -= cycles
+	for p.timer <= 0 {
+		p.timer += (p.period + 1) * 2
+		p.step = (p.step + 1) & 7
+	}
+}
+
+func (p *pulse) sweepTarget() int {
+	change := p.period >> p.sweepShift
+	if !p.sweepNegate {
+		return p.period + change
+	}
+
+	if p.second {
+		return p.period - change
+	}
+
+	return p.period - change - 1
+}
+
+func (p *pulse) muted() bool {
+	return p.period < 8 || p.sweepTarget() > 0x7FF
+}
+
+func (p *pulse) clockHalfFrame() {
+	if p.length > 0 && !p.envelope.loop {
+		p.length--
+	}
+
+	if p.sweepDivider == 0 && p.sweepEnabled && p.sweepShift > 0 && !p.muted() {
+		p.period = p.sweepTarget()
+	}
+
+	if p.sweepDivider == 0 || p.sweepReload {
+		p.sweepDivider = p.sweepPeriod
+		p.sweepReload = false
+	} else {
+		p.sweepDivider--
+	}
+}
+
+func (p *pulse) output() byte {
+	if p.length == 0 || p.muted() || dutyTable[p.duty][p.step] == 0 {
+		return 0
+	}
+
+	return p.envelope.output()
+}
+
+// triangle is the triangle wave channel, which has a linear counter besides the length counter and no volume
+type triangle struct {
+	control       bool
+	reload        bool
+	reloadValue   byte
+	linearCounter byte
+	length        int
+	step          byte
+	period        int
+	timer         int
+}
+
+func (t *triangle) write(reg uint16, v byte, enabled bool) {
+	switch reg {
+	case 0:
+		t.control = v&0x80 != 0
+		t.reloadValue = v & 0x7F
+	case 2:
+		t.period = t.period&0x700 | int(v)
+	case 3:
+		t.period = t.period&0xFF | int(v&7)<<8
+		if enabled {
+			t.length = lengthTable[v>>3]
+		}
+
+		t.reload = true
+	}
+}
+
+func (t *triangle) clock(cycles int) {
+	// Ultrasonic periods are silenced instead of played as pops, like most emulators do
+	if t.length == 0 || t.linearCounter == 0 || t.period < 2 {
+		return
+	}
+
+	t.timer -= cycles
+	for t.timer <= 0 {
+		t.timer += t.period + 1
+		t.step = (t.step + 1) & 31
+	}
+}
+
+func (t *triangle) clockQuarterFrame() {
+	if t.reload {
+		t.linearCounter = t.reloadValue
+	} else if t.linearCounter > 0 {
+		t.linearCounter--
+	}
+
+	if !t.control {
+		t.reload = false
+	}
+}
+
+func (t *triangle) clockHalfFrame() {
+	if t.length > 0 && !t.control {
+		t.length--
+	}
+}
+
+func (t *triangle) output() byte {
+	return triangleTable[t.step]
+}
+
+// noise is the pseudo-random noise channel
+type noise struct {
+	envelope envelope
+	length   int
+	short    bool
+	period   int
+	timer    int
+	shift    uint16
+}
+
+func (n *noise) write(reg uint16, v byte, enabled bool, periods *[16]int) {
+	switch reg {
+	case 0:
+		n.envelope.set(v)
+	case 2:
+		n.short = v&0x80 != 0
+		n.period = periods[v&0xF]
+	case 3:
+		if enabled {
+			n.length = lengthTable[v>>3]
+		}
+
+		n.envelope.start = true
+	}
+}
+
+func (n *noise) clock(cycles int) {
+	n.timer -= cycles
+	for n.timer <= 0 {
+		n.timer += n.period
+		tap := uint16(1)
+		if n.short {
+			tap = 6
+		}
+
+		feedback := (n.shift ^ n.shift>>tap) & 1
+		n.shift = n.shift>>1 | feedback<<14
+	}
+}
+
+func (n *noise) clockHalfFrame() {
+	if n.length > 0 && !n.envelope.loop {
+		n.length--
+	}
+}
+
+func (n *noise) output() byte {
+	if n.length == 0 || n.shift&1 != 0 {
+		return 0
+	}
+
+	return n.envelope.output()
+}
+
+// dmc is the delta modulation channel, which plays 1-bit delta encoded samples from memory or levels written to it
+type dmc struct {
+	mem       memory
+	loop      bool
+	period    int
+	timer     int
+	level     byte
+	address   uint16
+	length    int
+	current   uint16
+	remaining int
+
+	buffer      byte
+	bufferEmpty bool
+	shift       byte
+	bits        int
+	silence     bool
+}
+
+func (d *dmc) write(reg uint16, v byte, periods *[16]int) {
+	switch reg {
+	case 0:
+		d.loop = v&0x40 != 0
+		d.period = periods[v&0xF]
+	case 1:
+		d.level = v & 0x7F
+	case 2:
+		d.address = 0xC000 | uint16(v)<<6
+	case 3:
+		d.length = int(v)<<4 | 1
+	}
+}
+
+func (d *dmc) restart() {
+	d.current = d.address
+	d.remaining = d.length
+}
+
+func (d *dmc) fill() {
+	if !d.bufferEmpty || d.remaining == 0 {
+		return
+	}
+
+	d.buffer = d.mem.read(d.current)
+	d.bufferEmpty = false
+	d.current++
+	if d.current == 0 {
+		d.current = 0x8000
+	}
+
+	d.remaining--
+	if d.remaining == 0 && d.loop {
+		d.restart()
+	}
+}
+
+func (d *dmc) clock(cycles int) {
+	if d.period == 0 {
+		return
+	}
+
+	d.timer -= cycles
+	for d.timer <= 0 {
+		d.timer += d.period
+		if !d.silence {
+			if d.shift&1 != 0 {
+				if d.level <= 125 {
+					d.level += 2
+				}
+			} else if d.level >= 2 {
+				d.level -= 2
+			}
+
+			d.shift >>= 1
+		}
+
+		d.bits--
+		if d.bits <= 0 {
+			d.bits = 8
+			d.silence = d.bufferEmpty
+			if !d.bufferEmpty {
+				d.shift = d.buffer
+				d.bufferEmpty = true
+			}
+		}
+
+		d.fill()
+	}
+}
+
+// apu emulates the audio processing unit of the 2A03, the CPU of the NES, without its frame interrupt
+type apu struct {
+	region   *region
+	pulses   [2]pulse
+	triangle triangle
+	noise    noise
+	dmc      dmc
+	enabled  byte
+
+	// frameTimer counts down the cycles to the next step of the frame counter, which clocks the envelopes, sweeps,
+	// and length counters
+	frameTimer int
+	frameStep  int
+	fiveStep   bool
+}
+
+func newAPU(r *region, mem memory) *apu {
+	a := &apu{region: r, frameTimer: r.frameCycles}
+	a.pulses[1].second = true
+	a.noise.shift = 1
+	a.noise.period = r.noisePeriods[0]
+	a.dmc.mem = mem
+	a.dmc.period = r.dmcPeriods[0]
+	a.dmc.bufferEmpty = true
+	a.dmc.silence = true
+	a.dmc.bits = 8
+	return a
+}
+
+func (a *apu) write(addr uint16, v byte) {
+	reg := addr & 3
+	switch {
+	case addr < 0x4004:
+		a.pulses[0].write(reg, v, a.enabled&0x01 != 0)
+	case addr < 0x4008:
+		a.pulses[1].write(reg, v, a.enabled&0x02 != 0)
+	case addr < 0x400C:
+		a.triangle.write(reg, v, a.enabled&0x04 != 0)
+	case addr < 0x4010:
+		a.noise.write(reg, v, a.enabled&0x08 != 0, &a.region.noisePeriods)
+	case addr < 0x4014:
+		a.dmc.write(reg, v, &a.region.dmcPeriods)
+	case addr == 0x4015:
+		a.enable(v)
+	case addr == 0x4017:
+		a.fiveStep = v&0x80 != 0
+		a.frameStep = 0
+		a.frameTimer = a.region.frameCycles
+		if a.fiveStep {
+			a.clockQuarterFrame()
+			a.clockHalfFrame()
+		}
+	}
+}
+
+func (a *apu) enable(v byte) {
+	a.enabled = v
+	if v&0x01 == 0 {
+		a.pulses[0].length = 0
+	}
+
+	if v&0x02 == 0 {
+		a.pulses[1].length = 0
+	}
+
+	if v&0x04 == 0 {
+		a.triangle.length = 0
+	}
+
+	if v&0x08 == 0 {
+		a.noise.length = 0
+	}
+
+	if v&0x10 == 0 {
+		a.dmc.remaining = 0
+	} else if a.dmc.remaining == 0 {
+		a.dmc.restart()
+		a.dmc.fill()
+	}
+}
+
+// status returns the status register, which has the length counters that are running and whether the DMC is playing
+func (a *apu) status() byte {
+	var v byte
+	for i, length := range []int{a.pulses[0].length, a.pulses[1].length, a.triangle.length, a.noise.length} {
+		if length > 0 {
+			v |= 1 << uint(i)
+		}
+	}
+
+	if a.dmc.remaining > 0 {
+		v |= 0x10
+	}
+
+	return v
+}
+
+func (a *apu) clockQuarterFrame() {
+	a.pulses[0].envelope.clock()
+	a.pulses[1].envelope.clock()
+	a.triangle.clockQuarterFrame()
+	a.noise.envelope.clock()
+}
+
+func (a *apu) clockHalfFrame() {
+	a.pulses[0].clockHalfFrame()
+	a.pulses[1].clockHalfFrame()
+	a.triangle.clockHalfFrame()
+	a.noise.clockHalfFrame()
+}
+
+// clock runs the APU for a number of CPU cycles
+func (a *apu) clock(cycles int) {
+	a.pulses[0].clock(cycles)
+	a.pulses[1].clock(cycles)
+	a.triangle.clock(cycles)
+	a.noise.clock(cycles)
+	a.dmc.clock(cycles)
+
+	a.frameTimer -= cycles
+	for a.frameTimer <= 0 {
+		a.frameTimer += a.region.frameCycles
+		a.stepFrame()
+	}
+}
+
+// stepFrame steps the frame counter. The four step sequence clocks the length counters and sweeps every other step,
+// the five step one skips its fourth step
+func (a *apu) stepFrame() {
+	if a.fiveStep {
+		a.frameStep = (a.frameStep + 1) % 5
+		switch a.frameStep {
+		case 1, 3:
+			a.clockQuarterFrame()
+		case 2, 0:
+			a.clockQuarterFrame()
+			a.clockHalfFrame()
+		}
+
+		return
+	}
+
+	a.frameStep = (a.frameStep + 1) % 4
+	a.clockQuarterFrame()
+	if a.frameStep%2 == 0 {
+		a.clockHalfFrame()
+	}
+}
+
+// output mixes the channels the way the resistors at the output of the 2A03 do, from 0 to about 1
+func (a *apu) output() float64 {
+	var out float64
+	if pulses := float64(a.pulses[0].output()) + float64(a.pulses[1].output()); pulses > 0 {
+		out = 95.88 / (8128/pulses + 100)
+	}
+
+	tnd := float64(a.triangle.output())/8227 + float64(a.noise.output())/12241 + float64(a.dmc.level)/22638
+	if tnd > 0 {
+		out += 159.79 / (1/tnd + 100)
+	}
+
+	return out
+}
@@ -0,0 +1,101 @@
+package chip
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestEnvelope(t *testing.T) {
+	e := &envelope{}
+	e.set(0x01)
+	e.start = true
+	e.clock()
+	assert.Equal(t, byte(15), e.output())
+
+	// The decay level drops every period + 1 clocks
+	e.clock()
+	e.clock()
+	assert.Equal(t, byte(14), e.output())
+
+	for i := 0; i < 100; i++ {
+		e.clock()
+	}
+
+	assert.Equal(t, byte(0), e.output())
+
+	e.set(0x10 | 9)
+	assert.Equal(t, byte(9), e.output())
+}
+
+func TestPulse_Sweep(t *testing.T) {
+	p := &pulse{period: 0x400, sweepShift: 1}
+	assert.Equal(t, 0x600, p.sweepTarget())
+	assert.False(t, p.muted())
+
+	// The first pulse channel subtracts one more than the second when sweeping down
+	p.sweepNegate = true
+	assert.Equal(t, 0x1FF, p.sweepTarget())
+	p.second = true
+	assert.Equal(t, 0x200, p.sweepTarget())
+
+	// Sweeps past the highest period mute the channel even when they're disabled
+	p = &pulse{period: 0x600, sweepShift: 1}
+	assert.True(t, p.muted())
+	p = &pulse{period: 7}
+	assert.True(t, p.muted())
+}
+
+func TestAPU_Length(t *testing.T) {
+	a := newAPU(ntsc, &testMemory{})
+	a.write(0x4015, 0x01)
+	a.write(0x4000, 0x3F)
+	a.write(0x4002, 0xFD)
+	a.write(0x4003, 0x08)
+	assert.Equal(t, byte(0x01), a.status())
+	assert.Equal(t, 254, a.pulses[0].length)
+
+	// Writes to disabled channels don't load their length counters
+	a.write(0x400B, 0x08)
+	assert.Equal(t, 0, a.triangle.length)
+
+	// The length counter counts down twice per frame
+	a.write(0x4000, 0x1F)
+	a.clock(ntsc.frameCycles * 4)
+	assert.Equal(t, 252, a.pulses[0].length)
+
+	a.write(0x4015, 0)
+	assert.Equal(t, byte(0), a.status())
+}
+
+func TestAPU_DMC(t *testing.T) {
+	mem := &testMemory{}
+	mem[0xC000] = 0xFF
+	a := newAPU(ntsc, mem)
+	a.write(0x4010, 0x0F)
+	a.write(0x4011, 0x40)
+	a.write(0x4012, 0x00)
+	a.write(0x4013, 0x00)
+	a.write(0x4015, 0x10)
+	assert.Equal(t, byte(0x40), a.dmc.level)
+
+	// Set bits raise the level by two
+	a.clock(ntsc.dmcPeriods[0xF] * 16)
+	assert.Equal(t, byte(0x40+16), a.dmc.level)
+	assert.Equal(t, byte(0), a.status()&0x10)
+}
+
+func TestAPU_Output(t *testing.T) {
+	// The triangle holds its level when stopped, which adds an offset
+	a := newAPU(ntsc, &testMemory{})
+	offset := a.output()
+	assert.InDelta(t, 0.246, offset, 0.001)
+
+	a.write(0x4011, 0x7F)
+	assert.True(t, a.output() > offset)
+
+	a.pulses[0].length, a.pulses[0].period, a.pulses[0].envelope.constant, a.pulses[0].envelope.volume = 1, 100, true, 15
+	a.pulses[0].step = 1
+	a.pulses[1] = a.pulses[0]
+	a.dmc.level, a.triangle.step = 0, 15
+	assert.InDelta(t, 0.2585, a.output(), 0.001)
+}
@@ -0,0 +1,227 @@
+// Package chip plays dumps of the music drivers of game consoles and home computers, i.e. NSF files for the NES, SPC
+// files for the SNES, and SID files for the C64, by emulating the CPU which runs the driver and the sound chip it
+// drives. The dumps don't say how long songs are, except for SPC files, so each format has a heuristic length after
+// which the song fades out, and songs end early once they fall silent
+package chip
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+const (
+	// silenceDuration is how long a song has to be silent to end before its length. Drivers stop making sound at the
+	// end of songs which don't loop, like jingles and sound effects
+	silenceDuration = 3 * time.Second
+
+	// silenceLevel is the loudest a frame can be and still count as silent
+	silenceLevel = 1.0 / 4096
+
+	// defaultFade is how long songs fade out for at the end of their length
+	defaultFade = 8 * time.Second
+
+	// seekFrames is the number of frames emulated at a time when seeking
+	seekFrames = 4096
+)
+
+var (
+	// ErrUnknownFormat is returned when data isn't a chiptune in a supported format
+	ErrUnknownFormat = errors.New("unknown chiptune format")
+
+	// ErrTruncated is returned when a chiptune ends before all of its data
+	ErrTruncated = errors.New("chiptune is truncated")
+)
+
+// emulator plays a song on an emulated machine
+type emulator interface {
+	// render fills samples with the next frames of the song
+	render(samples [][2]float64)
+}
+
+// Tune is a chiptune ready to be played. Dumps can have many songs, of which Song is played
+type Tune struct {
+	Title      string
+	Artist     string
+	Format     string
+	Songs      int
+	Song       int
+	SampleRate int
+
+	// Length is how long the song plays before fading out for Fade
+	Length time.Duration
+	Fade   time.Duration
+
+	// newEmulator creates an emulator which plays song from the start, counting from 1
+	newEmulator func(song int) emulator
+}
+
+// Load loads a chiptune in any of the supported formats
+func Load(data []byte) (*Tune, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte(nsfSignature)):
+		return LoadNSF(data)
+	case bytes.HasPrefix(data, []byte(spcSignature)):
+		return LoadSPC(data)
+	case bytes.HasPrefix(data, []byte(psidSignature)), bytes.HasPrefix(data, []byte(rsidSignature)):
+		return LoadSID(data)
+	default:
+		return nil, ErrUnknownFormat
+	}
+}
+
+// SelectSong selects which of the songs of the tune is played, counting from 1. Streams created before keep playing
+// the song they started with
+func (t *Tune) SelectSong(song int) error {
+	if song < 1 || song > t.Songs {
+		return fmt.Errorf("song %d doesn't exist, the tune has songs 1 to %d", song, t.Songs)
+	}
+
+	t.Song = song
+	return nil
+}
+
+// Stream plays a tune as stereo audio at its sample rate. It implements beep.StreamSeekCloser
+type Stream struct {
+	tune     *Tune
+	emulator emulator
+	position int
+	length   int
+
+	// fadeStart is the frame where the song starts to fade out. silentFrames is the number of frames the song has been
+	// silent for, which ends it once it reaches maxSilentFrames
+	fadeStart       int
+	silentFrames    int
+	maxSilentFrames int
+	ended           bool
+}
+
+// NewStream creates a stream which plays t
+func NewStream(t *Tune) *Stream {
+	s := &Stream{
+		tune:            t,
+		emulator:        t.newEmulator(t.Song),
+		length:          frames(t.Length+t.Fade, t.SampleRate),
+		fadeStart:       frames(t.Length, t.SampleRate),
+		maxSilentFrames: frames(silenceDuration, t.SampleRate),
+	}
+
+	return s
+}
+
+// frames returns the number of frames in d at sampleRate
+func frames(d time.Duration, sampleRate int) int {
+	return int(d.Seconds() * float64(sampleRate))
+}
+
+// Stream plays the next frames of the song into samples
+func (s *Stream) Stream(samples [][2]float64) (int, bool) {
+	if s.ended || s.position >= s.length {
+		return 0, false
+	}
+
+	if left := s.length - s.position; len(samples) > left {
+		samples = samples[:left]
+	}
+
+	s.emulator.render(samples)
+	for i := range samples {
+		if position := s.position + i; position >= s.fadeStart {
+			gain := 1 - float64(position-s.fadeStart)/float64(s.length-s.fadeStart)
+			samples[i][0] *= gain
+			samples[i][1] *= gain
+		}
+
+		if math.Abs(samples[i][0]) > silenceLevel || math.Abs(samples[i][1]) > silenceLevel {
+			s.silentFrames = 0
+			continue
+		}
+
+		s.silentFrames++
+		if s.silentFrames >= s.maxSilentFrames {
+			s.ended = true
+			s.position += i + 1
+			return i + 1, true
+		}
+	}
+
+	s.position += len(samples)
+	return len(samples), true
+}
+
+// Err always returns nil since emulation can't fail
+func (s *Stream) Err() error {
+	return nil
+}
+
+// Len returns the number of frames in the song, which may end earlier if it falls silent
+func (s *Stream) Len() int {
+	return s.length
+}
+
+// Position returns the number of frames already played
+func (s *Stream) Position() int {
+	return s.position
+}
+
+// Seek moves to frame p. Emulators can only run forward, so seeking emulates the song up to p, from the start when
+// seeking backwards
+func (s *Stream) Seek(p int) error {
+	if p < 0 {
+		p = 0
+	}
+
+	if p > s.length {
+		p = s.length
+	}
+
+	if p < s.position {
+		s.emulator = s.tune.newEmulator(s.tune.Song)
+		s.position, s.silentFrames, s.ended = 0, 0, false
+	}
+
+	samples := make([][2]float64, seekFrames)
+	for s.position < p {
+		n := p - s.position
+		if n > len(samples) {
+			n = len(samples)
+		}
+
+		if _, ok := s.Stream(samples[:n]); !ok {
+			break
+		}
+	}
+
+	return nil
+}
+
+// Close does nothing since tunes are held in memory
+func (s *Stream) Close() error {
+	return nil
+}
+
+// dcFilter removes the DC offset from audio, like the coupling capacitors at the output of sound chips
+type dcFilter struct {
+	input, output float64
+}
+
+// dcFilterPole sets the cutoff of DC filters to a few Hz
+const dcFilterPole = 0.999
+
+func (f *dcFilter) filter(v float64) float64 {
+	f.output = v - f.input + dcFilterPole*f.output
+	f.input = v
+	return f.output
+}
+
+// text returns a string stored in a fixed length field of a header, which ends at the first NUL
+func text(data []byte) string {
+	if i := bytes.IndexByte(data, 0); i >= 0 {
+		data = data[:i]
+	}
+
+	return strings.TrimSpace(string(data))
+}
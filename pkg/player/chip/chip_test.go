@@ -0,0 +1,180 @@
+package chip
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+const testSampleRate = 1000
+
+// testEmulator renders a constant value and counts the frames it rendered
+type testEmulator struct {
+	value  float64
+	frames int
+}
+
+func (e *testEmulator) render(samples [][2]float64) {
+	for i := range samples {
+		samples[i] = [2]float64{e.value, e.value}
+	}
+
+	e.frames += len(samples)
+}
+
+// newTestTune creates a tune of length seconds which fades out for fade seconds and renders value
+func newTestTune(value float64, length, fade time.Duration) (*Tune, *[]*testEmulator) {
+	emulators := &[]*testEmulator{}
+	return &Tune{
+		SampleRate: testSampleRate,
+		Length:     length,
+		Fade:       fade,
+		newEmulator: func(song int) emulator {
+			e := &testEmulator{value: value}
+			*emulators = append(*emulators, e)
+			return e
+		},
+	}, emulators
+}
+
+// zeroCrossings counts how many times the left channel changes sign
+func zeroCrossings(samples [][2]float64) int {
+	crossings := 0
+	for i := 1; i < len(samples); i++ {
+		if (samples[i-1][0] < 0) != (samples[i][0] < 0) {
+			crossings++
+		}
+	}
+
+	return crossings
+}
+
+func TestLoad(t *testing.T) {
+	testCases := []struct {
+		name   string
+		data   []byte
+		format string
+	}{
+		{"NSF", newTestNSF(testNSFInit), "nsf"},
+		{"SPC", newTestSPC(testSPCTextTag), "spc"},
+		{"SID", newTestSID(testSIDInit, testSIDPlayAddress), "sid"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			tune, err := Load(testCase.data)
+			require.NoError(tt, err)
+			assert.Equal(tt, testCase.format, tune.Format)
+		})
+	}
+
+	tune, err := Load([]byte("RIFF"))
+	assert.Equal(t, ErrUnknownFormat, err)
+	assert.Nil(t, tune)
+}
+
+func TestStream_Len(t *testing.T) {
+	tune, _ := newTestTune(0.5, 2*time.Second, time.Second)
+	s := NewStream(tune)
+	assert.Equal(t, 3*testSampleRate, s.Len())
+	assert.Equal(t, 0, s.Position())
+	assert.NoError(t, s.Err())
+	assert.NoError(t, s.Close())
+}
+
+func TestStream_Fade(t *testing.T) {
+	tune, _ := newTestTune(0.5, 2*time.Second, time.Second)
+	s := NewStream(tune)
+
+	samples := make([][2]float64, 4*testSampleRate)
+	n, ok := s.Stream(samples)
+	require.True(t, ok)
+	assert.Equal(t, 3*testSampleRate, n)
+	assert.Equal(t, [2]float64{0.5, 0.5}, samples[2*testSampleRate-1])
+	assert.InDelta(t, 0.25, samples[2*testSampleRate+testSampleRate/2][0], 1e-9)
+	assert.InDelta(t, 0, samples[3*testSampleRate-1][0], 1e-3)
+
+	n, ok = s.Stream(samples)
+	assert.False(t, ok)
+	assert.Equal(t, 0, n)
+}
+
+func TestStream_Silence(t *testing.T) {
+	// Silent songs end once they were silent for long enough
+	tune, _ := newTestTune(0, time.Minute, time.Second)
+	s := NewStream(tune)
+
+	samples := make([][2]float64, 10*testSampleRate)
+	n, ok := s.Stream(samples)
+	require.True(t, ok)
+	assert.Equal(t, frames(silenceDuration, testSampleRate), n)
+
+	_, ok = s.Stream(samples)
+	assert.False(t, ok)
+}
+
+func TestStream_Seek(t *testing.T) {
+	tune, emulators := newTestTune(0.5, 10*time.Second, time.Second)
+	s := NewStream(tune)
+
+	require.NoError(t, s.Seek(5*testSampleRate))
+	assert.Equal(t, 5*testSampleRate, s.Position())
+	require.Len(t, *emulators, 1)
+	assert.Equal(t, 5*testSampleRate, (*emulators)[0].frames)
+
+	// Seeking backwards emulates the song again from the start
+	require.NoError(t, s.Seek(testSampleRate))
+	assert.Equal(t, testSampleRate, s.Position())
+	require.Len(t, *emulators, 2)
+	assert.Equal(t, testSampleRate, (*emulators)[1].frames)
+
+	require.NoError(t, s.Seek(s.Len()+10))
+	assert.Equal(t, s.Len(), s.Position())
+
+	require.NoError(t, s.Seek(-1))
+	assert.Equal(t, 0, s.Position())
+}
+
+func TestDCFilter(t *testing.T) {
+	f := &dcFilter{}
+	var v float64
+	for i := 0; i < 100000; i++ {
+		v = f.filter(1)
+	}
+
+	assert.InDelta(t, 0, v, 1e-3)
+}
+
+func TestText(t *testing.T) {
+	assert.Equal(t, "Title", text([]byte("Title\x00garbage")))
+	assert.Equal(t, "Title", text([]byte(" Title ")))
+	assert.Equal(t, "", text(make([]byte, 4)))
+}
+
+func TestTune_SelectSong(t *testing.T) {
+	var songs []int
+	tune := &Tune{
+		SampleRate: testSampleRate,
+		Length:     time.Second,
+		Songs:      3,
+		Song:       1,
+		newEmulator: func(song int) emulator {
+			songs = append(songs, song)
+			return &testEmulator{value: 0.5}
+		},
+	}
+
+	require.NoError(t, tune.SelectSong(3))
+	assert.Equal(t, 3, tune.Song)
+
+	// Seeking backwards starts the selected song over
+	s := NewStream(tune)
+	require.NoError(t, s.Seek(100))
+	require.NoError(t, s.Seek(0))
+	assert.Equal(t, []int{3, 3}, songs)
+
+	assert.Error(t, tune.SelectSong(0))
+	assert.Error(t, tune.SelectSong(4))
+	assert.Equal(t, 3, tune.Song)
+}
@@ -0,0 +1,392 @@
+package chip
+
+// registers of the S-DSP. Voice registers are at the voice times 0x10 plus their offset
+const (
+	dspVolumeLeft   = 0x00
+	dspVolumeRight  = 0x01
+	dspPitchLow     = 0x02
+	dspPitchHigh    = 0x03
+	dspSource       = 0x04
+	dspADSR1        = 0x05
+	dspADSR2        = 0x06
+	dspGain         = 0x07
+	dspEnvelopeOut  = 0x08
+	dspSampleOut    = 0x09
+	dspMainLeft     = 0x0C
+	dspMainRight    = 0x1C
+	dspEchoLeft     = 0x2C
+	dspEchoRight    = 0x3C
+	dspKeyOn        = 0x4C
+	dspKeyOff       = 0x5C
+	dspFlags        = 0x6C
+	dspEnded        = 0x7C
+	dspFeedback     = 0x0D
+	dspPitchMod     = 0x2D
+	dspNoiseOn      = 0x3D
+	dspEchoOn       = 0x4D
+	dspDirectory    = 0x5D
+	dspEchoStart    = 0x6D
+	dspEchoDelay    = 0x7D
+	dspFIR          = 0x0F
+	dspRegisters    = 0x80
+	dspVoices       = 8
+	dspFlagReset    = 0x80
+	dspFlagMute     = 0x40
+	dspFlagEchoOff  = 0x20
+	dspMaxEnvelope  = 0x7FF
+	dspBRRBlockSize = 9
+)
+
+// dspRatePeriods are the number of samples between steps of envelopes at each rate, where rate 0 never steps
+var dspRatePeriods = [32]int{
+	0, 2048, 1536, 1280, 1024, 768, 640, 512, 384, 320, 256, 192, 160, 128, 96, 80,
+	64, 48, 40, 32, 24, 20, 16, 12, 10, 8, 6, 5, 4, 3, 2, 1,
+}
+
+const (
+	dspAttack = iota
+	dspDecay
+	dspSustain
+	dspRelease
+)
+
+// dspVoice is one of the eight voices of the S-DSP, which plays BRR compressed samples from memory
+type dspVoice struct {
+	address  uint16
+	nibble   int
+	header   byte
+	previous [2]int
+	history  [2]int
+	counter  int
+
+	envelope    int
+	state       int
+	rateCounter int
+	output      int
+}
+
+// dsp emulates the S-DSP, the sound chip of the SNES, with linear instead of gaussian interpolation
+type dsp struct {
+	ram       *[0x10000]byte
+	registers [dspRegisters]byte
+	voices    [dspVoices]dspVoice
+
+	noise        int
+	noiseCounter int
+	echoOffset   int
+	echoHistory  [8][2]int
+	echoIndex    int
+}
+
+func newDSP(ram *[0x10000]byte) *dsp {
+	d := &dsp{ram: ram, noise: 0x4000}
+	for i := range d.voices {
+		d.voices[i].state = dspRelease
+	}
+
+	return d
+}
+
+func (d *dsp) read(reg byte) byte {
+	return d.registers[reg&(dspRegisters-1)]
+}
+
+func (d *dsp) write(reg byte, v byte) {
+	reg &= dspRegisters - 1
+	d.registers[reg] = v
+	switch reg {
+	case dspKeyOn:
+		for i := range d.voices {
+			if v&(1<<uint(i)) != 0 {
+				d.keyOn(i)
+			}
+		}
+	case dspEnded:
+		d.registers[dspEnded] = 0
+	}
+}
+
+func (d *dsp) voiceRegister(voice int, reg byte) byte {
+	return d.registers[byte(voice)<<4|reg]
+}
+
+// keyOn starts a voice at the start of its sample
+func (d *dsp) keyOn(voice int) {
+	v := &d.voices[voice]
+	entry := uint16(d.registers[dspDirectory])<<8 + uint16(d.voiceRegister(voice, dspSource))*4
+	v.address = uint16(d.ram[entry]) | uint16(d.ram[entry+1])<<8
+	v.header = d.ram[v.address]
+	v.nibble = 0
+	v.previous = [2]int{}
+	v.history = [2]int{}
+	v.counter = 0
+	v.envelope = 0
+	v.state = dspAttack
+	v.rateCounter = 0
+	d.registers[dspEnded] &^= 1 << uint(voice)
+}
+
+// decode decodes the next sample of a voice, moving to the next block at the end of one. Blocks can end the sample,
+// which loops back to the loop point in the directory or releases the voice
+func (d *dsp) decode(voice int) {
+	v := &d.voices[voice]
+	if v.nibble == 16 {
+		v.nibble = 0
+		if v.header&1 != 0 {
+			d.registers[dspEnded] |= 1 << uint(voice)
+			if v.header&2 == 0 {
+				v.state = dspRelease
+				v.envelope = 0
+			}
+
+			entry := uint16(d.registers[dspDirectory])<<8 + uint16(d.voiceRegister(voice, dspSource))*4
+			v.address = uint16(d.ram[entry+2]) | uint16(d.ram[entry+3])<<8
+		} else {
+			v.address += dspBRRBlockSize
+		}
+
+		v.header = d.ram[v.address]
+	}
+
+	data := d.ram[v.address+1+uint16(v.nibble/2)]
+	if v.nibble%2 == 0 {
+		data >>= 4
+	}
+
+	v.nibble++
+	s := int(int8(data<<4) >> 4)
+	shift := uint(v.header >> 4)
+	if shift <= 12 {
+		s = s << shift >> 1
+	} else if s < 0 {
+		s = -2048
+	} else {
+		s = 0
+	}
+
+	p1, p2 := v.previous[0], v.previous[1]
+	switch v.header >> 2 & 3 {
+	case 1:
+		s += p1 + (-p1 >> 4)
+	case 2:
+		s += p1*2 + (-p1*3)>>5 - p2 + p2>>4
+	case 3:
+		s += p1*2 + (-p1*13)>>6 - p2 + (p2*3)>>4
+	}
+
+	out := int(int16(clamp16(s) * 2))
+	v.previous = [2]int{out >> 1, p1}
+	v.history = [2]int{out, v.history[0]}
+}
+
+// runEnvelope steps the envelope of a voice if its rate is due
+func (d *dsp) runEnvelope(voice int) {
+	v := &d.voices[voice]
+	if v.state == dspRelease {
+		v.envelope -= 8
+		if v.envelope < 0 {
+			v.envelope = 0
+		}
+
+		return
+	}
+
+	adsr1, adsr2 := d.voiceRegister(voice, dspADSR1), d.voiceRegister(voice, dspADSR2)
+	envelope := v.envelope
+	var rate int
+	if adsr1&0x80 != 0 {
+		switch v.state {
+		case dspAttack:
+			rate = int(adsr1&0xF)*2 + 1
+			if rate < 31 {
+				envelope += 0x20
+			} else {
+				envelope += 0x400
+			}
+		case dspDecay:
+			rate = int(adsr1>>3&0xE) + 0x10
+			envelope--
+			envelope -= envelope >> 8
+		default:
+			rate = int(adsr2 & 0x1F)
+			envelope--
+			envelope -= envelope >> 8
+		}
+	} else {
+		gain := d.voiceRegister(voice, dspGain)
+		rate = int(gain & 0x1F)
+		switch gain >> 5 {
+		case 4:
+			envelope -= 0x20
+		case 5:
+			envelope--
+			envelope -= envelope >> 8
+		case 6:
+			envelope += 0x20
+		case 7:
+			if envelope < 0x600 {
+				envelope += 0x20
+			} else {
+				envelope += 0x8
+			}
+		default:
+			envelope = int(gain&0x7F) * 0x10
+			rate = 31
+		}
+	}
+
+	overflow := envelope > dspMaxEnvelope
+	if envelope < 0 {
+		envelope = 0
+	} else if overflow {
+		envelope = dspMaxEnvelope
+	}
+
+	if rate == 0 {
+		return
+	}
+
+	v.rateCounter++
+	if v.rateCounter < dspRatePeriods[rate] {
+		return
+	}
+
+	v.rateCounter = 0
+	v.envelope = envelope
+	if v.state == dspAttack && overflow {
+		v.state = dspDecay
+	} else if v.state == dspDecay && adsr1&0x80 != 0 && envelope>>8 == int(adsr2>>5) {
+		v.state = dspSustain
+	}
+}
+
+// sample runs the DSP for a sample and returns the output
+func (d *dsp) sample() (int, int) {
+	flags := d.registers[dspFlags]
+	if rate := int(flags & 0x1F); rate > 0 {
+		d.noiseCounter++
+		if d.noiseCounter >= dspRatePeriods[rate] {
+			d.noiseCounter = 0
+			feedback := (d.noise<<13 ^ d.noise<<14) & 0x4000
+			d.noise = feedback ^ d.noise>>1
+		}
+	}
+
+	var left, right, echoLeft, echoRight int
+	keyOff := d.registers[dspKeyOff]
+	for i := range d.voices {
+		v := &d.voices[i]
+		bit := byte(1) << uint(i)
+		if keyOff&bit != 0 || flags&dspFlagReset != 0 {
+			v.state = dspRelease
+		}
+
+		if flags&dspFlagReset != 0 {
+			v.envelope = 0
+		}
+
+		d.runEnvelope(i)
+		if v.state == dspRelease && v.envelope == 0 {
+			v.output = 0
+			d.registers[byte(i)<<4|dspEnvelopeOut] = 0
+			d.registers[byte(i)<<4|dspSampleOut] = 0
+			continue
+		}
+
+		pitch := int(d.voiceRegister(i, dspPitchLow)) | int(d.voiceRegister(i, dspPitchHigh)&0x3F)<<8
+		if i > 0 && d.registers[dspPitchMod]&bit != 0 {
+			pitch += (d.voices[i-1].output >> 5) * pitch >> 10
+		}
+
+		sample := v.history[1] + (v.history[0]-v.history[1])*v.counter>>12
+		if d.registers[dspNoiseOn]&bit != 0 {
+			sample = int(int16(d.noise * 2))
+		}
+
+		v.output = sample * v.envelope >> 11
+		v.counter += pitch
+		for v.counter >= 0x1000 {
+			v.counter -= 0x1000
+			d.decode(i)
+		}
+
+		d.registers[byte(i)<<4|dspEnvelopeOut] = byte(v.envelope >> 4)
+		d.registers[byte(i)<<4|dspSampleOut] = byte(v.output >> 8)
+
+		voiceLeft := v.output * int(int8(d.voiceRegister(i, dspVolumeLeft))) >> 7
+		voiceRight := v.output * int(int8(d.voiceRegister(i, dspVolumeRight))) >> 7
+		left += voiceLeft
+		right += voiceRight
+		if d.registers[dspEchoOn]&bit != 0 {
+			echoLeft += voiceLeft
+			echoRight += voiceRight
+		}
+	}
+
+	outLeft, outRight := d.echo(clamp16(echoLeft), clamp16(echoRight))
+	left = clamp16(left)*int(int8(d.registers[dspMainLeft]))>>7 + outLeft
+	right = clamp16(right)*int(int8(d.registers[dspMainRight]))>>7 + outRight
+	if flags&dspFlagMute != 0 {
+		return 0, 0
+	}
+
+	return clamp16(left), clamp16(right)
+}
+
+// echo runs the echo for a sample. The echo buffer is in memory, with the samples filtered by the FIR filter as they
+// come out of it and fed back into it with the voices that have echo on. It returns the output of the echo
+func (d *dsp) echo(inLeft, inRight int) (int, int) {
+	start := int(d.registers[dspEchoStart]) << 8
+	length := int(d.registers[dspEchoDelay]&0xF) << 11
+	if length == 0 {
+		length = 4
+	}
+
+	addr := uint16(start + d.echoOffset)
+	d.echoIndex = (d.echoIndex + 1) % len(d.echoHistory)
+	d.echoHistory[d.echoIndex] = [2]int{d.readEcho(addr), d.readEcho(addr + 2)}
+
+	var firLeft, firRight int
+	for tap := 0; tap < len(d.echoHistory); tap++ {
+		h := d.echoHistory[(d.echoIndex+1+tap)%len(d.echoHistory)]
+		coefficient := int(int8(d.registers[byte(tap)<<4|dspFIR]))
+		firLeft += h[0] * coefficient >> 6
+		firRight += h[1] * coefficient >> 6
+	}
+
+	firLeft, firRight = clamp16(firLeft), clamp16(firRight)
+	if d.registers[dspFlags]&dspFlagEchoOff == 0 {
+		feedback := int(int8(d.registers[dspFeedback]))
+		d.writeEcho(addr, clamp16(inLeft+firLeft*feedback>>7))
+		d.writeEcho(addr+2, clamp16(inRight+firRight*feedback>>7))
+	}
+
+	d.echoOffset += 4
+	if d.echoOffset >= length {
+		d.echoOffset = 0
+	}
+
+	return firLeft * int(int8(d.registers[dspEchoLeft])) >> 7, firRight * int(int8(d.registers[dspEchoRight])) >> 7
+}
+
+func (d *dsp) readEcho(addr uint16) int {
+	return int(int16(uint16(d.ram[addr])|uint16(d.ram[addr+1])<<8)) >> 1
+}
+
+func (d *dsp) writeEcho(addr uint16, v int) {
+	v &^= 1
+	d.ram[addr], d.ram[addr+1] = byte(v), byte(v>>8)
+}
+
+// clamp16 clamps v to a signed 16-bit sample
+func clamp16(v int) int {
+	if v < -0x8000 {
+		return -0x8000
+	}
+
+	if v > 0x7FFF {
+		return 0x7FFF
+	}
+
+	return v
+}
@@ -0,0 +1,144 @@
+package chip
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// newTestDSP creates a DSP with the directory at 0x300 and the first sample at 0x400, which has the given blocks
+func newTestDSP(blocks ...[dspBRRBlockSize]byte) *dsp {
+	ram := &[0x10000]byte{}
+	ram[0x300], ram[0x301], ram[0x302], ram[0x303] = 0x00, 0x04, 0x00, 0x04
+	for i, block := range blocks {
+		copy(ram[0x400+i*dspBRRBlockSize:], block[:])
+	}
+
+	d := newDSP(ram)
+	d.write(dspDirectory, 0x03)
+	d.write(dspPitchHigh, 0x10)
+	d.write(dspGain, 0x7F)
+	d.write(dspVolumeLeft, 0x7F)
+	d.write(dspVolumeRight, 0x7F)
+	d.write(dspMainLeft, 0x7F)
+	d.write(dspMainRight, 0x7F)
+	d.write(dspFlags, dspFlagEchoOff)
+	return d
+}
+
+func TestDSP_Decode(t *testing.T) {
+	testCases := []struct {
+		name     string
+		block    [dspBRRBlockSize]byte
+		expected []int
+	}{
+		{"Shift", [dspBRRBlockSize]byte{0xC0, 0x7F}, []int{0x7000, -0x1000, 0}},
+		{"NoShift", [dspBRRBlockSize]byte{0x00, 0x12}, []int{0, 2, 0}},
+		{"InvalidShift", [dspBRRBlockSize]byte{0xF0, 0x97}, []int{-0x1000, 0, 0}},
+		{"Filter", [dspBRRBlockSize]byte{0xC4, 0x40}, []int{0x4000, 0x3C00, 0x3840}},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			d := newTestDSP(testCase.block)
+			d.keyOn(0)
+			for _, expected := range testCase.expected {
+				d.decode(0)
+				assert.Equal(tt, expected, d.voices[0].history[0])
+			}
+		})
+	}
+}
+
+func TestDSP_Loop(t *testing.T) {
+	end := [dspBRRBlockSize]byte{0x01}
+	loop := [dspBRRBlockSize]byte{0x03}
+
+	// Samples that end without looping release their voice
+	d := newTestDSP(end)
+	d.write(dspKeyOn, 1)
+	for i := 0; i < 17; i++ {
+		d.decode(0)
+	}
+
+	assert.Equal(t, dspRelease, d.voices[0].state)
+	assert.Equal(t, byte(1), d.read(dspEnded))
+
+	d = newTestDSP(loop)
+	d.write(dspKeyOn, 1)
+	for i := 0; i < 17; i++ {
+		d.decode(0)
+	}
+
+	assert.Equal(t, dspAttack, d.voices[0].state)
+	assert.Equal(t, uint16(0x400), d.voices[0].address)
+
+	// Writes to ENDX clear it
+	d.write(dspEnded, 0xFF)
+	assert.Equal(t, byte(0), d.read(dspEnded))
+}
+
+func TestDSP_Envelope(t *testing.T) {
+	d := newTestDSP()
+	d.keyOn(0)
+
+	// Direct gain sets the envelope straight away
+	d.runEnvelope(0)
+	assert.Equal(t, 0x7F0, d.voices[0].envelope)
+
+	// The fastest attack rises by 0x400 every sample until it decays to the sustain level
+	d.write(dspADSR1, 0x8F)
+	d.write(dspADSR2, 0x80)
+	d.keyOn(0)
+	d.runEnvelope(0)
+	assert.Equal(t, 0x400, d.voices[0].envelope)
+	d.runEnvelope(0)
+	assert.Equal(t, dspMaxEnvelope, d.voices[0].envelope)
+	assert.Equal(t, dspDecay, d.voices[0].state)
+
+	for i := 0; i < 10000; i++ {
+		d.runEnvelope(0)
+	}
+
+	assert.Equal(t, dspSustain, d.voices[0].state)
+	assert.Equal(t, 4, d.voices[0].envelope>>8)
+
+	// Released voices fade out quickly
+	d.write(dspKeyOff, 1)
+	d.sample()
+	assert.Equal(t, dspRelease, d.voices[0].state)
+	for i := 0; i < 0x100; i++ {
+		d.sample()
+	}
+
+	assert.Equal(t, 0, d.voices[0].envelope)
+}
+
+func TestDSP_Sample(t *testing.T) {
+	d := newTestDSP([dspBRRBlockSize]byte{0xC3, 0x77, 0x77, 0x77, 0x77, 0x77, 0x77, 0x77, 0x77})
+	d.write(dspKeyOn, 1)
+
+	// The voice interpolates from silence, so the sample starts late
+	for i := 0; i < 2; i++ {
+		left, right := d.sample()
+		assert.Equal(t, 0, left)
+		assert.Equal(t, 0, right)
+	}
+
+	left, right := d.sample()
+	assert.True(t, left > 0x6000)
+	assert.Equal(t, left, right)
+	assert.Equal(t, byte(0x7F), d.read(dspEnvelopeOut))
+
+	// Muting silences the output but not the voices
+	d.write(dspFlags, dspFlagMute|dspFlagEchoOff)
+	left, right = d.sample()
+	assert.Equal(t, 0, left)
+	assert.Equal(t, 0, right)
+	assert.True(t, d.read(dspSampleOut) > 0)
+}
+
+func TestClamp16(t *testing.T) {
+	assert.Equal(t, 0x7FFF, clamp16(0x10000))
+	assert.Equal(t, -0x8000, clamp16(-0x10000))
+	assert.Equal(t, 42, clamp16(42))
+}
@@ -0,0 +1,494 @@
+package chip
+
+// memory is the address space of an emulated machine as seen by its CPU
+type memory interface {
+	read(addr uint16) byte
+	write(addr uint16, v byte)
+}
+
+const (
+	flagCarry    = 0x01
+	flagZero     = 0x02
+	flagIRQ      = 0x04
+	flagDecimal  = 0x08
+	flagBreak    = 0x10
+	flagUnused   = 0x20
+	flagOverflow = 0x40
+	flagNegative = 0x80
+)
+
+// addressing modes of the 6502
+const (
+	modeImplied = iota
+	modeAccumulator
+	modeImmediate
+	modeZeroPage
+	modeZeroPageX
+	modeZeroPageY
+	modeAbsolute
+	modeAbsoluteX
+	modeAbsoluteY
+	modeIndirect
+	modeIndirectX
+	modeIndirectY
+	modeRelative
+)
+
+// mos6502Modes are the addressing modes of every opcode, including the undocumented ones
+var mos6502Modes = [256]byte{
+	0, 10, 0, 10, 3, 3, 3, 3, 0, 2, 1, 2, 6, 6, 6, 6,
+	12, 11, 0, 11, 4, 4, 4, 4, 0, 8, 0, 8, 7, 7, 7, 7,
+	6, 10, 0, 10, 3, 3, 3, 3, 0, 2, 1, 2, 6, 6, 6, 6,
+	12, 11, 0, 11, 4, 4, 4, 4, 0, 8, 0, 8, 7, 7, 7, 7,
+	0, 10, 0, 10, 3, 3, 3, 3, 0, 2, 1, 2, 6, 6, 6, 6,
+	12, 11, 0, 11, 4, 4, 4, 4, 0, 8, 0, 8, 7, 7, 7, 7,
+	0, 10, 0, 10, 3, 3, 3, 3, 0, 2, 1, 2, 9, 6, 6, 6,
+	12, 11, 0, 11, 4, 4, 4, 4, 0, 8, 0, 8, 7, 7, 7, 7,
+	2, 10, 2, 10, 3, 3, 3, 3, 0, 2, 0, 2, 6, 6, 6, 6,
+	12, 11, 0, 11, 4, 4, 5, 5, 0, 8, 0, 8, 7, 7, 8, 8,
+	2, 10, 2, 10, 3, 3, 3, 3, 0, 2, 0, 2, 6, 6, 6, 6,
+	12, 11, 0, 11, 4, 4, 5, 5, 0, 8, 0, 8, 7, 7, 8, 8,
+	2, 10, 2, 10, 3, 3, 3, 3, 0, 2, 0, 2, 6, 6, 6, 6,
+	12, 11, 0, 11, 4, 4, 4, 4, 0, 8, 0, 8, 7, 7, 7, 7,
+	2, 10, 2, 10, 3, 3, 3, 3, 0, 2, 0, 2, 6, 6, 6, 6,
+	12, 11, 0, 11, 4, 4, 4, 4, 0, 8, 0, 8, 7, 7, 7, 7,
+}
+
+// mos6502Cycles are the cycles every opcode takes, not counting extra cycles for crossing pages or taking branches
+var mos6502Cycles = [256]byte{
+	7, 6, 2, 8, 3, 3, 5, 5, 3, 2, 2, 2, 4, 4, 6, 6,
+	2, 5, 2, 8, 4, 4, 6, 6, 2, 4, 2, 7, 4, 4, 7, 7,
+	6, 6, 2, 8, 3, 3, 5, 5, 4, 2, 2, 2, 4, 4, 6, 6,
+	2, 5, 2, 8, 4, 4, 6, 6, 2, 4, 2, 7, 4, 4, 7, 7,
+	6, 6, 2, 8, 3, 3, 5, 5, 3, 2, 2, 2, 3, 4, 6, 6,
+	2, 5, 2, 8, 4, 4, 6, 6, 2, 4, 2, 7, 4, 4, 7, 7,
+	6, 6, 2, 8, 3, 3, 5, 5, 4, 2, 2, 2, 5, 4, 6, 6,
+	2, 5, 2, 8, 4, 4, 6, 6, 2, 4, 2, 7, 4, 4, 7, 7,
+	2, 6, 2, 6, 3, 3, 3, 3, 2, 2, 2, 2, 4, 4, 4, 4,
+	2, 6, 2, 6, 4, 4, 4, 4, 2, 5, 2, 5, 5, 5, 5, 5,
+	2, 6, 2, 6, 3, 3, 3, 3, 2, 2, 2, 2, 4, 4, 4, 4,
+	2, 5, 2, 5, 4, 4, 4, 4, 2, 4, 2, 4, 4, 4, 4, 4,
+	2, 6, 2, 8, 3, 3, 5, 5, 2, 2, 2, 2, 4, 4, 6, 6,
+	2, 5, 2, 8, 4, 4, 6, 6, 2, 4, 2, 7, 4, 4, 7, 7,
+	2, 6, 2, 8, 3, 3, 5, 5, 2, 2, 2, 2, 4, 4, 6, 6,
+	2, 5, 2, 8, 4, 4, 6, 6, 2, 4, 2, 7, 4, 4, 7, 7,
+}
+
+// mos6502 emulates the 6502 family of CPUs, i.e. the 2A03 of the NES and the 6510 of the C64. Undocumented opcodes
+// which combine two documented ones are supported since some drivers use them. The others do nothing
+type mos6502 struct {
+	a, x, y, s, p byte
+	pc            uint16
+	mem           memory
+
+	// decimal is true if the CPU supports decimal mode, which the 2A03 doesn't
+	decimal bool
+}
+
+// reset resets the registers of the CPU
+func (c *mos6502) reset() {
+	c.a, c.x, c.y, c.s, c.p, c.pc = 0, 0, 0, 0xFF, flagUnused|flagIRQ, 0
+}
+
+// call calls the subroutine at addr and runs it until it returns to ret or maxCycles pass. It returns the cycles taken
+// and whether the subroutine returned
+func (c *mos6502) call(addr, ret uint16, maxCycles int) (int, bool) {
+	c.jsr(addr, ret)
+	return c.run(ret, maxCycles)
+}
+
+// jsr jumps to the subroutine at addr as if by a JSR which returns to ret
+func (c *mos6502) jsr(addr, ret uint16) {
+	c.push16(ret - 1)
+	c.pc = addr
+}
+
+// irq jumps to the interrupt handler at addr as if by an IRQ which returns to ret
+func (c *mos6502) irq(addr, ret uint16) {
+	c.push16(ret)
+	c.push(c.p&^flagBreak | flagUnused)
+	c.p |= flagIRQ
+	c.pc = addr
+}
+
+// run runs the CPU until it reaches ret or maxCycles pass
+func (c *mos6502) run(ret uint16, maxCycles int) (int, bool) {
+	cycles := 0
+	for cycles < maxCycles {
+		if c.pc == ret {
+			return cycles, true
+		}
+
+		cycles += c.step()
+	}
+
+	return cycles, false
+}
+
+func (c *mos6502) push(v byte) {
+	c.mem.write(0x100|uint16(c.s), v)
+	c.s--
+}
+
+func (c *mos6502) pull() byte {
+	c.s++
+	return c.mem.read(0x100 | uint16(c.s))
+}
+
+func (c *mos6502) push16(v uint16) {
+	c.push(byte(v >> 8))
+	c.push(byte(v))
+}
+
+func (c *mos6502) pull16() uint16 {
+	lo := uint16(c.pull())
+	return uint16(c.pull())<<8 | lo
+}
+
+func (c *mos6502) read16(addr uint16) uint16 {
+	return uint16(c.mem.read(addr)) | uint16(c.mem.read(addr+1))<<8
+}
+
+// read16Page reads a word without carrying into the high byte of the address, like indirect addressing does
+func (c *mos6502) read16Page(addr uint16) uint16 {
+	hi := addr&0xFF00 | uint16(byte(addr)+1)
+	return uint16(c.mem.read(addr)) | uint16(c.mem.read(hi))<<8
+}
+
+func (c *mos6502) setZN(v byte) {
+	c.p &^= flagZero | flagNegative
+	if v == 0 {
+		c.p |= flagZero
+	}
+
+	c.p |= v & flagNegative
+}
+
+func (c *mos6502) setFlag(flag byte, on bool) {
+	if on {
+		c.p |= flag
+	} else {
+		c.p &^= flag
+	}
+}
+
+// address returns the address of the operand of an instruction with mode and moves the program counter past it
+func (c *mos6502) address(mode byte) uint16 {
+	pc := c.pc
+	switch mode {
+	case modeImmediate:
+		c.pc++
+		return pc
+	case modeZeroPage:
+		c.pc++
+		return uint16(c.mem.read(pc))
+	case modeZeroPageX:
+		c.pc++
+		return uint16(c.mem.read(pc) + c.x)
+	case modeZeroPageY:
+		c.pc++
+		return uint16(c.mem.read(pc) + c.y)
+	case modeAbsolute:
+		c.pc += 2
+		return c.read16(pc)
+	case modeAbsoluteX:
+		c.pc += 2
+		return c.read16(pc) + uint16(c.x)
+	case modeAbsoluteY:
+		c.pc += 2
+		return c.read16(pc) + uint16(c.y)
+	case modeIndirect:
+		c.pc += 2
+		return c.read16Page(c.read16(pc))
+	case modeIndirectX:
+		c.pc++
+		return c.read16Page(uint16(c.mem.read(pc) + c.x))
+	case modeIndirectY:
+		c.pc++
+		return c.read16Page(uint16(c.mem.read(pc))) + uint16(c.y)
+	case modeRelative:
+		c.pc++
+		return c.pc + uint16(int8(c.mem.read(pc)))
+	default:
+		return 0
+	}
+}
+
+// step executes an instruction and returns the cycles it took
+func (c *mos6502) step() int {
+	op := c.mem.read(c.pc)
+	c.pc++
+	mode := mos6502Modes[op]
+	addr := c.address(mode)
+	cycles := int(mos6502Cycles[op])
+
+	switch op {
+	case 0x00: // BRK
+		c.push16(c.pc + 1)
+		c.push(c.p | flagBreak | flagUnused)
+		c.p |= flagIRQ
+		c.pc = c.read16(0xFFFE)
+	case 0x08: // PHP
+		c.push(c.p | flagBreak | flagUnused)
+	case 0x28: // PLP
+		c.p = c.pull()&^flagBreak | flagUnused
+	case 0x48: // PHA
+		c.push(c.a)
+	case 0x68: // PLA
+		c.a = c.pull()
+		c.setZN(c.a)
+	case 0x20: // JSR
+		c.push16(c.pc - 1)
+		c.pc = addr
+	case 0x40: // RTI
+		c.p = c.pull()&^flagBreak | flagUnused
+		c.pc = c.pull16()
+	case 0x60: // RTS
+		c.pc = c.pull16() + 1
+	case 0x4C, 0x6C: // JMP
+		c.pc = addr
+	case 0x24, 0x2C: // BIT
+		v := c.mem.read(addr)
+		c.setFlag(flagZero, c.a&v == 0)
+		c.p = c.p&^(flagNegative|flagOverflow) | v&(flagNegative|flagOverflow)
+	case 0x10, 0x30, 0x50, 0x70, 0x90, 0xB0, 0xD0, 0xF0: // branches
+		flags := [4]byte{flagNegative, flagOverflow, flagCarry, flagZero}
+		set := c.p&flags[op>>6] != 0
+		if set == (op&0x20 != 0) {
+			c.pc = addr
+			cycles++
+		}
+	case 0x18, 0x38, 0x58, 0x78, 0xB8, 0xD8, 0xF8: // flag instructions
+		flags := [8]byte{flagCarry, flagCarry, flagIRQ, flagIRQ, 0, flagOverflow, flagDecimal, flagDecimal}
+		c.setFlag(flags[op>>5], op&0x20 != 0 && op != 0xB8)
+	case 0x88: // DEY
+		c.y--
+		c.setZN(c.y)
+	case 0xC8: // INY
+		c.y++
+		c.setZN(c.y)
+	case 0xCA: // DEX
+		c.x--
+		c.setZN(c.x)
+	case 0xE8: // INX
+		c.x++
+		c.setZN(c.x)
+	case 0x8A: // TXA
+		c.a = c.x
+		c.setZN(c.a)
+	case 0x98: // TYA
+		c.a = c.y
+		c.setZN(c.a)
+	case 0x9A: // TXS
+		c.s = c.x
+	case 0xA8: // TAY
+		c.y = c.a
+		c.setZN(c.y)
+	case 0xAA: // TAX
+		c.x = c.a
+		c.setZN(c.x)
+	case 0xBA: // TSX
+		c.x = c.s
+		c.setZN(c.x)
+	case 0x84, 0x8C, 0x94: // STY
+		c.mem.write(addr, c.y)
+	case 0x86, 0x8E, 0x96: // STX
+		c.mem.write(addr, c.x)
+	case 0xA0, 0xA4, 0xAC, 0xB4, 0xBC: // LDY
+		c.y = c.mem.read(addr)
+		c.setZN(c.y)
+	case 0xA2, 0xA6, 0xAE, 0xB6, 0xBE: // LDX
+		c.x = c.mem.read(addr)
+		c.setZN(c.x)
+	case 0xC0, 0xC4, 0xCC: // CPY
+		c.compare(c.y, c.mem.read(addr))
+	case 0xE0, 0xE4, 0xEC: // CPX
+		c.compare(c.x, c.mem.read(addr))
+	default:
+		c.execute(op, mode, addr)
+	}
+
+	return cycles
+}
+
+// execute executes the instructions which follow the regular layout of opcodes, where the low two bits pick the group
+// and the high three bits the operation. Undocumented opcodes in group 3 do the operations of groups 1 and 2 together
+func (c *mos6502) execute(op, mode byte, addr uint16) {
+	operation := op >> 5
+	switch op & 3 {
+	case 1:
+		c.aluOperation(operation, mode, addr)
+	case 2:
+		if mode == modeImplied || mode == modeImmediate {
+			return
+		}
+
+		c.rmwOperation(operation, mode, addr)
+	case 3:
+		if mode == modeImmediate {
+			if op == 0xEB { // SBC
+				c.sbc(c.mem.read(addr))
+			}
+
+			return
+		}
+
+		switch operation {
+		case 4: // SAX
+			c.mem.write(addr, c.a&c.x)
+		case 5: // LAX
+			c.a = c.mem.read(addr)
+			c.x = c.a
+			c.setZN(c.a)
+		default:
+			c.rmwOperation(operation, mode, addr)
+			c.aluOperation(operation, mode, addr)
+		}
+	}
+}
+
+// aluOperation executes the operations of group 1, which use the accumulator
+func (c *mos6502) aluOperation(operation, mode byte, addr uint16) {
+	if operation == 4 { // STA
+		if mode != modeImmediate {
+			c.mem.write(addr, c.a)
+		}
+
+		return
+	}
+
+	v := c.mem.read(addr)
+	switch operation {
+	case 0: // ORA
+		c.a |= v
+		c.setZN(c.a)
+	case 1: // AND
+		c.a &= v
+		c.setZN(c.a)
+	case 2: // EOR
+		c.a ^= v
+		c.setZN(c.a)
+	case 3: // ADC
+		c.adc(v)
+	case 5: // LDA
+		c.a = v
+		c.setZN(c.a)
+	case 6: // CMP
+		c.compare(c.a, v)
+	case 7: // SBC
+		c.sbc(v)
+	}
+}
+
+// rmwOperation executes the operations of group 2, which read, modify, and write memory or the accumulator
+func (c *mos6502) rmwOperation(operation, mode byte, addr uint16) {
+	switch operation {
+	case 4: // STX
+		c.mem.write(addr, c.x)
+		return
+	case 5: // LDX
+		c.x = c.mem.read(addr)
+		c.setZN(c.x)
+		return
+	}
+
+	var v byte
+	if mode == modeAccumulator {
+		v = c.a
+	} else {
+		v = c.mem.read(addr)
+	}
+
+	carry := c.p & flagCarry
+	switch operation {
+	case 0: // ASL
+		c.setFlag(flagCarry, v&0x80 != 0)
+		v <<= 1
+	case 1: // ROL
+		c.setFlag(flagCarry, v&0x80 != 0)
+		v = v<<1 | carry
+	case 2: // LSR
+		c.setFlag(flagCarry, v&1 != 0)
+		v >>= 1
+	case 3: // ROR
+		c.setFlag(flagCarry, v&1 != 0)
+		v = v>>1 | carry<<7
+	case 6: // DEC
+		v--
+	case 7: // INC
+		v++
+	}
+
+	c.setZN(v)
+	if mode == modeAccumulator {
+		c.a = v
+	} else {
+		c.mem.write(addr, v)
+	}
+}
+
+func (c *mos6502) compare(register, v byte) {
+	c.setFlag(flagCarry, register >= v)
+	c.setZN(register - v)
+}
+
+func (c *mos6502) adc(v byte) {
+	carry := uint16(c.p & flagCarry)
+	if c.decimal && c.p&flagDecimal != 0 {
+		lo := uint16(c.a&0xF) + uint16(v&0xF) + carry
+		hi := uint16(c.a>>4) + uint16(v>>4)
+		if lo > 9 {
+			lo += 6
+			hi++
+		}
+
+		c.setFlag(flagOverflow, (c.a^v)&0x80 == 0 && (c.a^byte(hi<<4))&0x80 != 0)
+		if hi > 9 {
+			hi += 6
+		}
+
+		c.setFlag(flagCarry, hi > 0xF)
+		c.a = byte(hi<<4 | lo&0xF)
+		c.setZN(c.a)
+		return
+	}
+
+	sum := uint16(c.a) + uint16(v) + carry
+	c.setFlag(flagCarry, sum > 0xFF)
+	c.setFlag(flagOverflow, (c.a^v)&0x80 == 0 && (c.a^byte(sum))&0x80 != 0)
+	c.a = byte(sum)
+	c.setZN(c.a)
+}
+
+func (c *mos6502) sbc(v byte) {
+	if c.decimal && c.p&flagDecimal != 0 {
+		borrow := int(1 - c.p&flagCarry)
+		lo := int(c.a&0xF) - int(v&0xF) - borrow
+		hi := int(c.a>>4) - int(v>>4)
+		if lo < 0 {
+			lo += 10
+			hi--
+		}
+
+		if hi < 0 {
+			hi += 10
+		}
+
+		difference := int(c.a) - int(v) - borrow
+		c.setFlag(flagCarry, difference >= 0)
+		c.setFlag(flagOverflow, (c.a^v)&0x80 != 0 && (c.a^byte(difference))&0x80 != 0)
+		c.a = byte(hi<<4 | lo&0xF)
+		c.setZN(c.a)
+		return
+	}
+
+	c.decimalSafeADC(^v)
+}
+
+// decimalSafeADC adds in binary regardless of decimal mode, which SBC relies on by adding the complement
+func (c *mos6502) decimalSafeADC(v byte) {
+	decimal := c.decimal
+	c.decimal = false
+	c.adc(v)
+	c.decimal = decimal
+}
@@ -0,0 +1,121 @@
+package chip
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+const testReturnAddress = 0xFFF0
+
+// testMemory is flat RAM
+type testMemory [0x10000]byte
+
+func (m *testMemory) read(addr uint16) byte {
+	return m[addr]
+}
+
+func (m *testMemory) write(addr uint16, v byte) {
+	m[addr] = v
+}
+
+// runTestProgram runs a program loaded at 0x200 as a subroutine on a 6502
+func runTestProgram(program []byte, decimal bool) (*mos6502, *testMemory) {
+	mem := &testMemory{}
+	copy(mem[0x200:], program)
+	c := &mos6502{mem: mem, decimal: decimal}
+	c.reset()
+	c.call(0x200, testReturnAddress, 10000)
+	return c, mem
+}
+
+func TestMOS6502(t *testing.T) {
+	testCases := []struct {
+		name    string
+		program []byte
+		decimal bool
+		a, x, y byte
+		flags   byte
+	}{
+		{"LoadAndTransfer", []byte{0xA9, 0x80, 0xAA, 0xA8, 0x60}, false, 0x80, 0x80, 0x80, flagNegative},
+		{"AddWithCarry", []byte{0x18, 0xA9, 0x7F, 0x69, 0x01, 0x60}, false, 0x80, 0, 0, flagNegative | flagOverflow},
+		{"AddCarryOut", []byte{0x38, 0xA9, 0xFF, 0x69, 0x00, 0x60}, false, 0, 0, 0, flagZero | flagCarry},
+		{"Subtract", []byte{0x38, 0xA9, 0x05, 0xE9, 0x06, 0x60}, false, 0xFF, 0, 0, flagNegative},
+		{"DecimalAdd", []byte{0xF8, 0x18, 0xA9, 0x19, 0x69, 0x28, 0x60}, true, 0x47, 0, 0, flagDecimal},
+		{"DecimalAddCarry", []byte{0xF8, 0x18, 0xA9, 0x99, 0x69, 0x01, 0x60}, true, 0x00, 0, 0, flagDecimal | flagCarry | flagZero},
+		{"DecimalSubtract", []byte{0xF8, 0x38, 0xA9, 0x40, 0xE9, 0x01, 0x60}, true, 0x39, 0, 0, flagDecimal | flagCarry},
+		{"DecimalIgnored", []byte{0xF8, 0x18, 0xA9, 0x19, 0x69, 0x28, 0x60}, false, 0x41, 0, 0, flagDecimal},
+		{"Loop", []byte{0xA2, 0x05, 0xA0, 0x00, 0xC8, 0xCA, 0xD0, 0xFC, 0x60}, false, 0, 0, 5, flagZero},
+		{"Shifts", []byte{0xA9, 0x81, 0x0A, 0x6A, 0x60}, false, 0x81, 0, 0, flagNegative},
+		{"Compare", []byte{0xA9, 0x10, 0xC9, 0x10, 0x60}, false, 0x10, 0, 0, flagZero | flagCarry},
+		{"Stack", []byte{0xA9, 0x42, 0x48, 0xA9, 0x00, 0x68, 0x60}, false, 0x42, 0, 0, 0},
+		{"Subroutine", []byte{0x20, 0x06, 0x02, 0xE8, 0x60, 0x00, 0xA2, 0x07, 0x60}, false, 0, 8, 0, 0},
+		{"UndocumentedLAX", []byte{0xA9, 0x33, 0x85, 0x10, 0xA7, 0x10, 0x60}, false, 0x33, 0x33, 0, 0},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			c, _ := runTestProgram(testCase.program, testCase.decimal)
+			assert.Equal(tt, uint16(testReturnAddress), c.pc)
+			assert.Equal(tt, testCase.a, c.a)
+			assert.Equal(tt, testCase.x, c.x)
+			assert.Equal(tt, testCase.y, c.y)
+			assert.Equal(tt, testCase.flags, c.p&^(flagUnused|flagIRQ|flagBreak))
+		})
+	}
+}
+
+func TestMOS6502_Memory(t *testing.T) {
+	_, mem := runTestProgram([]byte{
+		0xA9, 0x10, 0x85, 0x20, // LDA #$10, STA $20
+		0xA9, 0x03, 0x85, 0x21, // LDA #$03, STA $21
+		0xA0, 0x02, 0xA9, 0x55, // LDY #$02, LDA #$55
+		0x91, 0x20, // STA ($20),Y
+		0xE6, 0x20, // INC $20
+		0xA9, 0x05, 0x8D, 0x00, 0x04, // LDA #$05, STA $0400
+		0xCF, 0x00, 0x04, // DCP $0400
+		0x60,
+	}, false)
+
+	assert.Equal(t, byte(0x55), mem[0x312])
+	assert.Equal(t, byte(0x11), mem[0x20])
+	assert.Equal(t, byte(0x04), mem[0x400])
+}
+
+func TestMOS6502_IndirectJump(t *testing.T) {
+	// Indirect jumps read the high byte of the address from the start of the page when the pointer is at its end
+	mem := &testMemory{}
+	copy(mem[0x200:], []byte{0x6C, 0xFF, 0x03})
+	mem[0x3FF], mem[0x300], mem[0x400] = 0x00, 0x05, 0x06
+	c := &mos6502{mem: mem}
+	c.reset()
+	c.pc = 0x200
+	assert.Equal(t, 5, c.step())
+	assert.Equal(t, uint16(0x0500), c.pc)
+}
+
+func TestMOS6502_Interrupt(t *testing.T) {
+	mem := &testMemory{}
+	copy(mem[0x200:], []byte{0xE6, 0x10, 0x40}) // INC $10, RTI
+	c := &mos6502{mem: mem}
+	c.reset()
+	c.p |= flagCarry
+	c.irq(0x200, testReturnAddress)
+
+	cycles, returned := c.run(testReturnAddress, 100)
+	assert.True(t, returned)
+	assert.Equal(t, 11, cycles)
+	assert.Equal(t, byte(1), mem[0x10])
+	assert.NotZero(t, c.p&flagCarry)
+	assert.Equal(t, byte(0xFF), c.s)
+}
+
+func TestMOS6502_MaxCycles(t *testing.T) {
+	mem := &testMemory{}
+	copy(mem[0x200:], []byte{0x4C, 0x00, 0x02}) // JMP $0200
+	c := &mos6502{mem: mem}
+	c.reset()
+
+	cycles, returned := c.call(0x200, testReturnAddress, 100)
+	assert.False(t, returned)
+	assert.Equal(t, 102, cycles)
+}
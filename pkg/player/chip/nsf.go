@@ -0,0 +1,266 @@
+package chip
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+const (
+	nsfSignature    = "NESM\x1a"
+	nsfHeaderLength = 0x80
+	nsfBankSize     = 0x1000
+
+	// nsfReturnAddress is where the init and play routines return to. Nothing is mapped there, so drivers never run
+	// code from it
+	nsfReturnAddress = 0x5FF0
+
+	// nsfSampleRate is the sample rate NSF files are rendered at
+	nsfSampleRate = 44100
+
+	// nsfLength is how long NSF songs play for. Most game music loops after a minute or two
+	nsfLength = 150 * time.Second
+
+	// nsfInitCycles is the longest the init routine can run for, since some decompress their data first
+	nsfInitCycles = 10000000
+)
+
+// nsfHeader is the header of an NSF file
+type nsfHeader struct {
+	songs        int
+	startSong    int
+	loadAddress  uint16
+	initAddress  uint16
+	playAddress  uint16
+	title        string
+	artist       string
+	ntscSpeed    int
+	palSpeed     int
+	banks        [8]byte
+	pal          bool
+	bankswitched bool
+}
+
+// LoadNSF loads an NSF file, which has the code and data of the sound driver of an NES game. Only the sound channels
+// of the 2A03 are emulated, so songs which use the extra channels of chips on the cartridge play without them
+func LoadNSF(data []byte) (*Tune, error) {
+	if len(data) < len(nsfSignature) || string(data[:len(nsfSignature)]) != nsfSignature {
+		return nil, ErrUnknownFormat
+	}
+
+	if len(data) <= nsfHeaderLength {
+		return nil, ErrTruncated
+	}
+
+	h := nsfHeader{
+		songs:       int(data[0x06]),
+		startSong:   int(data[0x07]),
+		loadAddress: binary.LittleEndian.Uint16(data[0x08:]),
+		initAddress: binary.LittleEndian.Uint16(data[0x0A:]),
+		playAddress: binary.LittleEndian.Uint16(data[0x0C:]),
+		title:       text(data[0x0E:0x2E]),
+		artist:      text(data[0x2E:0x4E]),
+		ntscSpeed:   int(binary.LittleEndian.Uint16(data[0x6E:])),
+		palSpeed:    int(binary.LittleEndian.Uint16(data[0x78:])),
+		pal:         data[0x7A]&0x3 == 0x1,
+	}
+
+	copy(h.banks[:], data[0x70:0x78])
+	for _, bank := range h.banks {
+		h.bankswitched = h.bankswitched || bank != 0
+	}
+
+	if h.songs < 1 {
+		h.songs = 1
+	}
+
+	if h.startSong < 1 || h.startSong > h.songs {
+		h.startSong = 1
+	}
+
+	if h.loadAddress < 0x8000 {
+		return nil, fmt.Errorf("unsupported load address %#04x", h.loadAddress)
+	}
+
+	rom := nsfROM(&h, data[nsfHeaderLength:])
+	return &Tune{
+		Title:      h.title,
+		Artist:     h.artist,
+		Format:     "nsf",
+		Songs:      h.songs,
+		Song:       h.startSong,
+		SampleRate: nsfSampleRate,
+		Length:     nsfLength,
+		Fade:       defaultFade,
+		newEmulator: func(song int) emulator {
+			return newNSFMachine(&h, rom, song)
+		},
+	}, nil
+}
+
+// nsfROM lays the program out in 4K banks. Programs which aren't bankswitched fill the 32K from the load address
+// onwards, the others start at the offset of the load address into the first bank
+func nsfROM(h *nsfHeader, program []byte) []byte {
+	if !h.bankswitched {
+		rom := make([]byte, 0x8000)
+		copy(rom[h.loadAddress-0x8000:], program)
+		return rom
+	}
+
+	padding := int(h.loadAddress & (nsfBankSize - 1))
+	rom := make([]byte, padding+len(program))
+	copy(rom[padding:], program)
+	return rom
+}
+
+// nsfMachine is an NES which only has the RAM, the cartridge, and the APU. It runs the init routine once and then
+// the play routine at the speed in the header
+type nsfMachine struct {
+	cpu   mos6502
+	apu   *apu
+	ram   [0x800]byte
+	wram  [0x2000]byte
+	rom   []byte
+	banks [8]int
+
+	// playPeriod is the number of cycles between calls to the play routine. untilPlay counts down to the next call,
+	// which happens once the previous one returned
+	playAddress     uint16
+	playPeriod      float64
+	untilPlay       float64
+	playing         bool
+	cyclesPerSample float64
+	sampleCycles    float64
+	filter          dcFilter
+}
+
+func newNSFMachine(h *nsfHeader, rom []byte, song int) *nsfMachine {
+	r, speed := ntsc, h.ntscSpeed
+	if h.pal {
+		r, speed = pal, h.palSpeed
+	}
+
+	period := float64(r.defaultPeriod)
+	if speed > 0 {
+		period = float64(speed) * r.clock / 1e6
+	}
+
+	m := &nsfMachine{
+		rom:             rom,
+		playAddress:     h.playAddress,
+		playPeriod:      period,
+		cyclesPerSample: r.clock / nsfSampleRate,
+	}
+
+	m.apu = newAPU(r, m)
+	m.cpu.mem = m
+	m.cpu.reset()
+	for i := range m.banks {
+		m.banks[i] = i
+		if h.bankswitched {
+			m.banks[i] = int(h.banks[i])
+		}
+	}
+
+	for addr := uint16(0x4000); addr < 0x4014; addr++ {
+		m.write(addr, 0)
+	}
+
+	m.write(0x4015, 0x0F)
+	m.write(0x4017, 0x40)
+
+	// The triangle holds its level while stopped, which the DC filter starts at so songs don't start with a pop
+	m.filter.input = m.apu.output()
+	m.cpu.a = byte(song - 1)
+	if h.pal {
+		m.cpu.x = 1
+	}
+
+	m.cpu.call(h.initAddress, nsfReturnAddress, nsfInitCycles)
+	return m
+}
+
+func (m *nsfMachine) read(addr uint16) byte {
+	switch {
+	case addr < 0x2000:
+		return m.ram[addr&0x7FF]
+	case addr == 0x4015:
+		return m.apu.status()
+	case addr >= 0x6000 && addr < 0x8000:
+		return m.wram[addr-0x6000]
+	case addr >= 0x8000:
+		offset := m.banks[(addr-0x8000)/nsfBankSize]*nsfBankSize + int(addr&(nsfBankSize-1))
+		if offset < len(m.rom) {
+			return m.rom[offset]
+		}
+	}
+
+	return 0
+}
+
+func (m *nsfMachine) write(addr uint16, v byte) {
+	switch {
+	case addr < 0x2000:
+		m.ram[addr&0x7FF] = v
+	case addr >= 0x4000 && addr < 0x4018:
+		m.apu.write(addr, v)
+	case addr >= 0x5FF8 && addr < 0x6000:
+		m.banks[addr-0x5FF8] = int(v)
+	case addr >= 0x6000 && addr < 0x8000:
+		m.wram[addr-0x6000] = v
+	}
+}
+
+// render runs the machine for the cycles of each sample and averages the output of the APU over them
+func (m *nsfMachine) render(samples [][2]float64) {
+	for i := range samples {
+		m.sampleCycles += m.cyclesPerSample
+		var sum float64
+		cycles := 0
+		for float64(cycles) < m.sampleCycles {
+			c := m.run(int(m.sampleCycles+1) - cycles)
+			m.apu.clock(c)
+			sum += m.apu.output() * float64(c)
+			cycles += c
+		}
+
+		m.sampleCycles -= float64(cycles)
+		v := m.filter.filter(sum / float64(cycles))
+		samples[i] = [2]float64{v, v}
+	}
+}
+
+// run runs the CPU for an instruction of the play routine, or idles for up to budget cycles between calls to it. It
+// returns the cycles that passed
+func (m *nsfMachine) run(budget int) int {
+	if m.untilPlay <= 0 && !m.playing {
+		// Play routines which ran for longer than the period don't make the next calls catch up
+		if m.untilPlay < -m.playPeriod {
+			m.untilPlay = 0
+		}
+
+		m.untilPlay += m.playPeriod
+		m.playing = true
+		m.cpu.s = 0xFD
+		m.cpu.jsr(m.playAddress, nsfReturnAddress)
+	}
+
+	if m.playing {
+		c := m.cpu.step()
+		m.playing = m.cpu.pc != nsfReturnAddress
+		m.untilPlay -= float64(c)
+		return c
+	}
+
+	c := budget
+	if until := int(m.untilPlay + 1); c > until {
+		c = until
+	}
+
+	if c < 1 {
+		c = 1
+	}
+
+	m.untilPlay -= float64(c)
+	return c
+}
@@ -0,0 +1,136 @@
+package chip
+
+import (
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+const (
+	testTitle  = "Test Song"
+	testArtist = "Test Artist"
+
+	testNSFPlayAddress = 0x8040
+)
+
+// testNSFInit plays a 440Hz square wave on the first pulse channel at full volume
+var testNSFInit = []byte{
+	0xA9, 0xBF, 0x8D, 0x00, 0x40, // LDA #$BF, STA $4000
+	0xA9, 0xFD, 0x8D, 0x02, 0x40, // LDA #$FD, STA $4002
+	0xA9, 0x00, 0x8D, 0x03, 0x40, // LDA #$00, STA $4003
+	0x60, // RTS
+}
+
+// newTestNSF creates an NSF with three songs, starting at the second, which runs init from 0x8000 and has a play
+// routine that returns straight away
+func newTestNSF(init []byte) []byte {
+	data := make([]byte, nsfHeaderLength+0x100)
+	copy(data, nsfSignature)
+	data[0x05], data[0x06], data[0x07] = 1, 3, 2
+	binary.LittleEndian.PutUint16(data[0x08:], 0x8000)
+	binary.LittleEndian.PutUint16(data[0x0A:], 0x8000)
+	binary.LittleEndian.PutUint16(data[0x0C:], testNSFPlayAddress)
+	copy(data[0x0E:], testTitle)
+	copy(data[0x2E:], testArtist)
+	binary.LittleEndian.PutUint16(data[0x6E:], 16639)
+
+	program := data[nsfHeaderLength:]
+	copy(program, init)
+	program[testNSFPlayAddress-0x8000] = 0x60
+	return data
+}
+
+func TestLoadNSF(t *testing.T) {
+	tune, err := LoadNSF(newTestNSF(testNSFInit))
+	require.NoError(t, err)
+	assert.Equal(t, testTitle, tune.Title)
+	assert.Equal(t, testArtist, tune.Artist)
+	assert.Equal(t, "nsf", tune.Format)
+	assert.Equal(t, 3, tune.Songs)
+	assert.Equal(t, 2, tune.Song)
+	assert.Equal(t, nsfSampleRate, tune.SampleRate)
+	assert.Equal(t, nsfLength, tune.Length)
+	assert.Equal(t, defaultFade, tune.Fade)
+}
+
+func TestLoadNSF_Invalid(t *testing.T) {
+	lowLoadAddress := newTestNSF(testNSFInit)
+	binary.LittleEndian.PutUint16(lowLoadAddress[0x08:], 0x6000)
+
+	testCases := []struct {
+		name string
+		data []byte
+	}{
+		{"Empty", []byte{}},
+		{"NoSignature", make([]byte, nsfHeaderLength+1)},
+		{"TruncatedHeader", newTestNSF(testNSFInit)[:nsfHeaderLength]},
+		{"LowLoadAddress", lowLoadAddress},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			tune, err := LoadNSF(testCase.data)
+			assert.Error(tt, err)
+			assert.Nil(tt, tune)
+		})
+	}
+}
+
+func TestLoadNSF_Play(t *testing.T) {
+	tune, err := LoadNSF(newTestNSF(testNSFInit))
+	require.NoError(t, err)
+
+	s := NewStream(tune)
+	samples := make([][2]float64, nsfSampleRate)
+	n, ok := s.Stream(samples)
+	require.True(t, ok)
+	require.Equal(t, len(samples), n)
+
+	// The pulse crosses zero twice per cycle
+	assert.InDelta(t, 2*440, zeroCrossings(samples), 10)
+}
+
+func TestLoadNSF_Silent(t *testing.T) {
+	// Songs which never make a sound end once they were silent for long enough
+	tune, err := LoadNSF(newTestNSF([]byte{0x60}))
+	require.NoError(t, err)
+
+	s := NewStream(tune)
+	samples := make([][2]float64, 5*nsfSampleRate)
+	n, ok := s.Stream(samples)
+	require.True(t, ok)
+	assert.Equal(t, frames(silenceDuration, nsfSampleRate), n)
+}
+
+func TestNSFROM(t *testing.T) {
+	program := []byte{1, 2, 3}
+
+	rom := nsfROM(&nsfHeader{loadAddress: 0x8100}, program)
+	assert.Len(t, rom, 0x8000)
+	assert.Equal(t, program, rom[0x100:0x103])
+
+	rom = nsfROM(&nsfHeader{loadAddress: 0x8100, bankswitched: true}, program)
+	assert.Equal(t, append(make([]byte, 0x100), program...), rom)
+}
+
+func TestNSFMachine_Bankswitching(t *testing.T) {
+	rom := make([]byte, 3*nsfBankSize)
+	rom[nsfBankSize], rom[2*nsfBankSize] = 1, 2
+
+	m := &nsfMachine{rom: rom}
+	m.apu = newAPU(ntsc, m)
+	assert.Equal(t, byte(0), m.read(0x8000))
+
+	m.write(0x5FF8, 2)
+	m.write(0x5FFF, 1)
+	assert.Equal(t, byte(2), m.read(0x8000))
+	assert.Equal(t, byte(1), m.read(0xF000))
+
+	// Banks past the end of the ROM are empty
+	m.write(0x5FF8, 10)
+	assert.Equal(t, byte(0), m.read(0x8000))
+
+	m.write(0x0801, 7)
+	assert.Equal(t, byte(7), m.read(0x0001))
+}
@@ -0,0 +1,293 @@
+package chip
+
+import (
+	"encoding/binary"
+	"time"
+)
+
+const (
+	psidSignature = "PSID"
+	rsidSignature = "RSID"
+
+	sidHeaderLength   = 0x76
+	sidHeaderLengthV2 = 0x7C
+
+	// sidReturnAddress is where the init and play routines return to. It's in the zero page, where drivers don't run
+	// code from
+	sidReturnAddress = 0x0002
+
+	// sidSampleRate is the sample rate SID files are rendered at
+	sidSampleRate = 44100
+
+	// sidLength is how long SID songs play for, which is the default length of the songs in the HVSC
+	sidLength = 3 * time.Minute
+
+	// sidInitCycles is the longest the init routine can run for. The init routines of RSID files often never return
+	sidInitCycles = 2000000
+
+	sidPALClock  = 985248
+	sidNTSCClock = 1022727
+
+	// sidPALFrameCycles and sidNTSCFrameCycles are the cycles in a frame of the screen, which the play routine of
+	// most songs is called once per
+	sidPALFrameCycles  = 312 * 63
+	sidNTSCFrameCycles = 263 * 65
+
+	// sidKernalIRQ is the entry point of the interrupt handler of the KERNAL. The handler calls the vector at
+	// sidIRQVector, which jumps back to sidKernalReturn once done
+	sidKernalIRQ    = 0xFF48
+	sidKernalReturn = 0xEA31
+	sidKernalExit   = 0xEA81
+	sidIRQVector    = 0x0314
+)
+
+// sidHeader is the header of a PSID or RSID file
+type sidHeader struct {
+	rsid        bool
+	songs       int
+	startSong   int
+	loadAddress uint16
+	initAddress uint16
+	playAddress uint16
+	speed       uint32
+	title       string
+	artist      string
+	ntsc        bool
+	program     []byte
+}
+
+// LoadSID loads a PSID or RSID file, which has the code and data of a C64 program that plays music on the SID. The
+// KERNAL isn't emulated, except for the interrupt handler so that songs can install their own, so only programs that
+// make sound from the init and play routines or the interrupts of the screen and timer play
+func LoadSID(data []byte) (*Tune, error) {
+	if len(data) < 4 || string(data[:4]) != psidSignature && string(data[:4]) != rsidSignature {
+		return nil, ErrUnknownFormat
+	}
+
+	if len(data) < sidHeaderLength {
+		return nil, ErrTruncated
+	}
+
+	offset := int(binary.BigEndian.Uint16(data[0x06:]))
+	h := sidHeader{
+		rsid:        string(data[:4]) == rsidSignature,
+		loadAddress: binary.BigEndian.Uint16(data[0x08:]),
+		initAddress: binary.BigEndian.Uint16(data[0x0A:]),
+		playAddress: binary.BigEndian.Uint16(data[0x0C:]),
+		songs:       int(binary.BigEndian.Uint16(data[0x0E:])),
+		startSong:   int(binary.BigEndian.Uint16(data[0x10:])),
+		speed:       binary.BigEndian.Uint32(data[0x12:]),
+		title:       text(data[0x16:0x36]),
+		artist:      text(data[0x36:0x56]),
+	}
+
+	if offset >= sidHeaderLengthV2 && len(data) >= sidHeaderLengthV2 {
+		h.ntsc = binary.BigEndian.Uint16(data[0x76:])>>2&3 == 2
+	}
+
+	if offset < sidHeaderLength || offset > len(data) {
+		return nil, ErrTruncated
+	}
+
+	h.program = data[offset:]
+	if h.loadAddress == 0 {
+		if len(h.program) < 2 {
+			return nil, ErrTruncated
+		}
+
+		h.loadAddress = binary.LittleEndian.Uint16(h.program)
+		h.program = h.program[2:]
+	}
+
+	if h.initAddress == 0 {
+		h.initAddress = h.loadAddress
+	}
+
+	if h.songs < 1 {
+		h.songs = 1
+	}
+
+	if h.startSong < 1 || h.startSong > h.songs {
+		h.startSong = 1
+	}
+
+	return &Tune{
+		Title:      h.title,
+		Artist:     h.artist,
+		Format:     "sid",
+		Songs:      h.songs,
+		Song:       h.startSong,
+		SampleRate: sidSampleRate,
+		Length:     sidLength,
+		Fade:       defaultFade,
+		newEmulator: func(song int) emulator {
+			return newSIDMachine(&h, song)
+		},
+	}, nil
+}
+
+// sidMachine is a C64 with its RAM, the SID, the raster counter of the screen, and the timer latch of the first CIA.
+// The play routine, or the interrupt handler when there isn't one, is called once per frame or at the rate of the
+// timer
+type sidMachine struct {
+	cpu        mos6502
+	sid        *sidChip
+	ram        [0x10000]byte
+	cycles     int
+	timerLatch uint16
+	cia        bool
+
+	playAddress     uint16
+	frameCycles     int
+	untilPlay       int
+	cyclesPerSample float64
+	sampleCycles    float64
+	filter          dcFilter
+}
+
+// sidKernal is the part of the KERNAL that interrupts go through, as 6502 code at the address it's at
+var sidKernal = map[uint16][]byte{
+	// PHA, TXA, PHA, TYA, PHA, JMP (sidIRQVector)
+	sidKernalIRQ: {0x48, 0x8A, 0x48, 0x98, 0x48, 0x6C, sidIRQVector & 0xFF, sidIRQVector >> 8},
+	// JMP sidKernalExit
+	sidKernalReturn: {0x4C, sidKernalExit & 0xFF, sidKernalExit >> 8},
+	// PLA, TAY, PLA, TAX, PLA, RTI
+	sidKernalExit: {0x68, 0xA8, 0x68, 0xAA, 0x68, 0x40},
+}
+
+func newSIDMachine(h *sidHeader, song int) *sidMachine {
+	clock, frameCycles := float64(sidPALClock), sidPALFrameCycles
+	if h.ntsc {
+		clock, frameCycles = sidNTSCClock, sidNTSCFrameCycles
+	}
+
+	m := &sidMachine{
+		sid:             newSIDChip(sidSampleRate),
+		playAddress:     h.playAddress,
+		frameCycles:     frameCycles,
+		cyclesPerSample: clock / sidSampleRate,
+	}
+
+	m.cpu.mem = m
+	m.cpu.decimal = true
+	m.cpu.reset()
+	for addr, code := range sidKernal {
+		copy(m.ram[addr:], code)
+	}
+
+	m.ram[sidIRQVector], m.ram[sidIRQVector+1] = sidKernalReturn&0xFF, sidKernalReturn>>8
+	m.ram[0xFFFE], m.ram[0xFFFF] = sidKernalIRQ&0xFF, sidKernalIRQ>>8
+	copy(m.ram[h.loadAddress:], h.program)
+
+	// The memory configuration has the KERNAL and I/O unless the song loads over them
+	switch {
+	case h.initAddress < 0xA000:
+		m.ram[1] = 0x37
+	case h.initAddress < 0xD000:
+		m.ram[1] = 0x36
+	default:
+		m.ram[1] = 0x35
+	}
+
+	// Songs with CIA speed are played at the rate of the timer, which is 60Hz unless the init routine sets it
+	song--
+	m.cia = h.rsid || song < 32 && h.speed&(1<<uint(song)) != 0
+	if m.cia {
+		m.timerLatch = uint16(clock / 60)
+	}
+
+	m.cpu.a = byte(song)
+	m.cpu.jsr(h.initAddress, sidReturnAddress)
+	m.run(sidInitCycles)
+	return m
+}
+
+// io returns whether the I/O area is mapped in by the memory configuration
+func (m *sidMachine) io() bool {
+	return m.ram[1]&3 != 0 && m.ram[1]&4 != 0
+}
+
+func (m *sidMachine) read(addr uint16) byte {
+	if addr >= 0xD000 && addr < 0xE000 && m.io() {
+		switch {
+		case addr >= 0xD400 && addr < 0xD800:
+			return m.sid.read(byte(addr))
+		case addr == 0xD011:
+			return byte(m.raster()>>1) & 0x80
+		case addr == 0xD012:
+			return byte(m.raster())
+		}
+	}
+
+	return m.ram[addr]
+}
+
+func (m *sidMachine) write(addr uint16, v byte) {
+	if addr >= 0xD000 && addr < 0xE000 && m.io() {
+		switch {
+		case addr >= 0xD400 && addr < 0xD800:
+			m.sid.write(byte(addr), v)
+		case addr == 0xDC04:
+			m.timerLatch = m.timerLatch&0xFF00 | uint16(v)
+		case addr == 0xDC05:
+			m.timerLatch = m.timerLatch&0xFF | uint16(v)<<8
+		}
+	}
+
+	m.ram[addr] = v
+}
+
+// raster returns the line of the screen being drawn, which drivers wait on for timing
+func (m *sidMachine) raster() int {
+	return m.cycles / 63 % 312
+}
+
+// playCycles returns the cycles between calls to the play routine
+func (m *sidMachine) playCycles() int {
+	if m.cia && m.timerLatch > 0 {
+		return int(m.timerLatch)
+	}
+
+	return m.frameCycles
+}
+
+// play calls the play routine, or the interrupt handler the init routine installed when there isn't one
+func (m *sidMachine) play() {
+	m.cpu.s = 0xFF
+	switch {
+	case m.playAddress != 0:
+		m.cpu.jsr(m.playAddress, sidReturnAddress)
+	case m.ram[1]&2 != 0:
+		m.cpu.irq(sidKernalIRQ, sidReturnAddress)
+	default:
+		m.cpu.irq(m.cpu.read16(0xFFFE), sidReturnAddress)
+	}
+
+	m.run(m.playCycles())
+}
+
+// run runs the CPU until it returns to sidReturnAddress or budget cycles pass, counting the cycles for the raster
+func (m *sidMachine) run(budget int) {
+	for end := m.cycles + budget; m.cycles < end && m.cpu.pc != sidReturnAddress; {
+		m.cycles += m.cpu.step()
+	}
+}
+
+// render runs the SID for the cycles of each sample, calling the play routine whenever it's due
+func (m *sidMachine) render(samples [][2]float64) {
+	for i := range samples {
+		if m.untilPlay <= 0 {
+			m.play()
+			m.untilPlay += m.playCycles()
+		}
+
+		m.sampleCycles += m.cyclesPerSample
+		cycles := int(m.sampleCycles)
+		m.sampleCycles -= float64(cycles)
+		m.untilPlay -= cycles
+		m.cycles += cycles
+
+		v := m.filter.filter(m.sid.clock(cycles))
+		samples[i] = [2]float64{v, v}
+	}
+}
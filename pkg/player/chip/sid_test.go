@@ -0,0 +1,141 @@
+package chip
+
+import (
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+const (
+	testSIDLoadAddress = 0x1000
+	testSIDPlayAddress = 0x1080
+)
+
+// testSIDInit plays a 440Hz sawtooth on the first voice at full volume
+var testSIDInit = []byte{
+	0xA9, 0x45, 0x8D, 0x00, 0xD4, // LDA #$45, STA $D400
+	0xA9, 0x1D, 0x8D, 0x01, 0xD4, // LDA #$1D, STA $D401
+	0xA9, 0x00, 0x8D, 0x05, 0xD4, // LDA #$00, STA $D405
+	0xA9, 0xF0, 0x8D, 0x06, 0xD4, // LDA #$F0, STA $D406
+	0xA9, 0x0F, 0x8D, 0x18, 0xD4, // LDA #$0F, STA $D418
+	0xA9, 0x21, 0x8D, 0x04, 0xD4, // LDA #$21, STA $D404
+	0x60, // RTS
+}
+
+// newTestSID creates a PSID v2 with two songs, starting at the first, which loads at 0x1000 with the load address in
+// the data and runs init from there. The play routine at 0x1080 increments a counter at 0x10FF
+func newTestSID(init []byte, playAddress uint16) []byte {
+	data := make([]byte, sidHeaderLengthV2+2+0x100)
+	copy(data, psidSignature)
+	binary.BigEndian.PutUint16(data[0x04:], 2)
+	binary.BigEndian.PutUint16(data[0x06:], sidHeaderLengthV2)
+	binary.BigEndian.PutUint16(data[0x0A:], testSIDLoadAddress)
+	binary.BigEndian.PutUint16(data[0x0C:], playAddress)
+	binary.BigEndian.PutUint16(data[0x0E:], 2)
+	binary.BigEndian.PutUint16(data[0x10:], 1)
+	copy(data[0x16:], testTitle)
+	copy(data[0x36:], testArtist)
+	binary.BigEndian.PutUint16(data[0x76:], 1<<2)
+
+	binary.LittleEndian.PutUint16(data[sidHeaderLengthV2:], testSIDLoadAddress)
+	program := data[sidHeaderLengthV2+2:]
+	copy(program, init)
+	copy(program[testSIDPlayAddress-testSIDLoadAddress:], []byte{0xEE, 0xFF, 0x10, 0x60}) // INC $10FF, RTS
+	return data
+}
+
+func TestLoadSID(t *testing.T) {
+	tune, err := LoadSID(newTestSID(testSIDInit, testSIDPlayAddress))
+	require.NoError(t, err)
+	assert.Equal(t, testTitle, tune.Title)
+	assert.Equal(t, testArtist, tune.Artist)
+	assert.Equal(t, "sid", tune.Format)
+	assert.Equal(t, 2, tune.Songs)
+	assert.Equal(t, 1, tune.Song)
+	assert.Equal(t, sidSampleRate, tune.SampleRate)
+	assert.Equal(t, sidLength, tune.Length)
+}
+
+func TestLoadSID_Invalid(t *testing.T) {
+	valid := newTestSID(testSIDInit, testSIDPlayAddress)
+	badOffset := newTestSID(testSIDInit, testSIDPlayAddress)
+	binary.BigEndian.PutUint16(badOffset[0x06:], 0x1000)
+
+	testCases := []struct {
+		name string
+		data []byte
+	}{
+		{"Empty", []byte{}},
+		{"NoSignature", make([]byte, sidHeaderLengthV2)},
+		{"TruncatedHeader", valid[:sidHeaderLength-1]},
+		{"BadOffset", badOffset},
+		{"NoLoadAddress", valid[:sidHeaderLengthV2+1]},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			tune, err := LoadSID(testCase.data)
+			assert.Error(tt, err)
+			assert.Nil(tt, tune)
+		})
+	}
+}
+
+func TestLoadSID_Play(t *testing.T) {
+	tune, err := LoadSID(newTestSID(testSIDInit, testSIDPlayAddress))
+	require.NoError(t, err)
+
+	m := tune.newEmulator(tune.Song).(*sidMachine)
+	samples := make([][2]float64, sidSampleRate)
+	m.render(samples)
+
+	// The sawtooth crosses zero twice per cycle, and the play routine runs once per frame
+	assert.InDelta(t, 2*440, zeroCrossings(samples), 10)
+	assert.InDelta(t, 50, int(m.ram[0x10FF]), 1)
+}
+
+func TestLoadSID_Interrupt(t *testing.T) {
+	// Songs without a play routine install an interrupt handler, which the KERNAL calls and returns from
+	init := []byte{
+		0xA9, 0x80, 0x8D, 0x14, 0x03, // LDA #$80, STA $0314
+		0xA9, 0x10, 0x8D, 0x15, 0x03, // LDA #$10, STA $0315
+		0x60, // RTS
+	}
+
+	handler := []byte{0xEE, 0xFF, 0x10, 0x4C, sidKernalReturn & 0xFF, sidKernalReturn >> 8} // INC $10FF, JMP $EA31
+	data := newTestSID(init, 0)
+	copy(data[sidHeaderLengthV2+2+testSIDPlayAddress-testSIDLoadAddress:], handler)
+
+	tune, err := LoadSID(data)
+	require.NoError(t, err)
+
+	m := tune.newEmulator(tune.Song).(*sidMachine)
+	m.render(make([][2]float64, sidSampleRate))
+	assert.InDelta(t, 50, int(m.ram[0x10FF]), 1)
+	assert.Equal(t, byte(0xFF), m.cpu.s)
+}
+
+func TestSIDMachine_Memory(t *testing.T) {
+	m := newSIDMachine(&sidHeader{initAddress: 0x1000, program: []byte{0x60}, loadAddress: 0x1000}, 1)
+
+	// The SID is only mapped in with the I/O area
+	m.write(0xD418, 0x0F)
+	assert.Equal(t, byte(0x0F), m.sid.volume)
+
+	m.ram[1] = 0x34
+	m.write(0xD418, 0x07)
+	assert.Equal(t, byte(0x0F), m.sid.volume)
+	assert.Equal(t, byte(0x07), m.read(0xD418))
+
+	// Songs set the rate of CIA speed songs through the timer
+	m.ram[1] = 0x37
+	m.cia = true
+	m.write(0xDC04, 0x25)
+	m.write(0xDC05, 0x40)
+	assert.Equal(t, 0x4025, m.playCycles())
+
+	m.cycles = 63 * 300
+	assert.Equal(t, byte(300-256), m.read(0xD012))
+	assert.Equal(t, byte(0x80), m.read(0xD011))
+}
@@ -0,0 +1,321 @@
+package chip
+
+import "math"
+
+const (
+	sidGate     = 0x01
+	sidSync     = 0x02
+	sidRing     = 0x04
+	sidTest     = 0x08
+	sidTriangle = 0x10
+	sidSawtooth = 0x20
+	sidPulse    = 0x40
+	sidNoise    = 0x80
+
+	sidFilterLowPass  = 0x1
+	sidFilterBandPass = 0x2
+	sidFilterHighPass = 0x4
+	sidVoice3Off      = 0x8
+
+	sidRegisters = 0x20
+
+	// sidGain scales the output of the voices, which is 12 bits for the waveform times 8 bits for the envelope
+	sidGain = 1.0 / (2 * 2048 * 255)
+)
+
+// sidRatePeriods are the number of cycles between steps of the envelope at each of the attack, decay, and release
+// rates
+var sidRatePeriods = [16]int{9, 32, 63, 95, 149, 220, 267, 313, 392, 977, 1954, 3126, 3907, 11720, 19532, 31251}
+
+const (
+	envelopeAttack = iota
+	envelopeDecay
+	envelopeRelease
+)
+
+// sidVoice is one of the three voices of the SID, an oscillator with four waveforms and an ADSR envelope
+type sidVoice struct {
+	frequency   uint32
+	pulseWidth  uint32
+	control     byte
+	attack      byte
+	decay       byte
+	sustain     byte
+	release     byte
+	accumulator uint32
+	noise       uint32
+	msbRose     bool
+
+	envelope           int
+	state              int
+	rateCounter        int
+	exponentialCounter int
+}
+
+// advance runs the oscillator for a number of cycles
+func (v *sidVoice) advance(cycles int) {
+	if v.control&sidTest != 0 {
+		v.accumulator = 0
+		v.msbRose = false
+		return
+	}
+
+	previous := v.accumulator
+	next := previous + v.frequency*uint32(cycles)
+	v.accumulator = next & 0xFFFFFF
+	v.msbRose = previous&0x800000 == 0 && (next&0x800000 != 0 || next > 0xFFFFFF)
+
+	// The noise shift register is clocked when bit 19 of the accumulator rises
+	clocks := (next+0x80000)>>20 - (previous+0x80000)>>20
+	if clocks > 23 {
+		clocks = 23
+	}
+
+	for ; clocks > 0; clocks-- {
+		bit := (v.noise>>22 ^ v.noise>>17) & 1
+		v.noise = (v.noise<<1)&0x7FFFFF | bit
+	}
+}
+
+// waveform returns the 12-bit output of the oscillator. Combined waveforms are approximated by the AND of each
+func (v *sidVoice) waveform(source *sidVoice) uint32 {
+	out := uint32(0xFFF)
+	selected := false
+	if v.control&sidTriangle != 0 {
+		msb := v.accumulator & 0x800000
+		if v.control&sidRing != 0 {
+			msb ^= source.accumulator & 0x800000
+		}
+
+		accumulator := v.accumulator
+		if msb != 0 {
+			accumulator = ^accumulator
+		}
+
+		out &= accumulator >> 11 & 0xFFF
+		selected = true
+	}
+
+	if v.control&sidSawtooth != 0 {
+		out &= v.accumulator >> 12
+		selected = true
+	}
+
+	if v.control&sidPulse != 0 {
+		if v.control&sidTest == 0 && v.accumulator>>12 < v.pulseWidth {
+			out = 0
+		}
+
+		selected = true
+	}
+
+	if v.control&sidNoise != 0 {
+		n := v.noise
+		out &= n>>9&0x800 | n>>8&0x400 | n>>5&0x200 | n>>3&0x100 | n>>2&0x080 | n<<1&0x040 | n<<3&0x020 | n<<4&0x010
+		selected = true
+	}
+
+	if !selected {
+		return 0
+	}
+
+	return out
+}
+
+func (v *sidVoice) setControl(control byte) {
+	if control&sidGate != 0 && v.control&sidGate == 0 {
+		v.state = envelopeAttack
+	} else if control&sidGate == 0 && v.control&sidGate != 0 {
+		v.state = envelopeRelease
+	}
+
+	v.control = control
+}
+
+// exponentialPeriod returns how many steps of the rate counter the envelope takes to decay by one at its level,
+// which makes decays and releases approximately exponential
+func (v *sidVoice) exponentialPeriod() int {
+	switch {
+	case v.envelope > 93:
+		return 1
+	case v.envelope > 54:
+		return 2
+	case v.envelope > 26:
+		return 4
+	case v.envelope > 14:
+		return 8
+	case v.envelope > 6:
+		return 16
+	case v.envelope > 0:
+		return 30
+	default:
+		return 1
+	}
+}
+
+// clockEnvelope runs the envelope for a number of cycles
+func (v *sidVoice) clockEnvelope(cycles int) {
+	rate := v.attack
+	switch v.state {
+	case envelopeDecay:
+		rate = v.decay
+	case envelopeRelease:
+		rate = v.release
+	}
+
+	period := sidRatePeriods[rate]
+	v.rateCounter += cycles
+	for v.rateCounter >= period {
+		v.rateCounter -= period
+		switch {
+		case v.state == envelopeAttack:
+			v.exponentialCounter = 0
+			v.envelope++
+			if v.envelope >= 255 {
+				v.envelope = 255
+				v.state = envelopeDecay
+			}
+		case v.state == envelopeDecay && v.envelope <= int(v.sustain)*17, v.envelope == 0:
+		default:
+			v.exponentialCounter++
+			if v.exponentialCounter >= v.exponentialPeriod() {
+				v.exponentialCounter = 0
+				v.envelope--
+			}
+		}
+	}
+}
+
+// sidChip emulates the SID, the sound chip of the C64, at the level of samples rather than cycles. The filter is a
+// state variable filter with a cutoff that rises linearly with its register, which is close enough to both models
+type sidChip struct {
+	voices     [3]sidVoice
+	registers  [sidRegisters]byte
+	cutoff     int
+	resonance  int
+	routing    byte
+	mode       byte
+	volume     byte
+	sampleRate float64
+
+	low, band float64
+}
+
+func newSIDChip(sampleRate float64) *sidChip {
+	s := &sidChip{sampleRate: sampleRate}
+	for i := range s.voices {
+		s.voices[i].noise = 0x7FFFF8
+		s.voices[i].state = envelopeRelease
+	}
+
+	return s
+}
+
+func (s *sidChip) write(reg byte, value byte) {
+	reg %= sidRegisters
+	s.registers[reg] = value
+	if reg < 21 {
+		v := &s.voices[reg/7]
+		switch reg % 7 {
+		case 0:
+			v.frequency = v.frequency&0xFF00 | uint32(value)
+		case 1:
+			v.frequency = v.frequency&0xFF | uint32(value)<<8
+		case 2:
+			v.pulseWidth = v.pulseWidth&0xF00 | uint32(value)
+		case 3:
+			v.pulseWidth = v.pulseWidth&0xFF | uint32(value&0xF)<<8
+		case 4:
+			v.setControl(value)
+		case 5:
+			v.attack, v.decay = value>>4, value&0xF
+		case 6:
+			v.sustain, v.release = value>>4, value&0xF
+		}
+
+		return
+	}
+
+	switch reg {
+	case 21:
+		s.cutoff = s.cutoff&0x7F8 | int(value&7)
+	case 22:
+		s.cutoff = s.cutoff&7 | int(value)<<3
+	case 23:
+		s.resonance, s.routing = int(value>>4), value&0xF
+	case 24:
+		s.mode, s.volume = value>>4, value&0xF
+	}
+}
+
+func (s *sidChip) read(reg byte) byte {
+	switch reg % sidRegisters {
+	case 27:
+		return byte(s.voices[2].waveform(&s.voices[1]) >> 4)
+	case 28:
+		return byte(s.voices[2].envelope)
+	default:
+		return 0
+	}
+}
+
+// clock runs the chip for a number of cycles and returns the sample at the end of them
+func (s *sidChip) clock(cycles int) float64 {
+	for i := range s.voices {
+		s.voices[i].advance(cycles)
+		s.voices[i].clockEnvelope(cycles)
+	}
+
+	// Each voice is synced and ring modulated by the one before it
+	for i := range s.voices {
+		v, source := &s.voices[i], &s.voices[(i+2)%3]
+		if v.control&sidSync != 0 && source.msbRose {
+			v.accumulator = 0
+		}
+	}
+
+	var direct, filtered float64
+	for i := range s.voices {
+		v := &s.voices[i]
+		out := (float64(v.waveform(&s.voices[(i+2)%3])) - 0x800) * float64(v.envelope)
+		switch {
+		case s.routing&(1<<uint(i)) != 0:
+			filtered += out
+		case i == 2 && s.mode&sidVoice3Off != 0:
+		default:
+			direct += out
+		}
+	}
+
+	return (direct + s.filter(filtered)) * float64(s.volume) / 15 * sidGain
+}
+
+// filter runs the state variable filter for a sample and mixes the outputs selected by the mode
+func (s *sidChip) filter(in float64) float64 {
+	if s.mode&(sidFilterLowPass|sidFilterBandPass|sidFilterHighPass) == 0 {
+		return 0
+	}
+
+	cutoff := 30 + float64(s.cutoff)*5.8
+	f := 2 * math.Sin(math.Pi*math.Min(cutoff, s.sampleRate/6)/s.sampleRate)
+	damping := 1 / (0.707 + float64(s.resonance)/15*1.3)
+
+	s.low += f * s.band
+	high := in - s.low - damping*s.band
+	s.band += f * high
+
+	var out float64
+	if s.mode&sidFilterLowPass != 0 {
+		out += s.low
+	}
+
+	if s.mode&sidFilterBandPass != 0 {
+		out += s.band
+	}
+
+	if s.mode&sidFilterHighPass != 0 {
+		out += high
+	}
+
+	return out
+}
@@ -0,0 +1,114 @@
+package chip
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSIDVoice_Waveform(t *testing.T) {
+	testCases := []struct {
+		name        string
+		control     byte
+		accumulator uint32
+		pulseWidth  uint32
+		expected    uint32
+	}{
+		{"Sawtooth", sidSawtooth, 0x123456, 0, 0x123},
+		{"TriangleRising", sidTriangle, 0x400000, 0, 0x800},
+		{"TriangleFalling", sidTriangle, 0xC00000, 0, 0x7FF},
+		{"PulseHigh", sidPulse, 0x800000, 0x800, 0xFFF},
+		{"PulseLow", sidPulse, 0x7FF000, 0x800, 0},
+		{"Combined", sidSawtooth | sidPulse, 0x900000, 0x800, 0x900},
+		{"None", 0, 0x900000, 0, 0},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			v := &sidVoice{control: testCase.control, accumulator: testCase.accumulator, pulseWidth: testCase.pulseWidth}
+			assert.Equal(tt, testCase.expected, v.waveform(&sidVoice{}))
+		})
+	}
+}
+
+func TestSIDVoice_RingModulation(t *testing.T) {
+	// Ring modulation flips the triangle with the top bit of the source
+	v := &sidVoice{control: sidTriangle | sidRing, accumulator: 0x400000}
+	assert.Equal(t, uint32(0x800), v.waveform(&sidVoice{}))
+	assert.Equal(t, uint32(0x7FF), v.waveform(&sidVoice{accumulator: 0x800000}))
+}
+
+func TestSIDVoice_Advance(t *testing.T) {
+	v := &sidVoice{frequency: 0x1000, accumulator: 0x7FF000, noise: 0x7FFFF8}
+	v.advance(2)
+	assert.Equal(t, uint32(0x801000), v.accumulator)
+	assert.True(t, v.msbRose)
+
+	// The accumulator wraps and is held at zero while testing
+	v.accumulator = 0xFFF000
+	v.advance(2)
+	assert.Equal(t, uint32(0x001000), v.accumulator)
+	assert.False(t, v.msbRose)
+
+	v.control = sidTest
+	v.advance(2)
+	assert.Equal(t, uint32(0), v.accumulator)
+
+	// The noise register shifts whenever bit 19 rises
+	v = &sidVoice{frequency: 0x10000, noise: 0x7FFFF8}
+	v.advance(16)
+	assert.NotEqual(t, uint32(0x7FFFF8), v.noise)
+}
+
+func TestSIDVoice_Envelope(t *testing.T) {
+	v := &sidVoice{state: envelopeRelease, sustain: 8}
+	v.setControl(sidGate)
+	assert.Equal(t, envelopeAttack, v.state)
+
+	// The fastest attack rises by one every 9 cycles
+	v.clockEnvelope(9 * 255)
+	assert.Equal(t, 255, v.envelope)
+	assert.Equal(t, envelopeDecay, v.state)
+
+	// Decays stop at the sustain level
+	v.clockEnvelope(9 * 10000)
+	assert.Equal(t, 8*17, v.envelope)
+
+	v.setControl(0)
+	assert.Equal(t, envelopeRelease, v.state)
+	v.clockEnvelope(9 * 10000)
+	assert.Equal(t, 0, v.envelope)
+}
+
+func TestSIDChip(t *testing.T) {
+	s := newSIDChip(sidSampleRate)
+	s.write(0x00, 0x34)
+	s.write(0x01, 0x12)
+	s.write(0x02, 0xFF)
+	s.write(0x03, 0xF8)
+	s.write(0x05, 0x5A)
+	s.write(0x06, 0xC3)
+	s.write(0x04, sidPulse|sidGate)
+	s.write(0x15, 0x07)
+	s.write(0x16, 0xFF)
+	s.write(0x17, 0xF1)
+	s.write(0x18, 0x1F)
+
+	v := &s.voices[0]
+	assert.Equal(t, uint32(0x1234), v.frequency)
+	assert.Equal(t, uint32(0x8FF), v.pulseWidth)
+	assert.Equal(t, byte(5), v.attack)
+	assert.Equal(t, byte(0xA), v.decay)
+	assert.Equal(t, byte(0xC), v.sustain)
+	assert.Equal(t, byte(3), v.release)
+	assert.Equal(t, envelopeAttack, v.state)
+	assert.Equal(t, 0x7FF, s.cutoff)
+	assert.Equal(t, 15, s.resonance)
+	assert.Equal(t, byte(1), s.routing)
+	assert.Equal(t, byte(sidFilterLowPass), s.mode)
+	assert.Equal(t, byte(15), s.volume)
+
+	// Silent voices output nothing and the third voice can be read back
+	assert.Equal(t, 0.0, s.clock(22))
+	s.voices[2].envelope = 42
+	assert.Equal(t, byte(42), s.read(0x1C))
+}
@@ -0,0 +1,259 @@
+package chip
+
+import (
+	"strconv"
+	"time"
+)
+
+const (
+	spcSignature    = "SNES-SPC700 Sound File Data"
+	spcRAMOffset    = 0x100
+	spcDSPOffset    = 0x10100
+	spcLength       = spcDSPOffset + dspRegisters
+	spcHasTag       = 26
+	spcSampleRate   = 32000
+	spcSampleCycles = 32
+
+	// spcDefaultLength and spcDefaultFade are how long SPC songs play and fade out for when their tag doesn't say
+	spcDefaultLength = 3 * time.Minute
+	spcDefaultFade   = 10 * time.Second
+)
+
+// spcTimerDividers are the number of cycles between ticks of each of the three timers
+var spcTimerDividers = [3]int{128, 128, 16}
+
+// spcHeader is the header of an SPC file, which has the state of the sound module of the SNES while it was playing
+type spcHeader struct {
+	pc           uint16
+	a, x, y, psw byte
+	sp           byte
+	title        string
+	artist       string
+	length       time.Duration
+	fade         time.Duration
+	ram          []byte
+	dsp          []byte
+}
+
+// LoadSPC loads an SPC file, which has the memory, registers, and DSP of the sound module of a SNES game while it
+// was playing a song. The ID666 tag says how long the song is. The IPL ROM isn't emulated since songs don't call it
+func LoadSPC(data []byte) (*Tune, error) {
+	if len(data) < len(spcSignature) || string(data[:len(spcSignature)]) != spcSignature {
+		return nil, ErrUnknownFormat
+	}
+
+	if len(data) < spcLength {
+		return nil, ErrTruncated
+	}
+
+	h := spcHeader{
+		pc:     uint16(data[0x25]) | uint16(data[0x26])<<8,
+		a:      data[0x27],
+		x:      data[0x28],
+		y:      data[0x29],
+		psw:    data[0x2A],
+		sp:     data[0x2B],
+		length: spcDefaultLength,
+		fade:   spcDefaultFade,
+		ram:    data[spcRAMOffset:spcDSPOffset],
+		dsp:    data[spcDSPOffset:spcLength],
+	}
+
+	if data[0x23] == spcHasTag {
+		parseID666(&h, data)
+	}
+
+	return &Tune{
+		Title:      h.title,
+		Artist:     h.artist,
+		Format:     "spc",
+		Songs:      1,
+		Song:       1,
+		SampleRate: spcSampleRate,
+		Length:     h.length,
+		Fade:       h.fade,
+		newEmulator: func(song int) emulator {
+			return newSPCMachine(&h)
+		},
+	}, nil
+}
+
+// parseID666 parses the ID666 tag in the header, which is either in text or binary. Text tags have the length in
+// decimal digits, which binary tags would be unlikely to have there
+func parseID666(h *spcHeader, data []byte) {
+	h.title = text(data[0x2E:0x4E])
+	if h.title == "" {
+		h.title = text(data[0x4E:0x6E])
+	}
+
+	var seconds, fade int
+	if isDigits(data[0xA9:0xAC]) && isDigits(data[0xAC:0xB1]) {
+		seconds, _ = strconv.Atoi(text(data[0xA9:0xAC]))
+		fade, _ = strconv.Atoi(text(data[0xAC:0xB1]))
+		h.artist = text(data[0xB1:0xD1])
+	} else {
+		seconds = int(data[0xA9]) | int(data[0xAA])<<8 | int(data[0xAB])<<16
+		fade = int(data[0xAC]) | int(data[0xAD])<<8 | int(data[0xAE])<<16 | int(data[0xAF])<<24
+		h.artist = text(data[0xB0:0xD0])
+	}
+
+	if seconds > 0 {
+		h.length = time.Duration(seconds) * time.Second
+	}
+
+	if fade > 0 && fade < int(time.Hour/time.Millisecond) {
+		h.fade = time.Duration(fade) * time.Millisecond
+	}
+}
+
+// isDigits returns whether a field of a text tag has only digits, up to a NUL
+func isDigits(field []byte) bool {
+	for _, b := range field {
+		if b == 0 {
+			break
+		}
+
+		if b < '0' || b > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// spcTimer is one of the three timers of the sound module, which count up to their target and then tick a 4-bit
+// counter that drivers poll for tempo
+type spcTimer struct {
+	enabled bool
+	stage   int
+	divider int
+	count   int
+	target  byte
+	counter byte
+}
+
+func (t *spcTimer) clock(cycles int) {
+	t.stage += cycles
+	for t.stage >= t.divider {
+		t.stage -= t.divider
+		if !t.enabled {
+			continue
+		}
+
+		t.count++
+		target := int(t.target)
+		if target == 0 {
+			target = 256
+		}
+
+		if t.count >= target {
+			t.count = 0
+			t.counter = (t.counter + 1) & 0xF
+		}
+	}
+}
+
+// spcMachine is the sound module of the SNES, which has the SPC700, the S-DSP, and their shared RAM. The ports the
+// SNES talks to it through keep the values they had when the file was saved
+type spcMachine struct {
+	cpu        spc700
+	dsp        *dsp
+	ram        [0x10000]byte
+	timers     [3]spcTimer
+	dspAddress byte
+	ports      [4]byte
+	debt       int
+}
+
+func newSPCMachine(h *spcHeader) *spcMachine {
+	m := &spcMachine{}
+	copy(m.ram[:], h.ram)
+	m.dsp = newDSP(&m.ram)
+	for reg, v := range h.dsp {
+		if reg != dspKeyOn && reg != dspEnded {
+			m.dsp.registers[reg] = v
+		}
+	}
+
+	m.cpu = spc700{pc: h.pc, a: h.a, x: h.x, y: h.y, psw: h.psw, sp: h.sp, mem: m}
+	copy(m.ports[:], m.ram[0xF4:0xF8])
+	m.dspAddress = m.ram[0xF2]
+	for i := range m.timers {
+		m.timers[i].divider = spcTimerDividers[i]
+		m.timers[i].enabled = m.ram[0xF1]&(1<<uint(i)) != 0
+		m.timers[i].target = m.ram[0xFA+i]
+		m.timers[i].counter = m.ram[0xFD+i] & 0xF
+	}
+
+	return m
+}
+
+func (m *spcMachine) read(addr uint16) byte {
+	switch addr {
+	case 0xF2:
+		return m.dspAddress
+	case 0xF3:
+		return m.dsp.read(m.dspAddress)
+	case 0xF4, 0xF5, 0xF6, 0xF7:
+		return m.ports[addr-0xF4]
+	case 0xFA, 0xFB, 0xFC:
+		return 0
+	case 0xFD, 0xFE, 0xFF:
+		t := &m.timers[addr-0xFD]
+		counter := t.counter
+		t.counter = 0
+		return counter
+	}
+
+	return m.ram[addr]
+}
+
+func (m *spcMachine) write(addr uint16, v byte) {
+	switch addr {
+	case 0xF1:
+		for i := range m.timers {
+			enabled := v&(1<<uint(i)) != 0
+			if enabled && !m.timers[i].enabled {
+				m.timers[i].count, m.timers[i].counter = 0, 0
+			}
+
+			m.timers[i].enabled = enabled
+		}
+
+		if v&0x10 != 0 {
+			m.ports[0], m.ports[1] = 0, 0
+		}
+
+		if v&0x20 != 0 {
+			m.ports[2], m.ports[3] = 0, 0
+		}
+	case 0xF2:
+		m.dspAddress = v
+	case 0xF3:
+		if m.dspAddress < dspRegisters {
+			m.dsp.write(m.dspAddress, v)
+		}
+	case 0xFA, 0xFB, 0xFC:
+		m.timers[addr-0xFA].target = v
+	}
+
+	m.ram[addr] = v
+}
+
+// render runs the SPC700 for the cycles of each sample and then the DSP for the sample
+func (m *spcMachine) render(samples [][2]float64) {
+	for i := range samples {
+		for m.debt < spcSampleCycles {
+			cycles := m.cpu.step()
+			for t := range m.timers {
+				m.timers[t].clock(cycles)
+			}
+
+			m.debt += cycles
+		}
+
+		m.debt -= spcSampleCycles
+		left, right := m.dsp.sample()
+		samples[i] = [2]float64{float64(left) / 0x8000, float64(right) / 0x8000}
+	}
+}
@@ -0,0 +1,590 @@
+package chip
+
+const (
+	spcFlagDirectPage = 0x20
+	spcFlagBreak      = 0x10
+	spcFlagHalfCarry  = 0x08
+	spcFlagInterrupt  = 0x04
+)
+
+// spc700Cycles are the cycles every opcode takes, not counting extra cycles for taking branches
+var spc700Cycles = [256]byte{
+	2, 8, 4, 5, 3, 4, 3, 6, 2, 6, 5, 4, 5, 4, 6, 8,
+	2, 8, 4, 5, 4, 5, 5, 6, 5, 5, 6, 5, 2, 2, 4, 6,
+	2, 8, 4, 5, 3, 4, 3, 6, 2, 6, 5, 4, 5, 4, 5, 4,
+	2, 8, 4, 5, 4, 5, 5, 6, 5, 5, 6, 5, 2, 2, 3, 8,
+	2, 8, 4, 5, 3, 4, 3, 6, 2, 6, 4, 4, 5, 4, 6, 6,
+	2, 8, 4, 5, 4, 5, 5, 6, 5, 5, 4, 5, 2, 2, 4, 3,
+	2, 8, 4, 5, 3, 4, 3, 6, 2, 6, 4, 4, 5, 4, 5, 5,
+	2, 8, 4, 5, 4, 5, 5, 6, 5, 5, 5, 5, 2, 2, 3, 6,
+	2, 8, 4, 5, 3, 4, 3, 6, 2, 6, 5, 4, 5, 2, 4, 5,
+	2, 8, 4, 5, 4, 5, 5, 6, 5, 5, 5, 5, 2, 2, 12, 5,
+	3, 8, 4, 5, 3, 4, 3, 6, 2, 6, 4, 4, 5, 2, 4, 4,
+	2, 8, 4, 5, 4, 5, 5, 6, 5, 5, 5, 5, 2, 2, 3, 4,
+	3, 8, 4, 5, 4, 5, 4, 7, 2, 5, 6, 4, 5, 2, 4, 9,
+	2, 8, 4, 5, 5, 6, 6, 7, 4, 5, 5, 5, 2, 2, 6, 3,
+	2, 8, 4, 5, 3, 4, 3, 6, 2, 4, 5, 3, 4, 3, 4, 3,
+	2, 8, 4, 5, 4, 5, 5, 6, 3, 4, 5, 4, 2, 2, 4, 3,
+}
+
+// spc700 emulates the SPC700, the CPU of the sound module of the SNES
+type spc700 struct {
+	a, x, y, sp, psw byte
+	pc               uint16
+	mem              memory
+
+	// halted is true once SLEEP or STOP ran, which only a reset wakes the CPU from
+	halted bool
+}
+
+func (c *spc700) fetch() byte {
+	v := c.mem.read(c.pc)
+	c.pc++
+	return v
+}
+
+func (c *spc700) fetch16() uint16 {
+	lo := uint16(c.fetch())
+	return uint16(c.fetch())<<8 | lo
+}
+
+// dp returns the address of an offset into the direct page, which is the first or second page of memory
+func (c *spc700) dp(offset byte) uint16 {
+	if c.psw&spcFlagDirectPage != 0 {
+		return 0x100 | uint16(offset)
+	}
+
+	return uint16(offset)
+}
+
+func (c *spc700) read16(addr uint16) uint16 {
+	return uint16(c.mem.read(addr)) | uint16(c.mem.read(addr+1))<<8
+}
+
+// readDP16 reads a word from the direct page, wrapping within it
+func (c *spc700) readDP16(offset byte) uint16 {
+	return uint16(c.mem.read(c.dp(offset))) | uint16(c.mem.read(c.dp(offset+1)))<<8
+}
+
+func (c *spc700) writeDP16(offset byte, v uint16) {
+	c.mem.write(c.dp(offset), byte(v))
+	c.mem.write(c.dp(offset+1), byte(v>>8))
+}
+
+func (c *spc700) push(v byte) {
+	c.mem.write(0x100|uint16(c.sp), v)
+	c.sp--
+}
+
+func (c *spc700) pop() byte {
+	c.sp++
+	return c.mem.read(0x100 | uint16(c.sp))
+}
+
+func (c *spc700) push16(v uint16) {
+	c.push(byte(v >> 8))
+	c.push(byte(v))
+}
+
+func (c *spc700) pop16() uint16 {
+	lo := uint16(c.pop())
+	return uint16(c.pop())<<8 | lo
+}
+
+func (c *spc700) ya() uint16 {
+	return uint16(c.y)<<8 | uint16(c.a)
+}
+
+func (c *spc700) setYA(v uint16) {
+	c.y, c.a = byte(v>>8), byte(v)
+}
+
+func (c *spc700) setFlag(flag byte, on bool) {
+	if on {
+		c.psw |= flag
+	} else {
+		c.psw &^= flag
+	}
+}
+
+func (c *spc700) setNZ(v byte) byte {
+	c.setFlag(flagZero, v == 0)
+	c.setFlag(flagNegative, v&0x80 != 0)
+	return v
+}
+
+func (c *spc700) setNZ16(v uint16) {
+	c.setFlag(flagZero, v == 0)
+	c.setFlag(flagNegative, v&0x8000 != 0)
+}
+
+// branch branches by the offset in the next byte if taken and returns the extra cycles it took
+func (c *spc700) branch(taken bool) int {
+	offset := int8(c.fetch())
+	if !taken {
+		return 0
+	}
+
+	c.pc += uint16(offset)
+	return 2
+}
+
+// operandAddress returns the address of the operand of the instructions in columns 4 to 7 of the opcode table, where
+// the odd rows index the addressing mode of the even ones
+func (c *spc700) operandAddress(op byte) uint16 {
+	indexed := op&0x10 != 0
+	switch op & 0xF {
+	case 4: // d, d+X
+		offset := c.fetch()
+		if indexed {
+			offset += c.x
+		}
+
+		return c.dp(offset)
+	case 5: // !a, !a+X
+		addr := c.fetch16()
+		if indexed {
+			addr += uint16(c.x)
+		}
+
+		return addr
+	case 6: // (X), !a+Y
+		if indexed {
+			return c.fetch16() + uint16(c.y)
+		}
+
+		return c.dp(c.x)
+	default: // [d+X], [d]+Y
+		if indexed {
+			return c.readDP16(c.fetch()) + uint16(c.y)
+		}
+
+		return c.readDP16(c.fetch() + c.x)
+	}
+}
+
+// alu does the arithmetic and logic operations of the first six rows of the opcode table on a and b. Comparisons
+// return a unchanged
+func (c *spc700) alu(operation, a, b byte) byte {
+	switch operation {
+	case 0: // OR
+		return c.setNZ(a | b)
+	case 1: // AND
+		return c.setNZ(a & b)
+	case 2: // EOR
+		return c.setNZ(a ^ b)
+	case 3: // CMP
+		c.compare(a, b)
+		return a
+	case 4: // ADC
+		return c.adc(a, b)
+	default: // SBC
+		return c.adc(a, ^b)
+	}
+}
+
+func (c *spc700) compare(a, b byte) {
+	c.setFlag(flagCarry, a >= b)
+	c.setNZ(a - b)
+}
+
+func (c *spc700) adc(a, b byte) byte {
+	carry := uint16(c.psw & flagCarry)
+	sum := uint16(a) + uint16(b) + carry
+	c.setFlag(flagCarry, sum > 0xFF)
+	c.setFlag(spcFlagHalfCarry, uint16(a&0xF)+uint16(b&0xF)+carry > 0xF)
+	c.setFlag(flagOverflow, (a^b)&0x80 == 0 && (a^byte(sum))&0x80 != 0)
+	return c.setNZ(byte(sum))
+}
+
+// shift does the shifts, increments, and decrements of the first six rows of columns B and C of the opcode table
+func (c *spc700) shift(operation, v byte) byte {
+	carry := c.psw & flagCarry
+	switch operation {
+	case 0: // ASL
+		c.setFlag(flagCarry, v&0x80 != 0)
+		v <<= 1
+	case 1: // ROL
+		c.setFlag(flagCarry, v&0x80 != 0)
+		v = v<<1 | carry
+	case 2: // LSR
+		c.setFlag(flagCarry, v&1 != 0)
+		v >>= 1
+	case 3: // ROR
+		c.setFlag(flagCarry, v&1 != 0)
+		v = v>>1 | carry<<7
+	case 4: // DEC
+		v--
+	default: // INC
+		v++
+	}
+
+	return c.setNZ(v)
+}
+
+// memoryBit returns the address and bit of the operand of the instructions on single bits of memory
+func (c *spc700) memoryBit() (uint16, byte) {
+	operand := c.fetch16()
+	return operand & 0x1FFF, byte(operand >> 13)
+}
+
+// step executes an instruction and returns the cycles it took
+func (c *spc700) step() int {
+	if c.halted {
+		return 2
+	}
+
+	op := c.fetch()
+	cycles := int(spc700Cycles[op])
+	operation := op >> 5
+	switch column := op & 0xF; {
+	case column >= 4 && column <= 7 && op < 0xC0:
+		c.a = c.alu(operation, c.a, c.mem.read(c.operandAddress(op)))
+	case column >= 4 && column <= 7 && op < 0xE0:
+		c.mem.write(c.operandAddress(op), c.a)
+	case column >= 4 && column <= 7:
+		c.a = c.setNZ(c.mem.read(c.operandAddress(op)))
+	case column == 8 && op < 0xC0 && op&0x10 == 0: // A, #i
+		c.a = c.alu(operation, c.a, c.fetch())
+	case column == 8 && op < 0xC0: // d, #i
+		v := c.fetch()
+		addr := c.dp(c.fetch())
+		c.aluMemory(operation, addr, v)
+	case column == 9 && op < 0xC0 && op&0x10 == 0: // dd, ds
+		v := c.mem.read(c.dp(c.fetch()))
+		addr := c.dp(c.fetch())
+		c.aluMemory(operation, addr, v)
+	case column == 9 && op < 0xC0: // (X), (Y)
+		c.aluMemory(operation, c.dp(c.x), c.mem.read(c.dp(c.y)))
+	case (column == 0xB || column == 0xC) && op < 0xC0 && op&0x1F != 0x1C:
+		var addr uint16
+		switch {
+		case column == 0xB && op&0x10 == 0:
+			addr = c.dp(c.fetch())
+		case column == 0xB:
+			addr = c.dp(c.fetch() + c.x)
+		default:
+			addr = c.fetch16()
+		}
+
+		c.mem.write(addr, c.shift(operation, c.mem.read(addr)))
+	case column == 0xC && op < 0xC0: // A
+		c.a = c.shift(operation, c.a)
+	case column == 1: // TCALL
+		c.push16(c.pc)
+		c.pc = c.read16(0xFFDE - uint16(op>>4)*2)
+	case column == 2: // SET1, CLR1
+		addr := c.dp(c.fetch())
+		bit := byte(1) << operation
+		if op&0x10 == 0 {
+			c.mem.write(addr, c.mem.read(addr)|bit)
+		} else {
+			c.mem.write(addr, c.mem.read(addr)&^bit)
+		}
+	case column == 3: // BBS, BBC
+		v := c.mem.read(c.dp(c.fetch()))
+		set := v&(1<<operation) != 0
+		cycles += c.branch(set == (op&0x10 == 0))
+	case column == 0 && op&0x10 != 0: // branches on flags
+		flags := [4]byte{flagNegative, flagOverflow, flagCarry, flagZero}
+		set := c.psw&flags[op>>6] != 0
+		cycles += c.branch(set == (op&0x20 != 0))
+	default:
+		cycles += c.execute(op)
+	}
+
+	return cycles
+}
+
+// aluMemory does an operation of alu on memory, which comparisons leave unchanged
+func (c *spc700) aluMemory(operation byte, addr uint16, v byte) {
+	result := c.alu(operation, c.mem.read(addr), v)
+	if operation != 3 {
+		c.mem.write(addr, result)
+	}
+}
+
+// execute executes the instructions which don't follow the layout of the opcode table and returns their extra cycles
+func (c *spc700) execute(op byte) int {
+	switch op {
+	case 0x00: // NOP
+	case 0x20: // CLRP
+		c.psw &^= spcFlagDirectPage
+	case 0x40: // SETP
+		c.psw |= spcFlagDirectPage
+	case 0x60: // CLRC
+		c.psw &^= flagCarry
+	case 0x80: // SETC
+		c.psw |= flagCarry
+	case 0xA0: // EI
+		c.psw |= spcFlagInterrupt
+	case 0xC0: // DI
+		c.psw &^= spcFlagInterrupt
+	case 0xE0: // CLRV
+		c.psw &^= flagOverflow | spcFlagHalfCarry
+	case 0xED: // NOTC
+		c.psw ^= flagCarry
+
+	case 0x0A, 0x2A, 0x4A, 0x6A, 0x8A, 0xAA, 0xCA, 0xEA:
+		c.bitOperation(op)
+
+	case 0x1A: // DECW d
+		offset := c.fetch()
+		v := c.readDP16(offset) - 1
+		c.writeDP16(offset, v)
+		c.setNZ16(v)
+	case 0x3A: // INCW d
+		offset := c.fetch()
+		v := c.readDP16(offset) + 1
+		c.writeDP16(offset, v)
+		c.setNZ16(v)
+	case 0x5A: // CMPW YA, d
+		ya, v := c.ya(), c.readDP16(c.fetch())
+		c.setFlag(flagCarry, ya >= v)
+		c.setNZ16(ya - v)
+	case 0x7A: // ADDW YA, d
+		ya, v := c.ya(), c.readDP16(c.fetch())
+		sum := uint32(ya) + uint32(v)
+		c.setFlag(flagCarry, sum > 0xFFFF)
+		c.setFlag(spcFlagHalfCarry, ya&0xFFF+v&0xFFF > 0xFFF)
+		c.setFlag(flagOverflow, (ya^v)&0x8000 == 0 && (ya^uint16(sum))&0x8000 != 0)
+		c.setYA(uint16(sum))
+		c.setNZ16(uint16(sum))
+	case 0x9A: // SUBW YA, d
+		ya, v := c.ya(), c.readDP16(c.fetch())
+		difference := ya - v
+		c.setFlag(flagCarry, ya >= v)
+		c.setFlag(spcFlagHalfCarry, ya&0xFFF >= v&0xFFF)
+		c.setFlag(flagOverflow, (ya^v)&0x8000 != 0 && (ya^difference)&0x8000 != 0)
+		c.setYA(difference)
+		c.setNZ16(difference)
+	case 0xBA: // MOVW YA, d
+		v := c.readDP16(c.fetch())
+		c.setYA(v)
+		c.setNZ16(v)
+	case 0xDA: // MOVW d, YA
+		c.writeDP16(c.fetch(), c.ya())
+
+	case 0x0E, 0x4E: // TSET1 !a, TCLR1 !a
+		addr := c.fetch16()
+		v := c.mem.read(addr)
+		c.setNZ(c.a - v)
+		if op == 0x0E {
+			c.mem.write(addr, v|c.a)
+		} else {
+			c.mem.write(addr, v&^c.a)
+		}
+	case 0x1E: // CMP X, !a
+		c.compare(c.x, c.mem.read(c.fetch16()))
+	case 0x3E: // CMP X, d
+		c.compare(c.x, c.mem.read(c.dp(c.fetch())))
+	case 0x5E: // CMP Y, !a
+		c.compare(c.y, c.mem.read(c.fetch16()))
+	case 0x7E: // CMP Y, d
+		c.compare(c.y, c.mem.read(c.dp(c.fetch())))
+	case 0xC8: // CMP X, #i
+		c.compare(c.x, c.fetch())
+	case 0xAD: // CMP Y, #i
+		c.compare(c.y, c.fetch())
+
+	case 0x2E: // CBNE d, r
+		v := c.mem.read(c.dp(c.fetch()))
+		return c.branch(c.a != v)
+	case 0xDE: // CBNE d+X, r
+		v := c.mem.read(c.dp(c.fetch() + c.x))
+		return c.branch(c.a != v)
+	case 0x6E: // DBNZ d, r
+		addr := c.dp(c.fetch())
+		v := c.mem.read(addr) - 1
+		c.mem.write(addr, v)
+		return c.branch(v != 0)
+	case 0xFE: // DBNZ Y, r
+		c.y--
+		return c.branch(c.y != 0)
+	case 0x2F: // BRA r
+		return c.branch(true) - 2
+
+	case 0x0D: // PUSH PSW
+		c.push(c.psw)
+	case 0x2D: // PUSH A
+		c.push(c.a)
+	case 0x4D: // PUSH X
+		c.push(c.x)
+	case 0x6D: // PUSH Y
+		c.push(c.y)
+	case 0x8E: // POP PSW
+		c.psw = c.pop()
+	case 0xAE: // POP A
+		c.a = c.pop()
+	case 0xCE: // POP X
+		c.x = c.pop()
+	case 0xEE: // POP Y
+		c.y = c.pop()
+
+	case 0x1D: // DEC X
+		c.x = c.setNZ(c.x - 1)
+	case 0x3D: // INC X
+		c.x = c.setNZ(c.x + 1)
+	case 0xDC: // DEC Y
+		c.y = c.setNZ(c.y - 1)
+	case 0xFC: // INC Y
+		c.y = c.setNZ(c.y + 1)
+	case 0x5D: // MOV X, A
+		c.x = c.setNZ(c.a)
+	case 0x7D: // MOV A, X
+		c.a = c.setNZ(c.x)
+	case 0xDD: // MOV A, Y
+		c.a = c.setNZ(c.y)
+	case 0xFD: // MOV Y, A
+		c.y = c.setNZ(c.a)
+	case 0x9D: // MOV X, SP
+		c.x = c.setNZ(c.sp)
+	case 0xBD: // MOV SP, X
+		c.sp = c.x
+	case 0x8D: // MOV Y, #i
+		c.y = c.setNZ(c.fetch())
+	case 0xCD: // MOV X, #i
+		c.x = c.setNZ(c.fetch())
+	case 0xE8: // MOV A, #i
+		c.a = c.setNZ(c.fetch())
+
+	case 0xD8: // MOV d, X
+		c.mem.write(c.dp(c.fetch()), c.x)
+	case 0xF8: // MOV X, d
+		c.x = c.setNZ(c.mem.read(c.dp(c.fetch())))
+	case 0xC9: // MOV !a, X
+		c.mem.write(c.fetch16(), c.x)
+	case 0xD9: // MOV d+Y, X
+		c.mem.write(c.dp(c.fetch()+c.y), c.x)
+	case 0xE9: // MOV X, !a
+		c.x = c.setNZ(c.mem.read(c.fetch16()))
+	case 0xF9: // MOV X, d+Y
+		c.x = c.setNZ(c.mem.read(c.dp(c.fetch() + c.y)))
+	case 0xCB: // MOV d, Y
+		c.mem.write(c.dp(c.fetch()), c.y)
+	case 0xDB: // MOV d+X, Y
+		c.mem.write(c.dp(c.fetch()+c.x), c.y)
+	case 0xEB: // MOV Y, d
+		c.y = c.setNZ(c.mem.read(c.dp(c.fetch())))
+	case 0xFB: // MOV Y, d+X
+		c.y = c.setNZ(c.mem.read(c.dp(c.fetch() + c.x)))
+	case 0xCC: // MOV !a, Y
+		c.mem.write(c.fetch16(), c.y)
+	case 0xEC: // MOV Y, !a
+		c.y = c.setNZ(c.mem.read(c.fetch16()))
+	case 0x8F: // MOV d, #i
+		v := c.fetch()
+		c.mem.write(c.dp(c.fetch()), v)
+	case 0xFA: // MOV dd, ds
+		v := c.mem.read(c.dp(c.fetch()))
+		c.mem.write(c.dp(c.fetch()), v)
+	case 0xAF: // MOV (X)+, A
+		c.mem.write(c.dp(c.x), c.a)
+		c.x++
+	case 0xBF: // MOV A, (X)+
+		c.a = c.setNZ(c.mem.read(c.dp(c.x)))
+		c.x++
+
+	case 0x0F: // BRK
+		c.push16(c.pc)
+		c.push(c.psw)
+		c.psw = (c.psw | spcFlagBreak) &^ spcFlagInterrupt
+		c.pc = c.read16(0xFFDE)
+	case 0x1F: // JMP [!a+X]
+		c.pc = c.read16(c.fetch16() + uint16(c.x))
+	case 0x3F: // CALL !a
+		addr := c.fetch16()
+		c.push16(c.pc)
+		c.pc = addr
+	case 0x4F: // PCALL u
+		addr := 0xFF00 | uint16(c.fetch())
+		c.push16(c.pc)
+		c.pc = addr
+	case 0x5F: // JMP !a
+		c.pc = c.fetch16()
+	case 0x6F: // RET
+		c.pc = c.pop16()
+	case 0x7F: // RETI
+		c.psw = c.pop()
+		c.pc = c.pop16()
+
+	case 0x9F: // XCN A
+		c.a = c.setNZ(c.a>>4 | c.a<<4)
+	case 0xCF: // MUL YA
+		product := uint16(c.y) * uint16(c.a)
+		c.setYA(product)
+		c.setNZ(c.y)
+	case 0x9E: // DIV YA, X
+		c.divide()
+	case 0xDF: // DAA
+		if c.psw&flagCarry != 0 || c.a > 0x99 {
+			c.a += 0x60
+			c.psw |= flagCarry
+		}
+
+		if c.psw&spcFlagHalfCarry != 0 || c.a&0xF > 9 {
+			c.a += 6
+		}
+
+		c.setNZ(c.a)
+	case 0xBE: // DAS
+		if c.psw&flagCarry == 0 || c.a > 0x99 {
+			c.a -= 0x60
+			c.psw &^= flagCarry
+		}
+
+		if c.psw&spcFlagHalfCarry == 0 || c.a&0xF > 9 {
+			c.a -= 6
+		}
+
+		c.setNZ(c.a)
+	case 0xEF, 0xFF: // SLEEP, STOP
+		c.halted = true
+	}
+
+	return 0
+}
+
+// bitOperation executes the instructions on single bits of memory and the carry
+func (c *spc700) bitOperation(op byte) {
+	addr, bit := c.memoryBit()
+	v := c.mem.read(addr)
+	set := v&(1<<bit) != 0
+	carry := c.psw&flagCarry != 0
+	switch op {
+	case 0x0A: // OR1 C, m.b
+		c.setFlag(flagCarry, carry || set)
+	case 0x2A: // OR1 C, /m.b
+		c.setFlag(flagCarry, carry || !set)
+	case 0x4A: // AND1 C, m.b
+		c.setFlag(flagCarry, carry && set)
+	case 0x6A: // AND1 C, /m.b
+		c.setFlag(flagCarry, carry && !set)
+	case 0x8A: // EOR1 C, m.b
+		c.setFlag(flagCarry, carry != set)
+	case 0xAA: // MOV1 C, m.b
+		c.setFlag(flagCarry, set)
+	case 0xCA: // MOV1 m.b, C
+		if carry {
+			c.mem.write(addr, v|1<<bit)
+		} else {
+			c.mem.write(addr, v&^(1<<bit))
+		}
+	case 0xEA: // NOT1 m.b
+		c.mem.write(addr, v^1<<bit)
+	}
+}
+
+// divide divides YA by X, including the results the SPC700 gives when the quotient doesn't fit in A
+func (c *spc700) divide() {
+	ya, x := uint32(c.ya()), uint32(c.x)
+	c.setFlag(flagOverflow, c.y >= c.x)
+	c.setFlag(spcFlagHalfCarry, c.y&0xF >= c.x&0xF)
+	if uint32(c.y) < x<<1 {
+		c.a, c.y = byte(ya/x), byte(ya%x)
+	} else {
+		c.a, c.y = byte(255-(ya-x<<9)/(256-x)), byte(x+(ya-x<<9)%(256-x))
+	}
+
+	c.setNZ(c.a)
+}
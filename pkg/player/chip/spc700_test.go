@@ -0,0 +1,89 @@
+package chip
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// runTestSPC700Program runs a program loaded at 0x200 on an SPC700 until it sleeps
+func runTestSPC700Program(program []byte) (*spc700, *testMemory, int) {
+	mem := &testMemory{}
+	copy(mem[0x200:], program)
+	c := &spc700{pc: 0x200, sp: 0xEF, mem: mem}
+	cycles := 0
+	for i := 0; i < 1000 && !c.halted; i++ {
+		cycles += c.step()
+	}
+
+	return c, mem, cycles
+}
+
+func TestSPC700(t *testing.T) {
+	const flags = flagNegative | flagOverflow | flagZero | flagCarry | spcFlagHalfCarry
+
+	testCases := []struct {
+		name    string
+		program []byte
+		a, x, y byte
+		flags   byte
+	}{
+		{"LoadAndTransfer", []byte{0xE8, 0x80, 0x5D, 0xFD, 0xEF}, 0x80, 0x80, 0x80, flagNegative},
+		{"AddWithCarry", []byte{0x60, 0xE8, 0x7F, 0x88, 0x01, 0xEF}, 0x80, 0, 0, flagNegative | flagOverflow | spcFlagHalfCarry},
+		{"SubtractWithBorrow", []byte{0x80, 0xE8, 0x10, 0xA8, 0x10, 0xEF}, 0, 0, 0, flagZero | flagCarry | spcFlagHalfCarry},
+		{"Compare", []byte{0xCD, 0x05, 0xC8, 0x06, 0xEF}, 0, 0x05, 0, flagNegative},
+		{"Multiply", []byte{0x8D, 0x12, 0xE8, 0x34, 0xCF, 0xEF}, 0xA8, 0, 0x03, 0},
+		{"Divide", []byte{0x8D, 0x01, 0xE8, 0x00, 0xCD, 0x10, 0x9E, 0xEF}, 0x10, 0x10, 0, spcFlagHalfCarry},
+		{"DivideOverflow", []byte{0x8D, 0xFF, 0xE8, 0xFF, 0xCD, 0x01, 0x9E, 0xEF}, 0x01, 0x01, 0xFE, flagOverflow | spcFlagHalfCarry},
+		{"DecimalAdjust", []byte{0x60, 0xE8, 0x19, 0x88, 0x28, 0xDF, 0xEF}, 0x47, 0, 0, spcFlagHalfCarry},
+		{"ExchangeNibbles", []byte{0xE8, 0x1F, 0x9F, 0xEF}, 0xF1, 0, 0, flagNegative},
+		{"Shifts", []byte{0x80, 0xE8, 0x81, 0x7C, 0xEF}, 0xC0, 0, 0, flagNegative | flagCarry},
+		{"Loop", []byte{0x8D, 0x05, 0xCD, 0x00, 0x3D, 0xFE, 0xFD, 0xEF}, 0, 0x05, 0, 0},
+		{"Call", []byte{0x3F, 0x10, 0x02, 0xEF, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0xE8, 0x42, 0x6F}, 0x42, 0, 0, 0},
+		{"Stack", []byte{0xE8, 0x42, 0x2D, 0xE8, 0x00, 0xCE, 0xEF}, 0, 0x42, 0, flagZero},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			c, _, _ := runTestSPC700Program(testCase.program)
+			assert.Equal(tt, testCase.a, c.a)
+			assert.Equal(tt, testCase.x, c.x)
+			assert.Equal(tt, testCase.y, c.y)
+			assert.Equal(tt, testCase.flags, c.psw&flags)
+		})
+	}
+}
+
+func TestSPC700_Memory(t *testing.T) {
+	c, mem, _ := runTestSPC700Program([]byte{
+		0x8F, 0x34, 0x10, // MOV $10, #$34
+		0x8F, 0x12, 0x11, // MOV $11, #$12
+		0x3A, 0x10, // INCW $10
+		0xFA, 0x10, 0x20, // MOV $20, $10
+		0x40,             // SETP
+		0x8F, 0x99, 0x10, // MOV $110, #$99
+		0x20,       // CLRP
+		0x02, 0x21, // SET1 $21.0
+		0xEF, // SLEEP
+	})
+
+	assert.Equal(t, byte(0x35), mem[0x10])
+	assert.Equal(t, byte(0x12), mem[0x11])
+	assert.Equal(t, byte(0x35), mem[0x20])
+	assert.Equal(t, byte(0x01), mem[0x21])
+	assert.Equal(t, byte(0x99), mem[0x110])
+	assert.True(t, c.halted)
+}
+
+func TestSPC700_Branch(t *testing.T) {
+	// Taken branches take two more cycles
+	_, _, notTaken := runTestSPC700Program([]byte{0x80, 0x90, 0x00, 0xEF})
+	_, _, taken := runTestSPC700Program([]byte{0x60, 0x90, 0x00, 0xEF})
+	assert.Equal(t, notTaken+2, taken)
+
+	// Sleeping stops the CPU
+	c, _, _ := runTestSPC700Program([]byte{0xEF, 0xE8, 0x01})
+	pc := c.pc
+	assert.Equal(t, 2, c.step())
+	assert.Equal(t, pc, c.pc)
+	assert.Equal(t, byte(0), c.a)
+}
@@ -0,0 +1,185 @@
+package chip
+
+import (
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+// testSPCDriver is the DSP registers the test driver writes before keying on the first voice, which plays the sample
+// from the directory at 0x300
+var testSPCDriver = [][2]byte{
+	{dspDirectory, 0x03},
+	{dspSource, 0x00},
+	{dspVolumeLeft, 0x7F},
+	{dspVolumeRight, 0x7F},
+	{dspPitchLow, 0x00},
+	{dspPitchHigh, 0x10},
+	{dspGain, 0x7F},
+	{dspMainLeft, 0x7F},
+	{dspMainRight, 0x7F},
+	{dspFlags, dspFlagEchoOff},
+	{dspKeyOn, 0x01},
+}
+
+// testSPCTextTag writes an ID666 tag in text with a length of 5 seconds and a fade of 1 second
+func testSPCTextTag(data []byte) {
+	data[0x23] = spcHasTag
+	copy(data[0x2E:], testTitle)
+	copy(data[0xA9:], "5")
+	copy(data[0xAC:], "1000")
+	copy(data[0xB1:], testArtist)
+}
+
+// testSPCBinaryTag writes an ID666 tag in binary with a length of 5 seconds and a fade of 1 second. The title is
+// missing, so it's the game instead
+func testSPCBinaryTag(data []byte) {
+	data[0x23] = spcHasTag
+	copy(data[0x4E:], testTitle)
+	data[0xA9] = 5
+	binary.LittleEndian.PutUint32(data[0xAC:], 1000)
+	copy(data[0xB0:], testArtist)
+}
+
+// newTestSPC creates an SPC which runs a driver at 0x200 that plays a square wave of 1000Hz. The sample at 0x400 has
+// a high and a low block of 16 samples, which the voice loops at the rate of the DSP
+func newTestSPC(tag func(data []byte)) []byte {
+	data := make([]byte, spcLength)
+	copy(data, spcSignature)
+	binary.LittleEndian.PutUint16(data[0x25:], 0x200)
+	data[0x2B] = 0xEF
+	if tag != nil {
+		tag(data)
+	}
+
+	ram := data[spcRAMOffset:spcDSPOffset]
+	driver := ram[0x200:]
+	for i, write := range testSPCDriver {
+		copy(driver[i*6:], []byte{0x8F, write[0], 0xF2, 0x8F, write[1], 0xF3}) // MOV $F2, #reg; MOV $F3, #v
+	}
+
+	copy(driver[len(testSPCDriver)*6:], []byte{0x2F, 0xFE}) // BRA -2
+	copy(ram[0x300:], []byte{0x00, 0x04, 0x00, 0x04})
+	copy(ram[0x400:], []byte{0xC0, 0x77, 0x77, 0x77, 0x77, 0x77, 0x77, 0x77, 0x77})
+	copy(ram[0x409:], []byte{0xC3, 0x99, 0x99, 0x99, 0x99, 0x99, 0x99, 0x99, 0x99})
+	return data
+}
+
+func TestLoadSPC(t *testing.T) {
+	testCases := []struct {
+		name   string
+		tag    func(data []byte)
+		title  string
+		artist string
+		length time.Duration
+		fade   time.Duration
+	}{
+		{"TextTag", testSPCTextTag, testTitle, testArtist, 5 * time.Second, time.Second},
+		{"BinaryTag", testSPCBinaryTag, testTitle, testArtist, 5 * time.Second, time.Second},
+		{"NoTag", nil, "", "", spcDefaultLength, spcDefaultFade},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			tune, err := LoadSPC(newTestSPC(testCase.tag))
+			require.NoError(tt, err)
+			assert.Equal(tt, testCase.title, tune.Title)
+			assert.Equal(tt, testCase.artist, tune.Artist)
+			assert.Equal(tt, "spc", tune.Format)
+			assert.Equal(tt, 1, tune.Songs)
+			assert.Equal(tt, spcSampleRate, tune.SampleRate)
+			assert.Equal(tt, testCase.length, tune.Length)
+			assert.Equal(tt, testCase.fade, tune.Fade)
+		})
+	}
+}
+
+func TestLoadSPC_Invalid(t *testing.T) {
+	testCases := []struct {
+		name string
+		data []byte
+	}{
+		{"Empty", []byte{}},
+		{"NoSignature", make([]byte, spcLength)},
+		{"Truncated", newTestSPC(nil)[:spcDSPOffset]},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			tune, err := LoadSPC(testCase.data)
+			assert.Error(tt, err)
+			assert.Nil(tt, tune)
+		})
+	}
+}
+
+func TestLoadSPC_Play(t *testing.T) {
+	tune, err := LoadSPC(newTestSPC(testSPCTextTag))
+	require.NoError(t, err)
+
+	s := NewStream(tune)
+	samples := make([][2]float64, spcSampleRate)
+	n, ok := s.Stream(samples)
+	require.True(t, ok)
+	require.Equal(t, len(samples), n)
+
+	// The square wave crosses zero twice per cycle
+	assert.InDelta(t, 2*1000, zeroCrossings(samples), 10)
+	assert.Equal(t, samples[len(samples)-1][0], samples[len(samples)-1][1])
+}
+
+func TestSPCTimer(t *testing.T) {
+	timer := &spcTimer{divider: 128, target: 2}
+	timer.clock(1024)
+	assert.Equal(t, byte(0), timer.counter)
+
+	// The counter ticks once the timer counts up to its target, and wraps after 4 bits
+	timer.enabled = true
+	timer.clock(256)
+	assert.Equal(t, byte(1), timer.counter)
+
+	timer.clock(256 * 16)
+	assert.Equal(t, byte(1), timer.counter)
+
+	// A target of 0 counts to 256
+	timer = &spcTimer{divider: 16, enabled: true}
+	timer.clock(16 * 255)
+	assert.Equal(t, byte(0), timer.counter)
+	timer.clock(16)
+	assert.Equal(t, byte(1), timer.counter)
+}
+
+func TestSPCMachine_Memory(t *testing.T) {
+	data := newTestSPC(nil)
+	ram := data[spcRAMOffset:spcDSPOffset]
+	ram[0xF1], ram[0xF2], ram[0xF4], ram[0xFA] = 0x01, dspMainLeft, 0x42, 0x01
+	data[spcDSPOffset+dspMainLeft] = 0x55
+	data[spcDSPOffset+dspKeyOn] = 0x01
+
+	tune, err := LoadSPC(data)
+	require.NoError(t, err)
+	m := tune.newEmulator(tune.Song).(*spcMachine)
+
+	// The state of the DSP and the I/O registers is restored, except for keying on voices
+	assert.Equal(t, byte(0x55), m.read(0xF3))
+	assert.Equal(t, byte(0x42), m.read(0xF4))
+	assert.Equal(t, byte(0), m.dsp.registers[dspKeyOn])
+	assert.Equal(t, dspRelease, m.dsp.voices[0].state)
+	assert.True(t, m.timers[0].enabled)
+
+	m.write(0xF2, dspMainRight)
+	m.write(0xF3, 0x66)
+	assert.Equal(t, byte(0x66), m.dsp.registers[dspMainRight])
+
+	// Reading a counter clears it
+	m.timers[0].clock(128)
+	assert.Equal(t, byte(1), m.read(0xFD))
+	assert.Equal(t, byte(0), m.read(0xFD))
+
+	// Ports are cleared through the control register
+	m.write(0xF1, 0x10)
+	assert.Equal(t, byte(0), m.read(0xF4))
+	assert.False(t, m.timers[0].enabled)
+}
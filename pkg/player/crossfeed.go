@@ -0,0 +1,78 @@
+package player
+
+import (
+	"github.com/faiface/beep"
+	"math"
+	"time"
+)
+
+const (
+	// DefaultCrossfeedLevel is the default portion of each channel which is blended into the other channel
+	DefaultCrossfeedLevel = 0.3
+
+	// DefaultCrossfeedDelay is the default delay applied to the blended channel. This roughly matches the time it
+	// takes for sound to travel from one ear to the other
+	DefaultCrossfeedDelay = 300 * time.Microsecond
+
+	// DefaultCrossfeedCutoff is the default cutoff frequency in hertz of the low-pass filter applied to the blended
+	// channel. Only low frequencies naturally reach the opposite ear
+	DefaultCrossfeedCutoff = 700
+)
+
+// Crossfeed is a beep.Streamer which blends a delayed and low-passed portion of each channel into the other channel.
+// Hard-panned chiptune channels are fatiguing to listen to on headphones and crossfeed makes them sound closer to
+// speakers. When Enabled is false, samples are passed through untouched
+type Crossfeed struct {
+	Streamer beep.Streamer
+	Enabled  bool
+
+	level   float64
+	alpha   float64
+	delay   [][2]float64
+	pos     int
+	lowpass [2]float64
+}
+
+// NewCrossfeed returns a Crossfeed which blends level (from 0 to 1) of each channel of streamer into the other channel
+func NewCrossfeed(streamer beep.Streamer, sampleRate beep.SampleRate, level float64) *Crossfeed {
+	delay := sampleRate.N(DefaultCrossfeedDelay)
+	if delay < 1 {
+		delay = 1
+	}
+
+	return &Crossfeed{
+		Streamer: streamer,
+		Enabled:  true,
+		level:    math.Max(0, math.Min(1, level)),
+		alpha:    1 - math.Exp(-2*math.Pi*DefaultCrossfeedCutoff/float64(sampleRate)),
+		delay:    make([][2]float64, delay),
+	}
+}
+
+// Stream streams from the wrapped streamer, blending the channels if Enabled is true
+func (c *Crossfeed) Stream(samples [][2]float64) (n int, ok bool) {
+	n, ok = c.Streamer.Stream(samples)
+	if !c.Enabled {
+		return n, ok
+	}
+
+	for i := range samples[:n] {
+		delayed := c.delay[c.pos]
+		c.delay[c.pos] = samples[i]
+		c.pos = (c.pos + 1) % len(c.delay)
+
+		c.lowpass[0] += c.alpha * (delayed[0] - c.lowpass[0])
+		c.lowpass[1] += c.alpha * (delayed[1] - c.lowpass[1])
+
+		left, right := samples[i][0], samples[i][1]
+		samples[i][0] = (left + c.level*c.lowpass[1]) / (1 + c.level)
+		samples[i][1] = (right + c.level*c.lowpass[0]) / (1 + c.level)
+	}
+
+	return n, ok
+}
+
+// Err propagates the wrapped streamer's error
+func (c *Crossfeed) Err() error {
+	return c.Streamer.Err()
+}
@@ -0,0 +1,61 @@
+package player
+
+import (
+	"github.com/faiface/beep"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func newConstantStreamer(left, right float64) beep.Streamer {
+	return beep.StreamerFunc(func(samples [][2]float64) (n int, ok bool) {
+		for i := range samples {
+			samples[i] = [2]float64{left, right}
+		}
+
+		return len(samples), true
+	})
+}
+
+func TestCrossfeed_Disabled(t *testing.T) {
+	crossfeed := NewCrossfeed(newConstantStreamer(1, 0), beep.SampleRate(44100), DefaultCrossfeedLevel)
+	crossfeed.Enabled = false
+
+	samples := make([][2]float64, 512)
+	n, ok := crossfeed.Stream(samples)
+	assert.Equal(t, len(samples), n)
+	assert.True(t, ok)
+
+	for _, sample := range samples {
+		assert.Equal(t, [2]float64{1, 0}, sample)
+	}
+}
+
+func TestCrossfeed_BlendsChannels(t *testing.T) {
+	crossfeed := NewCrossfeed(newConstantStreamer(1, 0), beep.SampleRate(44100), DefaultCrossfeedLevel)
+
+	// Stream long enough for the delay line and low-pass filter to settle
+	samples := make([][2]float64, 44100)
+	n, ok := crossfeed.Stream(samples)
+	assert.Equal(t, len(samples), n)
+	assert.True(t, ok)
+
+	// Before the delay has elapsed, nothing has been blended into the right channel
+	assert.Zero(t, samples[0][1])
+
+	last := samples[len(samples)-1]
+	assert.InDelta(t, 1/(1+DefaultCrossfeedLevel), last[0], 0.0001)
+	assert.InDelta(t, DefaultCrossfeedLevel/(1+DefaultCrossfeedLevel), last[1], 0.0001)
+}
+
+func TestCrossfeed_ClampsLevel(t *testing.T) {
+	crossfeed := NewCrossfeed(newConstantStreamer(1, 1), beep.SampleRate(44100), 2)
+	assert.Equal(t, 1.0, crossfeed.level)
+
+	crossfeed = NewCrossfeed(newConstantStreamer(1, 1), beep.SampleRate(44100), -1)
+	assert.Equal(t, 0.0, crossfeed.level)
+}
+
+func TestCrossfeed_Err(t *testing.T) {
+	crossfeed := NewCrossfeed(newConstantStreamer(1, 1), beep.SampleRate(44100), DefaultCrossfeedLevel)
+	assert.NoError(t, crossfeed.Err())
+}
@@ -0,0 +1,204 @@
+package player
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"github.com/broar/chipmusic-cli/pkg/chipmusic"
+	"github.com/broar/chipmusic-cli/pkg/player/chip"
+	"github.com/broar/chipmusic-cli/pkg/player/tracker"
+	"github.com/faiface/beep"
+	"github.com/faiface/beep/flac"
+	"github.com/faiface/beep/mp3"
+	"github.com/faiface/beep/vorbis"
+	"github.com/faiface/beep/wav"
+	"io"
+	"io/ioutil"
+	"sync"
+)
+
+const (
+	// oggCapturePattern is the magic number at the start of every page of an OGG file
+	oggCapturePattern = "OggS"
+
+	// moduleSampleRate is the sample rate tracker modules are rendered at
+	moduleSampleRate beep.SampleRate = 44100
+)
+
+var (
+	decodersMux sync.RWMutex
+
+	// decoders maps the file types a TrackPlayer is able to decode to their decoder
+	decoders = map[chipmusic.AudioFileType]Decoder{}
+
+	// decoderOrder is the order file types were registered in so SupportedFormats is stable
+	decoderOrder []chipmusic.AudioFileType
+)
+
+func init() {
+	RegisterDecoder(chipmusic.AudioFileTypeMP3, mp3.Decode)
+	RegisterDecoder(chipmusic.AudioFileTypeWAV, func(rc io.ReadCloser) (beep.StreamSeekCloser, beep.Format, error) {
+		return wav.Decode(rc)
+	})
+	RegisterDecoder(chipmusic.AudioFileTypeOGG, decodeVorbis)
+	RegisterDecoder(chipmusic.AudioFileTypeFLAC, func(rc io.ReadCloser) (beep.StreamSeekCloser, beep.Format, error) {
+		return flac.Decode(rc)
+	})
+
+	for _, fileType := range []chipmusic.AudioFileType{
+		chipmusic.AudioFileTypeMOD,
+		chipmusic.AudioFileTypeS3M,
+		chipmusic.AudioFileTypeXM,
+		chipmusic.AudioFileTypeIT,
+	} {
+		RegisterDecoder(fileType, decodeModule)
+	}
+
+	for _, fileType := range []chipmusic.AudioFileType{
+		chipmusic.AudioFileTypeNSF,
+		chipmusic.AudioFileTypeSPC,
+		chipmusic.AudioFileTypeSID,
+	} {
+		RegisterDecoder(fileType, decodeChiptune)
+	}
+}
+
+// Decoder decodes audio read from rc. Closing the returned stream must close rc. If rc is also an io.Seeker, the stream
+// should support seeking
+type Decoder func(rc io.ReadCloser) (beep.StreamSeekCloser, beep.Format, error)
+
+// RegisterDecoder registers a decoder for a file type so tracks of that type can be played, e.g. for chiptune formats
+// beep does not support. Registering a decoder for a file type which already has one replaces it. Like sql.Register,
+// this is meant to be called during initialization and panics if fileType is empty or decoder is nil
+func RegisterDecoder(fileType chipmusic.AudioFileType, decoder Decoder) {
+	if fileType == "" {
+		panic("player: file type of decoder cannot be empty")
+	}
+
+	if decoder == nil {
+		panic(fmt.Sprintf("player: decoder for %s cannot be nil", fileType))
+	}
+
+	decodersMux.Lock()
+	defer decodersMux.Unlock()
+	if _, ok := decoders[fileType]; !ok {
+		decoderOrder = append(decoderOrder, fileType)
+	}
+
+	decoders[fileType] = decoder
+}
+
+// SupportedFormats returns the audio file types which a TrackPlayer is able to decode
+func SupportedFormats() []chipmusic.AudioFileType {
+	decodersMux.RLock()
+	defer decodersMux.RUnlock()
+
+	formats := make([]chipmusic.AudioFileType, len(decoderOrder))
+	copy(formats, decoderOrder)
+	return formats
+}
+
+// IsSupportedFormat returns true if a TrackPlayer is able to decode the audio file type
+func IsSupportedFormat(fileType chipmusic.AudioFileType) bool {
+	decodersMux.RLock()
+	defer decodersMux.RUnlock()
+
+	_, ok := decoders[fileType]
+	return ok
+}
+
+// Decode decodes the audio of a track with the decoder registered for its FileType. Closing the returned stream closes
+// the Reader of the track
+func Decode(track *chipmusic.Track) (beep.StreamSeekCloser, beep.Format, error) {
+	decodersMux.RLock()
+	decoder, ok := decoders[track.FileType]
+	decodersMux.RUnlock()
+
+	if !ok {
+		return beep.StreamSeekCloser(nil), beep.Format{}, fmt.Errorf("%w: %s", ErrUnknownFileFormat, track.FileType)
+	}
+
+	return decoder(track.Reader)
+}
+
+// decodeVorbis decodes OGG/Vorbis audio. The vorbis decoder searches for the first page forever when the audio isn't
+// an OGG file, so the capture pattern which starts every OGG file is checked first
+func decodeVorbis(rc io.ReadCloser) (beep.StreamSeekCloser, beep.Format, error) {
+	pattern := make([]byte, len(oggCapturePattern))
+	rs, ok := rc.(io.ReadSeeker)
+	if !ok {
+		// Without seeking, the pattern is peeked at instead so the decoder still reads it
+		buffered := bufio.NewReader(rc)
+		peeked, err := buffered.Peek(len(pattern))
+		if err != nil {
+			return nil, beep.Format{}, fmt.Errorf("failed to read OGG capture pattern: %w", err)
+		}
+
+		if string(peeked) != oggCapturePattern {
+			return nil, beep.Format{}, errors.New("missing OGG capture pattern")
+		}
+
+		return vorbis.Decode(&struct {
+			io.Reader
+			io.Closer
+		}{buffered, rc})
+	}
+
+	if _, err := io.ReadFull(rs, pattern); err != nil {
+		return nil, beep.Format{}, fmt.Errorf("failed to read OGG capture pattern: %w", err)
+	}
+
+	if string(pattern) != oggCapturePattern {
+		return nil, beep.Format{}, errors.New("missing OGG capture pattern")
+	}
+
+	if _, err := rs.Seek(0, io.SeekStart); err != nil {
+		return nil, beep.Format{}, fmt.Errorf("failed to seek to start of track: %w", err)
+	}
+
+	return vorbis.Decode(rc)
+}
+
+// decodeModule decodes a tracker module by rendering it. Modules are small, so the whole module is read and rc is closed
+// right away. The format is detected from the content since modules are often served with the wrong extension, but
+// 15-sample MODs have no signature to detect
+func decodeModule(rc io.ReadCloser) (beep.StreamSeekCloser, beep.Format, error) {
+	defer rc.Close()
+
+	data, err := ioutil.ReadAll(rc)
+	if err != nil {
+		return nil, beep.Format{}, fmt.Errorf("failed to read module: %w", err)
+	}
+
+	module, err := tracker.Load(data)
+	if errors.Is(err, tracker.ErrUnknownFormat) {
+		module, err = tracker.LoadMOD(data)
+	}
+
+	if err != nil {
+		return nil, beep.Format{}, fmt.Errorf("failed to load module: %w", err)
+	}
+
+	format := beep.Format{SampleRate: moduleSampleRate, NumChannels: 2, Precision: 2}
+	return tracker.NewStream(module, int(moduleSampleRate)), format, nil
+}
+
+// decodeChiptune decodes a rip of the music of a game by emulating the sound chip it was made for. Like modules, the
+// whole rip is read and rc is closed right away, and the format is detected from the content. Each format is rendered
+// at the sample rate of its chip
+func decodeChiptune(rc io.ReadCloser) (beep.StreamSeekCloser, beep.Format, error) {
+	defer rc.Close()
+
+	data, err := ioutil.ReadAll(rc)
+	if err != nil {
+		return nil, beep.Format{}, fmt.Errorf("failed to read chiptune: %w", err)
+	}
+
+	tune, err := chip.Load(data)
+	if err != nil {
+		return nil, beep.Format{}, fmt.Errorf("failed to load chiptune: %w", err)
+	}
+
+	format := beep.Format{SampleRate: beep.SampleRate(tune.SampleRate), NumChannels: 2, Precision: 2}
+	return chip.NewStream(tune), format, nil
+}
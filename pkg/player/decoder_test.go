@@ -0,0 +1,190 @@
+package player
+
+import (
+	"bytes"
+	"errors"
+	"github.com/broar/chipmusic-cli/pkg/chipmusic"
+	"github.com/faiface/beep"
+	"github.com/faiface/beep/wav"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+// unregisterDecoder removes a decoder registered by a test so other tests see the built-in decoders only
+func unregisterDecoder(fileType chipmusic.AudioFileType) {
+	decodersMux.Lock()
+	defer decodersMux.Unlock()
+
+	delete(decoders, fileType)
+	for i, registered := range decoderOrder {
+		if registered == fileType {
+			decoderOrder = append(decoderOrder[:i], decoderOrder[i+1:]...)
+			break
+		}
+	}
+}
+
+func TestRegisterDecoder(t *testing.T) {
+	const fileType chipmusic.AudioFileType = "vgm"
+	defer unregisterDecoder(fileType)
+
+	decodeErr := errors.New("not implemented")
+	RegisterDecoder(fileType, func(rc io.ReadCloser) (beep.StreamSeekCloser, beep.Format, error) {
+		return nil, beep.Format{}, decodeErr
+	})
+
+	assert.True(t, IsSupportedFormat(fileType))
+	assert.Equal(t, fileType, SupportedFormats()[len(SupportedFormats())-1])
+
+	_, _, err := Decode(&chipmusic.Track{FileType: fileType})
+	assert.Equal(t, decodeErr, err)
+
+	// Registering the file type again replaces the decoder without listing it twice
+	formats := len(SupportedFormats())
+	RegisterDecoder(fileType, func(rc io.ReadCloser) (beep.StreamSeekCloser, beep.Format, error) {
+		return nil, beep.Format{}, nil
+	})
+
+	_, _, err = Decode(&chipmusic.Track{FileType: fileType})
+	assert.NoError(t, err)
+	assert.Len(t, SupportedFormats(), formats)
+}
+
+func TestRegisterDecoder_Invalid(t *testing.T) {
+	decoder := func(rc io.ReadCloser) (beep.StreamSeekCloser, beep.Format, error) {
+		return nil, beep.Format{}, nil
+	}
+
+	assert.Panics(t, func() { RegisterDecoder("", decoder) })
+	assert.Panics(t, func() { RegisterDecoder("vgm", nil) })
+	assert.False(t, IsSupportedFormat("vgm"))
+}
+
+func TestDecodeVorbis_NotSeekable(t *testing.T) {
+	testCases := []struct {
+		name    string
+		content []byte
+	}{
+		{"NotOGG", []byte("some.content")},
+		{"Truncated", []byte("Og")},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			_, _, err := decodeVorbis(ioutil.NopCloser(bytes.NewReader(testCase.content)))
+			assert.Error(tt, err)
+		})
+	}
+}
+
+func TestDecode(t *testing.T) {
+	file, err := os.Open(testAudio)
+	require.NoError(t, err)
+
+	stream, format, err := Decode(&chipmusic.Track{FileType: chipmusic.AudioFileTypeMP3, Reader: file})
+	require.NoError(t, err)
+
+	defer stream.Close()
+
+	assert.True(t, stream.Len() > 0)
+	assert.Equal(t, 2, format.NumChannels)
+}
+
+func TestSupportedFormats(t *testing.T) {
+	formats := SupportedFormats()
+	assert.Contains(t, formats, chipmusic.AudioFileTypeMP3)
+
+	// Modifying the returned slice should not modify the supported formats
+	formats[0] = "some.type"
+	assert.Contains(t, SupportedFormats(), chipmusic.AudioFileTypeMP3)
+}
+
+func TestIsSupportedFormat(t *testing.T) {
+	assert.True(t, IsSupportedFormat(chipmusic.AudioFileTypeMP3))
+	assert.True(t, IsSupportedFormat(chipmusic.AudioFileTypeWAV))
+	assert.True(t, IsSupportedFormat(chipmusic.AudioFileTypeOGG))
+	assert.True(t, IsSupportedFormat(chipmusic.AudioFileTypeFLAC))
+	assert.True(t, IsSupportedFormat(chipmusic.AudioFileTypeMOD))
+	assert.True(t, IsSupportedFormat(chipmusic.AudioFileTypeIT))
+	assert.True(t, IsSupportedFormat(chipmusic.AudioFileTypeNSF))
+	assert.True(t, IsSupportedFormat(chipmusic.AudioFileTypeSPC))
+	assert.True(t, IsSupportedFormat(chipmusic.AudioFileTypeSID))
+	assert.False(t, IsSupportedFormat("some.type"))
+}
+
+func TestDecode_WAV(t *testing.T) {
+	file, err := ioutil.TempFile("", "chipmusic-*.wav")
+	require.NoError(t, err)
+
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	encoded := beep.Format{SampleRate: 44100, NumChannels: 1, Precision: 2}
+	require.NoError(t, wav.Encode(file, beep.Silence(encoded.SampleRate.N(time.Second)), encoded))
+
+	_, err = file.Seek(0, io.SeekStart)
+	require.NoError(t, err)
+
+	stream, format, err := Decode(&chipmusic.Track{FileType: chipmusic.AudioFileTypeWAV, Reader: file})
+	require.NoError(t, err)
+
+	defer stream.Close()
+
+	assert.Equal(t, encoded.SampleRate.N(time.Second), stream.Len())
+	assert.Equal(t, encoded, format)
+}
+
+func TestDecode_Module(t *testing.T) {
+	// A Soundtracker module with one empty pattern, which has no signature to detect it by
+	const patternLength = 64 * 4 * 4
+	content := make([]byte, 20+15*30+2+128+patternLength)
+	content[20+15*30] = 1
+
+	track := &chipmusic.Track{
+		FileType: chipmusic.AudioFileTypeMOD,
+		Reader:   &chipmusic.ReadSeekNopCloser{Reader: bytes.NewReader(content)},
+	}
+
+	stream, format, err := Decode(track)
+	require.NoError(t, err)
+
+	defer stream.Close()
+
+	// 64 rows of 6 ticks at 125 ticks per 2.5 seconds
+	assert.Equal(t, beep.Format{SampleRate: moduleSampleRate, NumChannels: 2, Precision: 2}, format)
+	assert.Equal(t, moduleSampleRate.N(64*6*20*time.Millisecond), stream.Len())
+}
+
+func TestDecode_Chiptune(t *testing.T) {
+	// An NSF with one song, which loads at 0x8000 and returns straight away from init and play
+	content := make([]byte, 0x80+1)
+	copy(content, "NESM\x1a")
+	content[0x05], content[0x06], content[0x07] = 1, 1, 1
+	content[0x09], content[0x0B], content[0x0D] = 0x80, 0x80, 0x80
+	content[0x80] = 0x60
+
+	track := &chipmusic.Track{
+		FileType: chipmusic.AudioFileTypeNSF,
+		Reader:   &chipmusic.ReadSeekNopCloser{Reader: bytes.NewReader(content)},
+	}
+
+	stream, format, err := Decode(track)
+	require.NoError(t, err)
+
+	defer stream.Close()
+
+	// NSFs play for two and a half minutes and then fade out
+	assert.Equal(t, beep.Format{SampleRate: 44100, NumChannels: 2, Precision: 2}, format)
+	assert.Equal(t, format.SampleRate.N(158*time.Second), stream.Len())
+}
+
+func TestDecode_UnknownFileFormat(t *testing.T) {
+	stream, _, err := Decode(&chipmusic.Track{FileType: "some.type"})
+	assert.True(t, errors.Is(err, ErrUnknownFileFormat))
+	assert.Nil(t, stream)
+}
@@ -0,0 +1,66 @@
+package player
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// DefaultDeviceInterval is how often the default audio device is checked for changes
+const DefaultDeviceInterval = 2 * time.Second
+
+// ErrDeviceDetectionUnsupported is returned when the default audio device cannot be detected on this system
+var ErrDeviceDetectionUnsupported = errors.New("detecting the default audio device is not supported on this system")
+
+// DeviceDetector detects when the default audio device of the system changes, e.g. because headphones were plugged in
+// or a Bluetooth device connected. The speaker keeps playing on the device it was initialized with, so playback must be
+// reinitialized to move to the new device
+type DeviceDetector struct {
+	interval time.Duration
+
+	// device returns the name of the default audio device
+	device func() (string, error)
+}
+
+// NewDeviceDetector creates a DeviceDetector which checks the default audio device every interval
+func NewDeviceDetector(interval time.Duration) *DeviceDetector {
+	return &DeviceDetector{
+		interval: interval,
+		device:   DefaultDevice,
+	}
+}
+
+// Watch returns a channel receiving the name of the default audio device each time it changes. The channel is closed
+// once ctx is done. Errors checking the device are ignored since the device may briefly be unavailable while it changes
+func (d *DeviceDetector) Watch(ctx context.Context) <-chan string {
+	changes := make(chan string)
+	current, _ := d.device()
+	go func() {
+		defer close(changes)
+
+		ticker := time.NewTicker(d.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			device, err := d.device()
+			if err != nil || device == "" || device == current {
+				continue
+			}
+
+			current = device
+			select {
+			case changes <- device:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return changes
+}
@@ -0,0 +1,45 @@
+// +build linux
+
+package player
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// DefaultDevice returns the name of the default audio device. The speaker plays through the default ALSA device, which
+// PulseAudio and PipeWire route to their default sink, so the default sink is asked for with pactl. Without a sound
+// server, the default ALSA device is fixed and ErrDeviceDetectionUnsupported is returned
+func DefaultDevice() (string, error) {
+	if _, err := exec.LookPath("pactl"); err != nil {
+		return "", ErrDeviceDetectionUnsupported
+	}
+
+	cmd := exec.Command("pactl", "info")
+
+	// pactl translates its output, so the C locale keeps the field names stable
+	cmd.Env = append(os.Environ(), "LC_ALL=C")
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get sound server info: %w", err)
+	}
+
+	return parseDefaultSink(output)
+}
+
+// parseDefaultSink returns the name of the default sink in the output of pactl info
+func parseDefaultSink(output []byte) (string, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		if sink := strings.TrimPrefix(scanner.Text(), "Default Sink:"); sink != scanner.Text() {
+			return strings.TrimSpace(sink), nil
+		}
+	}
+
+	return "", errors.New("sound server info has no default sink")
+}
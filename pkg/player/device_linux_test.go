@@ -0,0 +1,46 @@
+// +build linux
+
+package player
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseDefaultSink(t *testing.T) {
+	testCases := []struct {
+		name        string
+		output      string
+		expected    string
+		expectError bool
+	}{
+		{
+			name:     "PulseAudio",
+			output:   "Server Name: pulseaudio\nDefault Sink: alsa_output.pci-0000_00_1f.3.analog-stereo\nDefault Source: alsa_input.pci-0000_00_1f.3.analog-stereo\n",
+			expected: "alsa_output.pci-0000_00_1f.3.analog-stereo",
+		},
+		{
+			name:     "PipeWire",
+			output:   "Server Name: PulseAudio (on PipeWire 0.3.65)\nDefault Sink: bluez_output.00_1B_66_AA_BB_CC.1\n",
+			expected: "bluez_output.00_1B_66_AA_BB_CC.1",
+		},
+		{
+			name:        "NoDefaultSink",
+			output:      "Server Name: pulseaudio\n",
+			expectError: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			sink, err := parseDefaultSink([]byte(testCase.output))
+			if testCase.expectError {
+				assert.Error(tt, err)
+				return
+			}
+
+			assert.NoError(tt, err)
+			assert.Equal(tt, testCase.expected, sink)
+		})
+	}
+}
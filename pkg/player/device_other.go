@@ -0,0 +1,9 @@
+// +build !linux
+
+package player
+
+// DefaultDevice returns the name of the default audio device. Detecting it is only supported on Linux, so
+// ErrDeviceDetectionUnsupported is always returned
+func DefaultDevice() (string, error) {
+	return "", ErrDeviceDetectionUnsupported
+}
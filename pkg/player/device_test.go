@@ -0,0 +1,49 @@
+package player
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDeviceDetector_Watch(t *testing.T) {
+	mux := sync.Mutex{}
+	device, err := "speakers", error(nil)
+	detector := &DeviceDetector{
+		interval: time.Millisecond,
+		device: func() (string, error) {
+			mux.Lock()
+			defer mux.Unlock()
+			return device, err
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	changes := detector.Watch(ctx)
+
+	// The device is briefly unavailable while it changes
+	mux.Lock()
+	device, err = "", errors.New("no sound server")
+	mux.Unlock()
+	time.Sleep(10 * time.Millisecond)
+
+	mux.Lock()
+	device, err = "headphones", nil
+	mux.Unlock()
+
+	select {
+	case changed := <-changes:
+		assert.Equal(t, "headphones", changed)
+	case <-time.After(defaultTestTimeout):
+		require.FailNow(t, "device change was not detected")
+	}
+
+	cancel()
+	for range changes {
+		require.FailNow(t, "only one device change should be detected")
+	}
+}
@@ -0,0 +1,115 @@
+package player
+
+import (
+	"github.com/broar/chipmusic-cli/pkg/chipmusic"
+	"time"
+)
+
+const (
+	// DefaultEventBuffer is the number of events buffered on the channel returned by Events
+	DefaultEventBuffer = 64
+)
+
+// PlayerEvent is an interface for everything emitted on the channel returned by TrackPlayer.Events. Receivers should
+// use a type switch to handle the events they are interested in and ignore the rest
+type PlayerEvent interface {
+	// Name returns the name of the event
+	Name() string
+}
+
+// TrackStarted is emitted when a track or jingle starts playing
+type TrackStarted struct {
+	// Track is the track which started playing. It is nil for jingles
+	Track *chipmusic.Track
+
+	// Offset is where in the track playback started
+	Offset time.Duration
+
+	// Length is how long the whole track is
+	Length time.Duration
+}
+
+func (e TrackStarted) Name() string {
+	return "track-started"
+}
+
+// Paused is emitted when the current track is paused
+type Paused struct {
+	Position time.Duration
+}
+
+func (e Paused) Name() string {
+	return "paused"
+}
+
+// Resumed is emitted when the current track is unpaused
+type Resumed struct {
+	Position time.Duration
+}
+
+func (e Resumed) Name() string {
+	return "resumed"
+}
+
+// Stopped is emitted when the current track is stopped and rewound to its start
+type Stopped struct{}
+
+func (e Stopped) Name() string {
+	return "stopped"
+}
+
+// Finished is emitted when the current track played to its end, right before the next queued track starts or Done is
+// closed. It isn't emitted for tracks replaced or closed before their end
+type Finished struct {
+	// Track is the track which finished playing. It is nil for jingles
+	Track *chipmusic.Track
+}
+
+func (e Finished) Name() string {
+	return "finished"
+}
+
+// Looped is emitted when looping of the current track is turned on or off
+type Looped struct {
+	Looping bool
+}
+
+func (e Looped) Name() string {
+	return "looped"
+}
+
+// QueueChanged is emitted when tracks are added to or removed from the queue, including when the next track starts
+type QueueChanged struct {
+	// Queue is the tracks in the queue in the order they will be played
+	Queue []*chipmusic.Track
+
+	// Lengths is how long each track in the queue plays for, from where it starts to its end
+	Lengths []time.Duration
+}
+
+func (e QueueChanged) Name() string {
+	return "queue-changed"
+}
+
+// Error is emitted when the current track fails while playing, e.g. because its audio is corrupt past the start
+type Error struct {
+	Err error
+}
+
+func (e Error) Name() string {
+	return "error"
+}
+
+// PriorityRaised is emitted when the speaker first streams audio after being initialized if the priority of its thread
+// is raised, see WithRealtimePriority
+type PriorityRaised struct {
+	// Priority is how far the priority was raised. It is empty if it couldn't be
+	Priority ThreadPriority
+
+	// Err is why the priority couldn't be raised
+	Err error
+}
+
+func (e PriorityRaised) Name() string {
+	return "priority-raised"
+}
@@ -0,0 +1,86 @@
+package player
+
+import (
+	"github.com/broar/chipmusic-cli/pkg/chipmusic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestEvents(t *testing.T) {
+	tp, err := NewTrackPlayer()
+	require.NoError(t, err)
+
+	defer tp.Close()
+
+	file, err := os.Open(testAudio)
+	require.NoError(t, err)
+
+	track := &chipmusic.Track{Title: "some.title", FileType: chipmusic.AudioFileTypeMP3, Reader: file}
+	defer track.Close()
+
+	require.NoError(t, tp.PlayFrom(track, 100*time.Millisecond))
+	tp.Pause()
+	tp.SetPaused(false)
+	tp.SetPaused(false)
+	tp.Loop()
+	tp.Loop()
+	require.NoError(t, tp.Stop())
+	tp.SetPaused(false)
+	require.NoError(t, tp.Skip())
+
+	select {
+	case <-tp.Done():
+	case <-time.After(defaultTestTimeout):
+		require.FailNow(t, "track did not finish playing")
+	}
+
+	var names []string
+	for len(tp.Events()) > 0 {
+		event := <-tp.Events()
+		names = append(names, event.Name())
+
+		switch event := event.(type) {
+		case TrackStarted:
+			assert.Equal(t, track, event.Track)
+			assert.Equal(t, 100*time.Millisecond, event.Offset)
+		case Finished:
+			assert.Equal(t, track, event.Track)
+		}
+	}
+
+	// Unpausing a track which isn't paused doesn't emit anything
+	expected := []string{"track-started", "paused", "resumed", "looped", "looped", "stopped", "resumed", "finished"}
+	assert.Equal(t, expected, names)
+}
+
+func TestEvents_Jingle(t *testing.T) {
+	tp, err := NewTrackPlayer()
+	require.NoError(t, err)
+
+	defer tp.Close()
+
+	require.NoError(t, tp.PlayJingle(Jingle{{Frequency: 440, Duration: 10 * time.Millisecond}}))
+
+	select {
+	case <-tp.Done():
+	case <-time.After(defaultTestTimeout):
+		require.FailNow(t, "jingle did not finish playing")
+	}
+
+	assert.Equal(t, TrackStarted{Length: 10 * time.Millisecond}, <-tp.Events())
+	assert.Equal(t, Finished{}, <-tp.Events())
+}
+
+func TestEvents_DropsWhenBufferIsFull(t *testing.T) {
+	tp, err := NewTrackPlayer()
+	require.NoError(t, err)
+
+	for i := 0; i < DefaultEventBuffer+1; i++ {
+		tp.emit(Stopped{})
+	}
+
+	assert.Len(t, tp.Events(), DefaultEventBuffer)
+}
@@ -0,0 +1,51 @@
+package player
+
+import (
+	"github.com/faiface/beep"
+	"time"
+)
+
+// FadeOut is a beep.Streamer which ramps the volume of a streamer down to silence and then ends, even if the wrapped
+// streamer has samples left
+type FadeOut struct {
+	Streamer beep.Streamer
+
+	length int
+	pos    int
+}
+
+// NewFadeOut returns a FadeOut which silences streamer over d
+func NewFadeOut(streamer beep.Streamer, sampleRate beep.SampleRate, d time.Duration) *FadeOut {
+	length := sampleRate.N(d)
+	if length < 1 {
+		length = 1
+	}
+
+	return &FadeOut{Streamer: streamer, length: length}
+}
+
+// Stream streams from the wrapped streamer with a linearly decreasing gain until the fade is over
+func (f *FadeOut) Stream(samples [][2]float64) (n int, ok bool) {
+	if f.pos >= f.length {
+		return 0, false
+	}
+
+	if remaining := f.length - f.pos; len(samples) > remaining {
+		samples = samples[:remaining]
+	}
+
+	n, ok = f.Streamer.Stream(samples)
+	for i := range samples[:n] {
+		gain := 1 - float64(f.pos)/float64(f.length)
+		samples[i][0] *= gain
+		samples[i][1] *= gain
+		f.pos++
+	}
+
+	return n, ok
+}
+
+// Err propagates the error of the wrapped streamer
+func (f *FadeOut) Err() error {
+	return f.Streamer.Err()
+}
@@ -0,0 +1,50 @@
+package player
+
+import (
+	"github.com/faiface/beep"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestFadeOut_Ramp(t *testing.T) {
+	fade := NewFadeOut(newConstantStreamer(1, -1), beep.SampleRate(1000), 100*time.Millisecond)
+
+	samples := make([][2]float64, 512)
+	n, ok := fade.Stream(samples)
+	assert.Equal(t, 100, n, "expected the fade to stop after its length")
+	assert.True(t, ok)
+
+	assert.Equal(t, [2]float64{1, -1}, samples[0])
+	assert.InDelta(t, 0.5, samples[50][0], 0.0001)
+	assert.InDelta(t, -0.5, samples[50][1], 0.0001)
+	for i := 1; i < n; i++ {
+		assert.True(t, samples[i][0] < samples[i-1][0], "expected the gain to decrease")
+	}
+
+	n, ok = fade.Stream(samples)
+	assert.Zero(t, n)
+	assert.False(t, ok, "expected the fade to end the stream")
+}
+
+func TestFadeOut_StreamerEndsFirst(t *testing.T) {
+	streamer := beep.Take(10, newConstantStreamer(1, 1))
+	fade := NewFadeOut(streamer, beep.SampleRate(1000), time.Second)
+
+	samples := make([][2]float64, 512)
+	n, ok := fade.Stream(samples)
+	assert.Equal(t, 10, n)
+	assert.True(t, ok)
+
+	_, ok = fade.Stream(samples)
+	assert.False(t, ok)
+	assert.NoError(t, fade.Err())
+}
+
+func TestNewFadeOut_ZeroDuration(t *testing.T) {
+	fade := NewFadeOut(newConstantStreamer(1, 1), beep.SampleRate(1000), 0)
+
+	samples := make([][2]float64, 4)
+	n, _ := fade.Stream(samples)
+	assert.Equal(t, 1, n)
+}
@@ -0,0 +1,75 @@
+package player
+
+import (
+	"fmt"
+	"github.com/broar/chipmusic-cli/pkg/chipmusic"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Idents are short clips played between tracks, e.g. station idents, to make a session feel like a radio station
+type Idents struct {
+	paths  []string
+	random *rand.Rand
+
+	// last is the index of the clip opened last so the same clip isn't played twice in a row. It is -1 before the
+	// first clip is opened
+	last int
+}
+
+// LoadIdents finds the clips in dir which a TrackPlayer is able to decode. Clips are picked at random using random.
+// Subdirectories and files of other types are ignored
+func LoadIdents(dir string, random *rand.Rand) (*Idents, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ident directory %s: %w", dir, err)
+	}
+
+	var paths []string
+	for _, entry := range entries {
+		if entry.IsDir() || !IsSupportedFormat(chipmusic.FileTypeFromPath(entry.Name())) {
+			continue
+		}
+
+		paths = append(paths, filepath.Join(dir, entry.Name()))
+	}
+
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no idents found in %s", dir)
+	}
+
+	sort.Strings(paths)
+	return &Idents{paths: paths, random: random, last: -1}, nil
+}
+
+// Len returns the number of clips
+func (i *Idents) Len() int {
+	return len(i.paths)
+}
+
+// Next opens a random clip as a track titled after its file name. Closing the track closes the clip
+func (i *Idents) Next() (*chipmusic.Track, error) {
+	next := i.random.Intn(len(i.paths))
+	if len(i.paths) > 1 && next == i.last {
+		// Picking from the other clips keeps every clip equally likely
+		next = (next + 1 + i.random.Intn(len(i.paths)-1)) % len(i.paths)
+	}
+
+	i.last = next
+	path := i.paths[next]
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ident %s: %w", path, err)
+	}
+
+	name := filepath.Base(path)
+	return &chipmusic.Track{
+		Title:    strings.TrimSuffix(name, filepath.Ext(name)),
+		Reader:   file,
+		FileType: chipmusic.FileTypeFromPath(path),
+	}, nil
+}
@@ -0,0 +1,91 @@
+package player
+
+import (
+	"github.com/broar/chipmusic-cli/pkg/chipmusic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newIdentDir creates a directory with files with names, which is removed by the returned function
+func newIdentDir(t *testing.T, names ...string) (string, func()) {
+	dir, err := ioutil.TempDir("", "chipmusic-idents-*")
+	require.NoError(t, err)
+
+	for _, name := range names {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte("some.content"), 0644))
+	}
+
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+func TestLoadIdents(t *testing.T) {
+	dir, remove := newIdentDir(t, "station.mp3", "jingle.WAV", "notes.txt", "cover")
+	defer remove()
+
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "old.mp3"), 0755))
+
+	idents, err := LoadIdents(dir, rand.New(rand.NewSource(1)))
+	require.NoError(t, err)
+	assert.Equal(t, 2, idents.Len())
+
+	track, err := idents.Next()
+	require.NoError(t, err)
+
+	defer track.Close()
+
+	assert.Contains(t, []string{"station", "jingle"}, track.Title)
+	assert.Contains(t, []chipmusic.AudioFileType{chipmusic.AudioFileTypeMP3, chipmusic.AudioFileTypeWAV}, track.FileType)
+
+	content, err := ioutil.ReadAll(track.Reader)
+	require.NoError(t, err)
+	assert.Equal(t, "some.content", string(content))
+}
+
+func TestLoadIdents_Invalid(t *testing.T) {
+	empty, remove := newIdentDir(t, "notes.txt")
+	defer remove()
+
+	testCases := []struct {
+		name string
+		dir  string
+	}{
+		{"NoIdents", empty},
+		{"Missing", filepath.Join(empty, "missing")},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			idents, err := LoadIdents(testCase.dir, rand.New(rand.NewSource(1)))
+			assert.Error(tt, err)
+			assert.Nil(tt, idents)
+		})
+	}
+}
+
+func TestIdents_Next(t *testing.T) {
+	dir, remove := newIdentDir(t, "a.mp3", "b.mp3", "c.mp3")
+	defer remove()
+
+	idents, err := LoadIdents(dir, rand.New(rand.NewSource(1)))
+	require.NoError(t, err)
+
+	// The same clip is never played twice in a row, but every clip is played eventually
+	played := map[string]bool{}
+	last := ""
+	for i := 0; i < 30; i++ {
+		track, err := idents.Next()
+		require.NoError(t, err)
+		track.Close()
+
+		assert.NotEqual(t, last, track.Title)
+		last = track.Title
+		played[track.Title] = true
+	}
+
+	assert.Len(t, played, 3)
+}
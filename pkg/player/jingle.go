@@ -0,0 +1,200 @@
+package player
+
+import (
+	"errors"
+	"github.com/faiface/beep"
+	"math"
+	"time"
+)
+
+const (
+	// jingleSampleRate is the sample rate jingles are synthesized at
+	jingleSampleRate beep.SampleRate = 44100
+
+	// jingleAmplitude keeps jingles well below the level of most tracks
+	jingleAmplitude = 0.2
+
+	// jingleRamp is how long each note takes to rise and fall so notes don't click
+	jingleRamp = 3 * time.Millisecond
+)
+
+var (
+	// StartupJingle is an arpeggio rising up a C major chord
+	StartupJingle = Jingle{
+		{Frequency: 523.25, Duration: 70 * time.Millisecond},
+		{Frequency: 659.25, Duration: 70 * time.Millisecond},
+		{Frequency: 783.99, Duration: 70 * time.Millisecond},
+		{Frequency: 1046.50, Duration: 210 * time.Millisecond},
+	}
+
+	// ShutdownJingle is an arpeggio falling down a C major chord
+	ShutdownJingle = Jingle{
+		{Frequency: 1046.50, Duration: 70 * time.Millisecond},
+		{Frequency: 783.99, Duration: 70 * time.Millisecond},
+		{Frequency: 659.25, Duration: 70 * time.Millisecond},
+		{Frequency: 523.25, Duration: 210 * time.Millisecond},
+	}
+)
+
+// Waveform is the shape of the wave a Note is played with
+type Waveform int
+
+const (
+	// WaveformSquare is a square wave with a 50% duty cycle, like the pulse channels of a Game Boy or NES
+	WaveformSquare Waveform = iota
+
+	// WaveformTriangle is a triangle wave, like the triangle channel of an NES
+	WaveformTriangle
+
+	// WaveformNoise is white noise which changes Frequency times per second, like the noise channel of an NES
+	WaveformNoise
+)
+
+// Note is a note of a Jingle. A Frequency of 0 is a rest
+type Note struct {
+	Frequency float64
+	Duration  time.Duration
+	Waveform  Waveform
+
+	// Pan moves the note from both channels towards the left channel at -1 or the right channel at 1
+	Pan float64
+}
+
+// Jingle is a short melody in the style of an old sound chip, played on square waves unless its notes say otherwise
+type Jingle []Note
+
+// Duration returns how long the jingle plays for
+func (j Jingle) Duration() time.Duration {
+	var total time.Duration
+	for _, note := range j {
+		total += note.Duration
+	}
+
+	return total
+}
+
+// Synth is a beep.StreamSeekCloser which synthesizes a jingle
+type Synth struct {
+	jingle     Jingle
+	sampleRate beep.SampleRate
+
+	// ends are the positions each note ends at
+	ends []int
+	ramp int
+	pos  int
+}
+
+// NewSynth returns a Synth which plays jingle at sampleRate
+func NewSynth(jingle Jingle, sampleRate beep.SampleRate) *Synth {
+	s := &Synth{jingle: jingle, sampleRate: sampleRate, ramp: sampleRate.N(jingleRamp)}
+	end := 0
+	for _, note := range jingle {
+		end += sampleRate.N(note.Duration)
+		s.ends = append(s.ends, end)
+	}
+
+	return s
+}
+
+// Stream synthesizes the next samples of the jingle
+func (s *Synth) Stream(samples [][2]float64) (n int, ok bool) {
+	note := 0
+	for n < len(samples) && s.pos < s.Len() {
+		for s.pos >= s.ends[note] {
+			note++
+		}
+
+		start := 0
+		if note > 0 {
+			start = s.ends[note-1]
+		}
+
+		v := s.sample(s.jingle[note], s.pos-start, s.ends[note]-start)
+		pan := s.jingle[note].Pan
+		samples[n] = [2]float64{v * (1 - math.Max(pan, 0)), v * (1 + math.Min(pan, 0))}
+		s.pos++
+		n++
+	}
+
+	return n, n > 0
+}
+
+// sample returns the value of a note at offset, ramping it in and out at its ends
+func (s *Synth) sample(note Note, offset, length int) float64 {
+	if note.Frequency <= 0 {
+		return 0
+	}
+
+	cycles := float64(offset) * note.Frequency / float64(s.sampleRate)
+	phase := cycles - math.Floor(cycles)
+	var v float64
+	switch note.Waveform {
+	case WaveformTriangle:
+		v = jingleAmplitude * (1 - 4*math.Abs(phase-0.5))
+	case WaveformNoise:
+		v = jingleAmplitude * noise(uint32(cycles))
+	default:
+		v = jingleAmplitude
+		if phase >= 0.5 {
+			v = -v
+		}
+	}
+
+	if s.ramp > 0 {
+		if offset < s.ramp {
+			v *= float64(offset) / float64(s.ramp)
+		} else if remaining := length - 1 - offset; remaining < s.ramp {
+			v *= float64(remaining) / float64(s.ramp)
+		}
+	}
+
+	return v
+}
+
+// Err always returns nil since synthesizing can't fail
+func (s *Synth) Err() error {
+	return nil
+}
+
+// Len returns the total number of samples of the jingle
+func (s *Synth) Len() int {
+	if len(s.ends) == 0 {
+		return 0
+	}
+
+	return s.ends[len(s.ends)-1]
+}
+
+// Position returns the current position of the jingle
+func (s *Synth) Position() int {
+	return s.pos
+}
+
+// Seek moves the jingle to position p
+func (s *Synth) Seek(p int) error {
+	if p < 0 || p > s.Len() {
+		return errors.New("position out of range")
+	}
+
+	s.pos = p
+	return nil
+}
+
+// Close does nothing since there is nothing to release
+func (s *Synth) Close() error {
+	return nil
+}
+
+// noise returns a pseudorandom 1 or -1 for step. Hashing the step instead of clocking a shift register keeps noise the
+// same after seeking
+func noise(step uint32) float64 {
+	step *= 0x9E3779B1
+	step ^= step >> 15
+	step *= 0x85EBCA77
+	step ^= step >> 13
+	if step&1 == 0 {
+		return -1
+	}
+
+	return 1
+}
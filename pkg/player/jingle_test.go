@@ -0,0 +1,137 @@
+package player
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestJingle_Duration(t *testing.T) {
+	assert.Equal(t, 420*time.Millisecond, StartupJingle.Duration())
+	assert.Equal(t, StartupJingle.Duration(), ShutdownJingle.Duration())
+	assert.Equal(t, time.Duration(0), Jingle{}.Duration())
+}
+
+func TestSynth(t *testing.T) {
+	// A 100Hz note is high for 5 samples and low for 5 samples at 1kHz, then the rest is silent. The first and last 3
+	// samples of the note are ramped
+	jingle := Jingle{{Frequency: 100, Duration: 20 * time.Millisecond}, {Duration: 10 * time.Millisecond}}
+	s := NewSynth(jingle, 1000)
+	assert.Equal(t, 30, s.Len())
+
+	samples := make([][2]float64, 40)
+	n, ok := s.Stream(samples)
+	require.True(t, ok)
+	require.Equal(t, 30, n)
+	assert.Equal(t, 30, s.Position())
+
+	for i, sample := range samples[:n] {
+		assert.Equal(t, sample[0], sample[1])
+		switch {
+		case i >= 20:
+			assert.Equal(t, 0.0, sample[0], "sample %d", i)
+		case i < 3 || i >= 17:
+			assert.True(t, math.Abs(sample[0]) < jingleAmplitude, "sample %d", i)
+		case i%10 < 5:
+			assert.Equal(t, jingleAmplitude, sample[0], "sample %d", i)
+		default:
+			assert.Equal(t, -jingleAmplitude, sample[0], "sample %d", i)
+		}
+	}
+
+	n, ok = s.Stream(samples)
+	assert.False(t, ok)
+	assert.Equal(t, 0, n)
+}
+
+func TestSynth_Ramp(t *testing.T) {
+	// Notes fade in and out so they don't click
+	s := NewSynth(Jingle{{Frequency: 10, Duration: time.Second}}, 44100)
+	samples := make([][2]float64, s.Len())
+	n, _ := s.Stream(samples)
+	require.Equal(t, s.Len(), n)
+
+	assert.Equal(t, 0.0, samples[0][0])
+	assert.True(t, samples[1][0] < jingleAmplitude)
+	assert.Equal(t, jingleAmplitude, samples[1000][0])
+	assert.True(t, samples[n-1][0] < jingleAmplitude)
+}
+
+func TestSynth_Seek(t *testing.T) {
+	s := NewSynth(StartupJingle, jingleSampleRate)
+	require.NoError(t, s.Seek(s.Len()))
+	assert.Equal(t, s.Len(), s.Position())
+
+	_, ok := s.Stream(make([][2]float64, 1))
+	assert.False(t, ok)
+
+	assert.Error(t, s.Seek(-1))
+	assert.Error(t, s.Seek(s.Len()+1))
+	assert.NoError(t, s.Err())
+	assert.NoError(t, s.Close())
+}
+
+func TestSynth_Waveforms(t *testing.T) {
+	// Waveforms are checked away from the ramps at the ends of the note
+	stream := func(note Note) [][2]float64 {
+		note.Duration = time.Second
+		s := NewSynth(Jingle{note}, 1000)
+		samples := make([][2]float64, s.Len())
+		n, _ := s.Stream(samples)
+		require.Equal(t, s.Len(), n)
+		return samples[100:900]
+	}
+
+	t.Run("Triangle", func(tt *testing.T) {
+		// A 10Hz triangle rises from the bottom to the top in 50 samples at 1kHz
+		samples := stream(Note{Frequency: 10, Waveform: WaveformTriangle})
+		assert.Equal(tt, -jingleAmplitude, samples[0][0])
+		assert.InDelta(tt, 0.0, samples[25][0], 1e-9)
+		assert.Equal(tt, jingleAmplitude, samples[50][0])
+		assert.InDelta(tt, 0.0, samples[75][0], 1e-9)
+	})
+
+	t.Run("Noise", func(tt *testing.T) {
+		// 100Hz noise holds each value for 10 samples at 1kHz and doesn't stick to either value
+		samples := stream(Note{Frequency: 100, Waveform: WaveformNoise})
+		high := 0
+		for i, sample := range samples {
+			assert.Equal(tt, jingleAmplitude, math.Abs(sample[0]), "sample %d", i)
+			assert.Equal(tt, samples[i-i%10][0], sample[0], "sample %d", i)
+			if sample[0] > 0 {
+				high++
+			}
+		}
+
+		assert.True(tt, high > len(samples)/4 && high < len(samples)*3/4, "%d of %d samples are high", high, len(samples))
+	})
+}
+
+func TestSynth_Pan(t *testing.T) {
+	testCases := []struct {
+		name  string
+		pan   float64
+		left  float64
+		right float64
+	}{
+		{"Center", 0, 1, 1},
+		{"Left", -1, 1, 0},
+		{"Right", 1, 0, 1},
+		{"HalfRight", 0.5, 0.5, 1},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			s := NewSynth(Jingle{{Frequency: 10, Duration: time.Second, Pan: testCase.pan}}, 1000)
+			samples := make([][2]float64, 20)
+			_, ok := s.Stream(samples)
+			require.True(tt, ok)
+
+			// The 10th sample is high and past the ramp
+			assert.Equal(tt, jingleAmplitude*testCase.left, samples[10][0])
+			assert.Equal(tt, jingleAmplitude*testCase.right, samples[10][1])
+		})
+	}
+}
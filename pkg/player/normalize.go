@@ -0,0 +1,108 @@
+package player
+
+import (
+	"github.com/faiface/beep"
+	"math"
+	"time"
+)
+
+const (
+	// DefaultNormalizeTarget is the default RMS level the Normalizer brings tracks to, about -18 dBFS
+	DefaultNormalizeTarget = 0.125
+
+	// normalizeWindow is roughly how much of the most recent audio the loudness is measured over. A long window keeps
+	// the gain from pumping with the beat of a track
+	normalizeWindow = 3 * time.Second
+
+	// normalizeGainTime is roughly how long the gain takes to settle after the loudness changes
+	normalizeGainTime = 2 * time.Second
+
+	// normalizeMinGain and normalizeMaxGain limit the gain to -12 dB and +12 dB
+	normalizeMinGain = 0.25
+	normalizeMaxGain = 4
+
+	// normalizeGate is the RMS level, about -50 dBFS, below which the gain is held so silence and fades aren't boosted
+	normalizeGate = 0.003
+
+	// normalizeCeiling is the highest level a sample is amplified to, so raising quiet tracks doesn't clip their peaks
+	normalizeCeiling = 0.98
+)
+
+// Normalizer is a beep.Streamer which continuously adjusts the gain of the wrapped streamer so its loudness, measured
+// as the RMS level of the last few seconds, stays near a target level. Uploads to chipmusic.org are mastered at wildly
+// different levels and this saves adjusting the volume for each track. When Enabled is false, samples are passed
+// through untouched
+type Normalizer struct {
+	Streamer beep.Streamer
+	Enabled  bool
+
+	target     float64
+	meanSquare float64
+	gain       float64
+
+	// measured counts the samples measured up to the length of the window. Until the window is full, the loudness is
+	// the plain average so the first samples of a track aren't weighed against silence
+	measured int
+	window   int
+
+	// measure and settle are the coefficients of the exponential moving averages of the loudness and the gain
+	measure float64
+	settle  float64
+}
+
+// NewNormalizer returns a Normalizer which brings streamer to the RMS level target, between 0 and 1
+func NewNormalizer(streamer beep.Streamer, sampleRate beep.SampleRate, target float64) *Normalizer {
+	window := sampleRate.N(normalizeWindow)
+	return &Normalizer{
+		Streamer: streamer,
+		Enabled:  true,
+		target:   math.Max(0, math.Min(1, target)),
+		gain:     1,
+		window:   window,
+		measure:  1 - math.Exp(-1/float64(window)),
+		settle:   1 - math.Exp(-1/float64(sampleRate.N(normalizeGainTime))),
+	}
+}
+
+// Stream streams from the wrapped streamer, adjusting the gain if Enabled is true
+func (n *Normalizer) Stream(samples [][2]float64) (count int, ok bool) {
+	count, ok = n.Streamer.Stream(samples)
+	if !n.Enabled {
+		return count, ok
+	}
+
+	for i := range samples[:count] {
+		left, right := samples[i][0], samples[i][1]
+		square := (left*left + right*right) / 2
+		if n.measured < n.window {
+			n.measured++
+			n.meanSquare += (square - n.meanSquare) / float64(n.measured)
+		} else {
+			n.meanSquare += n.measure * (square - n.meanSquare)
+		}
+
+		if rms := math.Sqrt(n.meanSquare); rms > normalizeGate {
+			desired := math.Max(normalizeMinGain, math.Min(normalizeMaxGain, n.target/rms))
+			n.gain += n.settle * (desired - n.gain)
+		}
+
+		// Peaks which would clip pull the gain down at once, and it recovers as slowly as it settles
+		if peak := math.Max(math.Abs(left), math.Abs(right)); peak*n.gain > normalizeCeiling {
+			n.gain = normalizeCeiling / peak
+		}
+
+		samples[i] = [2]float64{left * n.gain, right * n.gain}
+	}
+
+	return count, ok
+}
+
+// Err propagates the wrapped streamer's error
+func (n *Normalizer) Err() error {
+	return n.Streamer.Err()
+}
+
+// Gain returns the gain currently applied to the wrapped streamer
+func (n *Normalizer) Gain() float64 {
+	return n.gain
+}
@@ -0,0 +1,73 @@
+package player
+
+import (
+	"github.com/faiface/beep"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// normalizeTestRate keeps the tests fast while streaming long enough for the normalizer to settle
+const normalizeTestRate = beep.SampleRate(1000)
+
+func TestNormalizer(t *testing.T) {
+	testCases := []struct {
+		name     string
+		level    float64
+		target   float64
+		expected float64
+	}{
+		{"Quiet", 0.05, DefaultNormalizeTarget, DefaultNormalizeTarget},
+		{"Loud", 0.5, DefaultNormalizeTarget, DefaultNormalizeTarget},
+		{"TooQuietToReach", 0.01, DefaultNormalizeTarget, 0.01 * normalizeMaxGain},
+		{"Peaks", 0.4, 1, normalizeCeiling},
+		{"Silence", normalizeGate / 2, DefaultNormalizeTarget, normalizeGate / 2},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			normalizer := NewNormalizer(newConstantStreamer(testCase.level, -testCase.level), normalizeTestRate, testCase.target)
+
+			samples := make([][2]float64, normalizeTestRate.N(normalizeWindow+normalizeGainTime)*10)
+			n, ok := normalizer.Stream(samples)
+			assert.Equal(tt, len(samples), n)
+			assert.True(tt, ok)
+
+			for _, sample := range samples {
+				assert.True(tt, sample[0] <= normalizeCeiling)
+			}
+
+			last := samples[len(samples)-1]
+			assert.InDelta(tt, testCase.expected, last[0], 0.001)
+			assert.InDelta(tt, -testCase.expected, last[1], 0.001)
+		})
+	}
+}
+
+func TestNormalizer_StartsAtUnityGain(t *testing.T) {
+	normalizer := NewNormalizer(newConstantStreamer(0.05, 0.05), normalizeTestRate, DefaultNormalizeTarget)
+
+	samples := make([][2]float64, 1)
+	normalizer.Stream(samples)
+	assert.InDelta(t, 0.05, samples[0][0], 0.001)
+}
+
+func TestNormalizer_Disabled(t *testing.T) {
+	normalizer := NewNormalizer(newConstantStreamer(0.05, 0), normalizeTestRate, DefaultNormalizeTarget)
+	normalizer.Enabled = false
+
+	samples := make([][2]float64, normalizeTestRate.N(normalizeWindow)*10)
+	n, ok := normalizer.Stream(samples)
+	assert.Equal(t, len(samples), n)
+	assert.True(t, ok)
+
+	for _, sample := range samples {
+		assert.Equal(t, [2]float64{0.05, 0}, sample)
+	}
+
+	assert.Equal(t, 1.0, normalizer.Gain())
+}
+
+func TestNormalizer_Err(t *testing.T) {
+	normalizer := NewNormalizer(newConstantStreamer(1, 1), normalizeTestRate, DefaultNormalizeTarget)
+	assert.NoError(t, normalizer.Err())
+}
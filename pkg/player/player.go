@@ -6,8 +6,7 @@ import (
 	"fmt"
 	"github.com/broar/chipmusic-cli/pkg/chipmusic"
 	"github.com/faiface/beep"
-	"github.com/faiface/beep/mp3"
-	"github.com/faiface/beep/speaker"
+	"github.com/faiface/beep/effects"
 	"io"
 	"math"
 	"sync"
@@ -17,6 +16,9 @@ import (
 const (
 	// DefaultBufferSize is the default size of the buffer used for the track player
 	DefaultBufferSize = 1 * time.Second / 10
+
+	// DefaultSampleRate is the default sample rate of the speaker. Tracks with another sample rate are resampled to it
+	DefaultSampleRate beep.SampleRate = 44100
 	NoCurrentTrack = -1
 )
 
@@ -24,8 +26,11 @@ var (
 	// ErrNilTrack is an error returned when attempting to play a nil Track
 	ErrNilTrack = errors.New("track cannot be nil")
 
-	// ErrUnknownFileFormat is an error returned when a Track's FileFormat cannot be decoded by beep
+	// ErrUnknownFileFormat is an error returned when no decoder is registered for a Track's FileType
 	ErrUnknownFileFormat = errors.New("unknown file format")
+
+	// ErrCorruptTrack is an error returned when a Track's audio cannot be decoded or does not contain any audio frames
+	ErrCorruptTrack = errors.New("corrupt track")
 )
 
 // TrackPlayer is a struct capable of playing tracks from readers. It offers a simple suite of audio controls such as
@@ -33,6 +38,17 @@ var (
 type TrackPlayer struct {
 	bufferSize time.Duration
 
+	// sink is where the audio is sent. This defaults to the speaker
+	sink Sink
+
+	// sinkMux guards sinkReady, which is whether the sink was initialized. The sink is only initialized once, since
+	// reinitializing the speaker for every track can glitch and leak the audio device
+	sinkMux   sync.Mutex
+	sinkReady bool
+
+	// realtime is whether the priority of the thread the speaker streams audio on is raised
+	realtime bool
+
 	mux     sync.Mutex
 	ctrl    *beep.Ctrl
 	format  beep.Format
@@ -40,6 +56,34 @@ type TrackPlayer struct {
 	ctx     context.Context
 	cancel  context.CancelFunc
 	looping bool
+
+	// rate is the sample rate the speaker is initialized with. Tracks with another sample rate are resampled to it so
+	// they can follow each other without reinitializing the speaker. This defaults to DefaultSampleRate
+	rate beep.SampleRate
+
+	// queue holds the decoded tracks which play after the current track, and previous holds the tracks played before
+	// it with the most recent last
+	queue    []*queuedTrack
+	previous []*queuedTrack
+
+	crossfeed       bool
+	crossfeedStream *Crossfeed
+
+	normalize        bool
+	normalizerStream *Normalizer
+
+	volume       int
+	volumeStream *effects.Volume
+
+	// output is the streamer of the current track given to the speaker, kept so it can be given to the speaker again
+	// when the speaker is reinitialized
+	output beep.Streamer
+
+	tracer *Tracer
+
+	// track is the track currently playing. It is nil for jingles
+	track  *chipmusic.Track
+	events chan PlayerEvent
 }
 
 // Option is an alias for a function that modifies a TrackPlayer. An Option is used to override the default values of TrackPlayer
@@ -58,11 +102,70 @@ func WithBufferSize(bufferSize time.Duration) Option {
 	}
 }
 
+// WithSampleRate allows overriding the sample rate of the speaker. Every track is resampled to it
+func WithSampleRate(rate beep.SampleRate) Option {
+	return func(player *TrackPlayer) error {
+		if rate <= 0 {
+			return errors.New("sample rate must be greater than 0")
+		}
+
+		player.rate = rate
+		return nil
+	}
+}
+
+// WithCrossfeed allows enabling crossfeed for playback. Crossfeed blends a portion of each channel into the other and
+// makes hard-panned tracks less fatiguing on headphones
+func WithCrossfeed(enabled bool) Option {
+	return func(player *TrackPlayer) error {
+		player.crossfeed = enabled
+		return nil
+	}
+}
+
+// WithNormalization allows enabling loudness normalization for playback. Normalization continuously adjusts the gain
+// of tracks so quiet and loud masters play at a similar loudness
+func WithNormalization(enabled bool) Option {
+	return func(player *TrackPlayer) error {
+		player.normalize = enabled
+		return nil
+	}
+}
+
+// WithVolume allows overriding the volume tracks are played at, between MinVolume and MaxVolume
+func WithVolume(volume int) Option {
+	return func(player *TrackPlayer) error {
+		if volume != ClampVolume(volume) {
+			return fmt.Errorf("volume must be between %d and %d", MinVolume, MaxVolume)
+		}
+
+		player.volume = volume
+		return nil
+	}
+}
+
+// WithTracer allows tracing the playback pipeline to debug stuttering. Decode timings, buffer fill levels, and underruns
+// are logged by the tracer
+func WithTracer(tracer *Tracer) Option {
+	return func(player *TrackPlayer) error {
+		if tracer == nil {
+			return errors.New("tracer cannot be nil")
+		}
+
+		player.tracer = tracer
+		return nil
+	}
+}
+
 // NewTrackPlayer creates a new TrackPlayer object that is configured with a list of Options
 func NewTrackPlayer(options ...Option) (*TrackPlayer, error) {
 	player := &TrackPlayer{
 		bufferSize: DefaultBufferSize,
+		sink:       speakerSink{},
+		rate:       DefaultSampleRate,
+		volume:     DefaultVolume,
 		mux:        sync.Mutex{},
+		events:     make(chan PlayerEvent, DefaultEventBuffer),
 	}
 
 	for _, option := range options {
@@ -71,6 +174,16 @@ func NewTrackPlayer(options ...Option) (*TrackPlayer, error) {
 		}
 	}
 
+	if _, ok := player.sink.(speakerSink); ok && player.realtime {
+		player.sink = &prioritySink{
+			Sink:  player.sink,
+			raise: RaisePriority,
+			raised: func(priority ThreadPriority, err error) {
+				player.emit(PriorityRaised{Priority: priority, Err: err})
+			},
+		}
+	}
+
 	return player, nil
 }
 
@@ -81,17 +194,75 @@ func NewTrackPlayer(options ...Option) (*TrackPlayer, error) {
 // 2. Call Done and listen for a signal returned on the channel
 // 3. Call Close to release any resources associated with the current track OR simply call Play which already does this
 func (t *TrackPlayer) Play(track *chipmusic.Track) error {
+	return t.PlayFrom(track, 0)
+}
+
+// PlayFrom starts playing a track from offset. This is useful for skipping long intros. If offset is past the end of
+// the track, the track finishes immediately. The same rules for calling Play apply to this method
+func (t *TrackPlayer) PlayFrom(track *chipmusic.Track, offset time.Duration) error {
+	stream, format, err := t.decodeFrom(track, offset)
+	if err != nil {
+		return err
+	}
+
+	return t.play(track, offset, stream, format)
+}
+
+// decodeFrom decodes the audio of a track, makes sure it is playable, and seeks it to offset
+func (t *TrackPlayer) decodeFrom(track *chipmusic.Track, offset time.Duration) (beep.StreamSeekCloser, beep.Format, error) {
 	if track == nil {
-		return ErrNilTrack
+		return nil, beep.Format{}, ErrNilTrack
 	}
 
+	start := time.Now()
 	stream, format, err := t.decodeTrackAudio(track)
-	if err != nil {
-		return fmt.Errorf("failed to decode track audio: %w", err)
+	if t.tracer != nil {
+		t.tracer.Decode(track.Title, time.Since(start), err)
 	}
 
-	if err := speaker.Init(format.SampleRate, format.SampleRate.N(t.bufferSize)); err != nil {
-		return fmt.Errorf("failed to initalize speaker with format %+v: %w", format, err)
+	if errors.Is(err, ErrUnknownFileFormat) {
+		return nil, beep.Format{}, fmt.Errorf("failed to decode track audio: %w", err)
+	} else if err != nil {
+		return nil, beep.Format{}, fmt.Errorf("failed to decode track audio: %w: %v", ErrCorruptTrack, err)
+	}
+
+	if err := validateStream(stream); err != nil {
+		stream.Close()
+		return nil, beep.Format{}, fmt.Errorf("failed to validate track audio: %w: %v", ErrCorruptTrack, err)
+	}
+
+	if offset > 0 {
+		position := format.SampleRate.N(offset)
+		if position >= stream.Len() {
+			position = stream.Len() - 1
+		}
+
+		if err := stream.Seek(position); err != nil {
+			stream.Close()
+			return nil, beep.Format{}, fmt.Errorf("failed to seek to %s: %w", offset, err)
+		}
+	}
+
+	return stream, format, nil
+}
+
+// PlayJingle plays a jingle the same way as a track, so Done and the audio controls work with it. Since a jingle goes
+// through the whole output pipeline, playing one is also a quick check that audio output works. The same rules for
+// calling Play apply to this method
+func (t *TrackPlayer) PlayJingle(jingle Jingle) error {
+	if len(jingle) == 0 {
+		return errors.New("jingle cannot be empty")
+	}
+
+	format := beep.Format{SampleRate: jingleSampleRate, NumChannels: 2, Precision: 2}
+	return t.play(nil, 0, NewSynth(jingle, jingleSampleRate), format)
+}
+
+// play replaces the current track with stream and starts playing it from offset, dropping the queue. The track is nil
+// for jingles
+func (t *TrackPlayer) play(track *chipmusic.Track, offset time.Duration, stream beep.StreamSeekCloser, format beep.Format) error {
+	if err := t.initSink(); err != nil {
+		return err
 	}
 
 	if err := t.Close(); err != nil {
@@ -101,22 +272,77 @@ func (t *TrackPlayer) Play(track *chipmusic.Track) error {
 	t.mux.Lock()
 
 	t.current = stream
+	t.track = track
 	t.format = format
-	t.ctrl = &beep.Ctrl{Streamer: stream, Paused: false}
+	t.looping = false
+	t.ctrl = &beep.Ctrl{Streamer: t.resample(stream, format), Paused: false}
+	t.crossfeedStream = NewCrossfeed(&gapless{player: t}, t.rate, DefaultCrossfeedLevel)
+	t.crossfeedStream.Enabled = t.crossfeed
+	t.normalizerStream = NewNormalizer(t.crossfeedStream, t.rate, DefaultNormalizeTarget)
+	t.normalizerStream.Enabled = t.normalize
+	t.volumeStream = newVolume(t.normalizerStream, t.volume)
 	if t.ctx == nil {
 		t.ctx, t.cancel = context.WithCancel(context.Background())
 	}
 
 	t.mux.Unlock()
 
-	speaker.Play(beep.Seq(t.ctrl, beep.Callback(func() {
+	var streamer beep.Streamer = t.volumeStream
+	if t.tracer != nil {
+		streamer = newTracingStreamer(streamer, t.rate, t.tracer)
+	}
+
+	output := beep.Seq(streamer, beep.Callback(func() {
+		t.mux.Lock()
+		defer t.mux.Unlock()
+
+		t.finish()
 		t.cancel()
-	})))
+	}))
 
+	t.mux.Lock()
+	t.output = output
+	t.mux.Unlock()
+
+	t.sink.Play(output)
+	t.emit(TrackStarted{Track: track, Offset: offset, Length: format.SampleRate.D(stream.Len())})
+
+	return nil
+}
+
+// initSink initializes the sink at the sample rate of the player unless it already was
+func (t *TrackPlayer) initSink() error {
+	t.sinkMux.Lock()
+	defer t.sinkMux.Unlock()
+
+	if t.sinkReady {
+		return nil
+	}
+
+	if err := t.sink.Init(t.rate, t.rate.N(t.bufferSize)); err != nil {
+		return fmt.Errorf("failed to initalize speaker at %d Hz: %w", t.rate, err)
+	}
+
+	t.sinkReady = true
 	return nil
 }
 
-// Done returns a channel signifying when the current track is done playing which clients can listen on
+// validateStream ensures that at least one sample can be decoded from the stream and then rewinds it to the start
+func validateStream(stream beep.StreamSeekCloser) error {
+	samples := make([][2]float64, 1)
+	if n, ok := stream.Stream(samples); !ok || n == 0 {
+		if err := stream.Err(); err != nil {
+			return err
+		}
+
+		return errors.New("no audio frames")
+	}
+
+	return stream.Seek(0)
+}
+
+// Done returns a channel signifying when the current track and every track queued after it are done playing which
+// clients can listen on
 func (t *TrackPlayer) Done() <-chan struct{} {
 	t.mux.Lock()
 	defer t.mux.Unlock()
@@ -127,31 +353,99 @@ func (t *TrackPlayer) Done() <-chan struct{} {
 	return t.ctx.Done()
 }
 
-func (t *TrackPlayer) decodeTrackAudio(track *chipmusic.Track) (beep.StreamSeekCloser, beep.Format, error) {
-	switch track.FileType {
-	case chipmusic.AudioFileTypeMP3:
-		return mp3.Decode(track.Reader)
+// Events returns a channel receiving events about playback, so clients can react to it without polling. The same
+// channel is returned for every call and it is never closed. Emitting never blocks: if the channel's buffer is full, the
+// event is dropped so a slow receiver can never stall playback
+func (t *TrackPlayer) Events() <-chan PlayerEvent {
+	return t.events
+}
+
+// emit sends event on the events channel unless its buffer is full
+func (t *TrackPlayer) emit(event PlayerEvent) {
+	select {
+	case t.events <- event:
 	default:
-		return beep.StreamSeekCloser(nil), beep.Format{}, fmt.Errorf("%w: %s", ErrUnknownFileFormat, track.FileType)
 	}
 }
 
+// emitPaused emits Paused or Resumed for the current state of the current track. The speaker must be locked by the
+// caller
+func (t *TrackPlayer) emitPaused() {
+	position := t.format.SampleRate.D(t.current.Position())
+	if t.ctrl.Paused {
+		t.emit(Paused{Position: position})
+	} else {
+		t.emit(Resumed{Position: position})
+	}
+}
+
+func (t *TrackPlayer) decodeTrackAudio(track *chipmusic.Track) (beep.StreamSeekCloser, beep.Format, error) {
+	return Decode(track)
+}
+
 // Pause pauses/unpauses the currently playing track. If there is no track is currently playing, this method does nothing
 func (t *TrackPlayer) Pause() {
-	speaker.Lock()
-	defer speaker.Unlock()
+	t.sink.Lock()
+	defer t.sink.Unlock()
 	if t.ctrl == nil {
 		return
 	}
 
 	t.ctrl.Paused = !t.ctrl.Paused
+	t.emitPaused()
+}
+
+// SetPaused pauses or unpauses the currently playing track. Unlike Pause, calling this method several times has the
+// same effect as calling it once. If there is no track currently playing, this method does nothing
+func (t *TrackPlayer) SetPaused(paused bool) {
+	t.sink.Lock()
+	defer t.sink.Unlock()
+	if t.ctrl == nil {
+		return
+	}
+
+	if t.ctrl.Paused == paused {
+		return
+	}
+
+	t.ctrl.Paused = paused
+	t.emitPaused()
+}
+
+// Paused returns true if the currently playing track is paused. If there is no track currently playing, this method
+// returns false
+func (t *TrackPlayer) Paused() bool {
+	t.sink.Lock()
+	defer t.sink.Unlock()
+	return t.ctrl != nil && t.ctrl.Paused
+}
+
+// Reinit reinitializes the speaker and continues the currently playing track from where it left off. This recovers
+// playback after the audio backend broke, e.g. because the system was suspended or the audio device disappeared. If
+// there is no track currently playing, this method does nothing
+func (t *TrackPlayer) Reinit() error {
+	t.mux.Lock()
+	output, rate := t.output, t.rate
+	t.mux.Unlock()
+
+	if output == nil {
+		return nil
+	}
+
+	// Initializing the speaker closes the old audio backend and drops every streamer it was playing
+	if err := t.sink.Init(rate, rate.N(t.bufferSize)); err != nil {
+		return fmt.Errorf("failed to reinitalize speaker at %d Hz: %w", rate, err)
+	}
+
+	t.sink.Play(output)
+	return nil
 }
 
 // Stop pauses the currently playing track and resets its position to the start. If there is no track currently playing,
 // this method does nothing
 func (t *TrackPlayer) Stop() error {
-	speaker.Lock()
-	defer speaker.Unlock()
+	t.sink.Lock()
+	defer t.sink.Unlock()
 	if t.ctrl == nil {
 		return nil
 	}
@@ -161,14 +455,15 @@ func (t *TrackPlayer) Stop() error {
 		return fmt.Errorf("failed to seek to start of track: %w", err)
 	}
 
+	t.emit(Stopped{})
 	return nil
 }
 
 // Loop loops the currently playing track. If the current track is already looping, this method disables looping. If
 // there is no track currently playing, this method does nothing
 func (t *TrackPlayer) Loop() {
-	speaker.Lock()
-	defer speaker.Unlock()
+	t.sink.Lock()
+	defer t.sink.Unlock()
 	if t.ctrl == nil {
 		return
 	}
@@ -177,19 +472,106 @@ func (t *TrackPlayer) Loop() {
 	defer t.mux.Unlock()
 
 	if t.looping {
-		t.ctrl.Streamer = t.current
+		t.ctrl.Streamer = t.resample(t.current, t.format)
 		t.looping = false
 	} else {
-		t.ctrl.Streamer = beep.Loop(math.MaxInt32, t.current)
+		t.ctrl.Streamer = t.resample(beep.Loop(math.MaxInt32, t.current), t.format)
 		t.looping = true
 	}
+
+	t.emit(Looped{Looping: t.looping})
+}
+
+// FadeOut fades the currently playing track out over d and then finishes it early. If there is no track currently
+// playing, this method does nothing
+func (t *TrackPlayer) FadeOut(d time.Duration) {
+	t.sink.Lock()
+	defer t.sink.Unlock()
+	if t.ctrl == nil {
+		return
+	}
+
+	t.mux.Lock()
+	defer t.mux.Unlock()
+
+	t.ctrl.Streamer = NewFadeOut(t.ctrl.Streamer, t.rate, d)
+}
+
+// Crossfeed enables crossfeed for the current and future tracks. If crossfeed is already enabled, this method disables
+// crossfeed
+func (t *TrackPlayer) Crossfeed() {
+	t.sink.Lock()
+	defer t.sink.Unlock()
+
+	t.mux.Lock()
+	defer t.mux.Unlock()
+
+	t.crossfeed = !t.crossfeed
+	if t.crossfeedStream != nil {
+		t.crossfeedStream.Enabled = t.crossfeed
+	}
+}
+
+// CrossfeedEnabled returns true if crossfeed is enabled
+func (t *TrackPlayer) CrossfeedEnabled() bool {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	return t.crossfeed
+}
+
+// Normalize enables loudness normalization for the current and future tracks. If normalization is already enabled,
+// this method disables normalization
+func (t *TrackPlayer) Normalize() {
+	t.sink.Lock()
+	defer t.sink.Unlock()
+
+	t.mux.Lock()
+	defer t.mux.Unlock()
+
+	t.normalize = !t.normalize
+	if t.normalizerStream != nil {
+		t.normalizerStream.Enabled = t.normalize
+	}
+}
+
+// NormalizationEnabled returns true if loudness normalization is enabled
+func (t *TrackPlayer) NormalizationEnabled() bool {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	return t.normalize
+}
+
+// SetVolume sets the volume tracks are played at, including the currently playing track. The volume is clamped between
+// MinVolume and MaxVolume and the volume which was set is returned
+func (t *TrackPlayer) SetVolume(volume int) int {
+	volume = ClampVolume(volume)
+
+	t.sink.Lock()
+	defer t.sink.Unlock()
+
+	t.mux.Lock()
+	defer t.mux.Unlock()
+
+	t.volume = volume
+	if t.volumeStream != nil {
+		setVolume(t.volumeStream, volume)
+	}
+
+	return volume
+}
+
+// Volume returns the volume tracks are played at
+func (t *TrackPlayer) Volume() int {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	return t.volume
 }
 
 // Skip seeks to the end of the current track and effectively skips it. If there is no track currently playing,
 // this method does nothing
 func (t *TrackPlayer) Skip() error {
-	speaker.Lock()
-	defer speaker.Unlock()
+	t.sink.Lock()
+	defer t.sink.Unlock()
 	if t.ctrl == nil {
 		return nil
 	}
@@ -204,41 +586,95 @@ func (t *TrackPlayer) Skip() error {
 	return nil
 }
 
+// Seek moves the current track to offset from its start so users can scrub within it. Offsets before the start or
+// past the end of the track are clamped to the track. If there is no track currently playing, this method does nothing
+func (t *TrackPlayer) Seek(offset time.Duration) error {
+	t.sink.Lock()
+	defer t.sink.Unlock()
+	if t.ctrl == nil {
+		return nil
+	}
+
+	return t.seek(t.format.SampleRate.N(offset))
+}
+
+// SeekRelative moves the current track forwards by d, or backwards if d is negative. The new position is clamped to
+// the track. If there is no track currently playing, this method does nothing
+func (t *TrackPlayer) SeekRelative(d time.Duration) error {
+	t.sink.Lock()
+	defer t.sink.Unlock()
+	if t.ctrl == nil {
+		return nil
+	}
+
+	return t.seek(t.current.Position() + t.format.SampleRate.N(d))
+}
+
+// seek seeks the current track to position, clamped between the start and the last sample of the track like Skip. The
+// speaker must be locked by the caller
+func (t *TrackPlayer) seek(position int) error {
+	if last := t.current.Len() - 1; position > last {
+		position = last
+	}
+
+	if position < 0 {
+		position = 0
+	}
+
+	if err := t.current.Seek(position); err != nil {
+		return fmt.Errorf("failed to seek to %s: %w", t.format.SampleRate.D(position), err)
+	}
+
+	return nil
+}
+
 // CurrentTime returns the current position of the track as a duration. If there is no track currently playing, this
 // method does nothing
 func (t *TrackPlayer) CurrentTime() time.Duration {
+	// The speaker is locked first like everywhere else, since the speaker holds its lock while moving to the next track
+	t.sink.Lock()
+	defer t.sink.Unlock()
+
 	t.mux.Lock()
 	defer t.mux.Unlock()
 	if t.current == nil {
 		return NoCurrentTrack
 	}
 
-	speaker.Lock()
-	defer speaker.Unlock()
 	return t.format.SampleRate.D(t.current.Position())
 }
 
 // TotalTime returns the total length of the track as a duration. If there is no track currently playing, this
 // method does nothing
 func (t *TrackPlayer) TotalTime() time.Duration {
+	t.sink.Lock()
+	defer t.sink.Unlock()
+
 	t.mux.Lock()
 	defer t.mux.Unlock()
 	if t.current == nil {
 		return NoCurrentTrack
 	}
 
-	speaker.Lock()
-	defer speaker.Unlock()
 	return t.format.SampleRate.D(t.current.Len())
 }
 
-// Close closes all resources associated with the current track. If there is no track currently playing, this method
-// does nothing. This method is implicitly called by Play. There is no need for clients call this method themselves if
-// planning to call Play again; however, this method does need to be called when a TrackPlayer will no longer be used
+// Close closes all resources associated with the current track and the queue. If there is no track currently playing,
+// this method does nothing. This method is implicitly called by Play. There is no need for clients call this method
+// themselves if planning to call Play again; however, this method does need to be called when a TrackPlayer will no
+// longer be used
 func (t *TrackPlayer) Close() error {
+	// The speaker keeps playing the output of the current track until it is cleared, so it must stop before the track
+	// is closed
+	t.sink.Clear()
+
 	t.mux.Lock()
 	defer t.mux.Unlock()
 
+	closeTracks(t.queue)
+	closeTracks(t.previous)
+	t.queue, t.previous = nil, nil
+
 	if t.current == nil {
 		return nil
 	}
@@ -249,5 +685,6 @@ func (t *TrackPlayer) Close() error {
 		t.cancel = nil
 	}
 
+	t.output = nil
 	return t.current.Close()
 }
@@ -1,12 +1,15 @@
 package player
 
 import (
+	"bytes"
 	"errors"
 	"github.com/broar/chipmusic-cli/pkg/chipmusic"
+	"github.com/faiface/beep"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"os"
 	"path/filepath"
+	"sync"
 	"testing"
 	"time"
 )
@@ -84,7 +87,7 @@ func TestPlay_BadFileFormat(t *testing.T) {
 	track := &chipmusic.Track{
 		Title:    "some.title",
 		Artist:   "some.artist",
-		FileType: "wav",
+		FileType: "some.type",
 	}
 
 	err = tp.Play(track)
@@ -106,6 +109,33 @@ func TestPause(t *testing.T) {
 	})
 }
 
+func TestSetPaused(t *testing.T) {
+	startTrackPlayerTest(t, func(track *chipmusic.Track, tp *TrackPlayer) {
+		err := tp.Play(track)
+		require.NoError(t, err)
+
+		// Pausing twice keeps the track paused unlike Pause
+		tp.SetPaused(true)
+		tp.SetPaused(true)
+		assert.True(t, tp.Paused())
+		tp.SetPaused(false)
+		assert.False(t, tp.Paused())
+	})
+}
+
+func TestReinit(t *testing.T) {
+	startTrackPlayerTest(t, func(track *chipmusic.Track, tp *TrackPlayer) {
+		err := tp.Play(track)
+		require.NoError(t, err)
+
+		// The track keeps playing until it is done after the speaker is reinitialized
+		tp.SetPaused(true)
+		require.NoError(t, tp.Reinit())
+		assert.True(t, tp.Paused())
+		tp.SetPaused(false)
+	})
+}
+
 func TestStop(t *testing.T) {
 	tp, err := NewTrackPlayer()
 	require.NoError(t, err)
@@ -157,17 +187,282 @@ func TestSkip(t *testing.T) {
 	})
 }
 
+func TestSeek(t *testing.T) {
+	startTrackPlayerTest(t, func(track *chipmusic.Track, tp *TrackPlayer) {
+		err := tp.Play(track)
+		require.NoError(t, err)
+
+		// Pausing keeps the position from moving between seeking and checking it
+		tp.SetPaused(true)
+		defer tp.SetPaused(false)
+
+		require.NoError(t, tp.Seek(500*time.Millisecond))
+		assert.Equal(t, tp.format.SampleRate.N(500*time.Millisecond), tp.current.Position())
+
+		require.NoError(t, tp.SeekRelative(-250*time.Millisecond))
+		assert.Equal(t, tp.format.SampleRate.N(250*time.Millisecond), tp.current.Position())
+
+		require.NoError(t, tp.SeekRelative(-time.Hour))
+		assert.Equal(t, 0, tp.current.Position())
+
+		require.NoError(t, tp.Seek(time.Hour))
+		assert.Equal(t, tp.current.Len()-1, tp.current.Position())
+	})
+}
+
+func TestFadeOut(t *testing.T) {
+	startTrackPlayerTest(t, func(track *chipmusic.Track, tp *TrackPlayer) {
+		err := tp.Play(track)
+		require.NoError(t, err)
+
+		tp.FadeOut(10 * time.Millisecond)
+	})
+}
+
 func TestAudioControlsWithNoCurrentTrack(t *testing.T) {
 	tp, err := NewTrackPlayer()
 	require.NoError(t, err)
 	require.NotNil(t, tp)
 
 	tp.Pause()
+	tp.SetPaused(true)
+	assert.False(t, tp.Paused())
 	tp.Loop()
+	tp.Crossfeed()
+	tp.Normalize()
+	tp.FadeOut(time.Second)
+	assert.NoError(t, tp.Reinit())
 	err = tp.Stop()
 	assert.NoError(t, err)
 	err = tp.Skip()
 	assert.NoError(t, err)
+	err = tp.Seek(time.Second)
+	assert.NoError(t, err)
+	err = tp.SeekRelative(-time.Second)
+	assert.NoError(t, err)
 	err = tp.Close()
 	assert.NoError(t, err)
 }
+
+func TestWithSampleRate(t *testing.T) {
+	tp, err := NewTrackPlayer(WithSampleRate(0))
+	assert.Error(t, err)
+	assert.Nil(t, tp)
+}
+
+func TestPlay_InitializesSinkOnce(t *testing.T) {
+	sink := &recordingSink{}
+	tp, err := NewTrackPlayer(WithSink(sink), WithSampleRate(48000))
+	require.NoError(t, err)
+
+	defer tp.Close()
+
+	file, err := os.Open(testAudio)
+	require.NoError(t, err)
+
+	track := &chipmusic.Track{FileType: chipmusic.AudioFileTypeMP3, Reader: file}
+	defer track.Close()
+
+	// Neither the jingle nor the track is at the sample rate of the sink, so both are resampled to it
+	require.NoError(t, tp.PlayJingle(StartupJingle))
+	require.NoError(t, tp.Play(track))
+
+	assert.Equal(t, []beep.SampleRate{48000}, sink.inits)
+	assert.Equal(t, 2, sink.plays)
+	assert.True(t, sink.clears >= 2, "the output of each track should be cleared before the next one plays")
+}
+
+func TestWithCrossfeed(t *testing.T) {
+	tp, err := NewTrackPlayer(WithCrossfeed(true))
+	require.NoError(t, err)
+	require.NotNil(t, tp)
+	assert.True(t, tp.CrossfeedEnabled())
+}
+
+func TestCrossfeed(t *testing.T) {
+	startTrackPlayerTest(t, func(track *chipmusic.Track, tp *TrackPlayer) {
+		err := tp.Play(track)
+		require.NoError(t, err)
+		assert.False(t, tp.CrossfeedEnabled())
+
+		// Enable and then disable crossfeed
+		tp.Crossfeed()
+		assert.True(t, tp.CrossfeedEnabled())
+		tp.Crossfeed()
+		assert.False(t, tp.CrossfeedEnabled())
+	})
+}
+
+func TestWithNormalization(t *testing.T) {
+	tp, err := NewTrackPlayer(WithNormalization(true))
+	require.NoError(t, err)
+	require.NotNil(t, tp)
+	assert.True(t, tp.NormalizationEnabled())
+}
+
+func TestNormalize(t *testing.T) {
+	startTrackPlayerTest(t, func(track *chipmusic.Track, tp *TrackPlayer) {
+		err := tp.Play(track)
+		require.NoError(t, err)
+		assert.False(t, tp.NormalizationEnabled())
+
+		// Enable and then disable normalization
+		tp.Normalize()
+		assert.True(t, tp.NormalizationEnabled())
+		tp.Normalize()
+		assert.False(t, tp.NormalizationEnabled())
+	})
+}
+
+func TestWithVolume(t *testing.T) {
+	testCases := []struct {
+		name        string
+		volume      int
+		expectError bool
+	}{
+		{"Silent", MinVolume, false},
+		{"Half", 50, false},
+		{"TooLow", MinVolume - 1, true},
+		{"TooHigh", MaxVolume + 1, true},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			tp, err := NewTrackPlayer(WithVolume(testCase.volume))
+			if testCase.expectError {
+				assert.Error(tt, err)
+				assert.Nil(tt, tp)
+				return
+			}
+
+			require.NoError(tt, err)
+			assert.Equal(tt, testCase.volume, tp.Volume())
+		})
+	}
+}
+
+func TestSetVolume(t *testing.T) {
+	startTrackPlayerTest(t, func(track *chipmusic.Track, tp *TrackPlayer) {
+		err := tp.Play(track)
+		require.NoError(t, err)
+		assert.Equal(t, DefaultVolume, tp.Volume())
+
+		assert.Equal(t, 40, tp.SetVolume(40))
+		assert.Equal(t, 40, tp.Volume())
+
+		// Volumes out of range are clamped
+		assert.Equal(t, MaxVolume, tp.SetVolume(MaxVolume+VolumeStep))
+		assert.Equal(t, MinVolume, tp.SetVolume(MinVolume-VolumeStep))
+		assert.Equal(t, MinVolume, tp.Volume())
+	})
+}
+
+func TestPlayFrom(t *testing.T) {
+	testCases := []struct {
+		name   string
+		offset time.Duration
+	}{
+		{"Start", 0},
+		{"Middle", 500 * time.Millisecond},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			startTrackPlayerTest(tt, func(track *chipmusic.Track, tp *TrackPlayer) {
+				err := tp.PlayFrom(track, testCase.offset)
+				require.NoError(tt, err)
+				assert.True(tt, tp.CurrentTime() >= testCase.offset)
+			})
+		})
+	}
+}
+
+func TestPlayFrom_PastEnd(t *testing.T) {
+	startTrackPlayerTest(t, func(track *chipmusic.Track, tp *TrackPlayer) {
+		err := tp.PlayFrom(track, time.Hour)
+		require.NoError(t, err)
+	})
+}
+
+func TestPlayJingle(t *testing.T) {
+	tp, err := NewTrackPlayer()
+	require.NoError(t, err)
+
+	defer tp.Close()
+
+	require.NoError(t, tp.PlayJingle(StartupJingle))
+	assert.Equal(t, StartupJingle.Duration(), tp.TotalTime())
+
+	select {
+	case <-tp.Done():
+	case <-time.After(defaultTestTimeout):
+		t.Fatalf("jingle did not finish playing after %s", defaultTestTimeout)
+	}
+
+	assert.Error(t, tp.PlayJingle(nil))
+}
+
+func TestWithTracer(t *testing.T) {
+	tp, err := NewTrackPlayer(WithTracer(nil))
+	assert.Error(t, err)
+	assert.Nil(t, tp)
+}
+
+func TestPlay_CorruptTrack(t *testing.T) {
+	testCases := []struct {
+		name     string
+		fileType chipmusic.AudioFileType
+		content  []byte
+	}{
+		{"Empty", chipmusic.AudioFileTypeMP3, []byte{}},
+		{"NotAudio", chipmusic.AudioFileTypeMP3, []byte("some.content")},
+		{"NotWAV", chipmusic.AudioFileTypeWAV, []byte("some.content")},
+		{"NotOGG", chipmusic.AudioFileTypeOGG, []byte("some.content")},
+		{"TruncatedOGG", chipmusic.AudioFileTypeOGG, []byte("OggSsome.content")},
+		{"NotFLAC", chipmusic.AudioFileTypeFLAC, []byte("some.content")},
+		{"NotModule", chipmusic.AudioFileTypeXM, []byte("some.content")},
+		{"TruncatedModule", chipmusic.AudioFileTypeXM, []byte("Extended Module: some.content")},
+		{"NotChiptune", chipmusic.AudioFileTypeNSF, []byte("some.content")},
+		{"TruncatedChiptune", chipmusic.AudioFileTypeSPC, []byte("SNES-SPC700 Sound File Data")},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			tp, err := NewTrackPlayer()
+			require.NoError(tt, err)
+			require.NotNil(tt, tp)
+
+			track := &chipmusic.Track{
+				Title:    "some.title",
+				Artist:   "some.artist",
+				FileType: testCase.fileType,
+				Reader:   &chipmusic.ReadSeekNopCloser{Reader: bytes.NewReader(testCase.content)},
+			}
+
+			err = tp.Play(track)
+			assert.Error(tt, err)
+			assert.True(tt, errors.Is(err, ErrCorruptTrack))
+		})
+	}
+}
+
+// recordingSink is a Sink which records how it is used without playing anything
+type recordingSink struct {
+	sync.Mutex
+	inits  []beep.SampleRate
+	plays  int
+	clears int
+}
+
+func (r *recordingSink) Init(sampleRate beep.SampleRate, _ int) error {
+	r.inits = append(r.inits, sampleRate)
+	return nil
+}
+
+func (r *recordingSink) Play(beep.Streamer) {
+	r.plays++
+}
+
+func (r *recordingSink) Clear() {
+	r.clears++
+}
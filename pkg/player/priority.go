@@ -0,0 +1,80 @@
+package player
+
+import (
+	"errors"
+	"github.com/faiface/beep"
+	"sync"
+)
+
+const (
+	// ThreadPriorityRealtime means the thread is scheduled ahead of every normal thread of the system
+	ThreadPriorityRealtime ThreadPriority = "real-time"
+
+	// ThreadPriorityHigh means the thread keeps the normal scheduling policy but is favored over other normal threads
+	ThreadPriorityHigh ThreadPriority = "high"
+)
+
+// ErrPriorityUnsupported is returned when the priority of a thread cannot be raised on this system
+var ErrPriorityUnsupported = errors.New("raising the priority of the audio thread is not supported on this system")
+
+// ThreadPriority is how far RaisePriority raised the priority of a thread
+type ThreadPriority string
+
+// WithRealtimePriority allows raising the priority of the thread the speaker streams audio on, so decoding and effects
+// keep up on loaded systems instead of causing buffer underruns. Only the speaker is affected, since other sinks such as
+// FileSink don't play in real time. Whether the priority was raised is emitted as PriorityRaised
+func WithRealtimePriority(enabled bool) Option {
+	return func(player *TrackPlayer) error {
+		player.realtime = enabled
+		return nil
+	}
+}
+
+// prioritySink raises the priority of the thread its sink streams audio on the first time the sink asks for audio.
+// Initializing the sink again starts a new thread, so the priority is raised again afterwards
+type prioritySink struct {
+	Sink
+
+	// raise raises the priority of the calling thread, and raised is called with the result
+	raise  func() (ThreadPriority, error)
+	raised func(priority ThreadPriority, err error)
+
+	mux  sync.Mutex
+	done bool
+}
+
+func (s *prioritySink) Init(sampleRate beep.SampleRate, bufferSize int) error {
+	s.mux.Lock()
+	s.done = false
+	s.mux.Unlock()
+
+	return s.Sink.Init(sampleRate, bufferSize)
+}
+
+func (s *prioritySink) Play(streamer beep.Streamer) {
+	s.Sink.Play(&priorityStreamer{Streamer: streamer, sink: s})
+}
+
+// raiseOnce raises the priority of the calling thread unless it was already raised since the sink was initialized
+func (s *prioritySink) raiseOnce() {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if s.done {
+		return
+	}
+
+	s.done = true
+	s.raised(s.raise())
+}
+
+// priorityStreamer raises the priority of the thread it is streamed on before streaming its streamer
+type priorityStreamer struct {
+	beep.Streamer
+	sink *prioritySink
+}
+
+func (p *priorityStreamer) Stream(samples [][2]float64) (int, bool) {
+	p.sink.raiseOnce()
+	return p.Streamer.Stream(samples)
+}
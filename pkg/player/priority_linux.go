@@ -0,0 +1,48 @@
+// +build linux
+
+package player
+
+import (
+	"fmt"
+	"runtime"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	// schedRR is the SCHED_RR scheduling policy, and realtimePriority is the priority the thread gets under it. The
+	// priority is kept low so sound servers and the kernel's own real-time threads still go first
+	schedRR          = 2
+	realtimePriority = 10
+
+	// highNice is the niceness the thread gets when real-time scheduling isn't allowed
+	highNice = -10
+)
+
+// schedParam is the sched_param structure taken by sched_setscheduler
+type schedParam struct {
+	priority int32
+}
+
+// RaisePriority locks the calling goroutine to its thread and raises the priority of the thread. Real-time round-robin
+// scheduling is tried first, which needs CAP_SYS_NICE or an RLIMIT_RTPRIO of at least 10, and a niceness of -10 after
+// that, which needs CAP_SYS_NICE or an RLIMIT_NICE of at least 30. The goroutine stays locked to its thread unless an
+// error is returned, so it shouldn't be called from goroutines which don't stream audio
+func RaisePriority() (ThreadPriority, error) {
+	runtime.LockOSThread()
+
+	// Both calls only change the calling thread when given its thread ID
+	tid := syscall.Gettid()
+	param := schedParam{priority: realtimePriority}
+	_, _, errno := syscall.RawSyscall(syscall.SYS_SCHED_SETSCHEDULER, uintptr(tid), schedRR, uintptr(unsafe.Pointer(&param)))
+	if errno == 0 {
+		return ThreadPriorityRealtime, nil
+	}
+
+	if err := syscall.Setpriority(syscall.PRIO_PROCESS, tid, highNice); err != nil {
+		runtime.UnlockOSThread()
+		return "", fmt.Errorf("failed to raise the priority of the audio thread: %w", err)
+	}
+
+	return ThreadPriorityHigh, nil
+}
@@ -0,0 +1,9 @@
+// +build !linux,!windows
+
+package player
+
+// RaisePriority raises the priority of the calling thread. Raising it is only supported on Linux and Windows, so
+// ErrPriorityUnsupported is always returned
+func RaisePriority() (ThreadPriority, error) {
+	return "", ErrPriorityUnsupported
+}
@@ -0,0 +1,70 @@
+package player
+
+import (
+	"errors"
+	"github.com/faiface/beep"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestPrioritySink(t *testing.T) {
+	sink := &streamingSink{}
+	raises := 0
+	var results []error
+	priority := &prioritySink{
+		Sink: sink,
+		raise: func() (ThreadPriority, error) {
+			raises++
+			if raises > 1 {
+				return "", errors.New("some.error")
+			}
+
+			return ThreadPriorityRealtime, nil
+		},
+		raised: func(priority ThreadPriority, err error) {
+			results = append(results, err)
+		},
+	}
+
+	assert.NoError(t, priority.Init(DefaultSampleRate, 512))
+	priority.Play(beep.Silence(4))
+	assert.Equal(t, 0, raises, "expected the priority to be raised once audio is streamed")
+
+	samples := make([][2]float64, 2)
+	n, ok := sink.streamer.Stream(samples)
+	assert.Equal(t, 2, n)
+	assert.True(t, ok)
+
+	priority.Play(beep.Silence(4))
+	sink.streamer.Stream(samples)
+	assert.Equal(t, 1, raises, "expected the priority to be raised once per initialization")
+
+	assert.NoError(t, priority.Init(DefaultSampleRate, 512))
+	sink.streamer.Stream(samples)
+	assert.Equal(t, 2, raises, "expected the priority to be raised again after initializing the sink")
+	if assert.Len(t, results, 2) {
+		assert.NoError(t, results[0])
+		assert.Error(t, results[1])
+	}
+}
+
+func TestWithRealtimePriority(t *testing.T) {
+	tp, err := NewTrackPlayer(WithRealtimePriority(true))
+	assert.NoError(t, err)
+	assert.IsType(t, &prioritySink{}, tp.sink)
+
+	sink := &recordingSink{}
+	tp, err = NewTrackPlayer(WithSink(sink), WithRealtimePriority(true))
+	assert.NoError(t, err)
+	assert.Equal(t, sink, tp.sink, "expected sinks other than the speaker to be left alone")
+}
+
+// streamingSink is a Sink which keeps the last streamer played so the test can stream it
+type streamingSink struct {
+	recordingSink
+	streamer beep.Streamer
+}
+
+func (s *streamingSink) Play(streamer beep.Streamer) {
+	s.streamer = streamer
+}
@@ -0,0 +1,33 @@
+// +build windows
+
+package player
+
+import (
+	"fmt"
+	"runtime"
+	"syscall"
+)
+
+// threadPriorityTimeCritical is THREAD_PRIORITY_TIME_CRITICAL, the highest priority of threads in a normal process
+const threadPriorityTimeCritical = 15
+
+var (
+	kernel32          = syscall.NewLazyDLL("kernel32.dll")
+	getCurrentThread  = kernel32.NewProc("GetCurrentThread")
+	setThreadPriority = kernel32.NewProc("SetThreadPriority")
+)
+
+// RaisePriority locks the calling goroutine to its thread and raises the priority of the thread to time-critical, which
+// runs it ahead of every other thread of normal processes. The goroutine stays locked to its thread unless an error is
+// returned, so it shouldn't be called from goroutines which don't stream audio
+func RaisePriority() (ThreadPriority, error) {
+	runtime.LockOSThread()
+
+	thread, _, _ := getCurrentThread.Call()
+	if ok, _, err := setThreadPriority.Call(thread, threadPriorityTimeCritical); ok == 0 {
+		runtime.UnlockOSThread()
+		return "", fmt.Errorf("failed to raise the priority of the audio thread: %w", err)
+	}
+
+	return ThreadPriorityRealtime, nil
+}
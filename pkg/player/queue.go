@@ -0,0 +1,292 @@
+package player
+
+import (
+	"fmt"
+	"github.com/broar/chipmusic-cli/pkg/chipmusic"
+	"github.com/faiface/beep"
+	"time"
+)
+
+const (
+	// PriorityEnd queues a track after every track in the queue
+	PriorityEnd Priority = iota
+
+	// PriorityNext queues a track in front of every track in the queue, so it plays right after the current track.
+	// Tracks queued next one after another play in the reverse order they were queued
+	PriorityNext
+)
+
+const (
+	// maxPreviousTracks is how many played tracks are kept open so Previous can go back to them
+	maxPreviousTracks = 10
+
+	// resampleQuality is the quality queued tracks are resampled with when their sample rate differs from the speaker's
+	resampleQuality = 4
+)
+
+// queuedTrack is a track which was decoded ahead of playing it
+type queuedTrack struct {
+	track  *chipmusic.Track
+	stream beep.StreamSeekCloser
+	format beep.Format
+	offset time.Duration
+}
+
+// Priority is where in the queue Enqueue puts a track
+type Priority int
+
+// closeTracks closes the streams of tracks
+func closeTracks(tracks []*queuedTrack) {
+	for _, queued := range tracks {
+		queued.stream.Close()
+	}
+}
+
+// Enqueue adds a track to the queue, at the end or at the front depending on priority. The track is decoded right away,
+// so it starts without a gap once the tracks before it finish. If nothing is playing, the track starts playing
+// immediately. Play drops the queue. The same rules for calling Play apply to this method
+func (t *TrackPlayer) Enqueue(track *chipmusic.Track, priority Priority) error {
+	return t.EnqueueFrom(track, 0, priority)
+}
+
+// EnqueueFrom adds a track to the queue which starts playing from offset, like PlayFrom. The same rules for calling
+// Enqueue apply to this method
+func (t *TrackPlayer) EnqueueFrom(track *chipmusic.Track, offset time.Duration, priority Priority) error {
+	stream, format, err := t.decodeFrom(track, offset)
+	if err != nil {
+		return err
+	}
+
+	// The speaker moves to the next track while holding its lock, so the queue can't run out while the track is added
+	t.sink.Lock()
+	t.mux.Lock()
+	idle := t.current == nil || t.ctx == nil || t.ctx.Err() != nil
+	if !idle {
+		queued := &queuedTrack{track: track, stream: stream, format: format, offset: offset}
+		if priority == PriorityNext {
+			t.queue = append([]*queuedTrack{queued}, t.queue...)
+		} else {
+			t.queue = append(t.queue, queued)
+		}
+
+		t.emitQueue()
+	}
+
+	t.mux.Unlock()
+	t.sink.Unlock()
+
+	if idle {
+		return t.play(track, offset, stream, format)
+	}
+
+	return nil
+}
+
+// Queue returns the tracks in the queue in the order they will be played
+func (t *TrackPlayer) Queue() []*chipmusic.Track {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+
+	return t.queuedTracks()
+}
+
+// queuedTracks returns the tracks in the queue. The player must be locked by the caller
+func (t *TrackPlayer) queuedTracks() []*chipmusic.Track {
+	tracks := make([]*chipmusic.Track, 0, len(t.queue))
+	for _, queued := range t.queue {
+		tracks = append(tracks, queued.track)
+	}
+
+	return tracks
+}
+
+// emitQueue reports the tracks in the queue along with their lengths after it changed. The player must be locked by
+// the caller
+func (t *TrackPlayer) emitQueue() {
+	lengths := make([]time.Duration, 0, len(t.queue))
+	for _, queued := range t.queue {
+		lengths = append(lengths, queued.format.SampleRate.D(queued.stream.Len()-queued.stream.Position()))
+	}
+
+	t.emit(QueueChanged{Queue: t.queuedTracks(), Lengths: lengths})
+}
+
+// ClearQueue removes every track from the queue. The current track keeps playing
+func (t *TrackPlayer) ClearQueue() {
+	t.sink.Lock()
+	defer t.sink.Unlock()
+
+	t.mux.Lock()
+	defer t.mux.Unlock()
+
+	closeTracks(t.queue)
+	t.queue = nil
+	t.emitQueue()
+}
+
+// Next finishes the current track early so the next track in the queue starts playing. If the queue is empty, playback
+// finishes like Skip. If there is no track currently playing, this method does nothing
+func (t *TrackPlayer) Next() error {
+	return t.Skip()
+}
+
+// Previous plays the track played before the current track from its start and puts the current track back at the front
+// of the queue. If no track was played before, the current track restarts. If there is no track currently playing,
+// this method does nothing
+func (t *TrackPlayer) Previous() error {
+	t.sink.Lock()
+	defer t.sink.Unlock()
+	if t.ctrl == nil {
+		return nil
+	}
+
+	t.mux.Lock()
+	defer t.mux.Unlock()
+
+	if t.ctx == nil || t.ctx.Err() != nil {
+		return nil
+	}
+
+	if len(t.previous) == 0 {
+		return t.seek(0)
+	}
+
+	previous := t.previous[len(t.previous)-1]
+	if err := previous.stream.Seek(0); err != nil {
+		return fmt.Errorf("failed to seek to start of previous track: %w", err)
+	}
+
+	if err := t.current.Seek(0); err != nil {
+		return fmt.Errorf("failed to seek to start of track: %w", err)
+	}
+
+	t.previous = t.previous[:len(t.previous)-1]
+	current := &queuedTrack{track: t.track, stream: t.current, format: t.format}
+	t.queue = append([]*queuedTrack{current}, t.queue...)
+
+	previous.offset = 0
+	t.start(previous)
+	t.emitQueue()
+	return nil
+}
+
+// JumpTo plays the track at index in the queue right away, where 0 is the next track. The tracks before it are removed
+// from the queue, while the current track can still be played again with Previous. If there is no track currently
+// playing, this method does nothing
+func (t *TrackPlayer) JumpTo(index int) error {
+	t.sink.Lock()
+	defer t.sink.Unlock()
+	if t.ctrl == nil {
+		return nil
+	}
+
+	t.mux.Lock()
+	defer t.mux.Unlock()
+
+	if t.ctx == nil || t.ctx.Err() != nil {
+		return nil
+	}
+
+	if index < 0 || index >= len(t.queue) {
+		return fmt.Errorf("no track at position %d of the queue of %d tracks", index+1, len(t.queue))
+	}
+
+	next := t.queue[index]
+	closeTracks(t.queue[:index])
+	t.queue = t.queue[index+1:]
+
+	t.keepPrevious()
+	t.start(next)
+	t.emitQueue()
+	return nil
+}
+
+// advance replaces the finished current track with the next track in the queue. It returns false if the queue is
+// empty. The speaker must be locked by the caller
+func (t *TrackPlayer) advance() bool {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+
+	if len(t.queue) == 0 {
+		return false
+	}
+
+	next := t.queue[0]
+	t.queue = t.queue[1:]
+
+	t.finish()
+	t.keepPrevious()
+	t.start(next)
+	t.emitQueue()
+	return true
+}
+
+// keepPrevious keeps the current track open so Previous can go back to it, closing the oldest track kept if there are
+// too many. The player must be locked by the caller
+func (t *TrackPlayer) keepPrevious() {
+	t.previous = append(t.previous, &queuedTrack{track: t.track, stream: t.current, format: t.format})
+	if len(t.previous) > maxPreviousTracks {
+		t.previous[0].stream.Close()
+		t.previous = t.previous[1:]
+	}
+}
+
+// start makes queued the current track. The speaker and the player must be locked by the caller
+func (t *TrackPlayer) start(queued *queuedTrack) {
+	t.current = queued.stream
+	t.track = queued.track
+	t.format = queued.format
+	t.looping = false
+	t.ctrl.Streamer = t.resample(queued.stream, queued.format)
+	t.emit(TrackStarted{Track: queued.track, Offset: queued.offset, Length: queued.format.SampleRate.D(queued.stream.Len())})
+}
+
+// finish reports that the current track played to its end. The player must be locked by the caller
+func (t *TrackPlayer) finish() {
+	if err := t.current.Err(); err != nil {
+		t.emit(Error{Err: fmt.Errorf("failed to play track audio: %w", err)})
+	}
+
+	t.emit(Finished{Track: t.track})
+}
+
+// resample resamples stream from format to the sample rate of the speaker if they differ
+func (t *TrackPlayer) resample(stream beep.Streamer, format beep.Format) beep.Streamer {
+	if format.SampleRate == t.rate {
+		return stream
+	}
+
+	return beep.Resample(resampleQuality, format.SampleRate, t.rate, stream)
+}
+
+// gapless streams the current track and moves on to the next track in the queue as soon as it finishes, within the same
+// buffer, so there is no gap between tracks
+type gapless struct {
+	player *TrackPlayer
+}
+
+// Stream streams the current track and then the tracks in the queue. It finishes once the queue is empty
+func (g *gapless) Stream(samples [][2]float64) (n int, ok bool) {
+	for n < len(samples) {
+		sn, sok := g.player.ctrl.Stream(samples[n:])
+		n += sn
+		if sok {
+			if sn == 0 {
+				break
+			}
+
+			continue
+		}
+
+		if !g.player.advance() {
+			return n, n > 0
+		}
+	}
+
+	return n, true
+}
+
+// Err always returns nil since errors of tracks are reported when they finish
+func (g *gapless) Err() error {
+	return nil
+}
@@ -0,0 +1,236 @@
+package player
+
+import (
+	"errors"
+	"github.com/broar/chipmusic-cli/pkg/chipmusic"
+	"github.com/faiface/beep"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"testing"
+	"time"
+)
+
+// openTestTrack opens the test audio as a track titled title
+func openTestTrack(t *testing.T, title string) *chipmusic.Track {
+	file, err := os.Open(testAudio)
+	require.NoError(t, err)
+
+	return &chipmusic.Track{Title: title, FileType: chipmusic.AudioFileTypeMP3, Reader: file}
+}
+
+// nextStarted returns the track of the next TrackStarted event of tp
+func nextStarted(t *testing.T, tp *TrackPlayer) *chipmusic.Track {
+	timer := time.After(defaultTestTimeout)
+	for {
+		select {
+		case event := <-tp.Events():
+			if started, ok := event.(TrackStarted); ok {
+				return started.Track
+			}
+		case <-timer:
+			require.FailNow(t, "no track started")
+		}
+	}
+}
+
+func TestEnqueue(t *testing.T) {
+	tp, err := NewTrackPlayer()
+	require.NoError(t, err)
+
+	defer tp.Close()
+
+	first, second := openTestTrack(t, "first"), openTestTrack(t, "second")
+	defer first.Close()
+	defer second.Close()
+
+	// Nothing is playing, so the first track starts right away
+	require.NoError(t, tp.Enqueue(first, PriorityEnd))
+	assert.Equal(t, first, nextStarted(t, tp))
+	assert.Empty(t, tp.Queue())
+
+	require.NoError(t, tp.Enqueue(second, PriorityEnd))
+	assert.Equal(t, []*chipmusic.Track{second}, tp.Queue())
+
+	require.NoError(t, tp.Next())
+	assert.Equal(t, second, nextStarted(t, tp))
+	assert.Empty(t, tp.Queue())
+
+	select {
+	case <-tp.Done():
+		require.FailNow(t, "player finished before the queued track")
+	default:
+	}
+
+	require.NoError(t, tp.Previous())
+	assert.Equal(t, first, nextStarted(t, tp))
+	assert.Equal(t, []*chipmusic.Track{second}, tp.Queue())
+	assert.True(t, tp.CurrentTime() < time.Second)
+
+	tp.ClearQueue()
+	assert.Empty(t, tp.Queue())
+
+	require.NoError(t, tp.Next())
+	select {
+	case <-tp.Done():
+	case <-time.After(defaultTestTimeout):
+		require.FailNow(t, "player did not finish after the queue was cleared")
+	}
+}
+
+func TestEnqueue_PriorityNext(t *testing.T) {
+	tp, err := NewTrackPlayer()
+	require.NoError(t, err)
+
+	defer tp.Close()
+
+	first, second, third, fourth := openTestTrack(t, "first"), openTestTrack(t, "second"), openTestTrack(t, "third"), openTestTrack(t, "fourth")
+	defer first.Close()
+	defer second.Close()
+	defer third.Close()
+	defer fourth.Close()
+
+	// Nothing is playing, so the first track starts right away whatever its priority
+	require.NoError(t, tp.Enqueue(first, PriorityNext))
+	assert.Equal(t, first, nextStarted(t, tp))
+
+	require.NoError(t, tp.Enqueue(second, PriorityEnd))
+	require.NoError(t, tp.Enqueue(third, PriorityNext))
+	assert.Equal(t, []*chipmusic.Track{third, second}, tp.Queue())
+
+	require.NoError(t, tp.Enqueue(fourth, PriorityNext))
+	assert.Equal(t, []*chipmusic.Track{fourth, third, second}, tp.Queue())
+
+	require.NoError(t, tp.Next())
+	assert.Equal(t, fourth, nextStarted(t, tp))
+}
+
+func TestJumpTo(t *testing.T) {
+	tp, err := NewTrackPlayer()
+	require.NoError(t, err)
+
+	defer tp.Close()
+
+	first, second, third, fourth := openTestTrack(t, "first"), openTestTrack(t, "second"), openTestTrack(t, "third"), openTestTrack(t, "fourth")
+	defer first.Close()
+	defer second.Close()
+	defer third.Close()
+	defer fourth.Close()
+
+	require.NoError(t, tp.Enqueue(first, PriorityEnd))
+	assert.Equal(t, first, nextStarted(t, tp))
+	for _, track := range []*chipmusic.Track{second, third, fourth} {
+		require.NoError(t, tp.Enqueue(track, PriorityEnd))
+	}
+
+	assert.Error(t, tp.JumpTo(3))
+	assert.Error(t, tp.JumpTo(-1))
+
+	require.NoError(t, tp.JumpTo(1))
+	assert.Equal(t, third, nextStarted(t, tp))
+	assert.Equal(t, []*chipmusic.Track{fourth}, tp.Queue())
+	assert.Equal(t, []*chipmusic.Track{fourth}, nextQueue(t, tp))
+
+	// The track playing before the jump is kept, but the tracks skipped over are dropped
+	require.NoError(t, tp.Previous())
+	assert.Equal(t, first, nextStarted(t, tp))
+	assert.Equal(t, []*chipmusic.Track{third, fourth}, tp.Queue())
+}
+
+func TestEnqueue_Lengths(t *testing.T) {
+	tp, err := NewTrackPlayer()
+	require.NoError(t, err)
+
+	defer tp.Close()
+
+	first, second := openTestTrack(t, "first"), openTestTrack(t, "second")
+	defer first.Close()
+	defer second.Close()
+
+	require.NoError(t, tp.Enqueue(first, PriorityEnd))
+	assert.Equal(t, first, nextStarted(t, tp))
+
+	require.NoError(t, tp.Enqueue(second, PriorityEnd))
+	changed := nextQueueChanged(t, tp)
+	assert.Equal(t, []*chipmusic.Track{second}, changed.Queue)
+	assert.Equal(t, []time.Duration{tp.TotalTime()}, changed.Lengths, "expected the same audio to be as long as the current track")
+}
+
+// nextQueue returns the queue of the next QueueChanged event of tp
+func nextQueue(t *testing.T, tp *TrackPlayer) []*chipmusic.Track {
+	return nextQueueChanged(t, tp).Queue
+}
+
+// nextQueueChanged returns the next QueueChanged event of tp
+func nextQueueChanged(t *testing.T, tp *TrackPlayer) QueueChanged {
+	timer := time.After(defaultTestTimeout)
+	for {
+		select {
+		case event := <-tp.Events():
+			if changed, ok := event.(QueueChanged); ok {
+				return changed
+			}
+		case <-timer:
+			require.FailNow(t, "queue did not change")
+		}
+	}
+}
+
+func TestEnqueue_Invalid(t *testing.T) {
+	tp, err := NewTrackPlayer()
+	require.NoError(t, err)
+
+	assert.Equal(t, ErrNilTrack, tp.Enqueue(nil, PriorityEnd))
+
+	track := &chipmusic.Track{Reader: &chipmusic.ReadSeekNopCloser{}, FileType: "vgm"}
+	assert.True(t, errors.Is(tp.Enqueue(track, PriorityEnd), ErrUnknownFileFormat))
+	assert.Empty(t, tp.Queue())
+}
+
+func TestQueueControlsWithNoCurrentTrack(t *testing.T) {
+	tp, err := NewTrackPlayer()
+	require.NoError(t, err)
+
+	assert.NoError(t, tp.Next())
+	assert.NoError(t, tp.Previous())
+	assert.NoError(t, tp.JumpTo(0))
+	tp.ClearQueue()
+	assert.Empty(t, tp.Queue())
+}
+
+func TestGapless(t *testing.T) {
+	// Two jingles of 10 and 20 samples at 1kHz follow each other within a single buffer
+	first, second := Jingle{{Frequency: 100, Duration: 10 * time.Millisecond}}, Jingle{{Frequency: 50, Duration: 20 * time.Millisecond}}
+	format := beep.Format{SampleRate: 1000, NumChannels: 2, Precision: 2}
+	tp, err := NewTrackPlayer()
+	require.NoError(t, err)
+
+	tp.current, tp.format, tp.rate = NewSynth(first, format.SampleRate), format, format.SampleRate
+	tp.ctrl = &beep.Ctrl{Streamer: tp.current}
+	tp.queue = []*queuedTrack{{track: &chipmusic.Track{Title: "second"}, stream: NewSynth(second, format.SampleRate), format: format}}
+
+	g := &gapless{player: tp}
+	samples := make([][2]float64, 40)
+	n, ok := g.Stream(samples)
+	assert.True(t, ok)
+	assert.Equal(t, 30, n)
+	assert.Empty(t, tp.queue)
+	assert.Len(t, tp.previous, 1)
+
+	assert.IsType(t, Finished{}, <-tp.Events())
+	assert.Equal(t, "second", (<-tp.Events()).(TrackStarted).Track.Title)
+
+	n, ok = g.Stream(samples)
+	assert.False(t, ok)
+	assert.Equal(t, 0, n)
+}
+
+func TestGapless_Resample(t *testing.T) {
+	tp, err := NewTrackPlayer()
+	require.NoError(t, err)
+
+	tp.rate = 44100
+	stream := NewSynth(StartupJingle, 44100)
+	assert.Equal(t, stream, tp.resample(stream, beep.Format{SampleRate: 44100}))
+	assert.IsType(t, &beep.Resampler{}, tp.resample(stream, beep.Format{SampleRate: 22050}))
+}
@@ -0,0 +1,19 @@
+package player
+
+import (
+	"fmt"
+	"github.com/faiface/beep"
+	"github.com/faiface/beep/wav"
+	"io"
+)
+
+// RenderWAV renders stream to w as a 16-bit stereo WAV file at the sample rate of format. The stream is rendered as
+// fast as it can be decoded rather than in real time, so it isn't played on the speaker
+func RenderWAV(w io.WriteSeeker, stream beep.Streamer, format beep.Format) error {
+	format.NumChannels, format.Precision = 2, 2
+	if err := wav.Encode(w, stream, format); err != nil {
+		return fmt.Errorf("failed to encode WAV: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,36 @@
+package player
+
+import (
+	"github.com/broar/chipmusic-cli/pkg/chipmusic"
+	"github.com/faiface/beep"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestRenderWAV(t *testing.T) {
+	file, err := ioutil.TempFile("", "chipmusic-render-*.wav")
+	require.NoError(t, err)
+
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	jingle := Jingle{{Frequency: 440, Duration: 100 * time.Millisecond}}
+	format := beep.Format{SampleRate: jingleSampleRate, NumChannels: 1, Precision: 1}
+	require.NoError(t, RenderWAV(file, NewSynth(jingle, jingleSampleRate), format))
+
+	// The rendered file plays like any other WAV file
+	_, err = file.Seek(0, 0)
+	require.NoError(t, err)
+
+	stream, decoded, err := Decode(&chipmusic.Track{Reader: file, FileType: chipmusic.AudioFileTypeWAV})
+	require.NoError(t, err)
+
+	defer stream.Close()
+
+	assert.Equal(t, beep.Format{SampleRate: jingleSampleRate, NumChannels: 2, Precision: 2}, decoded)
+	assert.Equal(t, jingleSampleRate.N(100*time.Millisecond), stream.Len())
+}
@@ -0,0 +1,215 @@
+package player
+
+import (
+	"errors"
+	"fmt"
+	"github.com/broar/chipmusic-cli/pkg/chipmusic"
+	"github.com/faiface/beep"
+	"github.com/faiface/beep/speaker"
+	"os"
+	"sync"
+)
+
+// Sink is where a TrackPlayer sends the audio it plays. Its methods mirror the speaker package, which is the default
+// sink: the player initializes the sink once at its sample rate, plays the output pipeline of each track it starts on it
+// after clearing the previous one, and locks it to change the pipeline while it is playing
+type Sink interface {
+	Init(sampleRate beep.SampleRate, bufferSize int) error
+	Play(streamer beep.Streamer)
+	Clear()
+	Lock()
+	Unlock()
+}
+
+// WithSink allows sending the audio to sink instead of the speaker, e.g. a FileSink to render tracks to a file
+func WithSink(sink Sink) Option {
+	return func(player *TrackPlayer) error {
+		if sink == nil {
+			return errors.New("sink cannot be nil")
+		}
+
+		player.sink = sink
+		return nil
+	}
+}
+
+// speakerSink plays audio on the speaker
+type speakerSink struct{}
+
+func (speakerSink) Init(sampleRate beep.SampleRate, bufferSize int) error {
+	return speaker.Init(sampleRate, bufferSize)
+}
+
+func (speakerSink) Play(streamer beep.Streamer) {
+	speaker.Play(streamer)
+}
+
+func (speakerSink) Clear() {
+	speaker.Clear()
+}
+
+func (speakerSink) Lock() {
+	speaker.Lock()
+}
+
+func (speakerSink) Unlock() {
+	speaker.Unlock()
+}
+
+// FileSink is a Sink which renders audio to a 16-bit stereo WAV file instead of playing it, so tracks go through the
+// same pipeline as playback including crossfeed, normalization, and volume. Audio is rendered as fast as it can be
+// decoded rather than in real time. The file has the sample rate the sink is first initialized with, which is the
+// sample rate of the TrackPlayer. Paused tracks are rendered as silence, so a FileSink isn't meant to be paused
+type FileSink struct {
+	file *os.File
+
+	mux  sync.Mutex
+	cond *sync.Cond
+
+	// rate is the sample rate of the file, and playRate is the sample rate of the streamers given to Play
+	rate     beep.SampleRate
+	playRate beep.SampleRate
+
+	// streamers are rendered one after another. The TrackPlayer only plays one streamer at a time, so they don't need
+	// to be mixed
+	streamers []beep.Streamer
+	closed    bool
+
+	// done is closed once the file is written, and err is why it couldn't be
+	done chan struct{}
+	err  error
+}
+
+// NewFileSink creates the file at path to render audio to. Only WAV files can be rendered. Call Close once every track
+// finished to write the file
+func NewFileSink(path string) (*FileSink, error) {
+	switch chipmusic.FileTypeFromPath(path) {
+	case chipmusic.AudioFileTypeWAV:
+	case chipmusic.AudioFileTypeMP3:
+		return nil, errors.New("rendering to MP3 isn't supported, so render to WAV and encode it with an MP3 encoder such as LAME")
+	default:
+		return nil, fmt.Errorf("output file %s must be a WAV file", path)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create output file %s: %w", path, err)
+	}
+
+	sink := &FileSink{file: file, done: make(chan struct{})}
+	sink.cond = sync.NewCond(&sink.mux)
+	return sink, nil
+}
+
+// Init drops the streamers being rendered, like initializing the speaker does, and sets the sample rate of the
+// streamers played next. The first call sets the sample rate of the file and starts rendering
+func (f *FileSink) Init(sampleRate beep.SampleRate, _ int) error {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	if f.closed {
+		return errors.New("file sink is closed")
+	}
+
+	f.streamers = nil
+	f.playRate = sampleRate
+	if f.rate == 0 {
+		f.rate = sampleRate
+		go f.render()
+	}
+
+	return nil
+}
+
+// Play renders streamer once the streamers played before it are done
+func (f *FileSink) Play(streamer beep.Streamer) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	if f.playRate != f.rate {
+		streamer = beep.Resample(resampleQuality, f.playRate, f.rate, streamer)
+	}
+
+	f.streamers = append(f.streamers, streamer)
+	f.cond.Broadcast()
+}
+
+// Clear drops the streamers being rendered
+func (f *FileSink) Clear() {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	f.streamers = nil
+}
+
+// Lock keeps the sink from rendering until Unlock is called
+func (f *FileSink) Lock() {
+	f.mux.Lock()
+}
+
+// Unlock lets the sink render again
+func (f *FileSink) Unlock() {
+	f.mux.Unlock()
+}
+
+// Close stops rendering, dropping any streamers which aren't done, and finishes writing the file. If nothing was played,
+// the file is removed
+func (f *FileSink) Close() error {
+	f.mux.Lock()
+	if f.closed {
+		f.mux.Unlock()
+		return nil
+	}
+
+	f.closed = true
+	started := f.rate != 0
+	f.cond.Broadcast()
+	f.mux.Unlock()
+
+	if !started {
+		f.file.Close()
+		return os.Remove(f.file.Name())
+	}
+
+	<-f.done
+	err := f.err
+	if closeErr := f.file.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to write output file: %w", closeErr)
+	}
+
+	return err
+}
+
+// render writes the streamers to the file until the sink is closed
+func (f *FileSink) render() {
+	defer close(f.done)
+
+	format := beep.Format{SampleRate: f.rate, NumChannels: 2, Precision: 2}
+	f.err = RenderWAV(f.file, beep.StreamerFunc(f.stream), format)
+}
+
+// stream fills samples from the streamers, waiting while there are none. Streamers are streamed with the sink locked,
+// so their callbacks run with the sink locked like they do on the speaker
+func (f *FileSink) stream(samples [][2]float64) (int, bool) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	for len(f.streamers) == 0 && !f.closed {
+		f.cond.Wait()
+	}
+
+	if f.closed {
+		return 0, false
+	}
+
+	filled := 0
+	for filled < len(samples) && len(f.streamers) > 0 {
+		n, ok := f.streamers[0].Stream(samples[filled:])
+		filled += n
+		if !ok || n == 0 {
+			f.streamers = f.streamers[1:]
+		}
+	}
+
+	return filled, true
+}
@@ -0,0 +1,124 @@
+package player
+
+import (
+	"github.com/broar/chipmusic-cli/pkg/chipmusic"
+	"github.com/faiface/beep"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNewFileSink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chipmusic-sink")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	tests := map[string]struct {
+		name  string
+		isErr bool
+	}{
+		"wav":   {name: "out.wav"},
+		"mp3":   {name: "out.mp3", isErr: true},
+		"other": {name: "out.txt", isErr: true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(tt *testing.T) {
+			sink, err := NewFileSink(filepath.Join(dir, test.name))
+			if test.isErr {
+				assert.Error(tt, err)
+				assert.Nil(tt, sink)
+				return
+			}
+
+			require.NoError(tt, err)
+
+			// Nothing was played, so no file is left behind
+			assert.NoError(tt, sink.Close())
+			_, err = os.Stat(filepath.Join(dir, test.name))
+			assert.True(tt, os.IsNotExist(err))
+		})
+	}
+}
+
+func TestWithSink(t *testing.T) {
+	tp, err := NewTrackPlayer(WithSink(nil))
+	assert.Error(t, err)
+	assert.Nil(t, tp)
+}
+
+func TestFileSink_TrackPlayer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chipmusic-sink")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	out := filepath.Join(dir, "out.wav")
+	sink, err := NewFileSink(out)
+	require.NoError(t, err)
+
+	tp, err := NewTrackPlayer(WithSink(sink))
+	require.NoError(t, err)
+
+	require.NoError(t, tp.PlayJingle(StartupJingle))
+	select {
+	case <-tp.Done():
+	case <-time.After(defaultTestTimeout):
+		t.Fatalf("jingle did not finish rendering after %s", defaultTestTimeout)
+	}
+
+	require.NoError(t, tp.Close())
+	require.NoError(t, sink.Close())
+
+	stream, format := decodeTestWAV(t, out)
+	assert.Equal(t, jingleSampleRate, format.SampleRate)
+	assert.Equal(t, jingleSampleRate.N(StartupJingle.Duration()), stream.Len())
+}
+
+func TestFileSink_Resamples(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chipmusic-sink")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	out := filepath.Join(dir, "out.wav")
+	sink, err := NewFileSink(out)
+	require.NoError(t, err)
+
+	// Each second of audio is rendered at the sample rate of the first one
+	for _, rate := range []beep.SampleRate{22050, 44100} {
+		done := make(chan struct{})
+		require.NoError(t, sink.Init(rate, rate.N(DefaultBufferSize)))
+		sink.Play(beep.Seq(beep.Silence(rate.N(time.Second)), beep.Callback(func() { close(done) })))
+
+		select {
+		case <-done:
+		case <-time.After(defaultTestTimeout):
+			t.Fatalf("audio at %d Hz did not finish rendering after %s", rate, defaultTestTimeout)
+		}
+	}
+
+	require.NoError(t, sink.Close())
+	assert.Error(t, sink.Init(44100, 4410))
+
+	stream, format := decodeTestWAV(t, out)
+	assert.Equal(t, beep.SampleRate(22050), format.SampleRate)
+	assert.InDelta(t, 2*22050, stream.Len(), 100)
+}
+
+// decodeTestWAV decodes the WAV file at path and closes it once the test is done
+func decodeTestWAV(t *testing.T, path string) (beep.StreamSeekCloser, beep.Format) {
+	file, err := os.Open(path)
+	require.NoError(t, err)
+
+	stream, format, err := Decode(&chipmusic.Track{Reader: file, FileType: chipmusic.AudioFileTypeWAV})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = stream.Close() })
+
+	return stream, format
+}
@@ -0,0 +1,89 @@
+package player
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	// DefaultSuspendInterval is how often the clocks are compared to detect a suspend
+	DefaultSuspendInterval = time.Second
+
+	// DefaultSuspendThreshold is how far the wall clock must jump ahead of the monotonic clock before it is considered
+	// a suspend. It leaves room for the wall clock being adjusted, e.g. by NTP
+	DefaultSuspendThreshold = 5 * time.Second
+)
+
+// SuspendDetector detects when the system was suspended and resumed. While the system is suspended, the wall clock
+// keeps moving but the monotonic clock does not, so a suspend shows up as the wall clock jumping ahead of the monotonic
+// clock
+type SuspendDetector struct {
+	interval  time.Duration
+	threshold time.Duration
+
+	// clock returns the wall clock and the monotonic clock
+	clock func() (time.Time, time.Duration)
+}
+
+// NewSuspendDetector creates a SuspendDetector which compares the clocks every interval and reports a suspend when the
+// wall clock jumps ahead of the monotonic clock by more than threshold
+func NewSuspendDetector(interval, threshold time.Duration) *SuspendDetector {
+	start := time.Now()
+	return &SuspendDetector{
+		interval:  interval,
+		threshold: threshold,
+		clock: func() (time.Time, time.Duration) {
+			now := time.Now()
+
+			// Rounding strips the monotonic reading, so the subtraction uses the wall clock
+			return now.Round(0), now.Sub(start)
+		},
+	}
+}
+
+// Watch returns a channel receiving how long the system was suspended each time it resumes. The channel is closed once
+// ctx is done
+func (d *SuspendDetector) Watch(ctx context.Context) <-chan time.Duration {
+	resumes := make(chan time.Duration)
+	wall, monotonic := d.clock()
+	go func() {
+		defer close(resumes)
+
+		ticker := time.NewTicker(d.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			nextWall, nextMonotonic := d.clock()
+			suspended, ok := suspendedFor(nextWall.Sub(wall), nextMonotonic-monotonic, d.threshold)
+			wall, monotonic = nextWall, nextMonotonic
+			if !ok {
+				continue
+			}
+
+			select {
+			case resumes <- suspended:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return resumes
+}
+
+// suspendedFor returns how long the system was suspended given how far the wall clock and the monotonic clock moved.
+// If the difference is within threshold, the system was not suspended and ok is false
+func suspendedFor(wall, monotonic, threshold time.Duration) (suspended time.Duration, ok bool) {
+	suspended = wall - monotonic
+	if suspended <= threshold {
+		return 0, false
+	}
+
+	return suspended, true
+}
@@ -0,0 +1,69 @@
+package player
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSuspendedFor(t *testing.T) {
+	testCases := []struct {
+		name      string
+		wall      time.Duration
+		monotonic time.Duration
+		expected  time.Duration
+		ok        bool
+	}{
+		{"InSync", time.Second, time.Second, 0, false},
+		{"ClockAdjusted", 3 * time.Second, time.Second, 0, false},
+		{"ClockMovedBack", -time.Hour, time.Second, 0, false},
+		{"Suspended", time.Hour + time.Second, time.Second, time.Hour, true},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			suspended, ok := suspendedFor(testCase.wall, testCase.monotonic, DefaultSuspendThreshold)
+			assert.Equal(tt, testCase.ok, ok)
+			assert.Equal(tt, testCase.expected, suspended)
+		})
+	}
+}
+
+func TestSuspendDetector_Watch(t *testing.T) {
+	mux := sync.Mutex{}
+	wall, monotonic := time.Now(), time.Duration(0)
+	detector := &SuspendDetector{
+		interval:  time.Millisecond,
+		threshold: DefaultSuspendThreshold,
+		clock: func() (time.Time, time.Duration) {
+			mux.Lock()
+			defer mux.Unlock()
+
+			// Both clocks move together, except the wall clock jumps an hour ahead once
+			wall, monotonic = wall.Add(time.Millisecond), monotonic+time.Millisecond
+			return wall, monotonic
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	resumes := detector.Watch(ctx)
+
+	mux.Lock()
+	wall = wall.Add(time.Hour)
+	mux.Unlock()
+
+	select {
+	case suspended := <-resumes:
+		assert.Equal(t, time.Hour, suspended)
+	case <-time.After(defaultTestTimeout):
+		require.FailNow(t, "suspend was not detected")
+	}
+
+	cancel()
+	for range resumes {
+		require.FailNow(t, "only one suspend should be detected")
+	}
+}
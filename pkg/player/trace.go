@@ -0,0 +1,170 @@
+package player
+
+import (
+	"fmt"
+	"github.com/faiface/beep"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// traceBufferSize is the number of trace records buffered for writing before further records are dropped
+	traceBufferSize = 1024
+
+	traceTimeFormat = "2006/01/02 15:04:05.000000"
+)
+
+// Tracer logs diagnostics about the playback pipeline such as decode timings, buffer fill levels, and underruns. The
+// output is meant to be attached to reports of crackling or stuttering playback. Records are written by a goroutine of
+// their own so tracing never blocks the speaker on I/O. If records arrive faster than they are written, they are
+// dropped and the number dropped is logged instead
+type Tracer struct {
+	// dropped is first so it is aligned for atomic operations on 32-bit platforms
+	dropped uint64
+	w       io.Writer
+	records chan traceRecord
+	quit    chan struct{}
+	done    chan struct{}
+	once    sync.Once
+}
+
+// traceRecord is a line of the trace along with the time it was recorded at
+type traceRecord struct {
+	time time.Time
+	line string
+}
+
+// NewTracer returns a Tracer which writes to w. The tracer must be closed to write the records still buffered
+func NewTracer(w io.Writer) *Tracer {
+	t := &Tracer{
+		w:       w,
+		records: make(chan traceRecord, traceBufferSize),
+		quit:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+
+	go t.write()
+	return t
+}
+
+// Close writes the records still buffered and stops the tracer. Records traced afterwards are dropped
+func (t *Tracer) Close() error {
+	t.once.Do(func() {
+		close(t.quit)
+	})
+
+	<-t.done
+	return nil
+}
+
+// Decode logs how long it took to decode the start of a track
+func (t *Tracer) Decode(title string, elapsed time.Duration, err error) {
+	if err != nil {
+		t.printf("event=decode title=%q elapsed=%s err=%q", title, elapsed, err)
+		return
+	}
+
+	t.printf("event=decode title=%q elapsed=%s", title, elapsed)
+}
+
+// Stream logs a single read from the playback pipeline. The buffer fill level is the portion of the requested samples
+// which were filled. An underrun is logged if producing the samples took longer than playing them back or if the
+// pipeline filled fewer samples than requested before reaching the end of the track
+func (t *Tracer) Stream(requested, filled int, ok bool, elapsed, budget, interval time.Duration) {
+	fill := 0.0
+	if requested > 0 {
+		fill = float64(filled) / float64(requested)
+	}
+
+	t.printf("event=stream requested=%d filled=%d fill=%.2f elapsed=%s budget=%s interval=%s", requested, filled, fill, elapsed, budget, interval)
+
+	if elapsed > budget {
+		t.printf("event=underrun reason=slow-stream elapsed=%s budget=%s", elapsed, budget)
+	}
+
+	if ok && filled < requested {
+		t.printf("event=underrun reason=short-read requested=%d filled=%d", requested, filled)
+	}
+}
+
+// printf queues a record for writing without blocking, dropping it if the buffer is full
+func (t *Tracer) printf(format string, args ...interface{}) {
+	select {
+	case t.records <- traceRecord{time: time.Now(), line: fmt.Sprintf(format, args...)}:
+	default:
+		atomic.AddUint64(&t.dropped, 1)
+	}
+}
+
+// write writes records until the tracer is closed, and then writes the records which are still buffered
+func (t *Tracer) write() {
+	defer close(t.done)
+
+	for {
+		select {
+		case record := <-t.records:
+			t.writeRecord(record)
+		case <-t.quit:
+			for {
+				select {
+				case record := <-t.records:
+					t.writeRecord(record)
+				default:
+					t.writeDropped(time.Now())
+					return
+				}
+			}
+		}
+	}
+}
+
+func (t *Tracer) writeRecord(record traceRecord) {
+	t.writeDropped(record.time)
+
+	// Errors are ignored since tracing must never interfere with playback
+	_, _ = fmt.Fprintf(t.w, "%s %s\n", record.time.Format(traceTimeFormat), record.line)
+}
+
+// writeDropped logs how many records were dropped since it was last called, if any
+func (t *Tracer) writeDropped(now time.Time) {
+	if dropped := atomic.SwapUint64(&t.dropped, 0); dropped > 0 {
+		_, _ = fmt.Fprintf(t.w, "%s event=dropped records=%d\n", now.Format(traceTimeFormat), dropped)
+	}
+}
+
+// tracingStreamer is a beep.Streamer which reports every read of the wrapped streamer to a Tracer
+type tracingStreamer struct {
+	streamer   beep.Streamer
+	sampleRate beep.SampleRate
+	tracer     *Tracer
+	last       time.Time
+}
+
+func newTracingStreamer(streamer beep.Streamer, sampleRate beep.SampleRate, tracer *Tracer) *tracingStreamer {
+	return &tracingStreamer{
+		streamer:   streamer,
+		sampleRate: sampleRate,
+		tracer:     tracer,
+	}
+}
+
+func (t *tracingStreamer) Stream(samples [][2]float64) (n int, ok bool) {
+	start := time.Now()
+	n, ok = t.streamer.Stream(samples)
+	elapsed := time.Since(start)
+
+	var interval time.Duration
+	if !t.last.IsZero() {
+		interval = start.Sub(t.last)
+	}
+
+	t.last = start
+	t.tracer.Stream(len(samples), n, ok, elapsed, t.sampleRate.D(len(samples)), interval)
+	return n, ok
+}
+
+func (t *tracingStreamer) Err() error {
+	return t.streamer.Err()
+}
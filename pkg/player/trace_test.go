@@ -0,0 +1,114 @@
+package player
+
+import (
+	"bytes"
+	"errors"
+	"github.com/faiface/beep"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTracer_Decode(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	tracer := NewTracer(buffer)
+
+	tracer.Decode("some.title", time.Millisecond, nil)
+	tracer.Decode("some.title", time.Millisecond, errors.New("an error occurred"))
+	require.NoError(t, tracer.Close())
+
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.Contains(t, lines[0], `event=decode title="some.title" elapsed=1ms`)
+	assert.NotContains(t, lines[0], "err=")
+	assert.Contains(t, lines[1], `err="an error occurred"`)
+}
+
+func TestTracer_Stream(t *testing.T) {
+	testCases := []struct {
+		name      string
+		requested int
+		filled    int
+		ok        bool
+		elapsed   time.Duration
+		underrun  string
+	}{
+		{"Healthy", 100, 100, true, time.Millisecond, ""},
+		{"EndOfTrack", 100, 50, false, time.Millisecond, ""},
+		{"SlowStream", 100, 100, true, time.Second, "reason=slow-stream"},
+		{"ShortRead", 100, 50, true, time.Millisecond, "reason=short-read"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			buffer := &bytes.Buffer{}
+			tracer := NewTracer(buffer)
+
+			tracer.Stream(testCase.requested, testCase.filled, testCase.ok, testCase.elapsed, 100*time.Millisecond, 0)
+			require.NoError(tt, tracer.Close())
+			assert.Contains(tt, buffer.String(), "event=stream")
+
+			if testCase.underrun == "" {
+				assert.NotContains(tt, buffer.String(), "event=underrun")
+			} else {
+				assert.Contains(tt, buffer.String(), testCase.underrun)
+			}
+		})
+	}
+}
+
+func TestTracingStreamer(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	tracer := NewTracer(buffer)
+	streamer := newTracingStreamer(newConstantStreamer(1, 1), beep.SampleRate(44100), tracer)
+
+	samples := make([][2]float64, 441)
+	n, ok := streamer.Stream(samples)
+	assert.Equal(t, len(samples), n)
+	assert.True(t, ok)
+	assert.NoError(t, streamer.Err())
+	require.NoError(t, tracer.Close())
+	assert.Contains(t, buffer.String(), "event=stream requested=441 filled=441 fill=1.00")
+	assert.Contains(t, buffer.String(), "budget=10ms")
+}
+
+func TestTracer_DropsWhenBufferIsFull(t *testing.T) {
+	r, w := io.Pipe()
+	tracer := NewTracer(w)
+
+	// Nothing reads the trace yet, so the writer blocks and tracing must drop records instead of blocking too
+	traced := make(chan struct{})
+	go func() {
+		defer close(traced)
+		for i := 0; i < 2*traceBufferSize; i++ {
+			tracer.Decode("some.title", time.Millisecond, nil)
+		}
+	}()
+
+	select {
+	case <-traced:
+	case <-time.After(5 * time.Second):
+		t.Fatal("tracing blocked on a writer which doesn't keep up")
+	}
+
+	output := make(chan string)
+	go func() {
+		content, _ := ioutil.ReadAll(r)
+		output <- string(content)
+	}()
+
+	require.NoError(t, tracer.Close())
+	require.NoError(t, w.Close())
+
+	content := <-output
+	assert.Contains(t, content, "event=dropped records=")
+	assert.True(t, strings.Count(content, "event=decode") <= traceBufferSize+1)
+
+	// Tracing after closing does nothing
+	tracer.Decode("some.title", time.Millisecond, nil)
+	require.NoError(t, tracer.Close())
+}
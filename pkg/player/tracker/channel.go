@@ -0,0 +1,294 @@
+package tracker
+
+import (
+	"math"
+)
+
+// vibratoTable is the first half of the sine wave used by vibrato and tremolo. The second half is negated
+var vibratoTable = [32]int{
+	0, 24, 49, 74, 97, 120, 141, 161, 180, 197, 212, 224, 235, 244, 250, 253,
+	255, 253, 250, 244, 235, 224, 212, 197, 180, 161, 141, 120, 97, 74, 49, 24,
+}
+
+// oscillate returns the value of the vibrato waveform at position, which goes from 0 to 63, scaled by depth
+func oscillate(position, depth int) int {
+	v := vibratoTable[position&31] * depth
+	if position&32 != 0 {
+		return -v
+	}
+
+	return v
+}
+
+// channel is the state of a channel of a module being played
+type channel struct {
+	instrument *Instrument
+	sample     *Sample
+	note       int
+	playing    bool
+
+	// position is the frame of the sample being played and backwards is true while a ping-pong loop plays in reverse
+	position  float64
+	backwards bool
+
+	// period is the pitch of the note, which is an Amiga period or a linear period depending on the module, and
+	// targetPeriod is the period tone portamento slides to
+	period       float64
+	targetPeriod float64
+
+	volume int
+	pan    int
+
+	// envelopeTick is the position in the envelope, fade is the volume left after a release, and released is true
+	// once the note is released
+	envelopeTick   int
+	envelopeVolume float64
+	fade           float64
+	released       bool
+
+	// Vibrato, tremolo, and arpeggio change the pitch and volume of a tick without changing the period and volume
+	periodOffset float64
+	volumeOffset int
+	arpeggio     int
+
+	vibratoSpeed, vibratoDepth, vibratoPosition int
+	tremoloSpeed, tremoloDepth, tremoloPosition int
+
+	// Effects which are given no parameter use the last parameter of the effect
+	portaMemory       int
+	finePortaMemory   int
+	tonePortaSpeed    int
+	volumeSlideMemory int
+	fineVolumeMemory  int
+	offsetMemory      int
+	retriggerMemory   int
+	arpeggioMemory    int
+	globalSlideMemory int
+
+	loopRow, loopCount int
+
+	// step, left, and right are how far the sample advances each frame and the volume of each speaker, which are
+	// updated every tick
+	step        float64
+	left, right float64
+}
+
+// trigger starts playing note with sample from the start
+func (c *channel) trigger(sample *Sample, note int, period float64) {
+	c.sample, c.note, c.playing = sample, note, true
+	c.period, c.targetPeriod = period, period
+	c.position, c.backwards = 0, false
+	c.envelopeTick, c.fade, c.released = 0, 1, false
+	c.vibratoPosition, c.tremoloPosition = 0, 0
+}
+
+// retrigger plays the current note again from the start
+func (c *channel) retrigger() {
+	if c.sample == nil {
+		return
+	}
+
+	c.position, c.backwards, c.playing = 0, false, true
+}
+
+// release releases the note so its envelope can finish. Notes without an envelope are cut
+func (c *channel) release() {
+	if c.instrument == nil || c.instrument.Envelope == nil {
+		c.playing = false
+		return
+	}
+
+	c.released = true
+}
+
+// offset moves the note to frame of the sample. Notes which are moved past the end of the sample stop
+func (c *channel) offset(frame int) {
+	if c.sample == nil {
+		return
+	}
+
+	if frame >= len(c.sample.Data) {
+		c.playing = false
+		return
+	}
+
+	c.position = float64(frame)
+}
+
+// setVolume sets the volume of the channel
+func (c *channel) setVolume(volume int) {
+	c.volume = clamp(volume, 0, MaxVolume)
+}
+
+// slideVolume slides the volume like EffectVolumeSlide
+func (c *channel) slideVolume(param int) {
+	if up := param >> 4; up != 0 {
+		c.setVolume(c.volume + up)
+	} else {
+		c.setVolume(c.volume - param&0xF)
+	}
+}
+
+// setVibrato sets the speed and depth of the vibrato, keeping the previous ones if they're 0
+func (c *channel) setVibrato(param int) {
+	if speed := param >> 4; speed != 0 {
+		c.vibratoSpeed = speed
+	}
+
+	if depth := param & 0xF; depth != 0 {
+		c.vibratoDepth = depth
+	}
+}
+
+// setTremolo sets the speed and depth of the tremolo, keeping the previous ones if they're 0
+func (c *channel) setTremolo(param int) {
+	if speed := param >> 4; speed != 0 {
+		c.tremoloSpeed = speed
+	}
+
+	if depth := param & 0xF; depth != 0 {
+		c.tremoloDepth = depth
+	}
+}
+
+// vibrato offsets the period for the tick by units per step of the waveform and moves along the waveform
+func (c *channel) vibrato(units float64) {
+	c.periodOffset = float64(oscillate(c.vibratoPosition, c.vibratoDepth)) / 128 * units
+	c.vibratoPosition = (c.vibratoPosition + c.vibratoSpeed) & 63
+}
+
+// tremolo offsets the volume for the tick and moves along the waveform
+func (c *channel) tremolo() {
+	c.volumeOffset = oscillate(c.tremoloPosition, c.tremoloDepth) / 64
+	c.tremoloPosition = (c.tremoloPosition + c.tremoloSpeed) & 63
+}
+
+// tonePorta slides the period towards the target period by units without passing it
+func (c *channel) tonePorta(units float64) {
+	if c.period < c.targetPeriod {
+		c.period = math.Min(c.period+units, c.targetPeriod)
+	} else if c.period > c.targetPeriod {
+		c.period = math.Max(c.period-units, c.targetPeriod)
+	}
+}
+
+// updateEnvelope moves the envelope and the fade out along by a tick
+func (c *channel) updateEnvelope() {
+	c.envelopeVolume = 1
+	if c.instrument == nil || c.instrument.Envelope == nil {
+		return
+	}
+
+	e := c.instrument.Envelope
+	c.envelopeVolume = e.value(c.envelopeTick)
+	c.envelopeTick = e.next(c.envelopeTick, c.released)
+	if !c.released {
+		return
+	}
+
+	c.fade -= c.instrument.Fadeout
+	if c.fade <= 0 {
+		c.fade, c.playing = 0, false
+	}
+}
+
+// advance moves the position of the sample by distance frames, following its loop. Notes without a loop stop at the
+// end of the sample
+func (c *channel) advance(distance float64) {
+	if c.backwards {
+		c.position -= distance
+	} else {
+		c.position += distance
+	}
+
+	s := c.sample
+	if !s.Loop {
+		if c.position >= float64(len(s.Data)) {
+			c.playing = false
+		}
+
+		return
+	}
+
+	start, end := float64(s.LoopStart), float64(s.LoopEnd)
+	if (!c.backwards && c.position < end) || (c.backwards && c.position >= start) {
+		return
+	}
+
+	length := end - start
+	if !s.PingPong {
+		c.position = start + math.Mod(c.position-start, length)
+		return
+	}
+
+	// A ping-pong loop is unfolded into a forward loop twice as long, where the second half plays backwards
+	unfolded := c.position - start
+	if c.backwards {
+		unfolded = 2*length - unfolded
+	}
+
+	unfolded = math.Mod(unfolded, 2*length)
+	if unfolded < 0 {
+		unfolded += 2 * length
+	}
+
+	if unfolded < length {
+		c.position, c.backwards = start+unfolded, false
+	} else {
+		c.position, c.backwards = start+2*length-unfolded, true
+	}
+}
+
+// value returns the value of the sample at the current position, interpolating between frames
+func (c *channel) value() float64 {
+	s := c.sample
+	i := int(c.position)
+	if i >= len(s.Data) {
+		i = len(s.Data) - 1
+	}
+
+	if i < 0 {
+		return 0
+	}
+
+	j := i + 1
+	if s.Loop && j >= s.LoopEnd {
+		j = s.LoopStart
+		if s.PingPong {
+			j = i
+		}
+	}
+
+	if j >= len(s.Data) {
+		j = i
+	}
+
+	a, b := float64(s.Data[i]), float64(s.Data[j])
+	return a + (b-a)*(c.position-float64(i))
+}
+
+// mix adds the channel to frames
+func (c *channel) mix(frames [][2]float64) {
+	if !c.playing || c.sample == nil || len(c.sample.Data) == 0 || c.left == 0 && c.right == 0 {
+		c.skip(len(frames))
+		return
+	}
+
+	for i := range frames {
+		v := c.value()
+		frames[i][0] += v * c.left
+		frames[i][1] += v * c.right
+
+		c.advance(c.step)
+		if !c.playing {
+			return
+		}
+	}
+}
+
+// skip moves the position of the sample by frames without mixing it
+func (c *channel) skip(frames int) {
+	if c.playing && c.sample != nil && len(c.sample.Data) > 0 {
+		c.advance(c.step * float64(frames))
+	}
+}
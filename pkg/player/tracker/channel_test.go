@@ -0,0 +1,117 @@
+package tracker
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// newTestSample creates a sample of length frames counting up from 0
+func newTestSample(length int) *Sample {
+	sample := newSample(testSampleName)
+	sample.Data = make([]float32, length)
+	for i := range sample.Data {
+		sample.Data[i] = float32(i)
+	}
+
+	return sample
+}
+
+func TestChannel_Advance(t *testing.T) {
+	noLoop := newTestSample(10)
+	loop := newTestSample(10)
+	loop.setLoop(4, 8, false)
+	pingPong := newTestSample(10)
+	pingPong.setLoop(4, 8, true)
+
+	testCases := []struct {
+		name      string
+		sample    *Sample
+		position  float64
+		backwards bool
+		distance  float64
+		expected  float64
+		reversed  bool
+		playing   bool
+	}{
+		{"NoLoop", noLoop, 2, false, 3, 5, false, true},
+		{"NoLoopEnd", noLoop, 8, false, 3, 11, false, false},
+		{"BeforeLoop", loop, 0, false, 3, 3, false, true},
+		{"Loop", loop, 6, false, 3, 5, false, true},
+		{"LoopMany", loop, 6, false, 11, 5, false, true},
+		{"PingPong", pingPong, 6, false, 3, 7, true, true},
+		{"PingPongBack", pingPong, 5, true, 3, 6, false, true},
+		{"PingPongMany", pingPong, 6, false, 9, 7, false, true},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			c := &channel{sample: testCase.sample, playing: true, position: testCase.position, backwards: testCase.backwards}
+			c.advance(testCase.distance)
+			assert.Equal(tt, testCase.playing, c.playing)
+			assert.InDelta(tt, testCase.expected, c.position, 1e-9)
+			assert.Equal(tt, testCase.reversed, c.backwards)
+		})
+	}
+}
+
+func TestChannel_Value(t *testing.T) {
+	loop := newTestSample(10)
+	loop.setLoop(4, 10, false)
+
+	testCases := []struct {
+		name     string
+		sample   *Sample
+		position float64
+		expected float64
+	}{
+		{"Frame", newTestSample(10), 3, 3},
+		{"BetweenFrames", newTestSample(10), 3.25, 3.25},
+		{"LastFrame", newTestSample(10), 9.5, 9},
+		{"LoopEnd", loop, 9.5, 6.5},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			c := &channel{sample: testCase.sample, position: testCase.position}
+			assert.InDelta(tt, testCase.expected, c.value(), 1e-6)
+		})
+	}
+}
+
+func TestChannel_Release(t *testing.T) {
+	// Notes without an envelope are cut
+	c := &channel{instrument: &Instrument{}, playing: true, fade: 1}
+	c.release()
+	assert.False(t, c.playing)
+
+	// Notes with an envelope fade out once released
+	envelope := &Envelope{Points: []EnvelopePoint{{0, 1}, {10, 1}}, Sustain: true}
+	c = &channel{instrument: &Instrument{Envelope: envelope, Fadeout: 0.5}, playing: true, fade: 1}
+	c.updateEnvelope()
+	assert.Equal(t, 1.0, c.fade)
+
+	c.release()
+	assert.True(t, c.playing)
+	c.updateEnvelope()
+	assert.Equal(t, 0.5, c.fade)
+	c.updateEnvelope()
+	assert.False(t, c.playing)
+}
+
+func TestChannel_TonePorta(t *testing.T) {
+	c := &channel{period: 400, targetPeriod: 428}
+	c.tonePorta(16)
+	assert.Equal(t, 416.0, c.period)
+	c.tonePorta(16)
+	assert.Equal(t, 428.0, c.period)
+
+	c.targetPeriod = 420
+	c.tonePorta(16)
+	assert.Equal(t, 420.0, c.period)
+}
+
+func TestOscillate(t *testing.T) {
+	assert.Equal(t, 0, oscillate(0, 15))
+	assert.Equal(t, 255*15, oscillate(16, 15))
+	assert.Equal(t, -255*15, oscillate(48, 15))
+}
@@ -0,0 +1,297 @@
+package tracker
+
+// Effect is an effect of a cell. Every format encodes effects differently, so loaders convert them to these effects
+// and their parameters to the meaning documented here. Slides happen on every tick but the first of a row unless
+// documented otherwise
+type Effect int
+
+const (
+	EffectNone Effect = iota
+
+	// EffectArpeggio cycles between the note, the note plus the high nibble, and the note plus the low nibble
+	// semitones on each tick
+	EffectArpeggio
+
+	// EffectPortaUp and EffectPortaDown slide the pitch by Param units. The Fine effects slide on the first tick
+	// only, and the ExtraFine effects slide by a quarter of the units on the first tick only
+	EffectPortaUp
+	EffectPortaDown
+	EffectFinePortaUp
+	EffectFinePortaDown
+	EffectExtraFinePortaUp
+	EffectExtraFinePortaDown
+
+	// EffectTonePorta slides the pitch towards the note of the cell by Param units without playing it again
+	EffectTonePorta
+
+	// EffectVibrato oscillates the pitch at the speed in the high nibble with the depth in the low nibble
+	EffectVibrato
+
+	// EffectTonePortaVolumeSlide and EffectVibratoVolumeSlide continue the last EffectTonePorta or EffectVibrato and
+	// slide the volume like EffectVolumeSlide
+	EffectTonePortaVolumeSlide
+	EffectVibratoVolumeSlide
+
+	// EffectTremolo oscillates the volume at the speed in the high nibble with the depth in the low nibble
+	EffectTremolo
+
+	// EffectSetPanning sets the panning from 0 (left) to 255 (right)
+	EffectSetPanning
+
+	// EffectSampleOffset starts the note Param * 256 frames into the sample
+	EffectSampleOffset
+
+	// EffectVolumeSlide raises the volume by the high nibble or else lowers it by the low nibble
+	EffectVolumeSlide
+
+	// EffectFineVolumeUp and EffectFineVolumeDown change the volume by Param on the first tick only
+	EffectFineVolumeUp
+	EffectFineVolumeDown
+
+	// EffectSetVolume sets the volume of the channel to Param
+	EffectSetVolume
+
+	// EffectPositionJump continues the song from order Param after the row
+	EffectPositionJump
+
+	// EffectPatternBreak continues the song from row Param of the next order after the row
+	EffectPatternBreak
+
+	// EffectSetSpeed sets the ticks per row and EffectSetTempo sets the ticks per 2.5 seconds
+	EffectSetSpeed
+	EffectSetTempo
+
+	// EffectRetrigger plays the note again every Param ticks
+	EffectRetrigger
+
+	// EffectNoteCut silences the note on tick Param and EffectNoteDelay waits until tick Param to play the cell
+	EffectNoteCut
+	EffectNoteDelay
+
+	// EffectPatternLoop marks the start of a loop if Param is 0 or else loops back to the start Param times
+	EffectPatternLoop
+
+	// EffectPatternDelay repeats the row Param times without playing its notes again
+	EffectPatternDelay
+
+	// EffectSetGlobalVolume sets the volume of the module to Param and EffectGlobalVolumeSlide slides it like
+	// EffectVolumeSlide
+	EffectSetGlobalVolume
+	EffectGlobalVolumeSlide
+
+	// EffectKeyOff releases the note on tick Param
+	EffectKeyOff
+)
+
+// volumeSlide converts a volume slide as used by S3M and IT, where a nibble of 0xF makes the slide fine, to an effect
+func volumeSlide(param int) (Effect, int) {
+	high, low := param>>4, param&0xF
+	switch {
+	case low == 0xF && high != 0:
+		return EffectFineVolumeUp, high
+	case high == 0xF && low != 0:
+		return EffectFineVolumeDown, low
+	default:
+		return EffectVolumeSlide, param
+	}
+}
+
+// portaSlide converts a pitch slide as used by S3M and IT, where a high nibble of 0xF makes the slide fine and 0xE
+// extra fine, to an effect
+func portaSlide(param int, up bool) (Effect, int) {
+	effects := [3]Effect{EffectPortaDown, EffectFinePortaDown, EffectExtraFinePortaDown}
+	if up {
+		effects = [3]Effect{EffectPortaUp, EffectFinePortaUp, EffectExtraFinePortaUp}
+	}
+
+	switch param >> 4 {
+	case 0xF:
+		return effects[1], param & 0xF
+	case 0xE:
+		return effects[2], param & 0xF
+	default:
+		return effects[0], param
+	}
+}
+
+// s3mEffect converts an effect of S3M and IT, which share the letters of their effects, to an effect. Effects that
+// aren't supported are ignored
+func s3mEffect(command byte, param int, it bool) (Effect, int) {
+	switch command {
+	case 'A':
+		return EffectSetSpeed, param
+	case 'B':
+		return EffectPositionJump, param
+	case 'C':
+		if it {
+			return EffectPatternBreak, param
+		}
+
+		// Scream Tracker stores the row in decimal
+		return EffectPatternBreak, (param>>4)*10 + param&0xF
+	case 'D':
+		return volumeSlide(param)
+	case 'E':
+		return portaSlide(param, false)
+	case 'F':
+		return portaSlide(param, true)
+	case 'G':
+		return EffectTonePorta, param
+	case 'H', 'U':
+		return EffectVibrato, param
+	case 'J':
+		return EffectArpeggio, param
+	case 'K':
+		return EffectVibratoVolumeSlide, param
+	case 'L':
+		return EffectTonePortaVolumeSlide, param
+	case 'O':
+		return EffectSampleOffset, param
+	case 'Q':
+		return EffectRetrigger, param & 0xF
+	case 'R':
+		return EffectTremolo, param
+	case 'S':
+		return s3mSpecialEffect(param)
+	case 'T':
+		if param < 0x20 {
+			return EffectNone, 0
+		}
+
+		return EffectSetTempo, param
+	case 'V':
+		if it {
+			return EffectSetGlobalVolume, param / 2
+		}
+
+		return EffectSetGlobalVolume, param
+	case 'W':
+		return EffectGlobalVolumeSlide, param
+	case 'X':
+		if it {
+			return EffectSetPanning, param
+		}
+
+		// Scream Tracker pans from 0 to 128 and anything higher is surround
+		return EffectSetPanning, clamp(param*2, 0, 255)
+	default:
+		return EffectNone, 0
+	}
+}
+
+// s3mSpecialEffect converts an S effect of S3M and IT, which holds the effect in its high nibble
+func s3mSpecialEffect(param int) (Effect, int) {
+	low := param & 0xF
+	switch param >> 4 {
+	case 0x8:
+		return EffectSetPanning, low * 17
+	case 0xB:
+		return EffectPatternLoop, low
+	case 0xC:
+		return EffectNoteCut, low
+	case 0xD:
+		return EffectNoteDelay, low
+	case 0xE:
+		return EffectPatternDelay, low
+	default:
+		return EffectNone, 0
+	}
+}
+
+// protrackerEffect converts an effect of MOD and XM, which share the numbers of their effects, to an effect. Effects
+// that aren't supported are ignored
+func protrackerEffect(command, param int) (Effect, int) {
+	switch command {
+	case 0x0:
+		if param == 0 {
+			return EffectNone, 0
+		}
+
+		return EffectArpeggio, param
+	case 0x1:
+		return EffectPortaUp, param
+	case 0x2:
+		return EffectPortaDown, param
+	case 0x3:
+		return EffectTonePorta, param
+	case 0x4:
+		return EffectVibrato, param
+	case 0x5:
+		return EffectTonePortaVolumeSlide, param
+	case 0x6:
+		return EffectVibratoVolumeSlide, param
+	case 0x7:
+		return EffectTremolo, param
+	case 0x8:
+		return EffectSetPanning, param
+	case 0x9:
+		return EffectSampleOffset, param
+	case 0xA:
+		return EffectVolumeSlide, param
+	case 0xB:
+		return EffectPositionJump, param
+	case 0xC:
+		return EffectSetVolume, clamp(param, 0, MaxVolume)
+	case 0xD:
+		// ProTracker stores the row in decimal
+		return EffectPatternBreak, (param>>4)*10 + param&0xF
+	case 0xE:
+		return protrackerExtendedEffect(param)
+	case 0xF:
+		if param == 0 {
+			return EffectNone, 0
+		}
+
+		if param < 0x20 {
+			return EffectSetSpeed, param
+		}
+
+		return EffectSetTempo, param
+	case 0x10:
+		return EffectSetGlobalVolume, clamp(param, 0, MaxVolume)
+	case 0x11:
+		return EffectGlobalVolumeSlide, param
+	case 0x14:
+		return EffectKeyOff, param
+	case 0x1B:
+		return EffectRetrigger, param & 0xF
+	case 0x21:
+		switch param >> 4 {
+		case 0x1:
+			return EffectExtraFinePortaUp, param & 0xF
+		case 0x2:
+			return EffectExtraFinePortaDown, param & 0xF
+		}
+	}
+
+	return EffectNone, 0
+}
+
+// protrackerExtendedEffect converts an E effect of MOD and XM, which holds the effect in its high nibble
+func protrackerExtendedEffect(param int) (Effect, int) {
+	low := param & 0xF
+	switch param >> 4 {
+	case 0x1:
+		return EffectFinePortaUp, low
+	case 0x2:
+		return EffectFinePortaDown, low
+	case 0x6:
+		return EffectPatternLoop, low
+	case 0x8:
+		return EffectSetPanning, low * 17
+	case 0x9:
+		return EffectRetrigger, low
+	case 0xA:
+		return EffectFineVolumeUp, low
+	case 0xB:
+		return EffectFineVolumeDown, low
+	case 0xC:
+		return EffectNoteCut, low
+	case 0xD:
+		return EffectNoteDelay, low
+	case 0xE:
+		return EffectPatternDelay, low
+	default:
+		return EffectNone, 0
+	}
+}
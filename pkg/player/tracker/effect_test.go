@@ -0,0 +1,75 @@
+package tracker
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestS3MEffect(t *testing.T) {
+	testCases := []struct {
+		name    string
+		command byte
+		param   int
+		it      bool
+		effect  Effect
+		value   int
+	}{
+		{"Speed", 'A', 3, false, EffectSetSpeed, 3},
+		{"PatternBreak", 'C', 0x12, false, EffectPatternBreak, 12},
+		{"PatternBreakIT", 'C', 0x12, true, EffectPatternBreak, 0x12},
+		{"VolumeSlide", 'D', 0x30, false, EffectVolumeSlide, 0x30},
+		{"FineVolumeUp", 'D', 0x3F, false, EffectFineVolumeUp, 3},
+		{"FineVolumeDown", 'D', 0xF3, false, EffectFineVolumeDown, 3},
+		{"PortaDown", 'E', 0x20, false, EffectPortaDown, 0x20},
+		{"FinePortaUp", 'F', 0xF2, false, EffectFinePortaUp, 2},
+		{"ExtraFinePortaUp", 'F', 0xE2, false, EffectExtraFinePortaUp, 2},
+		{"SetPanning", 'S', 0x8F, false, EffectSetPanning, 255},
+		{"NoteDelay", 'S', 0xD3, false, EffectNoteDelay, 3},
+		{"TempoSlide", 'T', 0x10, false, EffectNone, 0},
+		{"Tempo", 'T', 0x80, false, EffectSetTempo, 0x80},
+		{"GlobalVolume", 'V', 0x40, false, EffectSetGlobalVolume, 0x40},
+		{"GlobalVolumeIT", 'V', 0x80, true, EffectSetGlobalVolume, 0x40},
+		{"Panning", 'X', 0x40, false, EffectSetPanning, 0x80},
+		{"PanningIT", 'X', 0x40, true, EffectSetPanning, 0x40},
+		{"Unsupported", 'Z', 0x40, true, EffectNone, 0},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			effect, value := s3mEffect(testCase.command, testCase.param, testCase.it)
+			assert.Equal(tt, testCase.effect, effect)
+			assert.Equal(tt, testCase.value, value)
+		})
+	}
+}
+
+func TestProtrackerEffect(t *testing.T) {
+	testCases := []struct {
+		name    string
+		command int
+		param   int
+		effect  Effect
+		value   int
+	}{
+		{"None", 0x0, 0, EffectNone, 0},
+		{"Arpeggio", 0x0, 0x37, EffectArpeggio, 0x37},
+		{"TonePorta", 0x3, 0x10, EffectTonePorta, 0x10},
+		{"VolumeTooHigh", 0xC, 0x50, EffectSetVolume, MaxVolume},
+		{"PatternBreak", 0xD, 0x32, EffectPatternBreak, 32},
+		{"PatternLoop", 0xE, 0x63, EffectPatternLoop, 3},
+		{"NoteCut", 0xE, 0xC2, EffectNoteCut, 2},
+		{"StopSong", 0xF, 0, EffectNone, 0},
+		{"Speed", 0xF, 0x1F, EffectSetSpeed, 0x1F},
+		{"Tempo", 0xF, 0x20, EffectSetTempo, 0x20},
+		{"KeyOff", 0x14, 2, EffectKeyOff, 2},
+		{"Unsupported", 0x13, 2, EffectNone, 0},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			effect, value := protrackerEffect(testCase.command, testCase.param)
+			assert.Equal(tt, testCase.effect, effect)
+			assert.Equal(tt, testCase.value, value)
+		})
+	}
+}
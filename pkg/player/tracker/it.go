@@ -0,0 +1,535 @@
+package tracker
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+const (
+	itSignature          = "IMPM"
+	itHeaderLength       = 0xC0
+	itSampleLength       = 0x50
+	itInstrumentLength   = 0x130 + 82
+	itChannels           = 64
+	itEnvelopeNodes      = 25
+	itUseInstruments     = 0x4
+	itLinearSlides       = 0x8
+	itChannelDisabled    = 0x80
+	itSampleExists       = 0x1
+	itSample16Bit        = 0x2
+	itSampleStereo       = 0x4
+	itSampleCompressed   = 0x8
+	itSampleLoop         = 0x10
+	itSampleSustainLoop  = 0x20
+	itSamplePingPong     = 0x40
+	itSamplePingPongSus  = 0x80
+	itSampleSigned       = 0x1
+	itSampleDelta        = 0x4
+	itEnvelopeOn         = 0x1
+	itEnvelopeLoop       = 0x2
+	itEnvelopeSustain    = 0x4
+	itInstrumentFormat   = 0x200
+	itNoteOff            = 255
+	itNoteCut            = 254
+	itCompressedBlock8   = 0x8000
+	itCompressedBlock16  = 0x4000
+	itPatternHeaderBytes = 8
+)
+
+// LoadIT loads an Impulse Tracker module. Instruments saved by versions of Impulse Tracker older than 2.0 are played
+// as samples
+func LoadIT(data []byte) (*Module, error) {
+	if len(data) < itHeaderLength {
+		return nil, ErrTruncated
+	}
+
+	if string(data[:len(itSignature)]) != itSignature {
+		return nil, ErrUnknownFormat
+	}
+
+	orders := int(binary.LittleEndian.Uint16(data[0x20:]))
+	instruments := int(binary.LittleEndian.Uint16(data[0x22:]))
+	samples := int(binary.LittleEndian.Uint16(data[0x24:]))
+	patterns := int(binary.LittleEndian.Uint16(data[0x26:]))
+	compatible := int(binary.LittleEndian.Uint16(data[0x2A:]))
+	flags := binary.LittleEndian.Uint16(data[0x2C:])
+
+	pointers := itHeaderLength + orders
+	if len(data) < pointers+(instruments+samples+patterns)*4 {
+		return nil, ErrTruncated
+	}
+
+	pointer := func(i int) int {
+		return int(binary.LittleEndian.Uint32(data[pointers+i*4:]))
+	}
+
+	m := &Module{
+		Title:        text(data[4:0x1E]),
+		Format:       "it",
+		Channels:     itChannels,
+		Speed:        int(data[0x32]),
+		Tempo:        int(data[0x33]),
+		GlobalVolume: clamp(int(data[0x30])/2, 0, MaxVolume),
+		LinearSlides: flags&itLinearSlides != 0,
+	}
+
+	for i := 0; i < itChannels; i++ {
+		pan := int(data[0x40+i])
+		switch {
+		case pan&itChannelDisabled != 0:
+			m.Panning = append(m.Panning, CenterPan)
+		case pan <= 64:
+			m.Panning = append(m.Panning, clamp(pan*4, 0, 255))
+		default:
+			m.Panning = append(m.Panning, CenterPan)
+		}
+	}
+
+	for i := 0; i < orders; i++ {
+		// 254 marks a position which is skipped and 255 the end of the song
+		order := int(data[itHeaderLength+i])
+		if order == 255 {
+			break
+		}
+
+		if order < patterns {
+			m.Orders = append(m.Orders, order)
+		}
+	}
+
+	sampleList := make([]*Sample, samples)
+	for i := range sampleList {
+		sample, err := loadITSample(data, pointer(instruments+i))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load sample %d: %w", i+1, err)
+		}
+
+		sampleList[i] = sample
+	}
+
+	if flags&itUseInstruments != 0 && compatible >= itInstrumentFormat {
+		for i := 0; i < instruments; i++ {
+			instrument, err := loadITInstrument(data, pointer(i), sampleList)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load instrument %d: %w", i+1, err)
+			}
+
+			m.Instruments = append(m.Instruments, instrument)
+		}
+	} else {
+		for _, sample := range sampleList {
+			m.Instruments = append(m.Instruments, newSampleInstrument(sample))
+		}
+	}
+
+	for i := 0; i < patterns; i++ {
+		pattern, err := loadITPattern(data, pointer(instruments+samples+i))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load pattern %d: %w", i, err)
+		}
+
+		m.Patterns = append(m.Patterns, pattern)
+	}
+
+	return m, nil
+}
+
+// loadITInstrument loads the instrument at offset, which refers to samples by their 1-based index
+func loadITInstrument(data []byte, offset int, samples []*Sample) (*Instrument, error) {
+	if len(data) < offset+itInstrumentLength {
+		return nil, ErrTruncated
+	}
+
+	header := data[offset : offset+itInstrumentLength]
+	instrument := &Instrument{
+		Name:    text(header[0x20:0x3A]),
+		Samples: samples,
+		Fadeout: float64(binary.LittleEndian.Uint16(header[0x14:])) / 1024,
+	}
+
+	// Each note maps to another note of a sample, which transposes the sample. Transposition isn't supported, so
+	// only the sample is used
+	for note := 0; note < MaxNote; note++ {
+		instrument.Keymap[note+1] = int(header[0x41+note*2])
+	}
+
+	envelope := header[0x130:]
+	flags, points := envelope[0], int(envelope[1])
+	if flags&itEnvelopeOn != 0 && points <= itEnvelopeNodes {
+		e := &Envelope{
+			Loop:         flags&itEnvelopeLoop != 0,
+			LoopStart:    int(envelope[2]),
+			LoopEnd:      int(envelope[3]),
+			Sustain:      flags&itEnvelopeSustain != 0,
+			SustainStart: int(envelope[4]),
+			SustainEnd:   int(envelope[5]),
+		}
+
+		for i := 0; i < points; i++ {
+			node := envelope[6+i*3:]
+			e.Points = append(e.Points, EnvelopePoint{
+				Tick:  int(binary.LittleEndian.Uint16(node[1:])),
+				Value: float64(clamp(int(node[0]), 0, MaxVolume)) / MaxVolume,
+			})
+		}
+
+		if e.valid() {
+			instrument.Envelope = e
+		}
+	}
+
+	return instrument, nil
+}
+
+// loadITSample loads the sample with the header at offset. Samples may be compressed with the algorithm of Impulse
+// Tracker 2.14 or 2.15
+func loadITSample(data []byte, offset int) (*Sample, error) {
+	if len(data) < offset+itSampleLength {
+		return nil, ErrTruncated
+	}
+
+	header := data[offset : offset+itSampleLength]
+	sample := newSample(text(header[0x14:0x2E]))
+	sample.GlobalVolume = float64(clamp(int(header[0x11]), 0, MaxVolume)) / MaxVolume
+	sample.Volume = clamp(int(header[0x13]), 0, MaxVolume)
+	if pan := header[0x2F]; pan&0x80 != 0 {
+		sample.Panning = clamp(int(pan&0x7F)*4, 0, 255)
+	}
+
+	if c5Speed := binary.LittleEndian.Uint32(header[0x3C:]); c5Speed > 0 {
+		sample.C5Speed = float64(c5Speed)
+	}
+
+	flags, conversion := header[0x12], header[0x2E]
+	if flags&itSampleExists == 0 {
+		return sample, nil
+	}
+
+	length := int(binary.LittleEndian.Uint32(header[0x30:]))
+	start := int(binary.LittleEndian.Uint32(header[0x48:]))
+	if start > len(data) {
+		return nil, ErrTruncated
+	}
+
+	// Stereo samples store the left channel followed by the right channel, so only the left channel is played
+	wide := flags&itSample16Bit != 0
+	switch {
+	case flags&itSampleCompressed != 0:
+		sample.Data = decompressITSample(data[start:], length, wide, conversion&itSampleDelta != 0)
+	case wide:
+		length = minInt(length, (len(data)-start)/2)
+		sample.Data = make([]float32, length)
+		for i := range sample.Data {
+			v := binary.LittleEndian.Uint16(data[start+i*2:])
+			if conversion&itSampleSigned == 0 {
+				v ^= 0x8000
+			}
+
+			sample.Data[i] = float32(int16(v)) / 32768
+		}
+	default:
+		length = minInt(length, len(data)-start)
+		sample.Data = make([]float32, length)
+		for i := range sample.Data {
+			v := data[start+i]
+			if conversion&itSampleSigned == 0 {
+				v ^= 0x80
+			}
+
+			sample.Data[i] = float32(int8(v)) / 128
+		}
+	}
+
+	// Sustain loops are only played as loops when the sample doesn't have a loop since notes aren't held
+	switch {
+	case flags&itSampleLoop != 0:
+		sample.setLoop(int(binary.LittleEndian.Uint32(header[0x34:])), int(binary.LittleEndian.Uint32(header[0x38:])), flags&itSamplePingPong != 0)
+	case flags&itSampleSustainLoop != 0:
+		sample.setLoop(int(binary.LittleEndian.Uint32(header[0x40:])), int(binary.LittleEndian.Uint32(header[0x44:])), flags&itSamplePingPongSus != 0)
+	}
+
+	return sample, nil
+}
+
+// loadITPattern loads the packed pattern at offset. Each cell starts with a byte holding the channel and whether a mask
+// follows, which tells which fields follow and which fields repeat the last value of the channel
+func loadITPattern(data []byte, offset int) (*Pattern, error) {
+	if offset == 0 {
+		return newPattern(64, itChannels), nil
+	}
+
+	if len(data) < offset+itPatternHeaderBytes {
+		return nil, ErrTruncated
+	}
+
+	rows := int(binary.LittleEndian.Uint16(data[offset+2:]))
+	if rows < 1 || rows > 256 {
+		return nil, fmt.Errorf("invalid number of rows %d", rows)
+	}
+
+	var masks [itChannels]byte
+	var last [itChannels]Cell
+	var lastVolume [itChannels]byte
+	var lastCommand, lastParam [itChannels]byte
+
+	p := newPattern(rows, itChannels)
+	r := &byteReader{data: data, offset: offset + itPatternHeaderBytes}
+	for row := 0; row < rows; row++ {
+		for {
+			variable, err := r.byte()
+			if err != nil {
+				return nil, err
+			}
+
+			if variable == 0 {
+				break
+			}
+
+			channel := int(variable-1) & (itChannels - 1)
+			if variable&0x80 != 0 {
+				if masks[channel], err = r.byte(); err != nil {
+					return nil, err
+				}
+			}
+
+			mask := masks[channel]
+			cell := Cell{Volume: NoVolume}
+			if mask&0x1 != 0 {
+				note, err := r.byte()
+				if err != nil {
+					return nil, err
+				}
+
+				last[channel].Note = itNote(note)
+			}
+
+			if mask&0x2 != 0 {
+				instrument, err := r.byte()
+				if err != nil {
+					return nil, err
+				}
+
+				last[channel].Instrument = int(instrument)
+			}
+
+			if mask&0x4 != 0 {
+				if lastVolume[channel], err = r.byte(); err != nil {
+					return nil, err
+				}
+			}
+
+			if mask&0x8 != 0 {
+				if lastCommand[channel], lastParam[channel], err = r.pair(); err != nil {
+					return nil, err
+				}
+			}
+
+			if mask&0x11 != 0 {
+				cell.Note = last[channel].Note
+			}
+
+			if mask&0x22 != 0 {
+				cell.Instrument = last[channel].Instrument
+			}
+
+			if mask&0x44 != 0 {
+				cell.Volume, cell.VolumeEffect, cell.VolumeParam = itVolume(lastVolume[channel])
+			}
+
+			if mask&0x88 != 0 && lastCommand[channel] > 0 {
+				cell.Effect, cell.Param = s3mEffect('A'+lastCommand[channel]-1, int(lastParam[channel]), true)
+			}
+
+			p.Cells[row][channel] = cell
+		}
+	}
+
+	return p, nil
+}
+
+// itNote converts a note of a cell
+func itNote(note byte) int {
+	switch {
+	case note == itNoteOff:
+		return NoteOff
+	case note == itNoteCut:
+		return NoteCut
+	case note < MaxNote:
+		// Impulse Tracker plays samples at their C5Speed on C-5
+		return int(note) + 1
+	default:
+		return NoteFade
+	}
+}
+
+// itVolume converts the volume column, which holds either a volume, a panning, or one of a few effects depending on
+// its range
+func itVolume(v byte) (int, Effect, int) {
+	switch value := int(v); {
+	case value <= 64:
+		return value, EffectNone, 0
+	case value <= 74:
+		return NoVolume, EffectFineVolumeUp, value - 65
+	case value <= 84:
+		return NoVolume, EffectFineVolumeDown, value - 75
+	case value <= 94:
+		return NoVolume, EffectVolumeSlide, (value - 85) << 4
+	case value <= 104:
+		return NoVolume, EffectVolumeSlide, value - 95
+	case value <= 114:
+		return NoVolume, EffectPortaDown, (value - 105) * 4
+	case value <= 124:
+		return NoVolume, EffectPortaUp, (value - 115) * 4
+	case value >= 128 && value <= 192:
+		return NoVolume, EffectSetPanning, clamp((value-128)*4, 0, 255)
+	case value >= 193 && value <= 202:
+		return NoVolume, EffectTonePorta, itTonePortaSpeeds[value-193]
+	case value >= 203 && value <= 212:
+		return NoVolume, EffectVibrato, value - 203
+	default:
+		return NoVolume, EffectNone, 0
+	}
+}
+
+// itTonePortaSpeeds are the speeds of the tone portamento of the volume column
+var itTonePortaSpeeds = [...]int{0x00, 0x01, 0x04, 0x08, 0x10, 0x20, 0x40, 0x60, 0x80, 0xFF}
+
+// decompressITSample decompresses length frames of a sample compressed by Impulse Tracker. The data is stored in blocks,
+// each starting with its size, and holds the differences between frames with a bit width that changes along the way.
+// Samples compressed by Impulse Tracker 2.15 store the differences between the differences. Data which is corrupt ends
+// the sample early
+func decompressITSample(data []byte, length int, wide, delta bool) []float32 {
+	samples := make([]float32, 0, length)
+	blockFrames, maxWidth, scale := itCompressedBlock8, 9, float32(128)
+	if wide {
+		blockFrames, maxWidth, scale = itCompressedBlock16, 17, 32768
+	}
+
+	offset := 0
+	for len(samples) < length {
+		if len(data) < offset+2 {
+			break
+		}
+
+		size := int(binary.LittleEndian.Uint16(data[offset:]))
+		offset += 2
+		if len(data) < offset+size {
+			size = len(data) - offset
+		}
+
+		r := &bitReader{data: data[offset : offset+size]}
+		offset += size
+
+		frames := minInt(blockFrames, length-len(samples))
+		width := maxWidth
+		var d1, d2 int32
+		for i := 0; i < frames; {
+			value, ok := r.read(width)
+			if !ok {
+				return samples
+			}
+
+			bits := maxWidth - 1
+			switch {
+			case width < 7:
+				// Values with only the top bit set change the width to the 3-bit value which follows
+				if value == 1<<uint(width-1) {
+					next, ok := r.read(3)
+					if !ok {
+						return samples
+					}
+
+					width = changeWidth(int(next)+1, width)
+					continue
+				}
+			case width < maxWidth:
+				// Values within 8 of the top of the range change the width
+				border := (1<<uint(bits)-1)>>uint(maxWidth-width) - (maxWidth-1)/2
+				if int(value) > border && int(value) <= border+maxWidth-1 {
+					width = changeWidth(int(value)-border, width)
+					continue
+				}
+			case width == maxWidth:
+				// Values with the top bit set change the width to the rest of the value
+				if value&(1<<uint(bits)) != 0 {
+					width = int(value+1) & 0xFF
+					continue
+				}
+			default:
+				return samples
+			}
+
+			// Values narrower than the frames are sign extended
+			v := int32(value)
+			if width < bits {
+				shift := uint(32 - width)
+				v = v << shift >> shift
+			} else {
+				shift := uint(32 - bits)
+				v = v << shift >> shift
+			}
+
+			d1 = wrap(d1+v, bits)
+			d2 = wrap(d2+d1, bits)
+			if delta {
+				samples = append(samples, float32(d2)/scale)
+			} else {
+				samples = append(samples, float32(d1)/scale)
+			}
+
+			i++
+		}
+	}
+
+	return samples
+}
+
+// changeWidth returns the new width of compressed values. Widths skip the current width since it doesn't need to be
+// changed to
+func changeWidth(width, current int) int {
+	if width < current {
+		return width
+	}
+
+	return width + 1
+}
+
+// wrap wraps v around to a signed integer with bits bits
+func wrap(v int32, bits int) int32 {
+	shift := uint(32 - bits)
+	return v << shift >> shift
+}
+
+// bitReader reads values of any width from the least significant bits of bytes first
+type bitReader struct {
+	data   []byte
+	offset int
+	bit    uint
+}
+
+// read reads a value of width bits and returns false if there aren't enough bits left
+func (r *bitReader) read(width int) (uint32, bool) {
+	var value uint32
+	for i := 0; i < width; i++ {
+		if r.offset >= len(r.data) {
+			return 0, false
+		}
+
+		value |= uint32(r.data[r.offset]>>r.bit&1) << uint(i)
+		r.bit++
+		if r.bit == 8 {
+			r.offset, r.bit = r.offset+1, 0
+		}
+	}
+
+	return value, true
+}
+
+// minInt returns the lower of a and b
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
@@ -0,0 +1,282 @@
+package tracker
+
+import (
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+const (
+	testITInstrumentOffset = 0xD0
+	testITSampleOffset     = testITInstrumentOffset + itInstrumentLength
+	testITPatternOffset    = testITSampleOffset + itSampleLength
+	testITFadeout          = 256
+)
+
+// newTestIT creates an IT with linear slides, two enabled channels, an instrument with a volume envelope and a
+// looping sample, and one pattern. The first row plays C-5 on the first channel at half volume and sets the speed,
+// the second row breaks to row 10 and releases the second channel, and the third row repeats the first note
+func newTestIT() []byte {
+	data := make([]byte, testITPatternOffset)
+	copy(data, itSignature)
+	copy(data[4:], testTitle)
+	binary.LittleEndian.PutUint16(data[0x20:], 2)
+	binary.LittleEndian.PutUint16(data[0x22:], 1)
+	binary.LittleEndian.PutUint16(data[0x24:], 1)
+	binary.LittleEndian.PutUint16(data[0x26:], 1)
+	binary.LittleEndian.PutUint16(data[0x28:], 0x0214)
+	binary.LittleEndian.PutUint16(data[0x2A:], 0x0214)
+	binary.LittleEndian.PutUint16(data[0x2C:], 0x1|itUseInstruments|itLinearSlides)
+	data[0x30], data[0x31], data[0x32], data[0x33] = 128, 48, 6, 125
+
+	for i := 0; i < itChannels; i++ {
+		data[0x40+i] = 32 | itChannelDisabled
+	}
+
+	data[0x40], data[0x41] = 0, 64
+	data[0xC0], data[0xC1] = 0, 255
+	binary.LittleEndian.PutUint32(data[0xC2:], testITInstrumentOffset)
+	binary.LittleEndian.PutUint32(data[0xC6:], testITSampleOffset)
+	binary.LittleEndian.PutUint32(data[0xCA:], testITPatternOffset)
+
+	instrument := data[testITInstrumentOffset:]
+	copy(instrument, "IMPI")
+	binary.LittleEndian.PutUint16(instrument[0x14:], testITFadeout)
+	copy(instrument[0x20:], testSampleName)
+	for note := 0; note < MaxNote; note++ {
+		instrument[0x40+note*2], instrument[0x41+note*2] = byte(note), 1
+	}
+
+	envelope := instrument[0x130:]
+	envelope[0], envelope[1] = itEnvelopeOn|itEnvelopeSustain, 2
+	envelope[6], envelope[9] = 64, 0
+	binary.LittleEndian.PutUint16(envelope[10:], 10)
+
+	sample := data[testITSampleOffset:]
+	copy(sample, "IMPS")
+	sample[0x11], sample[0x12], sample[0x13] = MaxVolume, itSampleExists|itSampleLoop, 48
+	copy(sample[0x14:], testSampleName)
+	sample[0x2E], sample[0x2F] = itSampleSigned, 0x80|32
+	binary.LittleEndian.PutUint32(sample[0x30:], testSampleLength)
+	binary.LittleEndian.PutUint32(sample[0x38:], testSampleLength)
+	binary.LittleEndian.PutUint32(sample[0x3C:], amigaC5Speed)
+
+	cells := []byte{
+		0x81, 0x0F, 60, 1, 32, 'A' - 'A' + 1, 3, 0,
+		0x81, 0x08, 'C' - 'A' + 1, 10, 0x82, 0x01, itNoteOff, 0,
+		0x81, 0x30, 0,
+	}
+
+	for row := 3; row < 64; row++ {
+		cells = append(cells, 0)
+	}
+
+	pattern := make([]byte, itPatternHeaderBytes)
+	binary.LittleEndian.PutUint16(pattern, uint16(len(cells)))
+	binary.LittleEndian.PutUint16(pattern[2:], 64)
+	data = append(append(data, pattern...), cells...)
+
+	binary.LittleEndian.PutUint32(data[testITSampleOffset+0x48:], uint32(len(data)))
+	for i := 0; i < testSampleLength; i++ {
+		if i < testSampleLength/2 {
+			data = append(data, 0x40)
+		} else {
+			data = append(data, 0xC0)
+		}
+	}
+
+	return data
+}
+
+func TestLoadIT(t *testing.T) {
+	m, err := LoadIT(newTestIT())
+	require.NoError(t, err)
+	assert.Equal(t, testTitle, m.Title)
+	assert.Equal(t, "it", m.Format)
+	assert.Equal(t, itChannels, m.Channels)
+	assert.Equal(t, []int{0}, m.Orders)
+	assert.Equal(t, 0, m.Panning[0])
+	assert.Equal(t, 255, m.Panning[1])
+	assert.Equal(t, CenterPan, m.Panning[2])
+	assert.Equal(t, 6, m.Speed)
+	assert.Equal(t, 125, m.Tempo)
+	assert.Equal(t, MaxVolume, m.GlobalVolume)
+	assert.True(t, m.LinearSlides)
+	require.Len(t, m.Patterns, 1)
+	require.Len(t, m.Instruments, 1)
+
+	cells := m.Patterns[0].Cells
+	assert.Equal(t, Cell{Note: MiddleC, Instrument: 1, Volume: 32, Effect: EffectSetSpeed, Param: 3}, cells[0][0])
+	assert.Equal(t, Cell{Volume: NoVolume, Effect: EffectPatternBreak, Param: 10}, cells[1][0])
+	assert.Equal(t, Cell{Note: NoteOff, Volume: NoVolume}, cells[1][1])
+	assert.Equal(t, Cell{Note: MiddleC, Instrument: 1, Volume: NoVolume}, cells[2][0])
+
+	instrument := m.Instruments[0]
+	assert.Equal(t, testSampleName, instrument.Name)
+	assert.Equal(t, float64(testITFadeout)/1024, instrument.Fadeout)
+	require.NotNil(t, instrument.Envelope)
+	assert.Equal(t, []EnvelopePoint{{0, 1}, {10, 0}}, instrument.Envelope.Points)
+	assert.True(t, instrument.Envelope.Sustain)
+
+	sample := instrument.sample(MiddleC)
+	require.NotNil(t, sample)
+	assert.Equal(t, 48, sample.Volume)
+	assert.Equal(t, CenterPan, sample.Panning)
+	assert.Equal(t, float64(1), sample.GlobalVolume)
+	require.Len(t, sample.Data, testSampleLength)
+	assert.Equal(t, float32(0.5), sample.Data[0])
+	assert.Equal(t, float32(-0.5), sample.Data[testSampleLength-1])
+	assert.True(t, sample.Loop)
+}
+
+func TestLoadIT_Samples(t *testing.T) {
+	// Modules which don't use instruments play samples directly
+	data := newTestIT()
+	binary.LittleEndian.PutUint16(data[0x2C:], 0)
+
+	m, err := LoadIT(data)
+	require.NoError(t, err)
+	assert.False(t, m.LinearSlides)
+	require.Len(t, m.Instruments, 1)
+	assert.Nil(t, m.Instruments[0].Envelope)
+	assert.NotNil(t, m.Instruments[0].sample(1))
+}
+
+func TestLoadIT_Invalid(t *testing.T) {
+	valid := newTestIT()
+	testCases := []struct {
+		name string
+		data []byte
+	}{
+		{"Empty", []byte{}},
+		{"NoSignature", make([]byte, itHeaderLength)},
+		{"TruncatedPointers", valid[:0xC4]},
+		{"TruncatedInstrument", valid[:testITInstrumentOffset+10]},
+		{"TruncatedPattern", valid[:testITPatternOffset+12]},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			m, err := LoadIT(testCase.data)
+			assert.Error(tt, err)
+			assert.Nil(tt, m)
+		})
+	}
+}
+
+func TestLoadIT_Play(t *testing.T) {
+	m, err := LoadIT(newTestIT())
+	require.NoError(t, err)
+
+	// The song breaks after the second row at a speed of 3
+	s := NewStream(m, testSampleRate)
+	assert.Equal(t, 2*3*testTickFrames, s.Len())
+
+	samples := make([][2]float64, testTickFrames)
+	_, ok := s.Stream(samples)
+	require.True(t, ok)
+	assert.NotEqual(t, [2]float64{}, samples[testTickFrames/2])
+}
+
+func TestITVolume(t *testing.T) {
+	testCases := []struct {
+		name   string
+		value  byte
+		volume int
+		effect Effect
+		param  int
+	}{
+		{"Volume", 32, 32, EffectNone, 0},
+		{"FineVolumeUp", 67, NoVolume, EffectFineVolumeUp, 2},
+		{"VolumeSlideDown", 99, NoVolume, EffectVolumeSlide, 4},
+		{"PortaUp", 116, NoVolume, EffectPortaUp, 4},
+		{"Panning", 192, NoVolume, EffectSetPanning, 255},
+		{"TonePorta", 195, NoVolume, EffectTonePorta, 0x04},
+		{"Vibrato", 205, NoVolume, EffectVibrato, 2},
+		{"Unused", 126, NoVolume, EffectNone, 0},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			volume, effect, param := itVolume(testCase.value)
+			assert.Equal(tt, testCase.volume, volume)
+			assert.Equal(tt, testCase.effect, effect)
+			assert.Equal(tt, testCase.param, param)
+		})
+	}
+}
+
+// bitWriter writes values of any width to the least significant bits of bytes first
+type bitWriter struct {
+	data []byte
+	bits int
+}
+
+func (w *bitWriter) write(value uint32, width int) {
+	for i := 0; i < width; i++ {
+		if w.bits%8 == 0 {
+			w.data = append(w.data, 0)
+		}
+
+		w.data[len(w.data)-1] |= byte(value>>uint(i)&1) << uint(w.bits%8)
+		w.bits++
+	}
+}
+
+// block returns the written bits as a compressed block
+func (w *bitWriter) block() []byte {
+	block := make([]byte, 2)
+	binary.LittleEndian.PutUint16(block, uint16(len(w.data)))
+	return append(block, w.data...)
+}
+
+func TestDecompressITSample(t *testing.T) {
+	// 8-bit samples start at 9 bits, where values with the top bit set change the width
+	w8 := &bitWriter{}
+	w8.write(10, 9)
+	w8.write(0xFE, 9)
+	w8.write(0x100|2, 9)
+	w8.write(1, 3)
+	w8.write(0x7, 3)
+	w8.write(0x4, 3)
+	w8.write(7, 3)
+	w8.write(0xFF, 9)
+
+	// 16-bit samples start at 17 bits
+	w16 := &bitWriter{}
+	w16.write(1000, 17)
+	w16.write(0x1FFFF&^0x10000, 17)
+	w16.write(0x10000|7, 17)
+	w16.write(0xFF, 8)
+
+	testCases := []struct {
+		name     string
+		data     []byte
+		length   int
+		wide     bool
+		delta    bool
+		expected []float32
+	}{
+		{"8Bit", w8.block(), 5, false, false, []float32{10, 8, 9, 8, 7}},
+		{"8BitDelta", w8.block(), 5, false, true, []float32{10, 18, 27, 35, 42}},
+		{"16Bit", w16.block(), 3, true, false, []float32{1000, 999, 998}},
+		{"Truncated", w8.block(), 10, false, false, []float32{10, 8, 9, 8, 7}},
+		{"Empty", []byte{}, 10, false, false, []float32{}},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			scale := float32(128)
+			if testCase.wide {
+				scale = 32768
+			}
+
+			samples := decompressITSample(testCase.data, testCase.length, testCase.wide, testCase.delta)
+			require.Len(tt, samples, len(testCase.expected))
+			for i, expected := range testCase.expected {
+				assert.Equal(tt, expected/scale, samples[i])
+			}
+		})
+	}
+}
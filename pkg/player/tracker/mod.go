@@ -0,0 +1,168 @@
+package tracker
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	modTitleLength  = 20
+	modSampleOffset = 20
+	modSampleLength = 30
+	modOrdersLength = 128
+	modRows         = 64
+	modCellLength   = 4
+
+	// modTagOffset is the offset of the tag identifying 31-sample MODs and their number of channels
+	modTagOffset = modSampleOffset + 31*modSampleLength + 2 + modOrdersLength
+)
+
+// modChannels returns the number of channels of a MOD with tag or 0 if tag isn't a known MOD tag
+func modChannels(tag string) int {
+	switch tag {
+	case "M.K.", "M!K!", "M&K!", "FLT4", "4CHN", "N.T.":
+		return 4
+	case "FLT8", "OKTA", "OCTA", "CD81":
+		return 8
+	}
+
+	if strings.HasSuffix(tag, "CHN") {
+		if channels, err := strconv.Atoi(tag[:1]); err == nil && channels > 0 {
+			return channels
+		}
+	}
+
+	if strings.HasSuffix(tag, "CH") || strings.HasSuffix(tag, "CN") {
+		if channels, err := strconv.Atoi(tag[:2]); err == nil && channels > 0 && channels <= 32 {
+			return channels
+		}
+	}
+
+	return 0
+}
+
+// LoadMOD loads a ProTracker MOD or one of its variants. MODs without a tag are loaded as 15-sample Soundtracker
+// modules
+func LoadMOD(data []byte) (*Module, error) {
+	samples, channels := 31, 0
+	if len(data) >= modTagOffset+4 {
+		channels = modChannels(string(data[modTagOffset : modTagOffset+4]))
+	}
+
+	if channels == 0 {
+		samples, channels = 15, 4
+	}
+
+	ordersOffset := modSampleOffset + samples*modSampleLength + 2
+	patternsOffset := ordersOffset + modOrdersLength
+	if samples == 31 {
+		patternsOffset += 4
+	}
+
+	if len(data) < patternsOffset {
+		return nil, ErrTruncated
+	}
+
+	m := &Module{
+		Title:        text(data[:modTitleLength]),
+		Format:       "mod",
+		Channels:     channels,
+		Speed:        6,
+		Tempo:        125,
+		GlobalVolume: MaxVolume,
+		AmigaLimits:  channels == 4,
+	}
+
+	// Amiga channels are hard-panned left, right, right, left. Panning them partially sounds better on headphones
+	for i := 0; i < channels; i++ {
+		if i%4 == 0 || i%4 == 3 {
+			m.Panning = append(m.Panning, 64)
+		} else {
+			m.Panning = append(m.Panning, 192)
+		}
+	}
+
+	length := int(data[ordersOffset-2])
+	if length < 1 || length > modOrdersLength {
+		return nil, fmt.Errorf("invalid song length %d", length)
+	}
+
+	patterns := 0
+	for i := 0; i < modOrdersLength; i++ {
+		order := int(data[ordersOffset+i])
+		if i < length {
+			m.Orders = append(m.Orders, order)
+		}
+
+		// Some MODs store patterns which are only referenced past the end of the song
+		if order+1 > patterns {
+			patterns = order + 1
+		}
+	}
+
+	offset := patternsOffset
+	for i := 0; i < patterns; i++ {
+		size := modRows * channels * modCellLength
+		if len(data) < offset+size {
+			return nil, ErrTruncated
+		}
+
+		m.Patterns = append(m.Patterns, loadMODPattern(data[offset:offset+size], channels))
+		offset += size
+	}
+
+	for i := 0; i < samples; i++ {
+		header := data[modSampleOffset+i*modSampleLength : modSampleOffset+(i+1)*modSampleLength]
+		sample := newSample(text(header[:22]))
+
+		length := int(binary.BigEndian.Uint16(header[22:])) * 2
+		finetune := int(header[24]&0xF) << 28 >> 28
+		sample.C5Speed = amigaC5Speed * math.Pow(2, float64(finetune)/(12*8))
+		sample.Volume = clamp(int(header[25]), 0, MaxVolume)
+
+		// The last sample is often truncated by a few bytes
+		if offset+length > len(data) {
+			length = len(data) - offset
+		}
+
+		sample.Data = make([]float32, length)
+		for j := 0; j < length; j++ {
+			sample.Data[j] = float32(int8(data[offset+j])) / 128
+		}
+
+		offset += length
+
+		loopStart := int(binary.BigEndian.Uint16(header[26:])) * 2
+		loopLength := int(binary.BigEndian.Uint16(header[28:])) * 2
+		if loopLength > 2 {
+			sample.setLoop(loopStart, loopStart+loopLength, false)
+		}
+
+		m.Instruments = append(m.Instruments, newSampleInstrument(sample))
+	}
+
+	return m, nil
+}
+
+// loadMODPattern loads a pattern where each cell packs the sample, period, and effect in four bytes
+func loadMODPattern(data []byte, channels int) *Pattern {
+	p := newPattern(modRows, channels)
+	for row := 0; row < modRows; row++ {
+		for channel := 0; channel < channels; channel++ {
+			b := data[(row*channels+channel)*modCellLength:]
+			cell := &p.Cells[row][channel]
+
+			cell.Instrument = int(b[0]&0xF0) | int(b[2]>>4)
+			if period := int(b[0]&0xF)<<8 | int(b[1]); period > 0 {
+				cell.Note = clamp(MiddleC+int(math.Round(12*math.Log2(amigaC5Period/float64(period)))), 1, MaxNote)
+			}
+
+			cell.Effect, cell.Param = protrackerEffect(int(b[2]&0xF), int(b[3]))
+		}
+	}
+
+	return p
+}
@@ -0,0 +1,163 @@
+package tracker
+
+import (
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+const (
+	testTitle        = "some.title"
+	testSampleName   = "some.sample"
+	testSampleLength = 64
+	testC5Period     = 428
+)
+
+// modCell is a cell of a test MOD, placed on a row of a channel of the first pattern
+type modCell struct {
+	row, channel int
+	period       int
+	sample       int
+	effect       int
+	param        int
+}
+
+// newTestMOD creates a 4-channel MOD with a looping square wave as its only sample and a single pattern with cells
+func newTestMOD(cells ...modCell) []byte {
+	data := make([]byte, modTagOffset)
+	copy(data, testTitle)
+
+	header := data[modSampleOffset:]
+	copy(header, testSampleName)
+	binary.BigEndian.PutUint16(header[22:], testSampleLength/2)
+	header[25] = MaxVolume
+	binary.BigEndian.PutUint16(header[28:], testSampleLength/2)
+
+	data[modTagOffset-modOrdersLength-2] = 1
+	data = append(data, "M.K."...)
+
+	pattern := make([]byte, modRows*4*modCellLength)
+	for _, cell := range cells {
+		b := pattern[(cell.row*4+cell.channel)*modCellLength:]
+		b[0] = byte(cell.sample&0xF0) | byte(cell.period>>8)
+		b[1] = byte(cell.period)
+		b[2] = byte(cell.sample&0xF)<<4 | byte(cell.effect)
+		b[3] = byte(cell.param)
+	}
+
+	data = append(data, pattern...)
+	for i := 0; i < testSampleLength; i++ {
+		if i < testSampleLength/2 {
+			data = append(data, 0x40)
+		} else {
+			data = append(data, 0xC0)
+		}
+	}
+
+	return data
+}
+
+func TestLoadMOD(t *testing.T) {
+	data := newTestMOD(
+		modCell{row: 0, channel: 0, period: testC5Period, sample: 1},
+		modCell{row: 1, channel: 1, period: 214, sample: 1, effect: 0xC, param: 0x20},
+		modCell{row: 2, channel: 2, effect: 0xD, param: 0x12},
+		modCell{row: 3, channel: 3, effect: 0xE, param: 0xA3},
+	)
+
+	m, err := LoadMOD(data)
+	require.NoError(t, err)
+	assert.Equal(t, testTitle, m.Title)
+	assert.Equal(t, "mod", m.Format)
+	assert.Equal(t, 4, m.Channels)
+	assert.Equal(t, []int{0}, m.Orders)
+	assert.Equal(t, []int{64, 192, 192, 64}, m.Panning)
+	require.Len(t, m.Patterns, 1)
+	require.Len(t, m.Instruments, 31)
+
+	cells := m.Patterns[0].Cells
+	assert.Equal(t, Cell{Note: MiddleC, Instrument: 1, Volume: NoVolume}, cells[0][0])
+	assert.Equal(t, Cell{Note: MiddleC + 12, Instrument: 1, Volume: NoVolume, Effect: EffectSetVolume, Param: 0x20}, cells[1][1])
+	assert.Equal(t, Cell{Volume: NoVolume, Effect: EffectPatternBreak, Param: 12}, cells[2][2])
+	assert.Equal(t, Cell{Volume: NoVolume, Effect: EffectFineVolumeUp, Param: 3}, cells[3][3])
+
+	sample := m.Instruments[0].sample(MiddleC)
+	require.NotNil(t, sample)
+	assert.Equal(t, testSampleName, sample.Name)
+	assert.Equal(t, MaxVolume, sample.Volume)
+	assert.Len(t, sample.Data, testSampleLength)
+	assert.Equal(t, float32(0.5), sample.Data[0])
+	assert.Equal(t, float32(-0.5), sample.Data[testSampleLength-1])
+	assert.True(t, sample.Loop)
+	assert.Equal(t, 0, sample.LoopStart)
+	assert.Equal(t, testSampleLength, sample.LoopEnd)
+	assert.Equal(t, float64(amigaC5Speed), sample.C5Speed)
+}
+
+func TestLoadMOD_Soundtracker(t *testing.T) {
+	// Soundtracker modules have 15 samples and no tag
+	data := make([]byte, modSampleOffset+15*modSampleLength+2+modOrdersLength+modRows*4*modCellLength)
+	copy(data, testTitle)
+	data[modSampleOffset+15*modSampleLength] = 1
+
+	m, err := LoadMOD(data)
+	require.NoError(t, err)
+	assert.Equal(t, testTitle, m.Title)
+	assert.Equal(t, 4, m.Channels)
+	assert.Len(t, m.Instruments, 15)
+	assert.Len(t, m.Patterns, 1)
+}
+
+func TestLoadMOD_Invalid(t *testing.T) {
+	valid := newTestMOD()
+	noSongLength := newTestMOD()
+	noSongLength[modTagOffset-modOrdersLength-2] = 0
+
+	testCases := []struct {
+		name string
+		data []byte
+	}{
+		{"Empty", []byte{}},
+		{"TruncatedHeader", valid[:modTagOffset]},
+		{"TruncatedPattern", valid[:modTagOffset+4+10]},
+		{"NoSongLength", noSongLength},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			m, err := LoadMOD(testCase.data)
+			assert.Error(tt, err)
+			assert.Nil(tt, m)
+		})
+	}
+}
+
+func TestLoadMOD_TruncatedSample(t *testing.T) {
+	// The last sample is played up to where the module ends
+	data := newTestMOD()
+	m, err := LoadMOD(data[:len(data)-testSampleLength/2])
+	require.NoError(t, err)
+	assert.Len(t, m.Instruments[0].Samples[0].Data, testSampleLength/2)
+}
+
+func TestModChannels(t *testing.T) {
+	testCases := []struct {
+		tag      string
+		channels int
+	}{
+		{"M.K.", 4},
+		{"FLT8", 8},
+		{"6CHN", 6},
+		{"12CH", 12},
+		{"32CH", 32},
+		{"99CH", 0},
+		{"some", 0},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.tag, func(tt *testing.T) {
+			assert.Equal(tt, testCase.channels, modChannels(testCase.tag))
+		})
+	}
+}
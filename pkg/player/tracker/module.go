@@ -0,0 +1,334 @@
+package tracker
+
+import (
+	"bytes"
+	"errors"
+	"math"
+)
+
+const (
+	// NoNote means a cell of a pattern does not play a note
+	NoNote = 0
+
+	// MiddleC is the note at which a sample plays at its C5Speed
+	MiddleC = 61
+
+	// MaxNote is the highest note which can be played
+	MaxNote = 120
+
+	// NoteFade fades out the note playing on a channel
+	NoteFade = 252
+
+	// NoteCut silences the note playing on a channel immediately
+	NoteCut = 253
+
+	// NoteOff releases the note playing on a channel so its envelope can finish
+	NoteOff = 254
+
+	// NoVolume means a cell of a pattern does not set the volume
+	NoVolume = -1
+
+	// MaxVolume is the highest volume of a channel, sample, or module
+	MaxVolume = 64
+
+	// CenterPan is the panning of a channel playing equally on both speakers
+	CenterPan = 128
+
+	// amigaC5Period is the Amiga period of a sample with a C5Speed of amigaC5Speed playing MiddleC
+	amigaC5Period = 428
+
+	// amigaC5Speed is the sample rate of a sample with no finetune playing MiddleC
+	amigaC5Speed = 8363
+
+	// linearC5Period is the linear period of MiddleC. Linear periods are 64 units per semitone
+	linearC5Period = 3840
+)
+
+var (
+	// ErrUnknownFormat is an error returned when data is not a module in any of the supported formats
+	ErrUnknownFormat = errors.New("unknown module format")
+
+	// ErrTruncated is an error returned when a module ends before all of its data was read
+	ErrTruncated = errors.New("module is truncated")
+)
+
+// Module is a tracker module loaded from any of the supported formats. Every format is converted to the same
+// representation, with effects normalized to a common set, so a single player can play all of them
+type Module struct {
+	Title  string
+	Format string
+
+	// Channels is the number of channels in every pattern
+	Channels int
+
+	// Orders are the indices of the patterns in the order they are played
+	Orders []int
+
+	Patterns    []*Pattern
+	Instruments []*Instrument
+
+	// Panning is the initial panning of each channel, from 0 (left) to 255 (right)
+	Panning []int
+
+	// Speed is the initial number of ticks per row and Tempo the initial number of ticks per 2.5 seconds
+	Speed int
+	Tempo int
+
+	// GlobalVolume is the initial volume of the whole module, up to MaxVolume
+	GlobalVolume int
+
+	// LinearSlides is true if pitch slides change the pitch by the same interval at any pitch. Otherwise pitch slides
+	// change the Amiga period, like ProTracker
+	LinearSlides bool
+
+	// AmigaLimits is true if pitches are limited to the three octaves of ProTracker
+	AmigaLimits bool
+}
+
+// Pattern is a grid of cells with a row for each step of the song and a column for each channel
+type Pattern struct {
+	Rows  int
+	Cells [][]Cell
+}
+
+// newPattern creates an empty pattern
+func newPattern(rows, channels int) *Pattern {
+	p := &Pattern{Rows: rows, Cells: make([][]Cell, rows)}
+	for i := range p.Cells {
+		p.Cells[i] = make([]Cell, channels)
+		for j := range p.Cells[i] {
+			p.Cells[i][j].Volume = NoVolume
+		}
+	}
+
+	return p
+}
+
+// Cell is what a channel does on a row of a pattern
+type Cell struct {
+	// Note is the note to play from 1 to MaxNote, NoNote, NoteOff, NoteCut, or NoteFade
+	Note int
+
+	// Instrument is the 1-based index of the instrument to play. If 0, the last instrument of the channel is used
+	Instrument int
+
+	// Volume is the volume to set or NoVolume
+	Volume int
+
+	// Effect and Param are from the effect column. VolumeEffect and VolumeParam are from the volume column of formats
+	// which allow effects there
+	Effect       Effect
+	Param        int
+	VolumeEffect Effect
+	VolumeParam  int
+}
+
+// Instrument maps notes to samples and shapes the volume of the notes it plays
+type Instrument struct {
+	Name string
+
+	// Samples maps each note to the 1-based index of the sample it plays in Samples. 0 plays nothing
+	Keymap  [MaxNote + 1]int
+	Samples []*Sample
+
+	// Envelope is the volume envelope. If nil, notes play at a constant volume and are cut when released
+	Envelope *Envelope
+
+	// Fadeout is how much of the volume is lost each tick after a note is released
+	Fadeout float64
+}
+
+// newSampleInstrument creates an instrument which plays a single sample for every note, for formats without
+// instruments
+func newSampleInstrument(sample *Sample) *Instrument {
+	instrument := &Instrument{Name: sample.Name, Samples: []*Sample{sample}}
+	for note := range instrument.Keymap {
+		instrument.Keymap[note] = 1
+	}
+
+	return instrument
+}
+
+// sample returns the sample played for note or nil if the instrument plays nothing for it
+func (i *Instrument) sample(note int) *Sample {
+	if note < 1 || note > MaxNote {
+		return nil
+	}
+
+	index := i.Keymap[note]
+	if index < 1 || index > len(i.Samples) {
+		return nil
+	}
+
+	return i.Samples[index-1]
+}
+
+// Sample is mono audio played by an instrument
+type Sample struct {
+	Name string
+
+	// Data is the audio normalized between -1 and 1
+	Data []float32
+
+	// LoopStart and LoopEnd are the frames between which the sample loops. If Loop is false, the sample plays once
+	Loop      bool
+	PingPong  bool
+	LoopStart int
+	LoopEnd   int
+
+	// Volume is the default volume, up to MaxVolume
+	Volume int
+
+	// GlobalVolume scales every note played by the sample, from 0 to 1
+	GlobalVolume float64
+
+	// Panning is the default panning from 0 to 255 or -1 to keep the panning of the channel
+	Panning int
+
+	// C5Speed is the sample rate at which the sample plays MiddleC
+	C5Speed float64
+}
+
+// newSample creates a sample with defaults shared by every format
+func newSample(name string) *Sample {
+	return &Sample{Name: name, Volume: MaxVolume, GlobalVolume: 1, Panning: -1, C5Speed: amigaC5Speed}
+}
+
+// setLoop sets the loop of the sample if it's within the data of the sample
+func (s *Sample) setLoop(start, end int, pingPong bool) {
+	if end > len(s.Data) {
+		end = len(s.Data)
+	}
+
+	if start < 0 || end-start < 2 {
+		return
+	}
+
+	s.Loop, s.PingPong, s.LoopStart, s.LoopEnd = true, pingPong, start, end
+}
+
+// Envelope changes the volume of a note over time. Points are sorted by tick and values go from 0 to 1
+type Envelope struct {
+	Points []EnvelopePoint
+
+	// Sustain holds the envelope between the points at SustainStart and SustainEnd until the note is released. Loop
+	// repeats the envelope between the points at LoopStart and LoopEnd
+	Sustain      bool
+	SustainStart int
+	SustainEnd   int
+	Loop         bool
+	LoopStart    int
+	LoopEnd      int
+}
+
+// EnvelopePoint is a point of an envelope
+type EnvelopePoint struct {
+	Tick  int
+	Value float64
+}
+
+// valid returns true if the envelope has points and its sustain and loop refer to them
+func (e *Envelope) valid() bool {
+	if len(e.Points) == 0 {
+		return false
+	}
+
+	for i := 1; i < len(e.Points); i++ {
+		if e.Points[i].Tick < e.Points[i-1].Tick {
+			return false
+		}
+	}
+
+	inRange := func(start, end int) bool {
+		return start >= 0 && start <= end && end < len(e.Points)
+	}
+
+	return (!e.Sustain || inRange(e.SustainStart, e.SustainEnd)) && (!e.Loop || inRange(e.LoopStart, e.LoopEnd))
+}
+
+// value returns the value of the envelope at tick by interpolating between its points
+func (e *Envelope) value(tick int) float64 {
+	points := e.Points
+	if tick <= points[0].Tick {
+		return points[0].Value
+	}
+
+	for i := 1; i < len(points); i++ {
+		if tick < points[i].Tick {
+			previous := points[i-1]
+			span := float64(points[i].Tick - previous.Tick)
+			return previous.Value + (points[i].Value-previous.Value)*float64(tick-previous.Tick)/span
+		}
+	}
+
+	return points[len(points)-1].Value
+}
+
+// next returns the tick following tick. Sustain holds the envelope until released and loops send it back to the
+// start of the loop
+func (e *Envelope) next(tick int, released bool) int {
+	if e.Sustain && !released {
+		if end := e.Points[e.SustainEnd].Tick; tick >= end {
+			return e.Points[e.SustainStart].Tick
+		}
+	}
+
+	tick++
+	if e.Loop {
+		if end := e.Points[e.LoopEnd].Tick; tick > end {
+			return e.Points[e.LoopStart].Tick
+		}
+	}
+
+	return tick
+}
+
+// amigaPeriod returns the Amiga period of note for a sample with c5Speed
+func amigaPeriod(note int, c5Speed float64) float64 {
+	return amigaC5Period * math.Pow(2, float64(MiddleC-note)/12) * amigaC5Speed / c5Speed
+}
+
+// linearPeriod returns the linear period of note
+func linearPeriod(note int) float64 {
+	return linearC5Period - float64(note-MiddleC)*64
+}
+
+// Load loads a module in any of the supported formats, which are ProTracker MOD, Scream Tracker 3 S3M, FastTracker 2
+// XM, and Impulse Tracker IT. The format is detected from the content of data. Formats without a signature, i.e.
+// 15-sample Soundtracker MODs, are only loaded by LoadMOD
+func Load(data []byte) (*Module, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte(xmSignature)):
+		return LoadXM(data)
+	case bytes.HasPrefix(data, []byte(itSignature)):
+		return LoadIT(data)
+	case len(data) >= s3mSignatureOffset+len(s3mSignature) && string(data[s3mSignatureOffset:s3mSignatureOffset+len(s3mSignature)]) == s3mSignature:
+		return LoadS3M(data)
+	case len(data) >= modTagOffset+4 && modChannels(string(data[modTagOffset:modTagOffset+4])) > 0:
+		return LoadMOD(data)
+	default:
+		return nil, ErrUnknownFormat
+	}
+}
+
+// text returns a string from a fixed-size field, which is padded with nulls or spaces
+func text(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+
+	return string(bytes.TrimRight(b, " "))
+}
+
+// clamp limits v to the range from min to max
+func clamp(v, min, max int) int {
+	if v < min {
+		return min
+	}
+
+	if v > max {
+		return max
+	}
+
+	return v
+}
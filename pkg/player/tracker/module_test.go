@@ -0,0 +1,127 @@
+package tracker
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	testCases := []struct {
+		name   string
+		data   []byte
+		format string
+	}{
+		{"MOD", newTestMOD(), "mod"},
+		{"S3M", newTestS3M(), "s3m"},
+		{"XM", newTestXM(), "xm"},
+		{"IT", newTestIT(), "it"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			m, err := Load(testCase.data)
+			require.NoError(tt, err)
+			assert.Equal(tt, testCase.format, m.Format)
+			assert.Equal(tt, testTitle, m.Title)
+		})
+	}
+}
+
+func TestLoad_UnknownFormat(t *testing.T) {
+	testCases := []struct {
+		name string
+		data []byte
+	}{
+		{"Empty", []byte{}},
+		{"NotModule", []byte("some.content")},
+		{"Soundtracker", make([]byte, modTagOffset+4)},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			m, err := Load(testCase.data)
+			assert.True(tt, errors.Is(err, ErrUnknownFormat))
+			assert.Nil(tt, m)
+		})
+	}
+}
+
+func TestEnvelope_Value(t *testing.T) {
+	e := &Envelope{Points: []EnvelopePoint{{0, 0}, {10, 1}, {20, 0.5}}}
+	require.True(t, e.valid())
+
+	testCases := []struct {
+		tick     int
+		expected float64
+	}{
+		{0, 0},
+		{5, 0.5},
+		{10, 1},
+		{15, 0.75},
+		{20, 0.5},
+		{100, 0.5},
+	}
+
+	for _, testCase := range testCases {
+		assert.InDelta(t, testCase.expected, e.value(testCase.tick), 1e-9)
+	}
+}
+
+func TestEnvelope_Next(t *testing.T) {
+	points := []EnvelopePoint{{0, 1}, {4, 1}, {8, 0.5}, {12, 0}}
+	testCases := []struct {
+		name     string
+		envelope *Envelope
+		tick     int
+		released bool
+		expected int
+	}{
+		{"Continue", &Envelope{Points: points}, 4, false, 5},
+		{"Sustain", &Envelope{Points: points, Sustain: true, SustainStart: 1, SustainEnd: 1}, 4, false, 4},
+		{"SustainLoop", &Envelope{Points: points, Sustain: true, SustainStart: 1, SustainEnd: 2}, 8, false, 4},
+		{"Released", &Envelope{Points: points, Sustain: true, SustainStart: 1, SustainEnd: 1}, 4, true, 5},
+		{"Loop", &Envelope{Points: points, Loop: true, LoopStart: 0, LoopEnd: 2}, 8, true, 0},
+		{"PastEnd", &Envelope{Points: points}, 12, true, 13},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			require.True(tt, testCase.envelope.valid())
+			assert.Equal(tt, testCase.expected, testCase.envelope.next(testCase.tick, testCase.released))
+		})
+	}
+}
+
+func TestEnvelope_Invalid(t *testing.T) {
+	points := []EnvelopePoint{{0, 1}, {4, 0}}
+	testCases := []struct {
+		name     string
+		envelope *Envelope
+	}{
+		{"NoPoints", &Envelope{}},
+		{"Unsorted", &Envelope{Points: []EnvelopePoint{{4, 1}, {0, 0}}}},
+		{"Sustain", &Envelope{Points: points, Sustain: true, SustainStart: 2, SustainEnd: 2}},
+		{"Loop", &Envelope{Points: points, Loop: true, LoopStart: 1, LoopEnd: 0}},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			assert.False(tt, testCase.envelope.valid())
+		})
+	}
+}
+
+func TestPeriods(t *testing.T) {
+	assert.InDelta(t, amigaC5Period, amigaPeriod(MiddleC, amigaC5Speed), 1e-9)
+	assert.InDelta(t, amigaC5Period/2, amigaPeriod(MiddleC+12, amigaC5Speed), 1e-9)
+	assert.InDelta(t, amigaC5Period/2, amigaPeriod(MiddleC, amigaC5Speed*2), 1e-9)
+	assert.Equal(t, float64(linearC5Period), linearPeriod(MiddleC))
+	assert.Equal(t, float64(linearC5Period-64), linearPeriod(MiddleC+1))
+}
+
+func TestText(t *testing.T) {
+	assert.Equal(t, testTitle, text([]byte(testTitle+"\x00\x00garbage")))
+	assert.Equal(t, testTitle, text([]byte(testTitle+"   ")))
+}
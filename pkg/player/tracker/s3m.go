@@ -0,0 +1,278 @@
+package tracker
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+const (
+	s3mSignature       = "SCRM"
+	s3mSignatureOffset = 0x2C
+	s3mHeaderLength    = 0x60
+	s3mSampleLength    = 0x50
+	s3mChannels        = 32
+	s3mRows            = 64
+
+	// s3mDefaultPanning is the value of the default panning field when the header is followed by the panning of
+	// each channel
+	s3mDefaultPanning = 252
+)
+
+// LoadS3M loads a Scream Tracker 3 module
+func LoadS3M(data []byte) (*Module, error) {
+	if len(data) < s3mHeaderLength {
+		return nil, ErrTruncated
+	}
+
+	if string(data[s3mSignatureOffset:s3mSignatureOffset+len(s3mSignature)]) != s3mSignature {
+		return nil, ErrUnknownFormat
+	}
+
+	orders := int(binary.LittleEndian.Uint16(data[0x20:]))
+	samples := int(binary.LittleEndian.Uint16(data[0x22:]))
+	patterns := int(binary.LittleEndian.Uint16(data[0x24:]))
+	signed := binary.LittleEndian.Uint16(data[0x2A:]) == 1
+
+	pointers := s3mHeaderLength + orders
+	panningOffset := pointers + (samples+patterns)*2
+	if len(data) < panningOffset {
+		return nil, ErrTruncated
+	}
+
+	m := &Module{
+		Title:        text(data[:0x1C]),
+		Format:       "s3m",
+		Speed:        int(data[0x31]),
+		Tempo:        int(data[0x32]),
+		GlobalVolume: clamp(int(data[0x30]), 0, MaxVolume),
+	}
+
+	// Channels are mapped to the ones which are enabled so disabled channels don't take up any space
+	stereo := data[0x33]&0x80 != 0
+	channelMap := make([]int, s3mChannels)
+	for i := 0; i < s3mChannels; i++ {
+		setting := data[0x40+i]
+		if setting >= 16 {
+			channelMap[i] = -1
+			continue
+		}
+
+		channelMap[i] = m.Channels
+		m.Channels++
+
+		pan := CenterPan
+		if stereo && setting < 8 {
+			pan = 0x30
+		} else if stereo {
+			pan = 0xC0
+		}
+
+		m.Panning = append(m.Panning, pan)
+	}
+
+	if data[0x35] == s3mDefaultPanning && len(data) >= panningOffset+s3mChannels {
+		for i := 0; i < s3mChannels; i++ {
+			if pan := data[panningOffset+i]; channelMap[i] >= 0 && pan&0x20 != 0 {
+				m.Panning[channelMap[i]] = int(pan&0xF) * 17
+			}
+		}
+	}
+
+	for i := 0; i < orders; i++ {
+		// 254 marks a position which is skipped and 255 the end of the song
+		order := int(data[s3mHeaderLength+i])
+		if order == 255 {
+			break
+		}
+
+		if order < patterns {
+			m.Orders = append(m.Orders, order)
+		}
+	}
+
+	for i := 0; i < samples; i++ {
+		offset := int(binary.LittleEndian.Uint16(data[pointers+i*2:])) * 16
+		sample, err := loadS3MSample(data, offset, signed)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load sample %d: %w", i+1, err)
+		}
+
+		m.Instruments = append(m.Instruments, newSampleInstrument(sample))
+	}
+
+	for i := 0; i < patterns; i++ {
+		offset := int(binary.LittleEndian.Uint16(data[pointers+(samples+i)*2:])) * 16
+		pattern, err := loadS3MPattern(data, offset, channelMap, m.Channels)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load pattern %d: %w", i, err)
+		}
+
+		m.Patterns = append(m.Patterns, pattern)
+	}
+
+	return m, nil
+}
+
+// loadS3MSample loads the sample with the header at offset. Headers which aren't for samples, i.e. AdLib instruments,
+// are loaded as silent samples
+func loadS3MSample(data []byte, offset int, signed bool) (*Sample, error) {
+	if offset == 0 {
+		return newSample(""), nil
+	}
+
+	if len(data) < offset+s3mSampleLength {
+		return nil, ErrTruncated
+	}
+
+	header := data[offset : offset+s3mSampleLength]
+	sample := newSample(text(header[0x30:0x4C]))
+	if header[0] != 1 {
+		return sample, nil
+	}
+
+	sample.Volume = clamp(int(header[0x1C]), 0, MaxVolume)
+	if c5Speed := binary.LittleEndian.Uint32(header[0x20:]); c5Speed > 0 {
+		sample.C5Speed = float64(c5Speed)
+	}
+
+	flags := header[0x1F]
+	wide := flags&0x4 != 0
+	length := int(binary.LittleEndian.Uint32(header[0x10:]))
+	start := (int(header[0x0D])<<16 | int(binary.LittleEndian.Uint16(header[0x0E:]))) * 16
+
+	width := 1
+	if wide {
+		width = 2
+	}
+
+	// Stereo samples store the left channel followed by the right channel, so only the left channel is played
+	if start+length*width > len(data) {
+		length = (len(data) - start) / width
+		if length < 0 {
+			return nil, ErrTruncated
+		}
+	}
+
+	sample.Data = make([]float32, length)
+	for i := 0; i < length; i++ {
+		if wide {
+			v := binary.LittleEndian.Uint16(data[start+i*2:])
+			if !signed {
+				v ^= 0x8000
+			}
+
+			sample.Data[i] = float32(int16(v)) / 32768
+		} else {
+			v := data[start+i]
+			if !signed {
+				v ^= 0x80
+			}
+
+			sample.Data[i] = float32(int8(v)) / 128
+		}
+	}
+
+	if flags&0x1 != 0 {
+		sample.setLoop(int(binary.LittleEndian.Uint32(header[0x14:])), int(binary.LittleEndian.Uint32(header[0x18:])), false)
+	}
+
+	return sample, nil
+}
+
+// loadS3MPattern loads the packed pattern at offset, where each cell starts with a byte holding the channel and which
+// of its fields follow. Rows end with a zero byte
+func loadS3MPattern(data []byte, offset int, channelMap []int, channels int) (*Pattern, error) {
+	p := newPattern(s3mRows, channels)
+	if offset == 0 {
+		return p, nil
+	}
+
+	if len(data) < offset+2 {
+		return nil, ErrTruncated
+	}
+
+	r := &byteReader{data: data, offset: offset + 2}
+	for row := 0; row < s3mRows; row++ {
+		for {
+			what, err := r.byte()
+			if err != nil {
+				return nil, err
+			}
+
+			if what == 0 {
+				break
+			}
+
+			var cell Cell
+			if what&0x20 != 0 {
+				note, instrument, err := r.pair()
+				if err != nil {
+					return nil, err
+				}
+
+				switch {
+				case note == 254:
+					cell.Note = NoteCut
+				case note < 0xA0 && note&0xF < 12:
+					// Scream Tracker plays samples at their C5Speed on C-4
+					cell.Note = (int(note>>4)+1)*12 + int(note&0xF) + 1
+				}
+
+				cell.Instrument = int(instrument)
+			}
+
+			cell.Volume = NoVolume
+			if what&0x40 != 0 {
+				volume, err := r.byte()
+				if err != nil {
+					return nil, err
+				}
+
+				cell.Volume = clamp(int(volume), 0, MaxVolume)
+			}
+
+			if what&0x80 != 0 {
+				command, param, err := r.pair()
+				if err != nil {
+					return nil, err
+				}
+
+				cell.Effect, cell.Param = s3mEffect('A'+command-1, int(param), false)
+			}
+
+			if channel := channelMap[what&0x1F]; channel >= 0 {
+				p.Cells[row][channel] = cell
+			}
+		}
+	}
+
+	return p, nil
+}
+
+// byteReader reads bytes from packed patterns, returning ErrTruncated when it reads past the end of the data
+type byteReader struct {
+	data   []byte
+	offset int
+}
+
+// byte reads a byte
+func (r *byteReader) byte() (byte, error) {
+	if r.offset >= len(r.data) {
+		return 0, ErrTruncated
+	}
+
+	b := r.data[r.offset]
+	r.offset++
+	return b, nil
+}
+
+// pair reads two bytes
+func (r *byteReader) pair() (byte, byte, error) {
+	first, err := r.byte()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	second, err := r.byte()
+	return first, second, err
+}
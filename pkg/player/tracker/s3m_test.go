@@ -0,0 +1,143 @@
+package tracker
+
+import (
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+const (
+	testS3MSampleOffset  = 0x90
+	testS3MPatternOffset = 0xE0
+)
+
+// newTestS3M creates an S3M with two enabled channels, one looping sample, and one pattern. The first row plays C-4
+// on the first channel at half volume and sets the speed, the second row breaks to row 10 and plays a note on a
+// disabled channel
+func newTestS3M() []byte {
+	data := make([]byte, testS3MPatternOffset)
+	copy(data, testTitle)
+	data[0x1C], data[0x1D] = 0x1A, 16
+	binary.LittleEndian.PutUint16(data[0x20:], 2)
+	binary.LittleEndian.PutUint16(data[0x22:], 1)
+	binary.LittleEndian.PutUint16(data[0x24:], 1)
+	binary.LittleEndian.PutUint16(data[0x2A:], 2)
+	copy(data[s3mSignatureOffset:], s3mSignature)
+	data[0x30], data[0x31], data[0x32], data[0x33] = MaxVolume, 6, 125, 0xB0
+	data[0x35] = s3mDefaultPanning
+
+	for i := 0; i < s3mChannels; i++ {
+		data[0x40+i] = 255
+	}
+
+	data[0x40], data[0x41] = 0, 8
+	data[0x60], data[0x61] = 0, 255
+	binary.LittleEndian.PutUint16(data[0x62:], testS3MSampleOffset/16)
+	binary.LittleEndian.PutUint16(data[0x64:], testS3MPatternOffset/16)
+	data[0x66], data[0x67] = 0x20|3, 0x20|12
+
+	sample := data[testS3MSampleOffset:]
+	sample[0] = 1
+	sample[0x1C], sample[0x1F] = 48, 0x1
+	binary.LittleEndian.PutUint32(sample[0x10:], testSampleLength)
+	binary.LittleEndian.PutUint32(sample[0x18:], testSampleLength)
+	binary.LittleEndian.PutUint32(sample[0x20:], amigaC5Speed)
+	copy(sample[0x30:], testSampleName)
+	copy(sample[0x4C:], "SCRS")
+
+	pattern := []byte{0, 0,
+		0x20 | 0x40 | 0x80 | 0, 0x40, 1, 32, 'A' - 'A' + 1, 3, 0,
+		0x80 | 1, 'C' - 'A' + 1, 0x10, 0x20 | 5, 0x40, 1, 0,
+	}
+
+	for row := 2; row < s3mRows; row++ {
+		pattern = append(pattern, 0)
+	}
+
+	binary.LittleEndian.PutUint16(pattern, uint16(len(pattern)))
+	data = append(data, pattern...)
+
+	for len(data)%16 != 0 {
+		data = append(data, 0)
+	}
+
+	start := len(data) / 16
+	pointer := data[testS3MSampleOffset+0x0D:]
+	pointer[0], pointer[1], pointer[2] = byte(start>>16), byte(start), byte(start>>8)
+	for i := 0; i < testSampleLength; i++ {
+		data = append(data, byte(0x80+i))
+	}
+
+	return data
+}
+
+func TestLoadS3M(t *testing.T) {
+	m, err := LoadS3M(newTestS3M())
+	require.NoError(t, err)
+	assert.Equal(t, testTitle, m.Title)
+	assert.Equal(t, "s3m", m.Format)
+	assert.Equal(t, 2, m.Channels)
+	assert.Equal(t, []int{0}, m.Orders)
+	assert.Equal(t, []int{3 * 17, 12 * 17}, m.Panning)
+	assert.Equal(t, 6, m.Speed)
+	assert.Equal(t, 125, m.Tempo)
+	assert.Equal(t, MaxVolume, m.GlobalVolume)
+	assert.False(t, m.LinearSlides)
+	require.Len(t, m.Patterns, 1)
+	require.Len(t, m.Instruments, 1)
+
+	cells := m.Patterns[0].Cells
+	assert.Equal(t, Cell{Note: MiddleC, Instrument: 1, Volume: 32, Effect: EffectSetSpeed, Param: 3}, cells[0][0])
+	assert.Equal(t, Cell{Volume: NoVolume}, cells[0][1])
+	assert.Equal(t, Cell{Volume: NoVolume, Effect: EffectPatternBreak, Param: 10}, cells[1][1])
+	assert.Equal(t, Cell{Volume: NoVolume}, cells[1][0])
+
+	sample := m.Instruments[0].sample(MiddleC)
+	require.NotNil(t, sample)
+	assert.Equal(t, testSampleName, sample.Name)
+	assert.Equal(t, 48, sample.Volume)
+	assert.Equal(t, float64(amigaC5Speed), sample.C5Speed)
+	require.Len(t, sample.Data, testSampleLength)
+	assert.Equal(t, float32(0), sample.Data[0])
+	assert.Equal(t, float32(1)/128, sample.Data[1])
+	assert.True(t, sample.Loop)
+	assert.Equal(t, testSampleLength, sample.LoopEnd)
+}
+
+func TestLoadS3M_Invalid(t *testing.T) {
+	valid := newTestS3M()
+
+	testCases := []struct {
+		name string
+		data []byte
+	}{
+		{"Empty", []byte{}},
+		{"NoSignature", make([]byte, s3mHeaderLength)},
+		{"TruncatedHeader", valid[:s3mHeaderLength+1]},
+		{"TruncatedSampleHeader", valid[:testS3MSampleOffset+10]},
+		{"TruncatedSampleData", valid[:testS3MPatternOffset+4]},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			m, err := LoadS3M(testCase.data)
+			assert.Error(tt, err)
+			assert.Nil(tt, m)
+		})
+	}
+}
+
+func TestLoadS3M_Play(t *testing.T) {
+	m, err := LoadS3M(newTestS3M())
+	require.NoError(t, err)
+
+	// The song breaks after the second row at a speed of 3
+	s := NewStream(m, testSampleRate)
+	assert.Equal(t, 2*3*testTickFrames, s.Len())
+
+	samples := make([][2]float64, testTickFrames)
+	_, ok := s.Stream(samples)
+	require.True(t, ok)
+	assert.NotEqual(t, [2]float64{}, samples[testTickFrames/2])
+}
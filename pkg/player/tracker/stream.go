@@ -0,0 +1,626 @@
+package tracker
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	// MaxDuration is the longest a module plays. Modules which never end, i.e. by looping in a way that isn't detected
+	// as the end of the song, stop after it
+	MaxDuration = time.Hour
+
+	defaultSpeed = 6
+	defaultTempo = 125
+	minTempo     = 32
+
+	// mixGain is the volume of a channel playing a full-scale sample at full volume in the center
+	mixGain = 1.0
+)
+
+// Stream plays a module as stereo audio. It implements beep.StreamSeekCloser. The song ends when it reaches the end
+// of its orders or a row it already played, which is where modules loop
+type Stream struct {
+	module     *Module
+	sampleRate int
+	channels   []*channel
+
+	// order, row, and tick are the position in the song. Rows are made longer by patternDelay
+	order, row, tick int
+	speed, tempo     int
+	globalVolume     int
+	patternDelay     int
+
+	// Jumps, breaks, and loops take effect after the row. Rows which were played are visited so the song ends when
+	// it jumps back to one, unless a pattern loop is looping
+	jump         bool
+	jumpOrder    int
+	patternBreak bool
+	breakRow     int
+	loopJump     bool
+	loopRow      int
+	looping      bool
+	visited      map[int]bool
+	ended        bool
+	startOfRow   bool
+
+	// tickFrames is the number of frames in the current tick and tickPos is the number of them already played.
+	// frameError carries the fraction of a frame left over by each tick
+	tickFrames  int
+	tickPos     int
+	frameError  float64
+	position    int
+	length      int
+	maxPosition int
+}
+
+// NewStream creates a stream which plays m at sampleRate
+func NewStream(m *Module, sampleRate int) *Stream {
+	s := &Stream{module: m, sampleRate: sampleRate, maxPosition: int(MaxDuration.Seconds()) * sampleRate}
+	s.length = s.measure()
+	s.reset()
+	return s
+}
+
+// measure plays the song without mixing it to find how many frames it has
+func (s *Stream) measure() int {
+	s.reset()
+	for s.nextTick() {
+		for _, c := range s.channels {
+			c.skip(s.tickFrames)
+		}
+
+		s.position += s.tickFrames
+	}
+
+	return s.position
+}
+
+// reset moves the stream back to the start of the song
+func (s *Stream) reset() {
+	m := s.module
+	s.order, s.row, s.tick = 0, 0, 0
+	s.speed, s.tempo = m.Speed, m.Tempo
+	if s.speed < 1 {
+		s.speed = defaultSpeed
+	}
+
+	if s.tempo < minTempo {
+		s.tempo = defaultTempo
+	}
+
+	s.globalVolume = clamp(m.GlobalVolume, 0, MaxVolume)
+	s.patternDelay = 0
+	s.jump, s.patternBreak, s.loopJump, s.looping = false, false, false, false
+	s.visited = make(map[int]bool)
+	s.ended, s.startOfRow = false, true
+	s.tickFrames, s.tickPos, s.frameError, s.position = 0, 0, 0, 0
+
+	s.channels = make([]*channel, m.Channels)
+	for i := range s.channels {
+		s.channels[i] = &channel{pan: CenterPan, fade: 1, envelopeVolume: 1}
+		if i < len(m.Panning) {
+			s.channels[i].pan = clamp(m.Panning[i], 0, 255)
+		}
+	}
+}
+
+// Stream mixes the next frames of the song into samples
+func (s *Stream) Stream(samples [][2]float64) (int, bool) {
+	n := 0
+	for n < len(samples) {
+		if s.tickPos >= s.tickFrames {
+			if !s.nextTick() {
+				break
+			}
+		}
+
+		frames := len(samples) - n
+		if left := s.tickFrames - s.tickPos; frames > left {
+			frames = left
+		}
+
+		mixed := samples[n : n+frames]
+		for i := range mixed {
+			mixed[i] = [2]float64{}
+		}
+
+		for _, c := range s.channels {
+			c.mix(mixed)
+		}
+
+		for i := range mixed {
+			mixed[i][0] = math.Max(-1, math.Min(1, mixed[i][0]))
+			mixed[i][1] = math.Max(-1, math.Min(1, mixed[i][1]))
+		}
+
+		n += frames
+		s.tickPos += frames
+		s.position += frames
+	}
+
+	return n, n > 0
+}
+
+// Err always returns nil since modules are fully loaded before they're played
+func (s *Stream) Err() error {
+	return nil
+}
+
+// Len returns the number of frames in the song
+func (s *Stream) Len() int {
+	return s.length
+}
+
+// Position returns the current frame of the song
+func (s *Stream) Position() int {
+	return s.position
+}
+
+// Seek moves to frame p of the song. The song is played again from the start up to p without mixing it since the
+// state of a module at any point depends on everything played before it
+func (s *Stream) Seek(p int) error {
+	if p < s.position {
+		s.reset()
+	}
+
+	for s.position < p {
+		if s.tickPos >= s.tickFrames {
+			if !s.nextTick() {
+				return nil
+			}
+		}
+
+		frames := s.tickFrames - s.tickPos
+		if frames > p-s.position {
+			frames = p - s.position
+		}
+
+		for _, c := range s.channels {
+			c.skip(frames)
+		}
+
+		s.tickPos += frames
+		s.position += frames
+	}
+
+	return nil
+}
+
+// Close does nothing since the stream holds nothing but memory
+func (s *Stream) Close() error {
+	return nil
+}
+
+// nextTick processes the next tick of the song and returns false once the song ends
+func (s *Stream) nextTick() bool {
+	if s.ended {
+		return false
+	}
+
+	if s.startOfRow {
+		if !s.enterRow() {
+			s.ended = true
+			return false
+		}
+
+		s.startOfRow = false
+		s.playRow()
+	} else {
+		s.playTick()
+	}
+
+	for _, c := range s.channels {
+		s.updateChannel(c)
+	}
+
+	exact := float64(s.sampleRate)*2.5/float64(s.tempo) + s.frameError
+	s.tickFrames = int(exact)
+	s.frameError = exact - float64(s.tickFrames)
+	s.tickPos = 0
+
+	s.tick++
+	if s.tick >= s.speed*(s.patternDelay+1) {
+		s.nextRow()
+	}
+
+	if s.position+s.tickFrames > s.maxPosition {
+		s.ended = true
+		return false
+	}
+
+	return true
+}
+
+// enterRow moves to the row at the current position of the song, skipping orders which don't exist, and returns
+// false if the song ended
+func (s *Stream) enterRow() bool {
+	m := s.module
+	for s.order < len(m.Orders) && (m.Orders[s.order] >= len(m.Patterns) || s.row >= m.Patterns[m.Orders[s.order]].Rows) {
+		s.order, s.row = s.order+1, 0
+	}
+
+	if s.order >= len(m.Orders) {
+		return false
+	}
+
+	key := s.order<<8 | s.row
+	if s.visited[key] && !s.looping {
+		return false
+	}
+
+	s.visited[key] = true
+	return true
+}
+
+// nextRow moves to the row which follows the current row, taking jumps, breaks, and loops into account
+func (s *Stream) nextRow() {
+	s.tick, s.patternDelay, s.startOfRow = 0, 0, true
+	switch {
+	case s.loopJump:
+		s.row = s.loopRow
+	case s.jump:
+		s.order, s.row = s.jumpOrder, 0
+		if s.patternBreak {
+			s.row = s.breakRow
+		}
+	case s.patternBreak:
+		s.order, s.row = s.order+1, s.breakRow
+	default:
+		s.row++
+	}
+
+	s.jump, s.patternBreak, s.loopJump = false, false, false
+}
+
+// cells returns the cells of the current row
+func (s *Stream) cells() []Cell {
+	m := s.module
+	return m.Patterns[m.Orders[s.order]].Cells[s.row]
+}
+
+// playRow processes the first tick of a row, which plays its notes and starts its effects
+func (s *Stream) playRow() {
+	cells := s.cells()
+	for i, c := range s.channels {
+		c.periodOffset, c.volumeOffset, c.arpeggio = 0, 0, 0
+		if i >= len(cells) {
+			continue
+		}
+
+		cell := cells[i]
+		if cell.Effect != EffectNoteDelay || cell.Param == 0 {
+			s.playCell(c, cell)
+		}
+
+		s.firstTickVolumeEffect(c, cell)
+		s.firstTickEffect(c, cell)
+	}
+}
+
+// playTick processes a tick of a row other than the first, which continues its effects
+func (s *Stream) playTick() {
+	cells := s.cells()
+	tick := s.tick % s.speed
+	for i, c := range s.channels {
+		c.periodOffset, c.volumeOffset, c.arpeggio = 0, 0, 0
+		if i >= len(cells) {
+			continue
+		}
+
+		cell := cells[i]
+		if cell.Effect == EffectNoteDelay && cell.Param == tick && s.tick < s.speed {
+			s.playCell(c, cell)
+			s.firstTickVolumeEffect(c, cell)
+		}
+
+		s.tickVolumeEffect(c, cell)
+		s.tickEffect(c, cell, tick)
+	}
+}
+
+// playCell plays the note, instrument, and volume of a cell
+func (s *Stream) playCell(c *channel, cell Cell) {
+	m := s.module
+	if cell.Instrument > 0 && cell.Instrument <= len(m.Instruments) {
+		c.instrument = m.Instruments[cell.Instrument-1]
+	}
+
+	tonePorta := cell.Effect == EffectTonePorta || cell.Effect == EffectTonePortaVolumeSlide ||
+		cell.VolumeEffect == EffectTonePorta
+
+	switch {
+	case cell.Note >= 1 && cell.Note <= MaxNote && c.instrument != nil:
+		sample := c.instrument.sample(cell.Note)
+		switch {
+		case tonePorta && c.playing && c.sample != nil:
+			c.note, c.targetPeriod = cell.Note, s.notePeriod(cell.Note, c.sample)
+		case sample != nil:
+			c.trigger(sample, cell.Note, s.notePeriod(cell.Note, sample))
+			if cell.Effect == EffectSampleOffset {
+				if cell.Param != 0 {
+					c.offsetMemory = cell.Param
+				}
+
+				c.offset(c.offsetMemory * 256)
+			}
+		}
+	case cell.Note == NoteOff || cell.Note == NoteFade:
+		c.release()
+	case cell.Note == NoteCut:
+		c.playing = false
+	}
+
+	if cell.Instrument > 0 && c.sample != nil {
+		c.setVolume(c.sample.Volume)
+		if c.sample.Panning >= 0 {
+			c.pan = c.sample.Panning
+		}
+
+		if !tonePorta {
+			c.envelopeTick, c.fade, c.released = 0, 1, false
+		}
+	}
+
+	if cell.Volume != NoVolume {
+		c.setVolume(cell.Volume)
+	}
+}
+
+// notePeriod returns the period of note played by sample
+func (s *Stream) notePeriod(note int, sample *Sample) float64 {
+	if s.module.LinearSlides {
+		return linearPeriod(note)
+	}
+
+	return amigaPeriod(note, sample.C5Speed)
+}
+
+// units returns the change in period of param units of a pitch slide. Linear periods are four times finer
+func (s *Stream) units(param int) float64 {
+	if s.module.LinearSlides {
+		return float64(param) * 4
+	}
+
+	return float64(param)
+}
+
+// slide slides the period of c by units. Negative units raise the pitch
+func (s *Stream) slide(c *channel, units float64) {
+	c.period += units
+	switch {
+	case s.module.LinearSlides:
+		c.period = math.Max(linearPeriod(MaxNote), math.Min(linearPeriod(1), c.period))
+	case s.module.AmigaLimits:
+		c.period = math.Max(113, math.Min(856, c.period))
+	default:
+		c.period = math.Max(1, c.period)
+	}
+}
+
+// firstTickEffect starts the effect of a cell on the first tick of a row
+func (s *Stream) firstTickEffect(c *channel, cell Cell) {
+	param := cell.Param
+	switch cell.Effect {
+	case EffectArpeggio:
+		if param != 0 {
+			c.arpeggioMemory = param
+		}
+	case EffectPortaUp, EffectPortaDown:
+		if param != 0 {
+			c.portaMemory = param
+		}
+	case EffectFinePortaUp, EffectFinePortaDown, EffectExtraFinePortaUp, EffectExtraFinePortaDown:
+		if param != 0 {
+			c.finePortaMemory = param
+		}
+
+		units := s.units(c.finePortaMemory)
+		if cell.Effect == EffectExtraFinePortaUp || cell.Effect == EffectExtraFinePortaDown {
+			units /= 4
+		}
+
+		if cell.Effect == EffectFinePortaUp || cell.Effect == EffectExtraFinePortaUp {
+			units = -units
+		}
+
+		s.slide(c, units)
+	case EffectTonePorta:
+		if param != 0 {
+			c.tonePortaSpeed = param
+		}
+	case EffectVibrato:
+		c.setVibrato(param)
+	case EffectTonePortaVolumeSlide, EffectVibratoVolumeSlide, EffectVolumeSlide:
+		if param != 0 {
+			c.volumeSlideMemory = param
+		}
+	case EffectTremolo:
+		c.setTremolo(param)
+	case EffectSetPanning:
+		c.pan = clamp(param, 0, 255)
+	case EffectFineVolumeUp, EffectFineVolumeDown:
+		if param != 0 {
+			c.fineVolumeMemory = param
+		}
+
+		if cell.Effect == EffectFineVolumeUp {
+			c.setVolume(c.volume + c.fineVolumeMemory)
+		} else {
+			c.setVolume(c.volume - c.fineVolumeMemory)
+		}
+	case EffectSetVolume:
+		c.setVolume(param)
+	case EffectPositionJump:
+		s.jump, s.jumpOrder = true, param
+	case EffectPatternBreak:
+		s.patternBreak, s.breakRow = true, param
+	case EffectSetSpeed:
+		if param > 0 {
+			s.speed = param
+		}
+	case EffectSetTempo:
+		if param >= minTempo {
+			s.tempo = param
+		}
+	case EffectRetrigger:
+		if param != 0 {
+			c.retriggerMemory = param
+		}
+	case EffectNoteCut:
+		if param == 0 {
+			c.setVolume(0)
+		}
+	case EffectKeyOff:
+		if param == 0 {
+			c.release()
+		}
+	case EffectPatternLoop:
+		s.patternLoop(c, param)
+	case EffectPatternDelay:
+		s.patternDelay = param
+	case EffectSetGlobalVolume:
+		s.globalVolume = clamp(param, 0, MaxVolume)
+	case EffectGlobalVolumeSlide:
+		if param != 0 {
+			c.globalSlideMemory = param
+		}
+	}
+}
+
+// patternLoop marks the start of a loop or loops back to it until it has looped param times
+func (s *Stream) patternLoop(c *channel, param int) {
+	if param == 0 {
+		c.loopRow = s.row
+		return
+	}
+
+	if c.loopCount == 0 {
+		c.loopCount = param
+	} else {
+		c.loopCount--
+	}
+
+	if c.loopCount > 0 {
+		s.loopJump, s.loopRow, s.looping = true, c.loopRow, true
+	} else {
+		s.looping = false
+	}
+}
+
+// tickEffect continues the effect of a cell on a tick other than the first of a row
+func (s *Stream) tickEffect(c *channel, cell Cell, tick int) {
+	switch cell.Effect {
+	case EffectArpeggio:
+		switch tick % 3 {
+		case 1:
+			c.arpeggio = c.arpeggioMemory >> 4
+		case 2:
+			c.arpeggio = c.arpeggioMemory & 0xF
+		}
+	case EffectPortaUp:
+		s.slide(c, -s.units(c.portaMemory))
+	case EffectPortaDown:
+		s.slide(c, s.units(c.portaMemory))
+	case EffectTonePorta:
+		c.tonePorta(s.units(c.tonePortaSpeed))
+	case EffectTonePortaVolumeSlide:
+		c.tonePorta(s.units(c.tonePortaSpeed))
+		c.slideVolume(c.volumeSlideMemory)
+	case EffectVibrato:
+		c.vibrato(s.units(1))
+	case EffectVibratoVolumeSlide:
+		c.vibrato(s.units(1))
+		c.slideVolume(c.volumeSlideMemory)
+	case EffectVolumeSlide:
+		c.slideVolume(c.volumeSlideMemory)
+	case EffectTremolo:
+		c.tremolo()
+	case EffectRetrigger:
+		if c.retriggerMemory > 0 && tick%c.retriggerMemory == 0 {
+			c.retrigger()
+		}
+	case EffectNoteCut:
+		if tick == cell.Param {
+			c.setVolume(0)
+		}
+	case EffectKeyOff:
+		if tick == cell.Param {
+			c.release()
+		}
+	case EffectGlobalVolumeSlide:
+		if up := c.globalSlideMemory >> 4; up != 0 {
+			s.globalVolume = clamp(s.globalVolume+up, 0, MaxVolume)
+		} else {
+			s.globalVolume = clamp(s.globalVolume-c.globalSlideMemory&0xF, 0, MaxVolume)
+		}
+	}
+}
+
+// firstTickVolumeEffect starts the effect of the volume column of a cell on the first tick of a row. Effects of the
+// volume column don't share the memory of the effect column
+func (s *Stream) firstTickVolumeEffect(c *channel, cell Cell) {
+	param := cell.VolumeParam
+	switch cell.VolumeEffect {
+	case EffectFineVolumeUp:
+		c.setVolume(c.volume + param)
+	case EffectFineVolumeDown:
+		c.setVolume(c.volume - param)
+	case EffectSetPanning:
+		c.pan = clamp(param, 0, 255)
+	case EffectTonePorta:
+		if param != 0 {
+			c.tonePortaSpeed = param
+		}
+	case EffectVibrato:
+		c.setVibrato(param)
+	}
+}
+
+// tickVolumeEffect continues the effect of the volume column of a cell on a tick other than the first of a row.
+// Effects which are also in the effect column are only applied once
+func (s *Stream) tickVolumeEffect(c *channel, cell Cell) {
+	param := cell.VolumeParam
+	switch cell.VolumeEffect {
+	case EffectVolumeSlide:
+		c.slideVolume(param)
+	case EffectPortaUp:
+		s.slide(c, -s.units(param))
+	case EffectPortaDown:
+		s.slide(c, s.units(param))
+	case EffectTonePorta:
+		if cell.Effect != EffectTonePorta && cell.Effect != EffectTonePortaVolumeSlide {
+			c.tonePorta(s.units(c.tonePortaSpeed))
+		}
+	case EffectVibrato:
+		if cell.Effect != EffectVibrato && cell.Effect != EffectVibratoVolumeSlide {
+			c.vibrato(s.units(1))
+		}
+	}
+}
+
+// updateChannel updates the envelope of c and the pitch and volume it's mixed at for the tick
+func (s *Stream) updateChannel(c *channel) {
+	c.updateEnvelope()
+	if !c.playing || c.sample == nil {
+		c.step, c.left, c.right = 0, 0, 0
+		return
+	}
+
+	period := c.period + c.periodOffset
+	var frequency float64
+	if s.module.LinearSlides {
+		period -= float64(c.arpeggio) * 64
+		frequency = c.sample.C5Speed * math.Pow(2, (linearC5Period-period)/768)
+	} else {
+		period *= math.Pow(2, -float64(c.arpeggio)/12)
+		frequency = amigaC5Speed * amigaC5Period / math.Max(1, period)
+	}
+
+	c.step = frequency / float64(s.sampleRate)
+
+	volume := float64(clamp(c.volume+c.volumeOffset, 0, MaxVolume)) / MaxVolume
+	volume *= c.envelopeVolume * c.fade * c.sample.GlobalVolume * float64(s.globalVolume) / MaxVolume * mixGain
+	c.left = volume * float64(255-c.pan) / 255
+	c.right = volume * float64(c.pan) / 255
+}
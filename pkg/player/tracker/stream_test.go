@@ -0,0 +1,166 @@
+package tracker
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+const (
+	testSampleRate = 44100
+
+	// testTickFrames is the number of frames in a tick at the default tempo
+	testTickFrames = testSampleRate * 5 / 2 / defaultTempo
+
+	// testRowFrames is the number of frames in a row at the default speed and tempo
+	testRowFrames = testTickFrames * defaultSpeed
+)
+
+// newTestStream creates a stream playing a test MOD with cells
+func newTestStream(t *testing.T, cells ...modCell) *Stream {
+	m, err := LoadMOD(newTestMOD(cells...))
+	require.NoError(t, err)
+	return NewStream(m, testSampleRate)
+}
+
+func TestStream_Len(t *testing.T) {
+	testCases := []struct {
+		name     string
+		cells    []modCell
+		expected int
+	}{
+		{"Pattern", nil, modRows * testRowFrames},
+		{"PatternBreak", []modCell{{row: 3, effect: 0xD}}, 4 * testRowFrames},
+		{"PositionJump", []modCell{{row: 7, effect: 0xB}}, 8 * testRowFrames},
+		{"PatternBreakPastEnd", []modCell{{row: 3, effect: 0xD, param: 0x99}}, 4 * testRowFrames},
+		{"Speed", []modCell{{row: 0, effect: 0xF, param: 3}}, modRows * testRowFrames / 2},
+		{"Tempo", []modCell{{row: 0, effect: 0xF, param: defaultTempo * 2}}, modRows * testRowFrames / 2},
+		{"PatternDelay", []modCell{{row: 0, effect: 0xE, param: 0xE2}}, (modRows + 2) * testRowFrames},
+		{"PatternLoop", []modCell{{row: 1, effect: 0xE, param: 0x60}, {row: 2, effect: 0xE, param: 0x62}}, (modRows + 4) * testRowFrames},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			s := newTestStream(tt, testCase.cells...)
+			assert.Equal(tt, testCase.expected, s.Len())
+			assert.Zero(tt, s.Position())
+			assert.NoError(tt, s.Err())
+		})
+	}
+}
+
+func TestStream_Stream(t *testing.T) {
+	s := newTestStream(t, modCell{row: 0, channel: 0, period: testC5Period, sample: 1})
+
+	// The note plays on the left channel, which is panned partially left
+	samples := make([][2]float64, testRowFrames)
+	n, ok := s.Stream(samples)
+	require.True(t, ok)
+	assert.Equal(t, len(samples), n)
+	assert.Equal(t, n, s.Position())
+
+	var left, right float64
+	for _, sample := range samples {
+		assert.True(t, sample[0] >= -1 && sample[0] <= 1)
+		left += sample[0] * sample[0]
+		right += sample[1] * sample[1]
+	}
+
+	assert.True(t, left > 0)
+	assert.True(t, left > right)
+
+	// The stream stops at the end of the song
+	require.NoError(t, s.Seek(s.Len()-10))
+	n, ok = s.Stream(samples)
+	assert.True(t, ok)
+	assert.Equal(t, 10, n)
+
+	n, ok = s.Stream(samples)
+	assert.False(t, ok)
+	assert.Zero(t, n)
+	assert.NoError(t, s.Close())
+}
+
+func TestStream_Silent(t *testing.T) {
+	testCases := []struct {
+		name  string
+		cells []modCell
+	}{
+		{"NoNotes", nil},
+		{"NoteCut", []modCell{{row: 0, period: testC5Period, sample: 1, effect: 0xE, param: 0xC0}}},
+		{"Volume", []modCell{{row: 0, period: testC5Period, sample: 1, effect: 0xC, param: 0}}},
+		{"MissingSample", []modCell{{row: 0, period: testC5Period, sample: 2}}},
+		{"NoteDelay", []modCell{{row: 0, period: testC5Period, sample: 1, effect: 0xE, param: 0xD8}}},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			s := newTestStream(tt, testCase.cells...)
+			samples := make([][2]float64, testRowFrames)
+			_, ok := s.Stream(samples)
+			require.True(tt, ok)
+
+			for _, sample := range samples {
+				assert.Equal(tt, [2]float64{}, sample)
+			}
+		})
+	}
+}
+
+func TestStream_Seek(t *testing.T) {
+	cells := []modCell{
+		{row: 0, channel: 0, period: testC5Period, sample: 1, effect: 0x4, param: 0x48},
+		{row: 2, channel: 1, period: 214, sample: 1, effect: 0xA, param: 0x01},
+		{row: 5, channel: 2, period: 320, sample: 1, effect: 0x3, param: 0x10},
+	}
+
+	testCases := []struct {
+		name     string
+		position int
+	}{
+		{"Start", 0},
+		{"MiddleOfTick", testTickFrames / 2},
+		{"LaterRow", 5*testRowFrames + 100},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			// Seeking must leave the stream in the same state as playing up to the position
+			played := newTestStream(tt, cells...)
+			played.Stream(make([][2]float64, testCase.position))
+
+			seeked := newTestStream(tt, cells...)
+
+			// Seeking backwards plays the song again from the start
+			seeked.Stream(make([][2]float64, 6*testRowFrames))
+			require.NoError(tt, seeked.Seek(testCase.position))
+			assert.Equal(tt, testCase.position, seeked.Position())
+
+			expected := make([][2]float64, 2*testRowFrames)
+			actual := make([][2]float64, 2*testRowFrames)
+			played.Stream(expected)
+			seeked.Stream(actual)
+			for i := range expected {
+				assert.InDelta(tt, expected[i][0], actual[i][0], 1e-6)
+				assert.InDelta(tt, expected[i][1], actual[i][1], 1e-6)
+			}
+		})
+	}
+}
+
+func TestStream_SeekPastEnd(t *testing.T) {
+	s := newTestStream(t)
+	require.NoError(t, s.Seek(s.Len()+testRowFrames))
+	assert.Equal(t, s.Len(), s.Position())
+
+	_, ok := s.Stream(make([][2]float64, 1))
+	assert.False(t, ok)
+}
+
+func TestStream_EmptyModule(t *testing.T) {
+	s := NewStream(&Module{Channels: 4}, testSampleRate)
+	assert.Zero(t, s.Len())
+
+	_, ok := s.Stream(make([][2]float64, 1))
+	assert.False(t, ok)
+}
@@ -0,0 +1,319 @@
+package tracker
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+const (
+	xmSignature       = "Extended Module: "
+	xmHeaderOffset    = 60
+	xmOrdersLength    = 256
+	xmSampleLength    = 40
+	xmEnvelopePoints  = 12
+	xmKeyOff          = 97
+	xmNotes           = 96
+	xmMaxChannels     = 64
+	xmMaxInstruments  = 128
+	xmEnvelopeOn      = 0x1
+	xmEnvelopeSustain = 0x2
+	xmEnvelopeLoop    = 0x4
+)
+
+// LoadXM loads a FastTracker 2 extended module
+func LoadXM(data []byte) (*Module, error) {
+	if len(data) < xmHeaderOffset+20 {
+		return nil, ErrTruncated
+	}
+
+	if string(data[:len(xmSignature)]) != xmSignature {
+		return nil, ErrUnknownFormat
+	}
+
+	header := data[xmHeaderOffset:]
+	headerLength := int(binary.LittleEndian.Uint32(header))
+	length := int(binary.LittleEndian.Uint16(header[4:]))
+	channels := int(binary.LittleEndian.Uint16(header[8:]))
+	patterns := int(binary.LittleEndian.Uint16(header[10:]))
+	instruments := int(binary.LittleEndian.Uint16(header[12:]))
+	if length > xmOrdersLength || channels < 1 || channels > xmMaxChannels || instruments > xmMaxInstruments {
+		return nil, fmt.Errorf("invalid header")
+	}
+
+	if len(data) < xmHeaderOffset+20+length {
+		return nil, ErrTruncated
+	}
+
+	m := &Module{
+		Title:        text(data[17:37]),
+		Format:       "xm",
+		Channels:     channels,
+		Speed:        int(binary.LittleEndian.Uint16(header[16:])),
+		Tempo:        int(binary.LittleEndian.Uint16(header[18:])),
+		GlobalVolume: MaxVolume,
+		LinearSlides: binary.LittleEndian.Uint16(header[14:])&0x1 != 0,
+	}
+
+	for i := 0; i < channels; i++ {
+		m.Panning = append(m.Panning, CenterPan)
+	}
+
+	for i := 0; i < length; i++ {
+		if order := int(header[20+i]); order < patterns {
+			m.Orders = append(m.Orders, order)
+		}
+	}
+
+	offset := xmHeaderOffset + headerLength
+	for i := 0; i < patterns; i++ {
+		pattern, next, err := loadXMPattern(data, offset, channels)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load pattern %d: %w", i, err)
+		}
+
+		m.Patterns = append(m.Patterns, pattern)
+		offset = next
+	}
+
+	for i := 0; i < instruments; i++ {
+		instrument, next, err := loadXMInstrument(data, offset)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load instrument %d: %w", i+1, err)
+		}
+
+		m.Instruments = append(m.Instruments, instrument)
+		offset = next
+	}
+
+	return m, nil
+}
+
+// loadXMPattern loads the pattern at offset and returns the offset following it. Cells are packed by starting with a
+// byte with the high bit set which tells which fields follow. Otherwise all five fields follow
+func loadXMPattern(data []byte, offset, channels int) (*Pattern, int, error) {
+	if len(data) < offset+9 {
+		return nil, 0, ErrTruncated
+	}
+
+	headerLength := int(binary.LittleEndian.Uint32(data[offset:]))
+	rows := int(binary.LittleEndian.Uint16(data[offset+5:]))
+	size := int(binary.LittleEndian.Uint16(data[offset+7:]))
+	if rows < 1 || rows > 256 {
+		return nil, 0, fmt.Errorf("invalid number of rows %d", rows)
+	}
+
+	start := offset + headerLength
+	end := start + size
+	if len(data) < end {
+		return nil, 0, ErrTruncated
+	}
+
+	p := newPattern(rows, channels)
+	if size == 0 {
+		return p, end, nil
+	}
+
+	r := &byteReader{data: data[:end], offset: start}
+	for row := 0; row < rows; row++ {
+		for channel := 0; channel < channels; channel++ {
+			var fields [5]byte
+			b, err := r.byte()
+			if err != nil {
+				return nil, 0, err
+			}
+
+			mask := byte(0x1F)
+			if b&0x80 != 0 {
+				mask = b
+			} else {
+				fields[0], mask = b, 0x1E
+			}
+
+			for i := range fields {
+				if mask&(1<<uint(i)) == 0 {
+					continue
+				}
+
+				if fields[i], err = r.byte(); err != nil {
+					return nil, 0, err
+				}
+			}
+
+			p.Cells[row][channel] = xmCell(fields)
+		}
+	}
+
+	return p, end, nil
+}
+
+// xmCell converts the note, instrument, volume, effect, and parameter of a cell
+func xmCell(fields [5]byte) Cell {
+	cell := Cell{Instrument: int(fields[1]), Volume: NoVolume}
+	switch note := int(fields[0]); {
+	case note == xmKeyOff:
+		cell.Note = NoteOff
+	case note > 0 && note <= xmNotes:
+		// FastTracker 2 plays samples at their C5Speed on C-4
+		cell.Note = note + 12
+	}
+
+	volume, low := int(fields[2]), int(fields[2]&0xF)
+	switch volume >> 4 {
+	case 0x1, 0x2, 0x3, 0x4:
+		cell.Volume = volume - 0x10
+	case 0x5:
+		if volume == 0x50 {
+			cell.Volume = MaxVolume
+		}
+	case 0x6:
+		cell.VolumeEffect, cell.VolumeParam = EffectVolumeSlide, low
+	case 0x7:
+		cell.VolumeEffect, cell.VolumeParam = EffectVolumeSlide, low<<4
+	case 0x8:
+		cell.VolumeEffect, cell.VolumeParam = EffectFineVolumeDown, low
+	case 0x9:
+		cell.VolumeEffect, cell.VolumeParam = EffectFineVolumeUp, low
+	case 0xB:
+		cell.VolumeEffect, cell.VolumeParam = EffectVibrato, low
+	case 0xC:
+		cell.VolumeEffect, cell.VolumeParam = EffectSetPanning, low*17
+	case 0xF:
+		cell.VolumeEffect, cell.VolumeParam = EffectTonePorta, low<<4
+	}
+
+	cell.Effect, cell.Param = protrackerEffect(int(fields[3]), int(fields[4]))
+
+	// FastTracker 2 pans from 0 to 255 unlike other trackers
+	if cell.Effect == EffectSetPanning && fields[3] == 0x8 {
+		cell.Param = int(fields[4])
+	}
+
+	return cell
+}
+
+// loadXMInstrument loads the instrument at offset and its samples and returns the offset following them
+func loadXMInstrument(data []byte, offset int) (*Instrument, int, error) {
+	if len(data) < offset+29 {
+		return nil, 0, ErrTruncated
+	}
+
+	headerLength := int(binary.LittleEndian.Uint32(data[offset:]))
+	samples := int(binary.LittleEndian.Uint16(data[offset+27:]))
+	instrument := &Instrument{Name: text(data[offset+4 : offset+26])}
+
+	next := offset + headerLength
+	if samples == 0 {
+		return instrument, next, nil
+	}
+
+	// The sample header length and instrument settings follow the number of samples
+	const settingsLength = 4 + xmNotes + xmEnvelopePoints*4*2 + 14 + 4 + 2
+	if len(data) < offset+29+settingsLength || headerLength < 29+settingsLength {
+		return nil, 0, ErrTruncated
+	}
+
+	settings := data[offset+29:]
+	sampleHeaderLength := int(binary.LittleEndian.Uint32(settings))
+	keymap := settings[4 : 4+xmNotes]
+	envelope := settings[4+xmNotes:]
+	points := int(envelope[xmEnvelopePoints*4*2])
+	sustain := int(envelope[xmEnvelopePoints*4*2+2])
+	loopStart := int(envelope[xmEnvelopePoints*4*2+3])
+	loopEnd := int(envelope[xmEnvelopePoints*4*2+4])
+	flags := envelope[xmEnvelopePoints*4*2+8]
+	instrument.Fadeout = float64(binary.LittleEndian.Uint16(envelope[xmEnvelopePoints*4*2+14:])) / 32768
+
+	if flags&xmEnvelopeOn != 0 && points <= xmEnvelopePoints {
+		e := &Envelope{
+			Sustain:      flags&xmEnvelopeSustain != 0,
+			SustainStart: sustain,
+			SustainEnd:   sustain,
+			Loop:         flags&xmEnvelopeLoop != 0,
+			LoopStart:    loopStart,
+			LoopEnd:      loopEnd,
+		}
+
+		for i := 0; i < points; i++ {
+			e.Points = append(e.Points, EnvelopePoint{
+				Tick:  int(binary.LittleEndian.Uint16(envelope[i*4:])),
+				Value: float64(clamp(int(binary.LittleEndian.Uint16(envelope[i*4+2:])), 0, MaxVolume)) / MaxVolume,
+			})
+		}
+
+		if e.valid() {
+			instrument.Envelope = e
+		}
+	}
+
+	// Sample headers are all stored before the data of the samples
+	headers := make([][]byte, samples)
+	for i := range headers {
+		start := next + i*sampleHeaderLength
+		if len(data) < start+xmSampleLength {
+			return nil, 0, ErrTruncated
+		}
+
+		headers[i] = data[start : start+xmSampleLength]
+	}
+
+	next += samples * sampleHeaderLength
+	for _, header := range headers {
+		sample, length, err := loadXMSample(data, next, header)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		instrument.Samples = append(instrument.Samples, sample)
+		next += length
+	}
+
+	for note := 1; note <= xmNotes; note++ {
+		instrument.Keymap[note+12] = int(keymap[note-1]) + 1
+	}
+
+	return instrument, next, nil
+}
+
+// loadXMSample loads the delta-encoded data of a sample at offset and returns the number of bytes it used
+func loadXMSample(data []byte, offset int, header []byte) (*Sample, int, error) {
+	sample := newSample(text(header[18:40]))
+	length := int(binary.LittleEndian.Uint32(header))
+	loopStart := int(binary.LittleEndian.Uint32(header[4:]))
+	loopLength := int(binary.LittleEndian.Uint32(header[8:]))
+	sample.Volume = clamp(int(header[12]), 0, MaxVolume)
+	sample.Panning = int(header[15])
+
+	// FastTracker 2 tunes samples with a relative note and a finetune of 1/128th of a semitone
+	relative := float64(int8(header[16])) + float64(int8(header[13]))/128
+	sample.C5Speed = amigaC5Speed * math.Pow(2, relative/12)
+
+	flags := header[14]
+	if len(data) < offset+length {
+		return nil, 0, ErrTruncated
+	}
+
+	if flags&0x10 != 0 {
+		var v int16
+		sample.Data = make([]float32, length/2)
+		for i := range sample.Data {
+			v += int16(binary.LittleEndian.Uint16(data[offset+i*2:]))
+			sample.Data[i] = float32(v) / 32768
+		}
+
+		loopStart, loopLength = loopStart/2, loopLength/2
+	} else {
+		var v int8
+		sample.Data = make([]float32, length)
+		for i := range sample.Data {
+			v += int8(data[offset+i])
+			sample.Data[i] = float32(v) / 128
+		}
+	}
+
+	if loop := flags & 0x3; loop != 0 {
+		sample.setLoop(loopStart, loopStart+loopLength, loop == 2)
+	}
+
+	return sample, length, nil
+}
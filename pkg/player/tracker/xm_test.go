@@ -0,0 +1,194 @@
+package tracker
+
+import (
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+const (
+	testXMHeaderLength     = 276
+	testXMInstrumentLength = 263
+	testXMFadeout          = 0x800
+)
+
+// newTestXM creates an XM with linear slides, two channels, an instrument with a volume envelope and a looping
+// sample, and one pattern. The first row plays C-4 on the first channel at half volume and sets the speed, the second
+// row breaks to row 10 and releases the second channel
+func newTestXM() []byte {
+	data := make([]byte, xmHeaderOffset+testXMHeaderLength)
+	copy(data, xmSignature)
+	copy(data[17:], testTitle)
+	data[37] = 0x1A
+	binary.LittleEndian.PutUint16(data[58:], 0x0104)
+
+	header := data[xmHeaderOffset:]
+	binary.LittleEndian.PutUint32(header, testXMHeaderLength)
+	binary.LittleEndian.PutUint16(header[4:], 1)
+	binary.LittleEndian.PutUint16(header[8:], 2)
+	binary.LittleEndian.PutUint16(header[10:], 1)
+	binary.LittleEndian.PutUint16(header[12:], 1)
+	binary.LittleEndian.PutUint16(header[14:], 1)
+	binary.LittleEndian.PutUint16(header[16:], 6)
+	binary.LittleEndian.PutUint16(header[18:], 125)
+
+	cells := []byte{
+		49, 1, 0x10 + 32, 0xF, 3, 0x80,
+		0x80 | 0x08 | 0x10, 0xD, 0x10, 0x80 | 0x01, xmKeyOff,
+	}
+
+	for row := 2; row < 64; row++ {
+		cells = append(cells, 0x80, 0x80)
+	}
+
+	pattern := make([]byte, 9)
+	binary.LittleEndian.PutUint32(pattern, 9)
+	binary.LittleEndian.PutUint16(pattern[5:], 64)
+	binary.LittleEndian.PutUint16(pattern[7:], uint16(len(cells)))
+	data = append(append(data, pattern...), cells...)
+
+	instrument := make([]byte, testXMInstrumentLength)
+	binary.LittleEndian.PutUint32(instrument, testXMInstrumentLength)
+	copy(instrument[4:], testSampleName)
+	binary.LittleEndian.PutUint16(instrument[27:], 1)
+	binary.LittleEndian.PutUint32(instrument[29:], xmSampleLength)
+
+	envelope := instrument[29+4+xmNotes:]
+	for i, point := range [][2]int{{0, 64}, {10, 32}, {20, 0}} {
+		binary.LittleEndian.PutUint16(envelope[i*4:], uint16(point[0]))
+		binary.LittleEndian.PutUint16(envelope[i*4+2:], uint16(point[1]))
+	}
+
+	settings := envelope[xmEnvelopePoints*4*2:]
+	settings[0], settings[2], settings[8] = 3, 1, xmEnvelopeOn|xmEnvelopeSustain
+	binary.LittleEndian.PutUint16(settings[14:], testXMFadeout)
+	data = append(data, instrument...)
+
+	sample := make([]byte, xmSampleLength)
+	binary.LittleEndian.PutUint32(sample, testSampleLength)
+	binary.LittleEndian.PutUint32(sample[8:], testSampleLength)
+	sample[12], sample[14], sample[15] = 48, 0x1, CenterPan
+	copy(sample[18:], testSampleName)
+	data = append(data, sample...)
+
+	// Sample data is stored as the difference from the previous frame
+	for i := 0; i < testSampleLength; i++ {
+		switch i {
+		case 0:
+			data = append(data, 0x40)
+		case testSampleLength / 2:
+			data = append(data, 0x80)
+		default:
+			data = append(data, 0)
+		}
+	}
+
+	return data
+}
+
+func TestLoadXM(t *testing.T) {
+	m, err := LoadXM(newTestXM())
+	require.NoError(t, err)
+	assert.Equal(t, testTitle, m.Title)
+	assert.Equal(t, "xm", m.Format)
+	assert.Equal(t, 2, m.Channels)
+	assert.Equal(t, []int{0}, m.Orders)
+	assert.Equal(t, 6, m.Speed)
+	assert.Equal(t, 125, m.Tempo)
+	assert.True(t, m.LinearSlides)
+	require.Len(t, m.Patterns, 1)
+	require.Len(t, m.Instruments, 1)
+
+	cells := m.Patterns[0].Cells
+	assert.Equal(t, Cell{Note: MiddleC, Instrument: 1, Volume: 32, Effect: EffectSetSpeed, Param: 3}, cells[0][0])
+	assert.Equal(t, Cell{Volume: NoVolume}, cells[0][1])
+	assert.Equal(t, Cell{Volume: NoVolume, Effect: EffectPatternBreak, Param: 10}, cells[1][0])
+	assert.Equal(t, Cell{Note: NoteOff, Volume: NoVolume}, cells[1][1])
+
+	instrument := m.Instruments[0]
+	assert.Equal(t, testSampleName, instrument.Name)
+	assert.Equal(t, float64(testXMFadeout)/32768, instrument.Fadeout)
+	require.NotNil(t, instrument.Envelope)
+	assert.Equal(t, []EnvelopePoint{{0, 1}, {10, 0.5}, {20, 0}}, instrument.Envelope.Points)
+	assert.True(t, instrument.Envelope.Sustain)
+	assert.Equal(t, 1, instrument.Envelope.SustainStart)
+	assert.False(t, instrument.Envelope.Loop)
+
+	sample := instrument.sample(MiddleC)
+	require.NotNil(t, sample)
+	assert.Nil(t, instrument.sample(1))
+	assert.Equal(t, 48, sample.Volume)
+	assert.Equal(t, CenterPan, sample.Panning)
+	assert.Equal(t, float64(amigaC5Speed), sample.C5Speed)
+	require.Len(t, sample.Data, testSampleLength)
+	assert.Equal(t, float32(0.5), sample.Data[1])
+	assert.Equal(t, float32(-0.5), sample.Data[testSampleLength-1])
+	assert.True(t, sample.Loop)
+	assert.False(t, sample.PingPong)
+}
+
+func TestXMCell(t *testing.T) {
+	testCases := []struct {
+		name     string
+		fields   [5]byte
+		expected Cell
+	}{
+		{"Empty", [5]byte{}, Cell{Volume: NoVolume}},
+		{"MaxVolume", [5]byte{0, 0, 0x50, 0, 0}, Cell{Volume: MaxVolume}},
+		{"VolumeSlideDown", [5]byte{0, 0, 0x63, 0, 0}, Cell{Volume: NoVolume, VolumeEffect: EffectVolumeSlide, VolumeParam: 0x03}},
+		{"VolumeSlideUp", [5]byte{0, 0, 0x73, 0, 0}, Cell{Volume: NoVolume, VolumeEffect: EffectVolumeSlide, VolumeParam: 0x30}},
+		{"Panning", [5]byte{0, 0, 0xCF, 0, 0}, Cell{Volume: NoVolume, VolumeEffect: EffectSetPanning, VolumeParam: 255}},
+		{"TonePorta", [5]byte{0, 0, 0xF2, 0, 0}, Cell{Volume: NoVolume, VolumeEffect: EffectTonePorta, VolumeParam: 0x20}},
+		{"SetPanning", [5]byte{0, 0, 0, 0x8, 0x40}, Cell{Volume: NoVolume, Effect: EffectSetPanning, Param: 0x40}},
+		{"GlobalVolume", [5]byte{0, 0, 0, 0x10, 0x20}, Cell{Volume: NoVolume, Effect: EffectSetGlobalVolume, Param: 0x20}},
+		{"ExtraFinePorta", [5]byte{0, 0, 0, 0x21, 0x13}, Cell{Volume: NoVolume, Effect: EffectExtraFinePortaUp, Param: 3}},
+		{"NoteTooHigh", [5]byte{xmKeyOff + 1, 0, 0, 0, 0}, Cell{Volume: NoVolume}},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			assert.Equal(tt, testCase.expected, xmCell(testCase.fields))
+		})
+	}
+}
+
+func TestLoadXM_Invalid(t *testing.T) {
+	valid := newTestXM()
+	tooManyChannels := append([]byte{}, valid...)
+	binary.LittleEndian.PutUint16(tooManyChannels[xmHeaderOffset+8:], xmMaxChannels+1)
+
+	testCases := []struct {
+		name string
+		data []byte
+	}{
+		{"Empty", []byte{}},
+		{"NoSignature", make([]byte, xmHeaderOffset+testXMHeaderLength)},
+		{"TooManyChannels", tooManyChannels},
+		{"TruncatedPattern", valid[:xmHeaderOffset+testXMHeaderLength+12]},
+		{"TruncatedInstrument", valid[:len(valid)-testSampleLength-xmSampleLength-10]},
+		{"TruncatedSample", valid[:len(valid)-1]},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			m, err := LoadXM(testCase.data)
+			assert.Error(tt, err)
+			assert.Nil(tt, m)
+		})
+	}
+}
+
+func TestLoadXM_Play(t *testing.T) {
+	m, err := LoadXM(newTestXM())
+	require.NoError(t, err)
+
+	// The song breaks after the second row at a speed of 3
+	s := NewStream(m, testSampleRate)
+	assert.Equal(t, 2*3*testTickFrames, s.Len())
+
+	samples := make([][2]float64, testTickFrames)
+	_, ok := s.Stream(samples)
+	require.True(t, ok)
+	assert.NotEqual(t, [2]float64{}, samples[testTickFrames/2])
+}
@@ -0,0 +1,50 @@
+package player
+
+import (
+	"github.com/faiface/beep"
+	"github.com/faiface/beep/effects"
+	"math"
+)
+
+const (
+	// MinVolume is the lowest volume, which silences playback
+	MinVolume = 0
+
+	// MaxVolume is the highest volume, which plays tracks at their original level
+	MaxVolume = 100
+
+	// DefaultVolume is the volume tracks are played at unless another volume is set
+	DefaultVolume = MaxVolume
+
+	// VolumeStep is how much the volume changes when it is turned up or down by a step
+	VolumeStep = 5
+)
+
+// ClampVolume limits volume to the range between MinVolume and MaxVolume
+func ClampVolume(volume int) int {
+	if volume < MinVolume {
+		return MinVolume
+	}
+
+	if volume > MaxVolume {
+		return MaxVolume
+	}
+
+	return volume
+}
+
+// newVolume returns a streamer which plays streamer at volume
+func newVolume(streamer beep.Streamer, volume int) *effects.Volume {
+	v := &effects.Volume{Streamer: streamer, Base: 2}
+	setVolume(v, volume)
+	return v
+}
+
+// setVolume sets the volume of v. Loudness is perceived logarithmically, so the amplitude is the square of the volume
+// to make each step sound like a similar change
+func setVolume(v *effects.Volume, volume int) {
+	v.Silent = volume <= MinVolume
+	if !v.Silent {
+		v.Volume = 2 * math.Log2(float64(volume)/MaxVolume)
+	}
+}
@@ -0,0 +1,59 @@
+package player
+
+import (
+	"github.com/faiface/beep"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestClampVolume(t *testing.T) {
+	testCases := []struct {
+		name     string
+		volume   int
+		expected int
+	}{
+		{"InRange", 40, 40},
+		{"TooLow", -5, MinVolume},
+		{"TooHigh", 105, MaxVolume},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			assert.Equal(tt, testCase.expected, ClampVolume(testCase.volume))
+		})
+	}
+}
+
+func TestVolume(t *testing.T) {
+	testCases := []struct {
+		name     string
+		volume   int
+		expected float64
+	}{
+		{"Max", MaxVolume, 1},
+		{"Half", 50, 0.25},
+		{"Silent", MinVolume, 0},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			streamer := newVolume(beep.StreamerFunc(func(samples [][2]float64) (int, bool) {
+				for i := range samples {
+					samples[i] = [2]float64{1, -1}
+				}
+
+				return len(samples), true
+			}), testCase.volume)
+
+			samples := make([][2]float64, 4)
+			n, ok := streamer.Stream(samples)
+			assert.True(tt, ok)
+			assert.Equal(tt, len(samples), n)
+
+			for _, sample := range samples {
+				assert.InDelta(tt, testCase.expected, sample[0], 1e-9)
+				assert.InDelta(tt, -testCase.expected, sample[1], 1e-9)
+			}
+		})
+	}
+}
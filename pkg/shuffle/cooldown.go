@@ -0,0 +1,131 @@
+package shuffle
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrInvalidCooldown is an error returned when attempting to create a Cooldown with a negative window
+var ErrInvalidCooldown = errors.New("cooldown cannot be negative")
+
+// Cooldown keeps an artist from playing again within a window of tracks or of time after it last played, so a few
+// prolific artists don't play close together even when their tracks aren't duplicates. Artists are told apart by
+// ArtistKey
+type Cooldown struct {
+	mux    sync.Mutex
+	tracks int
+	window time.Duration
+	exempt map[string]bool
+
+	// plays are the artists played within the window, oldest first
+	plays []cooldownPlay
+}
+
+type cooldownPlay struct {
+	artist string
+	at     time.Time
+}
+
+// NewCooldown returns a Cooldown which keeps an artist from playing again within the next tracks tracks or within
+// window after it last played. A window of 0 is not enforced. The artists in exempt, given by name, may play as often
+// as they come up, e.g. the artist a station is built around
+func NewCooldown(tracks int, window time.Duration, exempt ...string) (*Cooldown, error) {
+	if tracks < 0 || window < 0 {
+		return nil, ErrInvalidCooldown
+	}
+
+	c := &Cooldown{tracks: tracks, window: window, exempt: map[string]bool{}}
+	// ArtistKey lowercases the name in the URL, so names are compared the same way
+	for _, artist := range exempt {
+		c.exempt[strings.ToLower(artist)] = true
+	}
+
+	return c, nil
+}
+
+// Record records that the track at trackURL started playing at now
+func (c *Cooldown) Record(trackURL string, now time.Time) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	c.plays = append(c.plays, cooldownPlay{artist: ArtistKey(trackURL), at: now})
+	c.prune(now)
+}
+
+// Allows returns true if the track at trackURL may play at now
+func (c *Cooldown) Allows(trackURL string, now time.Time) bool {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	c.prune(now)
+	return c.allows(c.plays, ArtistKey(trackURL), now)
+}
+
+// Filter returns the tracks among trackURLs which may play back to back from now on, in their order except that tracks
+// are moved later until their artist cooled down. Tracks whose artist never cools down within trackURLs are returned
+// as held. The tracks are assumed to play within the window of time, so an artist only plays once among them unless
+// the window of tracks is shorter than trackURLs
+func (c *Cooldown) Filter(trackURLs []string, now time.Time) (allowed, held []string) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	c.prune(now)
+	plays := append([]cooldownPlay{}, c.plays...)
+	remaining := append([]string{}, trackURLs...)
+	allowed = make([]string, 0, len(trackURLs))
+	for len(remaining) > 0 {
+		pick := -1
+		for i, trackURL := range remaining {
+			if c.allows(plays, ArtistKey(trackURL), now) {
+				pick = i
+				break
+			}
+		}
+
+		if pick < 0 {
+			return allowed, remaining
+		}
+
+		allowed = append(allowed, remaining[pick])
+		plays = append(plays, cooldownPlay{artist: ArtistKey(remaining[pick]), at: now})
+		remaining = append(remaining[:pick], remaining[pick+1:]...)
+	}
+
+	return allowed, nil
+}
+
+// allows returns true if artist may play at now after plays. The cooldown must be locked by the caller
+func (c *Cooldown) allows(plays []cooldownPlay, artist string, now time.Time) bool {
+	if c.exempt[artist] {
+		return true
+	}
+
+	for i := len(plays) - 1; i >= 0; i-- {
+		withinTracks := len(plays)-i <= c.tracks
+		withinWindow := c.window > 0 && now.Sub(plays[i].at) < c.window
+		if !withinTracks && !withinWindow {
+			break
+		}
+
+		if plays[i].artist == artist {
+			return false
+		}
+	}
+
+	return true
+}
+
+// prune drops the plays which are outside of both windows at now. The cooldown must be locked by the caller
+func (c *Cooldown) prune(now time.Time) {
+	keep := len(c.plays)
+	for i, play := range c.plays {
+		if len(c.plays)-i <= c.tracks || (c.window > 0 && now.Sub(play.at) < c.window) {
+			keep = i
+			break
+		}
+	}
+
+	c.plays = c.plays[keep:]
+}
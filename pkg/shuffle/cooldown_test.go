@@ -0,0 +1,79 @@
+package shuffle
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestNewCooldown(t *testing.T) {
+	cooldown, err := NewCooldown(-1, 0)
+	assert.Equal(t, ErrInvalidCooldown, err)
+	assert.Nil(t, cooldown)
+
+	cooldown, err = NewCooldown(0, -time.Minute)
+	assert.Equal(t, ErrInvalidCooldown, err)
+	assert.Nil(t, cooldown)
+}
+
+func TestCooldown_Tracks(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	cooldown, err := NewCooldown(2, 0)
+	require.NoError(t, err)
+
+	cooldown.Record("https://chipmusic.org/a/music/1", now)
+	assert.False(t, cooldown.Allows("https://chipmusic.org/A/music/2", now), "expected artists to be compared regardless of case")
+
+	cooldown.Record("https://chipmusic.org/b/music/1", now)
+	assert.False(t, cooldown.Allows("https://chipmusic.org/a/music/2", now))
+
+	cooldown.Record("https://chipmusic.org/c/music/1", now)
+	assert.True(t, cooldown.Allows("https://chipmusic.org/a/music/2", now), "expected the artist to cool down after two other tracks")
+	assert.False(t, cooldown.Allows("https://chipmusic.org/b/music/2", now))
+}
+
+func TestCooldown_Window(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	cooldown, err := NewCooldown(0, 30*time.Minute)
+	require.NoError(t, err)
+
+	cooldown.Record("https://chipmusic.org/a/music/1", now)
+	cooldown.Record("https://chipmusic.org/b/music/1", now.Add(10*time.Minute))
+	cooldown.Record("https://chipmusic.org/c/music/1", now.Add(20*time.Minute))
+	assert.False(t, cooldown.Allows("https://chipmusic.org/a/music/2", now.Add(29*time.Minute)))
+	assert.True(t, cooldown.Allows("https://chipmusic.org/a/music/2", now.Add(30*time.Minute)), "expected the artist to cool down after the window")
+}
+
+func TestCooldown_Filter(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	cooldown, err := NewCooldown(1, 0, "Some Artist")
+	require.NoError(t, err)
+
+	cooldown.Record("https://chipmusic.org/a/music/1", now)
+
+	allowed, held := cooldown.Filter([]string{
+		"https://chipmusic.org/a/music/2",
+		"https://chipmusic.org/a/music/3",
+		"https://chipmusic.org/b/music/1",
+		"https://chipmusic.org/Some+Artist/music/1",
+		"https://chipmusic.org/Some+Artist/music/2",
+		"https://chipmusic.org/b/music/2",
+	}, now)
+
+	assert.Equal(t, []string{
+		"https://chipmusic.org/b/music/1",
+		"https://chipmusic.org/a/music/2",
+		"https://chipmusic.org/Some+Artist/music/1",
+		"https://chipmusic.org/a/music/3",
+		"https://chipmusic.org/Some+Artist/music/2",
+		"https://chipmusic.org/b/music/2",
+	}, allowed, "expected tracks to be moved later until their artist cooled down, except for exempt artists")
+	assert.Empty(t, held)
+
+	assert.True(t, cooldown.Allows("https://chipmusic.org/b/music/3", now), "expected filtering not to record plays")
+
+	allowed, held = cooldown.Filter([]string{"https://chipmusic.org/a/music/2", "https://chipmusic.org/a/music/3"}, now)
+	assert.Empty(t, allowed)
+	assert.Equal(t, []string{"https://chipmusic.org/a/music/2", "https://chipmusic.org/a/music/3"}, held)
+}
@@ -0,0 +1,70 @@
+package shuffle
+
+import (
+	"math/rand"
+)
+
+// MixSource is a pool of track page URLs which contributes a share of a mix
+type MixSource struct {
+
+	// Name describes where the tracks come from, e.g. "favorites"
+	Name string
+
+	// Tracks are the track page URLs of the source in the order they should be used
+	Tracks []string
+
+	// Proportion is the share of the mix taken from this source relative to the other sources. Sources with a
+	// proportion of 0 or less are not used
+	Proportion float64
+}
+
+// Mix blends the tracks of every source into a single queue. Each position of the queue is taken from a source picked
+// with a probability matching its proportion. Once a source runs out of tracks, the remaining sources fill the rest of
+// the queue. Tracks which appear in more than one source are only queued once
+func Mix(sources []MixSource, rand *rand.Rand) []string {
+	remaining := make([][]string, len(sources))
+	for i, source := range sources {
+		if source.Proportion > 0 {
+			remaining[i] = source.Tracks
+		}
+	}
+
+	mixed := make([]string, 0)
+	queued := map[string]bool{}
+	for {
+		total := 0.0
+		for i, source := range sources {
+			if len(remaining[i]) > 0 {
+				total += source.Proportion
+			}
+		}
+
+		if total == 0 {
+			return mixed
+		}
+
+		pick := -1
+		target := rand.Float64() * total
+		for i, source := range sources {
+			if len(remaining[i]) == 0 {
+				continue
+			}
+
+			pick = i
+			if target < source.Proportion {
+				break
+			}
+
+			target -= source.Proportion
+		}
+
+		trackURL := remaining[pick][0]
+		remaining[pick] = remaining[pick][1:]
+		if queued[trackURL] {
+			continue
+		}
+
+		queued[trackURL] = true
+		mixed = append(mixed, trackURL)
+	}
+}
@@ -0,0 +1,55 @@
+package shuffle
+
+import (
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+func TestMix(t *testing.T) {
+	testCases := []struct {
+		name     string
+		sources  []MixSource
+		expected []string
+	}{
+		{"NoSources", nil, []string{}},
+		{"SingleSource", []MixSource{{Name: "a", Tracks: []string{"1", "2"}, Proportion: 1}}, []string{"1", "2"}},
+		{"UnusedSource", []MixSource{{Name: "a", Tracks: []string{"1"}, Proportion: 1}, {Name: "b", Tracks: []string{"2"}, Proportion: 0}}, []string{"1"}},
+		{"Duplicates", []MixSource{{Name: "a", Tracks: []string{"1", "1"}, Proportion: 1}, {Name: "b", Tracks: []string{"1", "2"}, Proportion: 1}}, []string{"1", "2"}},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			mixed := Mix(testCase.sources, rand.New(rand.NewSource(1)))
+			assert.ElementsMatch(tt, testCase.expected, mixed)
+		})
+	}
+}
+
+func TestMix_Proportions(t *testing.T) {
+	a := make([]string, 1000)
+	b := make([]string, 1000)
+	for i := range a {
+		a[i] = "a" + strconv.Itoa(i)
+		b[i] = "b" + strconv.Itoa(i)
+	}
+
+	sources := []MixSource{
+		{Name: "a", Tracks: a, Proportion: 0.75},
+		{Name: "b", Tracks: b, Proportion: 0.25},
+	}
+
+	mixed := Mix(sources, rand.New(rand.NewSource(1)))
+	assert.Len(t, mixed, 2000)
+
+	// Before either source runs out, the mix should roughly follow the proportions
+	fromA := 0
+	for _, trackURL := range mixed[:400] {
+		if trackURL[0] == 'a' {
+			fromA++
+		}
+	}
+
+	assert.True(t, fromA > 260 && fromA < 340, "expected roughly 300 tracks from a but got %d", fromA)
+}
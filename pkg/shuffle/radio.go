@@ -0,0 +1,50 @@
+package shuffle
+
+import (
+	"github.com/broar/chipmusic-cli/pkg/store"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultLikedListen is how long a track has to be listened to before it counts as liked. Tracks skipped sooner
+	// don't say anything good about their artist
+	DefaultLikedListen = time.Minute
+
+	// favoriteWeight is how many liked plays marking a track as a favorite counts as
+	favoriteWeight = 3
+)
+
+// LikedArtists returns the artists the user likes, ordered from most to least liked, for seeding a radio station
+// around the artist like. Every play listened to for at least minListened counts for its artist, and every favorite
+// counts as several plays. The artist like is left out, since its own catalog is played anyway
+func LikedArtists(history []store.HistoryEntry, favorites []store.Favorite, like string, minListened time.Duration) []string {
+	likes := map[string]int{}
+	for _, entry := range history {
+		if entry.Listened >= minListened {
+			likes[entry.Artist]++
+		}
+	}
+
+	for _, favorite := range favorites {
+		likes[favorite.Artist] += favoriteWeight
+	}
+
+	artists := make([]string, 0, len(likes))
+	for artist := range likes {
+		if artist != "" && !strings.EqualFold(artist, like) {
+			artists = append(artists, artist)
+		}
+	}
+
+	sort.Slice(artists, func(i, j int) bool {
+		if likes[artists[i]] == likes[artists[j]] {
+			return artists[i] < artists[j]
+		}
+
+		return likes[artists[i]] > likes[artists[j]]
+	})
+
+	return artists
+}
@@ -0,0 +1,29 @@
+package shuffle
+
+import (
+	"github.com/broar/chipmusic-cli/pkg/store"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestLikedArtists(t *testing.T) {
+	history := []store.HistoryEntry{
+		{URL: "a1", Artist: "a", Listened: 2 * time.Minute},
+		{URL: "a2", Artist: "a", Listened: time.Minute},
+		{URL: "b1", Artist: "b", Listened: 3 * time.Minute},
+		{URL: "c1", Artist: "c", Listened: 10 * time.Second},
+		{URL: "c2", Artist: "c", Listened: 5 * time.Second},
+		{URL: "like1", Artist: "Like", Listened: 4 * time.Minute},
+		{URL: "unknown1", Artist: "", Listened: 4 * time.Minute},
+	}
+
+	favorites := []store.Favorite{{URL: "d1", Artist: "d"}}
+
+	liked := LikedArtists(history, favorites, "like", DefaultLikedListen)
+	assert.Equal(t, []string{"d", "a", "b"}, liked, "expected favorites to count more, skipped tracks not to count, and the artist of the station to be left out")
+}
+
+func TestLikedArtists_NoHistory(t *testing.T) {
+	assert.Empty(t, LikedArtists(nil, nil, "like", DefaultLikedListen))
+}
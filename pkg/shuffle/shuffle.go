@@ -0,0 +1,132 @@
+package shuffle
+
+import (
+	"fmt"
+	"github.com/broar/chipmusic-cli/pkg/store"
+	"math"
+	"math/rand"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// StrategyPureRandom plays tracks in a uniformly random order, ignoring history
+	StrategyPureRandom = "pure-random"
+
+	// StrategyBalanced down-weights artists which were played recently or often so a few prolific artists don't
+	// dominate the shuffle
+	StrategyBalanced = "balanced"
+
+	// StrategyDiscovery is like StrategyBalanced but also boosts artists which have never been played
+	StrategyDiscovery = "discovery"
+
+	// DefaultHalfLife is how long it takes for a play to count half as much against its artist
+	DefaultHalfLife = 7 * 24 * time.Hour
+
+	// discoveryBoost is how much more likely a never-played artist is to be picked with StrategyDiscovery
+	discoveryBoost = 4
+)
+
+// Strategies returns the names of every shuffle strategy
+func Strategies() []string {
+	return []string{StrategyBalanced, StrategyPureRandom, StrategyDiscovery}
+}
+
+// Shuffler orders track page URLs according to a strategy and the listening history
+type Shuffler struct {
+	strategy string
+	plays    map[string]float64
+	rand     *rand.Rand
+}
+
+// NewShuffler returns a Shuffler for strategy. Every entry in history counts against its artist, with older entries
+// counting less than recent ones. An empty strategy is the same as StrategyPureRandom
+func NewShuffler(strategy string, history []store.HistoryEntry, now time.Time, rand *rand.Rand) (*Shuffler, error) {
+	switch strategy {
+	case "":
+		strategy = StrategyPureRandom
+	case StrategyPureRandom, StrategyBalanced, StrategyDiscovery:
+	default:
+		return nil, fmt.Errorf("unknown shuffle strategy %q: must be one of [%s]", strategy, strings.Join(Strategies(), ", "))
+	}
+
+	plays := map[string]float64{}
+	for _, entry := range history {
+		age := now.Sub(entry.PlayedAt)
+		if age < 0 {
+			age = 0
+		}
+
+		plays[ArtistKey(entry.URL)] += math.Pow(0.5, float64(age)/float64(DefaultHalfLife))
+	}
+
+	return &Shuffler{strategy: strategy, plays: plays, rand: rand}, nil
+}
+
+// Shuffle returns trackURLs in a random order. Tracks with a higher weight are more likely to be placed first
+func (s *Shuffler) Shuffle(trackURLs []string) []string {
+	remaining := append([]string{}, trackURLs...)
+	weights := make([]float64, len(remaining))
+	for i, trackURL := range remaining {
+		weights[i] = s.Weight(trackURL)
+	}
+
+	// Pick tracks one at a time without replacement, each with a probability proportional to its weight
+	shuffled := make([]string, 0, len(remaining))
+	for len(remaining) > 0 {
+		total := 0.0
+		for _, weight := range weights {
+			total += weight
+		}
+
+		pick := len(remaining) - 1
+		target := s.rand.Float64() * total
+		for i, weight := range weights {
+			if target < weight {
+				pick = i
+				break
+			}
+
+			target -= weight
+		}
+
+		shuffled = append(shuffled, remaining[pick])
+		remaining = append(remaining[:pick], remaining[pick+1:]...)
+		weights = append(weights[:pick], weights[pick+1:]...)
+	}
+
+	return shuffled
+}
+
+// Weight returns how likely the track at trackURL is to be picked relative to other tracks
+func (s *Shuffler) Weight(trackURL string) float64 {
+	if s.strategy == StrategyPureRandom {
+		return 1
+	}
+
+	plays, ok := s.plays[ArtistKey(trackURL)]
+	if !ok && s.strategy == StrategyDiscovery {
+		return discoveryBoost
+	}
+
+	return 1 / (1 + plays)
+}
+
+// ArtistKey returns a key identifying the artist of the track page at trackURL. Track pages live under the profile of
+// their artist, e.g. https://chipmusic.org/Fearofdark/music/lovesickness-2a03, so the artist is known without fetching
+// the page
+func ArtistKey(trackURL string) string {
+	u, err := url.Parse(trackURL)
+	if err != nil {
+		return trackURL
+	}
+
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	artist, err := url.PathUnescape(segments[0])
+	if err != nil {
+		artist = segments[0]
+	}
+
+	return strings.ToLower(strings.ReplaceAll(artist, "+", " "))
+}
@@ -0,0 +1,117 @@
+package shuffle
+
+import (
+	"github.com/broar/chipmusic-cli/pkg/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+var (
+	now = time.Date(2020, time.December, 20, 12, 0, 0, 0, time.UTC)
+
+	history = []store.HistoryEntry{
+		{URL: "https://chipmusic.org/Fearofdark/music/lovesickness-2a03", PlayedAt: now.Add(-time.Hour)},
+		{URL: "https://chipmusic.org/Fearofdark/music/motorway", PlayedAt: now.Add(-2 * time.Hour)},
+		{URL: "https://chipmusic.org/daisy/music/bump", PlayedAt: now.Add(-30 * 24 * time.Hour)},
+	}
+)
+
+func TestNewShuffler_UnknownStrategy(t *testing.T) {
+	shuffler, err := NewShuffler("some.strategy", nil, now, rand.New(rand.NewSource(1)))
+	assert.Error(t, err)
+	assert.Nil(t, shuffler)
+}
+
+func TestShuffler_Weight(t *testing.T) {
+	testCases := []struct {
+		name     string
+		strategy string
+		trackURL string
+		expected float64
+	}{
+		{"PureRandomIgnoresHistory", StrategyPureRandom, "https://chipmusic.org/Fearofdark/music/some.music", 1},
+		{"DefaultIsPureRandom", "", "https://chipmusic.org/Fearofdark/music/some.music", 1},
+		{"BalancedNeverPlayed", StrategyBalanced, "https://chipmusic.org/some.artist/music/some.music", 1},
+		{"DiscoveryNeverPlayed", StrategyDiscovery, "https://chipmusic.org/some.artist/music/some.music", discoveryBoost},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			shuffler, err := NewShuffler(testCase.strategy, history, now, rand.New(rand.NewSource(1)))
+			require.NoError(tt, err)
+
+			assert.Equal(tt, testCase.expected, shuffler.Weight(testCase.trackURL))
+		})
+	}
+}
+
+func TestShuffler_Weight_Balanced(t *testing.T) {
+	for _, strategy := range []string{StrategyBalanced, StrategyDiscovery} {
+		t.Run(strategy, func(tt *testing.T) {
+			shuffler, err := NewShuffler(strategy, history, now, rand.New(rand.NewSource(1)))
+			require.NoError(tt, err)
+
+			heavy := shuffler.Weight("https://chipmusic.org/fearofdark/music/some.music")
+			light := shuffler.Weight("https://chipmusic.org/daisy/music/some.music")
+			assert.True(tt, heavy < light, "recently and heavily played artists should weigh less")
+			assert.True(tt, light < 1, "played artists should weigh less than artists which were never played")
+		})
+	}
+}
+
+func TestShuffler_Shuffle(t *testing.T) {
+	tracks := []string{
+		"https://chipmusic.org/a/music/1",
+		"https://chipmusic.org/b/music/2",
+		"https://chipmusic.org/c/music/3",
+		"https://chipmusic.org/d/music/4",
+	}
+
+	for _, strategy := range Strategies() {
+		t.Run(strategy, func(tt *testing.T) {
+			shuffler, err := NewShuffler(strategy, history, now, rand.New(rand.NewSource(1)))
+			require.NoError(tt, err)
+
+			shuffled := shuffler.Shuffle(tracks)
+			assert.ElementsMatch(tt, tracks, shuffled)
+		})
+	}
+}
+
+func TestShuffler_Shuffle_Discovery(t *testing.T) {
+	played := "https://chipmusic.org/Fearofdark/music/some.music"
+	unheard := "https://chipmusic.org/some.artist/music/some.music"
+
+	shuffler, err := NewShuffler(StrategyDiscovery, history, now, rand.New(rand.NewSource(1)))
+	require.NoError(t, err)
+
+	first := 0
+	for i := 0; i < 1000; i++ {
+		if shuffler.Shuffle([]string{played, unheard})[0] == unheard {
+			first++
+		}
+	}
+
+	assert.True(t, first > 800, "never played artists should usually be picked first")
+}
+
+func TestArtistKey(t *testing.T) {
+	testCases := []struct {
+		name     string
+		trackURL string
+		expected string
+	}{
+		{"Simple", "https://chipmusic.org/Fearofdark/music/lovesickness-2a03", "fearofdark"},
+		{"Spaces", "https://chipmusic.org/Hide+Your+Tigers/music/virtues-lsdj", "hide your tigers"},
+		{"Escaped", "https://chipmusic.org/some%20artist/music/some.music", "some artist"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			assert.Equal(tt, testCase.expected, ArtistKey(testCase.trackURL))
+		})
+	}
+}
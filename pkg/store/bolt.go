@@ -0,0 +1,400 @@
+package store
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	bolt "go.etcd.io/bbolt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultFileName is the default name of the database file within the data directory
+	DefaultFileName = "chipmusic.db"
+
+	defaultOpenTimeout = 1 * time.Second
+)
+
+var (
+	seenBucket      = []byte("seen")
+	introSkipBucket = []byte("intro-skip")
+	historyBucket   = []byte("history")
+	favoriteBucket  = []byte("favorite")
+	playlistBucket  = []byte("playlist")
+	settingBucket   = []byte("setting")
+	metadataBucket  = []byte("metadata")
+
+	buckets = [][]byte{
+		seenBucket,
+		introSkipBucket,
+		historyBucket,
+		favoriteBucket,
+		playlistBucket,
+		settingBucket,
+		metadataBucket,
+	}
+)
+
+// BoltStore is a Store backed by a bbolt database file. bbolt locks the file for as long as it is open, so the file is
+// only opened for each read and write. That way other processes, e.g. a history command run while a session is playing,
+// can use the same file at the same time
+type BoltStore struct {
+	path string
+
+	// mux keeps the process from waiting on its own lock of the file. Reads share the file while writes need it to
+	// themselves
+	mux sync.RWMutex
+}
+
+// OpenBoltStore opens the bbolt database at path, creating the file and any parent directories if they do not exist
+func OpenBoltStore(path string) (*BoltStore, error) {
+	if path == "" {
+		return nil, errors.New("path cannot be empty")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create directory for %s: %w", path, err)
+	}
+
+	s := &BoltStore{path: path}
+	err := s.update(func(tx *bolt.Tx) error {
+		for _, bucket := range buckets {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return fmt.Errorf("failed to create bucket %s: %w", bucket, err)
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize database %s: %w", path, err)
+	}
+
+	return s, nil
+}
+
+// view opens the database file for reading and calls fn within a read-only transaction
+func (s *BoltStore) view(fn func(tx *bolt.Tx) error) error {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	db, err := bolt.Open(s.path, 0600, &bolt.Options{Timeout: defaultOpenTimeout, ReadOnly: true})
+	if err != nil {
+		return fmt.Errorf("failed to open database %s: %w", s.path, err)
+	}
+
+	defer db.Close()
+	return db.View(fn)
+}
+
+// update opens the database file for writing and calls fn within a read-write transaction
+func (s *BoltStore) update(fn func(tx *bolt.Tx) error) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	db, err := bolt.Open(s.path, 0600, &bolt.Options{Timeout: defaultOpenTimeout})
+	if err != nil {
+		return fmt.Errorf("failed to open database %s: %w", s.path, err)
+	}
+
+	if err := db.Update(fn); err != nil {
+		db.Close()
+		return err
+	}
+
+	if err := db.Close(); err != nil {
+		return fmt.Errorf("failed to close database %s: %w", s.path, err)
+	}
+
+	return nil
+}
+
+// LastSeen returns the URL of the newest track seen for key. If key has never been seen, an empty string is returned
+func (s *BoltStore) LastSeen(key string) (string, error) {
+	var url string
+	err := s.view(func(tx *bolt.Tx) error {
+		url = string(tx.Bucket(seenBucket).Get([]byte(key)))
+		return nil
+	})
+
+	return url, err
+}
+
+// SetLastSeen records url as the newest track seen for key
+func (s *BoltStore) SetLastSeen(key, url string) error {
+	return s.update(func(tx *bolt.Tx) error {
+		return tx.Bucket(seenBucket).Put([]byte(key), []byte(url))
+	})
+}
+
+// Setting returns the value of the setting with name. If the setting has never been set, an empty string is returned
+func (s *BoltStore) Setting(name string) (string, error) {
+	var value string
+	err := s.view(func(tx *bolt.Tx) error {
+		value = string(tx.Bucket(settingBucket).Get([]byte(name)))
+		return nil
+	})
+
+	return value, err
+}
+
+// SetSetting sets the setting with name to value. Setting an empty value removes the setting
+func (s *BoltStore) SetSetting(name, value string) error {
+	return s.update(func(tx *bolt.Tx) error {
+		if value == "" {
+			return tx.Bucket(settingBucket).Delete([]byte(name))
+		}
+
+		return tx.Bucket(settingBucket).Put([]byte(name), []byte(value))
+	})
+}
+
+// IntroSkip returns how much of the start of a track should be skipped. A rule for the track page URL takes precedence
+// over a rule for the artist. If there are no rules for either, 0 is returned
+func (s *BoltStore) IntroSkip(trackURL, artist string) (time.Duration, error) {
+	var skip time.Duration
+	err := s.view(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(introSkipBucket)
+		for _, key := range []string{trackIntroSkipKey(trackURL), artistIntroSkipKey(artist)} {
+			value := bucket.Get([]byte(key))
+			if value == nil {
+				continue
+			}
+
+			nanoseconds, err := strconv.ParseInt(string(value), 10, 64)
+			if err != nil {
+				return fmt.Errorf("failed to parse intro skip for %s: %w", key, err)
+			}
+
+			skip = time.Duration(nanoseconds)
+			return nil
+		}
+
+		return nil
+	})
+
+	return skip, err
+}
+
+// SetTrackIntroSkip sets how much of the start of the track at trackURL should be skipped. A skip of 0 or less removes
+// the rule
+func (s *BoltStore) SetTrackIntroSkip(trackURL string, skip time.Duration) error {
+	return s.setIntroSkip(trackIntroSkipKey(trackURL), skip)
+}
+
+// SetArtistIntroSkip sets how much of the start of every track by artist should be skipped. A skip of 0 or less
+// removes the rule
+func (s *BoltStore) SetArtistIntroSkip(artist string, skip time.Duration) error {
+	return s.setIntroSkip(artistIntroSkipKey(artist), skip)
+}
+
+func (s *BoltStore) setIntroSkip(key string, skip time.Duration) error {
+	return s.update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(introSkipBucket)
+		if skip <= 0 {
+			return bucket.Delete([]byte(key))
+		}
+
+		return bucket.Put([]byte(key), []byte(strconv.FormatInt(int64(skip), 10)))
+	})
+}
+
+// AddHistory records a played track
+func (s *BoltStore) AddHistory(entry HistoryEntry) error {
+	value, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode history entry: %w", err)
+	}
+
+	return s.update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(historyBucket)
+		sequence, err := bucket.NextSequence()
+		if err != nil {
+			return fmt.Errorf("failed to get next history sequence: %w", err)
+		}
+
+		// Keys are ordered by when the track was played and then by insertion order
+		key := make([]byte, 16)
+		binary.BigEndian.PutUint64(key, uint64(entry.PlayedAt.UnixNano()))
+		binary.BigEndian.PutUint64(key[8:], sequence)
+		return bucket.Put(key, value)
+	})
+}
+
+// History returns up to limit played tracks ordered from most to least recently played. If limit is 0 or less, all
+// played tracks are returned
+func (s *BoltStore) History(limit int) ([]HistoryEntry, error) {
+	entries := make([]HistoryEntry, 0)
+	err := s.view(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(historyBucket).Cursor()
+		for key, value := cursor.Last(); key != nil; key, value = cursor.Prev() {
+			if limit > 0 && len(entries) >= limit {
+				break
+			}
+
+			entry := HistoryEntry{}
+			if err := json.Unmarshal(value, &entry); err != nil {
+				return fmt.Errorf("failed to decode history entry: %w", err)
+			}
+
+			entries = append(entries, entry)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// AddFavorite adds a track to the favorites. Adding a track which is already a favorite replaces it
+func (s *BoltStore) AddFavorite(favorite Favorite) error {
+	value, err := json.Marshal(favorite)
+	if err != nil {
+		return fmt.Errorf("failed to encode favorite: %w", err)
+	}
+
+	return s.update(func(tx *bolt.Tx) error {
+		return tx.Bucket(favoriteBucket).Put([]byte(favorite.URL), value)
+	})
+}
+
+// RemoveFavorite removes the track at trackURL from the favorites. Removing a track which is not a favorite does
+// nothing
+func (s *BoltStore) RemoveFavorite(trackURL string) error {
+	return s.update(func(tx *bolt.Tx) error {
+		return tx.Bucket(favoriteBucket).Delete([]byte(trackURL))
+	})
+}
+
+// Favorites returns all favorite tracks ordered from oldest to newest
+func (s *BoltStore) Favorites() ([]Favorite, error) {
+	favorites := make([]Favorite, 0)
+	err := s.view(func(tx *bolt.Tx) error {
+		return tx.Bucket(favoriteBucket).ForEach(func(_, value []byte) error {
+			favorite := Favorite{}
+			if err := json.Unmarshal(value, &favorite); err != nil {
+				return fmt.Errorf("failed to decode favorite: %w", err)
+			}
+
+			favorites = append(favorites, favorite)
+			return nil
+		})
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(favorites, func(i, j int) bool {
+		return favorites[i].AddedAt.Before(favorites[j].AddedAt)
+	})
+
+	return favorites, nil
+}
+
+// SavePlaylist saves a list of track URLs under name, replacing any existing playlist with the same name. Saving an
+// empty playlist deletes it
+func (s *BoltStore) SavePlaylist(name string, trackURLs []string) error {
+	if name == "" {
+		return errors.New("playlist name cannot be empty")
+	}
+
+	value, err := json.Marshal(trackURLs)
+	if err != nil {
+		return fmt.Errorf("failed to encode playlist: %w", err)
+	}
+
+	return s.update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(playlistBucket)
+		if len(trackURLs) == 0 {
+			return bucket.Delete([]byte(name))
+		}
+
+		return bucket.Put([]byte(name), value)
+	})
+}
+
+// Playlist returns the track URLs of the playlist with name. If there is no such playlist, an empty slice is returned
+func (s *BoltStore) Playlist(name string) ([]string, error) {
+	trackURLs := make([]string, 0)
+	err := s.view(func(tx *bolt.Tx) error {
+		value := tx.Bucket(playlistBucket).Get([]byte(name))
+		if value == nil {
+			return nil
+		}
+
+		return json.Unmarshal(value, &trackURLs)
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode playlist: %w", err)
+	}
+
+	return trackURLs, nil
+}
+
+// Playlists returns the names of all playlists in alphabetical order
+func (s *BoltStore) Playlists() ([]string, error) {
+	names := make([]string, 0)
+	err := s.view(func(tx *bolt.Tx) error {
+		return tx.Bucket(playlistBucket).ForEach(func(key, _ []byte) error {
+			names = append(names, string(key))
+			return nil
+		})
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
+
+// TrackMetadata returns the metadata cached for the track page at trackURL. If none is cached, nil is returned
+func (s *BoltStore) TrackMetadata(trackURL string) (*TrackMetadata, error) {
+	var metadata *TrackMetadata
+	err := s.view(func(tx *bolt.Tx) error {
+		value := tx.Bucket(metadataBucket).Get([]byte(trackURL))
+		if value == nil {
+			return nil
+		}
+
+		metadata = &TrackMetadata{}
+		return json.Unmarshal(value, metadata)
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode track metadata: %w", err)
+	}
+
+	return metadata, nil
+}
+
+// SetTrackMetadata caches the metadata of a track, replacing any metadata cached for the same track page
+func (s *BoltStore) SetTrackMetadata(metadata TrackMetadata) error {
+	value, err := json.Marshal(metadata)
+	if err != nil {
+		return fmt.Errorf("failed to encode track metadata: %w", err)
+	}
+
+	return s.update(func(tx *bolt.Tx) error {
+		return tx.Bucket(metadataBucket).Put([]byte(metadata.URL), value)
+	})
+}
+
+// Close does nothing since the database file is only open while it is read or written
+func (s *BoltStore) Close() error {
+	return nil
+}
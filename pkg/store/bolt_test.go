@@ -0,0 +1,123 @@
+package store
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func openTestBoltStore(t *testing.T) (*BoltStore, func()) {
+	dir, err := ioutil.TempDir("", "chipmusic-store")
+	require.NoError(t, err, "failed to create temporary directory")
+
+	store, err := OpenBoltStore(filepath.Join(dir, "nested", DefaultFileName))
+	require.NoError(t, err, "failed to open store")
+
+	return store, func() {
+		store.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestOpenBoltStore_EmptyPath(t *testing.T) {
+	store, err := OpenBoltStore("")
+	assert.Error(t, err)
+	assert.Nil(t, store)
+}
+
+func TestBoltStore_OpenTwice(t *testing.T) {
+	store, cleanup := openTestBoltStore(t)
+	defer cleanup()
+
+	// Another process, e.g. the history command run during a session, opens the same file while the store is open
+	other, err := OpenBoltStore(store.path)
+	require.NoError(t, err)
+
+	defer other.Close()
+
+	entry := HistoryEntry{URL: "some.url", Title: "some.title", Artist: "some.artist", PlayedAt: time.Now()}
+	require.NoError(t, store.AddHistory(entry))
+
+	history, err := other.History(0)
+	require.NoError(t, err)
+	assertHistoryEqual(t, []HistoryEntry{entry}, history)
+
+	require.NoError(t, other.SetSetting("some.setting", "some.value"))
+	value, err := store.Setting("some.setting")
+	require.NoError(t, err)
+	assert.Equal(t, "some.value", value)
+}
+
+func TestBoltStore_LastSeen(t *testing.T) {
+	store, cleanup := openTestBoltStore(t)
+	defer cleanup()
+
+	url, err := store.LastSeen("some.key")
+	require.NoError(t, err)
+	assert.Empty(t, url)
+
+	err = store.SetLastSeen("some.key", "some.url")
+	require.NoError(t, err)
+
+	url, err = store.LastSeen("some.key")
+	require.NoError(t, err)
+	assert.Equal(t, "some.url", url)
+
+	url, err = store.LastSeen("other.key")
+	require.NoError(t, err)
+	assert.Empty(t, url)
+}
+
+func TestBoltStore_IntroSkip(t *testing.T) {
+	testCases := []struct {
+		name     string
+		track    time.Duration
+		artist   time.Duration
+		expected time.Duration
+	}{
+		{"NoRules", 0, 0, 0},
+		{"OnlyTrack", 10 * time.Second, 0, 10 * time.Second},
+		{"OnlyArtist", 0, 5 * time.Second, 5 * time.Second},
+		{"TrackTakesPrecedence", 10 * time.Second, 5 * time.Second, 10 * time.Second},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			store, cleanup := openTestBoltStore(tt)
+			defer cleanup()
+
+			require.NoError(tt, store.SetTrackIntroSkip("some.url", testCase.track))
+			require.NoError(tt, store.SetArtistIntroSkip("some.artist", testCase.artist))
+
+			skip, err := store.IntroSkip("some.url", "some.artist")
+			require.NoError(tt, err)
+			assert.Equal(tt, testCase.expected, skip)
+
+			skip, err = store.IntroSkip("other.url", "other.artist")
+			require.NoError(tt, err)
+			assert.Zero(tt, skip)
+		})
+	}
+}
+
+func TestBoltStore_SetIntroSkip_RemovesRule(t *testing.T) {
+	store, cleanup := openTestBoltStore(t)
+	defer cleanup()
+
+	require.NoError(t, store.SetArtistIntroSkip("some.artist", 5*time.Second))
+	require.NoError(t, store.SetArtistIntroSkip("some.artist", 0))
+
+	skip, err := store.IntroSkip("some.url", "some.artist")
+	require.NoError(t, err)
+	assert.Zero(t, skip)
+}
+
+func TestBoltStore(t *testing.T) {
+	testStore(t, func(t *testing.T) (Store, func()) {
+		return openTestBoltStore(t)
+	})
+}
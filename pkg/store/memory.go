@@ -0,0 +1,245 @@
+package store
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryStore is a Store which keeps state in memory. Nothing is persisted, which makes it useful for tests and for
+// running without touching the disk
+type MemoryStore struct {
+	mux        sync.Mutex
+	seen       map[string]string
+	introSkips map[string]time.Duration
+	history    []HistoryEntry
+	favorites  map[string]Favorite
+	playlists  map[string][]string
+	settings   map[string]string
+	metadata   map[string]TrackMetadata
+}
+
+// NewMemoryStore returns an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		seen:       map[string]string{},
+		introSkips: map[string]time.Duration{},
+		favorites:  map[string]Favorite{},
+		playlists:  map[string][]string{},
+		settings:   map[string]string{},
+		metadata:   map[string]TrackMetadata{},
+	}
+}
+
+// LastSeen returns the URL of the newest track seen for key. If key has never been seen, an empty string is returned
+func (m *MemoryStore) LastSeen(key string) (string, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	return m.seen[key], nil
+}
+
+// SetLastSeen records url as the newest track seen for key
+func (m *MemoryStore) SetLastSeen(key, url string) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	m.seen[key] = url
+	return nil
+}
+
+// Setting returns the value of the setting with name. If the setting has never been set, an empty string is returned
+func (m *MemoryStore) Setting(name string) (string, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	return m.settings[name], nil
+}
+
+// SetSetting sets the setting with name to value. Setting an empty value removes the setting
+func (m *MemoryStore) SetSetting(name, value string) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	if value == "" {
+		delete(m.settings, name)
+		return nil
+	}
+
+	m.settings[name] = value
+	return nil
+}
+
+// IntroSkip returns how much of the start of a track should be skipped. A rule for the track page URL takes precedence
+// over a rule for the artist. If there are no rules for either, 0 is returned
+func (m *MemoryStore) IntroSkip(trackURL, artist string) (time.Duration, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	if skip, ok := m.introSkips[trackIntroSkipKey(trackURL)]; ok {
+		return skip, nil
+	}
+
+	return m.introSkips[artistIntroSkipKey(artist)], nil
+}
+
+// SetTrackIntroSkip sets how much of the start of the track at trackURL should be skipped. A skip of 0 or less removes
+// the rule
+func (m *MemoryStore) SetTrackIntroSkip(trackURL string, skip time.Duration) error {
+	m.setIntroSkip(trackIntroSkipKey(trackURL), skip)
+	return nil
+}
+
+// SetArtistIntroSkip sets how much of the start of every track by artist should be skipped. A skip of 0 or less
+// removes the rule
+func (m *MemoryStore) SetArtistIntroSkip(artist string, skip time.Duration) error {
+	m.setIntroSkip(artistIntroSkipKey(artist), skip)
+	return nil
+}
+
+func (m *MemoryStore) setIntroSkip(key string, skip time.Duration) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	if skip <= 0 {
+		delete(m.introSkips, key)
+		return
+	}
+
+	m.introSkips[key] = skip
+}
+
+// AddHistory records a played track
+func (m *MemoryStore) AddHistory(entry HistoryEntry) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	m.history = append(m.history, entry)
+	sort.SliceStable(m.history, func(i, j int) bool {
+		return m.history[i].PlayedAt.Before(m.history[j].PlayedAt)
+	})
+
+	return nil
+}
+
+// History returns up to limit played tracks ordered from most to least recently played. If limit is 0 or less, all
+// played tracks are returned
+func (m *MemoryStore) History(limit int) ([]HistoryEntry, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	entries := make([]HistoryEntry, 0)
+	for i := len(m.history) - 1; i >= 0; i-- {
+		if limit > 0 && len(entries) >= limit {
+			break
+		}
+
+		entries = append(entries, m.history[i])
+	}
+
+	return entries, nil
+}
+
+// AddFavorite adds a track to the favorites. Adding a track which is already a favorite replaces it
+func (m *MemoryStore) AddFavorite(favorite Favorite) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	m.favorites[favorite.URL] = favorite
+	return nil
+}
+
+// RemoveFavorite removes the track at trackURL from the favorites. Removing a track which is not a favorite does
+// nothing
+func (m *MemoryStore) RemoveFavorite(trackURL string) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	delete(m.favorites, trackURL)
+	return nil
+}
+
+// Favorites returns all favorite tracks ordered from oldest to newest
+func (m *MemoryStore) Favorites() ([]Favorite, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	favorites := make([]Favorite, 0, len(m.favorites))
+	for _, favorite := range m.favorites {
+		favorites = append(favorites, favorite)
+	}
+
+	sort.Slice(favorites, func(i, j int) bool {
+		if favorites[i].AddedAt.Equal(favorites[j].AddedAt) {
+			return favorites[i].URL < favorites[j].URL
+		}
+
+		return favorites[i].AddedAt.Before(favorites[j].AddedAt)
+	})
+
+	return favorites, nil
+}
+
+// SavePlaylist saves a list of track URLs under name, replacing any existing playlist with the same name. Saving an
+// empty playlist deletes it
+func (m *MemoryStore) SavePlaylist(name string, trackURLs []string) error {
+	if name == "" {
+		return errors.New("playlist name cannot be empty")
+	}
+
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	if len(trackURLs) == 0 {
+		delete(m.playlists, name)
+		return nil
+	}
+
+	m.playlists[name] = append([]string{}, trackURLs...)
+	return nil
+}
+
+// Playlist returns the track URLs of the playlist with name. If there is no such playlist, an empty slice is returned
+func (m *MemoryStore) Playlist(name string) ([]string, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	return append([]string{}, m.playlists[name]...), nil
+}
+
+// Playlists returns the names of all playlists in alphabetical order
+func (m *MemoryStore) Playlists() ([]string, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	names := make([]string, 0, len(m.playlists))
+	for name := range m.playlists {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
+
+// TrackMetadata returns the metadata cached for the track page at trackURL. If none is cached, nil is returned
+func (m *MemoryStore) TrackMetadata(trackURL string) (*TrackMetadata, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	metadata, ok := m.metadata[trackURL]
+	if !ok {
+		return nil, nil
+	}
+
+	metadata.Tags = append([]string{}, metadata.Tags...)
+	return &metadata, nil
+}
+
+// SetTrackMetadata caches the metadata of a track, replacing any metadata cached for the same track page
+func (m *MemoryStore) SetTrackMetadata(metadata TrackMetadata) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	metadata.Tags = append([]string{}, metadata.Tags...)
+	m.metadata[metadata.URL] = metadata
+	return nil
+}
+
+// Close does nothing since there are no resources to release
+func (m *MemoryStore) Close() error {
+	return nil
+}
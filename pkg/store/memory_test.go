@@ -0,0 +1,14 @@
+package store
+
+import (
+	"testing"
+)
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, func(t *testing.T) (Store, func()) {
+		store := NewMemoryStore()
+		return store, func() {
+			store.Close()
+		}
+	})
+}
@@ -0,0 +1,382 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	_ "github.com/mattn/go-sqlite3"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// DefaultSQLiteFileName is the default name of the SQLite database file within the data directory
+	DefaultSQLiteFileName = "chipmusic.sqlite"
+)
+
+var (
+	sqliteSchema = []string{
+		`CREATE TABLE IF NOT EXISTS seen (
+			key TEXT PRIMARY KEY,
+			url TEXT NOT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS intro_skips (
+			key TEXT PRIMARY KEY,
+			skip INTEGER NOT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS history (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			url TEXT NOT NULL,
+			title TEXT NOT NULL,
+			artist TEXT NOT NULL,
+			played_at INTEGER NOT NULL,
+			listened INTEGER NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS history_played_at ON history (played_at)`,
+		`CREATE TABLE IF NOT EXISTS favorites (
+			url TEXT PRIMARY KEY,
+			title TEXT NOT NULL,
+			artist TEXT NOT NULL,
+			added_at INTEGER NOT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS playlists (
+			name TEXT NOT NULL,
+			position INTEGER NOT NULL,
+			url TEXT NOT NULL,
+			PRIMARY KEY (name, position)
+		)`,
+		`CREATE TABLE IF NOT EXISTS settings (
+			name TEXT PRIMARY KEY,
+			value TEXT NOT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS track_metadata (
+			url TEXT PRIMARY KEY,
+			title TEXT NOT NULL,
+			artist TEXT NOT NULL,
+			download_url TEXT NOT NULL,
+			tags TEXT NOT NULL,
+			posted_at INTEGER NOT NULL,
+			description TEXT NOT NULL,
+			cached_at INTEGER NOT NULL
+		)`,
+	}
+)
+
+// SQLiteStore is a Store backed by a SQLite database file. Times are stored as Unix nanoseconds and durations as
+// nanoseconds so the database can be queried directly with SQL
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// OpenSQLiteStore opens the SQLite database at path, creating the file, any parent directories, and the schema if they
+// do not exist
+func OpenSQLiteStore(path string) (*SQLiteStore, error) {
+	if path == "" {
+		return nil, errors.New("path cannot be empty")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create directory for %s: %w", path, err)
+	}
+
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database %s: %w", path, err)
+	}
+
+	for _, statement := range sqliteSchema {
+		if _, err := db.Exec(statement); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to initialize database %s: %w", path, err)
+		}
+	}
+
+	return &SQLiteStore{db: db}, nil
+}
+
+// LastSeen returns the URL of the newest track seen for key. If key has never been seen, an empty string is returned
+func (s *SQLiteStore) LastSeen(key string) (string, error) {
+	var url string
+	err := s.db.QueryRow(`SELECT url FROM seen WHERE key = ?`, key).Scan(&url)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+
+	return url, err
+}
+
+// SetLastSeen records url as the newest track seen for key
+func (s *SQLiteStore) SetLastSeen(key, url string) error {
+	_, err := s.db.Exec(`INSERT OR REPLACE INTO seen (key, url) VALUES (?, ?)`, key, url)
+	return err
+}
+
+// Setting returns the value of the setting with name. If the setting has never been set, an empty string is returned
+func (s *SQLiteStore) Setting(name string) (string, error) {
+	var value string
+	err := s.db.QueryRow(`SELECT value FROM settings WHERE name = ?`, name).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+
+	return value, err
+}
+
+// SetSetting sets the setting with name to value. Setting an empty value removes the setting
+func (s *SQLiteStore) SetSetting(name, value string) error {
+	if value == "" {
+		_, err := s.db.Exec(`DELETE FROM settings WHERE name = ?`, name)
+		return err
+	}
+
+	_, err := s.db.Exec(`INSERT OR REPLACE INTO settings (name, value) VALUES (?, ?)`, name, value)
+	return err
+}
+
+// IntroSkip returns how much of the start of a track should be skipped. A rule for the track page URL takes precedence
+// over a rule for the artist. If there are no rules for either, 0 is returned
+func (s *SQLiteStore) IntroSkip(trackURL, artist string) (time.Duration, error) {
+	for _, key := range []string{trackIntroSkipKey(trackURL), artistIntroSkipKey(artist)} {
+		var skip int64
+		err := s.db.QueryRow(`SELECT skip FROM intro_skips WHERE key = ?`, key).Scan(&skip)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		} else if err != nil {
+			return 0, fmt.Errorf("failed to get intro skip for %s: %w", key, err)
+		}
+
+		return time.Duration(skip), nil
+	}
+
+	return 0, nil
+}
+
+// SetTrackIntroSkip sets how much of the start of the track at trackURL should be skipped. A skip of 0 or less removes
+// the rule
+func (s *SQLiteStore) SetTrackIntroSkip(trackURL string, skip time.Duration) error {
+	return s.setIntroSkip(trackIntroSkipKey(trackURL), skip)
+}
+
+// SetArtistIntroSkip sets how much of the start of every track by artist should be skipped. A skip of 0 or less
+// removes the rule
+func (s *SQLiteStore) SetArtistIntroSkip(artist string, skip time.Duration) error {
+	return s.setIntroSkip(artistIntroSkipKey(artist), skip)
+}
+
+func (s *SQLiteStore) setIntroSkip(key string, skip time.Duration) error {
+	if skip <= 0 {
+		_, err := s.db.Exec(`DELETE FROM intro_skips WHERE key = ?`, key)
+		return err
+	}
+
+	_, err := s.db.Exec(`INSERT OR REPLACE INTO intro_skips (key, skip) VALUES (?, ?)`, key, int64(skip))
+	return err
+}
+
+// AddHistory records a played track
+func (s *SQLiteStore) AddHistory(entry HistoryEntry) error {
+	_, err := s.db.Exec(
+		`INSERT INTO history (url, title, artist, played_at, listened) VALUES (?, ?, ?, ?, ?)`,
+		entry.URL, entry.Title, entry.Artist, entry.PlayedAt.UnixNano(), int64(entry.Listened),
+	)
+
+	return err
+}
+
+// History returns up to limit played tracks ordered from most to least recently played. If limit is 0 or less, all
+// played tracks are returned
+func (s *SQLiteStore) History(limit int) ([]HistoryEntry, error) {
+	if limit <= 0 {
+		limit = -1
+	}
+
+	rows, err := s.db.Query(`SELECT url, title, artist, played_at, listened FROM history ORDER BY played_at DESC, id DESC LIMIT ?`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query history: %w", err)
+	}
+
+	defer rows.Close()
+
+	entries := make([]HistoryEntry, 0)
+	for rows.Next() {
+		var playedAt, listened int64
+		entry := HistoryEntry{}
+		if err := rows.Scan(&entry.URL, &entry.Title, &entry.Artist, &playedAt, &listened); err != nil {
+			return nil, fmt.Errorf("failed to scan history entry: %w", err)
+		}
+
+		entry.PlayedAt = time.Unix(0, playedAt)
+		entry.Listened = time.Duration(listened)
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
+
+// AddFavorite adds a track to the favorites. Adding a track which is already a favorite replaces it
+func (s *SQLiteStore) AddFavorite(favorite Favorite) error {
+	_, err := s.db.Exec(
+		`INSERT OR REPLACE INTO favorites (url, title, artist, added_at) VALUES (?, ?, ?, ?)`,
+		favorite.URL, favorite.Title, favorite.Artist, favorite.AddedAt.UnixNano(),
+	)
+
+	return err
+}
+
+// RemoveFavorite removes the track at trackURL from the favorites. Removing a track which is not a favorite does
+// nothing
+func (s *SQLiteStore) RemoveFavorite(trackURL string) error {
+	_, err := s.db.Exec(`DELETE FROM favorites WHERE url = ?`, trackURL)
+	return err
+}
+
+// Favorites returns all favorite tracks ordered from oldest to newest
+func (s *SQLiteStore) Favorites() ([]Favorite, error) {
+	rows, err := s.db.Query(`SELECT url, title, artist, added_at FROM favorites ORDER BY added_at, url`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query favorites: %w", err)
+	}
+
+	defer rows.Close()
+
+	favorites := make([]Favorite, 0)
+	for rows.Next() {
+		var addedAt int64
+		favorite := Favorite{}
+		if err := rows.Scan(&favorite.URL, &favorite.Title, &favorite.Artist, &addedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan favorite: %w", err)
+		}
+
+		favorite.AddedAt = time.Unix(0, addedAt)
+		favorites = append(favorites, favorite)
+	}
+
+	return favorites, rows.Err()
+}
+
+// SavePlaylist saves a list of track URLs under name, replacing any existing playlist with the same name. Saving an
+// empty playlist deletes it
+func (s *SQLiteStore) SavePlaylist(name string, trackURLs []string) error {
+	if name == "" {
+		return errors.New("playlist name cannot be empty")
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM playlists WHERE name = ?`, name); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete playlist %s: %w", name, err)
+	}
+
+	for position, trackURL := range trackURLs {
+		if _, err := tx.Exec(`INSERT INTO playlists (name, position, url) VALUES (?, ?, ?)`, name, position, trackURL); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to insert track into playlist %s: %w", name, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Playlist returns the track URLs of the playlist with name. If there is no such playlist, an empty slice is returned
+func (s *SQLiteStore) Playlist(name string) ([]string, error) {
+	rows, err := s.db.Query(`SELECT url FROM playlists WHERE name = ? ORDER BY position`, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query playlist %s: %w", name, err)
+	}
+
+	defer rows.Close()
+
+	trackURLs := make([]string, 0)
+	for rows.Next() {
+		var trackURL string
+		if err := rows.Scan(&trackURL); err != nil {
+			return nil, fmt.Errorf("failed to scan playlist track: %w", err)
+		}
+
+		trackURLs = append(trackURLs, trackURL)
+	}
+
+	return trackURLs, rows.Err()
+}
+
+// Playlists returns the names of all playlists in alphabetical order
+func (s *SQLiteStore) Playlists() ([]string, error) {
+	rows, err := s.db.Query(`SELECT DISTINCT name FROM playlists ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query playlists: %w", err)
+	}
+
+	defer rows.Close()
+
+	names := make([]string, 0)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan playlist name: %w", err)
+		}
+
+		names = append(names, name)
+	}
+
+	return names, rows.Err()
+}
+
+// TrackMetadata returns the metadata cached for the track page at trackURL. If none is cached, nil is returned
+func (s *SQLiteStore) TrackMetadata(trackURL string) (*TrackMetadata, error) {
+	var tags string
+	var postedAt, cachedAt int64
+	metadata := &TrackMetadata{}
+	err := s.db.QueryRow(
+		`SELECT url, title, artist, download_url, tags, posted_at, description, cached_at FROM track_metadata WHERE url = ?`,
+		trackURL,
+	).Scan(&metadata.URL, &metadata.Title, &metadata.Artist, &metadata.DownloadURL, &tags, &postedAt, &metadata.Description, &cachedAt)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get track metadata for %s: %w", trackURL, err)
+	}
+
+	if err := json.Unmarshal([]byte(tags), &metadata.Tags); err != nil {
+		return nil, fmt.Errorf("failed to decode tags of %s: %w", trackURL, err)
+	}
+
+	// A zero time is stored as 0 rather than the nanoseconds of year 1, which don't fit in an int64
+	if postedAt != 0 {
+		metadata.PostedAt = time.Unix(0, postedAt)
+	}
+
+	metadata.CachedAt = time.Unix(0, cachedAt)
+	return metadata, nil
+}
+
+// SetTrackMetadata caches the metadata of a track, replacing any metadata cached for the same track page
+func (s *SQLiteStore) SetTrackMetadata(metadata TrackMetadata) error {
+	tags, err := json.Marshal(metadata.Tags)
+	if err != nil {
+		return fmt.Errorf("failed to encode tags: %w", err)
+	}
+
+	var postedAt int64
+	if !metadata.PostedAt.IsZero() {
+		postedAt = metadata.PostedAt.UnixNano()
+	}
+
+	_, err = s.db.Exec(
+		`INSERT OR REPLACE INTO track_metadata (url, title, artist, download_url, tags, posted_at, description, cached_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		metadata.URL, metadata.Title, metadata.Artist, metadata.DownloadURL, string(tags), postedAt, metadata.Description, metadata.CachedAt.UnixNano(),
+	)
+
+	return err
+}
+
+// Close releases the database file
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,31 @@
+package store
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSQLiteStore(t *testing.T) {
+	testStore(t, func(t *testing.T) (Store, func()) {
+		dir, err := ioutil.TempDir("", "chipmusic-store")
+		require.NoError(t, err, "failed to create temporary directory")
+
+		store, err := OpenSQLiteStore(filepath.Join(dir, "nested", DefaultSQLiteFileName))
+		require.NoError(t, err, "failed to open store")
+
+		return store, func() {
+			store.Close()
+			os.RemoveAll(dir)
+		}
+	})
+}
+
+func TestOpenSQLiteStore_EmptyPath(t *testing.T) {
+	store, err := OpenSQLiteStore("")
+	assert.Error(t, err)
+	assert.Nil(t, store)
+}
@@ -0,0 +1,220 @@
+package store
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// BackendBolt is the name of the default storage backend, which stores state in a bbolt database file
+	BackendBolt = "bolt"
+
+	// BackendSQLite is the name of the storage backend which stores state in a SQLite database file. This is useful for
+	// big libraries or for querying state with SQL
+	BackendSQLite = "sqlite"
+
+	// BackendMemory is the name of the storage backend which keeps state in memory. Nothing is persisted
+	BackendMemory = "memory"
+)
+
+// Store is an interface for persisting local state such as listening history, favorites, and playlists
+type Store interface {
+
+	// LastSeen returns the URL of the newest track seen for key. If key has never been seen, an empty string is returned
+	LastSeen(key string) (string, error)
+
+	// SetLastSeen records url as the newest track seen for key
+	SetLastSeen(key, url string) error
+
+	// Setting returns the value of the setting with name. If the setting has never been set, an empty string is returned
+	Setting(name string) (string, error)
+
+	// SetSetting sets the setting with name to value. Setting an empty value removes the setting
+	SetSetting(name, value string) error
+
+	// IntroSkip returns how much of the start of a track should be skipped. A rule for the track page URL takes
+	// precedence over a rule for the artist. If there are no rules for either, 0 is returned
+	IntroSkip(trackURL, artist string) (time.Duration, error)
+
+	// SetTrackIntroSkip sets how much of the start of the track at trackURL should be skipped. A skip of 0 or less
+	// removes the rule
+	SetTrackIntroSkip(trackURL string, skip time.Duration) error
+
+	// SetArtistIntroSkip sets how much of the start of every track by artist should be skipped. A skip of 0 or less
+	// removes the rule
+	SetArtistIntroSkip(artist string, skip time.Duration) error
+
+	// AddHistory records a played track
+	AddHistory(entry HistoryEntry) error
+
+	// History returns up to limit played tracks ordered from most to least recently played. If limit is 0 or less, all
+	// played tracks are returned
+	History(limit int) ([]HistoryEntry, error)
+
+	// AddFavorite adds a track to the favorites. Adding a track which is already a favorite replaces it
+	AddFavorite(favorite Favorite) error
+
+	// RemoveFavorite removes the track at trackURL from the favorites. Removing a track which is not a favorite does
+	// nothing
+	RemoveFavorite(trackURL string) error
+
+	// Favorites returns all favorite tracks ordered from oldest to newest
+	Favorites() ([]Favorite, error)
+
+	// SavePlaylist saves a list of track URLs under name, replacing any existing playlist with the same name. Saving an
+	// empty playlist deletes it
+	SavePlaylist(name string, trackURLs []string) error
+
+	// Playlist returns the track URLs of the playlist with name. If there is no such playlist, an empty slice is returned
+	Playlist(name string) ([]string, error)
+
+	// Playlists returns the names of all playlists in alphabetical order
+	Playlists() ([]string, error)
+
+	// TrackMetadata returns the metadata cached for the track page at trackURL. If none is cached, nil is returned
+	TrackMetadata(trackURL string) (*TrackMetadata, error)
+
+	// SetTrackMetadata caches the metadata of a track, replacing any metadata cached for the same track page
+	SetTrackMetadata(metadata TrackMetadata) error
+
+	// Close releases any resources associated with the store
+	Close() error
+}
+
+// HistoryEntry is a record of a played track
+type HistoryEntry struct {
+
+	// URL is the URL of the track page on chipmusic.org
+	URL string
+
+	// Title is the name of the track
+	Title string
+
+	// Artist is the name of the author who composed the track
+	Artist string
+
+	// PlayedAt is when the track started playing
+	PlayedAt time.Time
+
+	// Listened is how long the track was listened to
+	Listened time.Duration
+}
+
+// HistoryFilter selects entries of the listening history. Fields left empty match every entry
+type HistoryFilter struct {
+
+	// Artist only matches tracks by this artist, ignoring case
+	Artist string
+
+	// Query only matches tracks whose title or artist contains it, ignoring case
+	Query string
+
+	// Since only matches tracks which started playing at or after it
+	Since time.Time
+
+	// MinListened only matches tracks listened to for at least this long
+	MinListened time.Duration
+}
+
+// Matches returns true if entry is selected by the filter
+func (f HistoryFilter) Matches(entry HistoryEntry) bool {
+	if f.Artist != "" && !strings.EqualFold(entry.Artist, f.Artist) {
+		return false
+	}
+
+	query := strings.ToLower(f.Query)
+	if query != "" && !strings.Contains(strings.ToLower(entry.Title), query) && !strings.Contains(strings.ToLower(entry.Artist), query) {
+		return false
+	}
+
+	if !f.Since.IsZero() && entry.PlayedAt.Before(f.Since) {
+		return false
+	}
+
+	return entry.Listened >= f.MinListened
+}
+
+// FilterHistory returns up to limit entries selected by filter in the same order. If limit is 0 or less, every entry
+// selected is returned
+func FilterHistory(entries []HistoryEntry, filter HistoryFilter, limit int) []HistoryEntry {
+	filtered := make([]HistoryEntry, 0)
+	for _, entry := range entries {
+		if limit > 0 && len(filtered) >= limit {
+			break
+		}
+
+		if filter.Matches(entry) {
+			filtered = append(filtered, entry)
+		}
+	}
+
+	return filtered
+}
+
+// Favorite is a track marked as a favorite
+type Favorite struct {
+
+	// URL is the URL of the track page on chipmusic.org
+	URL string
+
+	// Title is the name of the track
+	Title string
+
+	// Artist is the name of the author who composed the track
+	Artist string
+
+	// AddedAt is when the track was marked as a favorite
+	AddedAt time.Time
+}
+
+// TrackMetadata is the metadata parsed from a track page, cached so the page doesn't have to be fetched again
+type TrackMetadata struct {
+
+	// URL is the URL of the track page on chipmusic.org
+	URL string
+
+	// Title is the name of the track
+	Title string
+
+	// Artist is the name of the author who composed the track
+	Artist string
+
+	// DownloadURL is the URL of the audio file for the track
+	DownloadURL string
+
+	// Tags are the tags the artist added to the track
+	Tags []string
+
+	// PostedAt is when the track was posted to chipmusic.org
+	PostedAt time.Time
+
+	// Description is the text the artist wrote about the track
+	Description string
+
+	// CachedAt is when the metadata was parsed from the track page
+	CachedAt time.Time
+}
+
+// Open opens the store for backend within dir, creating any files it needs
+func Open(backend, dir string) (Store, error) {
+	switch backend {
+	case BackendBolt, "":
+		return OpenBoltStore(filepath.Join(dir, DefaultFileName))
+	case BackendSQLite:
+		return OpenSQLiteStore(filepath.Join(dir, DefaultSQLiteFileName))
+	case BackendMemory:
+		return NewMemoryStore(), nil
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q: must be one of [%s, %s, %s]", backend, BackendBolt, BackendSQLite, BackendMemory)
+	}
+}
+
+func trackIntroSkipKey(trackURL string) string {
+	return "track:" + trackURL
+}
+
+func artistIntroSkipKey(artist string) string {
+	return "artist:" + artist
+}
@@ -0,0 +1,281 @@
+package store
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+// testStore runs the behaviour shared by every Store implementation against the store returned by open
+func testStore(t *testing.T, open func(t *testing.T) (Store, func())) {
+	t.Run("LastSeen", func(tt *testing.T) {
+		store, cleanup := open(tt)
+		defer cleanup()
+
+		url, err := store.LastSeen("some.key")
+		require.NoError(tt, err)
+		assert.Empty(tt, url)
+
+		require.NoError(tt, store.SetLastSeen("some.key", "some.url"))
+		require.NoError(tt, store.SetLastSeen("some.key", "other.url"))
+
+		url, err = store.LastSeen("some.key")
+		require.NoError(tt, err)
+		assert.Equal(tt, "other.url", url)
+	})
+
+	t.Run("Setting", func(tt *testing.T) {
+		store, cleanup := open(tt)
+		defer cleanup()
+
+		value, err := store.Setting("some.setting")
+		require.NoError(tt, err)
+		assert.Empty(tt, value)
+
+		require.NoError(tt, store.SetSetting("some.setting", "some.value"))
+		require.NoError(tt, store.SetSetting("some.setting", "other.value"))
+
+		value, err = store.Setting("some.setting")
+		require.NoError(tt, err)
+		assert.Equal(tt, "other.value", value)
+
+		require.NoError(tt, store.SetSetting("some.setting", ""))
+
+		value, err = store.Setting("some.setting")
+		require.NoError(tt, err)
+		assert.Empty(tt, value)
+	})
+
+	t.Run("IntroSkip", func(tt *testing.T) {
+		store, cleanup := open(tt)
+		defer cleanup()
+
+		require.NoError(tt, store.SetArtistIntroSkip("some.artist", 5*time.Second))
+
+		skip, err := store.IntroSkip("some.url", "some.artist")
+		require.NoError(tt, err)
+		assert.Equal(tt, 5*time.Second, skip)
+
+		require.NoError(tt, store.SetTrackIntroSkip("some.url", 10*time.Second))
+
+		skip, err = store.IntroSkip("some.url", "some.artist")
+		require.NoError(tt, err)
+		assert.Equal(tt, 10*time.Second, skip)
+
+		require.NoError(tt, store.SetTrackIntroSkip("some.url", 0))
+		require.NoError(tt, store.SetArtistIntroSkip("some.artist", 0))
+
+		skip, err = store.IntroSkip("some.url", "some.artist")
+		require.NoError(tt, err)
+		assert.Zero(tt, skip)
+	})
+
+	t.Run("History", func(tt *testing.T) {
+		store, cleanup := open(tt)
+		defer cleanup()
+
+		entries, err := store.History(0)
+		require.NoError(tt, err)
+		assert.Empty(tt, entries)
+
+		now := time.Unix(1600000000, 0)
+		first := HistoryEntry{URL: "first.url", Title: "first.title", Artist: "some.artist", PlayedAt: now, Listened: time.Minute}
+		second := HistoryEntry{URL: "second.url", Title: "second.title", Artist: "some.artist", PlayedAt: now.Add(time.Hour), Listened: time.Second}
+		third := HistoryEntry{URL: "third.url", Title: "third.title", Artist: "other.artist", PlayedAt: now.Add(time.Minute)}
+
+		for _, entry := range []HistoryEntry{first, second, third} {
+			require.NoError(tt, store.AddHistory(entry))
+		}
+
+		entries, err = store.History(0)
+		require.NoError(tt, err)
+		assertHistoryEqual(tt, []HistoryEntry{second, third, first}, entries)
+
+		entries, err = store.History(2)
+		require.NoError(tt, err)
+		assertHistoryEqual(tt, []HistoryEntry{second, third}, entries)
+	})
+
+	t.Run("Favorites", func(tt *testing.T) {
+		store, cleanup := open(tt)
+		defer cleanup()
+
+		now := time.Unix(1600000000, 0)
+		older := Favorite{URL: "b.url", Title: "older.title", Artist: "some.artist", AddedAt: now}
+		newer := Favorite{URL: "a.url", Title: "newer.title", Artist: "some.artist", AddedAt: now.Add(time.Hour)}
+
+		require.NoError(tt, store.AddFavorite(newer))
+		require.NoError(tt, store.AddFavorite(older))
+
+		favorites, err := store.Favorites()
+		require.NoError(tt, err)
+		require.Len(tt, favorites, 2)
+		assert.Equal(tt, older.URL, favorites[0].URL)
+		assert.True(tt, older.AddedAt.Equal(favorites[0].AddedAt))
+		assert.Equal(tt, newer.URL, favorites[1].URL)
+
+		require.NoError(tt, store.RemoveFavorite(newer.URL))
+		require.NoError(tt, store.RemoveFavorite("missing.url"))
+
+		favorites, err = store.Favorites()
+		require.NoError(tt, err)
+		require.Len(tt, favorites, 1)
+		assert.Equal(tt, older.URL, favorites[0].URL)
+	})
+
+	t.Run("Playlists", func(tt *testing.T) {
+		store, cleanup := open(tt)
+		defer cleanup()
+
+		tracks, err := store.Playlist("missing")
+		require.NoError(tt, err)
+		assert.Empty(tt, tracks)
+
+		require.NoError(tt, store.SavePlaylist("b", []string{"c.url", "a.url", "b.url"}))
+		require.NoError(tt, store.SavePlaylist("a", []string{"a.url"}))
+		require.NoError(tt, store.SavePlaylist("a", []string{"b.url", "a.url"}))
+		assert.Error(tt, store.SavePlaylist("", []string{"a.url"}))
+
+		tracks, err = store.Playlist("b")
+		require.NoError(tt, err)
+		assert.Equal(tt, []string{"c.url", "a.url", "b.url"}, tracks)
+
+		tracks, err = store.Playlist("a")
+		require.NoError(tt, err)
+		assert.Equal(tt, []string{"b.url", "a.url"}, tracks)
+
+		names, err := store.Playlists()
+		require.NoError(tt, err)
+		assert.Equal(tt, []string{"a", "b"}, names)
+
+		require.NoError(tt, store.SavePlaylist("a", nil))
+
+		names, err = store.Playlists()
+		require.NoError(tt, err)
+		assert.Equal(tt, []string{"b"}, names)
+	})
+
+	t.Run("TrackMetadata", func(tt *testing.T) {
+		store, cleanup := open(tt)
+		defer cleanup()
+
+		metadata, err := store.TrackMetadata("some.url")
+		require.NoError(tt, err)
+		assert.Nil(tt, metadata)
+
+		now := time.Unix(1600000000, 0)
+		cached := TrackMetadata{
+			URL:         "some.url",
+			Title:       "some.title",
+			Artist:      "some.artist",
+			DownloadURL: "some.download.url",
+			Tags:        []string{"lsdj", "electro"},
+			Description: "some.description",
+			CachedAt:    now,
+		}
+
+		require.NoError(tt, store.SetTrackMetadata(cached))
+
+		metadata, err = store.TrackMetadata("some.url")
+		require.NoError(tt, err)
+		require.NotNil(tt, metadata)
+		assert.Equal(tt, cached.Title, metadata.Title)
+		assert.Equal(tt, cached.DownloadURL, metadata.DownloadURL)
+		assert.Equal(tt, cached.Tags, metadata.Tags)
+		assert.Equal(tt, cached.Description, metadata.Description)
+		assert.True(tt, metadata.PostedAt.IsZero(), "expected an unknown posting date to stay unknown")
+		assert.True(tt, now.Equal(metadata.CachedAt))
+
+		cached.Title, cached.PostedAt = "new.title", now.Add(-time.Hour)
+		require.NoError(tt, store.SetTrackMetadata(cached))
+
+		metadata, err = store.TrackMetadata("some.url")
+		require.NoError(tt, err)
+		assert.Equal(tt, "new.title", metadata.Title)
+		assert.True(tt, cached.PostedAt.Equal(metadata.PostedAt))
+	})
+}
+
+func assertHistoryEqual(t *testing.T, expected, actual []HistoryEntry) {
+	require.Len(t, actual, len(expected))
+	for i := range expected {
+		assert.Equal(t, expected[i].URL, actual[i].URL)
+		assert.Equal(t, expected[i].Title, actual[i].Title)
+		assert.Equal(t, expected[i].Artist, actual[i].Artist)
+		assert.True(t, expected[i].PlayedAt.Equal(actual[i].PlayedAt))
+		assert.Equal(t, expected[i].Listened, actual[i].Listened)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	testCases := []struct {
+		name    string
+		backend string
+	}{
+		{"Default", ""},
+		{"Bolt", BackendBolt},
+		{"SQLite", BackendSQLite},
+		{"Memory", BackendMemory},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			dir, err := ioutil.TempDir("", "chipmusic-store")
+			require.NoError(tt, err, "failed to create temporary directory")
+
+			defer os.RemoveAll(dir)
+
+			store, err := Open(testCase.backend, dir)
+			require.NoError(tt, err)
+			assert.NoError(tt, store.Close())
+		})
+	}
+}
+
+func TestOpen_UnknownBackend(t *testing.T) {
+	store, err := Open("some.backend", os.TempDir())
+	assert.Error(t, err)
+	assert.Nil(t, store)
+}
+
+func TestFilterHistory(t *testing.T) {
+	now := time.Date(2020, 1, 2, 12, 0, 0, 0, time.UTC)
+	entries := []HistoryEntry{
+		{URL: "first.url", Title: "Some Title", Artist: "Some Artist", PlayedAt: now, Listened: 2 * time.Minute},
+		{URL: "second.url", Title: "Other Title", Artist: "Other Artist", PlayedAt: now.Add(-time.Hour), Listened: 10 * time.Second},
+		{URL: "third.url", Title: "Some Other Title", Artist: "Other Artist", PlayedAt: now.Add(-48 * time.Hour), Listened: 3 * time.Minute},
+	}
+
+	urls := func(entries []HistoryEntry) []string {
+		urls := make([]string, 0, len(entries))
+		for _, entry := range entries {
+			urls = append(urls, entry.URL)
+		}
+
+		return urls
+	}
+
+	testCases := []struct {
+		name     string
+		filter   HistoryFilter
+		limit    int
+		expected []string
+	}{
+		{"NoFilter", HistoryFilter{}, 0, []string{"first.url", "second.url", "third.url"}},
+		{"Limit", HistoryFilter{}, 2, []string{"first.url", "second.url"}},
+		{"Artist", HistoryFilter{Artist: "other artist"}, 0, []string{"second.url", "third.url"}},
+		{"Query", HistoryFilter{Query: "some"}, 0, []string{"first.url", "third.url"}},
+		{"Since", HistoryFilter{Since: now.Add(-time.Hour)}, 0, []string{"first.url", "second.url"}},
+		{"MinListened", HistoryFilter{MinListened: time.Minute}, 0, []string{"first.url", "third.url"}},
+		{"LimitAfterFilter", HistoryFilter{Artist: "Other Artist"}, 1, []string{"second.url"}},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(tt *testing.T) {
+			assert.Equal(tt, testCase.expected, urls(FilterHistory(entries, testCase.filter, testCase.limit)))
+		})
+	}
+}